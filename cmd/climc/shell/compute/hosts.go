@@ -16,6 +16,7 @@ package compute
 
 import (
 	"fmt"
+	"strings"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
@@ -825,6 +826,66 @@ func init() {
 		return nil
 	})
 
+	type HostRebalanceOptions struct {
+		GroupBy   string  `help:"Rebalance among hosts of the same zone or schedtag" choices:"zone|schedtag"`
+		MaxMoves  int     `help:"Maximal live migrations"`
+		Threshold float32 `help:"Rebalance when the load gap between the busiest and idlest hosts exceeds this ratio"`
+	}
+	R(&HostRebalanceOptions{}, "host-rebalance-plan", "Show live migrations to rebalance host load", func(s *mcclient.ClientSession, args *HostRebalanceOptions) error {
+		result, err := modules.Hosts.Get(s, "rebalance-plan", jsonutils.Marshal(args))
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+
+	R(&HostRebalanceOptions{}, "host-rebalance", "Rebalance host load by live migrating guests", func(s *mcclient.ClientSession, args *HostRebalanceOptions) error {
+		result, err := modules.Hosts.PerformClassAction(s, "rebalance", jsonutils.Marshal(args))
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+
+	type HostEnterMaintenanceOptions struct {
+		ID          string   `help:"ID or name of host" json:"-"`
+		PreferHost  string   `help:"Migrate guests to this host"`
+		DrainPolicy string   `help:"Default drain policy of guests" choices:"live-migrate|cold-migrate|stop|skip"`
+		GuestPolicy []string `help:"Drain policy of a guest, e.g. vm1=stop" json:"-"`
+		Concurrency int      `help:"Count of guests drained in a batch, next batch starts after the whole batch is done"`
+	}
+	R(&HostEnterMaintenanceOptions{}, "host-enter-maintenance", "Drain guests out of host and enter maintenance", func(s *mcclient.ClientSession, args *HostEnterMaintenanceOptions) error {
+		params := jsonutils.Marshal(args).(*jsonutils.JSONDict)
+		policies := jsonutils.NewDict()
+		for _, p := range args.GuestPolicy {
+			segs := strings.SplitN(p, "=", 2)
+			if len(segs) != 2 {
+				return fmt.Errorf("invalid guest policy %s", p)
+			}
+			policies.Add(jsonutils.NewString(segs[1]), segs[0])
+		}
+		if policies.Length() > 0 {
+			params.Add(policies, "guest_policies")
+		}
+		result, err := modules.Hosts.PerformAction(s, args.ID, "host-maintenance", params)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+
+	R(&HostDetailOptions{}, "host-exit-maintenance", "Exit maintenance and resume scheduling", func(s *mcclient.ClientSession, args *HostDetailOptions) error {
+		result, err := modules.Hosts.PerformAction(s, args.ID, "host-exit-maintenance", nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+
 	type HostSetReservedResourceForIsolatedDevice struct {
 		ID              []string `help:"ID or name of host" json:"-"`
 		ReservedCpu     *int     `help:"reserved cpu count"`
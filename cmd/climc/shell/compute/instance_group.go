@@ -30,6 +30,8 @@ func init() {
 		ParentId    string `help:"Parent ID"`
 		ZoneId      string `help:"Zone ID"`
 		Server      string `help:"Guest ID or Name"`
+
+		AffinityType string `help:"Filter by affinity type" choices:"affinity|anti-affinity"`
 	}
 
 	R(&InstanceGroupListOptions{}, "instance-group-list", "List instance group", func(s *mcclient.ClientSession,
@@ -70,6 +72,11 @@ func init() {
 		SchedStrategy   string `help:"scheduler strategy"`
 		Granularity     string `help:"the upper limit number of guests with this group in a host"`
 		ForceDispersion bool   `help:"force to make guest dispersion"`
+
+		AffinityType   string `help:"schedule guests of the group by affinity rule instead of granularity" choices:"affinity|anti-affinity"`
+		AffinityMode   string `help:"hard rule filters hosts, soft rule only scores them" choices:"hard|soft"`
+		TopologyKey    string `help:"topology domain of the affinity rule, host, rack, zone, storage or schedtag:<prefix>"`
+		AffinityWeight int    `help:"score weight of soft affinity rule"`
 	}
 
 	R(&InstanceGroupCreateOptions{}, "instance-group-create", "Create a instance group",
@@ -103,6 +110,11 @@ func init() {
 		Name            string `help:"New name to change"`
 		Granularity     string `help:"the upper limit number of guests with this group in a host"`
 		ForceDispersion string `help:"force to make guest dispersion" choices:"yes|no" json:"-"`
+
+		AffinityType   string `help:"affinity type of the group" choices:"affinity|anti-affinity"`
+		AffinityMode   string `help:"hard rule filters hosts, soft rule only scores them" choices:"hard|soft"`
+		TopologyKey    string `help:"topology domain of the affinity rule, host, rack, zone, storage or schedtag:<prefix>"`
+		AffinityWeight int    `help:"score weight of soft affinity rule"`
 	}
 
 	R(&InstanceGroupUpdateOptions{}, "instance-group-update", "update a instance group",
@@ -118,11 +118,20 @@ func init() {
 		PREFIX  string `help:"Start of IPv4 address range"`
 		BgpType string `help:"Internet service provider name" positional:"false"`
 		Desc    string `help:"Description" metavar:"DESCRIPTION"`
+
+		Ip6Prefix string `help:"IPv6 address prefix, e.g. fd00:1::/64"`
+		Ip6Mode   string `help:"IPv6 address configuration mode" choices:"dhcpv6|slaac"`
 	}
 	R(&NetworkCreateOptions2{}, "network-create2", "Create a virtual network", func(s *mcclient.ClientSession, args *NetworkCreateOptions2) error {
 		params := jsonutils.NewDict()
 		params.Add(jsonutils.NewString(args.NAME), "name")
 		params.Add(jsonutils.NewString(args.PREFIX), "guest_ip_prefix")
+		if len(args.Ip6Prefix) > 0 {
+			params.Add(jsonutils.NewString(args.Ip6Prefix), "guest_ip6_prefix")
+		}
+		if len(args.Ip6Mode) > 0 {
+			params.Add(jsonutils.NewString(args.Ip6Mode), "guest_ip6_mode")
+		}
 		if len(args.BgpType) > 0 {
 			params.Add(jsonutils.NewString(args.BgpType), "bgp_type")
 		}
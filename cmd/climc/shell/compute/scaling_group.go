@@ -66,6 +66,10 @@ func init() {
 		MaxInstanceNumber    string
 		DesireInstanceNumber string
 		Loadbalance          string
+		HealthCheckMode      string `choices:"normal|loadbalancer"`
+		HealthCheckProbe     string `help:"Probe the service of instances" choices:"tcp|http"`
+		HealthCheckPort      int    `help:"Port to probe"`
+		HealthCheckPath      string `help:"Path of http probe"`
 	}
 	R(&ScalingGroupCreateOptions{}, "scaling-group-create", "Create scaling group", func(s *mcclient.ClientSession, args *ScalingGroupCreateOptions) error {
 		params := jsonutils.Marshal(args).(*jsonutils.JSONDict)
@@ -142,4 +146,49 @@ func init() {
 		printObject(ret)
 		return nil
 	})
+
+	type ScalingGroupInstanceRefreshOptions struct {
+		ID                   string `help:"ScalingGroup ID or Name" json:"-"`
+		GuestTemplate        string `help:"New guest template ID or Name, the current one is used if not specified"`
+		MinHealthyPercentage int    `help:"Min percentage of healthy instances during refresh"`
+		DrainTime            int    `help:"Time to wait after removing instance from loadbalancer, unit: s"`
+		HealthCheckTimeout   int    `help:"Timeout for new instances becoming healthy, unit: s"`
+		DisableRollback      bool   `help:"Only pause the refresh when health check failed"`
+	}
+	R(&ScalingGroupInstanceRefreshOptions{}, "scaling-group-instance-refresh", "Replace instances of ScalingGroup in batches",
+		func(s *mcclient.ClientSession, args *ScalingGroupInstanceRefreshOptions) error {
+			params, err := options.StructToParams(args)
+			if err != nil {
+				return err
+			}
+			ret, err := modules.ScalingGroup.PerformAction(s, args.ID, "instance-refresh", params)
+			if err != nil {
+				return err
+			}
+			printObject(ret)
+			return nil
+		},
+	)
+
+	type ScalingGroupCompleteLifecycleActionOptions struct {
+		ID     string `help:"ScalingGroup ID or Name" json:"-"`
+		GUEST  string `help:"Server ID or Name which is waiting for the lifecycle hook"`
+		TOKEN  string `help:"Token in the notification of lifecycle hook"`
+		Result string `help:"Result of lifecycle action" choices:"continue|abandon" default:"continue"`
+	}
+	R(&ScalingGroupCompleteLifecycleActionOptions{}, "scaling-group-complete-lifecycle-action",
+		"Complete the lifecycle action of instance in ScalingGroup",
+		func(s *mcclient.ClientSession, args *ScalingGroupCompleteLifecycleActionOptions) error {
+			params, err := options.StructToParams(args)
+			if err != nil {
+				return err
+			}
+			ret, err := modules.ScalingGroup.PerformAction(s, args.ID, "complete-lifecycle-action", params)
+			if err != nil {
+				return err
+			}
+			printObject(ret)
+			return nil
+		},
+	)
 }
@@ -0,0 +1,95 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/modules"
+	"yunion.io/x/onecloud/pkg/mcclient/options"
+)
+
+func init() {
+	type ScalingLifecycleHookListOptions struct {
+		options.BaseListOptions
+		ScalingGroup string `help:"ScalingGroup ID or Name"`
+		Transition   string `help:"Lifecycle transition" choices:"launching|terminating"`
+	}
+	R(&ScalingLifecycleHookListOptions{}, "scaling-lifecycle-hook-list", "List Scaling Lifecycle Hook",
+		func(s *mcclient.ClientSession, args *ScalingLifecycleHookListOptions) error {
+			params, err := options.ListStructToParams(args)
+			if err != nil {
+				return err
+			}
+			hooks, err := modules.ScalingLifecycleHook.List(s, params)
+			if err != nil {
+				return err
+			}
+			printList(hooks, modules.ScalingLifecycleHook.GetColumns(s))
+			return nil
+		},
+	)
+
+	type ScalingLifecycleHookShowOptions struct {
+		ID string `help:"ScalingLifecycleHook ID or Name"`
+	}
+	R(&ScalingLifecycleHookShowOptions{}, "scaling-lifecycle-hook-show", "Show Scaling Lifecycle Hook",
+		func(s *mcclient.ClientSession, args *ScalingLifecycleHookShowOptions) error {
+			hook, err := modules.ScalingLifecycleHook.Get(s, args.ID, nil)
+			if err != nil {
+				return err
+			}
+			printObject(hook)
+			return nil
+		},
+	)
+
+	type ScalingLifecycleHookCreateOptions struct {
+		NAME            string
+		SCALINGGROUP    string `help:"ScalingGroup ID or Name" json:"scaling_group"`
+		TRANSITION      string `help:"Lifecycle transition" choices:"launching|terminating"`
+		NOTIFYTYPE      string `help:"Notify type" choices:"webhook|ansible" json:"notify_type"`
+		WebhookUrl      string `help:"Webhook url to post the notification"`
+		AnsiblePlaybook string `help:"Ansible playbook ID or Name to run on the instance"`
+		Timeout         int    `help:"Time to wait for the callback, unit: s"`
+		DefaultResult   string `help:"Result when timeout or notify failed" choices:"continue|abandon"`
+	}
+	R(&ScalingLifecycleHookCreateOptions{}, "scaling-lifecycle-hook-create", "Create Scaling Lifecycle Hook",
+		func(s *mcclient.ClientSession, args *ScalingLifecycleHookCreateOptions) error {
+			params := jsonutils.Marshal(args).(*jsonutils.JSONDict)
+			hook, err := modules.ScalingLifecycleHook.Create(s, params)
+			if err != nil {
+				return err
+			}
+			printObject(hook)
+			return nil
+		},
+	)
+
+	type ScalingLifecycleHookDeleteOptions struct {
+		ID string `help:"ScalingLifecycleHook ID or Name"`
+	}
+	R(&ScalingLifecycleHookDeleteOptions{}, "scaling-lifecycle-hook-delete", "Delete Scaling Lifecycle Hook",
+		func(s *mcclient.ClientSession, args *ScalingLifecycleHookDeleteOptions) error {
+			hook, err := modules.ScalingLifecycleHook.Delete(s, args.ID, nil)
+			if err != nil {
+				return err
+			}
+			printObject(hook)
+			return nil
+		},
+	)
+}
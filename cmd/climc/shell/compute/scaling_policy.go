@@ -16,6 +16,8 @@ package compute
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"yunion.io/x/jsonutils"
@@ -44,7 +46,7 @@ func init() {
 	type ScalingPolicyListOptions struct {
 		options.BaseListOptions
 		ScalingGroup string `help:"ScalingGroup ID or Name"`
-		TriggerType  string `help:"Trigger type" choices:"alarm|timing|cycle"`
+		TriggerType  string `help:"Trigger type" choices:"alarm|timing|cycle|target|step"`
 	}
 	R(&ScalingPolicyListOptions{}, "scaling-policy-list", "List Scaling Policy", func(s *mcclient.ClientSession,
 		args *ScalingPolicyListOptions) error {
@@ -81,14 +83,34 @@ func init() {
 		AlarmValue     float64 `help:"Value of Indicator" json:"value"`
 	}
 
+	type ScalingTarget struct {
+		TargetIndicator      string  `help:"Indicator for 'target' trigger"`
+		TargetValue          float64 `help:"Target value of indicator for 'target' trigger"`
+		TargetCycle          int     `help:"Time window to calculate the indicator, unit: s"`
+		TargetWarmUp         int     `help:"Warm up time of new instances, unit: s"`
+		TargetScaleOutCool   int     `help:"Cooling time after scaling out, unit: s"`
+		TargetScaleInCool    int     `help:"Cooling time after scaling in, unit: s"`
+		TargetDisableScaleIn bool    `help:"Only scale out for 'target' trigger"`
+	}
+
+	type ScalingStep struct {
+		StepIndicator string   `help:"Indicator for 'step' trigger"`
+		StepWrapper   string   `help:"Wrapper for Indicators" choices:"max|min|average"`
+		StepCycle     int      `help:"Time window to calculate the indicator, unit: s"`
+		StepWarmUp    int      `help:"Warm up time of new instances, unit: s"`
+		Step          []string `help:"Step of 'step' trigger, format: <lower_bound>,<upper_bound>,<action>,<number>,<unit>,<cooling_time>, empty bound means infinity, e.g. 80,,add,2,s,300" json:"-"`
+	}
+
 	type ScalingPolicyCreateOptions struct {
 		NAME         string `help:"ScalingPolicy Name" json:"name"`
 		ScalingGroup string `help:"ScalingGroup ID or Name" json:"scaling_group"`
-		TriggerType  string `help:"Trigger type" choices:"alarm|timing|cycle" json:"trigger_type"`
+		TriggerType  string `help:"Trigger type" choices:"alarm|timing|cycle|target|step" json:"trigger_type"`
 
 		Timer
 		CycleTimer
 		ScalingAlarm
+		ScalingTarget
+		ScalingStep
 
 		Action      string `help:"Action for scaling policy" choices:"add|remove|set" json:"action"`
 		Number      int    `help:"Instance number for action" json:"number"`
@@ -111,6 +133,14 @@ func init() {
 			if err != nil {
 				return fmt.Errorf("invalid time format for 'end_time'")
 			}
+			steps := make([]api.ScalingStep, 0, len(args.Step))
+			for _, stepStr := range args.Step {
+				step, err := parseScalingStep(stepStr)
+				if err != nil {
+					return err
+				}
+				steps = append(steps, step)
+			}
 			spCreateInput := api.ScalingPolicyCreateInput{
 				ScalingGroup: args.ScalingGroup,
 				TriggerType:  args.TriggerType,
@@ -134,6 +164,22 @@ func init() {
 					Operator:  args.AlarmOperator,
 					Value:     args.AlarmValue,
 				},
+				Target: api.ScalingTargetCreateInput{
+					Indicator:           args.TargetIndicator,
+					TargetValue:         args.TargetValue,
+					Cycle:               args.TargetCycle,
+					WarmUp:              args.TargetWarmUp,
+					ScaleOutCoolingTime: args.TargetScaleOutCool,
+					ScaleInCoolingTime:  args.TargetScaleInCool,
+					DisableScaleIn:      args.TargetDisableScaleIn,
+				},
+				Step: api.ScalingStepCreateInput{
+					Indicator: args.StepIndicator,
+					Wrapper:   args.StepWrapper,
+					Cycle:     args.StepCycle,
+					WarmUp:    args.StepWarmUp,
+					Steps:     steps,
+				},
 				Action:      args.Action,
 				Number:      args.Number,
 				Unit:        args.Unit,
@@ -200,3 +246,41 @@ func init() {
 		},
 	)
 }
+
+func parseScalingStep(str string) (api.ScalingStep, error) {
+	step := api.ScalingStep{}
+	segs := strings.Split(str, ",")
+	if len(segs) != 6 {
+		return step, fmt.Errorf("invalid step %s", str)
+	}
+	parseBound := func(s string) (*float64, error) {
+		if len(s) == 0 {
+			return nil, nil
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bound %s", s)
+		}
+		return &v, nil
+	}
+	var err error
+	step.LowerBound, err = parseBound(segs[0])
+	if err != nil {
+		return step, err
+	}
+	step.UpperBound, err = parseBound(segs[1])
+	if err != nil {
+		return step, err
+	}
+	step.Action = segs[2]
+	step.Number, err = strconv.Atoi(segs[3])
+	if err != nil {
+		return step, fmt.Errorf("invalid number %s", segs[3])
+	}
+	step.Unit = segs[4]
+	step.CoolingTime, err = strconv.Atoi(segs[5])
+	if err != nil {
+		return step, fmt.Errorf("invalid cooling time %s", segs[5])
+	}
+	return step, nil
+}
@@ -58,6 +58,34 @@ func init() {
 			return nil
 		})
 
+	R(&options.SchedulerExplainOptions{}, "scheduler-explain", "Explain predicate verdicts and priority scores of every candidate",
+		func(s *mcclient.ClientSession, args *options.SchedulerExplainOptions) error {
+			params, err := args.Params(s)
+			if err != nil {
+				return err
+			}
+			result, err := modules.SchedManager.DoExplain(s, params.JSON(params))
+			if err != nil {
+				return err
+			}
+			fmt.Println(result.YAMLString())
+			return nil
+		})
+
+	R(&options.SchedulerSimulateOptions{}, "scheduler-simulate", "Simulate hypothetical requests without reserving resources",
+		func(s *mcclient.ClientSession, args *options.SchedulerSimulateOptions) error {
+			params, err := args.Params(s)
+			if err != nil {
+				return err
+			}
+			result, err := modules.SchedManager.DoSimulate(s, params.JSON(params))
+			if err != nil {
+				return err
+			}
+			fmt.Println(result.YAMLString())
+			return nil
+		})
+
 	type SchedulerCandidateListOptions struct {
 		Type   string `help:"Sched type filter" choices:"baremetal|host"`
 		Region string `help:"Cloud region ID"`
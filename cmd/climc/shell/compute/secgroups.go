@@ -25,7 +25,7 @@ func init() {
 	cmd.List(&options.SecgroupListOptions{})
 	cmd.Create(&options.SecgroupCreateOptions{})
 	cmd.Show(&options.SecgroupIdOptions{})
-	cmd.Update(&options.BaseUpdateOptions{})
+	cmd.Update(&options.SecgroupUpdateOptions{})
 	cmd.Delete(&options.SecgroupIdOptions{})
 	cmd.Perform("merge", &options.SecgroupMergeOptions{})
 	cmd.Perform("public", &options.SecgroupIdOptions{})
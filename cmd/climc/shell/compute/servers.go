@@ -61,6 +61,17 @@ func init() {
 	cmd.BatchPerform("purge", new(options.ServerIdsOptions))
 	cmd.Perform("migrate", new(options.ServerMigrateOptions))
 	cmd.Perform("live-migrate", new(options.ServerLiveMigrateOptions))
+	cmd.Perform("change-disk-storage", new(options.ServerChangeDiskStorageOptions))
+	cmd.Perform("attach-virtiofs", new(options.ServerAttachVirtiofsOptions))
+	cmd.Perform("detach-virtiofs", new(options.ServerDetachVirtiofsOptions))
+	cmd.Perform("qga-fsfreeze", new(options.ServerIdOptions))
+	cmd.Perform("qga-fsthaw", new(options.ServerIdOptions))
+	cmd.Perform("qga-set-password", new(options.ServerQgaSetPasswordOptions))
+	cmd.Perform("qga-guest-info", new(options.ServerIdOptions))
+	cmd.Perform("qga-file-read", new(options.ServerQgaFileReadOptions))
+	cmd.Perform("qga-file-write", new(options.ServerQgaFileWriteOptions))
+	cmd.Perform("set-ha-policy", new(options.ServerSetHaPolicyOptions))
+	cmd.Perform("set-host-drain-policy", new(options.ServerSetHostDrainPolicyOptions))
 	cmd.Perform("modify-src-check", new(options.ServerModifySrcCheckOptions))
 	cmd.Perform("set-secgroup", new(options.ServerSecGroupsOptions))
 	cmd.Perform("add-secgroup", new(options.ServerSecGroupsOptions))
@@ -694,6 +705,7 @@ func init() {
 	type ServerCreateSnapshot struct {
 		ID       string `help:"ID or name of VM" json:"-"`
 		SNAPSHOT string `help:"Instance snapshot name" json:"name"`
+		Quiesce  bool   `help:"Freeze guest filesystems through guest agent before creating snapshot" json:"quiesce"`
 	}
 	R(&ServerCreateSnapshot{}, "instance-snapshot-create", "create instance snapshot", func(s *mcclient.ClientSession, opts *ServerCreateSnapshot) error {
 		params := jsonutils.Marshal(opts)
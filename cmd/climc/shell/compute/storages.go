@@ -16,6 +16,7 @@ package compute
 
 import (
 	"fmt"
+	"strings"
 
 	"yunion.io/x/jsonutils"
 
@@ -89,7 +90,7 @@ func init() {
 		ZONE                  string `help:"Zone id of storage"`
 		Capacity              int64  `help:"Capacity of the Storage"`
 		MediumType            string `help:"Medium type" choices:"ssd|rotate"`
-		StorageType           string `help:"Storage type" choices:"local|nas|vsan|rbd|nfs|gpfs|baremetal"`
+		StorageType           string `help:"Storage type" choices:"local|nas|vsan|rbd|nfs|gpfs|cifs|lvm|iscsi_lvm|plugin|baremetal"`
 		RbdMonHost            string `help:"Ceph mon_host config"`
 		RbdRadosMonOpTimeout  int64  `help:"ceph rados_mon_op_timeout"`
 		RbdRadosOsdOpTimeout  int64  `help:"ceph rados_osd_op_timeout"`
@@ -98,6 +99,21 @@ func init() {
 		RbdPool               string `help:"Ceph Pool Name"`
 		NfsHost               string `help:"NFS host"`
 		NfsSharedDir          string `help:"NFS shared dir"`
+		CifsHost              string `help:"CIFS/SMB server host"`
+		CifsSharedDir         string `help:"CIFS/SMB share name"`
+		CifsUsername          string `help:"CIFS/SMB username, mount as guest if not set"`
+		CifsPassword          string `help:"CIFS/SMB password"`
+		CifsDomain            string `help:"CIFS/SMB user domain"`
+		CifsMountOptions      string `help:"Extra mount.cifs options, e.g. vers=3.0"`
+		LvmVgName             string `help:"LVM volume group name"`
+		LvmThinPool           string `help:"LVM thin pool name, thick provisioning if not set"`
+		IscsiPortal           string `help:"iSCSI target portal, e.g. 192.168.1.10:3260"`
+		IscsiTarget           string `help:"iSCSI target iqn"`
+		IscsiChapUsername     string `help:"iSCSI CHAP username"`
+		IscsiChapPassword     string `help:"iSCSI CHAP password"`
+
+		PluginDriver string   `help:"Storage plugin driver name, plugin listens on <driver>.sock of host"`
+		PluginOption []string `help:"Storage plugin option, e.g. cluster=sheep1, can be repeated"`
 	}
 	R(&StorageCreateOptions{}, "storage-create", "Create a Storage", func(s *mcclient.ClientSession, args *StorageCreateOptions) error {
 		params, err := options.StructToParams(args)
@@ -113,6 +129,32 @@ func init() {
 			if len(args.NfsHost) == 0 || len(args.NfsSharedDir) == 0 {
 				return fmt.Errorf("Storage type nfs missing conf host or shared dir")
 			}
+		} else if args.StorageType == "cifs" {
+			if len(args.CifsHost) == 0 || len(args.CifsSharedDir) == 0 {
+				return fmt.Errorf("Storage type cifs missing conf host or shared dir")
+			}
+		} else if args.StorageType == "lvm" {
+			if len(args.LvmVgName) == 0 {
+				return fmt.Errorf("Storage type lvm missing conf vg name")
+			}
+		} else if args.StorageType == "iscsi_lvm" {
+			if len(args.IscsiPortal) == 0 || len(args.IscsiTarget) == 0 || len(args.LvmVgName) == 0 {
+				return fmt.Errorf("Storage type iscsi_lvm missing conf portal, target or vg name")
+			}
+		} else if args.StorageType == "plugin" {
+			if len(args.PluginDriver) == 0 {
+				return fmt.Errorf("Storage type plugin missing conf driver")
+			}
+			params.Remove("plugin_option")
+			pluginOptions := jsonutils.NewDict()
+			for _, opt := range args.PluginOption {
+				segs := strings.SplitN(opt, "=", 2)
+				if len(segs) != 2 {
+					return fmt.Errorf("invalid plugin option %s, should be key=value", opt)
+				}
+				pluginOptions.Set(segs[0], jsonutils.NewString(segs[1]))
+			}
+			params.Set("plugin_options", pluginOptions)
 		}
 		storage, err := modules.Storages.Create(s, params)
 		if err != nil {
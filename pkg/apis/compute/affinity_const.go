@@ -0,0 +1,48 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+const (
+	AFFINITY_TYPE_AFFINITY      = "affinity"
+	AFFINITY_TYPE_ANTI_AFFINITY = "anti-affinity"
+
+	// hard rules are enforced as scheduler filters
+	AFFINITY_MODE_HARD = "hard"
+	// soft rules only change candidate scores
+	AFFINITY_MODE_SOFT = "soft"
+
+	AFFINITY_TOPOLOGY_HOST    = "host"
+	AFFINITY_TOPOLOGY_RACK    = "rack"
+	AFFINITY_TOPOLOGY_ZONE    = "zone"
+	AFFINITY_TOPOLOGY_STORAGE = "storage"
+	// schedtag:<prefix> treats every host schedtag whose name starts with prefix as a domain
+	AFFINITY_TOPOLOGY_SCHEDTAG_PREFIX = "schedtag:"
+
+	AFFINITY_DEFAULT_WEIGHT = 10
+
+	// guest metadata key holding the affinity rules of a guest
+	VM_METADATA_AFFINITY_RULES = "affinity_rules"
+)
+
+var (
+	AFFINITY_TYPES         = []string{AFFINITY_TYPE_AFFINITY, AFFINITY_TYPE_ANTI_AFFINITY}
+	AFFINITY_MODES         = []string{AFFINITY_MODE_HARD, AFFINITY_MODE_SOFT}
+	AFFINITY_TOPOLOGY_KEYS = []string{
+		AFFINITY_TOPOLOGY_HOST,
+		AFFINITY_TOPOLOGY_RACK,
+		AFFINITY_TOPOLOGY_ZONE,
+		AFFINITY_TOPOLOGY_STORAGE,
+	}
+)
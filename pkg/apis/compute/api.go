@@ -29,6 +29,28 @@ type SchedtagConfig struct {
 	ResourceType string `json:"resource_type"`
 }
 
+type SchedAffinityRule struct {
+	// 规则类型
+	// enum: affinity, anti-affinity
+	Type string `json:"type"`
+	// 规则模式, hard为强制过滤, soft只影响调度评分
+	// enum: hard, soft
+	// default: hard
+	Mode string `json:"mode"`
+	// 拓扑域
+	// enum: host, rack, zone, storage, schedtag:<prefix>
+	// default: host
+	TopologyKey string `json:"topology_key"`
+
+	// 匹配属于该主机组的云主机
+	GroupId string `json:"group_id"`
+	// 匹配带有这些标签的云主机
+	Labels map[string]string `json:"labels"`
+
+	// soft模式下的权重
+	Weight int `json:"weight"`
+}
+
 type NetworkConfig struct {
 	apis.Meta
 
@@ -324,6 +346,9 @@ type ServerConfigs struct {
 	// 主机组列表, 参数可以是主机组名称或ID,建议使用ID
 	InstanceGroupIds []string `json:"groups"`
 
+	// 亲和性及反亲和性规则
+	AffinityRules []*SchedAffinityRule `json:"affinity_rules"`
+
 	// DEPRECATE
 	Suggestion bool `json:"suggestion"`
 }
@@ -396,6 +421,14 @@ type ServerCreateInput struct {
 	// emulate: BIOS, UEFI
 	Bios string `json:"bios"`
 
+	// 启用vTPM(TPM 2.0)设备, 仅KVM平台支持
+	// default: false
+	Vtpm bool `json:"vtpm"`
+
+	// 启用UEFI安全启动, 仅KVM平台支持, 会自动设置BIOS类型为UEFI
+	// default: false
+	SecureBoot bool `json:"secure_boot"`
+
 	// 启动顺序
 	// c: cdrome
 	// d: disk
@@ -572,6 +605,11 @@ type GuestBatchMigrateParams struct {
 	LiveMigrate bool
 	RescueMode  bool
 	OldStatus   string
+
+	// restart on host down, failed restart is retried until MaxAttempts
+	HaRestart   bool
+	Attempts    int
+	MaxAttempts int
 }
 
 type HostLoginInfo struct {
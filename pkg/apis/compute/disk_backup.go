@@ -0,0 +1,89 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import "yunion.io/x/onecloud/pkg/apis"
+
+const (
+	DISK_BACKUP_STATUS_CREATING      = "creating"
+	DISK_BACKUP_STATUS_CREATE_FAILED = "create_failed"
+	DISK_BACKUP_STATUS_READY         = "ready"
+	DISK_BACKUP_STATUS_DELETING      = "deleting"
+	DISK_BACKUP_STATUS_DELETE_FAILED = "delete_failed"
+	DISK_BACKUP_STATUS_RECOVERING    = "recovering"
+
+	DISK_BACKUP_MODE_FULL        = "full"
+	DISK_BACKUP_MODE_INCREMENTAL = "incremental"
+	DISK_BACKUP_MODE_AUTO        = "auto"
+
+	// dirty bitmap name prefix used by incremental backups
+	DISK_BACKUP_BITMAP_PREFIX = "backup-"
+)
+
+type DiskBackupCreateInput struct {
+	apis.VirtualResourceCreateInput
+
+	// 磁盘名称或Id
+	// required: true
+	Disk string `json:"disk"`
+	// swagger:ignore
+	DiskId string `json:"disk_id"`
+
+	// 备份模式
+	// full: 全量备份
+	// incremental: 基于上一个备份的增量备份
+	// auto: 自动选择, 增量链过长或不可用时做全量备份
+	// default: auto
+	BackupMode string `json:"backup_mode"`
+
+	// swagger:ignore
+	StorageId string `json:"storage_id"`
+	// swagger:ignore
+	ParentId string `json:"parent_id"`
+	// swagger:ignore
+	BitmapName string `json:"bitmap_name"`
+	// swagger:ignore
+	DiskSizeMb int `json:"disk_size_mb"`
+	// swagger:ignore
+	DiskType string `json:"disk_type"`
+}
+
+type DiskBackupListInput struct {
+	apis.VirtualResourceListInput
+
+	DiskFilterListInputBase
+
+	// filter by backup mode
+	BackupMode []string `json:"backup_mode"`
+	// filter by parent backup
+	ParentId string `json:"parent_id"`
+}
+
+type DiskBackupDetails struct {
+	apis.VirtualResourceDetails
+	DiskResourceInfo
+
+	SDiskBackup
+
+	// 增量备份链长度, 全量备份为0
+	ChainLength int `json:"chain_length"`
+}
+
+type DiskBackupRecoveryInput struct {
+	// 新磁盘名称
+	Name string `json:"name"`
+	// 新磁盘所在存储, 默认为原磁盘所在存储
+	Storage string `json:"storage"`
+}
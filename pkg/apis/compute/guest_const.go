@@ -87,6 +87,8 @@ const (
 	VM_DISK_RESET        = "disk_reset"
 	VM_DISK_RESET_FAIL   = "disk_reset_failed"
 
+	VM_DISK_CHANGE_STORAGE = "disk_change_storage"
+
 	VM_START_INSTANCE_SNAPSHOT   = "start_instance_snapshot"
 	VM_INSTANCE_SNAPSHOT_FAILED  = "instance_snapshot_failed"
 	VM_START_SNAPSHOT_RESET      = "start_snapshot_reset"
@@ -179,7 +181,7 @@ const (
 	OS_ARCH_AARCH64 = "aarch64"
 )
 
-var VM_RUNNING_STATUS = []string{VM_START_START, VM_STARTING, VM_RUNNING, VM_BLOCK_STREAM, VM_BLOCK_STREAM_FAIL}
+var VM_RUNNING_STATUS = []string{VM_START_START, VM_STARTING, VM_RUNNING, VM_BLOCK_STREAM, VM_BLOCK_STREAM_FAIL, VM_DISK_CHANGE_STORAGE}
 var VM_CREATING_STATUS = []string{VM_CREATE_NETWORK, VM_CREATE_DISK, VM_START_DEPLOY, VM_DEPLOYING}
 
 var HYPERVISORS = []string{
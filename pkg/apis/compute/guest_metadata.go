@@ -7,3 +7,13 @@ const (
 )
 
 const BASE_INSTANCE_SNAPSHOT_ID = "__base_instance_snapshot_id"
+
+const (
+	// HA policy of restarting guest on other host when its host down
+	HA_PRIORITY     = "__ha_priority"
+	HA_DISABLED     = "__ha_disabled"
+	HA_MAX_RESTARTS = "__ha_max_restarts"
+)
+
+// policy of the guest when its host entering maintenance
+const HOST_DRAIN_POLICY = "__host_drain_policy"
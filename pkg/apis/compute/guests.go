@@ -378,6 +378,79 @@ type GuestLiveMigrateInput struct {
 	SkipCpuCheck *bool `json:"skip_cpu_check"`
 }
 
+type ServerSetHostDrainPolicyInput struct {
+	// 宿主机进入维护模式时的排空策略, 为空时由宿主机维护请求决定
+	// enum: live-migrate, cold-migrate, stop, skip
+	Policy string `json:"policy"`
+}
+
+type ServerSetHaPolicyInput struct {
+	// 宿主机宕机后的重启顺序, 数值大的先重启
+	Priority *int `json:"priority"`
+	// 宿主机宕机后不自动重启
+	Disabled *bool `json:"disabled"`
+	// 重启失败后的最大尝试次数
+	MaxRestarts *int `json:"max_restarts"`
+}
+
+type ServerChangeDiskStorageInput struct {
+	// 要迁移的磁盘名称或Id, 磁盘必须挂载在该实例上
+	// required: true
+	Disk string `json:"disk"`
+	// 目标存储名称或Id, 必须挂载在实例所在的宿主机上
+	// required: true
+	TargetStorage string `json:"target_storage"`
+}
+
+type ServerAttachVirtiofsInput struct {
+	// 挂载标签, 虚拟机内通过 mount -t virtiofs <tag> <dir> 挂载, 同一实例内唯一
+	// required: true
+	Tag string `json:"tag"`
+	// 共享存储名称或Id, 仅支持nfs,gpfs,cifs类型的共享存储, 且必须挂载在实例所在的宿主机上
+	// 为空时source_path为宿主机上的目录
+	Storage string `json:"storage"`
+	// 共享目录路径, 指定storage时为相对于存储挂载点的路径, 否则为宿主机上的绝对路径
+	// required: true
+	SourcePath string `json:"source_path"`
+	// 是否只读
+	// default: false
+	Readonly bool `json:"readonly"`
+}
+
+type ServerDetachVirtiofsInput struct {
+	// 挂载标签
+	// required: true
+	Tag string `json:"tag"`
+}
+
+type ServerQgaSetPasswordInput struct {
+	// 虚拟机内的用户名, 默认为实例的登录用户
+	Username string `json:"username"`
+	// 新密码
+	// required: true
+	Password string `json:"password"`
+}
+
+type ServerQgaFileReadInput struct {
+	// 虚拟机内的文件路径
+	// required: true
+	Path string `json:"path"`
+	// 最多读取的字节数
+	// default: 65536
+	MaxSize int `json:"max_size"`
+}
+
+type ServerQgaFileWriteInput struct {
+	// 虚拟机内的文件路径
+	// required: true
+	Path string `json:"path"`
+	// 写入的内容
+	Content string `json:"content"`
+	// 是否追加写入, 默认覆盖原文件
+	// default: false
+	Append bool `json:"append"`
+}
+
 type GuestSetSecgroupInput struct {
 	// 安全组Id列表
 	// 实例必须处于运行,休眠或者关机状态
@@ -15,6 +15,8 @@
 package compute
 
 import (
+	"time"
+
 	"yunion.io/x/jsonutils"
 
 	"yunion.io/x/onecloud/pkg/apis"
@@ -186,6 +188,8 @@ type HostDetails struct {
 	// 允许开启宿主机健康检查
 	AllowHealthCheck      bool `json:"allow_health_check"`
 	AutoMigrateOnHostDown bool `json:"auto_migrate_on_host_down"`
+	// 进入维护模式时虚拟机的排空进度
+	DrainProgress *HostDrainProgress `json:"drain_progress"`
 
 	// reserved resource for isolated device
 	ReservedResourceForGpu IsolatedDeviceReservedResourceInput `json:"reserved_resource_for_gpu"`
@@ -379,6 +383,9 @@ type HostCreateInput struct {
 	// OVN软件版本
 	OvnVersion string `json:"ovn_version"`
 
+	// NUMA拓扑信息
+	NumaInfo jsonutils.JSONObject `json:"numa_info"`
+
 	// 是否为导入的宿主机
 	IsImport *bool `json:"is_import"`
 
@@ -416,6 +423,8 @@ type HostUpdateInput struct {
 	Version string `json:"version"`
 	// OVN软件版本
 	OvnVersion string `json:"ovn_version"`
+	// NUMA拓扑信息
+	NumaInfo jsonutils.JSONObject `json:"numa_info"`
 	// 是否为裸金属
 	IsBaremetal *bool `json:"is_baremetal"`
 
@@ -428,3 +437,144 @@ type HostUpdateInput struct {
 	// 主机启动模式, 可能值位PXE和ISO
 	BootMode string `json:"boot_mode"`
 }
+
+type HostNumaNode struct {
+	// NUMA节点Id
+	NodeId int `json:"node_id"`
+	// 节点上的CPU
+	Cpus []int `json:"cpus"`
+	// 节点内存大小, 单位MB
+	MemSizeMb int `json:"mem_size_mb"`
+	// 节点空闲内存, 单位MB
+	FreeMemMb int `json:"free_mem_mb"`
+	// 大页大小, 单位KB
+	HugepageSizeKb int `json:"hugepage_size_kb"`
+	// 大页总数
+	HugepageTotal int `json:"hugepage_total"`
+	// 空闲大页数
+	HugepageFree int `json:"hugepage_free"`
+	// 已绑定到节点的虚拟机CPU数
+	BoundCpuCount int `json:"bound_cpu_count"`
+}
+
+// GuestFreeMemMb returns memory could be allocated to guests on the node,
+// guests are backed by hugepages once hugepages reserved
+func (node HostNumaNode) GuestFreeMemMb() int {
+	if node.HugepageTotal > 0 {
+		return node.HugepageFree * node.HugepageSizeKb / 1024
+	}
+	return node.FreeMemMb
+}
+
+type HostNumaInfo struct {
+	Nodes []HostNumaNode `json:"nodes"`
+}
+
+// GuestFreeCpuCount returns cpus not pinned by guests bound to the node
+func (node HostNumaNode) GuestFreeCpuCount() int {
+	return len(node.Cpus) - node.BoundCpuCount
+}
+
+// Fits checks whether the whole guest could be held by the node
+func (node HostNumaNode) Fits(cpu int, memMb int) bool {
+	return node.GuestFreeCpuCount() >= cpu && node.GuestFreeMemMb() >= memMb
+}
+
+// GetNode returns the node of nodeId, nil if not exists
+func (info HostNumaInfo) GetNode(nodeId int) *HostNumaNode {
+	for i := range info.Nodes {
+		if info.Nodes[i].NodeId == nodeId {
+			return &info.Nodes[i]
+		}
+	}
+	return nil
+}
+
+// FitNode returns the node which has the most free memory among the nodes
+// that could hold the whole guest, nil if guest must spread across nodes
+func (info HostNumaInfo) FitNode(cpu int, memMb int) *HostNumaNode {
+	var fit *HostNumaNode
+	for i := range info.Nodes {
+		node := &info.Nodes[i]
+		if !node.Fits(cpu, memMb) {
+			continue
+		}
+		if fit == nil || fit.GuestFreeMemMb() < node.GuestFreeMemMb() {
+			fit = node
+		}
+	}
+	return fit
+}
+
+type HostRebalanceInput struct {
+	// 宿主机分组方式, 同组宿主机之间迁移
+	// enum: zone, schedtag
+	// default: zone
+	GroupBy string `json:"group_by"`
+
+	// 最多迁移的虚拟机数量
+	// default: 3
+	MaxMoves int `json:"max_moves"`
+
+	// 最忙和最闲宿主机负载差超过此值时才迁移, 范围0-1
+	// default: 0.2
+	Threshold float32 `json:"threshold"`
+}
+
+type HostRebalanceMove struct {
+	GuestId      string `json:"guest_id"`
+	Guest        string `json:"guest"`
+	SourceHostId string `json:"source_host_id"`
+	SourceHost   string `json:"source_host"`
+	TargetHostId string `json:"target_host_id"`
+	TargetHost   string `json:"target_host"`
+	// 迁移前源宿主机负载
+	SourceLoad float64 `json:"source_load"`
+	// 迁移前目标宿主机负载
+	TargetLoad float64 `json:"target_load"`
+}
+
+type HostRebalancePlan struct {
+	// 可用区或调度标签名称
+	Group string              `json:"group"`
+	Moves []HostRebalanceMove `json:"moves"`
+}
+
+type HostMaintenanceInput struct {
+	// 迁移的目标宿主机
+	PreferHost string `json:"prefer_host"`
+
+	// 虚拟机默认的排空策略, 未指定时运行中的虚拟机热迁移, 其余冷迁移
+	// enum: live-migrate, cold-migrate, stop, skip
+	DrainPolicy string `json:"drain_policy"`
+
+	// 指定虚拟机的排空策略, key为虚拟机Id或名称, 优先于虚拟机自身设置的策略
+	GuestPolicies map[string]string `json:"guest_policies"`
+
+	// 每批同时排空的虚拟机数量, 当前批次的虚拟机全部完成后才开始下一批
+	// default: 1
+	Concurrency int `json:"concurrency"`
+}
+
+type HostDrainGuest struct {
+	Id        string `json:"id"`
+	Name      string `json:"name"`
+	Policy    string `json:"policy"`
+	OldStatus string `json:"old_status"`
+	// enum: pending, draining, done, failed, skipped
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+	// 等待块复制任务完成的开始时间
+	WaitingSince time.Time `json:"waiting_since,omitempty"`
+}
+
+type HostDrainProgress struct {
+	Total    int `json:"total"`
+	Pending  int `json:"pending"`
+	Draining int `json:"draining"`
+	Done     int `json:"done"`
+	Failed   int `json:"failed"`
+	Skipped  int `json:"skipped"`
+
+	Guests []HostDrainGuest `json:"guests"`
+}
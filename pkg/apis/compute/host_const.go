@@ -135,3 +135,36 @@ const (
 const (
 	CPU_ARCH_AARCH64 = "aarch64"
 )
+
+const (
+	HOST_REBALANCE_MODE_RECOMMEND = "recommend"
+	HOST_REBALANCE_MODE_AUTO      = "auto"
+
+	HOST_REBALANCE_GROUP_BY_ZONE     = "zone"
+	HOST_REBALANCE_GROUP_BY_SCHEDTAG = "schedtag"
+)
+
+const (
+	HOST_DRAIN_POLICY_LIVE_MIGRATE = "live-migrate"
+	HOST_DRAIN_POLICY_COLD_MIGRATE = "cold-migrate"
+	HOST_DRAIN_POLICY_STOP         = "stop"
+	HOST_DRAIN_POLICY_SKIP         = "skip"
+
+	HOST_DRAIN_GUEST_PENDING  = "pending"
+	HOST_DRAIN_GUEST_DRAINING = "draining"
+	HOST_DRAIN_GUEST_DONE     = "done"
+	HOST_DRAIN_GUEST_FAILED   = "failed"
+	HOST_DRAIN_GUEST_SKIPPED  = "skipped"
+
+	// progress of draining guests while entering maintenance
+	HOST_METADATA_DRAIN_PROGRESS = "__drain_progress"
+	// host is disabled by maintenance, enable it when exiting maintenance
+	HOST_METADATA_MAINTENANCE_DISABLED = "__maintenance_disabled"
+)
+
+var HOST_DRAIN_POLICIES = []string{
+	HOST_DRAIN_POLICY_LIVE_MIGRATE,
+	HOST_DRAIN_POLICY_COLD_MIGRATE,
+	HOST_DRAIN_POLICY_STOP,
+	HOST_DRAIN_POLICY_SKIP,
+}
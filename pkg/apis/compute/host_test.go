@@ -0,0 +1,76 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import "testing"
+
+func TestHostNumaInfoFitNode(t *testing.T) {
+	info := HostNumaInfo{
+		Nodes: []HostNumaNode{
+			{NodeId: 0, Cpus: []int{0, 1, 2, 3}, FreeMemMb: 8192},
+			{NodeId: 1, Cpus: []int{4, 5, 6, 7}, FreeMemMb: 4096},
+		},
+	}
+	cases := []struct {
+		name      string
+		boundCpus []int
+		cpu       int
+		memMb     int
+		want      int
+	}{
+		{"most free memory", []int{0, 0}, 2, 2048, 0},
+		{"memory only fits node0", []int{0, 0}, 2, 6144, 0},
+		{"cpus of node0 pinned", []int{3, 0}, 2, 2048, 1},
+		{"cpus of all nodes pinned", []int{3, 3}, 2, 2048, -1},
+		{"too many cpus", []int{0, 0}, 8, 1024, -1},
+		{"too much memory", []int{0, 0}, 1, 10240, -1},
+	}
+	for _, c := range cases {
+		for i := range info.Nodes {
+			info.Nodes[i].BoundCpuCount = c.boundCpus[i]
+		}
+		node := info.FitNode(c.cpu, c.memMb)
+		got := -1
+		if node != nil {
+			got = node.NodeId
+		}
+		if got != c.want {
+			t.Errorf("%s: want node %d, got %d", c.name, c.want, got)
+		}
+	}
+}
+
+func TestHostNumaNodeGuestFreeMemMb(t *testing.T) {
+	node := HostNumaNode{FreeMemMb: 8192}
+	if node.GuestFreeMemMb() != 8192 {
+		t.Errorf("want free memory 8192, got %d", node.GuestFreeMemMb())
+	}
+	node.HugepageTotal = 1024
+	node.HugepageFree = 512
+	node.HugepageSizeKb = 2048
+	if node.GuestFreeMemMb() != 1024 {
+		t.Errorf("want hugepage free memory 1024, got %d", node.GuestFreeMemMb())
+	}
+}
+
+func TestHostNumaInfoGetNode(t *testing.T) {
+	info := HostNumaInfo{Nodes: []HostNumaNode{{NodeId: 0}, {NodeId: 2}}}
+	if node := info.GetNode(2); node == nil || node.NodeId != 2 {
+		t.Errorf("want node 2, got %v", node)
+	}
+	if node := info.GetNode(1); node != nil {
+		t.Errorf("want nil for missing node, got %v", node)
+	}
+}
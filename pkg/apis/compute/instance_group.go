@@ -32,6 +32,9 @@ type InstanceGroupListInput struct {
 
 	// 调度策略
 	SchedStrategy string `json:"sched_strategy"`
+
+	// 以亲和性类型过滤列表结果
+	AffinityType string `json:"affinity_type"`
 }
 
 type InstanceGroupDetail struct {
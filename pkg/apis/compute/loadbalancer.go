@@ -242,6 +242,11 @@ type LoadbalancerCreateInput struct {
 	// 负载均衡集群Id
 	ClusterId string `json:"cluster_id"`
 
+	// 数据面实现, 仅onecloud负载均衡有效, vpc内的负载均衡需显式指定ovn, 且需开启enable_ovn_loadbalancer
+	// enum: lbagent, ovn
+	// default: lbagent
+	DataplaneType string `json:"dataplane_type"`
+
 	// 计费类型
 	ChargeType string `json:"charge_type"`
 
@@ -141,6 +141,20 @@ var LB_NETWORK_TYPES = choices.NewChoices(
 	LB_NETWORK_TYPE_VPC,
 )
 
+// Load balancer dataplane decides who does the actual forwarding for onecloud
+// load balancers.  lbagent runs haproxy, gobetween and keepalived on agent
+// nodes of the lbcluster.  ovn has vpcagent program Load_Balancer rows into
+// ovn north database of the vpc the load balancer is in
+const (
+	LB_DATAPLANE_TYPE_LBAGENT = "lbagent"
+	LB_DATAPLANE_TYPE_OVN     = "ovn"
+)
+
+var LB_DATAPLANE_TYPES = choices.NewChoices(
+	LB_DATAPLANE_TYPE_LBAGENT,
+	LB_DATAPLANE_TYPE_OVN,
+)
+
 // TODO https_direct sni
 const (
 	LB_LISTENER_TYPE_TCP              = "tcp"
@@ -158,6 +172,12 @@ var LB_LISTENER_TYPES = choices.NewChoices(
 	LB_LISTENER_TYPE_HTTPS,
 )
 
+// ovn load balancers work at L4 only
+var LB_OVN_LISTENER_TYPES = choices.NewChoices(
+	LB_LISTENER_TYPE_TCP,
+	LB_LISTENER_TYPE_UDP,
+)
+
 // aws_network_lb_listener
 var AWS_NETWORK_LB_LISTENER_TYPES = choices.NewChoices(
 	LB_LISTENER_TYPE_TCP,
@@ -342,6 +362,11 @@ var LB_SCHEDULER_TYPES = choices.NewChoices(
 	LB_SCHEDULER_TCH,
 )
 
+// ovn selects backends by hash of the 5-tuple
+var LB_OVN_SCHEDULER_TYPES = choices.NewChoices(
+	LB_SCHEDULER_TCH,
+)
+
 const (
 	LB_SENDPROXY_OFF       = "off"
 	LB_SENDPROXY_V1        = "v1"
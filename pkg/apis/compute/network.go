@@ -200,6 +200,37 @@ type NetworkCreateInput struct {
 
 	// 线路类型
 	BgpType string `json:"bgp_type"`
+
+	// description: ipv6 range of guest, if not set, ipv6 is disabled unless guest_ip6_start, guest_ip6_end and guest_ip6_mask are set
+	// example: fd00:a::/64
+	GuestIp6Prefix string `json:"guest_ip6_prefix"`
+
+	// description: ipv6 range of guest ip start, if set guest_ip6_prefix, this parameter will be useless
+	// example: fd00:a::1
+	GuestIp6Start string `json:"guest_ip6_start"`
+
+	// description: ipv6 range of guest ip end, if set guest_ip6_prefix, this parameter will be useless
+	// example: fd00:a::ffff
+	GuestIp6End string `json:"guest_ip6_end"`
+
+	// description: ipv6 prefix length, if set guest_ip6_prefix, this parameter will be useless
+	// example: 64
+	// maximum: 126
+	// minimum: 48
+	GuestIp6Mask int8 `json:"guest_ip6_mask"`
+
+	// description: guest ipv6 gateway
+	// example: fd00:a::1
+	GuestGateway6 string `json:"guest_gateway6"`
+
+	// description: guest ipv6 dns
+	// example: fd00:a::2
+	GuestDns6 string `json:"guest_dns6"`
+
+	// description: ipv6 address configuration mode of guest
+	// enum: dhcpv6,slaac
+	// default: dhcpv6
+	GuestIp6Mode string `json:"guest_ip6_mode"`
 }
 
 type NetworkDetails struct {
@@ -321,6 +352,19 @@ type NetworkUpdateInput struct {
 
 	// 是否加入自动分配地址池
 	IsAutoAlloc *bool `json:"is_auto_alloc"`
+
+	// IPv6起始地址
+	GuestIp6Start string `json:"guest_ip6_start"`
+	// IPv6结束地址
+	GuestIp6End string `json:"guest_ip6_end"`
+	// IPv6前缀长度
+	GuestIp6Mask *int8 `json:"guest_ip6_mask"`
+	// IPv6网关地址
+	GuestGateway6 string `json:"guest_gateway6"`
+	// IPv6 DNS
+	GuestDns6 string `json:"guest_dns6"`
+	// IPv6地址分配方式
+	GuestIp6Mode string `json:"guest_ip6_mode"`
 }
 
 type GetNetworkAddressesInput struct {
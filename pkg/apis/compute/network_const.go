@@ -31,6 +31,9 @@ const (
 
 	STATIC_ALLOC = "static"
 
+	NETWORK_IP6_MODE_DHCPV6 = "dhcpv6"
+	NETWORK_IP6_MODE_SLAAC  = "slaac"
+
 	MAX_NETWORK_NAME_LEN = 11
 
 	EXTRA_DNS_UPDATE_TARGETS = "__extra_dns_update_targets"
@@ -57,6 +60,11 @@ var (
 		NETWORK_TYPE_EIP,
 	}
 
+	NETWORK_IP6_MODES = []string{
+		NETWORK_IP6_MODE_DHCPV6,
+		NETWORK_IP6_MODE_SLAAC,
+	}
+
 	REGIONAL_NETWORK_PROVIDERS = []string{
 		CLOUD_PROVIDER_HUAWEI,
 		CLOUD_PROVIDER_CTYUN,
@@ -21,3 +21,12 @@ type RouteTableAssociationListInput struct {
 	apis.ExternalizedResourceBaseListInput
 	RouteTableFilterList
 }
+
+type RouteTableAssociationCreateInput struct {
+	apis.StatusStandaloneResourceCreateInput
+
+	RouteTableId            string `json:"route_table_id"`
+	AssociationType         string `json:"association_type"`
+	AssociatedResourceId    string `json:"associated_resource_id"`
+	ExtAssociatedResourceId string `json:"ext_associated_resource_id"`
+}
@@ -25,9 +25,23 @@ const (
 	HEALTH_CHECK_MODE_NORMAL       = "normal"
 	HEALTH_CHECK_MODE_LOADBALANCER = "loadbalancer"
 
+	HEALTH_CHECK_PROBE_TCP  = "tcp"
+	HEALTH_CHECK_PROBE_HTTP = "http"
+
+	LIFECYCLE_TRANSITION_LAUNCHING   = "launching"   // 实例加入伸缩组
+	LIFECYCLE_TRANSITION_TERMINATING = "terminating" // 实例移出伸缩组
+
+	LIFECYCLE_NOTIFY_WEBHOOK = "webhook"
+	LIFECYCLE_NOTIFY_ANSIBLE = "ansible"
+
+	LIFECYCLE_RESULT_CONTINUE = "continue" // 继续
+	LIFECYCLE_RESULT_ABANDON  = "abandon"  // 放弃
+
 	TRIGGER_ALARM  = "alarm"  // 告警
 	TRIGGER_TIMING = "timing" // 定时
 	TRIGGER_CYCLE  = "cycle"  // 周期定时
+	TRIGGER_TARGET = "target" // 目标追踪
+	TRIGGER_STEP   = "step"   // 步进伸缩
 
 	ACTION_ADD    = "add"    // 增加
 	ACTION_REMOVE = "remove" // 减少
@@ -62,6 +76,9 @@ const (
 	SG_GUEST_STATUS_REMOVE_FAILED  = "remove_failed"  // 移除失败
 	SG_GUEST_STATUS_PENDING_REMOVE = "pending_remove" // 机器进入回收站
 
+	SG_GUEST_STATUS_PENDING_LAUNCH    = "pending_launch"    // 等待生命周期挂钩完成后加入
+	SG_GUEST_STATUS_PENDING_TERMINATE = "pending_terminate" // 等待生命周期挂钩完成后移除
+
 	// 只有ready状态是正常的
 	SG_STATUS_READY              = "ready"              // 正常
 	SG_STATUS_DELETING           = "deleting"           // 删除中
@@ -71,12 +88,22 @@ const (
 	SG_STATUS_CREATE_FAILED      = "create_failed"
 	SG_STATUS_DELETED            = "deleted" // 删除
 
+	SG_STATUS_INSTANCE_REFRESH        = "instance_refresh"        // 实例刷新中
+	SG_STATUS_INSTANCE_REFRESH_FAILED = "instance_refresh_failed" // 实例刷新失败
+
+	SG_METADATA_INSTANCE_REFRESH = "__instance_refresh"
+
 	SP_STATUS_READY         = "ready" // 正常
 	SP_STATUS_CREATING      = "creating"
 	SP_STATUS_CREATE_FAILED = "create_failed" // 创建失败
 	SP_STATUS_DELETING      = "deleting"      // 删除中
 	SP_STATUS_DELETE_FAILED = "delete_failed" // 删除失败
 
+	SG_INSTANCE_REFRESH_REFRESHING   = "refreshing"
+	SG_INSTANCE_REFRESH_ROLLING_BACK = "rolling_back"
+	SG_INSTANCE_REFRESH_SUCCEED      = "succeed"
+	SG_INSTANCE_REFRESH_FAILED       = "failed"
+
 	SA_STATUS_WAIT         = "wait"         // 等待中
 	SA_STATUS_EXEC         = "execution"    // 执行中
 	SA_STATUS_SUCCEED      = "succeed"      // 成功
@@ -14,7 +14,11 @@
 
 package compute
 
-import "yunion.io/x/onecloud/pkg/apis"
+import (
+	"time"
+
+	"yunion.io/x/onecloud/pkg/apis"
+)
 
 type ScalingGroupCreateInput struct {
 	apis.VirtualResourceCreateInput
@@ -80,6 +84,19 @@ type ScalingGroupCreateInput struct {
 	// example: 180
 	HealthCheckGov int `json:"health_check_gov"`
 
+	// description: 健康检查探测方式, 为空表示不探测
+	// enum: tcp,http
+	// example: http
+	HealthCheckProbe string `json:"health_check_probe"`
+
+	// description: 健康检查探测端口
+	// example: 80
+	HealthCheckPort int `json:"health_check_port"`
+
+	// description: http探测的路径
+	// example: /healthz
+	HealthCheckPath string `json:"health_check_path"`
+
 	// description: 负载均衡后端服务器组
 	// example: lbg-nihao
 	LbBackendGroup string `json:"lb_backend_group"`
@@ -136,6 +153,9 @@ type ScalingGroupDetails struct {
 
 	// description: 网络信息
 	Networks []ScalingGroupNetwork `json:"networks"`
+
+	// description: 实例刷新进度
+	InstanceRefresh *ScalingGroupInstanceRefreshProgress `json:"instance_refresh"`
 }
 
 type ScalingGroupNetwork struct {
@@ -174,3 +194,62 @@ type SGPerformDetachScalingGroupInput struct {
 	// example: true
 	Auto bool `json:"auto"`
 }
+
+type ScalingGroupInstanceRefreshInput struct {
+	// description: 新的主机模板 id or name, 为空表示使用当前主机模板(主机模板内容已修改)
+	// example: gt-test-two
+	GuestTemplate string `json:"guest_template"`
+
+	// swagger: ignore
+	GuestTemplateId string `json:"guest_template_id"`
+
+	// description: 刷新过程中健康实例占期望实例数的最小百分比, 决定每批替换的实例数
+	// example: 90
+	MinHealthyPercentage int `json:"min_healthy_percentage"`
+
+	// description: 删除实例前从负载均衡后端服务器组中摘除并等待的时间，单位s，0表示不等待
+	// example: 60
+	DrainTime int `json:"drain_time"`
+
+	// description: 每批新实例变为健康状态的超时时间，单位s
+	// example: 600
+	HealthCheckTimeout int `json:"health_check_timeout"`
+
+	// description: 健康检查失败时不回滚, 仅暂停刷新
+	// example: false
+	DisableRollback bool `json:"disable_rollback"`
+}
+
+type ScalingGroupInstanceRefreshProgress struct {
+	// description: 刷新状态
+	// enum: refreshing,rolling_back,succeed,failed
+	Status string `json:"status"`
+	// description: 刷新前的主机模板
+	OldGuestTemplateId string `json:"old_guest_template_id"`
+	// description: 刷新使用的主机模板
+	GuestTemplateId string `json:"guest_template_id"`
+	// description: 待替换的实例
+	Pending []string `json:"pending"`
+	// description: 正在替换的实例
+	Batch []string `json:"batch"`
+	// description: 已替换的实例数
+	Replaced int `json:"replaced"`
+	// description: 失败原因
+	Reason string `json:"reason"`
+
+	StartAt  time.Time `json:"start_at"`
+	UpdateAt time.Time `json:"update_at"`
+}
+
+type ScalingGroupCompleteLifecycleActionInput struct {
+	// description: 实例 Id
+	// example: 0cfa1cd0-6f6d-4f0e-8d86-d1b7aa8e6ed7
+	Guest string `json:"guest"`
+
+	// description: 生命周期挂钩通知中携带的token
+	Token string `json:"token"`
+
+	// description: 生命周期动作的结果
+	// enum: continue,abandon
+	Result string `json:"result"`
+}
@@ -0,0 +1,92 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import "yunion.io/x/onecloud/pkg/apis"
+
+type ScalingLifecycleHookDetails struct {
+	apis.VirtualResourceDetails
+	ScalingGroupResourceInfo
+	SScalingLifecycleHook
+}
+
+type ScalingLifecycleHookCreateInput struct {
+	apis.VirtualResourceCreateInput
+
+	// description: scaling_group ID or Name
+	// example: sg-test-one
+	ScalingGroup string `json:"scaling_group"`
+
+	// swagger: ignore
+	ScalingGroupId string `json:"scaling_group_id"`
+
+	// description: 挂钩的实例生命周期阶段
+	// enum: launching,terminating
+	// example: launching
+	Transition string `json:"transition"`
+
+	// description: 通知方式
+	// enum: webhook,ansible
+	// example: webhook
+	NotifyType string `json:"notify_type"`
+
+	// description: webhook地址, 实例进入挂起状态时POST通知此地址
+	// example: http://discovery.example.com/hooks
+	WebhookUrl string `json:"webhook_url"`
+
+	// description: 不校验https webhook的证书, 用于自签名证书的webhook
+	// default: false
+	WebhookSkipTlsVerify bool `json:"webhook_skip_tls_verify"`
+
+	// description: ansible playbook ID or Name, 以实例为inventory执行此playbook
+	// example: register-node
+	AnsiblePlaybook string `json:"ansible_playbook"`
+
+	// swagger: ignore
+	AnsiblePlaybookId string `json:"ansible_playbook_id"`
+
+	// description: 等待回调的超时时间，单位s
+	// example: 300
+	Timeout int `json:"timeout"`
+
+	// description: 超时或通知失败时的默认结果
+	// enum: continue,abandon
+	// example: continue
+	DefaultResult string `json:"default_result"`
+}
+
+type ScalingLifecycleHookListInput struct {
+	apis.VirtualResourceListInput
+
+	ScalingGroupFilterListInput
+
+	// description: 实例生命周期阶段
+	// enum: launching,terminating
+	Transition string `json:"transition"`
+}
+
+// ScalingLifecycleHookNotification is posted to the webhook of lifecycle hook, the receiver should
+// call the complete-lifecycle-action of scaling group with the token before timeout.
+type ScalingLifecycleHookNotification struct {
+	HookId         string   `json:"hook_id"`
+	HookName       string   `json:"hook_name"`
+	ScalingGroupId string   `json:"scaling_group_id"`
+	Transition     string   `json:"transition"`
+	GuestId        string   `json:"guest_id"`
+	GuestName      string   `json:"guest_name"`
+	Ips            []string `json:"ips"`
+	Token          string   `json:"token"`
+	Timeout        int      `json:"timeout"`
+}
@@ -26,6 +26,10 @@ type ScalingPolicyDetails struct {
 	CycleTimer CycleTimerDetails `json:"cycle_timer"`
 	//  告警方式触发
 	Alarm ScalingAlarmDetails `json:"alarm"`
+	// 目标追踪方式触发
+	Target ScalingTargetDetails `json:"target"`
+	// 步进方式触发
+	Step ScalingStepDetails `json:"step"`
 }
 
 type ScalingPolicyCreateInput struct {
@@ -40,12 +44,14 @@ type ScalingPolicyCreateInput struct {
 	ScalingGroupId string `json:"scaling_group_id"`
 
 	// description: trigger type
-	// enum: timing,cycle,alarm
+	// enum: timing,cycle,alarm,target,step
 	TriggerType string `json:"trigger_type"`
 
-	Timer      TimerCreateInput        `json:"timer"`
-	CycleTimer CycleTimerCreateInput   `json:"cycle_timer"`
-	Alarm      ScalingAlarmCreateInput `json:"alarm"`
+	Timer      TimerCreateInput         `json:"timer"`
+	CycleTimer CycleTimerCreateInput    `json:"cycle_timer"`
+	Alarm      ScalingAlarmCreateInput  `json:"alarm"`
+	Target     ScalingTargetCreateInput `json:"target"`
+	Step       ScalingStepCreateInput   `json:"step"`
 
 	// desciption: 伸缩策略的行为(增加还是删除或者调整为)
 	// enum: add,remove,set
@@ -75,7 +81,7 @@ type ScalingPolicyListInput struct {
 	ScalingGroupFilterListInput
 
 	// description: trigger type
-	// enum: timing,cycel,alarm
+	// enum: timing,cycel,alarm,target,step
 	// example: alarm
 	TriggerType string `json:"trigger_type"`
 }
@@ -81,6 +81,87 @@ type ScalingAlarmCreateInput struct {
 	Value float64 `json:"value"`
 }
 
+type ScalingTargetCreateInput struct {
+
+	// description: 监控指标
+	// example: cpu
+	// enum: cpu,disk_read,disk_write,flow_into,flow_out
+	Indicator string `json:"indicator"`
+
+	// description: 监控指标的目标值, 伸缩组会调整实例数量使所有实例的指标平均值保持在此值附近
+	// example: 60
+	TargetValue float64 `json:"target_value"`
+
+	// description: 计算指标平均值的时间窗口，单位s
+	// example: 300
+	Cycle int `json:"cycle"`
+
+	// description: 新加入实例的预热时间，单位s，预热中的实例不参与指标平均值的计算
+	// example: 300
+	WarmUp int `json:"warm_up"`
+
+	// description: 扩容后的冷却时间，单位s
+	// example: 300
+	ScaleOutCoolingTime int `json:"scale_out_cooling_time"`
+
+	// description: 缩容后的冷却时间，单位s
+	// example: 600
+	ScaleInCoolingTime int `json:"scale_in_cooling_time"`
+
+	// description: 只扩容不缩容
+	DisableScaleIn bool `json:"disable_scale_in"`
+}
+
+type ScalingStep struct {
+
+	// description: 指标下界(包含)，为空表示负无穷
+	LowerBound *float64 `json:"lower_bound"`
+
+	// description: 指标上界(不包含)，为空表示正无穷
+	UpperBound *float64 `json:"upper_bound"`
+
+	// description: 伸缩行为
+	// enum: add,remove,set
+	Action string `json:"action"`
+
+	// description: 实例的数量
+	Number int `json:"number"`
+
+	// description: 实例数量的单位
+	// enum: s,%
+	Unit string `json:"unit"`
+
+	// description: 此步执行后的冷却时间，单位s，冷却时间内此步不会再次执行
+	CoolingTime int `json:"cooling_time"`
+
+	// swagger: ignore
+	LastScaleTime time.Time `json:"last_scale_time"`
+}
+
+type ScalingStepCreateInput struct {
+
+	// description: 监控指标
+	// example: cpu
+	// enum: cpu,disk_read,disk_write,flow_into,flow_out
+	Indicator string `json:"indicator"`
+
+	// description: 监控指标的取值方式
+	// example: average
+	// enum: max,min,average
+	Wrapper string `json:"wrapper"`
+
+	// description: 计算指标的时间窗口，单位s
+	// example: 300
+	Cycle int `json:"cycle"`
+
+	// description: 新加入实例的预热时间，单位s，预热中的实例不参与指标的计算
+	// example: 300
+	WarmUp int `json:"warm_up"`
+
+	// description: 步进区间，区间之间不能重叠
+	Steps []ScalingStep `json:"steps"`
+}
+
 type TimerDetails struct {
 	// description: 执行时间
 	ExecTime time.Time `json:"exec_time"`
@@ -117,3 +198,37 @@ type ScalingAlarmDetails struct {
 	// description: 阈值
 	Value float64 `json:"value"`
 }
+
+type ScalingTargetDetails struct {
+	// description: 指标
+	Indicator string `json:"indicator"`
+	// description: 目标值
+	TargetValue float64 `json:"target_value"`
+	// description: 时间窗口
+	Cycle int `json:"cycle"`
+	// description: 预热时间
+	WarmUp int `json:"warm_up"`
+	// description: 扩容冷却时间
+	ScaleOutCoolingTime int `json:"scale_out_cooling_time"`
+	// description: 缩容冷却时间
+	ScaleInCoolingTime int `json:"scale_in_cooling_time"`
+	// description: 只扩容不缩容
+	DisableScaleIn bool `json:"disable_scale_in"`
+	// description: 最近一次计算得到的指标值
+	LastValue float64 `json:"last_value"`
+}
+
+type ScalingStepDetails struct {
+	// description: 指标
+	Indicator string `json:"indicator"`
+	// description: 指标的取值方式，最大值/最小值/平均值
+	Wrapper string `json:"wrapper"`
+	// description: 时间窗口
+	Cycle int `json:"cycle"`
+	// description: 预热时间
+	WarmUp int `json:"warm_up"`
+	// description: 步进区间
+	Steps []ScalingStep `json:"steps"`
+	// description: 最近一次计算得到的指标值
+	LastValue float64 `json:"last_value"`
+}
@@ -16,8 +16,13 @@ package compute
 
 import (
 	"fmt"
+	"net"
+	"reflect"
+	"time"
 
+	"yunion.io/x/jsonutils"
 	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/gotypes"
 	"yunion.io/x/pkg/util/regutils"
 	"yunion.io/x/pkg/util/secrules"
 
@@ -66,7 +71,7 @@ type SSecgroupRuleResource struct {
 	// required: true
 	Direction string `json:"direction"`
 
-	// ip或cidr地址, 若指定peer_secgroup_id此参数不生效
+	// ip或cidr地址, 支持ipv6, 若指定peer_secgroup_id此参数不生效
 	// example: 192.168.222.121
 	CIDR string `json:"cidr"`
 
@@ -85,6 +90,10 @@ type SSecgroupRuleResource struct {
 	// 对端安全组Id, 此参数和cidr参数互斥，并且优先级高于cidr, 同事peer_secgroup_id不能和它所在的安全组ID相同
 	// required: false
 	PeerSecgroupId string `json:"peer_secgroup_id"`
+
+	// 记录命中此规则的流量并统计命中次数, 仅对onecloud vpc内的主机生效, 安全组被经典网络的主机使用时不允许开启
+	// required: false
+	Log *bool `json:"log"`
 }
 
 type SSecgroupRuleCreateInput struct {
@@ -105,6 +114,14 @@ type SSecgroupRuleUpdateInput struct {
 	SSecgroupRuleResource
 }
 
+func isIP6CIDR(cidr string) bool {
+	if regutils.MatchIP6Addr(cidr) {
+		return true
+	}
+	ip, _, err := net.ParseCIDR(cidr)
+	return err == nil && ip.To4() == nil
+}
+
 func (input *SSecgroupRuleResource) Check() error {
 	priority := 1
 	if input.Priority != nil {
@@ -128,7 +145,7 @@ func (input *SSecgroupRuleResource) Check() error {
 	}
 
 	if len(input.CIDR) > 0 {
-		if !regutils.MatchCIDR(input.CIDR) && !regutils.MatchIPAddr(input.CIDR) {
+		if !regutils.MatchCIDR(input.CIDR) && !regutils.MatchIPAddr(input.CIDR) && !isIP6CIDR(input.CIDR) {
 			return fmt.Errorf("invalid ip address: %s", input.CIDR)
 		}
 	} else {
@@ -144,6 +161,18 @@ type SSecgroupCreateInput struct {
 	// 规则列表
 	// required: false
 	Rules []SSecgroupRuleCreateInput `json:"rules"`
+
+	// 记录命中此安全组所有规则的流量, 仅对onecloud vpc内的主机生效
+	// required: false
+	Log *bool `json:"log"`
+}
+
+type SecgroupUpdateInput struct {
+	apis.SharableVirtualResourceBaseUpdateInput
+
+	// 记录命中此安全组所有规则的流量, 安全组被经典网络的主机使用时不允许开启
+	// required: false
+	Log *bool `json:"log"`
 }
 
 type SecgroupListInput struct {
@@ -308,3 +337,50 @@ type SecurityGroupCloneInput struct {
 type SecgroupImportRulesInput struct {
 	Rules []SSecgroupRuleCreateInput `json:"rules"`
 }
+
+// 被拒绝的流量记录
+type SecgroupRuleDeniedFlow struct {
+	// 上报的宿主机ID
+	HostId string `json:"host_id"`
+	// 流量描述, 如 tcp,nw_src=10.0.0.2,nw_dst=10.0.0.3,tp_dst=22
+	Flow string `json:"flow"`
+	// 命中时间
+	At time.Time `json:"at"`
+}
+
+// 最近被拒绝的流量
+type SecgroupRuleDeniedFlows []SecgroupRuleDeniedFlow
+
+func (flows SecgroupRuleDeniedFlows) String() string {
+	return jsonutils.Marshal(flows).String()
+}
+
+func (flows SecgroupRuleDeniedFlows) IsZero() bool {
+	return len(flows) == 0
+}
+
+type SecgroupRuleHits struct {
+	// 安全组规则ID
+	RuleId string `json:"rule_id"`
+	// 上次上报后的新增命中报文数
+	Hits int64 `json:"hits"`
+	// 上次上报后的新增命中字节数
+	Bytes int64 `json:"bytes"`
+	// 最后命中时间
+	LastHitAt time.Time `json:"last_hit_at"`
+	// 新增的被拒绝流量记录
+	DeniedFlows []SecgroupRuleDeniedFlow `json:"denied_flows"`
+}
+
+type SecgroupRuleHitsReportInput struct {
+	// 上报的宿主机ID
+	HostId string `json:"host_id"`
+
+	Rules []SecgroupRuleHits `json:"rules"`
+}
+
+func init() {
+	gotypes.RegisterSerializable(reflect.TypeOf(&SecgroupRuleDeniedFlows{}), func() gotypes.ISerializable {
+		return &SecgroupRuleDeniedFlows{}
+	})
+}
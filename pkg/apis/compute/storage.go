@@ -37,10 +37,30 @@ type StorageCreateInput struct {
 	// | rbd 			| rbd_client_mount_timeout	| 否 		|	120		|单位: 秒	|
 	// | nfs 			| nfs_host					| 是 		|			|网络文件系统主机	|
 	// | nfs 			| nfs_shared_dir			| 是 		|			|网络文件系统共享目录	|
+	// | cifs 			| cifs_host					| 是 		|			|SMB/CIFS文件服务器主机	|
+	// | cifs 			| cifs_shared_dir			| 是 		|			|SMB/CIFS共享名称	|
+	// | cifs 			| cifs_username				| 否 		|			|SMB/CIFS访问用户名	|
+	// | cifs 			| cifs_password				| 否 		|			|SMB/CIFS访问密码	|
+	// | cifs 			| cifs_domain				| 否 		|			|SMB/CIFS用户所属域	|
+	// | cifs 			| cifs_mount_options		| 否 		|			|额外的mount.cifs挂载参数	|
+	// | lvm 			| lvm_vg_name				| 是 		|			|LVM卷组名称	|
+	// | lvm 			| lvm_thin_pool				| 否 		|			|LVM精简池名称, 为空时使用厚置备	|
+	// | iscsi_lvm 		| iscsi_portal				| 是 		|			|iSCSI target地址, 格式为 ip:port	|
+	// | iscsi_lvm 		| iscsi_target				| 是 		|			|iSCSI target IQN	|
+	// | iscsi_lvm 		| iscsi_chap_username		| 否 		|			|CHAP认证用户名	|
+	// | iscsi_lvm 		| iscsi_chap_password		| 否 		|			|CHAP认证密码	|
+	// | iscsi_lvm 		| lvm_vg_name				| 是 		|			|LUN上的共享LVM卷组名称	|
+	// | plugin 		| plugin_driver				| 是 		|			|存储插件名称	|
+	// | plugin 		| plugin_options			| 否 		|			|传递给存储插件的参数	|
 	// local: 本地存储
 	// rbd: ceph块存储, ceph存储创建时仅会检测是否重复创建，不会具体检测认证参数是否合法，只有挂载存储时
 	// 计算节点会验证参数，若挂载失败，宿主机和存储不会关联，可以通过查看存储日志查找挂载失败原因
-	// enum: local, rbd, nfs, gpfs
+	// lvm: 宿主机本地LVM卷组, 由计算节点根据配置自动注册, 每块磁盘对应一个逻辑卷
+	// iscsi_lvm: 多台宿主机通过iSCSI挂载的共享LUN, LUN上需预先创建lvmlockd共享卷组(vgcreate --shared),
+	// 每块磁盘对应一个逻辑卷, 虚拟机启动时以独占锁激活逻辑卷, 保证同一块磁盘不会被两台宿主机同时打开
+	// plugin: 第三方存储插件, 插件以gRPC服务的方式运行在宿主机上, 监听 <plugin_driver>.sock,
+	// 磁盘的创建,挂载,快照及删除均由插件完成
+	// enum: local, rbd, nfs, gpfs, cifs, lvm, iscsi_lvm, plugin
 	// required: true
 	StorageType string `json:"storage_type"`
 
@@ -105,6 +125,56 @@ type StorageCreateInput struct {
 	// 网络文件系统共享目录, storage_type 为 nfs 时, 此参数必传
 	// example: /nfs_root/
 	NfsSharedDir string `json:"nfs_shared_dir"`
+
+	// SMB/CIFS文件服务器主机, storage_type 为 cifs 时,此参数必传
+	// example: 192.168.222.5
+	CifsHost string `json:"cifs_host"`
+
+	// SMB/CIFS共享名称, storage_type 为 cifs 时, 此参数必传
+	// example: /vm_share
+	CifsSharedDir string `json:"cifs_shared_dir"`
+
+	// SMB/CIFS访问用户名, 为空时以guest方式挂载
+	CifsUsername string `json:"cifs_username"`
+
+	// SMB/CIFS访问密码
+	CifsPassword string `json:"cifs_password"`
+
+	// SMB/CIFS用户所属域
+	CifsDomain string `json:"cifs_domain"`
+
+	// 额外的mount.cifs挂载参数, 以逗号分隔
+	// example: vers=3.0,cache=strict
+	CifsMountOptions string `json:"cifs_mount_options"`
+
+	// LVM卷组名称, storage_type 为 lvm 时, 此参数必传
+	// example: vg_data
+	LvmVgName string `json:"lvm_vg_name"`
+
+	// LVM精简池名称, 设置后磁盘以精简卷方式创建并支持快照
+	// example: thinpool
+	LvmThinPool string `json:"lvm_thin_pool"`
+
+	// iSCSI target地址, storage_type 为 iscsi_lvm 时, 此参数必传
+	// example: 192.168.222.10:3260
+	IscsiPortal string `json:"iscsi_portal"`
+
+	// iSCSI target IQN, storage_type 为 iscsi_lvm 时, 此参数必传
+	// example: iqn.2003-01.org.linux-iscsi.san:sn.0123456789
+	IscsiTarget string `json:"iscsi_target"`
+
+	// iSCSI CHAP认证用户名
+	IscsiChapUsername string `json:"iscsi_chap_username"`
+
+	// iSCSI CHAP认证密码
+	IscsiChapPassword string `json:"iscsi_chap_password"`
+
+	// 存储插件名称, storage_type 为 plugin 时, 此参数必传
+	// example: sheepdog
+	PluginDriver string `json:"plugin_driver"`
+
+	// 存储插件参数, 原样传递给存储插件
+	PluginOptions map[string]string `json:"plugin_options"`
 }
 
 type SStorageCapacityInfo struct {
@@ -46,10 +46,19 @@ const (
 	VpcInterExtMac2  = "ee:ee:ee:ee:ee:f1"
 )
 
+const (
+	// link networks between routers of peered vpcs, one /30 for each
+	// vpc peering connection
+	sVpcPeeringCidr    = "100.65.128.0/17"
+	VpcPeeringLinkMask = 30
+)
+
 var (
 	vpcInterCidr   netutils.IPV4Prefix
 	vpcInterExtIP1 netutils.IPV4Addr
 	vpcInterExtIP2 netutils.IPV4Addr
+
+	vpcPeeringCidr netutils.IPV4Prefix
 )
 
 func VpcInterCidr() netutils.IPV4Prefix {
@@ -64,6 +73,16 @@ func VpcInterExtIP2() netutils.IPV4Addr {
 	return vpcInterExtIP2
 }
 
+func VpcPeeringCidr() netutils.IPV4Prefix {
+	return vpcPeeringCidr
+}
+
+// VpcPeeringLinkSlots returns the number of link networks in the vpc
+// peering cidr
+func VpcPeeringLinkSlots() int {
+	return 1 << uint(VpcPeeringLinkMask-vpcPeeringCidr.MaskLen)
+}
+
 const (
 	sVpcMappedCidr      = "100.64.0.0/17"
 	VpcMappedIPMask     = 17
@@ -128,6 +147,8 @@ func init() {
 	vpcInterExtIP1 = mi(netutils.NewIPV4Addr(sVpcInterExtIP1))
 	vpcInterExtIP2 = mi(netutils.NewIPV4Addr(sVpcInterExtIP2))
 
+	vpcPeeringCidr = mp(netutils.NewIPV4Prefix(sVpcPeeringCidr))
+
 	vpcMappedCidr = mp(netutils.NewIPV4Prefix(sVpcMappedCidr))
 	vpcMappedGatewayIP = mi(netutils.NewIPV4Addr(sVpcMappedGatewayIP))
 
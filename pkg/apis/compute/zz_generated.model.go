@@ -535,6 +535,27 @@ type SDisk struct {
 	IsSsd bool `json:"is_ssd"`
 }
 
+// SDiskBackup is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SDiskBackup.
+type SDiskBackup struct {
+	apis.SVirtualResourceBase
+	SDiskResourceBase
+	// 备份时磁盘所在存储
+	StorageId string `json:"storage_id"`
+	// 备份模式, full或incremental
+	BackupMode string `json:"backup_mode"`
+	// 增量备份所依赖的上一个备份
+	ParentId string `json:"parent_id"`
+	// 增量备份所使用的dirty bitmap名称
+	BitmapName string `json:"bitmap_name"`
+	// 备份文件在对象存储中的位置
+	Location string `json:"location"`
+	// 备份文件大小, 单位Mb
+	SizeMb int `json:"size_mb"`
+	// 备份时磁盘大小, 单位Mb
+	DiskSizeMb int    `json:"disk_size_mb"`
+	DiskType   string `json:"disk_type"`
+}
+
 // SDiskResourceBase is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SDiskResourceBase.
 type SDiskResourceBase struct {
 	DiskId string `json:"disk_id"`
@@ -862,6 +883,17 @@ type SGroup struct {
 	// the upper limit number of guests with this group in a host
 	Granularity     int   `json:"granularity"`
 	ForceDispersion *bool `json:"force_dispersion,omitempty"`
+	// 亲和性类型, 为空时按照Granularity分散调度
+	// enum: affinity, anti-affinity
+	AffinityType string `json:"affinity_type"`
+	// 亲和性模式
+	// enum: hard, soft
+	AffinityMode string `json:"affinity_mode"`
+	// 亲和性拓扑域
+	// enum: host, rack, zone, storage, schedtag:<prefix>
+	TopologyKey string `json:"topology_key"`
+	// soft模式下的权重
+	AffinityWeight int `json:"affinity_weight"`
 }
 
 // SGroupJointsBase is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SGroupJointsBase.
@@ -918,6 +950,10 @@ type SGuest struct {
 	Vdi          string `json:"vdi"`
 	Machine      string `json:"machine"`
 	Bios         string `json:"bios"`
+	// 是否启用vTPM(TPM 2.0)设备
+	Vtpm bool `json:"vtpm"`
+	// 是否启用UEFI安全启动
+	SecureBoot bool `json:"secure_boot"`
 	// 操作系统类型
 	OsType   string `json:"os_type"`
 	FlavorId string `json:"flavor_id"`
@@ -971,6 +1007,23 @@ type SGuestTemplateResourceBase struct {
 	GuestTemplateId string `json:"guest_template_id"`
 }
 
+// SGuestVirtiofs is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SGuestVirtiofs.
+type SGuestVirtiofs struct {
+	apis.SResourceBase
+	// 自增Id
+	Id int64 `json:"id"`
+	// 虚拟机Id
+	GuestId string `json:"guest_id"`
+	// 挂载标签
+	Tag string `json:"tag"`
+	// 共享存储Id, 为空时SourcePath为宿主机目录
+	StorageId string `json:"storage_id"`
+	// 共享目录路径
+	SourcePath string `json:"source_path"`
+	// 是否只读
+	Readonly bool `json:"readonly"`
+}
+
 // SGuestcdrom is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SGuestcdrom.
 type SGuestcdrom struct {
 	Id string `json:"id"`
@@ -1101,8 +1154,10 @@ type SHost struct {
 	// host服务软件版本
 	Version string `json:"version"`
 	// OVN软件版本
-	OvnVersion  string `json:"ovn_version"`
-	IsBaremetal bool   `json:"is_baremetal"`
+	OvnVersion string `json:"ovn_version"`
+	// NUMA拓扑信息
+	NumaInfo    interface{} `json:"numa_info"`
+	IsBaremetal bool        `json:"is_baremetal"`
 	// 是否处于维护状态
 	IsMaintenance     bool   `json:"is_maintenance"`
 	EnableHealthCheck bool   `json:"enable_health_check"`
@@ -1237,6 +1292,8 @@ type SInstanceSnapshot struct {
 	InstanceType string `json:"instance_type"`
 	// 主机快照磁盘容量和
 	SizeMb int `json:"size_mb"`
+	// 是否在冻结文件系统后创建, 即应用一致性快照
+	Quiesced bool `json:"quiesced"`
 }
 
 // SInstanceSnapshotJoint is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SInstanceSnapshotJoint.
@@ -1338,6 +1395,8 @@ type SLoadbalancer struct {
 	// 网络类型
 	NetworkType string `json:"network_type"`
 	SLoadbalancerClusterResourceBase
+	// 数据面实现
+	DataplaneType string `json:"dataplane_type"`
 	// 计费类型
 	ChargeType string `json:"charge_type"`
 	// 套餐名称
@@ -1723,15 +1782,22 @@ type SNetwork struct {
 	// DNS
 	GuestDns string `json:"guest_dns"`
 	// allow multiple dhcp, seperated by ","
-	GuestDhcp     string `json:"guest_dhcp"`
-	GuestDomain   string `json:"guest_domain"`
+	GuestDhcp   string `json:"guest_dhcp"`
+	GuestDomain string `json:"guest_domain"`
+	// IPv6起始地址
 	GuestIp6Start string `json:"guest_ip6_start"`
-	GuestIp6End   string `json:"guest_ip6_end"`
-	GuestIp6Mask  byte   `json:"guest_ip6_mask"`
+	// IPv6结束地址
+	GuestIp6End string `json:"guest_ip6_end"`
+	// IPv6前缀长度
+	GuestIp6Mask byte `json:"guest_ip6_mask"`
+	// IPv6网关地址
 	GuestGateway6 string `json:"guest_gateway6"`
-	GuestDns6     string `json:"guest_dns6"`
-	GuestDomain6  string `json:"guest_domain6"`
-	VlanId        int    `json:"vlan_id"`
+	// IPv6 DNS
+	GuestDns6    string `json:"guest_dns6"`
+	GuestDomain6 string `json:"guest_domain6"`
+	// IPv6地址分配方式, dhcpv6或slaac
+	GuestIp6Mode string `json:"guest_ip6_mode"`
+	VlanId       int    `json:"vlan_id"`
 	// 服务器类型
 	// example: server
 	ServerType string `json:"server_type"`
@@ -1975,10 +2041,14 @@ type SScalingGroup struct {
 	// ExpansionPrinciple represent the principle when creating new instance to join in.
 	ExpansionPrinciple string `json:"expansion_principle"`
 	// ShrinkPrinciple represent the principle when removing instance from scaling group.
-	ShrinkPrinciple           string `json:"shrink_principle"`
-	HealthCheckMode           string `json:"health_check_mode"`
-	HealthCheckCycle          int    `json:"health_check_cycle"`
-	HealthCheckGov            int    `json:"health_check_gov"`
+	ShrinkPrinciple  string `json:"shrink_principle"`
+	HealthCheckMode  string `json:"health_check_mode"`
+	HealthCheckCycle int    `json:"health_check_cycle"`
+	HealthCheckGov   int    `json:"health_check_gov"`
+	// HealthCheckProbe describe how to probe the service of instance, tcp or http, empty means no probe
+	HealthCheckProbe          string `json:"health_check_probe"`
+	HealthCheckPort           int    `json:"health_check_port"`
+	HealthCheckPath           string `json:"health_check_path"`
 	LoadbalancerBackendPort   int    `json:"loadbalancer_backend_port"`
 	LoadbalancerBackendWeight int    `json:"loadbalancer_backend_weight"`
 }
@@ -1989,6 +2059,11 @@ type SScalingGroupGuest struct {
 	ScalingGroupId string `json:"scaling_group_id"`
 	GuestStatus    string `json:"guest_status"`
 	Manual         *bool  `json:"manual,omitempty"`
+	// LifecycleHookId is the lifecycle hook which the guest is waiting for
+	LifecycleHookId   string    `json:"lifecycle_hook_id"`
+	LifecycleToken    string    `json:"lifecycle_token"`
+	LifecycleResult   string    `json:"lifecycle_result"`
+	LifecycleDeadline time.Time `json:"lifecycle_deadline"`
 }
 
 // SScalingGroupNetwork is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SScalingGroupNetwork.
@@ -2004,6 +2079,20 @@ type SScalingGroupResourceBase struct {
 	ScalingGroupId string `json:"scaling_group_id"`
 }
 
+// SScalingLifecycleHook is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SScalingLifecycleHook.
+type SScalingLifecycleHook struct {
+	apis.SVirtualResourceBase
+	SScalingGroupResourceBase
+	Transition string `json:"transition"`
+	NotifyType string `json:"notify_type"`
+	WebhookUrl string `json:"webhook_url"`
+	// AnsiblePlaybook is the template playbook, it is copied with the instance as inventory when notifying
+	AnsiblePlaybookId string `json:"ansible_playbook_id"`
+	// Timeout in seconds to wait for the callback
+	Timeout       int    `json:"timeout"`
+	DefaultResult string `json:"default_result"`
+}
+
 // SScalingPolicy is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SScalingPolicy.
 type SScalingPolicy struct {
 	apis.SVirtualResourceBase
@@ -2093,6 +2182,8 @@ type SScheduledTaskLabel struct {
 type SSecurityGroup struct {
 	apis.SSharableVirtualResourceBase
 	IsDirty bool `json:"is_dirty"`
+	// 记录命中所有规则的流量
+	Log bool `json:"log"`
 }
 
 // SSecurityGroupCache is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SSecurityGroupCache.
@@ -2125,6 +2216,16 @@ type SSecurityGroupRule struct {
 	CIDR        string `json:"cidr"`
 	Action      string `json:"action"`
 	Description string `json:"description"`
+	// 记录命中此规则的流量
+	Log bool `json:"log"`
+	// 命中的报文数, 来自acl流表的计数
+	HitCount int64 `json:"hit_count"`
+	// 命中的字节数
+	HitBytes int64 `json:"hit_bytes"`
+	// 最后命中时间
+	LastHitAt time.Time `json:"last_hit_at"`
+	// 最近被拒绝的流量
+	DeniedFlows *SecgroupRuleDeniedFlows `json:"denied_flows"`
 }
 
 // SServerSku is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SServerSku.
@@ -2195,6 +2296,8 @@ type SSnapshot struct {
 	RefCount      int       `json:"ref_count"`
 	BackingDiskId string    `json:"backing_disk_id"`
 	ExpiredAt     time.Time `json:"expired_at"`
+	// 是否在冻结文件系统后创建, 即应用一致性快照
+	Quiesced bool `json:"quiesced"`
 }
 
 // SSnapshotPolicy is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SSnapshotPolicy.
@@ -2206,6 +2309,8 @@ type SSnapshotPolicy struct {
 	// 0~23
 	TimePoints  uint32 `json:"time_points"`
 	IsActivated *bool  `json:"is_activated,omitempty"`
+	// 是否冻结虚拟机文件系统后再创建快照
+	Quiesce bool `json:"quiesce"`
 }
 
 // SSnapshotPolicyCache is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SSnapshotPolicyCache.
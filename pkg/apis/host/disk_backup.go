@@ -0,0 +1,52 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package host
+
+// S3 compatible object storage where disk backups are kept, hosts fetch it
+// from backup-storage details of the backup instead of receiving it in
+// requests
+type DiskBackupStorage struct {
+	Endpoint  string `json:"endpoint"`
+	AccessKey string `json:"access_key"`
+	SecretKey string `json:"secret_key"`
+	Bucket    string `json:"bucket"`
+	UseSSL    bool   `json:"use_ssl"`
+}
+
+type GuestDiskBackupRequest struct {
+	DiskId   string `json:"disk_id"`
+	BackupId string `json:"backup_id"`
+	// full or incremental
+	BackupMode string `json:"backup_mode"`
+	// dirty bitmap tracking changes since the last full backup
+	BitmapName string `json:"bitmap_name"`
+}
+
+type GuestDiskBackupResponse struct {
+	Location string `json:"location"`
+	SizeMb   int    `json:"size_mb"`
+	// actual backup mode, host falls back to full if bitmap is lost
+	BackupMode string `json:"backup_mode"`
+}
+
+type DiskBackupChainItem struct {
+	BackupId string `json:"backup_id"`
+	Location string `json:"location"`
+}
+
+// Restore a disk from a backup chain, chain[0] is the full backup
+type DiskRecoveryFromBackup struct {
+	Chain []DiskBackupChainItem `json:"chain"`
+}
@@ -0,0 +1,30 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package host
+
+// Move a disk of running guest to another storage of the same host
+type GuestChangeDiskStorageRequest struct {
+	DiskId          string `json:"disk_id"`
+	TargetStorageId string `json:"target_storage_id"`
+}
+
+type GuestChangeDiskStorageResponse struct {
+	DiskId    string `json:"disk_id"`
+	StorageId string `json:"storage_id"`
+	// path of the disk on target storage
+	DiskPath string `json:"disk_path"`
+	// format of the disk on target storage, block devices are always raw
+	DiskFormat string `json:"disk_format"`
+}
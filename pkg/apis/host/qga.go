@@ -0,0 +1,65 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package host
+
+type GuestQgaFsFreezeResponse struct {
+	// number of filesystems frozen or thawed
+	Count int `json:"count"`
+}
+
+type GuestQgaSetPasswordRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type GuestQgaOsInfo struct {
+	Id            string `json:"id"`
+	Name          string `json:"name"`
+	PrettyName    string `json:"pretty_name"`
+	Version       string `json:"version"`
+	VersionId     string `json:"version_id"`
+	KernelRelease string `json:"kernel_release"`
+	KernelVersion string `json:"kernel_version"`
+	Machine       string `json:"machine"`
+}
+
+type GuestQgaNetworkInterface struct {
+	Name string `json:"name"`
+	Mac  string `json:"mac"`
+	// ip addresses with prefix length, eg: 10.0.0.2/24
+	Ips []string `json:"ips"`
+}
+
+type GuestQgaGuestInfoResponse struct {
+	OsInfo     *GuestQgaOsInfo            `json:"os_info"`
+	Interfaces []GuestQgaNetworkInterface `json:"interfaces"`
+}
+
+type GuestQgaFileReadRequest struct {
+	Path string `json:"path"`
+	// read at most max_size bytes from the beginning of file
+	MaxSize int `json:"max_size"`
+}
+
+type GuestQgaFileReadResponse struct {
+	Content string `json:"content"`
+	Size    int    `json:"size"`
+}
+
+type GuestQgaFileWriteRequest struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+	Append  bool   `json:"append"`
+}
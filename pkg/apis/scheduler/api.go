@@ -31,6 +31,9 @@ type ScheduleBaseConfig struct {
 	// usedby test api
 	RecordLog bool `json:"record_to_history"`
 	Details   bool `json:"details"`
+
+	// used by simulate api, count of hypothetical requests run one by one
+	SimulateCount int `json:"simulate_count"`
 }
 
 type ForGuest struct {
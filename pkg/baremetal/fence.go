@@ -0,0 +1,95 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package baremetal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/baremetal/utils/ipmitool"
+	"yunion.io/x/onecloud/pkg/cloudcommon/types"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/modules"
+	"yunion.io/x/onecloud/pkg/util/redfish"
+)
+
+const (
+	hostFenceTimeout      = 60 * time.Second
+	hostFencePollInterval = 5 * time.Second
+)
+
+// FenceHost powers off a host through its BMC and waits until it is off,
+// the host needn't be a baremetal managed by this agent, region fences the
+// down hypervisor before restarting its guests on other hosts
+func FenceHost(ctx context.Context, session *mcclient.ClientSession, hostId string) error {
+	obj, err := modules.Hosts.GetSpecific(session, hostId, "ipmi", nil)
+	if err != nil {
+		return errors.Wrap(err, "get ipmi info")
+	}
+	ipmiInfo := types.SIPMIInfo{}
+	if err := obj.Unmarshal(&ipmiInfo); err != nil {
+		return errors.Wrap(err, "unmarshal ipmi info")
+	}
+	if len(ipmiInfo.IpAddr) == 0 {
+		return errors.Wrap(errors.ErrNotSupported, "host has no ipmi address")
+	}
+
+	var powerOff func() error
+	var powerStatus func() (string, error)
+	if ipmiInfo.RedfishApi {
+		drv := redfish.NewRedfishDriver(ctx, "https://"+ipmiInfo.IpAddr, ipmiInfo.Username, ipmiInfo.Password, false)
+		if drv == nil {
+			return errors.Wrapf(errors.ErrNotSupported, "no redfish driver for %s", ipmiInfo.IpAddr)
+		}
+		powerOff = func() error {
+			return drv.Reset(ctx, "ForceOff")
+		}
+		powerStatus = func() (string, error) {
+			_, sysInfo, err := drv.GetSystemInfo(ctx)
+			if err != nil {
+				return "", err
+			}
+			return sysInfo.PowerState, nil
+		}
+	} else {
+		ipmiTool := ipmitool.NewLanPlusIPMI(ipmiInfo.IpAddr, ipmiInfo.Username, ipmiInfo.Password)
+		powerOff = func() error {
+			return ipmitool.DoHardShutdown(ipmiTool)
+		}
+		powerStatus = func() (string, error) {
+			return ipmitool.GetChassisPowerStatus(ipmiTool)
+		}
+	}
+
+	if err := powerOff(); err != nil {
+		log.Errorf("fence host %s power off: %s", hostId, err)
+	}
+	deadline := time.Now().Add(hostFenceTimeout)
+	for {
+		status, err := powerStatus()
+		if err == nil && strings.EqualFold(status, types.POWER_STATUS_OFF) {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("host power status %q not off after %s: %v", status, hostFenceTimeout, err)
+		}
+		time.Sleep(hostFencePollInterval)
+	}
+}
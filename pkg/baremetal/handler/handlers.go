@@ -73,6 +73,7 @@ func initBaremetalsHandler(app *appsrv.Application) {
 	AddHandler(app, "POST", bmActionPrefix("ipmi-probe"), bmObjMiddleware(handleBaremetalIpmiProbe))
 	AddHandler(app, "POST", bmActionPrefix("cdrom"), bmObjMiddleware(handleBaremetalCdromTask))
 	AddHandler(app, "POST", bmActionPrefix("jnlp"), bmObjMiddleware(handleBaremetalJnlpTask))
+	AddHandler(app, "POST", bmActionPrefix("fence"), authMiddleware(handleHostFence))
 
 	// server actions handler
 	AddHandler(app, "POST", srvActionPrefix("create"), srvClassMiddleware(handleServerCreate))
@@ -162,6 +163,16 @@ func handleBaremetalCdromTask(ctx *Context, bm *baremetal.SBaremetalInstance) {
 	ctx.ResponseOk()
 }
 
+// handleHostFence powers off the host even if it's not a baremetal of this agent
+func handleHostFence(ctx *Context) {
+	hostId := ctx.Params()[PARAMS_BMID_KEY]
+	session := ctx.GetBaremetalManager().GetClientSession()
+	ctx.DelayProcess(func(data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+		return nil, baremetal.FenceHost(context.Background(), session, hostId)
+	}, nil)
+	ctx.ResponseOk()
+}
+
 func handleBaremetalJnlpTask(ctx *Context, bm *baremetal.SBaremetalInstance) {
 	jnlp, err := bm.GetConsoleJNLP(ctx)
 	if err != nil {
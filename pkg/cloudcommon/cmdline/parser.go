@@ -59,6 +59,53 @@ func ParseSchedtagConfig(desc string) (*compute.SchedtagConfig, error) {
 	return conf, nil
 }
 
+// ParseAffinityRule desc format: <type>[,<mode>][,<topology_key>][,group=<group>][,label=<key>=<value>...][,weight=<weight>]
+func ParseAffinityRule(desc string) (*compute.SchedAffinityRule, error) {
+	if len(desc) == 0 {
+		return nil, ErrorEmptyDesc
+	}
+	rule := &compute.SchedAffinityRule{}
+	for _, part := range strings.Split(desc, ",") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) == 1 {
+			switch {
+			case utils.IsInStringArray(part, compute.AFFINITY_TYPES):
+				rule.Type = part
+			case utils.IsInStringArray(part, compute.AFFINITY_MODES):
+				rule.Mode = part
+			default:
+				rule.TopologyKey = part
+			}
+			continue
+		}
+		switch kv[0] {
+		case "group":
+			rule.GroupId = kv[1]
+		case "label":
+			label := strings.SplitN(kv[1], "=", 2)
+			if len(label) != 2 {
+				return nil, fmt.Errorf("Invalid label %s", kv[1])
+			}
+			if rule.Labels == nil {
+				rule.Labels = make(map[string]string)
+			}
+			rule.Labels[label[0]] = label[1]
+		case "weight":
+			weight, err := strconv.Atoi(kv[1])
+			if err != nil {
+				return nil, fmt.Errorf("Invalid weight %s", kv[1])
+			}
+			rule.Weight = weight
+		default:
+			return nil, fmt.Errorf("Unknown affinity rule option %s", kv[0])
+		}
+	}
+	if len(rule.Type) == 0 {
+		return nil, fmt.Errorf("Missing affinity type in %s", desc)
+	}
+	return rule, nil
+}
+
 // ParseResourceSchedtagConfig desc format: <idx>:<schedtagName>:<strategy>
 func ParseResourceSchedtagConfig(desc string) (int, *compute.SchedtagConfig, error) {
 	if len(desc) == 0 {
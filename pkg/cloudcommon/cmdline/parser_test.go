@@ -72,6 +72,59 @@ func TestParseSchedtagConfig(t *testing.T) {
 	}
 }
 
+func TestParseAffinityRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		desc    string
+		want    *compute.SchedAffinityRule
+		wantErr bool
+	}{
+		{
+			name:    "empty input",
+			desc:    "",
+			wantErr: true,
+		},
+		{
+			name: "group",
+			desc: "anti-affinity,hard,rack,group=web",
+			want: &compute.SchedAffinityRule{Type: "anti-affinity", Mode: "hard", TopologyKey: "rack", GroupId: "web"},
+		},
+		{
+			name: "labels",
+			desc: "affinity,soft,schedtag:az-,label=app=db,label=env=prod,weight=5",
+			want: &compute.SchedAffinityRule{
+				Type:        "affinity",
+				Mode:        "soft",
+				TopologyKey: "schedtag:az-",
+				Labels:      map[string]string{"app": "db", "env": "prod"},
+				Weight:      5,
+			},
+		},
+		{
+			name:    "missing type",
+			desc:    "hard,host,group=web",
+			wantErr: true,
+		},
+		{
+			name:    "invalid label",
+			desc:    "affinity,label=app",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAffinityRule(tt.desc)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseAffinityRule() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseAffinityRule() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestParseRange(t *testing.T) {
 	type args struct {
 		rangeStr string
@@ -70,6 +70,9 @@ const (
 	ACT_MIGRATE      = "migrate"
 	ACT_MIGRATE_FAIL = "migrate_fail"
 
+	ACT_REBALANCE_RECOMMEND = "rebalance_recommend"
+	ACT_REBALANCE_MIGRATE   = "rebalance_migrate"
+
 	ACT_VM_CONVERT      = "vm_convert"
 	ACT_VM_CONVERTING   = "vm_converting"
 	ACT_VM_CONVERT_FAIL = "vm_convert_fail"
@@ -108,6 +111,8 @@ const (
 	ACT_VM_RESET_SNAPSHOT        = "instance_reset_snapshot"
 	ACT_VM_RESET_SNAPSHOT_FAILED = "instance_reset_snapshot_failed"
 
+	ACT_SNAPSHOT_QUIESCE_FAIL = "snapshot_quiesce_fail"
+
 	ACT_SNAPSHOT_POLICY_BIND_DISK        = "snapshot_policy_bind_disk"
 	ACT_SNAPSHOT_POLICY_BIND_DISK_FAIL   = "snapshot_policy_bind_disk_fail"
 	ACT_SNAPSHOT_POLICY_UNBIND_DISK      = "snapshot_policy_unbind_disk"
@@ -244,6 +249,8 @@ const (
 	ACT_GUEST_PANICKED                   = "guest_panicked"
 	ACT_HOST_MAINTENANCE                 = "host_maintenance"
 	ACT_HOST_DOWN                        = "host_down"
+	ACT_HOST_FENCE                       = "host_fence"
+	ACT_HOST_FENCE_FAIL                  = "host_fence_fail"
 
 	ACT_UPLOAD_OBJECT  = "upload_obj"
 	ACT_DELETE_OBJECT  = "delete_obj"
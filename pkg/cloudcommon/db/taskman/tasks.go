@@ -51,6 +51,7 @@ const (
 	PENDING_USAGE_KEY      = "__pending_usage__"
 	PARENT_TASK_NOTIFY_KEY = "__parent_task_notifyurl"
 	REQUEST_CONTEXT_KEY    = "__request_context"
+	SCHEDULE_RUN_DATA_KEY  = "__schedule_run_data"
 
 	TASK_STAGE_FAILED   = "failed"
 	TASK_STAGE_COMPLETE = "complete"
@@ -92,6 +93,9 @@ type STask struct {
 
 	Stage string `width:"64" charset:"ascii" nullable:"false" default:"on_init" list:"user"` // Column(VARCHAR(64, charset='ascii'), nullable=False, default='on_init')
 
+	// time to rerun the current stage, set by ScheduleRunAfter
+	RunAfter time.Time `nullable:"true" list:"user"`
+
 	taskObject  db.IStandaloneModel   `ignore:"true"`
 	taskObjects []db.IStandaloneModel `ignore:"true"`
 }
@@ -559,6 +563,84 @@ func (task *STask) ScheduleRun(data jsonutils.JSONObject) error {
 	return runTask(task.Id, data)
 }
 
+// ScheduleRunAfter reruns the current stage after delay, no task worker is held while waiting.
+// The time to rerun is saved with the task, ResumeScheduledTasks reruns the task
+// if the timer is lost by restarting the service.
+func (task *STask) ScheduleRunAfter(delay time.Duration, data jsonutils.JSONObject) {
+	_, err := db.Update(task, func() error {
+		params := task.Params.CopyExcludes(SCHEDULE_RUN_DATA_KEY)
+		if data != nil {
+			params.Add(data, SCHEDULE_RUN_DATA_KEY)
+		}
+		task.Params = params
+		task.RunAfter = time.Now().Add(delay)
+		return nil
+	})
+	if err != nil {
+		log.Errorf("save run_after of task %s: %v", task.Id, err)
+	}
+	taskId := task.Id
+	time.AfterFunc(delay, func() {
+		TaskManager.runScheduledTask(context.Background(), taskId)
+	})
+}
+
+func (task *STask) isScheduledRunDue(now time.Time) bool {
+	if task.RunAfter.IsZero() || task.RunAfter.After(now) {
+		return false
+	}
+	return !utils.IsInStringArray(task.Stage, []string{TASK_STAGE_COMPLETE, TASK_STAGE_FAILED})
+}
+
+// runScheduledTask reruns a task whose scheduled time is due, the time is cleared
+// under lock so that the timer and ResumeScheduledTasks run the task only once
+func (manager *STaskManager) runScheduledTask(ctx context.Context, taskId string) {
+	var data jsonutils.JSONObject
+	due := func() bool {
+		lockman.LockRawObject(ctx, "tasks", taskId)
+		defer lockman.ReleaseRawObject(ctx, "tasks", taskId)
+
+		task := manager.fetchTask(taskId)
+		if task == nil || !task.isScheduledRunDue(time.Now()) {
+			return false
+		}
+		data, _ = task.Params.Get(SCHEDULE_RUN_DATA_KEY)
+		_, err := db.Update(task, func() error {
+			task.RunAfter = time.Time{}
+			return nil
+		})
+		if err != nil {
+			log.Errorf("clear run_after of task %s: %v", taskId, err)
+			return false
+		}
+		return true
+	}()
+	if !due {
+		return
+	}
+	err := runTask(taskId, data)
+	if err != nil {
+		log.Errorf("schedule run task %s: %v", taskId, err)
+	}
+}
+
+// ResumeScheduledTasks reruns tasks whose scheduled time is due but not run, e.g. the
+// timers of ScheduleRunAfter are lost when the service restarts
+func (manager *STaskManager) ResumeScheduledTasks(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	q := manager.Query().IsNotNull("run_after").LE("run_after", time.Now())
+	q = q.NotIn("stage", []string{TASK_STAGE_COMPLETE, TASK_STAGE_FAILED})
+	tasks := make([]STask, 0)
+	err := db.FetchModelObjects(manager, q, &tasks)
+	if err != nil {
+		log.Errorf("fetch scheduled tasks: %v", err)
+		return
+	}
+	for i := range tasks {
+		log.Infof("resume scheduled task %s(%s) at stage %s", tasks[i].TaskName, tasks[i].Id, tasks[i].Stage)
+		manager.runScheduledTask(ctx, tasks[i].Id)
+	}
+}
+
 func (self *STask) IsSubtask() bool {
 	return self.HasParentTask()
 }
@@ -625,6 +707,8 @@ func (self *STask) SetStage(stageName string, data *jsonutils.JSONDict) error {
 			stageData.Add(jsonutils.NewTimeString(time.Now()), "complete_at")
 			stageList.Add(stageData)
 			self.Stage = stageName
+			// a scheduled rerun belongs to the previous stage
+			self.RunAfter = time.Time{}
 		}
 		self.Params = params
 		return nil
@@ -0,0 +1,74 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package taskman
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduledRunResumeAfterRestart(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		name     string
+		runAfter time.Time
+		stage    string
+		want     bool
+	}{
+		{
+			name:     "timer lost by restart",
+			runAfter: now.Add(-time.Minute),
+			stage:    "OnWaitBlockJobs",
+			want:     true,
+		},
+		{
+			name:     "due right now",
+			runAfter: now,
+			stage:    "OnWaitBlockJobs",
+			want:     true,
+		},
+		{
+			name:     "not due yet",
+			runAfter: now.Add(time.Minute),
+			stage:    "OnWaitBlockJobs",
+			want:     false,
+		},
+		{
+			name:  "already run",
+			stage: "OnWaitBlockJobs",
+			want:  false,
+		},
+		{
+			name:     "task complete",
+			runAfter: now.Add(-time.Minute),
+			stage:    TASK_STAGE_COMPLETE,
+			want:     false,
+		},
+		{
+			name:     "task failed",
+			runAfter: now.Add(-time.Minute),
+			stage:    TASK_STAGE_FAILED,
+			want:     false,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			task := &STask{Stage: c.stage, RunAfter: c.runAfter}
+			if got := task.isScheduledRunDue(now); got != c.want {
+				t.Errorf("want due %v, got %v", c.want, got)
+			}
+		})
+	}
+}
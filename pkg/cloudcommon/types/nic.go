@@ -74,6 +74,12 @@ type SServerNic struct {
 	LinkUp    bool     `json:"link_up,omitempty"`
 	TeamWith  string   `json:"team_with,omitempty"`
 
+	Ip6      string `json:"ip6,omitempty"`
+	Masklen6 int    `json:"masklen6,omitempty"`
+	Gateway6 string `json:"gateway6,omitempty"`
+	Dns6     string `json:"dns6,omitempty"`
+	Ip6Mode  string `json:"ip6_mode,omitempty"`
+
 	TeamingMaster *SServerNic   `json:"-"`
 	TeamingSlaves []*SServerNic `json:"-"`
 }
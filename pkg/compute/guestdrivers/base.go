@@ -24,6 +24,7 @@ import (
 	"yunion.io/x/pkg/errors"
 
 	api "yunion.io/x/onecloud/pkg/apis/compute"
+	host_api "yunion.io/x/onecloud/pkg/apis/host"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/quotas"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
 	"yunion.io/x/onecloud/pkg/cloudprovider"
@@ -254,6 +255,18 @@ func (self *SBaseGuestDriver) RequestDiskSnapshot(ctx context.Context, guest *mo
 	return fmt.Errorf("Not Implement")
 }
 
+func (self *SBaseGuestDriver) RequestDiskBackup(ctx context.Context, guest *models.SGuest, task taskman.ITask, input *host_api.GuestDiskBackupRequest) error {
+	return fmt.Errorf("Not Implement")
+}
+
+func (self *SBaseGuestDriver) ValidateChangeDiskStorage(ctx context.Context, userCred mcclient.TokenCredential, guest *models.SGuest, disk *models.SDisk, targetStorage *models.SStorage) error {
+	return httperrors.NewNotAcceptableError("Not allow for hypervisor %s", guest.GetHypervisor())
+}
+
+func (self *SBaseGuestDriver) RequestChangeDiskStorage(ctx context.Context, guest *models.SGuest, task taskman.ITask, input *host_api.GuestChangeDiskStorageRequest) error {
+	return fmt.Errorf("Not Implement")
+}
+
 func (self *SBaseGuestDriver) RequestDeleteSnapshot(ctx context.Context, guest *models.SGuest, task taskman.ITask, params *jsonutils.JSONDict) error {
 	return fmt.Errorf("Not Implement")
 }
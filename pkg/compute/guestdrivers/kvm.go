@@ -124,6 +124,36 @@ func (self *SKVMGuestDriver) RequestDiskSnapshot(ctx context.Context, guest *mod
 	return err
 }
 
+func (self *SKVMGuestDriver) RequestDiskBackup(ctx context.Context, guest *models.SGuest, task taskman.ITask, input *host_api.GuestDiskBackupRequest) error {
+	host := guest.GetHost()
+	url := fmt.Sprintf("%s/servers/%s/disk-backup", host.ManagerUri, guest.Id)
+	header := self.getTaskRequestHeader(task)
+	_, _, err := httputils.JSONRequest(httputils.GetDefaultClient(), ctx, "POST", url, header, jsonutils.Marshal(input), false)
+	return err
+}
+
+func (self *SKVMGuestDriver) ValidateChangeDiskStorage(ctx context.Context, userCred mcclient.TokenCredential, guest *models.SGuest, disk *models.SDisk, targetStorage *models.SStorage) error {
+	supportedTypes := []string{
+		api.STORAGE_LOCAL, api.STORAGE_RBD, api.STORAGE_NFS, api.STORAGE_GPFS, api.STORAGE_CIFS,
+		api.STORAGE_LVM, api.STORAGE_ISCSI_LVM, api.STORAGE_PLUGIN,
+	}
+	if !utils.IsInStringArray(targetStorage.StorageType, supportedTypes) {
+		return httperrors.NewNotSupportedError("Not support change disk storage to %s storage", targetStorage.StorageType)
+	}
+	if len(guest.BackupHostId) > 0 {
+		return httperrors.NewBadRequestError("Guest have backup, can't change disk storage")
+	}
+	return nil
+}
+
+func (self *SKVMGuestDriver) RequestChangeDiskStorage(ctx context.Context, guest *models.SGuest, task taskman.ITask, input *host_api.GuestChangeDiskStorageRequest) error {
+	host := guest.GetHost()
+	url := fmt.Sprintf("%s/servers/%s/change-disk-storage", host.ManagerUri, guest.Id)
+	header := self.getTaskRequestHeader(task)
+	_, _, err := httputils.JSONRequest(httputils.GetDefaultClient(), ctx, "POST", url, header, jsonutils.Marshal(input), false)
+	return err
+}
+
 func (self *SKVMGuestDriver) RequestDeleteSnapshot(ctx context.Context, guest *models.SGuest, task taskman.ITask, params *jsonutils.JSONDict) error {
 	host := guest.GetHost()
 	url := fmt.Sprintf("%s/servers/%s/delete-snapshot", host.ManagerUri, guest.Id)
@@ -722,6 +752,9 @@ func (self *SKVMGuestDriver) CheckMigrate(guest *models.SGuest, userCred mcclien
 	if len(devices) > 0 {
 		return httperrors.NewBadRequestError("Cannot migrate with isolated devices")
 	}
+	if guest.HasLVMDisks() {
+		return httperrors.NewBadRequestError("Cannot migrate with lvm disks")
+	}
 	if len(input.PreferHost) > 0 {
 		err := checkAssignHost(userCred, input.PreferHost)
 		if err != nil {
@@ -744,6 +777,13 @@ func (self *SKVMGuestDriver) CheckLiveMigrate(guest *models.SGuest, userCred mcc
 		if devices != nil && len(devices) > 0 {
 			return httperrors.NewBadRequestError("Cannot live migrate with isolated devices")
 		}
+		if guest.HasLVMDisks() {
+			return httperrors.NewBadRequestError("Cannot live migrate with lvm disks")
+		}
+		// vhost-user-fs device state is not migratable
+		if shares, _ := guest.GetVirtiofs(); len(shares) > 0 {
+			return httperrors.NewBadRequestError("Cannot live migrate with virtiofs shares")
+		}
 		if !guest.CheckQemuVersion(guest.GetQemuVersion(userCred), "1.1.2") {
 			return httperrors.NewBadRequestError("Cannot do live migrate, too low qemu version")
 		}
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"path"
 	"strings"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -59,7 +60,7 @@ func (self *SKVMHostDriver) GetHypervisor() string {
 }
 
 func (self *SKVMHostDriver) ValidateAttachStorage(ctx context.Context, userCred mcclient.TokenCredential, host *models.SHost, storage *models.SStorage, data *jsonutils.JSONDict) error {
-	if !utils.IsInStringArray(storage.StorageType, append([]string{api.STORAGE_LOCAL}, api.SHARED_STORAGE...)) {
+	if !utils.IsInStringArray(storage.StorageType, append([]string{api.STORAGE_LOCAL, api.STORAGE_LVM}, api.SHARED_STORAGE...)) {
 		return httperrors.NewUnsupportOperationError("Unsupport attach %s storage for %s host", storage.StorageType, host.HostType)
 	}
 	if storage.StorageType == api.STORAGE_RBD {
@@ -68,6 +69,18 @@ func (self *SKVMHostDriver) ValidateAttachStorage(ctx context.Context, userCred
 		}
 		pool, _ := storage.StorageConf.GetString("pool")
 		data.Set("mount_point", jsonutils.NewString(fmt.Sprintf("rbd:%s", pool)))
+	} else if storage.StorageType == api.STORAGE_ISCSI_LVM {
+		if host.HostStatus != api.HOST_ONLINE {
+			return httperrors.NewInvalidStatusError("Attach iscsi lvm storage require host status is online")
+		}
+		vgName, _ := storage.StorageConf.GetString("vg_name")
+		data.Set("mount_point", jsonutils.NewString(fmt.Sprintf("/dev/%s", vgName)))
+	} else if storage.StorageType == api.STORAGE_PLUGIN {
+		if host.HostStatus != api.HOST_ONLINE {
+			return httperrors.NewInvalidStatusError("Attach plugin storage require host status is online")
+		}
+		// volumes are published under mount point by storage plugin
+		data.Set("mount_point", jsonutils.NewString(path.Join(options.Options.DefaultStoragePluginVolumeDir, storage.Id)))
 	} else if utils.IsInStringArray(storage.StorageType, api.SHARED_FILE_STORAGE) {
 		mountPoint, err := data.GetString("mount_point")
 		if err != nil {
@@ -264,6 +277,9 @@ func (self *SKVMHostDriver) RequestAllocateDiskOnStorage(ctx context.Context, us
 			content.Set("snapshot_url", jsonutils.NewString(snapshot.Id))
 			content.Set("src_disk_id", jsonutils.NewString(snapshot.DiskId))
 			content.Set("src_pool", jsonutils.NewString(pool))
+		} else if utils.IsInStringArray(snapshotStorage.StorageType, []string{api.STORAGE_LVM, api.STORAGE_PLUGIN}) {
+			content.Set("snapshot_url", jsonutils.NewString(snapshot.Id))
+			content.Set("src_disk_id", jsonutils.NewString(snapshot.DiskId))
 		} else {
 			content.Set("snapshot_url", jsonutils.NewString(snapshot.Location))
 		}
@@ -0,0 +1,708 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/minio/minio-go"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	billing_api "yunion.io/x/onecloud/pkg/apis/billing"
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	host_api "yunion.io/x/onecloud/pkg/apis/host"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+type SDiskBackupManager struct {
+	db.SVirtualResourceBaseManager
+	SDiskResourceBaseManager
+}
+
+// 磁盘备份, 备份文件保存在S3兼容的对象存储中
+// 增量备份基于QEMU dirty bitmap, 一个全量备份及其后续增量备份组成一条备份链
+type SDiskBackup struct {
+	db.SVirtualResourceBase
+	SDiskResourceBase `width:"36" charset:"ascii" nullable:"false" list:"user" create:"required" index:"true"`
+
+	// 备份时磁盘所在存储
+	StorageId string `width:"36" charset:"ascii" nullable:"true" list:"user" create:"optional"`
+	// 备份模式, full或incremental
+	BackupMode string `width:"16" charset:"ascii" nullable:"false" default:"full" list:"user" create:"optional"`
+	// 增量备份所依赖的上一个备份
+	ParentId string `width:"36" charset:"ascii" nullable:"true" list:"user" create:"optional" index:"true"`
+	// 增量备份所使用的dirty bitmap名称
+	BitmapName string `width:"64" charset:"ascii" nullable:"true" list:"admin" create:"optional"`
+	// 备份文件在对象存储中的位置
+	Location string `charset:"ascii" nullable:"true" list:"admin"`
+	// 备份文件大小, 单位Mb
+	SizeMb int `nullable:"false" default:"0" list:"user"`
+	// 备份时磁盘大小, 单位Mb
+	DiskSizeMb int    `nullable:"false" default:"0" list:"user" create:"optional"`
+	DiskType   string `width:"32" charset:"ascii" nullable:"true" list:"user" create:"optional"`
+}
+
+var DiskBackupManager *SDiskBackupManager
+
+func init() {
+	DiskBackupManager = &SDiskBackupManager{
+		SVirtualResourceBaseManager: db.NewVirtualResourceBaseManager(
+			SDiskBackup{},
+			"disk_backups_tbl",
+			"diskbackup",
+			"diskbackups",
+		),
+	}
+	DiskBackupManager.SetVirtualObject(DiskBackupManager)
+}
+
+// 磁盘备份列表
+func (manager *SDiskBackupManager) ListItemFilter(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.DiskBackupListInput,
+) (*sqlchemy.SQuery, error) {
+	var err error
+
+	q, err = manager.SVirtualResourceBaseManager.ListItemFilter(ctx, q, userCred, query.VirtualResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SVirtualResourceBaseManager.ListItemFilter")
+	}
+	diskInput := api.DiskFilterListInput{
+		DiskFilterListInputBase: query.DiskFilterListInputBase,
+	}
+	q, err = manager.SDiskResourceBaseManager.ListItemFilter(ctx, q, userCred, diskInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SDiskResourceBaseManager.ListItemFilter")
+	}
+
+	if len(query.BackupMode) > 0 {
+		q = q.In("backup_mode", query.BackupMode)
+	}
+	if len(query.ParentId) > 0 {
+		q = q.Equals("parent_id", query.ParentId)
+	}
+
+	return q, nil
+}
+
+func (manager *SDiskBackupManager) OrderByExtraFields(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.DiskBackupListInput,
+) (*sqlchemy.SQuery, error) {
+	var err error
+
+	q, err = manager.SVirtualResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.VirtualResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SVirtualResourceBaseManager.OrderByExtraFields")
+	}
+	diskInput := api.DiskFilterListInput{
+		DiskFilterListInputBase: query.DiskFilterListInputBase,
+	}
+	q, err = manager.SDiskResourceBaseManager.OrderByExtraFields(ctx, q, userCred, diskInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SDiskResourceBaseManager.OrderByExtraFields")
+	}
+
+	return q, nil
+}
+
+func (manager *SDiskBackupManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	var err error
+
+	q, err = manager.SVirtualResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+
+	q, err = manager.SDiskResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+
+	return q, httperrors.ErrNotFound
+}
+
+func (manager *SDiskBackupManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.DiskBackupDetails {
+	rows := make([]api.DiskBackupDetails, len(objs))
+
+	virtRows := manager.SVirtualResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	diskRows := manager.SDiskResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+
+	backups := make([]*SDiskBackup, len(objs))
+	for i := range objs {
+		backups[i] = objs[i].(*SDiskBackup)
+	}
+	chainLengths, err := manager.fetchChainLengths(backups)
+	if err != nil {
+		log.Errorf("fetchChainLengths: %v", err)
+	}
+
+	for i := range rows {
+		rows[i] = api.DiskBackupDetails{
+			VirtualResourceDetails: virtRows[i],
+			DiskResourceInfo:       diskRows[i],
+		}
+		if backups[i].BackupMode == api.DISK_BACKUP_MODE_INCREMENTAL {
+			rows[i].ChainLength = chainLengths[backups[i].Id]
+		}
+	}
+
+	return rows
+}
+
+// fetchChainLengths returns number of incremental backups from the full
+// backup to each of the given backups.  Parents are fetched level by
+// level, so the number of queries is bounded by the max chain length
+// instead of the number of backups
+func (manager *SDiskBackupManager) fetchChainLengths(backups []*SDiskBackup) (map[string]int, error) {
+	return backupChainLengths(backups, manager.fetchParentIds, options.Options.DiskBackupMaxIncrementalCount)
+}
+
+// fetchParentIds returns parent_id of the given backups, removed backups
+// are absent from the result
+func (manager *SDiskBackupManager) fetchParentIds(ids []string) (map[string]string, error) {
+	q := manager.Query("id", "parent_id").In("id", ids)
+	rows, err := q.Rows()
+	if err != nil {
+		return nil, errors.Wrap(err, "query parents")
+	}
+	defer rows.Close()
+	ret := map[string]string{}
+	for rows.Next() {
+		var (
+			id       string
+			parentId sql.NullString
+		)
+		if err := rows.Scan(&id, &parentId); err != nil {
+			return nil, errors.Wrap(err, "scan parents")
+		}
+		ret[id] = parentId.String
+	}
+	return ret, nil
+}
+
+func backupChainLengths(backups []*SDiskBackup, fetchParentIds func(ids []string) (map[string]string, error), maxCount int) (map[string]int, error) {
+	parents := map[string]string{}
+	for _, backup := range backups {
+		parents[backup.Id] = backup.ParentId
+	}
+	pending := func() []string {
+		var ids []string
+		for _, parentId := range parents {
+			if len(parentId) == 0 {
+				continue
+			}
+			if _, ok := parents[parentId]; !ok && !utils.IsInStringArray(parentId, ids) {
+				ids = append(ids, parentId)
+			}
+		}
+		return ids
+	}
+	for i := 0; i <= maxCount; i++ {
+		ids := pending()
+		if len(ids) == 0 {
+			break
+		}
+		fetched, err := fetchParentIds(ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			// parent was removed, stop walking up
+			parents[id] = fetched[id]
+		}
+	}
+
+	ret := make(map[string]int, len(backups))
+	for _, backup := range backups {
+		length := 0
+		for cur := backup.ParentId; len(cur) > 0 && length <= maxCount; cur = parents[cur] {
+			length++
+		}
+		ret[backup.Id] = length
+	}
+	return ret, nil
+}
+
+func (manager *SDiskBackupManager) ListItemExportKeys(ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	keys stringutils2.SSortedStrings,
+) (*sqlchemy.SQuery, error) {
+	var err error
+	q, err = manager.SVirtualResourceBaseManager.ListItemExportKeys(ctx, q, userCred, keys)
+	if err != nil {
+		return nil, errors.Wrap(err, "SVirtualResourceBaseManager.ListItemExportKeys")
+	}
+	if keys.Contains("disk") {
+		q, err = manager.SDiskResourceBaseManager.ListItemExportKeys(ctx, q, userCred, stringutils2.NewSortedStrings([]string{"disk"}))
+		if err != nil {
+			return nil, errors.Wrap(err, "SDiskResourceBaseManager.ListItemExportKeys")
+		}
+	}
+	return q, nil
+}
+
+func (self *SDiskBackup) GetExtraDetails(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	isList bool,
+) (api.DiskBackupDetails, error) {
+	return api.DiskBackupDetails{}, nil
+}
+
+// GetBackupStorage returns object storage config of disk backups.  The
+// secret key is not sent along with backup and recovery requests, hosts
+// fetch it from backup-storage details with the service account
+func (manager *SDiskBackupManager) GetBackupStorage() host_api.DiskBackupStorage {
+	return host_api.DiskBackupStorage{
+		Endpoint:  options.Options.DiskBackupS3Endpoint,
+		AccessKey: options.Options.DiskBackupS3AccessKey,
+		SecretKey: options.Options.DiskBackupS3SecretKey,
+		Bucket:    options.Options.DiskBackupS3Bucket,
+		UseSSL:    options.Options.DiskBackupS3UseSSL,
+	}
+}
+
+func (manager *SDiskBackupManager) getDiskBackups(diskId string, status []string) ([]SDiskBackup, error) {
+	backups := make([]SDiskBackup, 0)
+	q := manager.Query().Equals("disk_id", diskId)
+	if len(status) > 0 {
+		q = q.In("status", status)
+	}
+	q = q.Desc("created_at")
+	err := db.FetchModelObjects(manager, q, &backups)
+	if err != nil {
+		return nil, errors.Wrap(err, "db.FetchModelObjects")
+	}
+	return backups, nil
+}
+
+// find the backup which a new incremental backup of the disk can be based on
+func (manager *SDiskBackupManager) getIncrementalParent(disk *SDisk) (*SDiskBackup, error) {
+	backups, err := manager.getDiskBackups(disk.Id, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(backups) == 0 {
+		return nil, errors.Wrap(httperrors.ErrNotFound, "no backup of disk")
+	}
+	latest := &backups[0]
+	chain, err := latest.GetBackupChain()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetBackupChain")
+	}
+	err = validateIncrementalParent(latest, disk, len(chain)-1, options.Options.DiskBackupMaxIncrementalCount)
+	if err != nil {
+		return nil, err
+	}
+	return latest, nil
+}
+
+// validateIncrementalParent checks whether a new incremental backup of disk
+// can be based on latest, the latest backup of the disk, whose chain already
+// holds chainLength incremental backups
+func validateIncrementalParent(latest *SDiskBackup, disk *SDisk, chainLength int, maxCount int) error {
+	// the latest backup must be ready, otherwise the dirty bitmap is not trustworthy
+	if latest.Status != api.DISK_BACKUP_STATUS_READY {
+		return errors.Wrapf(httperrors.ErrInvalidStatus, "latest backup %s status %s", latest.Name, latest.Status)
+	}
+	if latest.DiskSizeMb != disk.DiskSize {
+		return errors.Wrapf(httperrors.ErrInvalidStatus, "disk size changed since latest backup %s", latest.Name)
+	}
+	if len(latest.BitmapName) == 0 {
+		return errors.Wrapf(httperrors.ErrInvalidStatus, "latest backup %s has no dirty bitmap", latest.Name)
+	}
+	if chainLength >= maxCount {
+		return errors.Wrapf(httperrors.ErrOutOfLimit, "incremental backups exceed %d", maxCount)
+	}
+	return nil
+}
+
+func (manager *SDiskBackupManager) ValidateCreateData(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	ownerId mcclient.IIdentityProvider,
+	query jsonutils.JSONObject,
+	input api.DiskBackupCreateInput,
+) (api.DiskBackupCreateInput, error) {
+	if len(options.Options.DiskBackupS3Endpoint) == 0 {
+		return input, httperrors.NewNotSupportedError("object storage for disk backup is not configured")
+	}
+	for _, disk := range []string{input.Disk, input.DiskId} {
+		if len(disk) > 0 {
+			input.Disk = disk
+			break
+		}
+	}
+	if len(input.Disk) == 0 {
+		return input, httperrors.NewMissingParameterError("disk")
+	}
+	_disk, err := DiskManager.FetchByIdOrName(userCred, input.Disk)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return input, httperrors.NewResourceNotFoundError2(DiskManager.Keyword(), input.Disk)
+		}
+		return input, httperrors.NewGeneralError(errors.Wrap(err, "DiskManager.FetchByIdOrName"))
+	}
+	disk := _disk.(*SDisk)
+	if len(disk.ExternalId) > 0 {
+		return input, httperrors.NewNotSupportedError("backup of managed disk is not supported")
+	}
+	if disk.Status != api.DISK_READY {
+		return input, httperrors.NewInvalidStatusError("disk %s status is %s", disk.Name, disk.Status)
+	}
+	guests := disk.GetGuests()
+	if len(guests) != 1 {
+		return input, httperrors.NewUnsupportOperationError("disk %s should be attached to exactly one server", disk.Name)
+	}
+	guest := guests[0]
+	if guest.Hypervisor != api.HYPERVISOR_KVM {
+		return input, httperrors.NewNotSupportedError("backup of %s disk is not supported", guest.Hypervisor)
+	}
+	if !utils.IsInStringArray(guest.Status, []string{api.VM_RUNNING, api.VM_READY}) {
+		return input, httperrors.NewInvalidStatusError("server %s status is %s", guest.Name, guest.Status)
+	}
+	count, err := manager.Query().Equals("disk_id", disk.Id).Equals("status", api.DISK_BACKUP_STATUS_CREATING).CountWithError()
+	if err != nil {
+		return input, httperrors.NewGeneralError(err)
+	}
+	if count > 0 {
+		return input, httperrors.NewConflictError("disk %s has backup in progress", disk.Name)
+	}
+
+	if len(input.BackupMode) == 0 {
+		input.BackupMode = api.DISK_BACKUP_MODE_AUTO
+	}
+	switch input.BackupMode {
+	case api.DISK_BACKUP_MODE_FULL:
+	case api.DISK_BACKUP_MODE_INCREMENTAL, api.DISK_BACKUP_MODE_AUTO:
+		var parent *SDiskBackup
+		// dirty bitmaps only live in running qemu process
+		if guest.Status == api.VM_RUNNING {
+			parent, err = manager.getIncrementalParent(disk)
+		} else {
+			err = errors.Wrapf(httperrors.ErrInvalidStatus, "server %s is not running", guest.Name)
+		}
+		if err != nil {
+			if input.BackupMode == api.DISK_BACKUP_MODE_INCREMENTAL {
+				return input, httperrors.NewInputParameterError("unable to do incremental backup: %v", err)
+			}
+			log.Infof("disk %s fallback to full backup: %v", disk.Name, err)
+			input.BackupMode = api.DISK_BACKUP_MODE_FULL
+		} else {
+			input.BackupMode = api.DISK_BACKUP_MODE_INCREMENTAL
+			input.ParentId = parent.Id
+			input.BitmapName = parent.BitmapName
+		}
+	default:
+		return input, httperrors.NewInputParameterError("invalid backup_mode %s", input.BackupMode)
+	}
+
+	input.DiskId = disk.Id
+	input.StorageId = disk.StorageId
+	input.DiskSizeMb = disk.DiskSize
+	input.DiskType = disk.DiskType
+
+	input.VirtualResourceCreateInput, err = manager.SVirtualResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.VirtualResourceCreateInput)
+	if err != nil {
+		return input, err
+	}
+	return input, nil
+}
+
+func (self *SDiskBackup) CustomizeCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) error {
+	// use disk's ownerId instead of default ownerId
+	diskObj, err := DiskManager.FetchById(self.DiskId)
+	if err != nil {
+		return errors.Wrap(err, "DiskManager.FetchById")
+	}
+	ownerId = diskObj.(*SDisk).GetOwnerId()
+	return self.SVirtualResourceBase.CustomizeCreate(ctx, userCred, ownerId, query, data)
+}
+
+func (manager *SDiskBackupManager) OnCreateComplete(ctx context.Context, items []db.IModel, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) {
+	backup := items[0].(*SDiskBackup)
+	backup.StartDiskBackupCreateTask(ctx, userCred, "")
+}
+
+func (self *SDiskBackup) StartDiskBackupCreateTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	self.SetStatus(userCred, api.DISK_BACKUP_STATUS_CREATING, "")
+	task, err := taskman.TaskManager.NewTask(ctx, "DiskBackupCreateTask", self, userCred, nil, parentTaskId, "", nil)
+	if err != nil {
+		return err
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
+
+func (self *SDiskBackup) AllowUpdateItem(ctx context.Context, userCred mcclient.TokenCredential) bool {
+	return false
+}
+
+func (self *SDiskBackup) GetGuest() (*SGuest, error) {
+	disk := self.GetDisk()
+	if disk == nil {
+		return nil, errors.Wrapf(sql.ErrNoRows, "disk %s", self.DiskId)
+	}
+	guests := disk.GetGuests()
+	if len(guests) > 1 {
+		return nil, fmt.Errorf("Backup disk attach mutil guest")
+	} else if len(guests) == 1 {
+		return &guests[0], nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (self *SDiskBackup) GetParent() (*SDiskBackup, error) {
+	obj, err := DiskBackupManager.FetchById(self.ParentId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "DiskBackupManager.FetchById %s", self.ParentId)
+	}
+	return obj.(*SDiskBackup), nil
+}
+
+// GetBackupChain returns the backups required to restore this backup,
+// starting from the full backup and ending with this one
+func (self *SDiskBackup) GetBackupChain() ([]SDiskBackup, error) {
+	return backupChain(self, (*SDiskBackup).GetParent, options.Options.DiskBackupMaxIncrementalCount)
+}
+
+func backupChain(backup *SDiskBackup, getParent func(*SDiskBackup) (*SDiskBackup, error), maxCount int) ([]SDiskBackup, error) {
+	chain := []SDiskBackup{*backup}
+	cur := backup
+	for len(cur.ParentId) > 0 {
+		parent, err := getParent(cur)
+		if err != nil {
+			return nil, err
+		}
+		chain = append([]SDiskBackup{*parent}, chain...)
+		if len(chain) > maxCount+1 {
+			return nil, errors.Wrapf(httperrors.ErrOutOfLimit, "backup chain of %s too long", backup.Id)
+		}
+		cur = parent
+	}
+	return chain, nil
+}
+
+func (self *SDiskBackup) GetChildrenCount() (int, error) {
+	return DiskBackupManager.Query().Equals("parent_id", self.Id).CountWithError()
+}
+
+// only the service account, which hosts share with region, is allowed to
+// fetch credentials of the object storage
+func (self *SDiskBackup) AllowGetDetailsBackupStorage(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) bool {
+	return isServiceCredential(userCred)
+}
+
+func (self *SDiskBackup) GetDetailsBackupStorage(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(DiskBackupManager.GetBackupStorage()), nil
+}
+
+func (self *SDiskBackup) GetBackupRequest() *host_api.GuestDiskBackupRequest {
+	return &host_api.GuestDiskBackupRequest{
+		DiskId:     self.DiskId,
+		BackupId:   self.Id,
+		BackupMode: self.BackupMode,
+		BitmapName: self.BitmapName,
+	}
+}
+
+func isServiceCredential(userCred mcclient.TokenCredential) bool {
+	adminCred := auth.AdminCredential()
+	return userCred.GetUserId() == adminCred.GetUserId() && userCred.GetProjectId() == adminCred.GetProjectId()
+}
+
+func (self *SDiskBackup) GetRecoveryRequest() (*host_api.DiskRecoveryFromBackup, error) {
+	chain, err := self.GetBackupChain()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetBackupChain")
+	}
+	ret := &host_api.DiskRecoveryFromBackup{
+		Chain: make([]host_api.DiskBackupChainItem, len(chain)),
+	}
+	for i := range chain {
+		if chain[i].Status != api.DISK_BACKUP_STATUS_READY && chain[i].Id != self.Id {
+			return nil, errors.Wrapf(httperrors.ErrInvalidStatus, "backup %s status %s", chain[i].Name, chain[i].Status)
+		}
+		ret.Chain[i] = host_api.DiskBackupChainItem{
+			BackupId: chain[i].Id,
+			Location: chain[i].Location,
+		}
+	}
+	return ret, nil
+}
+
+// RemoveBackupObject removes backup file from object storage
+func (self *SDiskBackup) RemoveBackupObject() error {
+	if len(self.Location) == 0 {
+		return nil
+	}
+	storage := DiskBackupManager.GetBackupStorage()
+	client, err := minio.New(storage.Endpoint, storage.AccessKey, storage.SecretKey, storage.UseSSL)
+	if err != nil {
+		return errors.Wrap(err, "new minio client")
+	}
+	err = client.RemoveObject(storage.Bucket, self.Location)
+	if err != nil {
+		return errors.Wrapf(err, "remove object %s", self.Location)
+	}
+	return nil
+}
+
+func (self *SDiskBackup) ValidateDeleteCondition(ctx context.Context) error {
+	if utils.IsInStringArray(self.Status, []string{api.DISK_BACKUP_STATUS_CREATING, api.DISK_BACKUP_STATUS_DELETING, api.DISK_BACKUP_STATUS_RECOVERING}) {
+		return httperrors.NewInvalidStatusError("Cannot delete disk backup in status %s", self.Status)
+	}
+	count, err := self.GetChildrenCount()
+	if err != nil {
+		return httperrors.NewGeneralError(err)
+	}
+	if count > 0 {
+		return httperrors.NewNotEmptyError("disk backup has %d incremental backups depend on it", count)
+	}
+	return self.SVirtualResourceBase.ValidateDeleteCondition(ctx)
+}
+
+func (self *SDiskBackup) CustomizeDelete(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) error {
+	return self.StartDiskBackupDeleteTask(ctx, userCred, "")
+}
+
+func (self *SDiskBackup) Delete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	return nil
+}
+
+func (self *SDiskBackup) RealDelete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	return self.SVirtualResourceBase.Delete(ctx, userCred)
+}
+
+func (self *SDiskBackup) StartDiskBackupDeleteTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	self.SetStatus(userCred, api.DISK_BACKUP_STATUS_DELETING, "")
+	task, err := taskman.TaskManager.NewTask(ctx, "DiskBackupDeleteTask", self, userCred, nil, parentTaskId, "", nil)
+	if err != nil {
+		return err
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
+
+func (self *SDiskBackup) AllowPerformRecovery(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return self.IsOwner(userCred) || db.IsAdminAllowPerform(userCred, self, "recovery")
+}
+
+// 从备份恢复出一块新的磁盘
+func (self *SDiskBackup) PerformRecovery(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.DiskBackupRecoveryInput) (jsonutils.JSONObject, error) {
+	if self.Status != api.DISK_BACKUP_STATUS_READY {
+		return nil, httperrors.NewInvalidStatusError("Cannot recovery disk backup in status %s", self.Status)
+	}
+	if len(input.Storage) == 0 {
+		input.Storage = self.StorageId
+	}
+	if len(input.Storage) == 0 {
+		return nil, httperrors.NewMissingParameterError("storage")
+	}
+	storageObj, err := StorageManager.FetchByIdOrName(userCred, input.Storage)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, httperrors.NewResourceNotFoundError2(StorageManager.Keyword(), input.Storage)
+		}
+		return nil, httperrors.NewGeneralError(err)
+	}
+	storage := storageObj.(*SStorage)
+	if !utils.IsInStringArray(storage.StorageType, api.FIEL_STORAGE) {
+		return nil, httperrors.NewNotSupportedError("recovery disk backup to %s storage is not supported", storage.StorageType)
+	}
+	if !storage.Enabled.Bool() || storage.Status != api.STORAGE_ONLINE {
+		return nil, httperrors.NewInvalidStatusError("storage %s is not available", storage.Name)
+	}
+	host := storage.GetMasterHost()
+	if host == nil || host.HostType != api.HOST_TYPE_HYPERVISOR {
+		return nil, httperrors.NewNotSupportedError("no kvm host attached to storage %s", storage.Name)
+	}
+	if storage.GetFreeCapacity() < int64(self.DiskSizeMb) {
+		return nil, httperrors.NewOutOfResourceError("storage %s has not enough free capacity", storage.Name)
+	}
+	if _, err := self.GetRecoveryRequest(); err != nil {
+		return nil, httperrors.NewInvalidStatusError("%v", err)
+	}
+
+	ownerId := self.GetOwnerId()
+	name := input.Name
+	if len(name) == 0 {
+		name = fmt.Sprintf("%s-recovery", self.Name)
+	}
+	lockman.LockClass(ctx, DiskManager, db.GetLockClassKey(DiskManager, ownerId))
+	defer lockman.ReleaseClass(ctx, DiskManager, db.GetLockClassKey(DiskManager, ownerId))
+
+	name, err = db.GenerateName(DiskManager, ownerId, name)
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	diskConfig := &api.DiskConfig{
+		SizeMb:   self.DiskSizeMb,
+		Format:   "qcow2",
+		Backend:  storage.StorageType,
+		DiskType: self.DiskType,
+	}
+	disk, err := storage.createDisk(ctx, name, diskConfig, userCred, ownerId, false, false, billing_api.BILLING_TYPE_POSTPAID, "")
+	if err != nil {
+		return nil, httperrors.NewGeneralError(errors.Wrap(err, "storage.createDisk"))
+	}
+	err = self.StartDiskBackupRecoveryTask(ctx, userCred, disk.Id, "")
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	ret := jsonutils.NewDict()
+	ret.Set("disk_id", jsonutils.NewString(disk.Id))
+	return ret, nil
+}
+
+func (self *SDiskBackup) StartDiskBackupRecoveryTask(ctx context.Context, userCred mcclient.TokenCredential, diskId string, parentTaskId string) error {
+	params := jsonutils.NewDict()
+	params.Set("disk_id", jsonutils.NewString(diskId))
+	self.SetStatus(userCred, api.DISK_BACKUP_STATUS_RECOVERING, "")
+	task, err := taskman.TaskManager.NewTask(ctx, "DiskBackupRecoveryTask", self, userCred, params, parentTaskId, "", nil)
+	if err != nil {
+		return err
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
@@ -0,0 +1,169 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"database/sql"
+	"testing"
+
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/httperrors"
+)
+
+func newTestDiskBackup(id, parentId string) *SDiskBackup {
+	backup := &SDiskBackup{ParentId: parentId}
+	backup.Id = id
+	backup.Name = id
+	backup.Status = api.DISK_BACKUP_STATUS_READY
+	return backup
+}
+
+func TestBackupChainLengths(t *testing.T) {
+	// full <- inc1 <- inc2 <- inc3, inc1 of another chain has removed parent
+	stored := map[string]string{
+		"full":  "",
+		"inc1":  "full",
+		"inc2":  "inc1",
+		"inc3":  "inc2",
+		"other": "removed",
+	}
+	queries := 0
+	fetch := func(ids []string) (map[string]string, error) {
+		queries++
+		ret := map[string]string{}
+		for _, id := range ids {
+			if parentId, ok := stored[id]; ok {
+				ret[id] = parentId
+			}
+		}
+		return ret, nil
+	}
+	backups := []*SDiskBackup{
+		newTestDiskBackup("full", ""),
+		newTestDiskBackup("inc3", "inc2"),
+		newTestDiskBackup("inc1", "full"),
+		newTestDiskBackup("other", "removed"),
+	}
+	lengths, err := backupChainLengths(backups, fetch, 6)
+	if err != nil {
+		t.Fatalf("backupChainLengths: %v", err)
+	}
+	want := map[string]int{"full": 0, "inc1": 1, "inc3": 3, "other": 1}
+	for id, length := range want {
+		if lengths[id] != length {
+			t.Errorf("chain length of %s: want %d, got %d", id, length, lengths[id])
+		}
+	}
+	// inc2 and removed in the 1st round, inc1 is already known
+	if queries != 1 {
+		t.Errorf("want 1 query, got %d", queries)
+	}
+
+	t.Run("bounded by max count", func(t *testing.T) {
+		loop := map[string]string{"a": "b", "b": "a"}
+		fetch := func(ids []string) (map[string]string, error) {
+			ret := map[string]string{}
+			for _, id := range ids {
+				ret[id] = loop[id]
+			}
+			return ret, nil
+		}
+		lengths, err := backupChainLengths([]*SDiskBackup{newTestDiskBackup("a", "b")}, fetch, 3)
+		if err != nil {
+			t.Fatalf("backupChainLengths: %v", err)
+		}
+		if lengths["a"] != 4 {
+			t.Errorf("want length stopped at 4, got %d", lengths["a"])
+		}
+	})
+
+	t.Run("query error", func(t *testing.T) {
+		fetch := func(ids []string) (map[string]string, error) {
+			return nil, errors.Error("db down")
+		}
+		_, err := backupChainLengths([]*SDiskBackup{newTestDiskBackup("inc1", "full")}, fetch, 6)
+		if err == nil {
+			t.Errorf("want error")
+		}
+	})
+}
+
+func TestBackupChain(t *testing.T) {
+	backups := map[string]*SDiskBackup{}
+	for _, b := range []*SDiskBackup{
+		newTestDiskBackup("full", ""),
+		newTestDiskBackup("inc1", "full"),
+		newTestDiskBackup("inc2", "inc1"),
+		newTestDiskBackup("orphan", "removed"),
+	} {
+		backups[b.Id] = b
+	}
+	getParent := func(b *SDiskBackup) (*SDiskBackup, error) {
+		if parent, ok := backups[b.ParentId]; ok {
+			return parent, nil
+		}
+		return nil, sql.ErrNoRows
+	}
+
+	chain, err := backupChain(backups["inc2"], getParent, 6)
+	if err != nil {
+		t.Fatalf("backupChain: %v", err)
+	}
+	ids := []string{}
+	for i := range chain {
+		ids = append(ids, chain[i].Id)
+	}
+	if len(ids) != 3 || ids[0] != "full" || ids[1] != "inc1" || ids[2] != "inc2" {
+		t.Errorf("want chain full,inc1,inc2, got %v", ids)
+	}
+
+	if _, err := backupChain(backups["inc2"], getParent, 1); errors.Cause(err) != httperrors.ErrOutOfLimit {
+		t.Errorf("want out of limit, got %v", err)
+	}
+	if _, err := backupChain(backups["orphan"], getParent, 6); errors.Cause(err) != sql.ErrNoRows {
+		t.Errorf("want no rows, got %v", err)
+	}
+}
+
+func TestValidateIncrementalParent(t *testing.T) {
+	disk := &SDisk{DiskSize: 10240}
+	cases := []struct {
+		name        string
+		status      string
+		diskSizeMb  int
+		bitmapName  string
+		chainLength int
+		want        error
+	}{
+		{"ok", api.DISK_BACKUP_STATUS_READY, 10240, "bitmap", 0, nil},
+		{"ok before limit", api.DISK_BACKUP_STATUS_READY, 10240, "bitmap", 5, nil},
+		{"chain full", api.DISK_BACKUP_STATUS_READY, 10240, "bitmap", 6, httperrors.ErrOutOfLimit},
+		{"not ready", api.DISK_BACKUP_STATUS_CREATING, 10240, "bitmap", 0, httperrors.ErrInvalidStatus},
+		{"disk resized", api.DISK_BACKUP_STATUS_READY, 20480, "bitmap", 0, httperrors.ErrInvalidStatus},
+		{"no bitmap", api.DISK_BACKUP_STATUS_READY, 10240, "", 0, httperrors.ErrInvalidStatus},
+	}
+	for _, c := range cases {
+		latest := newTestDiskBackup("latest", "")
+		latest.Status = c.status
+		latest.DiskSizeMb = c.diskSizeMb
+		latest.BitmapName = c.bitmapName
+		err := validateIncrementalParent(latest, disk, c.chainLength, 6)
+		if errors.Cause(err) != c.want {
+			t.Errorf("%s: want %v, got %v", c.name, c.want, err)
+		}
+	}
+}
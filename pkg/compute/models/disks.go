@@ -1925,7 +1925,7 @@ func (self *SDisk) CustomizeDelete(ctx context.Context, userCred mcclient.TokenC
 			if cnt > 0 {
 				return httperrors.NewForbiddenError("not allow to delete. Virtual disk must not have snapshots")
 			}
-		} else if storage := self.GetStorage(); storage != nil && storage.StorageType == api.STORAGE_RBD {
+		} else if storage := self.GetStorage(); storage != nil && utils.IsInStringArray(storage.StorageType, []string{api.STORAGE_RBD, api.STORAGE_LVM}) {
 			scnt, err := self.GetSnapshotCount()
 			if err != nil {
 				return err
@@ -2291,6 +2291,18 @@ func (manager *SDiskManager) AutoDiskSnapshot(ctx context.Context, userCred mccl
 		return
 	}
 	now := time.Now()
+	quiesced := map[string][]string{}
+	defer func() {
+		for guestId, snapshotIds := range quiesced {
+			guest := GuestManager.FetchGuestById(guestId)
+			if guest == nil {
+				continue
+			}
+			if err := guest.StartQuiescedSnapshotsTask(ctx, userCred, snapshotIds, ""); err != nil {
+				log.Errorf("guest %s start quiesced snapshots task: %v", guest.Name, err)
+			}
+		}
+	}()
 	for i := 0; i < len(spds); i++ {
 		var (
 			disk                  = manager.FetchDiskById(spds[i].DiskId)
@@ -2306,7 +2318,7 @@ func (manager *SDiskManager) AutoDiskSnapshot(ctx context.Context, userCred mccl
 			goto onFail
 		}
 
-		if err = disk.CreateSnapshotAuto(ctx, userCred, snapshotName, snapshotPolicy); err != nil {
+		if err = disk.CreateSnapshotAuto(ctx, userCred, snapshotName, snapshotPolicy, quiesced); err != nil {
 			goto onFail
 		}
 
@@ -2332,9 +2344,13 @@ func (manager *SDiskManager) AutoDiskSnapshot(ctx context.Context, userCred mccl
 	}
 }
 
+// CreateSnapshotAuto creates snapshot of the disk for snapshot policy.
+// Snapshots to be quiesced of running guests are collected in quiesced by
+// guest id instead of being started, so that each guest is frozen once for
+// all its disks
 func (self *SDisk) CreateSnapshotAuto(
 	ctx context.Context, userCred mcclient.TokenCredential,
-	snapshotName string, snapshotPolicy *SSnapshotPolicy,
+	snapshotName string, snapshotPolicy *SSnapshotPolicy, quiesced map[string][]string,
 ) error {
 	snap, err := SnapshotManager.CreateSnapshot(ctx, self.GetOwnerId(), api.SNAPSHOT_AUTO,
 		self.Id, "", "", snapshotName, snapshotPolicy.RetentionDays)
@@ -2343,6 +2359,13 @@ func (self *SDisk) CreateSnapshotAuto(
 	}
 
 	db.OpsLog.LogEvent(snap, db.ACT_CREATE, "disk create snapshot auto", userCred)
+	if snapshotPolicy.Quiesce {
+		// filesystems of a stopped guest are consistent already
+		if guests := self.GetGuests(); len(guests) == 1 && guests[0].Status == api.VM_RUNNING {
+			quiesced[guests[0].Id] = append(quiesced[guests[0].Id], snap.Id)
+			return nil
+		}
+	}
 	err = snap.StartSnapshotCreateTask(ctx, userCred, nil, "")
 	if err != nil {
 		return errors.Wrap(err, "disk auto snapshot start snapshot task")
@@ -2457,7 +2480,7 @@ func (self *SDisk) IsNeedWaitSnapshotsDeleted() (bool, error) {
 	if storage == nil {
 		return false, fmt.Errorf("no valid storage")
 	}
-	if storage.StorageType == api.STORAGE_RBD {
+	if utils.IsInStringArray(storage.StorageType, []string{api.STORAGE_RBD, api.STORAGE_LVM}) {
 		scnt, err := self.GetSnapshotCount()
 		if err != nil {
 			return false, err
@@ -1285,23 +1285,34 @@ func (manager *SElasticipManager) NewEipForVMOnHost(ctx context.Context, userCre
 		eip.Name = fmt.Sprintf("eip-for-%s", nat.GetName())
 	}
 
-	if host != nil && host.ManagerId == "" { // kvm
+	if eip.ManagerId == "" { // kvm
 
-		hostq := HostManager.Query().SubQuery()
 		wireq := WireManager.Query().SubQuery()
-		hostwireq := HostwireManager.Query().SubQuery()
 		scope := policy.PolicyManager.AllowScope(userCred, consts.GetServiceType(), NetworkManager.KeywordPlural(), policy.PolicyActionList)
 		q := NetworkManager.Query()
 		q = NetworkManager.FilterByOwner(q, userCred, scope)
 		q = q.Join(wireq, sqlchemy.Equals(wireq.Field("id"), q.Field("wire_id")))
-		q = q.Join(hostwireq, sqlchemy.Equals(hostwireq.Field("wire_id"), wireq.Field("id")))
-		q = q.Join(hostq, sqlchemy.Equals(hostq.Field("id"), host.Id))
+		if host != nil {
+			hostq := HostManager.Query().SubQuery()
+			hostwireq := HostwireManager.Query().SubQuery()
+			q = q.Join(hostwireq, sqlchemy.Equals(hostwireq.Field("wire_id"), wireq.Field("id")))
+			q = q.Join(hostq, sqlchemy.Equals(hostq.Field("id"), host.Id))
+		} else {
+			// natgateway: eip networks of the same region
+			zoneq := ZoneManager.Query().SubQuery()
+			q = q.Join(zoneq, sqlchemy.Equals(zoneq.Field("id"), wireq.Field("zone_id")))
+			q = q.Filter(sqlchemy.Equals(zoneq.Field("cloudregion_id"), region.Id))
+		}
 		q = q.Equals("server_type", api.NETWORK_TYPE_EIP)
 		q = q.Equals("bgp_type", bgpType)
 		var nets []SNetwork
 		if err := db.FetchModelObjects(NetworkManager, q, &nets); err != nil {
-			return nil, errors.Wrapf(err, "fetch eip networks usable in host %s(%s)",
-				host.Name, host.Id)
+			if host != nil {
+				return nil, errors.Wrapf(err, "fetch eip networks usable in host %s(%s)",
+					host.Name, host.Id)
+			}
+			return nil, errors.Wrapf(err, "fetch eip networks usable in region %s(%s)",
+				region.Name, region.Id)
 		}
 		var net *SNetwork
 		for i := range nets {
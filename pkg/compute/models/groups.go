@@ -84,6 +84,18 @@ type SGroup struct {
 	// the upper limit number of guests with this group in a host
 	Granularity     int               `nullable:"false" list:"user" get:"user" create:"optional" update:"user" default:"1"`
 	ForceDispersion tristate.TriState `list:"user" get:"user" create:"optional" update:"user" default:"true"`
+
+	// 亲和性类型, 为空时按照Granularity分散调度
+	// enum: affinity, anti-affinity
+	AffinityType string `width:"16" charset:"ascii" nullable:"true" list:"user" update:"user" create:"optional"`
+	// 亲和性模式
+	// enum: hard, soft
+	AffinityMode string `width:"16" charset:"ascii" nullable:"true" list:"user" update:"user" create:"optional"`
+	// 亲和性拓扑域
+	// enum: host, rack, zone, storage, schedtag:<prefix>
+	TopologyKey string `width:"64" charset:"utf8" nullable:"true" list:"user" update:"user" create:"optional"`
+	// soft模式下的权重
+	AffinityWeight int `nullable:"false" default:"0" list:"user" update:"user" create:"optional"`
 	// 是否启用
 	// Enabled tristate.TriState `nullable:"false" default:"true" create:"optional" list:"user" update:"user"`
 }
@@ -129,10 +141,107 @@ func (sm *SGroupManager) ListItemFilter(
 	if len(input.SchedStrategy) > 0 {
 		q = q.Equals("sched_strategy", input.SchedStrategy)
 	}
+	if len(input.AffinityType) > 0 {
+		q = q.Equals("affinity_type", input.AffinityType)
+	}
 
 	return q, nil
 }
 
+func (sm *SGroupManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data *jsonutils.JSONDict) (*jsonutils.JSONDict, error) {
+	err := validateGroupAffinityData(data, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	input := apis.VirtualResourceCreateInput{}
+	err = data.Unmarshal(&input)
+	if err != nil {
+		return nil, httperrors.NewInternalServerError("unmarshal VirtualResourceCreateInput fail %s", err)
+	}
+	input, err = sm.SVirtualResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input)
+	if err != nil {
+		return nil, err
+	}
+	data.Update(jsonutils.Marshal(input))
+	return data, nil
+}
+
+func (group *SGroup) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data *jsonutils.JSONDict) (*jsonutils.JSONDict, error) {
+	err := validateGroupAffinityData(data, group)
+	if err != nil {
+		return nil, err
+	}
+
+	input := apis.VirtualResourceBaseUpdateInput{}
+	err = data.Unmarshal(&input)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+	input, err = group.SVirtualResourceBase.ValidateUpdateData(ctx, userCred, query, input)
+	if err != nil {
+		return nil, errors.Wrap(err, "SVirtualResourceBase.ValidateUpdateData")
+	}
+	data.Update(jsonutils.Marshal(input))
+	return data, nil
+}
+
+// validateGroupAffinityData checks the affinity columns of data, falling back to
+// the current values of group when they are not changed by an update
+func validateGroupAffinityData(data *jsonutils.JSONDict, group *SGroup) error {
+	rule := api.SchedAffinityRule{}
+	if group != nil {
+		rule = api.SchedAffinityRule{
+			Type:        group.AffinityType,
+			Mode:        group.AffinityMode,
+			TopologyKey: group.TopologyKey,
+			Weight:      group.AffinityWeight,
+		}
+	}
+	if data.Contains("affinity_type") {
+		rule.Type, _ = data.GetString("affinity_type")
+	}
+	if data.Contains("affinity_mode") {
+		rule.Mode, _ = data.GetString("affinity_mode")
+	}
+	if data.Contains("topology_key") {
+		rule.TopologyKey, _ = data.GetString("topology_key")
+	}
+	if data.Contains("affinity_weight") {
+		weight, _ := data.Int("affinity_weight")
+		rule.Weight = int(weight)
+	}
+	if len(rule.Type) == 0 {
+		// legacy group, scheduled by granularity
+		return nil
+	}
+	err := normalizeAffinityRule(&rule)
+	if err != nil {
+		return err
+	}
+	data.Set("affinity_mode", jsonutils.NewString(rule.Mode))
+	data.Set("topology_key", jsonutils.NewString(rule.TopologyKey))
+	data.Set("affinity_weight", jsonutils.NewInt(int64(rule.Weight)))
+	return nil
+}
+
+// IsAffinityGroup returns whether the guests of the group are scheduled by affinity rule
+// instead of the legacy granularity dispersion
+func (group *SGroup) IsAffinityGroup() bool {
+	return len(group.AffinityType) > 0
+}
+
+// ToAffinityRule converts an affinity group to a rule matching its member guests
+func (group *SGroup) ToAffinityRule() *api.SchedAffinityRule {
+	return &api.SchedAffinityRule{
+		Type:        group.AffinityType,
+		Mode:        group.AffinityMode,
+		TopologyKey: group.TopologyKey,
+		GroupId:     group.Id,
+		Weight:      group.AffinityWeight,
+	}
+}
+
 func (sm *SGroupManager) OrderByExtraFields(
 	ctx context.Context,
 	q *sqlchemy.SQuery,
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"path"
 	"strconv"
 	"strings"
 	"time"
@@ -40,6 +41,7 @@ import (
 	"yunion.io/x/onecloud/pkg/apis"
 	billing_api "yunion.io/x/onecloud/pkg/apis/billing"
 	api "yunion.io/x/onecloud/pkg/apis/compute"
+	host_api "yunion.io/x/onecloud/pkg/apis/host"
 	imageapi "yunion.io/x/onecloud/pkg/apis/image"
 	noapi "yunion.io/x/onecloud/pkg/apis/notify"
 	schedapi "yunion.io/x/onecloud/pkg/apis/scheduler"
@@ -463,6 +465,181 @@ func (self *SGuest) StartGuestLiveMigrateTask(ctx context.Context, userCred mccl
 	return nil
 }
 
+func (self *SGuest) AllowPerformChangeDiskStorage(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return db.IsAdminAllowPerform(userCred, self, "change-disk-storage")
+}
+
+// 在线迁移实例的一块磁盘到同一宿主机的其他存储
+func (self *SGuest) PerformChangeDiskStorage(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerChangeDiskStorageInput) (jsonutils.JSONObject, error) {
+	if self.Status != api.VM_RUNNING {
+		return nil, httperrors.NewInvalidStatusError("Cannot change disk storage in status %s", self.Status)
+	}
+	if len(input.Disk) == 0 {
+		return nil, httperrors.NewMissingParameterError("disk")
+	}
+	if len(input.TargetStorage) == 0 {
+		return nil, httperrors.NewMissingParameterError("target_storage")
+	}
+	diskObj, err := DiskManager.FetchByIdOrName(userCred, input.Disk)
+	if err != nil {
+		return nil, httperrors.NewResourceNotFoundError2(DiskManager.Keyword(), input.Disk)
+	}
+	disk := diskObj.(*SDisk)
+	if self.GetDiskIndex(disk.Id) < 0 {
+		return nil, httperrors.NewBadRequestError("Disk %s not attached to server %s", disk.Name, self.Name)
+	}
+	if disk.Status != api.DISK_READY {
+		return nil, httperrors.NewInvalidStatusError("Cannot change storage of disk in status %s", disk.Status)
+	}
+	// snapshots are kept on the source storage and can not follow the disk
+	cnt, err := disk.GetSnapshotCount()
+	if err != nil {
+		return nil, httperrors.NewInternalServerError("get disk snapshot count: %v", err)
+	}
+	if cnt > 0 {
+		return nil, httperrors.NewBadRequestError("Disk %s has %d snapshots, please delete them first", disk.Name, cnt)
+	}
+	storageObj, err := StorageManager.FetchByIdOrName(userCred, input.TargetStorage)
+	if err != nil {
+		return nil, httperrors.NewResourceNotFoundError2(StorageManager.Keyword(), input.TargetStorage)
+	}
+	storage := storageObj.(*SStorage)
+	if storage.Id == disk.StorageId {
+		return nil, httperrors.NewBadRequestError("Disk %s already on storage %s", disk.Name, storage.Name)
+	}
+	host := self.GetHost()
+	if host == nil || host.GetHoststorageOfId(storage.Id) == nil {
+		return nil, httperrors.NewBadRequestError("Storage %s not attached to host of server %s", storage.Name, self.Name)
+	}
+	if storage.Enabled.IsFalse() || storage.Status != api.STORAGE_ONLINE {
+		return nil, httperrors.NewInvalidStatusError("Storage %s is not enabled or online", storage.Name)
+	}
+	if storage.GetFreeCapacity() < int64(disk.DiskSize) {
+		return nil, httperrors.NewInsufficientResourceError("Storage %s free capacity %dM less than disk size %dM", storage.Name, storage.GetFreeCapacity(), disk.DiskSize)
+	}
+	if err := self.GetDriver().ValidateChangeDiskStorage(ctx, userCred, self, disk, storage); err != nil {
+		return nil, err
+	}
+	return nil, self.StartChangeDiskStorageTask(ctx, userCred, disk, storage, "")
+}
+
+func (self *SGuest) StartChangeDiskStorageTask(ctx context.Context, userCred mcclient.TokenCredential, disk *SDisk, targetStorage *SStorage, parentTaskId string) error {
+	data := jsonutils.NewDict()
+	data.Set("disk_id", jsonutils.NewString(disk.Id))
+	data.Set("source_storage_id", jsonutils.NewString(disk.StorageId))
+	data.Set("target_storage_id", jsonutils.NewString(targetStorage.Id))
+	data.Set("guest_status", jsonutils.NewString(self.Status))
+	task, err := taskman.TaskManager.NewTask(ctx, "GuestChangeDiskStorageTask", self, userCred, data, parentTaskId, "", nil)
+	if err != nil {
+		return err
+	}
+	self.SetStatus(userCred, api.VM_DISK_CHANGE_STORAGE, "")
+	disk.SetStatus(userCred, api.DISK_MIGRATING, "")
+	task.ScheduleRun(nil)
+	return nil
+}
+
+func (self *SGuest) AllowPerformAttachVirtiofs(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return db.IsAdminAllowPerform(userCred, self, "attach-virtiofs")
+}
+
+// 通过virtio-fs将宿主机目录或共享存储上的目录共享给虚拟机, 虚拟机下次启动后生效
+func (self *SGuest) PerformAttachVirtiofs(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerAttachVirtiofsInput) (jsonutils.JSONObject, error) {
+	if self.Hypervisor != api.HYPERVISOR_KVM {
+		return nil, httperrors.NewNotAcceptableError("Not allow for hypervisor %s", self.Hypervisor)
+	}
+	if self.Status != api.VM_READY {
+		return nil, httperrors.NewInvalidStatusError("Cannot attach virtiofs in status %s", self.Status)
+	}
+	if len(input.Tag) == 0 {
+		return nil, httperrors.NewMissingParameterError("tag")
+	}
+	if len(input.Tag) > 36 || !regutils.MatchName(input.Tag) {
+		return nil, httperrors.NewInputParameterError("invalid tag %s", input.Tag)
+	}
+	if len(input.SourcePath) == 0 {
+		return nil, httperrors.NewMissingParameterError("source_path")
+	}
+	if _, err := self.getVirtiofsByTag(input.Tag); err == nil {
+		return nil, httperrors.NewDuplicateResourceError("virtiofs tag %s already exists", input.Tag)
+	} else if errors.Cause(err) != errors.ErrNotFound {
+		return nil, httperrors.NewGeneralError(err)
+	}
+
+	share := &SGuestVirtiofs{
+		GuestId:  self.Id,
+		Tag:      input.Tag,
+		Readonly: input.Readonly,
+	}
+	if len(input.Storage) > 0 {
+		storageObj, err := StorageManager.FetchByIdOrName(userCred, input.Storage)
+		if err != nil {
+			return nil, httperrors.NewResourceNotFoundError2(StorageManager.Keyword(), input.Storage)
+		}
+		storage := storageObj.(*SStorage)
+		if !utils.IsInStringArray(storage.StorageType, api.SHARED_FILE_STORAGE) {
+			return nil, httperrors.NewUnsupportOperationError("Storage type %s not support virtiofs", storage.StorageType)
+		}
+		host := self.GetHost()
+		if host == nil || host.GetHoststorageOfId(storage.Id) == nil {
+			return nil, httperrors.NewBadRequestError("Storage %s not attached to host of server %s", storage.Name, self.Name)
+		}
+		sourcePath, err := cleanVirtiofsPath(input.SourcePath)
+		if err != nil {
+			return nil, httperrors.NewInputParameterError("invalid source_path %s", input.SourcePath)
+		}
+		share.StorageId = storage.Id
+		share.SourcePath = sourcePath
+	} else {
+		if !path.IsAbs(input.SourcePath) {
+			return nil, httperrors.NewInputParameterError("source_path %s must be an absolute path of host", input.SourcePath)
+		}
+		sourcePath, err := cleanVirtiofsPath(input.SourcePath)
+		if err != nil {
+			return nil, httperrors.NewInputParameterError("invalid source_path %s", input.SourcePath)
+		}
+		if !isVirtiofsHostPathAllowed(sourcePath) {
+			return nil, httperrors.NewForbiddenError("source_path %s is not under virtiofs host path roots", sourcePath)
+		}
+		share.SourcePath = sourcePath
+	}
+	share.SetModelManager(GuestVirtiofsManager, share)
+	err := GuestVirtiofsManager.TableSpec().Insert(ctx, share)
+	if err != nil {
+		return nil, httperrors.NewGeneralError(errors.Wrap(err, "insert virtiofs"))
+	}
+	db.OpsLog.LogEvent(self, db.ACT_ATTACH, share.Tag, userCred)
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_ATTACH_VIRTIOFS, input, userCred, true)
+	return nil, nil
+}
+
+func (self *SGuest) AllowPerformDetachVirtiofs(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return db.IsAdminAllowPerform(userCred, self, "detach-virtiofs")
+}
+
+func (self *SGuest) PerformDetachVirtiofs(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerDetachVirtiofsInput) (jsonutils.JSONObject, error) {
+	if self.Status != api.VM_READY {
+		return nil, httperrors.NewInvalidStatusError("Cannot detach virtiofs in status %s", self.Status)
+	}
+	if len(input.Tag) == 0 {
+		return nil, httperrors.NewMissingParameterError("tag")
+	}
+	share, err := self.getVirtiofsByTag(input.Tag)
+	if err != nil {
+		if errors.Cause(err) == errors.ErrNotFound {
+			return nil, httperrors.NewResourceNotFoundError2("virtiofs", input.Tag)
+		}
+		return nil, httperrors.NewGeneralError(err)
+	}
+	err = db.DeleteModel(ctx, userCred, share)
+	if err != nil {
+		return nil, httperrors.NewGeneralError(errors.Wrap(err, "delete virtiofs"))
+	}
+	db.OpsLog.LogEvent(self, db.ACT_DETACH, share.Tag, userCred)
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_DETACH_VIRTIOFS, input, userCred, true)
+	return nil, nil
+}
+
 func (self *SGuest) AllowPerformClone(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
 	return self.IsOwner(userCred) || db.IsAdminAllowPerform(userCred, self, "clone")
 }
@@ -2936,6 +3113,190 @@ func (self *SGuest) SendMonitorCommand(ctx context.Context, userCred mcclient.To
 	return ret, nil
 }
 
+func (self *SGuest) requestGuestAgent(ctx context.Context, userCred mcclient.TokenCredential, action string, body jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	if self.Hypervisor != api.HYPERVISOR_KVM {
+		return nil, httperrors.NewNotAcceptableError("Not allow for hypervisor %s", self.Hypervisor)
+	}
+	if self.Status != api.VM_RUNNING {
+		return nil, httperrors.NewInvalidStatusError("Cannot talk to guest agent in status %s", self.Status)
+	}
+	return self.doRequestGuestAgent(ctx, userCred, action, body)
+}
+
+// doRequestGuestAgent skips the status check, tasks call it while guest is in a transient status
+func (self *SGuest) doRequestGuestAgent(ctx context.Context, userCred mcclient.TokenCredential, action string, body jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	host := self.GetHost()
+	if host == nil {
+		return nil, httperrors.NewInternalServerError("guest %s host not found", self.Name)
+	}
+	url := fmt.Sprintf("%s/servers/%s/%s", host.ManagerUri, self.Id, action)
+	header := http.Header{}
+	header.Add("X-Auth-Token", userCred.GetTokenString())
+	if body == nil {
+		body = jsonutils.NewDict()
+	}
+	_, res, err := httputils.JSONRequest(httputils.GetDefaultClient(), ctx, "POST", url, header, body, false)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// GuestAgentFsFreeze freeze guest filesystems before taking snapshots,
+// the guest agent runs the fsfreeze hooks inside the guest
+func (self *SGuest) GuestAgentFsFreeze(ctx context.Context, userCred mcclient.TokenCredential) (int, error) {
+	res, err := self.doRequestGuestAgent(ctx, userCred, "qga-fsfreeze", nil)
+	if err != nil {
+		return 0, err
+	}
+	ret := host_api.GuestQgaFsFreezeResponse{}
+	if res != nil {
+		res.Unmarshal(&ret)
+	}
+	return ret.Count, nil
+}
+
+// GuestAgentFsThaw returns the number of thawed filesystems, which is 0 if
+// the host has thawed them already because they were frozen too long
+func (self *SGuest) GuestAgentFsThaw(ctx context.Context, userCred mcclient.TokenCredential) (int, error) {
+	res, err := self.doRequestGuestAgent(ctx, userCred, "qga-fsthaw", nil)
+	if err != nil {
+		return 0, err
+	}
+	ret := host_api.GuestQgaFsFreezeResponse{}
+	if res != nil {
+		res.Unmarshal(&ret)
+	}
+	return ret.Count, nil
+}
+
+// StartQuiescedSnapshotsTask freeze the guest once and take the snapshots
+// of its disks one by one
+func (self *SGuest) StartQuiescedSnapshotsTask(ctx context.Context, userCred mcclient.TokenCredential, snapshotIds []string, parentTaskId string) error {
+	params := jsonutils.NewDict()
+	params.Set("snapshot_ids", jsonutils.NewStringArray(snapshotIds))
+	params.Set("quiesce", jsonutils.JSONTrue)
+	task, err := taskman.TaskManager.NewTask(ctx, "GuestQuiescedSnapshotsTask", self, userCred, params, parentTaskId, "", nil)
+	if err != nil {
+		return err
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
+
+func (self *SGuest) AllowPerformQgaFsfreeze(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return db.IsAdminAllowPerform(userCred, self, "qga-fsfreeze")
+}
+
+// 通过qemu-guest-agent冻结虚拟机内的文件系统
+func (self *SGuest) PerformQgaFsfreeze(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	return self.requestGuestAgent(ctx, userCred, "qga-fsfreeze", nil)
+}
+
+func (self *SGuest) AllowPerformQgaFsthaw(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return db.IsAdminAllowPerform(userCred, self, "qga-fsthaw")
+}
+
+// 通过qemu-guest-agent解冻虚拟机内的文件系统
+func (self *SGuest) PerformQgaFsthaw(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	return self.requestGuestAgent(ctx, userCred, "qga-fsthaw", nil)
+}
+
+func (self *SGuest) AllowPerformQgaSetPassword(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return self.IsOwner(userCred) || db.IsAdminAllowPerform(userCred, self, "qga-set-password")
+}
+
+// 通过qemu-guest-agent在线修改虚拟机内用户密码, 无需重启虚拟机
+func (self *SGuest) PerformQgaSetPassword(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerQgaSetPasswordInput) (jsonutils.JSONObject, error) {
+	if len(input.Username) == 0 {
+		input.Username = self.GetMetadata(api.VM_METADATA_LOGIN_ACCOUNT, userCred)
+		if len(input.Username) == 0 {
+			return nil, httperrors.NewMissingParameterError("username")
+		}
+	}
+	if len(input.Password) == 0 {
+		return nil, httperrors.NewMissingParameterError("password")
+	}
+	err := seclib2.ValidatePassword(input.Password)
+	if err != nil {
+		return nil, err
+	}
+	body := jsonutils.Marshal(&host_api.GuestQgaSetPasswordRequest{
+		Username: input.Username,
+		Password: input.Password,
+	})
+	_, err = self.requestGuestAgent(ctx, userCred, "qga-set-password", body)
+	if err != nil {
+		logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_RESET_PSWD, err, userCred, false)
+		return nil, err
+	}
+
+	var key string
+	if len(self.KeypairId) > 0 {
+		key, err = seclib2.EncryptBase64(self.GetKeypairPublicKey(), input.Password)
+	} else {
+		key, err = utils.EncryptAESBase64(self.Id, input.Password)
+	}
+	if err != nil {
+		return nil, httperrors.NewInternalServerError("encrypt password: %v", err)
+	}
+	self.saveOldPassword(ctx, userCred)
+	info := jsonutils.NewDict()
+	info.Set("account", jsonutils.NewString(input.Username))
+	info.Set("key", jsonutils.NewString(key))
+	self.SaveDeployInfo(ctx, userCred, info)
+	db.OpsLog.LogEvent(self, db.ACT_RESET_PASSWORD, input.Username, userCred)
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_VM_RESET_PSWD, input.Username, userCred, true)
+	return nil, nil
+}
+
+func (self *SGuest) AllowPerformQgaGuestInfo(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return self.IsOwner(userCred) || db.IsAdminAllowPerform(userCred, self, "qga-guest-info")
+}
+
+// 通过qemu-guest-agent获取虚拟机内的网卡地址及操作系统信息
+func (self *SGuest) PerformQgaGuestInfo(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	return self.requestGuestAgent(ctx, userCred, "qga-guest-info", nil)
+}
+
+func (self *SGuest) AllowPerformQgaFileRead(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return db.IsAdminAllowPerform(userCred, self, "qga-file-read")
+}
+
+// 通过qemu-guest-agent读取虚拟机内的文件
+func (self *SGuest) PerformQgaFileRead(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerQgaFileReadInput) (jsonutils.JSONObject, error) {
+	if len(input.Path) == 0 {
+		return nil, httperrors.NewMissingParameterError("path")
+	}
+	body := jsonutils.Marshal(&host_api.GuestQgaFileReadRequest{
+		Path:    input.Path,
+		MaxSize: input.MaxSize,
+	})
+	return self.requestGuestAgent(ctx, userCred, "qga-file-read", body)
+}
+
+func (self *SGuest) AllowPerformQgaFileWrite(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return db.IsAdminAllowPerform(userCred, self, "qga-file-write")
+}
+
+// 通过qemu-guest-agent写入虚拟机内的文件
+func (self *SGuest) PerformQgaFileWrite(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerQgaFileWriteInput) (jsonutils.JSONObject, error) {
+	if len(input.Path) == 0 {
+		return nil, httperrors.NewMissingParameterError("path")
+	}
+	body := jsonutils.Marshal(&host_api.GuestQgaFileWriteRequest{
+		Path:    input.Path,
+		Content: input.Content,
+		Append:  input.Append,
+	})
+	_, err := self.requestGuestAgent(ctx, userCred, "qga-file-write", body)
+	if err != nil {
+		return nil, err
+	}
+	db.OpsLog.LogEvent(self, db.ACT_UPDATE, fmt.Sprintf("qga write file %s", input.Path), userCred)
+	return nil, nil
+}
+
 func (self *SGuest) AllowPerformAssociateEip(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
 	return self.IsOwner(userCred) || db.IsAdminAllowPerform(userCred, self, "associate-eip")
 }
@@ -4669,6 +5030,10 @@ func (self *SGuest) validateCreateInstanceSnapshot(
 		return nil, httperrors.NewInvalidStatusError("guest can't do snapshot in status %s", self.Status)
 	}
 
+	if jsonutils.QueryBoolean(data, "quiesce", false) && self.Hypervisor != api.HYPERVISOR_KVM {
+		return nil, httperrors.NewBadRequestError("guest hypervisor %s can't create quiesced instance snapshot", self.Hypervisor)
+	}
+
 	var name string
 	ownerId := self.GetOwnerId()
 	dataDict := data.(*jsonutils.JSONDict)
@@ -4737,7 +5102,9 @@ func (self *SGuest) PerformInstanceSnapshot(
 			ctx, userCred, pendingUsage, pendingUsage, false)
 		return nil, httperrors.NewInternalServerError("create instance snapshot failed: %s", err)
 	}
-	err = self.InstaceCreateSnapshot(ctx, userCred, instanceSnapshot, pendingUsage)
+	// freeze guest filesystems only when the guest is running
+	quiesce := jsonutils.QueryBoolean(data, "quiesce", false) && self.Status == api.VM_RUNNING
+	err = self.InstaceCreateSnapshot(ctx, userCred, instanceSnapshot, pendingUsage, quiesce)
 	if err != nil {
 		quotas.CancelPendingUsage(
 			ctx, userCred, pendingUsage, pendingUsage, false)
@@ -4751,9 +5118,15 @@ func (self *SGuest) InstaceCreateSnapshot(
 	userCred mcclient.TokenCredential,
 	instanceSnapshot *SInstanceSnapshot,
 	pendingUsage *SRegionQuota,
+	quiesce bool,
 ) error {
+	params := jsonutils.NewDict()
+	if quiesce {
+		params.Set("quiesce", jsonutils.JSONTrue)
+	}
+	params.Set("guest_status", jsonutils.NewString(self.Status))
 	self.SetStatus(userCred, api.VM_START_INSTANCE_SNAPSHOT, "instance snapshot")
-	return instanceSnapshot.StartCreateInstanceSnapshotTask(ctx, userCred, pendingUsage, "")
+	return instanceSnapshot.StartCreateInstanceSnapshotTask(ctx, userCred, pendingUsage, params, "")
 }
 
 func (self *SGuest) AllowPerformInstanceSnapshotReset(ctx context.Context,
@@ -5254,3 +5627,65 @@ func (self *SGuest) PerformListForward(ctx context.Context, userCred mcclient.To
 	}
 	return resp.JSON(), nil
 }
+
+func (self *SGuest) AllowPerformSetHaPolicy(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return db.IsAdminAllowPerform(userCred, self, "set-ha-policy")
+}
+
+// 设置宿主机宕机后虚拟机的自动重启策略
+func (self *SGuest) PerformSetHaPolicy(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerSetHaPolicyInput) (jsonutils.JSONObject, error) {
+	if input.MaxRestarts != nil && *input.MaxRestarts < 1 {
+		return nil, httperrors.NewInputParameterError("max_restarts must be greater than 0")
+	}
+	policy := map[string]interface{}{}
+	if input.Priority != nil {
+		policy[api.HA_PRIORITY] = *input.Priority
+	}
+	if input.Disabled != nil {
+		policy[api.HA_DISABLED] = *input.Disabled
+	}
+	if input.MaxRestarts != nil {
+		policy[api.HA_MAX_RESTARTS] = *input.MaxRestarts
+	}
+	if len(policy) == 0 {
+		return nil, nil
+	}
+	err := self.SetAllMetadata(ctx, policy, userCred)
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	return nil, nil
+}
+
+func (self *SGuest) getHaPriority() int {
+	priority, _ := strconv.Atoi(self.GetMetadata(api.HA_PRIORITY, nil))
+	return priority
+}
+
+func (self *SGuest) isHaDisabled() bool {
+	return self.GetMetadata(api.HA_DISABLED, nil) == "true"
+}
+
+func (self *SGuest) getHaMaxRestarts() int {
+	maxRestarts, _ := strconv.Atoi(self.GetMetadata(api.HA_MAX_RESTARTS, nil))
+	if maxRestarts <= 0 {
+		return options.Options.HaRestartMaxAttempts
+	}
+	return maxRestarts
+}
+
+func (self *SGuest) AllowPerformSetHostDrainPolicy(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return db.IsAdminAllowPerform(userCred, self, "set-host-drain-policy")
+}
+
+// 设置宿主机进入维护模式时虚拟机的排空策略
+func (self *SGuest) PerformSetHostDrainPolicy(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerSetHostDrainPolicyInput) (jsonutils.JSONObject, error) {
+	if len(input.Policy) > 0 && !utils.IsInStringArray(input.Policy, api.HOST_DRAIN_POLICIES) {
+		return nil, httperrors.NewInputParameterError("invalid policy %s, want %s", input.Policy, api.HOST_DRAIN_POLICIES)
+	}
+	err := self.SetMetadata(ctx, api.HOST_DRAIN_POLICY, input.Policy, userCred)
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	return nil, nil
+}
@@ -0,0 +1,267 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+// normalizeAffinityRule fills the defaults of rule and checks its type, mode and topology key
+func normalizeAffinityRule(rule *api.SchedAffinityRule) error {
+	if !utils.IsInStringArray(rule.Type, api.AFFINITY_TYPES) {
+		return httperrors.NewInputParameterError("invalid affinity type %q, must be one of %s", rule.Type, api.AFFINITY_TYPES)
+	}
+	if len(rule.Mode) == 0 {
+		rule.Mode = api.AFFINITY_MODE_HARD
+	}
+	if !utils.IsInStringArray(rule.Mode, api.AFFINITY_MODES) {
+		return httperrors.NewInputParameterError("invalid affinity mode %q, must be one of %s", rule.Mode, api.AFFINITY_MODES)
+	}
+	if len(rule.TopologyKey) == 0 {
+		rule.TopologyKey = api.AFFINITY_TOPOLOGY_HOST
+	}
+	if strings.HasPrefix(rule.TopologyKey, api.AFFINITY_TOPOLOGY_SCHEDTAG_PREFIX) {
+		if len(rule.TopologyKey) == len(api.AFFINITY_TOPOLOGY_SCHEDTAG_PREFIX) {
+			return httperrors.NewInputParameterError("empty schedtag prefix in topology key %q", rule.TopologyKey)
+		}
+	} else if !utils.IsInStringArray(rule.TopologyKey, api.AFFINITY_TOPOLOGY_KEYS) {
+		return httperrors.NewInputParameterError("invalid topology key %q, must be one of %s or %s<prefix>", rule.TopologyKey, api.AFFINITY_TOPOLOGY_KEYS, api.AFFINITY_TOPOLOGY_SCHEDTAG_PREFIX)
+	}
+	if rule.Weight < 0 {
+		return httperrors.NewInputParameterError("negative affinity weight %d", rule.Weight)
+	}
+	if rule.Mode == api.AFFINITY_MODE_SOFT && rule.Weight == 0 {
+		rule.Weight = api.AFFINITY_DEFAULT_WEIGHT
+	}
+	return nil
+}
+
+// ValidateAffinityRules checks the affinity rules of a server create request and
+// resolves the group names of the rules to ids
+func ValidateAffinityRules(userCred mcclient.TokenCredential, rules []*api.SchedAffinityRule) error {
+	for i, rule := range rules {
+		if rule == nil {
+			return httperrors.NewInputParameterError("empty affinity rule %d", i)
+		}
+		err := normalizeAffinityRule(rule)
+		if err != nil {
+			return err
+		}
+		if len(rule.GroupId) == 0 && len(rule.Labels) == 0 {
+			return httperrors.NewInputParameterError("affinity rule %d requires group_id or labels", i)
+		}
+		if len(rule.GroupId) > 0 {
+			model, err := GroupManager.FetchByIdOrName(userCred, rule.GroupId)
+			if err != nil {
+				if errors.Cause(err) == sqlchemy.ErrEmptyQuery {
+					return httperrors.NewResourceNotFoundError2(GroupManager.Keyword(), rule.GroupId)
+				}
+				return httperrors.NewGeneralError(err)
+			}
+			rule.GroupId = model.GetId()
+		}
+		for k := range rule.Labels {
+			if len(k) == 0 {
+				return httperrors.NewInputParameterError("empty label key in affinity rule %d", i)
+			}
+		}
+	}
+	return nil
+}
+
+// GetAffinityRules returns the affinity rules the guest was created with
+func (guest *SGuest) GetAffinityRules() []*api.SchedAffinityRule {
+	rules := make([]*api.SchedAffinityRule, 0)
+	meta := guest.GetMetadataJson(api.VM_METADATA_AFFINITY_RULES, nil)
+	if meta == nil {
+		return rules
+	}
+	err := meta.Unmarshal(&rules)
+	if err != nil {
+		log.Errorf("unmarshal affinity rules of guest %s: %v", guest.Name, err)
+	}
+	return rules
+}
+
+func (guest *SGuest) setAffinityRules(ctx context.Context, userCred mcclient.TokenCredential, rules []*api.SchedAffinityRule) {
+	err := guest.SetMetadata(ctx, api.VM_METADATA_AFFINITY_RULES, jsonutils.Marshal(rules), userCred)
+	if err != nil {
+		log.Errorf("set affinity rules of guest %s: %v", guest.Name, err)
+	}
+}
+
+func affinityLabelKey(key string) string {
+	if strings.Contains(key, ":") {
+		return key
+	}
+	return db.USER_TAG_PREFIX + key
+}
+
+// FetchAffinityGuests returns the placed guests matched by rule, excluding the guests of excludeIds
+func FetchAffinityGuests(rule *api.SchedAffinityRule, excludeIds []string) ([]SGuest, error) {
+	q := GuestManager.Query().IsNotEmpty("host_id")
+	if len(excludeIds) > 0 {
+		q = q.NotIn("id", excludeIds)
+	}
+	if len(rule.GroupId) > 0 {
+		sq := GroupguestManager.Query("guest_id").Equals("group_id", rule.GroupId).SubQuery()
+		q = q.In("id", sq)
+	}
+	for k, v := range rule.Labels {
+		sq := db.Metadata.Query("obj_id").Equals("obj_type", GuestManager.Keyword()).
+			Equals("key", affinityLabelKey(k)).Equals("value", v).SubQuery()
+		q = q.In("id", sq)
+	}
+	guests := make([]SGuest, 0)
+	err := db.FetchModelObjects(GuestManager, q, &guests)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	return guests, nil
+}
+
+// AffinityDomains returns the domains of topologyKey a placement on host with
+// the storages of storageIds belongs to
+func AffinityDomains(topologyKey string, host *SHost, storageIds []string, schedtags []SSchedtag) []string {
+	switch {
+	case topologyKey == api.AFFINITY_TOPOLOGY_HOST:
+		return []string{host.Id}
+	case topologyKey == api.AFFINITY_TOPOLOGY_RACK:
+		if len(host.Rack) == 0 {
+			return nil
+		}
+		return []string{host.Rack}
+	case topologyKey == api.AFFINITY_TOPOLOGY_ZONE:
+		if len(host.ZoneId) == 0 {
+			return nil
+		}
+		return []string{host.ZoneId}
+	case topologyKey == api.AFFINITY_TOPOLOGY_STORAGE:
+		return storageIds
+	case strings.HasPrefix(topologyKey, api.AFFINITY_TOPOLOGY_SCHEDTAG_PREFIX):
+		prefix := topologyKey[len(api.AFFINITY_TOPOLOGY_SCHEDTAG_PREFIX):]
+		domains := make([]string, 0)
+		for i := range schedtags {
+			if strings.HasPrefix(schedtags[i].Name, prefix) {
+				domains = append(domains, schedtags[i].Id)
+			}
+		}
+		return domains
+	}
+	return nil
+}
+
+// GetAffinityDomains returns the domains of topologyKey the guest is placed in
+func (guest *SGuest) GetAffinityDomains(topologyKey string) []string {
+	host := guest.GetHost()
+	if host == nil {
+		return nil
+	}
+	storageIds := make([]string, 0)
+	if topologyKey == api.AFFINITY_TOPOLOGY_STORAGE {
+		for _, gd := range guest.GetDisks() {
+			disk := gd.GetDisk()
+			if disk != nil && !utils.IsInStringArray(disk.StorageId, storageIds) {
+				storageIds = append(storageIds, disk.StorageId)
+			}
+		}
+	}
+	var schedtags []SSchedtag
+	if strings.HasPrefix(topologyKey, api.AFFINITY_TOPOLOGY_SCHEDTAG_PREFIX) {
+		schedtags = host.GetSchedtags()
+	}
+	return AffinityDomains(topologyKey, host, storageIds, schedtags)
+}
+
+// MatchAffinityRule tells whether a guest in groupIds with the metadata labels is matched by rule
+func MatchAffinityRule(rule *api.SchedAffinityRule, groupIds []string, labels map[string]string) bool {
+	if len(rule.GroupId) == 0 && len(rule.Labels) == 0 {
+		return false
+	}
+	if len(rule.GroupId) > 0 && !utils.IsInStringArray(rule.GroupId, groupIds) {
+		return false
+	}
+	normalized := make(map[string]string, len(labels))
+	for k, v := range labels {
+		normalized[affinityLabelKey(k)] = v
+	}
+	for k, v := range rule.Labels {
+		if val, ok := normalized[affinityLabelKey(k)]; !ok || val != v {
+			return false
+		}
+	}
+	return true
+}
+
+// FetchAntiAffinityDomains returns the count of placed guests in each domain, keyed by topology key,
+// whose hard anti-affinity rules match the guest of guestId, in groupIds and with the metadata labels.
+// guestId is empty for a guest not created yet.
+func FetchAntiAffinityDomains(guestId string, groupIds []string, labels map[string]string, excludeIds []string) (map[string]map[string]int, error) {
+	if len(guestId) > 0 {
+		guest := GuestManager.FetchGuestById(guestId)
+		if guest != nil {
+			meta, err := db.Metadata.GetAll(guest, nil, "", nil)
+			if err != nil {
+				return nil, errors.Wrap(err, "fetch metadata")
+			}
+			for k, v := range labels {
+				meta[k] = v
+			}
+			labels = meta
+		}
+	}
+	q := GuestManager.Query().IsNotEmpty("host_id")
+	if len(excludeIds) > 0 {
+		q = q.NotIn("id", excludeIds)
+	}
+	sq := db.Metadata.Query("obj_id").Equals("obj_type", GuestManager.Keyword()).
+		Equals("key", api.VM_METADATA_AFFINITY_RULES).SubQuery()
+	q = q.In("id", sq)
+	guests := make([]SGuest, 0)
+	err := db.FetchModelObjects(GuestManager, q, &guests)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	counts := make(map[string]map[string]int)
+	for i := range guests {
+		for _, rule := range guests[i].GetAffinityRules() {
+			if rule.Type != api.AFFINITY_TYPE_ANTI_AFFINITY || rule.Mode != api.AFFINITY_MODE_HARD {
+				continue
+			}
+			if !MatchAffinityRule(rule, groupIds, labels) {
+				continue
+			}
+			if _, ok := counts[rule.TopologyKey]; !ok {
+				counts[rule.TopologyKey] = make(map[string]int)
+			}
+			for _, domain := range guests[i].GetAffinityDomains(rule.TopologyKey) {
+				counts[rule.TopologyKey][domain]++
+			}
+		}
+	}
+	return counts, nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func TestMatchAffinityRule(t *testing.T) {
+	cases := []struct {
+		name     string
+		rule     *api.SchedAffinityRule
+		groupIds []string
+		labels   map[string]string
+		want     bool
+	}{
+		{
+			name:     "in group",
+			rule:     &api.SchedAffinityRule{GroupId: "g1"},
+			groupIds: []string{"g0", "g1"},
+			want:     true,
+		},
+		{
+			name:     "not in group",
+			rule:     &api.SchedAffinityRule{GroupId: "g1"},
+			groupIds: []string{"g2"},
+			want:     false,
+		},
+		{
+			name:   "user tag without prefix",
+			rule:   &api.SchedAffinityRule{Labels: map[string]string{"app": "db"}},
+			labels: map[string]string{"user:app": "db", "user:env": "prod"},
+			want:   true,
+		},
+		{
+			name:   "label of other value",
+			rule:   &api.SchedAffinityRule{Labels: map[string]string{"app": "db"}},
+			labels: map[string]string{"app": "web"},
+			want:   false,
+		},
+		{
+			name:     "group and labels",
+			rule:     &api.SchedAffinityRule{GroupId: "g1", Labels: map[string]string{"user:app": "db"}},
+			groupIds: []string{"g1"},
+			labels:   map[string]string{"env": "prod"},
+			want:     false,
+		},
+		{
+			name: "empty rule matches nothing",
+			rule: &api.SchedAffinityRule{},
+			want: false,
+		},
+	}
+	for _, c := range cases {
+		if got := MatchAffinityRule(c.rule, c.groupIds, c.labels); got != c.want {
+			t.Errorf("%s: want %v, got %v", c.name, c.want, got)
+		}
+	}
+}
@@ -365,6 +365,17 @@ func (self *SGuest) GetRealIPs() []string {
 	return nil
 }
 
+// GetClassicIPs returns the ips of guest which are not in vpc
+func (self *SGuest) GetClassicIPs(ctx context.Context) []string {
+	ips := make([]string, 0)
+	for _, nic := range self.fetchNICShortDesc(ctx) {
+		if len(nic.IpAddr) > 0 && (len(nic.VpcId) == 0 || nic.VpcId == api.DEFAULT_VPC_ID) {
+			ips = append(ips, nic.IpAddr)
+		}
+	}
+	return ips
+}
+
 func (self *SGuest) fetchNICShortDesc(ctx context.Context) []api.GuestnetworkShortDesc {
 	nicsMap := fetchGuestNICs(ctx, []string{self.Id}, tristate.False)
 	if nicsMap == nil {
@@ -0,0 +1,160 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+// +onecloud:swagger-gen-ignore
+type SGuestVirtiofsManager struct {
+	db.SResourceBaseManager
+}
+
+var GuestVirtiofsManager *SGuestVirtiofsManager
+
+func init() {
+	GuestVirtiofsManager = &SGuestVirtiofsManager{
+		SResourceBaseManager: db.NewResourceBaseManager(
+			SGuestVirtiofs{},
+			"guest_virtiofs_tbl",
+			"guest_virtiofs",
+			"guest_virtiofses",
+		),
+	}
+	GuestVirtiofsManager.SetVirtualObject(GuestVirtiofsManager)
+	GuestVirtiofsManager.TableSpec().AddIndex(true, "guest_id", "tag")
+}
+
+// +onecloud:swagger-gen-ignore
+type SGuestVirtiofs struct {
+	db.SResourceBase
+
+	// 自增Id
+	Id int64 `primary:"true" auto_increment:"true" list:"user"`
+	// 虚拟机Id
+	GuestId string `width:"36" charset:"ascii" nullable:"false" list:"user" index:"true"`
+	// 挂载标签
+	Tag string `width:"36" charset:"ascii" nullable:"false" list:"user"`
+	// 共享存储Id, 为空时SourcePath为宿主机目录
+	StorageId string `width:"36" charset:"ascii" nullable:"true" list:"user"`
+	// 共享目录路径
+	SourcePath string `width:"256" charset:"utf8" nullable:"false" list:"user"`
+	// 是否只读
+	Readonly bool `nullable:"false" default:"false" list:"user"`
+}
+
+func (manager *SGuestVirtiofsManager) fetchByGuestId(guestId string) ([]SGuestVirtiofs, error) {
+	q := manager.Query().Equals("guest_id", guestId).Asc("id")
+	shares := make([]SGuestVirtiofs, 0)
+	err := db.FetchModelObjects(manager, q, &shares)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	return shares, nil
+}
+
+// cleanVirtiofsPath rejects paths with parent directory elements and
+// returns the cleaned absolute path
+func cleanVirtiofsPath(p string) (string, error) {
+	for _, elem := range strings.Split(p, "/") {
+		if elem == ".." {
+			return "", errors.Wrapf(httperrors.ErrInputParameter, "parent directory in path %s", p)
+		}
+	}
+	return path.Clean("/" + p), nil
+}
+
+// isVirtiofsHostPathAllowed checks that the host path is under one of
+// the share roots configured by admin
+func isVirtiofsHostPathAllowed(hostPath string) bool {
+	for _, root := range options.Options.VirtiofsHostPathRoots {
+		root = path.Clean(root)
+		if !path.IsAbs(root) || root == "/" {
+			continue
+		}
+		if hostPath == root || strings.HasPrefix(hostPath, root+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// getHostPath returns the directory exported by virtiofsd on the host
+func (self *SGuestVirtiofs) getHostPath(host *SHost) (string, error) {
+	if len(self.StorageId) == 0 {
+		return self.SourcePath, nil
+	}
+	hs := host.GetHoststorageOfId(self.StorageId)
+	if hs == nil {
+		return "", errors.Wrapf(errors.ErrNotFound, "storage %s not attached to host %s", self.StorageId, host.Name)
+	}
+	return path.Join(hs.MountPoint, self.SourcePath), nil
+}
+
+func (self *SGuestVirtiofs) getDesc(host *SHost) (jsonutils.JSONObject, error) {
+	hostPath, err := self.getHostPath(host)
+	if err != nil {
+		return nil, err
+	}
+	desc := jsonutils.NewDict()
+	desc.Add(jsonutils.NewString(self.Tag), "tag")
+	desc.Add(jsonutils.NewString(hostPath), "path")
+	if self.Readonly {
+		desc.Add(jsonutils.JSONTrue, "readonly")
+	}
+	return desc, nil
+}
+
+func (self *SGuest) GetVirtiofs() ([]SGuestVirtiofs, error) {
+	return GuestVirtiofsManager.fetchByGuestId(self.Id)
+}
+
+func (self *SGuest) getVirtiofsByTag(tag string) (*SGuestVirtiofs, error) {
+	shares, err := self.GetVirtiofs()
+	if err != nil {
+		return nil, err
+	}
+	for i := range shares {
+		if shares[i].Tag == tag {
+			return &shares[i], nil
+		}
+	}
+	return nil, errors.ErrNotFound
+}
+
+func (self *SGuest) DetachAllVirtiofs(ctx context.Context, userCred mcclient.TokenCredential) error {
+	shares, err := self.GetVirtiofs()
+	if err != nil {
+		return err
+	}
+	for i := range shares {
+		err = db.DeleteModel(ctx, userCred, &shares[i])
+		if err != nil {
+			return errors.Wrapf(err, "delete virtiofs %s", shares[i].Tag)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+
+	"yunion.io/x/onecloud/pkg/compute/options"
+)
+
+func TestVirtiofsSourcePath(t *testing.T) {
+	options.Options.VirtiofsHostPathRoots = []string{"/data/shares", "/opt/export/", "/", "relative"}
+	defer func() {
+		options.Options.VirtiofsHostPathRoots = nil
+	}()
+
+	cases := []struct {
+		in      string
+		cleaned string
+		invalid bool
+		allowed bool
+	}{
+		{in: "/data/shares", cleaned: "/data/shares", allowed: true},
+		{in: "/data/shares/a//b/", cleaned: "/data/shares/a/b", allowed: true},
+		{in: "/opt/export/x", cleaned: "/opt/export/x", allowed: true},
+		{in: "/data/shares2", cleaned: "/data/shares2"},
+		{in: "/etc", cleaned: "/etc"},
+		{in: "/data/shares/../../etc", invalid: true},
+		{in: "/data/shares/..", invalid: true},
+		{in: "/data/shares/a..b", cleaned: "/data/shares/a..b", allowed: true},
+		{in: "relative/dir", cleaned: "/relative/dir"},
+	}
+	for _, c := range cases {
+		cleaned, err := cleanVirtiofsPath(c.in)
+		if c.invalid {
+			if err == nil {
+				t.Errorf("%s: want error, got %s", c.in, cleaned)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", c.in, err)
+			continue
+		}
+		if cleaned != c.cleaned {
+			t.Errorf("%s: want cleaned %s, got %s", c.in, c.cleaned, cleaned)
+		}
+		if allowed := isVirtiofsHostPathAllowed(cleaned); allowed != c.allowed {
+			t.Errorf("%s: want allowed %v, got %v", c.in, c.allowed, allowed)
+		}
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"yunion.io/x/jsonutils"
 
 	api "yunion.io/x/onecloud/pkg/apis/compute"
+	host_api "yunion.io/x/onecloud/pkg/apis/host"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/quotas"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
 	"yunion.io/x/onecloud/pkg/cloudprovider"
@@ -174,6 +175,9 @@ type IGuestDriver interface {
 	RequestDeleteSnapshot(ctx context.Context, guest *SGuest, task taskman.ITask, params *jsonutils.JSONDict) error
 	RequestReloadDiskSnapshot(ctx context.Context, guest *SGuest, task taskman.ITask, params *jsonutils.JSONDict) error
 	RequestSyncToBackup(ctx context.Context, guest *SGuest, task taskman.ITask) error
+	RequestDiskBackup(ctx context.Context, guest *SGuest, task taskman.ITask, input *host_api.GuestDiskBackupRequest) error
+	ValidateChangeDiskStorage(ctx context.Context, userCred mcclient.TokenCredential, guest *SGuest, disk *SDisk, targetStorage *SStorage) error
+	RequestChangeDiskStorage(ctx context.Context, guest *SGuest, task taskman.ITask, input *host_api.GuestChangeDiskStorageRequest) error
 
 	IsSupportEip() bool
 	IsSupportPublicIp() bool
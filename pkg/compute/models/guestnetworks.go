@@ -242,6 +242,7 @@ type newGuestNetworkArgs struct {
 	index int8
 
 	ipAddr              string
+	ip6Addr             string
 	allocDir            api.IPAllocationDirection
 	tryReserved         bool
 	requireDesignatedIP bool
@@ -330,6 +331,15 @@ func (manager *SGuestnetworkManager) newGuestNetwork(
 			gn.IpAddr = ipAddr
 		}
 
+		if network.IsIPv6Enabled() {
+			addrTable6 := network.GetUsedAddresses6()
+			ip6Addr, err := network.getFreeIP6(addrTable6, args.ip6Addr, macAddr, allocDir)
+			if err != nil {
+				return nil, errors.Wrap(err, "getFreeIP6")
+			}
+			gn.Ip6Addr = ip6Addr
+		}
+
 		if vpc := network.GetVpc(); vpc == nil {
 			return nil, fmt.Errorf("cannot find vpc of network %s(%s)", network.Id, network.Name)
 		} else if vpc.Id != api.DEFAULT_VPC_ID && vpc.GetProviderName() == api.CLOUD_PROVIDER_ONECLOUD {
@@ -578,6 +588,15 @@ func (self *SGuestnetwork) getJsonDesc(network *SNetwork) *jsonutils.JSONDict {
 	desc.Add(jsonutils.NewInt(int64(self.getBandwidth())), "bw")
 	desc.Add(jsonutils.NewInt(int64(self.getMtu(network))), "mtu")
 	desc.Add(jsonutils.NewInt(int64(self.Index)), "index")
+	if len(self.Ip6Addr) > 0 && network.IsIPv6Enabled() {
+		desc.Add(jsonutils.NewString(self.Ip6Addr), "ip6")
+		desc.Add(jsonutils.NewInt(int64(network.GuestIp6Mask)), "masklen6")
+		desc.Add(jsonutils.NewString(network.GetGateway6()), "gateway6")
+		if len(network.GuestDns6) > 0 {
+			desc.Add(jsonutils.NewString(network.GuestDns6), "dns6")
+		}
+		desc.Add(jsonutils.NewString(network.GetIp6Mode()), "ip6_mode")
+	}
 	vips := self.GetVirtualIPs()
 	if len(vips) > 0 {
 		desc.Add(jsonutils.NewStringArray(vips), "virtual_ips")
@@ -603,7 +622,10 @@ func (manager *SGuestnetworkManager) GetGuestByAddress(address string) *SGuest {
 	guests := GuestManager.Query()
 	q := guests.Join(networks, sqlchemy.AND(
 		sqlchemy.IsFalse(networks.Field("deleted")),
-		sqlchemy.Equals(networks.Field("ip_addr"), address),
+		sqlchemy.OR(
+			sqlchemy.Equals(networks.Field("ip_addr"), address),
+			sqlchemy.Equals(networks.Field("ip6_addr"), address),
+		),
 		sqlchemy.Equals(networks.Field("guest_id"), guests.Field("id")),
 	))
 	guest := &SGuest{}
@@ -19,6 +19,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"net"
 	"regexp"
 	"strings"
 	"time"
@@ -131,6 +132,10 @@ type SGuest struct {
 	Vdi     string `width:"36" charset:"ascii" nullable:"true" list:"user" update:"user" create:"optional"`
 	Machine string `width:"36" charset:"ascii" nullable:"true" list:"user" update:"user" create:"optional"`
 	Bios    string `width:"36" charset:"ascii" nullable:"true" list:"user" update:"user" create:"optional"`
+	// 是否启用vTPM(TPM 2.0)设备
+	Vtpm bool `nullable:"false" default:"false" list:"user" create:"optional"`
+	// 是否启用UEFI安全启动
+	SecureBoot bool `nullable:"false" default:"false" list:"user" create:"optional"`
 	// 操作系统类型
 	OsType string `width:"36" charset:"ascii" nullable:"true" list:"user" update:"user" create:"optional"`
 
@@ -746,6 +751,19 @@ func (guest *SGuest) GetDisks() []SGuestdisk {
 	return disks
 }
 
+func (guest *SGuest) HasLVMDisks() bool {
+	for _, guestdisk := range guest.GetDisks() {
+		disk := guestdisk.GetDisk()
+		if disk == nil {
+			continue
+		}
+		if storage := disk.GetStorage(); storage != nil && storage.StorageType == api.STORAGE_LVM {
+			return true
+		}
+	}
+	return false
+}
+
 func (guest *SGuest) GetGuestDisk(diskId string) *SGuestdisk {
 	guestdisk, err := db.NewModelObject(GuestdiskManager)
 	if err != nil {
@@ -1166,6 +1184,14 @@ func (manager *SGuestManager) validateCreateData(
 		input.InstanceGroupIds = newGroupIds
 	}
 
+	// check affinity rules
+	if len(input.AffinityRules) > 0 {
+		err = ValidateAffinityRules(userCred, input.AffinityRules)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// check that all image of disk is the part of guest imgae, if use guest image to create guest
 	err = manager.checkGuestImage(ctx, input)
 	if err != nil {
@@ -1254,6 +1280,18 @@ func (manager *SGuestManager) validateCreateData(
 	}
 
 	hypervisor = input.Hypervisor
+	if input.Vtpm || input.SecureBoot {
+		if hypervisor != api.HYPERVISOR_KVM {
+			return nil, httperrors.NewInputParameterError("vtpm and secure_boot only supported by hypervisor %s", api.HYPERVISOR_KVM)
+		}
+		if input.SecureBoot {
+			if len(input.Bios) == 0 {
+				input.Bios = "UEFI"
+			} else if input.Bios != "UEFI" {
+				return nil, httperrors.NewInputParameterError("secure_boot requires UEFI bios")
+			}
+		}
+	}
 	if hypervisor != api.HYPERVISOR_CONTAINER {
 		// support sku here
 		var sku *SServerSku
@@ -1814,6 +1852,15 @@ func (guest *SGuest) PostCreate(ctx context.Context, userCred mcclient.TokenCred
 	}
 	guest.setApptags(ctx, appTags, userCred)
 	guest.SetCreateParams(ctx, userCred, data)
+	if data.Contains("affinity_rules") {
+		rules := make([]*api.SchedAffinityRule, 0)
+		err := data.Unmarshal(&rules, "affinity_rules")
+		if err != nil {
+			log.Errorf("unmarshal affinity rules: %v", err)
+		} else if len(rules) > 0 {
+			guest.setAffinityRules(ctx, userCred, rules)
+		}
+	}
 	osProfileJson, _ := data.Get("__os_profile__")
 	if osProfileJson != nil {
 		guest.setOSProfile(ctx, userCred, osProfileJson)
@@ -2741,6 +2788,7 @@ type Attach2NetworkArgs struct {
 	Network *SNetwork
 
 	IpAddr              string
+	Ip6Addr             string
 	AllocDir            api.IPAllocationDirection
 	TryReserved         bool
 	RequireDesignatedIP bool
@@ -2763,6 +2811,7 @@ func (args *Attach2NetworkArgs) onceArgs(i int) attach2NetworkOnceArgs {
 		network: args.Network,
 
 		ipAddr:              args.IpAddr,
+		ip6Addr:             args.Ip6Addr,
 		allocDir:            args.AllocDir,
 		tryReserved:         args.TryReserved,
 		requireDesignatedIP: args.RequireDesignatedIP,
@@ -2778,6 +2827,7 @@ func (args *Attach2NetworkArgs) onceArgs(i int) attach2NetworkOnceArgs {
 	}
 	if i > 0 {
 		r.ipAddr = ""
+		r.ip6Addr = ""
 		r.bwLimit = 0
 		r.virtual = true
 		r.tryReserved = false
@@ -2793,6 +2843,7 @@ type attach2NetworkOnceArgs struct {
 	network *SNetwork
 
 	ipAddr              string
+	ip6Addr             string
 	allocDir            api.IPAllocationDirection
 	tryReserved         bool
 	requireDesignatedIP bool
@@ -2861,6 +2912,7 @@ func (self *SGuest) attach2NetworkOnce(
 		index: index,
 
 		ipAddr:              args.ipAddr,
+		ip6Addr:             args.ip6Addr,
 		allocDir:            args.allocDir,
 		tryReserved:         args.tryReserved,
 		requireDesignatedIP: args.requireDesignatedIP,
@@ -3471,6 +3523,7 @@ func (self *SGuest) attach2NamedNetworkDesc(ctx context.Context, userCred mcclie
 			Network:             net,
 			PendingUsage:        pendingUsage,
 			IpAddr:              netConfig.Address,
+			Ip6Addr:             netConfig.Address6,
 			NicDriver:           netConfig.Driver,
 			BwLimit:             netConfig.BwLimit,
 			Virtual:             netConfig.Vip,
@@ -3952,6 +4005,10 @@ func (self *SGuest) GetVdi() string {
 }
 
 func (self *SGuest) getMachine() string {
+	if self.SecureBoot {
+		// secure boot relies on SMM which is only emulated by q35
+		return "q35"
+	}
 	if utils.IsInStringArray(self.Machine, []string{"pc", "q35"}) {
 		return self.Machine
 	}
@@ -3987,6 +4044,12 @@ func (self *SGuest) GetJsonDescAtHypervisor(ctx context.Context, host *SHost) *j
 	desc.Add(jsonutils.NewString(self.GetVdi()), "vdi")
 	desc.Add(jsonutils.NewString(self.getMachine()), "machine")
 	desc.Add(jsonutils.NewString(self.getBios()), "bios")
+	if self.Vtpm {
+		desc.Add(jsonutils.JSONTrue, "vtpm")
+	}
+	if self.SecureBoot {
+		desc.Add(jsonutils.JSONTrue, "secure_boot")
+	}
 	desc.Add(jsonutils.NewString(self.BootOrder), "boot_order")
 
 	desc.Add(jsonutils.NewBool(self.SrcIpCheck.Bool()), "src_ip_check")
@@ -4040,6 +4103,24 @@ func (self *SGuest) GetJsonDescAtHypervisor(ctx context.Context, host *SHost) *j
 		}
 	}
 
+	// virtio-fs shares
+	shares, err := self.GetVirtiofs()
+	if err != nil {
+		log.Errorf("guest %s get virtiofs: %v", self.Name, err)
+	}
+	jsonShares := make([]jsonutils.JSONObject, 0)
+	for i := range shares {
+		shareDesc, err := shares[i].getDesc(host)
+		if err != nil {
+			log.Errorf("guest %s virtiofs %s desc: %v", self.Name, shares[i].Tag, err)
+			continue
+		}
+		jsonShares = append(jsonShares, shareDesc)
+	}
+	if len(jsonShares) > 0 {
+		desc.Add(jsonutils.NewArray(jsonShares...), "virtiofs")
+	}
+
 	// tenant
 	tc, _ := self.GetTenantCache(ctx)
 	if tc != nil {
@@ -4548,7 +4629,7 @@ func (manager *SGuestManager) GetIpInProjectWithName(projectId, name string, isE
 	guestnics := GuestnetworkManager.Query().SubQuery()
 	guests := manager.Query().SubQuery()
 	networks := NetworkManager.Query().SubQuery()
-	q := guestnics.Query(guestnics.Field("ip_addr")).Join(guests,
+	q := guestnics.Query(guestnics.Field("ip_addr"), guestnics.Field("ip6_addr")).Join(guests,
 		sqlchemy.AND(
 			sqlchemy.Equals(guests.Field("id"), guestnics.Field("guest_id")),
 			sqlchemy.OR(sqlchemy.IsNull(guests.Field("pending_deleted")),
@@ -4565,24 +4646,32 @@ func (manager *SGuestManager) GetIpInProjectWithName(projectId, name string, isE
 		return ips
 	}
 	defer rows.Close()
+	ip6s := make([]string, 0)
 	for rows.Next() {
-		var ip string
-		err = rows.Scan(&ip)
+		var (
+			ip  string
+			ip6 sql.NullString
+		)
+		err = rows.Scan(&ip, &ip6)
 		if err != nil {
 			log.Errorf("Get guest ip with name scan err: %v", err)
 			return ips
 		}
 		ips = append(ips, ip)
+		if ip6.Valid && len(ip6.String) > 0 {
+			ip6s = append(ip6s, ip6.String)
+		}
 	}
-	return manager.getIpsByExit(ips, isExitOnly)
+	// choose among addresses of the same family, so that A and AAAA
+	// queries are answered independently
+	return append(manager.getIpsByExit(ips, isExitOnly), manager.getIpsByExit(ip6s, isExitOnly)...)
 }
 
 func (manager *SGuestManager) getIpsByExit(ips []string, isExitOnly bool) []string {
 	intRet := make([]string, 0)
 	extRet := make([]string, 0)
 	for _, ip := range ips {
-		addr, _ := netutils.NewIPV4Addr(ip)
-		if netutils.IsExitAddress(addr) {
+		if isExitIpAddress(ip) {
 			extRet = append(extRet, ip)
 			continue
 		}
@@ -4596,6 +4685,21 @@ func (manager *SGuestManager) getIpsByExit(ips []string, isExitOnly bool) []stri
 	return extRet
 }
 
+// isExitIpAddress tells whether ip is a public address, unique local and
+// link local ipv6 addresses are considered internal
+func isExitIpAddress(ip string) bool {
+	if regutils.MatchIP6Addr(ip) {
+		addr := net.ParseIP(ip)
+		if addr == nil || addr.IsLinkLocalUnicast() || addr.IsLoopback() {
+			return false
+		}
+		// fc00::/7
+		return addr[0]&0xfe != 0xfc
+	}
+	addr, _ := netutils.NewIPV4Addr(ip)
+	return netutils.IsExitAddress(addr)
+}
+
 func (manager *SGuestManager) getExpiredPendingDeleteGuests() []SGuest {
 	deadline := time.Now().Add(time.Duration(options.Options.PendingDeleteExpireSeconds*-1) * time.Second)
 
@@ -5119,6 +5223,7 @@ func (self *SGuest) FillGroupSchedDesc(desc *api.ServerConfigs) {
 		groupids[i] = groups[i].GroupId
 	}
 	desc.InstanceGroupIds = groupids
+	desc.AffinityRules = self.GetAffinityRules()
 }
 
 func (self *SGuest) FillDiskSchedDesc(desc *api.ServerConfigs) {
@@ -5247,6 +5352,8 @@ func (self *SGuest) ToCreateInput(userCred mcclient.TokenCredential) *api.Server
 	userInput.Vga = genInput.Vga
 	userInput.Vdi = genInput.Vdi
 	userInput.Bios = genInput.Bios
+	userInput.Vtpm = genInput.Vtpm
+	userInput.SecureBoot = genInput.SecureBoot
 	userInput.Cdrom = genInput.Cdrom
 	userInput.Description = genInput.Description
 	userInput.BootOrder = genInput.BootOrder
@@ -5299,6 +5406,8 @@ func (self *SGuest) toCreateInput() *api.ServerCreateInput {
 	r.Vga = self.Vga
 	r.Vdi = self.Vdi
 	r.Bios = self.Bios
+	r.Vtpm = self.Vtpm
+	r.SecureBoot = self.SecureBoot
 	r.Description = self.Description
 	r.BootOrder = self.BootOrder
 	r.DisableDelete = new(bool)
@@ -0,0 +1,207 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+// getGuestDrainPolicy returns the policy specified in input, then the policy
+// of the guest itself, then the default policy of input
+func (host *SHost) getGuestDrainPolicy(guest *SGuest, input *api.HostMaintenanceInput) string {
+	for _, key := range []string{guest.Id, guest.Name} {
+		if policy, ok := input.GuestPolicies[key]; ok {
+			return policy
+		}
+	}
+	if policy := guest.GetMetadata(api.HOST_DRAIN_POLICY, nil); utils.IsInStringArray(policy, api.HOST_DRAIN_POLICIES) {
+		return policy
+	}
+	if len(input.DrainPolicy) > 0 {
+		return input.DrainPolicy
+	}
+	if guest.Status == api.VM_RUNNING {
+		return api.HOST_DRAIN_POLICY_LIVE_MIGRATE
+	}
+	return api.HOST_DRAIN_POLICY_COLD_MIGRATE
+}
+
+func (host *SHost) validateHostMaintenanceInput(input *api.HostMaintenanceInput) error {
+	if len(input.DrainPolicy) > 0 && !utils.IsInStringArray(input.DrainPolicy, api.HOST_DRAIN_POLICIES) {
+		return httperrors.NewInputParameterError("invalid drain_policy %s, want %s", input.DrainPolicy, api.HOST_DRAIN_POLICIES)
+	}
+	for guest, policy := range input.GuestPolicies {
+		if !utils.IsInStringArray(policy, api.HOST_DRAIN_POLICIES) {
+			return httperrors.NewInputParameterError("invalid drain policy %s of guest %s, want %s", policy, guest, api.HOST_DRAIN_POLICIES)
+		}
+	}
+	if input.Concurrency <= 0 {
+		input.Concurrency = 1
+	}
+	return nil
+}
+
+func (host *SHost) GetDrainProgress() *api.HostDrainProgress {
+	val := host.GetMetadata(api.HOST_METADATA_DRAIN_PROGRESS, nil)
+	if len(val) == 0 {
+		return nil
+	}
+	obj, err := jsonutils.ParseString(val)
+	if err != nil {
+		return nil
+	}
+	progress := &api.HostDrainProgress{}
+	if err := obj.Unmarshal(progress); err != nil {
+		return nil
+	}
+	return progress
+}
+
+// SetDrainProgress reports draining progress on host
+func (host *SHost) SetDrainProgress(ctx context.Context, userCred mcclient.TokenCredential, guests []api.HostDrainGuest) error {
+	progress := api.HostDrainProgress{
+		Total:  len(guests),
+		Guests: guests,
+	}
+	for i := range guests {
+		switch guests[i].Status {
+		case api.HOST_DRAIN_GUEST_PENDING:
+			progress.Pending += 1
+		case api.HOST_DRAIN_GUEST_DRAINING:
+			progress.Draining += 1
+		case api.HOST_DRAIN_GUEST_DONE:
+			progress.Done += 1
+		case api.HOST_DRAIN_GUEST_FAILED:
+			progress.Failed += 1
+		case api.HOST_DRAIN_GUEST_SKIPPED:
+			progress.Skipped += 1
+		}
+	}
+	return host.SetMetadata(ctx, api.HOST_METADATA_DRAIN_PROGRESS, jsonutils.Marshal(progress).String(), userCred)
+}
+
+// GuestHasBlockJobs checks the running block jobs such as mirror or stream,
+// guest must not be migrated until they finished
+func (host *SHost) GuestHasBlockJobs(ctx context.Context, userCred mcclient.TokenCredential, guest *SGuest) bool {
+	if guest.Status == api.VM_BLOCK_STREAM {
+		return true
+	}
+	body, err := guest.GetDriver().RequestSyncstatusOnHost(ctx, guest, host, userCred)
+	if err != nil {
+		log.Errorf("guest %s request status on host: %s", guest.Name, err)
+		return false
+	}
+	count, _ := body.Int("block_jobs_count")
+	return count > 0
+}
+
+// StartDrainGuest starts moving the guest out of host, or stopping it by the drain policy
+func (host *SHost) StartDrainGuest(ctx context.Context, userCred mcclient.TokenCredential, item *api.HostDrainGuest, preferHostId, parentTaskId string) error {
+	guest := GuestManager.FetchGuestById(item.Id)
+	if guest == nil {
+		return errors.Wrapf(errors.ErrNotFound, "guest %s", item.Id)
+	}
+	switch item.Policy {
+	case api.HOST_DRAIN_POLICY_LIVE_MIGRATE:
+		return guest.StartGuestLiveMigrateTask(ctx, userCred, item.OldStatus, preferHostId, nil, parentTaskId)
+	case api.HOST_DRAIN_POLICY_COLD_MIGRATE:
+		return guest.StartMigrateTask(ctx, userCred, item.OldStatus == api.VM_UNKNOWN, false, item.OldStatus, preferHostId, parentTaskId)
+	case api.HOST_DRAIN_POLICY_STOP:
+		guest.SetStatus(userCred, api.VM_START_STOP, "host maintenance")
+		return guest.StartGuestStopTask(ctx, userCred, false, false, parentTaskId)
+	}
+	return fmt.Errorf("unsupported drain policy %s", item.Policy)
+}
+
+// CheckDrainGuest returns the reason if the guest is not drained as the policy
+func (host *SHost) CheckDrainGuest(item *api.HostDrainGuest) string {
+	guest := GuestManager.FetchGuestById(item.Id)
+	if guest == nil {
+		// deleted while draining
+		return ""
+	}
+	switch item.Policy {
+	case api.HOST_DRAIN_POLICY_LIVE_MIGRATE, api.HOST_DRAIN_POLICY_COLD_MIGRATE:
+		if guest.HostId == host.Id {
+			return fmt.Sprintf("guest still on host in status %s", guest.Status)
+		}
+	case api.HOST_DRAIN_POLICY_STOP:
+		if guest.Status != api.VM_READY {
+			return fmt.Sprintf("guest not stopped in status %s", guest.Status)
+		}
+	}
+	return ""
+}
+
+func (host *SHost) prepareDrainGuests(ctx context.Context, userCred mcclient.TokenCredential, input *api.HostMaintenanceInput) ([]api.HostDrainGuest, error) {
+	guests := host.GetKvmGuests()
+	drainGuests := make([]api.HostDrainGuest, 0, len(guests))
+	for i := 0; i < len(guests); i++ {
+		lockman.LockObject(ctx, &guests[i])
+		defer lockman.ReleaseObject(ctx, &guests[i])
+		item := api.HostDrainGuest{
+			Id:        guests[i].Id,
+			Name:      guests[i].Name,
+			Policy:    host.getGuestDrainPolicy(&guests[i], input),
+			OldStatus: guests[i].Status,
+			Status:    api.HOST_DRAIN_GUEST_PENDING,
+		}
+		switch item.Policy {
+		case api.HOST_DRAIN_POLICY_SKIP:
+			item.Status = api.HOST_DRAIN_GUEST_SKIPPED
+		case api.HOST_DRAIN_POLICY_STOP:
+			if guests[i].Status == api.VM_READY {
+				item.Status = api.HOST_DRAIN_GUEST_DONE
+			} else if guests[i].Status != api.VM_RUNNING {
+				return nil, httperrors.NewBadRequestError("guest %s status %s can't stop", guests[i].Name, guests[i].Status)
+			}
+		default:
+			guest, err := guests[i].validateForBatchMigrate(ctx, false)
+			if err != nil {
+				return nil, err
+			}
+			guests[i] = *guest
+			if host.HostStatus == api.HOST_OFFLINE && guests[i].Status != api.VM_UNKNOWN {
+				return nil, httperrors.NewBadRequestError("Host %s can't migrate guests %s in status %s",
+					host.HostStatus, guests[i].Name, guests[i].Status)
+			}
+			item.OldStatus = guests[i].Status
+			if item.Policy == api.HOST_DRAIN_POLICY_LIVE_MIGRATE && guests[i].Status != api.VM_RUNNING {
+				item.Policy = api.HOST_DRAIN_POLICY_COLD_MIGRATE
+			}
+		}
+		drainGuests = append(drainGuests, item)
+	}
+	for i := range drainGuests {
+		if drainGuests[i].Status != api.HOST_DRAIN_GUEST_PENDING {
+			continue
+		}
+		if drainGuests[i].Policy != api.HOST_DRAIN_POLICY_STOP {
+			guests[i].SetStatus(userCred, api.VM_START_MIGRATE, "host maintainence")
+		}
+	}
+	return drainGuests, nil
+}
@@ -0,0 +1,509 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	"yunion.io/x/onecloud/pkg/mcclient/modules"
+	"yunion.io/x/onecloud/pkg/monitor/hostmetrics"
+)
+
+// sRebalanceHost tracks the resources of a host while planning, planned
+// moves are applied to it so that later moves see the effect of earlier ones
+type sRebalanceHost struct {
+	host *SHost
+
+	// committed resources of running guests and the overcommitted capacity
+	vcpuCount int
+	vmemSize  int
+	cpuCount  float64
+	memSize   float64
+
+	// real usage of host, cpus and MB used by guests estimated from metrics
+	metrics     *hostmetrics.HostMetrics
+	cpuUsed     float64
+	memUsed     float64
+	cpuCapacity float64
+	memCapacity float64
+
+	guests []SGuest
+}
+
+func newRebalanceHost(host *SHost, metrics *hostmetrics.HostMetrics) (*sRebalanceHost, error) {
+	h := &sRebalanceHost{
+		host:        host,
+		cpuCount:    float64(host.GetVirtualCPUCount()),
+		memSize:     float64(host.GetVirtualMemorySize()),
+		cpuCapacity: float64(host.CpuCount),
+		memCapacity: float64(host.MemSize),
+	}
+	usage := host.getGuestsResource(api.VM_RUNNING)
+	if usage != nil {
+		h.vcpuCount = usage.GuestVcpuCount
+		h.vmemSize = usage.GuestVmemSize
+	}
+	if metrics != nil && !metrics.IsStale(time.Duration(options.Options.HostRebalanceMetricsStaleMinutes)*time.Minute) {
+		h.metrics = metrics
+		h.cpuUsed = metrics.CPUUsage * h.cpuCapacity
+		h.memUsed = metrics.MemUsage * h.memCapacity
+	}
+	q := GuestManager.Query().Equals("host_id", host.Id).Equals("status", api.VM_RUNNING).
+		Equals("hypervisor", api.HYPERVISOR_KVM)
+	h.guests = make([]SGuest, 0)
+	err := db.FetchModelObjects(GuestManager, q, &h.guests)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fetch guests of host %s", host.Name)
+	}
+	return h, nil
+}
+
+// guestUsage estimates the real usage of guest by its share of committed resources
+func (h *sRebalanceHost) guestUsage(guest *SGuest) (float64, float64) {
+	var cpuUsed, memUsed float64
+	if h.vcpuCount > 0 {
+		cpuUsed = h.cpuUsed * float64(guest.VcpuCount) / float64(h.vcpuCount)
+	}
+	if h.vmemSize > 0 {
+		memUsed = h.memUsed * float64(guest.VmemSize) / float64(h.vmemSize)
+	}
+	return cpuUsed, memUsed
+}
+
+// sRebalanceDelta is the change of host resources when a guest moves in
+type sRebalanceDelta struct {
+	vcpuCount int
+	vmemSize  int
+	cpuUsed   float64
+	memUsed   float64
+}
+
+func newRebalanceDelta(src *sRebalanceHost, guest *SGuest) sRebalanceDelta {
+	cpuUsed, memUsed := src.guestUsage(guest)
+	return sRebalanceDelta{
+		vcpuCount: guest.VcpuCount,
+		vmemSize:  guest.VmemSize,
+		cpuUsed:   cpuUsed,
+		memUsed:   memUsed,
+	}
+}
+
+func (d sRebalanceDelta) negative() sRebalanceDelta {
+	return sRebalanceDelta{
+		vcpuCount: -d.vcpuCount,
+		vmemSize:  -d.vmemSize,
+		cpuUsed:   -d.cpuUsed,
+		memUsed:   -d.memUsed,
+	}
+}
+
+// loadWith returns the load after applying delta, the load is the larger one
+// of cpu and memory usage, real usage is used if metrics of the whole group
+// are known, otherwise the commit rate
+func (h *sRebalanceHost) loadWith(d sRebalanceDelta, useMetrics bool) float64 {
+	ratio := func(used, capacity float64) float64 {
+		if capacity <= 0 {
+			return 0
+		}
+		return used / capacity
+	}
+	if useMetrics {
+		return math.Max(ratio(h.cpuUsed+d.cpuUsed, h.cpuCapacity), ratio(h.memUsed+d.memUsed, h.memCapacity))
+	}
+	return math.Max(ratio(float64(h.vcpuCount+d.vcpuCount), h.cpuCount), ratio(float64(h.vmemSize+d.vmemSize), h.memSize))
+}
+
+// canHold checks the committed resources after delta within the capacity
+func (h *sRebalanceHost) canHold(d sRebalanceDelta) bool {
+	if h.cpuCount > 0 && float64(h.vcpuCount+d.vcpuCount) > h.cpuCount {
+		return false
+	}
+	if h.memSize > 0 && float64(h.vmemSize+d.vmemSize) > h.memSize {
+		return false
+	}
+	return true
+}
+
+func (h *sRebalanceHost) apply(d sRebalanceDelta) {
+	h.vcpuCount += d.vcpuCount
+	h.vmemSize += d.vmemSize
+	h.cpuUsed += d.cpuUsed
+	h.memUsed += d.memUsed
+}
+
+func (h *sRebalanceHost) removeGuest(guestId string) {
+	for i := range h.guests {
+		if h.guests[i].Id == guestId {
+			h.guests = append(h.guests[:i], h.guests[i+1:]...)
+			return
+		}
+	}
+}
+
+type sRebalanceGroup struct {
+	name  string
+	hosts []*sRebalanceHost
+}
+
+func (manager *SHostManager) getRebalanceGroups(ctx context.Context, groupBy string) ([]*sRebalanceGroup, error) {
+	q := manager.Query().Equals("host_type", api.HOST_TYPE_HYPERVISOR).IsTrue("enabled").
+		Equals("host_status", api.HOST_ONLINE).Equals("status", api.BAREMETAL_RUNNING)
+	hosts := make([]SHost, 0)
+	err := db.FetchModelObjects(manager, q, &hosts)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch hosts")
+	}
+	metrics, err := hostmetrics.FetchHostMetrics(ctx, options.Options.Region, options.Options.HostRebalanceMetricsWindow)
+	if err != nil {
+		// fallback to the commit rate
+		log.Warningf("fetch host metrics: %v", err)
+	}
+
+	groups := make([]*sRebalanceGroup, 0)
+	groupIdx := make(map[string]int)
+	addToGroup := func(key, name string, h *sRebalanceHost) {
+		idx, ok := groupIdx[key]
+		if !ok {
+			idx = len(groups)
+			groupIdx[key] = idx
+			groups = append(groups, &sRebalanceGroup{name: name})
+		}
+		groups[idx].hosts = append(groups[idx].hosts, h)
+	}
+	for i := range hosts {
+		// one host may belong to many schedtags, share the planning state
+		h, err := newRebalanceHost(&hosts[i], metrics[hosts[i].Id])
+		if err != nil {
+			return nil, err
+		}
+		if groupBy == api.HOST_REBALANCE_GROUP_BY_SCHEDTAG {
+			for _, tag := range hosts[i].GetSchedtags() {
+				addToGroup(tag.Id, tag.Name, h)
+			}
+		} else {
+			zone := hosts[i].GetZone()
+			if zone != nil {
+				addToGroup(zone.Id, zone.Name, h)
+			}
+		}
+	}
+	return groups, nil
+}
+
+func (group *sRebalanceGroup) hasMigratingGuests() (bool, error) {
+	hostIds := make([]string, len(group.hosts))
+	for i := range group.hosts {
+		hostIds[i] = group.hosts[i].host.Id
+	}
+	cnt, err := GuestManager.Query().In("host_id", hostIds).
+		In("status", []string{api.VM_START_MIGRATE, api.VM_MIGRATING}).CountWithError()
+	if err != nil {
+		return false, err
+	}
+	return cnt > 0, nil
+}
+
+// useMetrics tells whether the real usage of every host is known
+func (group *sRebalanceGroup) useMetrics() bool {
+	for _, h := range group.hosts {
+		if h.metrics == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// checkRebalanceTarget validates the live migration through the scheduler forecast
+func checkRebalanceTarget(ctx context.Context, userCred mcclient.TokenCredential, guest *SGuest, target *SHost) error {
+	if _, err := guest.validateForBatchMigrate(ctx, false); err != nil {
+		return err
+	}
+	if err := guest.GetDriver().CheckLiveMigrate(guest, userCred, api.GuestLiveMigrateInput{}); err != nil {
+		return err
+	}
+	schedDesc := guest.ToSchedDesc()
+	schedDesc.ServerConfig.PreferHost = target.Id
+	schedDesc.LiveMigrate = true
+	schedDesc.ReuseNetwork = true
+	if guest.GetMetadata("__cpu_mode", userCred) != api.CPU_MODE_QEMU {
+		host := guest.GetHost()
+		schedDesc.CpuDesc = host.CpuDesc
+		schedDesc.CpuMicrocode = host.CpuMicrocode
+		schedDesc.CpuMode = api.CPU_MODE_HOST
+	} else {
+		schedDesc.CpuMode = api.CPU_MODE_QEMU
+	}
+	s := auth.GetAdminSession(ctx, options.Options.Region, "")
+	canMigrate, res, err := modules.SchedManager.DoScheduleForecast(s, schedDesc, 1)
+	if err != nil {
+		return errors.Wrap(err, "DoScheduleForecast")
+	}
+	if !canMigrate {
+		return fmt.Errorf("forecast: %s", res)
+	}
+	return nil
+}
+
+// plan moves guests from the busiest host to the idlest host greedily, every
+// move must lower the load of the busiest host. The scheduler forecast only
+// knows the hosts before planning, so the capacity of target is checked
+// against the planning state which has earlier moves applied, check covers
+// the rest constraints such as networks and cpu compatibility
+func (group *sRebalanceGroup) plan(threshold float64, maxMoves int, check func(guest *SGuest, target *SHost) error) []api.HostRebalanceMove {
+	moves := make([]api.HostRebalanceMove, 0)
+	if len(group.hosts) < 2 {
+		return moves
+	}
+	useMetrics := group.useMetrics()
+	zero := sRebalanceDelta{}
+	for len(moves) < maxMoves {
+		sort.SliceStable(group.hosts, func(i, j int) bool {
+			return group.hosts[i].loadWith(zero, useMetrics) > group.hosts[j].loadWith(zero, useMetrics)
+		})
+		src, dst := group.hosts[0], group.hosts[len(group.hosts)-1]
+		srcLoad, dstLoad := src.loadWith(zero, useMetrics), dst.loadWith(zero, useMetrics)
+		if srcLoad-dstLoad < threshold {
+			break
+		}
+		type sCandidate struct {
+			guest *SGuest
+			delta sRebalanceDelta
+			gap   float64
+		}
+		candidates := make([]sCandidate, 0)
+		for i := range src.guests {
+			g := &src.guests[i]
+			delta := newRebalanceDelta(src, g)
+			if !dst.canHold(delta) {
+				continue
+			}
+			newSrcLoad := src.loadWith(delta.negative(), useMetrics)
+			newDstLoad := dst.loadWith(delta, useMetrics)
+			if math.Max(newSrcLoad, newDstLoad) >= srcLoad {
+				continue
+			}
+			candidates = append(candidates, sCandidate{guest: g, delta: delta, gap: math.Abs(newSrcLoad - newDstLoad)})
+		}
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].gap < candidates[j].gap
+		})
+		var moved *sCandidate
+		for i := range candidates {
+			err := check(candidates[i].guest, dst.host)
+			if err != nil {
+				log.Debugf("rebalance guest %s to host %s: %v", candidates[i].guest.Name, dst.host.Name, err)
+				continue
+			}
+			moved = &candidates[i]
+			break
+		}
+		if moved == nil {
+			break
+		}
+		moves = append(moves, api.HostRebalanceMove{
+			GuestId:      moved.guest.Id,
+			Guest:        moved.guest.Name,
+			SourceHostId: src.host.Id,
+			SourceHost:   src.host.Name,
+			TargetHostId: dst.host.Id,
+			TargetHost:   dst.host.Name,
+			SourceLoad:   srcLoad,
+			TargetLoad:   dstLoad,
+		})
+		src.apply(moved.delta.negative())
+		dst.apply(moved.delta)
+		src.removeGuest(moved.guest.Id)
+	}
+	return moves
+}
+
+// PlanRebalance computes live migrations that even out committed resources of hosts
+func (manager *SHostManager) PlanRebalance(ctx context.Context, userCred mcclient.TokenCredential, input api.HostRebalanceInput) ([]api.HostRebalancePlan, error) {
+	groups, err := manager.getRebalanceGroups(ctx, input.GroupBy)
+	if err != nil {
+		return nil, err
+	}
+	plans := make([]api.HostRebalancePlan, 0)
+	movesLeft := input.MaxMoves
+	for _, group := range groups {
+		if movesLeft <= 0 {
+			break
+		}
+		migrating, err := group.hasMigratingGuests()
+		if err != nil {
+			return nil, errors.Wrap(err, "hasMigratingGuests")
+		}
+		if migrating {
+			log.Infof("skip rebalance %s: guests migrating", group.name)
+			continue
+		}
+		moves := group.plan(float64(input.Threshold), movesLeft, func(guest *SGuest, target *SHost) error {
+			return checkRebalanceTarget(ctx, userCred, guest, target)
+		})
+		if len(moves) == 0 {
+			continue
+		}
+		movesLeft -= len(moves)
+		plans = append(plans, api.HostRebalancePlan{Group: group.name, Moves: moves})
+	}
+	return plans, nil
+}
+
+// ExecuteRebalance starts a task running live migrations of the plans,
+// at most HostRebalanceConcurrency migrations are running at the same time
+func (manager *SHostManager) ExecuteRebalance(ctx context.Context, userCred mcclient.TokenCredential, plans []api.HostRebalancePlan) error {
+	moves := make([]api.HostRebalanceMove, 0)
+	guests := make([]db.IStandaloneModel, 0)
+	for _, plan := range plans {
+		for _, move := range plan.Moves {
+			guest := GuestManager.FetchGuestById(move.GuestId)
+			if guest == nil {
+				continue
+			}
+			moves = append(moves, move)
+			guests = append(guests, guest)
+		}
+	}
+	if len(moves) == 0 {
+		return nil
+	}
+	params := jsonutils.NewDict()
+	params.Set("moves", jsonutils.Marshal(moves))
+	params.Set("concurrency", jsonutils.NewInt(int64(options.Options.HostRebalanceConcurrency)))
+	task, err := taskman.TaskManager.NewParallelTask(ctx, "HostRebalanceTask", guests, userCred, params, "", "")
+	if err != nil {
+		return errors.Wrap(err, "NewParallelTask HostRebalanceTask")
+	}
+	return task.ScheduleRun(nil)
+}
+
+// StartRebalanceMove starts live migration of a planned move as a subtask,
+// the move is skipped if the guest has left the source host or stopped since planned
+func (manager *SHostManager) StartRebalanceMove(ctx context.Context, userCred mcclient.TokenCredential, move api.HostRebalanceMove, parentTaskId string) error {
+	guest := GuestManager.FetchGuestById(move.GuestId)
+	if guest == nil {
+		return errors.Wrapf(errors.ErrNotFound, "guest %s", move.GuestId)
+	}
+	if guest.HostId != move.SourceHostId || guest.Status != api.VM_RUNNING {
+		return fmt.Errorf("guest %s is %s on host %s", guest.Name, guest.Status, guest.HostId)
+	}
+	db.OpsLog.LogEvent(guest, db.ACT_REBALANCE_MIGRATE, jsonutils.Marshal(move), userCred)
+	return guest.StartGuestLiveMigrateTask(ctx, userCred, guest.Status, move.TargetHostId, nil, parentTaskId)
+}
+
+func validateHostRebalanceInput(input *api.HostRebalanceInput) error {
+	if len(input.GroupBy) == 0 {
+		input.GroupBy = options.Options.HostRebalanceGroupBy
+	}
+	if !utils.IsInStringArray(input.GroupBy, []string{api.HOST_REBALANCE_GROUP_BY_ZONE, api.HOST_REBALANCE_GROUP_BY_SCHEDTAG}) {
+		return httperrors.NewInputParameterError("invalid group_by %s", input.GroupBy)
+	}
+	if input.MaxMoves <= 0 {
+		input.MaxMoves = options.Options.HostRebalanceMaxMoves
+	}
+	if input.Threshold <= 0 {
+		input.Threshold = options.Options.HostRebalanceThreshold
+	}
+	if input.Threshold > 1 {
+		return httperrors.NewInputParameterError("threshold should be between 0 and 1")
+	}
+	return nil
+}
+
+func (manager *SHostManager) AllowGetPropertyRebalancePlan(ctx context.Context, userCred mcclient.TokenCredential, query api.HostRebalanceInput) bool {
+	return db.IsAdminAllowGetSpec(userCred, manager, "rebalance-plan")
+}
+
+// 获取宿主机负载均衡迁移建议
+func (manager *SHostManager) GetPropertyRebalancePlan(ctx context.Context, userCred mcclient.TokenCredential, query api.HostRebalanceInput) (jsonutils.JSONObject, error) {
+	err := validateHostRebalanceInput(&query)
+	if err != nil {
+		return nil, err
+	}
+	plans, err := manager.PlanRebalance(ctx, userCred, query)
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	ret := jsonutils.NewDict()
+	ret.Set("plans", jsonutils.Marshal(plans))
+	return ret, nil
+}
+
+func (manager *SHostManager) AllowPerformRebalance(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.HostRebalanceInput) bool {
+	return db.IsAdminAllowClassPerform(userCred, manager, "rebalance")
+}
+
+// 立即执行宿主机负载均衡, 通过热迁移虚拟机
+func (manager *SHostManager) PerformRebalance(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.HostRebalanceInput) (jsonutils.JSONObject, error) {
+	err := validateHostRebalanceInput(&input)
+	if err != nil {
+		return nil, err
+	}
+	plans, err := manager.PlanRebalance(ctx, userCred, input)
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	err = manager.ExecuteRebalance(ctx, userCred, plans)
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	ret := jsonutils.NewDict()
+	ret.Set("plans", jsonutils.Marshal(plans))
+	return ret, nil
+}
+
+func (manager *SHostManager) AutoRebalance(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	input := api.HostRebalanceInput{}
+	if err := validateHostRebalanceInput(&input); err != nil {
+		log.Errorf("AutoRebalance: %v", err)
+		return
+	}
+	plans, err := manager.PlanRebalance(ctx, userCred, input)
+	if err != nil {
+		log.Errorf("AutoRebalance plan: %v", err)
+		return
+	}
+	if options.Options.HostRebalanceMode == api.HOST_REBALANCE_MODE_AUTO {
+		err = manager.ExecuteRebalance(ctx, userCred, plans)
+		if err != nil {
+			log.Errorf("AutoRebalance execute: %v", err)
+		}
+		return
+	}
+	for _, plan := range plans {
+		for _, move := range plan.Moves {
+			guest := GuestManager.FetchGuestById(move.GuestId)
+			if guest != nil {
+				db.OpsLog.LogEvent(guest, db.ACT_REBALANCE_RECOMMEND, jsonutils.Marshal(move), userCred)
+			}
+		}
+	}
+}
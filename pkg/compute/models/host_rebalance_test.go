@@ -0,0 +1,158 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"fmt"
+	"testing"
+
+	"yunion.io/x/onecloud/pkg/monitor/hostmetrics"
+)
+
+func newTestRebalanceHost(id string, cpuCount, memSize int, guests ...SGuest) *sRebalanceHost {
+	host := &SHost{}
+	host.Id = id
+	host.Name = id
+	h := &sRebalanceHost{
+		host:        host,
+		cpuCount:    float64(cpuCount),
+		memSize:     float64(memSize),
+		cpuCapacity: float64(cpuCount),
+		memCapacity: float64(memSize),
+		guests:      guests,
+	}
+	for _, g := range guests {
+		h.vcpuCount += g.VcpuCount
+		h.vmemSize += g.VmemSize
+	}
+	return h
+}
+
+func newTestRebalanceGuest(id string, vcpuCount, vmemSize int) SGuest {
+	guest := SGuest{}
+	guest.Id = id
+	guest.Name = id
+	guest.VcpuCount = vcpuCount
+	guest.VmemSize = vmemSize
+	return guest
+}
+
+func allowAllRebalance(guest *SGuest, target *SHost) error {
+	return nil
+}
+
+func TestRebalancePlanCommitRate(t *testing.T) {
+	group := &sRebalanceGroup{
+		hosts: []*sRebalanceHost{
+			newTestRebalanceHost("busy", 16, 32768,
+				newTestRebalanceGuest("g1", 4, 8192),
+				newTestRebalanceGuest("g2", 4, 8192),
+				newTestRebalanceGuest("g3", 4, 8192),
+				newTestRebalanceGuest("g4", 2, 4096),
+			),
+			newTestRebalanceHost("idle", 16, 32768),
+		},
+	}
+	moves := group.plan(0.2, 5, allowAllRebalance)
+	if len(moves) != 2 {
+		t.Fatalf("want 2 moves, got %d: %#v", len(moves), moves)
+	}
+	for _, move := range moves {
+		if move.SourceHostId != "busy" || move.TargetHostId != "idle" {
+			t.Errorf("unexpected move %#v", move)
+		}
+	}
+	// the second move is planned with the first move applied
+	if moves[1].SourceLoad >= moves[0].SourceLoad || moves[1].TargetLoad <= moves[0].TargetLoad {
+		t.Errorf("second move not based on state after first move: %#v", moves)
+	}
+	busy, idle := group.hosts[0], group.hosts[1]
+	if busy.host.Id != "busy" {
+		busy, idle = idle, busy
+	}
+	if busy.vcpuCount+idle.vcpuCount != 14 || busy.vmemSize+idle.vmemSize != 28672 {
+		t.Errorf("resources lost while planning: %d %d", busy.vcpuCount+idle.vcpuCount, busy.vmemSize+idle.vmemSize)
+	}
+	if len(busy.guests) != 2 {
+		t.Errorf("want 2 guests left on busy host, got %d", len(busy.guests))
+	}
+}
+
+func TestRebalancePlanTargetCapacity(t *testing.T) {
+	// every guest alone fits the target, but not all of them together
+	group := &sRebalanceGroup{
+		hosts: []*sRebalanceHost{
+			newTestRebalanceHost("busy", 8, 16384,
+				newTestRebalanceGuest("g1", 4, 4096),
+				newTestRebalanceGuest("g2", 4, 4096),
+				newTestRebalanceGuest("g3", 4, 4096),
+				newTestRebalanceGuest("g4", 4, 4096),
+			),
+			newTestRebalanceHost("small", 4, 16384),
+		},
+	}
+	moves := group.plan(0.1, 5, allowAllRebalance)
+	if len(moves) != 1 {
+		t.Fatalf("want 1 move within target capacity, got %d: %#v", len(moves), moves)
+	}
+}
+
+func TestRebalancePlanMetrics(t *testing.T) {
+	// committed resources are even, but the first host is really busy
+	busy := newTestRebalanceHost("busy", 16, 32768,
+		newTestRebalanceGuest("g1", 4, 4096),
+		newTestRebalanceGuest("g2", 4, 4096),
+	)
+	idle := newTestRebalanceHost("idle", 16, 32768,
+		newTestRebalanceGuest("g3", 4, 4096),
+		newTestRebalanceGuest("g4", 4, 4096),
+	)
+	group := &sRebalanceGroup{hosts: []*sRebalanceHost{busy, idle}}
+	if moves := group.plan(0.2, 5, allowAllRebalance); len(moves) != 0 {
+		t.Fatalf("want no move by commit rate, got %#v", moves)
+	}
+
+	busy.metrics, idle.metrics = &hostmetrics.HostMetrics{}, &hostmetrics.HostMetrics{}
+	busy.cpuUsed, idle.cpuUsed = 12, 2
+	moves := group.plan(0.2, 5, allowAllRebalance)
+	if len(moves) != 1 || moves[0].SourceHostId != "busy" {
+		t.Fatalf("want 1 move from busy host by real usage, got %#v", moves)
+	}
+	if busy.cpuUsed != 6 || idle.cpuUsed != 8 {
+		t.Errorf("want real usage moved with guest, got busy %v idle %v", busy.cpuUsed, idle.cpuUsed)
+	}
+}
+
+func TestRebalancePlanCheck(t *testing.T) {
+	group := &sRebalanceGroup{
+		hosts: []*sRebalanceHost{
+			newTestRebalanceHost("busy", 16, 32768,
+				newTestRebalanceGuest("g1", 4, 8192),
+				newTestRebalanceGuest("g2", 4, 8192),
+				newTestRebalanceGuest("g3", 4, 8192),
+			),
+			newTestRebalanceHost("idle", 16, 32768),
+		},
+	}
+	moves := group.plan(0.2, 5, func(guest *SGuest, target *SHost) error {
+		if guest.Id != "g3" {
+			return fmt.Errorf("forecast failed")
+		}
+		return nil
+	})
+	if len(moves) != 1 || moves[0].GuestId != "g3" {
+		t.Fatalf("want only g3 moved, got %#v", moves)
+	}
+}
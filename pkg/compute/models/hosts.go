@@ -161,6 +161,8 @@ type SHost struct {
 	Version string `width:"64" charset:"ascii" list:"domain" update:"domain" create:"domain_optional"`
 	// OVN软件版本
 	OvnVersion string `width:"64" charset:"ascii" list:"domain" update:"domain" create:"domain_optional"`
+	// NUMA拓扑信息
+	NumaInfo jsonutils.JSONObject `nullable:"true" list:"domain" update:"domain" create:"domain_optional"`
 
 	IsBaremetal bool `nullable:"true" default:"false" list:"domain" update:"domain" create:"domain_optional"`
 
@@ -544,6 +546,22 @@ func (self *SHost) IsArmHost() bool {
 	return self.CpuArchitecture == api.CPU_ARCH_AARCH64
 }
 
+// GetNumaInfo returns nil if the host does not report NUMA topology
+func (self *SHost) GetNumaInfo() *api.HostNumaInfo {
+	if self.NumaInfo == nil {
+		return nil
+	}
+	info := &api.HostNumaInfo{}
+	if err := self.NumaInfo.Unmarshal(info); err != nil {
+		log.Errorf("host %s unmarshal numa info: %s", self.Name, err)
+		return nil
+	}
+	if len(info.Nodes) == 0 {
+		return nil
+	}
+	return info
+}
+
 func (self *SHost) GetZone() *SZone {
 	if len(self.ZoneId) == 0 {
 		return nil
@@ -2935,6 +2953,7 @@ func (self *SHost) getMoreDetails(ctx context.Context, out api.HostDetails, show
 	if self.GetMetadata("__auto_migrate_on_host_down", nil) == "enable" {
 		out.AutoMigrateOnHostDown = true
 	}
+	out.DrainProgress = self.GetDrainProgress()
 
 	if count, rs := self.GetReservedResourceForIsolatedDevice(); rs != nil {
 		out.ReservedResourceForGpu = *rs
@@ -3895,6 +3914,16 @@ func (self *SHost) PerformPing(ctx context.Context, userCred mcclient.TokenCrede
 			return nil
 		})
 	}
+	// free memory of numa nodes is reported by every ping, save it only on change
+	if data != nil && data.Contains("numa_info") {
+		numaInfo, _ := data.Get("numa_info")
+		if self.NumaInfo == nil || self.NumaInfo.String() != numaInfo.String() {
+			self.SaveUpdates(func() error {
+				self.NumaInfo = numaInfo
+				return nil
+			})
+		}
+	}
 	result := jsonutils.NewDict()
 	result.Set("name", jsonutils.NewString(self.GetName()))
 	dependSvcs := []string{"ntpd", "kafka", "influxdb", "elasticsearch"}
@@ -5323,6 +5352,20 @@ func (host *SHost) PerformHostExitMaintenance(ctx context.Context, userCred mccl
 	if err != nil {
 		return nil, err
 	}
+	// resume scheduling if the host is disabled by maintenance
+	if host.GetMetadata(api.HOST_METADATA_MAINTENANCE_DISABLED, nil) == "true" {
+		_, err = host.PerformEnable(ctx, userCred, nil, apis.PerformEnableInput{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = host.SetAllMetadata(ctx, map[string]interface{}{
+		api.HOST_METADATA_MAINTENANCE_DISABLED: "",
+		api.HOST_METADATA_DRAIN_PROGRESS:       "",
+	}, userCred)
+	if err != nil {
+		return nil, err
+	}
 	return nil, nil
 }
 
@@ -5333,20 +5376,22 @@ func (host *SHost) AllowPerformHostMaintenance(ctx context.Context,
 	return db.IsAdminAllowPerform(userCred, host, "host maintenance")
 }
 
-func (host *SHost) PerformHostMaintenance(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+func (host *SHost) PerformHostMaintenance(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.HostMaintenanceInput) (jsonutils.JSONObject, error) {
 	if host.HostType != api.HOST_TYPE_HYPERVISOR {
 		return nil, httperrors.NewBadRequestError("host type %s can't do host maintenance", host.HostType)
 	}
-	if host.HostStatus == api.BAREMETAL_START_MAINTAIN {
+	if host.HostStatus == api.BAREMETAL_START_MAINTAIN || host.Status == api.BAREMETAL_START_MAINTAIN {
 		return nil, httperrors.NewBadRequestError("unsupport on host status %s", host.HostStatus)
 	}
+	if err := host.validateHostMaintenanceInput(&input); err != nil {
+		return nil, err
+	}
 
 	var preferHostId string
-	preferHost, _ := data.GetString("prefer_host")
-	if len(preferHost) > 0 {
-		iHost, _ := HostManager.FetchByIdOrName(userCred, preferHost)
+	if len(input.PreferHost) > 0 {
+		iHost, _ := HostManager.FetchByIdOrName(userCred, input.PreferHost)
 		if iHost == nil {
-			return nil, httperrors.NewBadRequestError("Host %s not found", preferHost)
+			return nil, httperrors.NewBadRequestError("Host %s not found", input.PreferHost)
 		}
 		host := iHost.(*SHost)
 		preferHostId = host.Id
@@ -5356,36 +5401,15 @@ func (host *SHost) PerformHostMaintenance(ctx context.Context, userCred mcclient
 		}
 	}
 
-	guests := host.GetKvmGuests()
-	for i := 0; i < len(guests); i++ {
-		lockman.LockObject(ctx, &guests[i])
-		defer lockman.ReleaseObject(ctx, &guests[i])
-		guest, err := guests[i].validateForBatchMigrate(ctx, false)
-		if err != nil {
-			return nil, err
-		}
-		guests[i] = *guest
-		if host.HostStatus == api.HOST_OFFLINE && guests[i].Status != api.VM_UNKNOWN {
-			return nil, httperrors.NewBadRequestError("Host %s can't migrate guests %s in status %s",
-				host.HostStatus, guests[i].Name, guests[i].Status)
-		}
-	}
-
-	var hostGuests = []*api.GuestBatchMigrateParams{}
-	for i := 0; i < len(guests); i++ {
-		bmp := &api.GuestBatchMigrateParams{
-			Id:          guests[i].Id,
-			LiveMigrate: guests[i].Status == api.VM_RUNNING,
-			RescueMode:  guests[i].Status == api.VM_UNKNOWN,
-			OldStatus:   guests[i].Status,
-		}
-		guests[i].SetStatus(userCred, api.VM_START_MIGRATE, "host maintainence")
-		hostGuests = append(hostGuests, bmp)
+	drainGuests, err := host.prepareDrainGuests(ctx, userCred, &input)
+	if err != nil {
+		return nil, err
 	}
 
 	kwargs := jsonutils.NewDict()
-	kwargs.Set("guests", jsonutils.Marshal(hostGuests))
+	kwargs.Set("drain_guests", jsonutils.Marshal(drainGuests))
 	kwargs.Set("prefer_host_id", jsonutils.NewString(preferHostId))
+	kwargs.Set("concurrency", jsonutils.NewInt(int64(input.Concurrency)))
 	return nil, host.StartMaintainTask(ctx, userCred, kwargs)
 }
 
@@ -5451,12 +5475,23 @@ func (host *SHost) switchWithBackup(ctx context.Context, userCred mcclient.Token
 
 func (host *SHost) migrateOnHostDown(ctx context.Context, userCred mcclient.TokenCredential) {
 	if host.GetMetadata("__auto_migrate_on_host_down", nil) == "enable" {
-		if err := host.MigrateSharedStorageServers(ctx, userCred); err != nil {
-			db.OpsLog.LogEvent(host, db.ACT_HOST_DOWN, fmt.Sprintf("migrate servers failed %s", err), userCred)
+		// guests restart after the host fenced
+		if err := host.StartFenceTask(ctx, userCred); err != nil {
+			db.OpsLog.LogEvent(host, db.ACT_HOST_FENCE_FAIL, fmt.Sprintf("fence host failed, skip restarting servers: %s", err), userCred)
+			logclient.AddSimpleActionLog(host, logclient.ACT_HOST_FENCE, err.Error(), userCred, false)
 		}
 	}
 }
 
+func (host *SHost) StartFenceTask(ctx context.Context, userCred mcclient.TokenCredential) error {
+	task, err := taskman.TaskManager.NewTask(ctx, "HostFenceTask", host, userCred, nil, "", "", nil)
+	if err != nil {
+		return err
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
+
 func (host *SHost) MigrateSharedStorageServers(ctx context.Context, userCred mcclient.TokenCredential) error {
 	guests, err := host.GetGuests()
 	if err != nil {
@@ -5464,7 +5499,15 @@ func (host *SHost) MigrateSharedStorageServers(ctx context.Context, userCred mcc
 	}
 	hostGuests := []*api.GuestBatchMigrateParams{}
 
+	// guests of higher ha priority restart first
+	sort.SliceStable(guests, func(i, j int) bool {
+		return guests[i].getHaPriority() > guests[j].getHaPriority()
+	})
 	for i := 0; i < len(guests); i++ {
+		if guests[i].isHaDisabled() {
+			logclient.AddSimpleActionLog(&guests[i], logclient.ACT_VM_HA_RESTART, "ha disabled, skip restart", userCred, false)
+			continue
+		}
 		lockman.LockObject(ctx, &guests[i])
 		defer lockman.ReleaseObject(ctx, &guests[i])
 		_, err := guests[i].validateForBatchMigrate(ctx, true)
@@ -5476,6 +5519,8 @@ func (host *SHost) MigrateSharedStorageServers(ctx context.Context, userCred mcc
 				LiveMigrate: false,
 				RescueMode:  true,
 				OldStatus:   guests[i].Status,
+				HaRestart:   true,
+				MaxAttempts: guests[i].getHaMaxRestarts(),
 			}
 			guests[i].SetStatus(userCred, api.VM_START_MIGRATE, "host down")
 			hostGuests = append(hostGuests, bmp)
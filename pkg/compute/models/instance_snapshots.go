@@ -77,6 +77,8 @@ type SInstanceSnapshot struct {
 	InstanceType string `width:"64" charset:"utf8" nullable:"true" list:"user" create:"optional"`
 	// 主机快照磁盘容量和
 	SizeMb int `nullable:"false"`
+	// 是否在冻结文件系统后创建, 即应用一致性快照
+	Quiesced bool `nullable:"false" default:"false" list:"user"`
 }
 
 type SInstanceSnapshotManager struct {
@@ -289,10 +291,11 @@ func (self *SInstanceSnapshot) StartCreateInstanceSnapshotTask(
 	ctx context.Context,
 	userCred mcclient.TokenCredential,
 	pendingUsage quotas.IQuota,
+	params *jsonutils.JSONDict,
 	parentTaskId string,
 ) error {
 	if task, err := taskman.TaskManager.NewTask(
-		ctx, "InstanceSnapshotCreateTask", self, userCred, nil, parentTaskId, "", pendingUsage); err != nil {
+		ctx, "InstanceSnapshotCreateTask", self, userCred, params, parentTaskId, "", pendingUsage); err != nil {
 		return err
 	} else {
 		task.ScheduleRun(nil)
@@ -116,6 +116,9 @@ type SLoadbalancer struct {
 
 	SLoadbalancerClusterResourceBase
 
+	// 数据面实现, lbagent或ovn
+	DataplaneType string `width:"16" charset:"ascii" nullable:"true" list:"user" create:"optional" json:"dataplane_type"`
+
 	// 计费类型
 	ChargeType string `list:"user" get:"user" create:"optional" update:"user" json:"charge_type"`
 
@@ -436,6 +439,12 @@ func (lb *SLoadbalancer) GetVpc() *SVpc {
 	return lb.SVpcResourceBase.GetVpc()
 }
 
+// IsOvnDataplane tells whether the load balancer is implemented with ovn
+// Load_Balancer instead of lbagent
+func (lb *SLoadbalancer) IsOvnDataplane() bool {
+	return lb.DataplaneType == api.LB_DATAPLANE_TYPE_OVN
+}
+
 func (lb *SLoadbalancer) GetNetworks() ([]SNetwork, error) {
 	networks := []SNetwork{}
 	networkIds := strings.Split(lb.NetworkId, ",")
@@ -171,7 +171,7 @@ func (man *SNatSEntryManager) ValidateCreateData(ctx context.Context, userCred m
 			return nil, httperrors.NewInputParameterError("%v", err)
 		}
 		// get natgateway
-		model, err := man.FetchById(input.NatgatewayId)
+		model, err := NatGatewayManager.FetchById(input.NatgatewayId)
 		if err != nil {
 			return nil, err
 		}
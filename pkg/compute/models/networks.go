@@ -47,6 +47,7 @@ import (
 	"yunion.io/x/onecloud/pkg/mcclient/auth"
 	"yunion.io/x/onecloud/pkg/util/billing"
 	"yunion.io/x/onecloud/pkg/util/logclient"
+	"yunion.io/x/onecloud/pkg/util/netutils2"
 	"yunion.io/x/onecloud/pkg/util/rand"
 	"yunion.io/x/onecloud/pkg/util/rbacutils"
 	"yunion.io/x/onecloud/pkg/util/stringutils2"
@@ -94,14 +95,22 @@ type SNetwork struct {
 
 	GuestDomain string `width:"128" charset:"ascii" nullable:"true" get:"user" update:"user"`
 
-	GuestIp6Start string `width:"64" charset:"ascii" nullable:"true"`
-	GuestIp6End   string `width:"64" charset:"ascii" nullable:"true"`
-	GuestIp6Mask  int8   `nullable:"true"`
-	GuestGateway6 string `width:"64" charset:"ascii" nullable:"true"`
-	GuestDns6     string `width:"64" charset:"ascii" nullable:"true"`
+	// IPv6起始地址
+	GuestIp6Start string `width:"64" charset:"ascii" nullable:"true" list:"user" update:"user" create:"optional"`
+	// IPv6结束地址
+	GuestIp6End string `width:"64" charset:"ascii" nullable:"true" list:"user" update:"user" create:"optional"`
+	// IPv6前缀长度
+	GuestIp6Mask int8 `nullable:"true" list:"user" update:"user" create:"optional"`
+	// IPv6网关地址
+	GuestGateway6 string `width:"64" charset:"ascii" nullable:"true" list:"user" update:"user" create:"optional"`
+	// IPv6 DNS
+	GuestDns6 string `width:"64" charset:"ascii" nullable:"true" list:"user" update:"user" create:"optional"`
 
 	GuestDomain6 string `width:"128" charset:"ascii" nullable:"true"`
 
+	// IPv6地址分配方式, dhcpv6或slaac
+	GuestIp6Mode string `width:"16" charset:"ascii" nullable:"true" list:"user" update:"user" create:"optional"`
+
 	VlanId int `nullable:"false" default:"1" list:"user" update:"user" create:"optional"`
 
 	// 二层网络Id
@@ -849,7 +858,17 @@ func (self *SNetwork) isAddressUsed(address string) (bool, error) {
 }
 
 func (manager *SNetworkManager) GetOnPremiseNetworkOfIP(ipAddr string, serverType string, isPublic tristate.TriState) (*SNetwork, error) {
-	address, err := netutils.NewIPV4Addr(ipAddr)
+	var (
+		address  netutils.IPV4Addr
+		address6 netutils2.IPV6Addr
+		isIp6    = regutils.MatchIP6Addr(ipAddr)
+		err      error
+	)
+	if isIp6 {
+		address6, err = netutils2.NewIPV6Addr(ipAddr)
+	} else {
+		address, err = netutils.NewIPV4Addr(ipAddr)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -875,7 +894,11 @@ func (manager *SNetworkManager) GetOnPremiseNetworkOfIP(ipAddr string, serverTyp
 		return nil, err
 	}
 	for _, n := range nets {
-		if n.IsAddressInRange(address) {
+		if isIp6 {
+			if n.IsIPv6Enabled() && n.getIP6Range().Contains(address6) {
+				return &n, nil
+			}
+		} else if n.IsAddressInRange(address) {
 			return &n, nil
 		}
 	}
@@ -1033,6 +1056,25 @@ func isValidNetworkInfo(userCred mcclient.TokenCredential, netConfig *api.Networ
 				}
 			}
 		}
+		if len(netConfig.Address6) > 0 {
+			if !net.IsIPv6Enabled() {
+				return httperrors.NewInputParameterError("Network %s is not ipv6 enabled", net.Name)
+			}
+			ip6Addr, err := netutils2.NewIPV6Addr(netConfig.Address6)
+			if err != nil {
+				return httperrors.NewInputParameterError("invalid ipv6 address %s", netConfig.Address6)
+			}
+			if !net.getIP6Range().Contains(ip6Addr) {
+				return httperrors.NewInputParameterError("Address %s not in range", netConfig.Address6)
+			}
+			used, err := net.isAddress6Used(ip6Addr.String())
+			if err != nil {
+				return httperrors.NewInternalServerError("isAddress6Used fail %s", err)
+			}
+			if used {
+				return httperrors.NewInputParameterError("Address %s has been used", netConfig.Address6)
+			}
+		}
 		if netConfig.BwLimit > api.MAX_BANDWIDTH {
 			return httperrors.NewInputParameterError("Bandwidth limit cannot exceed %dMbps", api.MAX_BANDWIDTH)
 		}
@@ -1554,6 +1596,11 @@ func (manager *SNetworkManager) ValidateCreateData(ctx context.Context, userCred
 		}
 	}
 
+	err = manager.validateCreateIp6Data(&input, vpc, region.Provider == api.CLOUD_PROVIDER_ONECLOUD && vpc.Id != api.DEFAULT_VPC_ID)
+	if err != nil {
+		return input, err
+	}
+
 	input.GuestIpStart = ipStart.String()
 	input.GuestIpEnd = ipEnd.String()
 	input.SharableVirtualResourceCreateInput, err = manager.SSharableVirtualResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.SharableVirtualResourceCreateInput)
@@ -1666,6 +1713,11 @@ func (self *SNetwork) validateUpdateData(ctx context.Context, userCred mcclient.
 		}
 	}
 
+	err = self.validateUpdateIp6Data(&input)
+	if err != nil {
+		return input, err
+	}
+
 	if input.IsAutoAlloc != nil && *input.IsAutoAlloc {
 		if self.ServerType != api.NETWORK_TYPE_GUEST {
 			return input, httperrors.NewInputParameterError("network server_type %s not support auto alloc", self.ServerType)
@@ -1690,6 +1742,12 @@ func (self *SNetwork) ValidateUpdateData(ctx context.Context, userCred mcclient.
 		input.GuestDns = ""
 		input.GuestDomain = ""
 		input.GuestDhcp = ""
+		input.GuestIp6Start = ""
+		input.GuestIp6End = ""
+		input.GuestIp6Mask = nil
+		input.GuestGateway6 = ""
+		input.GuestDns6 = ""
+		input.GuestIp6Mode = ""
 	}
 
 	var err error
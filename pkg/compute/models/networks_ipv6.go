@@ -0,0 +1,362 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"fmt"
+	"net"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/util/netutils2"
+)
+
+const (
+	minIp6MaskLen = 48
+	maxIp6MaskLen = 126
+
+	slaacIp6MaskLen = 64
+)
+
+// sNetworkIp6Conf is the ipv6 part of a network being created or updated
+type sNetworkIp6Conf struct {
+	prefix  string
+	start   string
+	end     string
+	masklen int8
+	gateway string
+	dns     string
+	mode    string
+}
+
+func (conf *sNetworkIp6Conf) isEmpty() bool {
+	return conf.prefix == "" && conf.start == "" && conf.end == ""
+}
+
+func (conf *sNetworkIp6Conf) getIPRange() netutils2.IPV6AddrRange {
+	start, _ := netutils2.NewIPV6Addr(conf.start)
+	end, _ := netutils2.NewIPV6Addr(conf.end)
+	return netutils2.NewIPV6AddrRange(start, end)
+}
+
+// validate checks and normalizes the ipv6 configuration.  When
+// reserveGateway is true, the first address of the prefix is always used as
+// gateway and excluded from the range, as onecloud vpc does for ipv4
+func (conf *sNetworkIp6Conf) validate(nets []SNetwork, reserveGateway bool) error {
+	if conf.isEmpty() {
+		if conf.gateway != "" || conf.dns != "" || conf.mode != "" {
+			return httperrors.NewInputParameterError("guest_ip6_prefix or guest_ip6_start, guest_ip6_end required")
+		}
+		return nil
+	}
+
+	var (
+		start netutils2.IPV6Addr
+		end   netutils2.IPV6Addr
+		err   error
+	)
+	if conf.prefix != "" {
+		ip, ipnet, err := net.ParseCIDR(conf.prefix)
+		if err != nil || ip.To4() != nil {
+			return httperrors.NewInputParameterError("invalid guest_ip6_prefix %s", conf.prefix)
+		}
+		masklen, _ := ipnet.Mask.Size()
+		conf.masklen = int8(masklen)
+		netAddr, _ := netutils2.NewIPV6Addr(ipnet.IP.String())
+		start = netAddr.StepUp()
+		end = netAddr
+		for i := int(masklen) / 8; i < len(end); i++ {
+			end[i] = 0xff
+		}
+		if masklen%8 != 0 {
+			end[masklen/8] |= 0xff >> uint(masklen%8)
+		}
+		conf.prefix = ipnet.String()
+	} else {
+		start, err = netutils2.NewIPV6Addr(conf.start)
+		if err != nil {
+			return httperrors.NewInputParameterError("invalid guest_ip6_start %s", conf.start)
+		}
+		end, err = netutils2.NewIPV6Addr(conf.end)
+		if err != nil {
+			return httperrors.NewInputParameterError("invalid guest_ip6_end %s", conf.end)
+		}
+	}
+	if conf.masklen < minIp6MaskLen || conf.masklen > maxIp6MaskLen {
+		return httperrors.NewInputParameterError("guest_ip6_mask should be in range [%d, %d]", minIp6MaskLen, maxIp6MaskLen)
+	}
+	netAddr := start.NetAddr(conf.masklen)
+	if end.NetAddr(conf.masklen) != netAddr {
+		return httperrors.NewInputParameterError("ipv6 start and end address not in the same subnet")
+	}
+
+	if conf.mode == "" {
+		conf.mode = api.NETWORK_IP6_MODE_DHCPV6
+	} else if !utils.IsInStringArray(conf.mode, api.NETWORK_IP6_MODES) {
+		return httperrors.NewInputParameterError("invalid guest_ip6_mode %s, want %s", conf.mode, api.NETWORK_IP6_MODES)
+	}
+	if conf.mode == api.NETWORK_IP6_MODE_SLAAC && conf.masklen != slaacIp6MaskLen {
+		return httperrors.NewInputParameterError("slaac requires a /%d ipv6 prefix", slaacIp6MaskLen)
+	}
+
+	if reserveGateway {
+		gateway := netAddr.StepUp()
+		if start.Compare(gateway) <= 0 {
+			start = gateway.StepUp()
+		}
+		conf.gateway = gateway.String()
+	}
+	if conf.gateway != "" {
+		gateway, err := netutils2.NewIPV6Addr(conf.gateway)
+		if err != nil {
+			return httperrors.NewInputParameterError("invalid guest_gateway6 %s", conf.gateway)
+		}
+		if gateway.NetAddr(conf.masklen) != netAddr {
+			return httperrors.NewInputParameterError("guest_gateway6 must be in the same subnet as ipv6 start, end address")
+		}
+		conf.gateway = gateway.String()
+	}
+	if conf.dns != "" {
+		dns, err := netutils2.NewIPV6Addr(conf.dns)
+		if err != nil {
+			return httperrors.NewInputParameterError("invalid guest_dns6 %s", conf.dns)
+		}
+		conf.dns = dns.String()
+	}
+
+	ipRange := netutils2.NewIPV6AddrRange(start, end)
+	for i := range nets {
+		if !nets[i].IsIPv6Enabled() {
+			continue
+		}
+		if nets[i].getIP6Range().IsOverlap(ipRange) {
+			return httperrors.NewInputParameterError("Conflict ipv6 address space with network %s", nets[i].Name)
+		}
+	}
+	conf.start = ipRange.StartIp().String()
+	conf.end = ipRange.EndIp().String()
+	return nil
+}
+
+func (manager *SNetworkManager) validateCreateIp6Data(input *api.NetworkCreateInput, vpc *SVpc, reserveGateway bool) error {
+	conf := sNetworkIp6Conf{
+		prefix:  input.GuestIp6Prefix,
+		start:   input.GuestIp6Start,
+		end:     input.GuestIp6End,
+		masklen: input.GuestIp6Mask,
+		gateway: input.GuestGateway6,
+		dns:     input.GuestDns6,
+		mode:    input.GuestIp6Mode,
+	}
+	if conf.isEmpty() {
+		return conf.validate(nil, false)
+	}
+	nets, err := vpc.GetNetworks()
+	if err != nil {
+		return httperrors.NewInternalServerError("fail to GetNetworks of vpc: %v", err)
+	}
+	err = conf.validate(nets, reserveGateway)
+	if err != nil {
+		return err
+	}
+	input.GuestIp6Prefix = conf.prefix
+	input.GuestIp6Start = conf.start
+	input.GuestIp6End = conf.end
+	input.GuestIp6Mask = conf.masklen
+	input.GuestGateway6 = conf.gateway
+	input.GuestDns6 = conf.dns
+	input.GuestIp6Mode = conf.mode
+	return nil
+}
+
+func (self *SNetwork) validateUpdateIp6Data(input *api.NetworkUpdateInput) error {
+	if input.GuestIp6Start == "" && input.GuestIp6End == "" && input.GuestIp6Mask == nil &&
+		input.GuestGateway6 == "" && input.GuestDns6 == "" && input.GuestIp6Mode == "" {
+		return nil
+	}
+	conf := sNetworkIp6Conf{
+		start:   self.GuestIp6Start,
+		end:     self.GuestIp6End,
+		masklen: self.GuestIp6Mask,
+		gateway: self.GuestGateway6,
+		dns:     self.GuestDns6,
+		mode:    self.GuestIp6Mode,
+	}
+	if input.GuestIp6Start != "" {
+		conf.start = input.GuestIp6Start
+	}
+	if input.GuestIp6End != "" {
+		conf.end = input.GuestIp6End
+	}
+	if input.GuestIp6Mask != nil {
+		conf.masklen = *input.GuestIp6Mask
+	}
+	if input.GuestGateway6 != "" {
+		conf.gateway = input.GuestGateway6
+	}
+	if input.GuestDns6 != "" {
+		conf.dns = input.GuestDns6
+	}
+	if input.GuestIp6Mode != "" {
+		conf.mode = input.GuestIp6Mode
+	}
+	if conf.start == "" || conf.end == "" {
+		return httperrors.NewInputParameterError("both guest_ip6_start and guest_ip6_end required")
+	}
+	nets := NetworkManager.getAllNetworks(self.WireId, self.Id)
+	if nets == nil {
+		return httperrors.NewInternalServerError("query all networks fail")
+	}
+	err := conf.validate(nets, false)
+	if err != nil {
+		return err
+	}
+	ipRange := conf.getIPRange()
+	for usedIpStr := range self.GetUsedAddresses6() {
+		if usedIpStr == conf.gateway || usedIpStr == self.GetGateway6() {
+			continue
+		}
+		usedIp, _ := netutils2.NewIPV6Addr(usedIpStr)
+		if !ipRange.Contains(usedIp) {
+			return httperrors.NewInputParameterError("IPv6 address been assigned out of new range")
+		}
+	}
+	input.GuestIp6Start = conf.start
+	input.GuestIp6End = conf.end
+	input.GuestIp6Mask = &conf.masklen
+	input.GuestGateway6 = conf.gateway
+	input.GuestDns6 = conf.dns
+	input.GuestIp6Mode = conf.mode
+	return nil
+}
+
+// IsIPv6Enabled tells whether the network is dual-stack
+func (self *SNetwork) IsIPv6Enabled() bool {
+	return len(self.GuestIp6Start) > 0 && len(self.GuestIp6End) > 0 && self.GuestIp6Mask > 0
+}
+
+func (self *SNetwork) GetIP6Range() netutils2.IPV6AddrRange {
+	return self.getIP6Range()
+}
+
+func (self *SNetwork) getIP6Range() netutils2.IPV6AddrRange {
+	start, _ := netutils2.NewIPV6Addr(self.GuestIp6Start)
+	end, _ := netutils2.NewIPV6Addr(self.GuestIp6End)
+	return netutils2.NewIPV6AddrRange(start, end)
+}
+
+func (self *SNetwork) GetIp6Mode() string {
+	if len(self.GuestIp6Mode) > 0 {
+		return self.GuestIp6Mode
+	}
+	return api.NETWORK_IP6_MODE_DHCPV6
+}
+
+// GetGateway6 returns the ipv6 gateway, defaults to the first address of the prefix
+func (self *SNetwork) GetGateway6() string {
+	if len(self.GuestGateway6) > 0 {
+		return self.GuestGateway6
+	}
+	start, _ := netutils2.NewIPV6Addr(self.GuestIp6Start)
+	return start.NetAddr(self.GuestIp6Mask).StepUp().String()
+}
+
+func (self *SNetwork) GetUsedAddresses6() map[string]bool {
+	used := make(map[string]bool)
+	if self.IsIPv6Enabled() {
+		used[self.GetGateway6()] = true
+	}
+	q := GuestnetworkManager.usedAddress6Query(self)
+	results, err := q.AllStringMap()
+	if err != nil {
+		log.Errorf("GetUsedAddresses6 fail %s", err)
+		return used
+	}
+	for _, result := range results {
+		used[result["ip6_addr"]] = true
+	}
+	return used
+}
+
+func (self *SNetwork) isAddress6Used(address string) (bool, error) {
+	q := GuestnetworkManager.usedAddress6Query(self)
+	q = q.Filter(sqlchemy.Equals(q.Field("ip6_addr"), address))
+	cnt, err := q.CountWithError()
+	if err != nil {
+		return false, err
+	}
+	return cnt > 0, nil
+}
+
+func (self *SNetwork) getFreeIP6(addrTable map[string]bool, candidate string, mac string, allocDir api.IPAllocationDirection) (string, error) {
+	iprange := self.getIP6Range()
+	if len(candidate) > 0 {
+		candIP, err := netutils2.NewIPV6Addr(candidate)
+		if err != nil {
+			return "", httperrors.NewInputParameterError("invalid ipv6 address %s", candidate)
+		}
+		if !iprange.Contains(candIP) {
+			return "", httperrors.NewInputParameterError("candidate %s out of range", candidate)
+		}
+		if addrTable[candIP.String()] {
+			return "", httperrors.NewConflictError("candidate ip %s is occupied", candidate)
+		}
+		return candIP.String(), nil
+	}
+	if self.GetIp6Mode() == api.NETWORK_IP6_MODE_SLAAC {
+		ip, err := netutils2.IPV6EUI64(iprange.StartIp(), mac)
+		if err != nil {
+			return "", httperrors.NewInputParameterError("%v", err)
+		}
+		if !iprange.Contains(ip) {
+			return "", httperrors.NewOutOfRangeError("slaac address %s out of range", ip.String())
+		}
+		if addrTable[ip.String()] {
+			return "", httperrors.NewConflictError("slaac address %s is occupied", ip.String())
+		}
+		return ip.String(), nil
+	}
+	if len(self.AllocPolicy) > 0 && api.IPAllocationDirection(self.AllocPolicy) != api.IPAllocationNone {
+		allocDir = api.IPAllocationDirection(self.AllocPolicy)
+	}
+	if allocDir == api.IPAllocationRadnom {
+		const MAX_TRIES = 5
+		for i := 0; i < MAX_TRIES; i += 1 {
+			ip := iprange.Random()
+			if !addrTable[ip.String()] {
+				return ip.String(), nil
+			}
+		}
+	}
+	// ipv6 ranges are huge, only walk as far as the used addresses go
+	ip := iprange.StartIp()
+	for i := 0; i <= len(addrTable) && iprange.Contains(ip); i++ {
+		if !addrTable[ip.String()] {
+			return ip.String(), nil
+		}
+		ip = ip.StepUp()
+	}
+	return "", httperrors.NewInsufficientResourceError("Out of IPv6 address")
+}
+
+// GetIp6Prefix returns the ipv6 subnet in cidr notation
+func (self *SNetwork) GetIp6Prefix() string {
+	start, _ := netutils2.NewIPV6Addr(self.GuestIp6Start)
+	return fmt.Sprintf("%s/%d", start.NetAddr(self.GuestIp6Mask).String(), self.GuestIp6Mask)
+}
@@ -77,6 +77,13 @@ func (manager *SGuestnetworkManager) usedAddressQuery(args *usedAddressQueryArgs
 	return retq
 }
 
+// usedAddress6Query returns ipv6 addresses allocated in network, only guest
+// nics are dual-stack by now
+func (manager *SGuestnetworkManager) usedAddress6Query(network *SNetwork) *sqlchemy.SQuery {
+	baseq := GuestnetworkManager.Query().Equals("network_id", network.Id).IsNotEmpty("ip6_addr").SubQuery()
+	return baseq.Query(baseq.Field("ip6_addr"))
+}
+
 func (manager *SHostnetworkManager) usedAddressQuery(args *usedAddressQueryArgs) *sqlchemy.SQuery {
 	var (
 		baseq = HostnetworkManager.Query().Equals("network_id", args.network.Id).SubQuery()
@@ -96,6 +96,8 @@ type IRegionDriver interface {
 	ValidateCreateEipData(ctx context.Context, userCred mcclient.TokenCredential, input *api.SElasticipCreateInput) error
 	RequestCreateVpc(ctx context.Context, userCred mcclient.TokenCredential, region *SCloudregion, vpc *SVpc, task taskman.ITask) error
 	RequestDeleteVpc(ctx context.Context, userCred mcclient.TokenCredential, region *SCloudregion, vpc *SVpc, task taskman.ITask) error
+	RequestCreateVpcPeeringConnection(ctx context.Context, userCred mcclient.TokenCredential, peer *SVpcPeeringConnection, task taskman.ITask) error
+	RequestDeleteVpcPeeringConnection(ctx context.Context, userCred mcclient.TokenCredential, peer *SVpcPeeringConnection, task taskman.ITask) error
 
 	// Region Driver Snapshot Policy Apis
 	//ValidateCreateSnapshotPolicyData(context.Context, mcclient.TokenCredential, *compute.SSnapshotPolicyCreateInput, mcclient.IIdentityProvider, *jsonutils.JSONDict) error
@@ -189,6 +191,13 @@ type INatGatewayDriver interface {
 	IsSupportedNatGateway() bool
 	IsSupportedNatAutoRenew() bool
 	ValidateCreateNatGateway(ctx context.Context, userCred mcclient.TokenCredential, input api.NatgatewayCreateInput) (api.NatgatewayCreateInput, error)
+
+	RequestCreateNatGateway(ctx context.Context, userCred mcclient.TokenCredential, nat *SNatGateway, task taskman.ITask) error
+	RequestDeleteNatGateway(ctx context.Context, userCred mcclient.TokenCredential, nat *SNatGateway, task taskman.ITask) error
+	RequestCreateNatSEntry(ctx context.Context, userCred mcclient.TokenCredential, snat *SNatSEntry, task taskman.ITask) error
+	RequestDeleteNatSEntry(ctx context.Context, userCred mcclient.TokenCredential, snat *SNatSEntry, task taskman.ITask) error
+	RequestCreateNatDEntry(ctx context.Context, userCred mcclient.TokenCredential, dnat *SNatDEntry, task taskman.ITask) error
+	RequestDeleteNatDEntry(ctx context.Context, userCred mcclient.TokenCredential, dnat *SNatDEntry, task taskman.ITask) error
 }
 
 type IElasticcacheDriver interface {
@@ -17,13 +17,16 @@ package models
 import (
 	"context"
 
+	"yunion.io/x/jsonutils"
 	"yunion.io/x/pkg/errors"
 	"yunion.io/x/sqlchemy"
 
 	api "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/validators"
 	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/httperrors"
 	"yunion.io/x/onecloud/pkg/mcclient"
 	"yunion.io/x/onecloud/pkg/util/stringutils2"
 )
@@ -87,6 +90,29 @@ func (manager *SRouteTableAssociationManager) ListItemFilter(
 	return q, nil
 }
 
+func (manager *SRouteTableAssociationManager) ValidateCreateData(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	ownerId mcclient.IIdentityProvider,
+	query jsonutils.JSONObject,
+	input api.RouteTableAssociationCreateInput,
+) (api.RouteTableAssociationCreateInput, error) {
+	var err error
+	input.StatusStandaloneResourceCreateInput, err = manager.SStatusStandaloneResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.StatusStandaloneResourceCreateInput)
+	if err != nil {
+		return input, errors.Wrap(err, "SStatusStandaloneResourceBaseManager.ValidateCreateData")
+	}
+	routeTableObj, err := validators.ValidateModel(userCred, RouteTableManager, &input.RouteTableId)
+	if err != nil {
+		return input, err
+	}
+	routeTable := routeTableObj.(*SRouteTable)
+	if vpc := routeTable.GetVpc(); vpc != nil && vpc.IsOneCloudVpc() {
+		return input, httperrors.NewNotSupportedError("routes of onecloud vpc apply to all its networks, association is not needed")
+	}
+	return input, nil
+}
+
 func (self *SRouteTableAssociation) syncRemoveAssociation(ctx context.Context, userCred mcclient.TokenCredential) error {
 	lockman.LockObject(ctx, self)
 	defer lockman.ReleaseObject(ctx, self)
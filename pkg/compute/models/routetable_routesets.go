@@ -127,6 +127,9 @@ func (manager *SRouteTableRouteSetManager) ValidateCreateData(
 	if !routeTable.IsOwner(userCred) && !userCred.HasSystemAdminPrivilege() {
 		return input, httperrors.NewForbiddenError("not enough privilege")
 	}
+	if vpc := routeTable.GetVpc(); vpc != nil && vpc.IsOneCloudVpc() {
+		return input, httperrors.NewUnsupportOperationError("routes of onecloud vpc are managed with add-routes of route table")
+	}
 
 	if input.NextHopType != api.Next_HOP_TYPE_VPCPEERING {
 		return input, httperrors.NewNotSupportedError("not supported next hop type %s", input.NextHopType)
@@ -233,6 +236,9 @@ func (self *SRouteTableRouteSet) ValidateUpdateData(
 	if err != nil {
 		return input, httperrors.NewGeneralError(err)
 	}
+	if vpc.IsOneCloudVpc() {
+		return input, httperrors.NewUnsupportOperationError("routes of onecloud vpc are managed with add-routes of route table")
+	}
 
 	account := vpc.GetCloudaccount()
 	factory, err := account.GetProviderFactory()
@@ -276,6 +282,9 @@ func (self *SRouteTableRouteSet) ValidateDeleteCondition(ctx context.Context) er
 	if err != nil {
 		return errors.Wrap(err, "self.GetVpc()")
 	}
+	if vpc.IsOneCloudVpc() {
+		return httperrors.NewUnsupportOperationError("routes of onecloud vpc are managed with del-routes of route table")
+	}
 	account := vpc.GetCloudaccount()
 	factory, err := account.GetProviderFactory()
 	if err != nil {
@@ -16,13 +16,16 @@ package models
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"strings"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
 	"yunion.io/x/pkg/errors"
 	"yunion.io/x/pkg/gotypes"
 	"yunion.io/x/pkg/util/compare"
+	"yunion.io/x/pkg/util/netutils"
 	"yunion.io/x/sqlchemy"
 
 	api "yunion.io/x/onecloud/pkg/apis/compute"
@@ -155,10 +158,17 @@ func (man *SRouteTableManager) ValidateCreateData(
 	if err != nil {
 		return input, errors.Wrap(err, "validateRoutes")
 	}
-	_, err = validators.ValidateModel(userCred, VpcManager, &input.VpcId)
+	vpcObj, err := validators.ValidateModel(userCred, VpcManager, &input.VpcId)
 	if err != nil {
 		return input, err
 	}
+	vpc := vpcObj.(*SVpc)
+	if vpc.IsOneCloudVpc() && input.Routes != nil {
+		err := man.validateOneCloudRoutes(userCred, vpc, "", *input.Routes)
+		if err != nil {
+			return input, err
+		}
+	}
 	input.StatusInfrasResourceBaseCreateInput, err = man.SStatusInfrasResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.StatusInfrasResourceBaseCreateInput)
 	if err != nil {
 		return input, errors.Wrap(err, "SStatusInfrasResourceBaseManager.ValidateCreateData")
@@ -166,6 +176,112 @@ func (man *SRouteTableManager) ValidateCreateData(
 	return input, nil
 }
 
+// validateOneCloudRoutes checks routes of route table on onecloud vpc.
+// Routes of all route tables of the vpc will be programmed into the vpc
+// router, so destination cidrs must be unique across them
+func (man *SRouteTableManager) validateOneCloudRoutes(userCred mcclient.TokenCredential, vpc *SVpc, routeTableId string, routes api.SRoutes) error {
+	if err := routes.Validate(); err != nil {
+		return err
+	}
+	found := map[string]string{}
+	routeTables := vpc.GetRouteTables()
+	for i := range routeTables {
+		routeTable := &routeTables[i]
+		if routeTable.Id == routeTableId || routeTable.Routes == nil {
+			continue
+		}
+		for _, route := range *routeTable.Routes {
+			found[oneCloudRouteCidrKey(route.Cidr)] = routeTable.Name
+		}
+	}
+	if err := checkOneCloudRouteCidrs(vpc, routes, found); err != nil {
+		return err
+	}
+	var networks []SNetwork
+	for _, route := range routes {
+		if route.Type == "" {
+			route.Type = api.ROUTE_ENTRY_TYPE_CUSTOM
+		}
+		switch route.NextHopType {
+		case api.Next_HOP_TYPE_IP:
+			ip, err := netutils.NewIPV4Addr(route.NextHopId)
+			if err != nil {
+				return httperrors.NewInputParameterError("invalid next hop ip %q", route.NextHopId)
+			}
+			if networks == nil {
+				networks, err = vpc.GetNetworks()
+				if err != nil {
+					return httperrors.NewGeneralError(err)
+				}
+			}
+			inVpc := false
+			for i := range networks {
+				if networks[i].IsAddressInRange(ip) {
+					inVpc = true
+					break
+				}
+			}
+			if !inVpc {
+				return httperrors.NewInputParameterError("next hop %s is not in any network of vpc %s", route.NextHopId, vpc.Name)
+			}
+		case api.Next_HOP_TYPE_VPCPEERING:
+			peerObj, err := VpcPeeringConnectionManager.FetchByIdOrName(userCred, route.NextHopId)
+			if err != nil {
+				if errors.Cause(err) == sql.ErrNoRows {
+					return httperrors.NewResourceNotFoundError2(VpcPeeringConnectionManager.Keyword(), route.NextHopId)
+				}
+				return httperrors.NewGeneralError(err)
+			}
+			peer := peerObj.(*SVpcPeeringConnection)
+			if peer.VpcId != vpc.Id && peer.PeerVpcId != vpc.Id {
+				return httperrors.NewInputParameterError("vpc peering connection %s is not of vpc %s", peer.Name, vpc.Name)
+			}
+			route.NextHopId = peer.Id
+		default:
+			return httperrors.NewNotSupportedError("not supported next hop type %q, want %s or %s",
+				route.NextHopType, api.Next_HOP_TYPE_IP, api.Next_HOP_TYPE_VPCPEERING)
+		}
+	}
+	return nil
+}
+
+// oneCloudRouteCidrKey returns the key of route cidr as seen by the vpc
+// router, where a bare address is the same prefix as its /32 form
+func oneCloudRouteCidrKey(cidr string) string {
+	if !strings.Contains(cidr, "/") {
+		return cidr + "/32"
+	}
+	return cidr
+}
+
+// checkOneCloudRouteCidrs rejects routes whose destination is the default
+// route, is repeated in routes, or is in found, which maps cidr keys to
+// names of other route tables of the vpc
+func checkOneCloudRouteCidrs(vpc *SVpc, routes api.SRoutes, found map[string]string) error {
+	seen := map[string]struct{}{}
+	for _, route := range routes {
+		key := oneCloudRouteCidrKey(route.Cidr)
+		if key == "0.0.0.0/0" {
+			return httperrors.NewInputParameterError("default route of vpc %s is decided by its external access mode", vpc.Name)
+		}
+		if _, ok := seen[key]; ok {
+			return httperrors.NewInputParameterError("duplicate route cidr %s", route.Cidr)
+		}
+		if name, ok := found[key]; ok {
+			return httperrors.NewDuplicateResourceError("route cidr %s already exists in route table %s", route.Cidr, name)
+		}
+		seen[key] = struct{}{}
+	}
+	return nil
+}
+
+func (rt *SRouteTable) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) {
+	rt.SStatusInfrasResourceBase.PostCreate(ctx, userCred, ownerId, query, data)
+	if vpc := rt.GetVpc(); vpc != nil && vpc.IsOneCloudVpc() {
+		rt.SetStatus(userCred, api.ROUTE_TABLE_AVAILABLE, "")
+	}
+}
+
 func (rt *SRouteTable) AllowPerformPurge(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
 	return db.IsAdminAllowPerform(userCred, rt, "purge")
 }
@@ -221,6 +337,12 @@ func (rt *SRouteTable) ValidateUpdateData(
 	if err != nil {
 		return input, errors.Wrap(err, "RouteTableManager.validateRoutes")
 	}
+	if vpc := rt.GetVpc(); vpc != nil && vpc.IsOneCloudVpc() && input.Routes != nil {
+		err := RouteTableManager.validateOneCloudRoutes(userCred, vpc, rt.Id, *input.Routes)
+		if err != nil {
+			return input, err
+		}
+	}
 	input.StatusInfrasResourceBaseUpdateInput, err = rt.SStatusInfrasResourceBase.ValidateUpdateData(ctx, userCred, query, input.StatusInfrasResourceBaseUpdateInput)
 	if err != nil {
 		return input, errors.Wrap(err, "SStatusInfrasResourceBase.ValidateUpdateData")
@@ -252,6 +374,12 @@ func (rt *SRouteTable) PerformAddRoutes(ctx context.Context, userCred mcclient.T
 		if err != nil {
 			return nil, err
 		}
+		if vpc := rt.GetVpc(); vpc != nil && vpc.IsOneCloudVpc() {
+			err := RouteTableManager.validateOneCloudRoutes(userCred, vpc, rt.Id, adds)
+			if err != nil {
+				return nil, err
+			}
+		}
 		for _, add := range adds {
 			found := false
 			for _, route := range routes {
@@ -0,0 +1,58 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func TestCheckOneCloudRouteCidrs(t *testing.T) {
+	vpc := &SVpc{}
+	vpc.Name = "vpc0"
+	found := map[string]string{
+		"10.1.0.0/16":   "rt1",
+		"172.16.0.1/32": "rt1",
+	}
+	cases := []struct {
+		name    string
+		cidrs   []string
+		wantErr bool
+	}{
+		{"unique", []string{"10.2.0.0/16", "10.3.0.0/16", "172.16.0.2"}, false},
+		{"duplicate in input", []string{"10.2.0.0/16", "10.3.0.0/16", "10.2.0.0/16"}, true},
+		{"duplicate address in input", []string{"10.2.0.1", "10.2.0.1/32"}, true},
+		{"in other route table", []string{"10.2.0.0/16", "10.1.0.0/16"}, true},
+		{"address in other route table", []string{"172.16.0.1"}, true},
+		{"default route", []string{"0.0.0.0/0"}, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			routes := api.SRoutes{}
+			for _, cidr := range c.cidrs {
+				routes = append(routes, &api.SRoute{
+					Cidr:        cidr,
+					NextHopType: api.Next_HOP_TYPE_IP,
+					NextHopId:   "192.168.0.1",
+				})
+			}
+			err := checkOneCloudRouteCidrs(vpc, routes, found)
+			if (err != nil) != c.wantErr {
+				t.Errorf("want error %v, got %v", c.wantErr, err)
+			}
+		})
+	}
+}
@@ -81,6 +81,11 @@ type SScalingGroup struct {
 	HealthCheckCycle int    `nullable:"false" default:"300" create:"optional" list:"user" update:"user" get:"user"`
 	HealthCheckGov   int    `nullable:"false" default:"180" create:"optional" list:"user" update:"user" get:"user"`
 
+	// HealthCheckProbe describe how to probe the service of instance, tcp or http, empty means no probe
+	HealthCheckProbe string `width:"8" charset:"ascii" create:"optional" list:"user" update:"user" get:"user"`
+	HealthCheckPort  int    `nullable:"false" default:"0" create:"optional" list:"user" update:"user" get:"user"`
+	HealthCheckPath  string `width:"128" charset:"utf8" create:"optional" list:"user" update:"user" get:"user"`
+
 	LoadbalancerBackendPort   int `nullable:"false" default:"80" create:"optional" list:"user" get:"user"`
 	LoadbalancerBackendWeight int `nillable:"false" default:"1" create:"optional" list:"user" get:"user"`
 
@@ -209,6 +214,17 @@ func (sgm *SScalingGroupManager) ValidateCreateData(ctx context.Context, userCre
 		return input, httperrors.NewInputParameterError("unkown health check mode %s", input.HealthCheckMode)
 	}
 
+	// check health check probe
+	if !utils.IsInStringArray(input.HealthCheckProbe, []string{api.HEALTH_CHECK_PROBE_TCP, api.HEALTH_CHECK_PROBE_HTTP, ""}) {
+		return input, httperrors.NewInputParameterError("unkown health check probe %s", input.HealthCheckProbe)
+	}
+	if len(input.HealthCheckProbe) > 0 && (input.HealthCheckPort < 1 || input.HealthCheckPort > 65535) {
+		return input, httperrors.NewInputParameterError("invalid health check port '%d'", input.HealthCheckPort)
+	}
+	if input.HealthCheckProbe == api.HEALTH_CHECK_PROBE_HTTP && len(input.HealthCheckPath) == 0 {
+		input.HealthCheckPath = "/"
+	}
+
 	// check lb
 	if len(input.LbBackendGroup) != 0 {
 		idOrName = input.LbBackendGroup
@@ -375,6 +391,7 @@ func (sgm *SScalingGroupManager) FetchCustomizeColumns(
 		n, _ = sg.ScalingPolicyNumber()
 		rows[i].ScalingPolicyNumber = n
 		rows[i].Brand = Hypervisor2Brand(sg.Hypervisor)
+		rows[i].InstanceRefresh = sg.GetInstanceRefreshProgress()
 		nets, err := sg.Networks()
 		if err != nil {
 			log.Errorf("sg.Networks error: %s", err)
@@ -714,15 +731,24 @@ func (s *SGuest) PerformDetachScalingGroup(ctx context.Context, userCred mcclien
 	}
 	sg := model.(*SScalingGroup)
 	input.ScalingGroup = sggs[0].ScalingGroupId
-	sggs[0].SetGuestStatus(api.SG_GUEST_STATUS_REMOVING)
+	err = sg.StartDetachGuestTask(ctx, userCred, &sggs[0], input)
+	if err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (sg *SScalingGroup) StartDetachGuestTask(ctx context.Context, userCred mcclient.TokenCredential,
+	sgg *SScalingGroupGuest, input api.SGPerformDetachScalingGroupInput) error {
+	sgg.SetGuestStatus(api.SG_GUEST_STATUS_REMOVING)
 	taskData := jsonutils.Marshal(input).(*jsonutils.JSONDict)
-	taskData.Set("guest", jsonutils.NewString(s.GetId()))
+	taskData.Set("guest", jsonutils.NewString(sgg.GuestId))
 	task, err := taskman.TaskManager.NewTask(ctx, "GuestDetachScalingGroupTask", sg, userCred, taskData, "", "")
 	if err != nil {
-		return nil, errors.Wrap(err, "Start GuestDetachScalingGroupTask failed")
+		return errors.Wrap(err, "Start GuestDetachScalingGroupTask failed")
 	}
 	task.ScheduleRun(nil)
-	return nil, nil
+	return nil
 }
 
 func (sg *SScalingGroup) Networks() ([]SNetwork, error) {
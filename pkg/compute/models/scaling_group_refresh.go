@@ -0,0 +1,222 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+func (sg *SScalingGroup) AllowPerformInstanceRefresh(ctx context.Context, userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject, input api.ScalingGroupInstanceRefreshInput) bool {
+	return sg.IsOwner(userCred) || db.IsAdminAllowPerform(userCred, sg, "instance-refresh")
+}
+
+// PerformInstanceRefresh replaces the instances of scaling group in batches, so that all instances are created
+// from the latest guest template.
+func (sg *SScalingGroup) PerformInstanceRefresh(ctx context.Context, userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject, input api.ScalingGroupInstanceRefreshInput) (jsonutils.JSONObject, error) {
+	if !utils.IsInStringArray(sg.Status, []string{api.SG_STATUS_READY, api.SG_STATUS_INSTANCE_REFRESH_FAILED}) {
+		return nil, httperrors.NewInvalidStatusError("Can't refresh instances of scaling group in status %s", sg.Status)
+	}
+	if sg.Enabled.IsFalse() {
+		return nil, httperrors.NewForbiddenError("Can't refresh instances of disabled scaling group")
+	}
+	if len(input.GuestTemplate) > 0 {
+		model, err := GuestTemplateManager.FetchByIdOrName(userCred, input.GuestTemplate)
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, httperrors.NewInputParameterError("no such guest template %s", input.GuestTemplate)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "GuestTempalteManager.FetchByIdOrName")
+		}
+		gt := model.(*SGuestTemplate)
+		nets, err := sg.NetworkIds()
+		if err != nil {
+			return nil, errors.Wrap(err, "NetworkIds")
+		}
+		if ok, reason := gt.Validate(ctx, userCred, sg.GetOwnerId(),
+			SGuestTemplateValidate{sg.Hypervisor, sg.CloudregionId, sg.VpcId, nets}); !ok {
+			return nil, httperrors.NewInputParameterError("the guest template %s is not valid for scaling group, "+
+				"reason: %s", input.GuestTemplate, reason)
+		}
+		input.GuestTemplateId = gt.Id
+	} else {
+		input.GuestTemplateId = sg.GuestTemplateId
+	}
+	if input.MinHealthyPercentage == 0 {
+		input.MinHealthyPercentage = 90
+	}
+	if input.MinHealthyPercentage < 0 || input.MinHealthyPercentage > 100 {
+		return nil, httperrors.NewInputParameterError("min_healthy_percentage should between 0 and 100")
+	}
+	if input.DrainTime < 0 {
+		return nil, httperrors.NewInputParameterError("drain_time should not be negative")
+	}
+	if input.HealthCheckTimeout == 0 {
+		input.HealthCheckTimeout = 600
+	}
+	if input.HealthCheckTimeout < 60 {
+		return nil, httperrors.NewInputParameterError("the min value of health_check_timeout is 60")
+	}
+	guests, err := sg.Guests()
+	if err != nil {
+		return nil, errors.Wrap(err, "Guests")
+	}
+	if len(guests) == 0 && input.GuestTemplateId == sg.GuestTemplateId {
+		return nil, httperrors.NewBadRequestError("no instance need to refresh")
+	}
+	return nil, sg.StartInstanceRefreshTask(ctx, userCred, input, guests)
+}
+
+// instanceRefreshBatchSize returns the number of instances which can be removed at the same time
+// while keeping min healthy percentage of desire instance number.
+func (sg *SScalingGroup) instanceRefreshBatchSize(minHealthyPercentage int) int {
+	minHealthy := int(math.Ceil(float64(sg.DesireInstanceNumber) * float64(minHealthyPercentage) / 100))
+	size := sg.DesireInstanceNumber - minHealthy
+	if size < 1 {
+		size = 1
+	}
+	return size
+}
+
+func (sg *SScalingGroup) StartInstanceRefreshTask(ctx context.Context, userCred mcclient.TokenCredential,
+	input api.ScalingGroupInstanceRefreshInput, guests []SGuest) error {
+	guestIds := make([]string, 0, len(guests))
+	for i := range guests {
+		guestIds = append(guestIds, guests[i].Id)
+	}
+	params := jsonutils.Marshal(input).(*jsonutils.JSONDict)
+	params.Set("batch_size", jsonutils.NewInt(int64(sg.instanceRefreshBatchSize(input.MinHealthyPercentage))))
+	params.Set("origin_guests", jsonutils.NewStringArray(guestIds))
+	sg.SetStatus(userCred, api.SG_STATUS_INSTANCE_REFRESH, "")
+	task, err := taskman.TaskManager.NewTask(ctx, "ScalingGroupInstanceRefreshTask", sg, userCred, params, "", "")
+	if err != nil {
+		sg.SetStatus(userCred, api.SG_STATUS_INSTANCE_REFRESH_FAILED, err.Error())
+		return errors.Wrap(err, "Start ScalingGroupInstanceRefreshTask failed")
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
+
+func (sg *SScalingGroup) GetInstanceRefreshProgress() *api.ScalingGroupInstanceRefreshProgress {
+	str := sg.GetMetadata(api.SG_METADATA_INSTANCE_REFRESH, nil)
+	if len(str) == 0 {
+		return nil
+	}
+	progress := &api.ScalingGroupInstanceRefreshProgress{}
+	obj, err := jsonutils.ParseString(str)
+	if err != nil {
+		log.Errorf("parse instance refresh progress of scaling group %s: %v", sg.Id, err)
+		return nil
+	}
+	err = obj.Unmarshal(progress)
+	if err != nil {
+		log.Errorf("unmarshal instance refresh progress of scaling group %s: %v", sg.Id, err)
+		return nil
+	}
+	return progress
+}
+
+func (sg *SScalingGroup) SetInstanceRefreshProgress(ctx context.Context, userCred mcclient.TokenCredential,
+	progress *api.ScalingGroupInstanceRefreshProgress) error {
+	progress.UpdateAt = time.Now()
+	return sg.SetMetadata(ctx, api.SG_METADATA_INSTANCE_REFRESH, jsonutils.Marshal(progress).String(), userCred)
+}
+
+func (sg *SScalingGroup) SetGuestTemplateId(templateId string) error {
+	if sg.GuestTemplateId == templateId {
+		return nil
+	}
+	_, err := db.Update(sg, func() error {
+		sg.GuestTemplateId = templateId
+		return nil
+	})
+	return err
+}
+
+// StartDrainInstance removes the loadbalancer backend of guest, the loadbalancer stops to send new requests
+// to the guest and the guest is deleted after drain time.
+func (sg *SScalingGroup) StartDrainInstance(ctx context.Context, userCred mcclient.TokenCredential, guestId string,
+	parentTaskId string) (bool, error) {
+	if len(sg.BackendGroupId) == 0 {
+		return false, nil
+	}
+	q := LoadbalancerBackendManager.Query().Equals("backend_id", guestId).Equals("backend_group_id", sg.BackendGroupId).
+		IsFalse("pending_deleted")
+	var lbBackend SLoadbalancerBackend
+	err := q.First(&lbBackend)
+	if errors.Cause(err) == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "fetch loadbalancer backend")
+	}
+	lbBackend.SetModelManager(LoadbalancerBackendManager, &lbBackend)
+	lbBackend.SetStatus(userCred, api.LB_STATUS_DELETING, "")
+	err = lbBackend.StartLoadBalancerBackendDeleteTask(ctx, userCred, jsonutils.NewDict(), parentTaskId)
+	if err != nil {
+		return false, errors.Wrap(err, "StartLoadBalancerBackendDeleteTask")
+	}
+	return true, nil
+}
+
+// CheckInstanceRefreshBatch checks whether the instances in batch have been replaced by healthy instances.
+// A non-empty reason is returned if the batch failed.
+func (sg *SScalingGroup) CheckInstanceRefreshBatch(batch []string) (bool, string) {
+	sggs := make([]SScalingGroupGuest, 0)
+	q := ScalingGroupGuestManager.Query().Equals("scaling_group_id", sg.Id)
+	err := db.FetchModelObjects(ScalingGroupGuestManager, q, &sggs)
+	if err != nil {
+		log.Errorf("fetch guests of scaling group %s: %v", sg.Id, err)
+		return false, ""
+	}
+	ready := make([]string, 0, len(sggs))
+	for i := range sggs {
+		if utils.IsInStringArray(sggs[i].GuestId, batch) {
+			if sggs[i].GuestStatus == api.SG_GUEST_STATUS_REMOVE_FAILED {
+				return false, fmt.Sprintf("remove instance %s failed", sggs[i].GuestId)
+			}
+			// still removing
+			return false, ""
+		}
+		if sggs[i].GuestStatus == api.SG_GUEST_STATUS_READY {
+			ready = append(ready, sggs[i].GuestId)
+		}
+	}
+	if len(ready) < sg.DesireInstanceNumber {
+		return false, ""
+	}
+	running, err := GuestManager.Query().In("id", ready).Equals("status", api.VM_RUNNING).CountWithError()
+	if err != nil {
+		log.Errorf("count running guests of scaling group %s: %v", sg.Id, err)
+		return false, ""
+	}
+	return running >= sg.DesireInstanceNumber, ""
+}
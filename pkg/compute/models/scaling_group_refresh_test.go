@@ -0,0 +1,42 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import "testing"
+
+func TestScalingGroupInstanceRefreshBatchSize(t *testing.T) {
+	cases := []struct {
+		name                 string
+		desire               int
+		minHealthyPercentage int
+		want                 int
+	}{
+		{"keep all healthy", 4, 100, 1},
+		{"no desire instance", 0, 90, 1},
+		{"exact percentage", 10, 90, 1},
+		{"round up min healthy", 10, 75, 2},
+		{"round up small group", 3, 50, 1},
+		{"no min healthy", 10, 0, 10},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			sg := &SScalingGroup{DesireInstanceNumber: c.desire}
+			got := sg.instanceRefreshBatchSize(c.minHealthyPercentage)
+			if got != c.want {
+				t.Errorf("desire %d min healthy %d%%: want %d, got %d", c.desire, c.minHealthyPercentage, c.want, got)
+			}
+		})
+	}
+}
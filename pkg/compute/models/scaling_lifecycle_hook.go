@@ -0,0 +1,526 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/stringutils"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	"yunion.io/x/onecloud/pkg/apis"
+	ansible_apis "yunion.io/x/onecloud/pkg/apis/ansible"
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	mcclient_models "yunion.io/x/onecloud/pkg/mcclient/models"
+	mcclient_modules "yunion.io/x/onecloud/pkg/mcclient/modules"
+	"yunion.io/x/onecloud/pkg/util/ansible"
+	"yunion.io/x/onecloud/pkg/util/httputils"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+type SScalingLifecycleHookManager struct {
+	db.SVirtualResourceBaseManager
+	SScalingGroupResourceBaseManager
+}
+
+// SScalingLifecycleHook puts the instance of scaling group into pending state when it is launching or terminating,
+// notifies the webhook or runs the ansible playbook, and waits for the callback or timeout.
+type SScalingLifecycleHook struct {
+	db.SVirtualResourceBase
+	SScalingGroupResourceBase
+
+	Transition string `width:"16" charset:"ascii" nullable:"false" create:"required" list:"user" get:"user"`
+	NotifyType string `width:"16" charset:"ascii" nullable:"false" create:"required" list:"user" get:"user"`
+	WebhookUrl string `width:"256" charset:"ascii" create:"optional" list:"user" get:"user" update:"user"`
+	// WebhookSkipTlsVerify skips verifying the certificate of https webhook, e.g. a self-signed one
+	WebhookSkipTlsVerify bool `nullable:"false" default:"false" create:"optional" list:"user" get:"user" update:"user"`
+
+	// AnsiblePlaybook is the template playbook, it is copied with the instance as inventory when notifying
+	AnsiblePlaybookId string `width:"36" charset:"ascii" create:"optional" list:"user" get:"user"`
+
+	// Timeout in seconds to wait for the callback
+	Timeout       int    `nullable:"false" default:"300" create:"optional" list:"user" get:"user" update:"user"`
+	DefaultResult string `width:"16" charset:"ascii" default:"continue" create:"optional" list:"user" get:"user" update:"user"`
+}
+
+var ScalingLifecycleHookManager *SScalingLifecycleHookManager
+
+func init() {
+	ScalingLifecycleHookManager = &SScalingLifecycleHookManager{
+		SVirtualResourceBaseManager: db.NewVirtualResourceBaseManager(
+			SScalingLifecycleHook{},
+			"scalinglifecyclehooks_tbl",
+			"scalinglifecyclehook",
+			"scalinglifecyclehooks",
+		),
+	}
+	ScalingLifecycleHookManager.SetVirtualObject(ScalingLifecycleHookManager)
+}
+
+func (hm *SScalingLifecycleHookManager) ListItemFilter(ctx context.Context, q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential, input api.ScalingLifecycleHookListInput) (*sqlchemy.SQuery, error) {
+	var err error
+	q, err = hm.SVirtualResourceBaseManager.ListItemFilter(ctx, q, userCred, input.VirtualResourceListInput)
+	if err != nil {
+		return q, err
+	}
+	q, err = hm.SScalingGroupResourceBaseManager.ListItemFilter(ctx, q, userCred, input.ScalingGroupFilterListInput)
+	if err != nil {
+		return q, err
+	}
+	if len(input.Transition) != 0 {
+		q = q.Equals("transition", input.Transition)
+	}
+	return q, nil
+}
+
+func (hm *SScalingLifecycleHookManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	q, err := hm.SVirtualResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	return hm.SScalingGroupResourceBaseManager.QueryDistinctExtraField(q, field)
+}
+
+func (hook *SScalingLifecycleHook) GetUniqValues() jsonutils.JSONObject {
+	return jsonutils.Marshal(map[string]string{"scaling_group_id": hook.ScalingGroupId})
+}
+
+func (hm *SScalingLifecycleHookManager) FetchUniqValues(ctx context.Context, data jsonutils.JSONObject) jsonutils.JSONObject {
+	return hm.SScalingGroupResourceBaseManager.FetchUniqValues(ctx, data)
+}
+
+func (hm *SScalingLifecycleHookManager) FilterByUniqValues(q *sqlchemy.SQuery, values jsonutils.JSONObject) *sqlchemy.SQuery {
+	return hm.SScalingGroupResourceBaseManager.FilterByUniqValues(q, values)
+}
+
+func (hm *SScalingLifecycleHookManager) OrderByExtraFields(ctx context.Context, q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential, query api.ScalingLifecycleHookListInput) (*sqlchemy.SQuery, error) {
+	return hm.SVirtualResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.VirtualResourceListInput)
+}
+
+func (hook *SScalingLifecycleHook) GetExtraDetails(ctx context.Context, userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject, isList bool) (api.ScalingLifecycleHookDetails, error) {
+	return api.ScalingLifecycleHookDetails{}, nil
+}
+
+func (hm *SScalingLifecycleHookManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.ScalingLifecycleHookDetails {
+	rows := make([]api.ScalingLifecycleHookDetails, len(objs))
+	virtRows := hm.SVirtualResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	sgRows := hm.SScalingGroupResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	for i := range rows {
+		rows[i].VirtualResourceDetails = virtRows[i]
+		rows[i].ScalingGroupResourceInfo = sgRows[i]
+	}
+	return rows
+}
+
+func (hm *SScalingLifecycleHookManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential,
+	ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, input api.ScalingLifecycleHookCreateInput) (
+	api.ScalingLifecycleHookCreateInput, error) {
+	var err error
+	input.VirtualResourceCreateInput, err = hm.SVirtualResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query,
+		input.VirtualResourceCreateInput)
+	if err != nil {
+		return input, err
+	}
+
+	// check scaling group
+	idOrName := input.ScalingGroup
+	if len(input.ScalingGroupId) != 0 {
+		idOrName = input.ScalingGroupId
+	}
+	model, err := ScalingGroupManager.FetchByIdOrName(userCred, idOrName)
+	if errors.Cause(err) == sql.ErrNoRows {
+		return input, httperrors.NewInputParameterError("no such scaling group %s", idOrName)
+	}
+	if err != nil {
+		return input, errors.Wrap(err, "ScalingGroupManager.FetchByIdOrName")
+	}
+	input.ScalingGroupId = model.GetId()
+
+	if !utils.IsInStringArray(input.Transition, []string{api.LIFECYCLE_TRANSITION_LAUNCHING, api.LIFECYCLE_TRANSITION_TERMINATING}) {
+		return input, httperrors.NewInputParameterError("unkown lifecycle transition %s", input.Transition)
+	}
+	switch input.NotifyType {
+	case api.LIFECYCLE_NOTIFY_WEBHOOK:
+		if err := validateWebhookUrl(input.WebhookUrl); err != nil {
+			return input, httperrors.NewInputParameterError("invalid webhook url '%s': %v", input.WebhookUrl, err)
+		}
+	case api.LIFECYCLE_NOTIFY_ANSIBLE:
+		if len(input.AnsiblePlaybook) == 0 {
+			return input, httperrors.NewMissingParameterError("ansible_playbook")
+		}
+		session := auth.GetSession(ctx, userCred, "", "")
+		pbJson, err := mcclient_modules.AnsiblePlaybooks.Get(session, input.AnsiblePlaybook, nil)
+		if err != nil {
+			return input, httperrors.NewInputParameterError("fetch ansible playbook %s: %v", input.AnsiblePlaybook, err)
+		}
+		input.AnsiblePlaybookId, _ = pbJson.GetString("id")
+	default:
+		return input, httperrors.NewInputParameterError("unkown notify type %s", input.NotifyType)
+	}
+	if input.Timeout == 0 {
+		input.Timeout = 300
+	}
+	if input.Timeout < 30 || input.Timeout > 7200 {
+		return input, httperrors.NewInputParameterError("timeout should between 30 and 7200")
+	}
+	if len(input.DefaultResult) == 0 {
+		input.DefaultResult = api.LIFECYCLE_RESULT_CONTINUE
+	}
+	if !utils.IsInStringArray(input.DefaultResult, []string{api.LIFECYCLE_RESULT_CONTINUE, api.LIFECYCLE_RESULT_ABANDON}) {
+		return input, httperrors.NewInputParameterError("unkown lifecycle result %s", input.DefaultResult)
+	}
+	return input, nil
+}
+
+func (hook *SScalingLifecycleHook) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject, data *jsonutils.JSONDict) (*jsonutils.JSONDict, error) {
+	if timeout, err := data.Int("timeout"); err == nil && (timeout < 30 || timeout > 7200) {
+		return nil, httperrors.NewInputParameterError("timeout should between 30 and 7200")
+	}
+	if webhookUrl, err := data.GetString("webhook_url"); err == nil && hook.NotifyType == api.LIFECYCLE_NOTIFY_WEBHOOK {
+		if err := validateWebhookUrl(webhookUrl); err != nil {
+			return nil, httperrors.NewInputParameterError("invalid webhook url '%s': %v", webhookUrl, err)
+		}
+	}
+	if result, _ := data.GetString("default_result"); len(result) > 0 &&
+		!utils.IsInStringArray(result, []string{api.LIFECYCLE_RESULT_CONTINUE, api.LIFECYCLE_RESULT_ABANDON}) {
+		return nil, httperrors.NewInputParameterError("unkown lifecycle result %s", result)
+	}
+	input := apis.VirtualResourceBaseUpdateInput{}
+	err := data.Unmarshal(&input)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+	input, err = hook.SVirtualResourceBase.ValidateUpdateData(ctx, userCred, query, input)
+	if err != nil {
+		return nil, errors.Wrap(err, "SVirtualResourceBase.ValidateUpdateData")
+	}
+	data.Update(jsonutils.Marshal(input))
+	return data, nil
+}
+
+func (hook *SScalingLifecycleHook) CustomizeCreate(ctx context.Context, userCred mcclient.TokenCredential,
+	ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) error {
+	// hook.Project must be same with hook.ScalingGroup
+	sg := hook.GetScalingGroup()
+	if sg == nil {
+		return errors.Wrapf(errors.ErrNotFound, "scaling group %s", hook.ScalingGroupId)
+	}
+	ownerId = sg.GetOwnerId()
+	return hook.SVirtualResourceBase.CustomizeCreate(ctx, userCred, ownerId, query, data)
+}
+
+func (sg *SScalingGroup) LifecycleHooks(transition string) ([]SScalingLifecycleHook, error) {
+	q := ScalingLifecycleHookManager.Query().Equals("scaling_group_id", sg.Id).Equals("transition", transition).
+		Asc("created_at")
+	hooks := make([]SScalingLifecycleHook, 0)
+	err := db.FetchModelObjects(ScalingLifecycleHookManager, q, &hooks)
+	return hooks, err
+}
+
+// StartLifecycleHookTask puts the guest into pending state and runs the lifecycle hooks of transition one by one
+// in ScalingLifecycleHookTask, the final result is saved in the scaling group guest. It returns false if there
+// is no hook to run.
+func (sg *SScalingGroup) StartLifecycleHookTask(ctx context.Context, userCred mcclient.TokenCredential, guest *SGuest,
+	transition string, parentTaskId string) (bool, error) {
+	hooks, err := sg.LifecycleHooks(transition)
+	if err != nil {
+		return false, errors.Wrapf(err, "fetch %s lifecycle hooks", transition)
+	}
+	if len(hooks) == 0 {
+		return false, nil
+	}
+	sggs, err := ScalingGroupGuestManager.Fetch(sg.Id, guest.Id)
+	if err != nil {
+		return false, errors.Wrap(err, "ScalingGroupGuestManager.Fetch")
+	}
+	if len(sggs) == 0 {
+		return false, nil
+	}
+	sgg := &sggs[0]
+	hookIds := make([]string, 0, len(hooks))
+	for i := range hooks {
+		hookIds = append(hookIds, hooks[i].Id)
+	}
+	params := jsonutils.NewDict()
+	params.Set("guest_id", jsonutils.NewString(guest.Id))
+	params.Set("transition", jsonutils.NewString(transition))
+	params.Set("hook_ids", jsonutils.NewStringArray(hookIds))
+	params.Set("origin_status", jsonutils.NewString(sgg.GuestStatus))
+
+	status := api.SG_GUEST_STATUS_PENDING_LAUNCH
+	if transition == api.LIFECYCLE_TRANSITION_TERMINATING {
+		status = api.SG_GUEST_STATUS_PENDING_TERMINATE
+	}
+	originStatus := sgg.GuestStatus
+	err = sgg.SetLifecycleAction("", "", time.Time{})
+	if err != nil {
+		return false, errors.Wrap(err, "reset lifecycle action")
+	}
+	sgg.SetGuestStatus(status)
+	task, err := taskman.TaskManager.NewTask(ctx, "ScalingLifecycleHookTask", sg, userCred, params, parentTaskId, "", nil)
+	if err != nil {
+		sgg.SetGuestStatus(originStatus)
+		return false, errors.Wrap(err, "Start ScalingLifecycleHookTask failed")
+	}
+	task.ScheduleRun(nil)
+	return true, nil
+}
+
+// NotifyLifecycleAction makes the guest waiting for the hook, then notifies the webhook or runs the ansible
+// playbook. It returns the token of the action and the id of the ansible playbook.
+func (hook *SScalingLifecycleHook) NotifyLifecycleAction(ctx context.Context, sg *SScalingGroup, guest *SGuest,
+	sgg *SScalingGroupGuest) (string, string, error) {
+	token := stringutils.UUID4()
+	deadline := time.Now().Add(time.Duration(hook.Timeout) * time.Second)
+	err := sgg.SetLifecycleAction(hook.Id, token, deadline)
+	if err != nil {
+		return "", "", errors.Wrap(err, "set lifecycle action")
+	}
+	var pbId string
+	switch hook.NotifyType {
+	case api.LIFECYCLE_NOTIFY_WEBHOOK:
+		err = hook.notifyWebhook(ctx, sg, guest, token)
+	case api.LIFECYCLE_NOTIFY_ANSIBLE:
+		pbId, err = hook.runAnsiblePlaybook(ctx, guest, token)
+	default:
+		err = fmt.Errorf("unkown notify type %s", hook.NotifyType)
+	}
+	return token, pbId, err
+}
+
+// CheckLifecycleAction checks whether the lifecycle action is completed by the callback, the ansible playbook
+// or the timeout, and returns the result and reason if so.
+func (hook *SScalingLifecycleHook) CheckLifecycleAction(ctx context.Context, sgg *SScalingGroupGuest, token string,
+	pbId string) (bool, string, string) {
+	if sgg.LifecycleToken == token && len(sgg.LifecycleResult) > 0 {
+		return true, sgg.LifecycleResult, "completed by callback"
+	}
+	if len(pbId) > 0 {
+		session := auth.GetAdminSession(ctx, options.Options.Region, "")
+		pbJson, err := mcclient_modules.AnsiblePlaybooks.Get(session, pbId, nil)
+		if err != nil {
+			log.Errorf("fetch ansible playbook %s: %v", pbId, err)
+		} else {
+			status, _ := pbJson.GetString("status")
+			switch status {
+			case ansible_apis.AnsiblePlaybookStatusSucceeded:
+				return true, api.LIFECYCLE_RESULT_CONTINUE, "ansible playbook succeeded"
+			case ansible_apis.AnsiblePlaybookStatusFailed, ansible_apis.AnsiblePlaybookStatusCanceled:
+				return true, hook.DefaultResult, fmt.Sprintf("ansible playbook %s", status)
+			}
+		}
+	}
+	if time.Now().After(sgg.LifecycleDeadline) {
+		return true, hook.DefaultResult, "timeout"
+	}
+	return false, "", ""
+}
+
+var webhookPrivateNets = []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "100.64.0.0/10", "fc00::/7"}
+
+// checkWebhookIP rejects the addresses which the webhook should not access, e.g. the metadata service and,
+// unless allowed by option, the services of the cloud platform itself in private network.
+func checkWebhookIP(ip net.IP) error {
+	if ip == nil {
+		return fmt.Errorf("invalid address")
+	}
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("address %s is not allowed", ip)
+	}
+	if options.Options.LifecycleHookWebhookAllowPrivate {
+		return nil
+	}
+	for _, cidr := range webhookPrivateNets {
+		_, ipnet, _ := net.ParseCIDR(cidr)
+		if ipnet.Contains(ip) {
+			return fmt.Errorf("private address %s is not allowed", ip)
+		}
+	}
+	return nil
+}
+
+// validateWebhookUrl checks the webhook url, the address of host name is checked when connecting
+func validateWebhookUrl(webhookUrl string) error {
+	u, err := url.Parse(webhookUrl)
+	if err != nil {
+		return errors.Wrap(err, "parse url")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	host := u.Hostname()
+	if len(host) == 0 {
+		return fmt.Errorf("empty host")
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return checkWebhookIP(ip)
+	}
+	return nil
+}
+
+var (
+	webhookClient         = newWebhookClient(false)
+	insecureWebhookClient = newWebhookClient(true)
+)
+
+// newWebhookClient returns the http client which checks the resolved address of every connection, including
+// the redirected ones.
+func newWebhookClient(insecure bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			return checkWebhookIP(net.ParseIP(host))
+		},
+	}
+	tr := httputils.GetTransport(insecure)
+	// the address of proxy would be checked instead of the webhook
+	tr.Proxy = nil
+	tr.DialContext = dialer.DialContext
+	return &http.Client{Transport: tr, Timeout: 30 * time.Second}
+}
+
+// getWebhookClient returns the shared client, certificate of webhook is verified unless the hook opts out
+func getWebhookClient(skipTlsVerify bool) *http.Client {
+	if skipTlsVerify {
+		return insecureWebhookClient
+	}
+	return webhookClient
+}
+
+func (hook *SScalingLifecycleHook) notifyWebhook(ctx context.Context, sg *SScalingGroup, guest *SGuest, token string) error {
+	ips := guest.GetRealIPs()
+	notification := api.ScalingLifecycleHookNotification{
+		HookId:         hook.Id,
+		HookName:       hook.Name,
+		ScalingGroupId: sg.Id,
+		Transition:     hook.Transition,
+		GuestId:        guest.Id,
+		GuestName:      guest.Name,
+		Ips:            ips,
+		Token:          token,
+		Timeout:        hook.Timeout,
+	}
+	client := getWebhookClient(hook.WebhookSkipTlsVerify)
+	_, _, err := httputils.JSONRequest(client, ctx, httputils.POST, hook.WebhookUrl, nil, jsonutils.Marshal(notification), false)
+	return err
+}
+
+// runAnsiblePlaybook copies the template playbook with the guest as inventory, the new playbook is run once created.
+func (hook *SScalingLifecycleHook) runAnsiblePlaybook(ctx context.Context, guest *SGuest, token string) (string, error) {
+	ips := guest.GetRealIPs()
+	if len(ips) == 0 {
+		return "", fmt.Errorf("instance %s has no ip", guest.Name)
+	}
+	session := auth.GetAdminSession(ctx, options.Options.Region, "")
+	pbJson, err := mcclient_modules.AnsiblePlaybooks.Get(session, hook.AnsiblePlaybookId, nil)
+	if err != nil {
+		return "", errors.Wrapf(err, "fetch ansible playbook %s", hook.AnsiblePlaybookId)
+	}
+	template := &mcclient_models.AnsiblePlaybook{}
+	err = pbJson.Unmarshal(template)
+	if err != nil || template.Playbook == nil {
+		return "", fmt.Errorf("invalid ansible playbook %s", hook.AnsiblePlaybookId)
+	}
+	pb := *template.Playbook
+	pb.Inventory = ansible.Inventory{
+		Hosts: []ansible.Host{
+			{
+				Name: ips[0],
+				Vars: map[string]string{
+					"scaling_group_id":     hook.ScalingGroupId,
+					"guest_id":             guest.Id,
+					"lifecycle_transition": hook.Transition,
+				},
+			},
+		},
+	}
+	input := &ansible_apis.AnsiblePlaybookCreateInput{
+		Name:     fmt.Sprintf("%s-%s-%s", hook.Name, guest.Id[:8], token[:8]),
+		Playbook: pb,
+	}
+	pbJson, err = mcclient_modules.AnsiblePlaybooks.Create(session, input.JSON(input))
+	if err != nil {
+		return "", errors.Wrap(err, "create ansible playbook")
+	}
+	return pbJson.GetString("id")
+}
+
+func (sg *SScalingGroup) AllowPerformCompleteLifecycleAction(ctx context.Context, userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject, input api.ScalingGroupCompleteLifecycleActionInput) bool {
+	return sg.IsOwner(userCred) || db.IsAdminAllowPerform(userCred, sg, "complete-lifecycle-action")
+}
+
+// PerformCompleteLifecycleAction is the callback of lifecycle hook, the instance waiting for the hook goes on
+// launching or terminating according to the result.
+func (sg *SScalingGroup) PerformCompleteLifecycleAction(ctx context.Context, userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject, input api.ScalingGroupCompleteLifecycleActionInput) (jsonutils.JSONObject, error) {
+	if len(input.Result) == 0 {
+		input.Result = api.LIFECYCLE_RESULT_CONTINUE
+	}
+	if !utils.IsInStringArray(input.Result, []string{api.LIFECYCLE_RESULT_CONTINUE, api.LIFECYCLE_RESULT_ABANDON}) {
+		return nil, httperrors.NewInputParameterError("unkown lifecycle result %s", input.Result)
+	}
+	if len(input.Guest) == 0 {
+		return nil, httperrors.NewMissingParameterError("guest")
+	}
+	guestId := input.Guest
+	if model, err := GuestManager.FetchByIdOrName(userCred, input.Guest); err == nil {
+		guestId = model.GetId()
+	}
+	sggs, err := ScalingGroupGuestManager.Fetch(sg.Id, guestId)
+	if err != nil {
+		return nil, errors.Wrap(err, "ScalingGroupGuestManager.Fetch")
+	}
+	if len(sggs) == 0 {
+		return nil, httperrors.NewResourceNotFoundError("instance %s is not in scaling group %s", input.Guest, sg.Name)
+	}
+	sgg := &sggs[0]
+	if len(sgg.LifecycleHookId) == 0 || sgg.LifecycleToken != input.Token {
+		return nil, httperrors.NewForbiddenError("instance %s is not waiting for the lifecycle action or token mismatch", input.Guest)
+	}
+	return nil, sgg.SetLifecycleResult(input.Result)
+}
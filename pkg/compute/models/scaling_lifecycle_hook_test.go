@@ -0,0 +1,73 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"net/http"
+	"testing"
+
+	"yunion.io/x/onecloud/pkg/compute/options"
+)
+
+func TestValidateWebhookUrl(t *testing.T) {
+	cases := []struct {
+		url          string
+		allowPrivate bool
+		wantErr      bool
+	}{
+		{"http://hooks.example.com/launch", false, false},
+		{"https://203.0.113.10:8443/hook", false, false},
+		{"ftp://hooks.example.com/launch", false, true},
+		{"http:///launch", false, true},
+		{"http://127.0.0.1:8080/hook", false, true},
+		{"http://[::1]/hook", false, true},
+		{"http://169.254.169.254/latest/meta-data", false, true},
+		{"http://0.0.0.0/hook", false, true},
+		{"http://10.1.2.3/hook", false, true},
+		{"http://192.168.1.1/hook", false, true},
+		{"http://10.1.2.3/hook", true, false},
+		{"http://169.254.169.254/latest/meta-data", true, true},
+	}
+	origin := options.Options.LifecycleHookWebhookAllowPrivate
+	defer func() {
+		options.Options.LifecycleHookWebhookAllowPrivate = origin
+	}()
+	for _, c := range cases {
+		options.Options.LifecycleHookWebhookAllowPrivate = c.allowPrivate
+		err := validateWebhookUrl(c.url)
+		if (err != nil) != c.wantErr {
+			t.Errorf("url %s allow private %v: want error %v, got %v", c.url, c.allowPrivate, c.wantErr, err)
+		}
+	}
+}
+
+func TestGetWebhookClient(t *testing.T) {
+	for _, skip := range []bool{false, true} {
+		client := getWebhookClient(skip)
+		if client != getWebhookClient(skip) {
+			t.Errorf("skip tls verify %v: client should be shared", skip)
+		}
+		tr, ok := client.Transport.(*http.Transport)
+		if !ok {
+			t.Fatalf("unexpected transport %T", client.Transport)
+		}
+		if tr.TLSClientConfig.InsecureSkipVerify != skip {
+			t.Errorf("skip tls verify %v: got InsecureSkipVerify %v", skip, tr.TLSClientConfig.InsecureSkipVerify)
+		}
+		if tr.Proxy != nil {
+			t.Errorf("skip tls verify %v: webhook should be connected without proxy", skip)
+		}
+	}
+}
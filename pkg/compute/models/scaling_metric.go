@@ -0,0 +1,523 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	monitorapi "yunion.io/x/onecloud/pkg/apis/monitor"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/monitor/hostmetrics"
+	"yunion.io/x/onecloud/pkg/monitor/tsdb"
+)
+
+// IScalingMetricTrigger is implemented by triggers which are evaluated periodically against the metrics of
+// the instances in scaling group instead of being fired by an external event.
+type IScalingMetricTrigger interface {
+	IScalingTrigger
+
+	// Evaluate returns the action to execute, nil means nothing needs to be done for now.
+	Evaluate(ctx context.Context, sg *SScalingGroup) (IScalingAction, error)
+}
+
+type SScalingTargetManager struct {
+	db.SStandaloneResourceBaseManager
+}
+
+// SScalingTarget keeps the average of the indicator of all instances around TargetValue
+type SScalingTarget struct {
+	db.SStandaloneResourceBase
+
+	SScalingPolicyBase
+
+	Indicator   string `width:"32" charset:"ascii"`
+	TargetValue float64
+	Cycle       int
+	// Instances joined within WarmUp seconds are excluded from the average
+	WarmUp int
+
+	ScaleOutCoolingTime int
+	ScaleInCoolingTime  int
+	DisableScaleIn      bool `default:"false"`
+
+	// The indicator value of the last evaluation
+	LastValue        float64
+	LastScaleOutTime time.Time
+	LastScaleInTime  time.Time
+}
+
+type SScalingStepManager struct {
+	db.SStandaloneResourceBaseManager
+}
+
+// SScalingStep executes the step whose interval contains the current indicator value
+type SScalingStep struct {
+	db.SStandaloneResourceBase
+
+	SScalingPolicyBase
+
+	Indicator string `width:"32" charset:"ascii"`
+	Wrapper   string `width:"16" charset:"ascii"`
+	Cycle     int
+	WarmUp    int
+
+	// Steps is the json array of api.ScalingStep
+	Steps jsonutils.JSONObject `nullable:"true"`
+
+	// The indicator value of the last evaluation
+	LastValue float64
+}
+
+var (
+	ScalingTargetManager *SScalingTargetManager
+	ScalingStepManager   *SScalingStepManager
+)
+
+func init() {
+	ScalingTargetManager = &SScalingTargetManager{
+		SStandaloneResourceBaseManager: db.NewStandaloneResourceBaseManager(
+			SScalingTarget{},
+			"scalingtargets_tbl",
+			"scalingtarget",
+			"scalingtargets",
+		),
+	}
+	ScalingTargetManager.SetVirtualObject(ScalingTargetManager)
+
+	ScalingStepManager = &SScalingStepManager{
+		SStandaloneResourceBaseManager: db.NewStandaloneResourceBaseManager(
+			SScalingStep{},
+			"scalingsteps_tbl",
+			"scalingstep",
+			"scalingsteps",
+		),
+	}
+	ScalingStepManager.SetVirtualObject(ScalingStepManager)
+}
+
+var scalingMetricIndicators = []string{api.INDICATOR_CPU, api.INDICATOR_DISK_READ, api.INDICATOR_DISK_WRITE,
+	api.INDICATOR_FLOW_INTO, api.INDICATOR_FLOW_OUT}
+
+func checkScalingMetricWindow(cycle, warmUp *int) error {
+	if *cycle == 0 {
+		*cycle = 300
+	}
+	if *cycle < 60 {
+		return fmt.Errorf("the min value of cycle is 60")
+	}
+	if *warmUp < 0 {
+		return fmt.Errorf("warm up should not be negative")
+	}
+	return nil
+}
+
+func (st *SScalingTarget) ValidateCreateData(input api.ScalingPolicyCreateInput) (api.ScalingPolicyCreateInput, error) {
+	target := &input.Target
+	if !utils.IsInStringArray(target.Indicator, scalingMetricIndicators) {
+		return input, httperrors.NewInputParameterError("unkown indicator in target %s", target.Indicator)
+	}
+	if target.TargetValue <= 0 {
+		return input, httperrors.NewInputParameterError("target value should be positive")
+	}
+	if target.Indicator == api.INDICATOR_CPU && target.TargetValue > 100 {
+		return input, httperrors.NewInputParameterError("target value of cpu should not be greater than 100")
+	}
+	err := checkScalingMetricWindow(&target.Cycle, &target.WarmUp)
+	if err != nil {
+		return input, httperrors.NewInputParameterError("%v", err)
+	}
+	if target.ScaleOutCoolingTime < 0 || target.ScaleInCoolingTime < 0 {
+		return input, httperrors.NewInputParameterError("cooling time should not be negative")
+	}
+	return input, nil
+}
+
+func (st *SScalingTarget) Register(ctx context.Context, userCred mcclient.TokenCredential) error {
+	err := ScalingTargetManager.TableSpec().Insert(ctx, st)
+	if err != nil {
+		return errors.Wrap(err, "STableSpec.Insert")
+	}
+	return nil
+}
+
+func (st *SScalingTarget) UnRegister(ctx context.Context, userCred mcclient.TokenCredential) error {
+	err := st.Delete(ctx, userCred)
+	if err != nil {
+		return errors.Wrap(err, "SScalingTarget.Delete")
+	}
+	return nil
+}
+
+func (st *SScalingTarget) TriggerId() string {
+	return st.GetId()
+}
+
+func (st *SScalingTarget) IsTrigger() bool {
+	return true
+}
+
+func (st *SScalingTarget) TriggerDescription() string {
+	name := st.ScalingPolicyId
+	sp, _ := st.ScalingPolicy()
+	if sp != nil {
+		name = sp.Name
+	}
+	return fmt.Sprintf(
+		`Target tracking task(the average %s of the instance is %f%s, target is %f%s) execute scaling policy "%s"`,
+		descs[st.Indicator], st.LastValue, units[st.Indicator], st.TargetValue, units[st.Indicator], name,
+	)
+}
+
+func (st *SScalingTarget) TargetDetails() api.ScalingTargetDetails {
+	return api.ScalingTargetDetails{
+		Indicator:           st.Indicator,
+		TargetValue:         st.TargetValue,
+		Cycle:               st.Cycle,
+		WarmUp:              st.WarmUp,
+		ScaleOutCoolingTime: st.ScaleOutCoolingTime,
+		ScaleInCoolingTime:  st.ScaleInCoolingTime,
+		DisableScaleIn:      st.DisableScaleIn,
+		LastValue:           st.LastValue,
+	}
+}
+
+// targetNumber returns the instance number which makes the average of indicator close to target value
+func (st *SScalingTarget) targetNumber(current int, value float64) int {
+	if current == 0 {
+		return 0
+	}
+	return int(math.Ceil(float64(current) * value / st.TargetValue))
+}
+
+func (st *SScalingTarget) Evaluate(ctx context.Context, sg *SScalingGroup) (IScalingAction, error) {
+	value, count, err := sg.fetchIndicator(st.Indicator, api.WRAPPER_AVER, st.Cycle, st.WarmUp)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetchIndicator")
+	}
+	if count == 0 {
+		return nil, nil
+	}
+	now := time.Now()
+	current := sg.DesireInstanceNumber
+	desire := sg.limitInstanceNumber(st.targetNumber(current, value))
+	var isScaleOut bool
+	switch {
+	case desire > current:
+		isScaleOut = true
+		if st.LastScaleOutTime.Add(time.Duration(st.ScaleOutCoolingTime) * time.Second).After(now) {
+			desire = current
+		}
+	case desire < current:
+		if st.DisableScaleIn || st.LastScaleInTime.Add(time.Duration(st.ScaleInCoolingTime)*time.Second).After(now) {
+			desire = current
+		}
+	}
+	_, err = db.Update(st, func() error {
+		st.LastValue = value
+		if desire != current {
+			if isScaleOut {
+				st.LastScaleOutTime = now
+			} else {
+				st.LastScaleInTime = now
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "db.Update")
+	}
+	if desire == current {
+		return nil, nil
+	}
+	return sScalingDesireAction(desire), nil
+}
+
+func (ss *SScalingStep) ValidateCreateData(input api.ScalingPolicyCreateInput) (api.ScalingPolicyCreateInput, error) {
+	step := &input.Step
+	if len(step.Wrapper) == 0 {
+		step.Wrapper = api.WRAPPER_AVER
+	}
+	if !utils.IsInStringArray(step.Indicator, scalingMetricIndicators) {
+		return input, httperrors.NewInputParameterError("unkown indicator in step %s", step.Indicator)
+	}
+	if !utils.IsInStringArray(step.Wrapper, []string{api.WRAPPER_MIN, api.WRAPPER_MAX, api.WRAPPER_AVER}) {
+		return input, httperrors.NewInputParameterError("unkown wrapper in step %s", step.Wrapper)
+	}
+	err := checkScalingMetricWindow(&step.Cycle, &step.WarmUp)
+	if err != nil {
+		return input, httperrors.NewInputParameterError("%v", err)
+	}
+	if len(step.Steps) == 0 {
+		return input, httperrors.NewInputParameterError("steps should not be empty")
+	}
+	for i := range step.Steps {
+		s := &step.Steps[i]
+		if !utils.IsInStringArray(s.Action, []string{api.ACTION_ADD, api.ACTION_REMOVE, api.ACTION_SET}) {
+			return input, httperrors.NewInputParameterError("unkown action %s of step %d", s.Action, i)
+		}
+		if len(s.Unit) == 0 {
+			s.Unit = api.UNIT_ONE
+		}
+		if !utils.IsInStringArray(s.Unit, []string{api.UNIT_ONE, api.UNIT_PERCENT}) {
+			return input, httperrors.NewInputParameterError("unkown unit %s of step %d", s.Unit, i)
+		}
+		if s.Number < 0 || s.CoolingTime < 0 {
+			return input, httperrors.NewInputParameterError("number and cooling time of step %d should not be negative", i)
+		}
+		if s.LowerBound != nil && s.UpperBound != nil && *s.LowerBound >= *s.UpperBound {
+			return input, httperrors.NewInputParameterError("lower bound of step %d should be less than upper bound", i)
+		}
+		s.LastScaleTime = time.Time{}
+	}
+	for i := range step.Steps {
+		for j := i + 1; j < len(step.Steps); j++ {
+			if isScalingStepOverlap(step.Steps[i], step.Steps[j]) {
+				return input, httperrors.NewInputParameterError("step %d overlaps with step %d", i, j)
+			}
+		}
+	}
+	return input, nil
+}
+
+func isScalingStepOverlap(a, b api.ScalingStep) bool {
+	// a is entirely below b or b is entirely below a
+	if a.UpperBound != nil && b.LowerBound != nil && *a.UpperBound <= *b.LowerBound {
+		return false
+	}
+	if b.UpperBound != nil && a.LowerBound != nil && *b.UpperBound <= *a.LowerBound {
+		return false
+	}
+	return true
+}
+
+func isInScalingStep(step api.ScalingStep, value float64) bool {
+	if step.LowerBound != nil && value < *step.LowerBound {
+		return false
+	}
+	if step.UpperBound != nil && value >= *step.UpperBound {
+		return false
+	}
+	return true
+}
+
+func (ss *SScalingStep) Register(ctx context.Context, userCred mcclient.TokenCredential) error {
+	err := ScalingStepManager.TableSpec().Insert(ctx, ss)
+	if err != nil {
+		return errors.Wrap(err, "STableSpec.Insert")
+	}
+	return nil
+}
+
+func (ss *SScalingStep) UnRegister(ctx context.Context, userCred mcclient.TokenCredential) error {
+	err := ss.Delete(ctx, userCred)
+	if err != nil {
+		return errors.Wrap(err, "SScalingStep.Delete")
+	}
+	return nil
+}
+
+func (ss *SScalingStep) TriggerId() string {
+	return ss.GetId()
+}
+
+func (ss *SScalingStep) IsTrigger() bool {
+	return true
+}
+
+func (ss *SScalingStep) TriggerDescription() string {
+	name := ss.ScalingPolicyId
+	sp, _ := ss.ScalingPolicy()
+	if sp != nil {
+		name = sp.Name
+	}
+	return fmt.Sprintf(
+		`Step task(the %s %s of the instance is %f%s) execute scaling policy "%s"`,
+		descs[ss.Wrapper], descs[ss.Indicator], ss.LastValue, units[ss.Indicator], name,
+	)
+}
+
+func (ss *SScalingStep) GetSteps() []api.ScalingStep {
+	steps := make([]api.ScalingStep, 0)
+	if ss.Steps != nil {
+		err := ss.Steps.Unmarshal(&steps)
+		if err != nil {
+			log.Errorf("unmarshal steps of scaling step %s: %v", ss.Id, err)
+		}
+	}
+	return steps
+}
+
+func (ss *SScalingStep) StepDetails() api.ScalingStepDetails {
+	return api.ScalingStepDetails{
+		Indicator: ss.Indicator,
+		Wrapper:   ss.Wrapper,
+		Cycle:     ss.Cycle,
+		WarmUp:    ss.WarmUp,
+		Steps:     ss.GetSteps(),
+		LastValue: ss.LastValue,
+	}
+}
+
+func (ss *SScalingStep) Evaluate(ctx context.Context, sg *SScalingGroup) (IScalingAction, error) {
+	value, count, err := sg.fetchIndicator(ss.Indicator, ss.Wrapper, ss.Cycle, ss.WarmUp)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetchIndicator")
+	}
+	if count == 0 {
+		return nil, nil
+	}
+	now := time.Now()
+	steps := ss.GetSteps()
+	var action IScalingAction
+	for i := range steps {
+		step := &steps[i]
+		if !isInScalingStep(*step, value) {
+			continue
+		}
+		// every step has its own cooling time
+		if step.LastScaleTime.Add(time.Duration(step.CoolingTime) * time.Second).After(now) {
+			break
+		}
+		current := sg.DesireInstanceNumber
+		desire := sg.limitInstanceNumber(scalingExec(step.Action, step.Number, step.Unit, current))
+		if desire == current {
+			break
+		}
+		step.LastScaleTime = now
+		action = sScalingDesireAction(desire)
+		break
+	}
+	_, err = db.Update(ss, func() error {
+		ss.LastValue = value
+		ss.Steps = jsonutils.Marshal(steps)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "db.Update")
+	}
+	return action, nil
+}
+
+// sScalingDesireAction set the desire instance number of scaling group directly, the cooling time has been
+// checked by the metric trigger itself.
+type sScalingDesireAction int
+
+func (a sScalingDesireAction) Exec(from int) int {
+	return int(a)
+}
+
+func (a sScalingDesireAction) CheckCoolTime() bool {
+	return false
+}
+
+func (sg *SScalingGroup) limitInstanceNumber(num int) int {
+	if num > sg.MaxInstanceNumber {
+		return sg.MaxInstanceNumber
+	}
+	if num < sg.MinInstanceNumber {
+		return sg.MinInstanceNumber
+	}
+	return num
+}
+
+// warmingGuestIds returns the guests which are not ready or became ready within warmUp seconds
+func (sg *SScalingGroup) warmingGuestIds(warmUp int) ([]string, error) {
+	sggs := make([]SScalingGroupGuest, 0)
+	q := ScalingGroupGuestManager.Query().Equals("scaling_group_id", sg.Id)
+	err := db.FetchModelObjects(ScalingGroupGuestManager, q, &sggs)
+	if err != nil {
+		return nil, err
+	}
+	warmedAt := time.Now().Add(-time.Duration(warmUp) * time.Second)
+	ids := make([]string, 0)
+	for i := range sggs {
+		if sggs[i].isWarmingUp(warmedAt) {
+			ids = append(ids, sggs[i].GuestId)
+		}
+	}
+	return ids, nil
+}
+
+// fetchIndicator aggregates the indicator of instances within the last cycle seconds by wrapper,
+// warming instances are excluded. The count of instances that participate in the calculation is returned as well.
+func (sg *SScalingGroup) fetchIndicator(indicator, wrapper string, cycle, warmUp int) (float64, int, error) {
+	tf, ok := indicatorMap[indicator]
+	if !ok {
+		return 0, 0, errors.Wrapf(errors.ErrNotSupported, "indicator %s", indicator)
+	}
+	excludes, err := sg.warmingGuestIds(warmUp)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "warmingGuestIds")
+	}
+	tags := []monitorapi.MetricQueryTag{
+		{Key: "vm_scaling_group_id", Operator: "=", Value: sg.Id},
+	}
+	req := &tsdb.TsdbQuery{
+		TimeRange: tsdb.NewTimeRange(fmt.Sprintf("%ds", cycle), "now"),
+		Queries: []*tsdb.Query{
+			hostmetrics.NewMeanQuery(indicator, tf.Table, tags, []string{tf.Field}, "vm_id", false),
+		},
+	}
+	resp, err := hostmetrics.Query(context.Background(), options.Options.Region, req)
+	if err != nil {
+		return 0, 0, err
+	}
+	values := make([]float64, 0)
+	for _, result := range resp.Results {
+		for _, series := range result.Series {
+			guestId := series.Tags["vm_id"]
+			if len(guestId) == 0 || utils.IsInStringArray(guestId, excludes) {
+				continue
+			}
+			if value, _, ok := hostmetrics.AverageSeries(series); ok {
+				values = append(values, value)
+			}
+		}
+	}
+	return wrapScalingMetric(wrapper, values), len(values), nil
+}
+
+func wrapScalingMetric(wrapper string, values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	ret := values[0]
+	var sum float64
+	for _, v := range values {
+		sum += v
+		switch wrapper {
+		case api.WRAPPER_MAX:
+			ret = math.Max(ret, v)
+		case api.WRAPPER_MIN:
+			ret = math.Min(ret, v)
+		}
+	}
+	if wrapper == api.WRAPPER_AVER {
+		ret = sum / float64(len(values))
+	}
+	return ret
+}
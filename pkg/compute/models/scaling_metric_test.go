@@ -0,0 +1,97 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+	"time"
+
+	"yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func TestSScalingTarget_targetNumber(t *testing.T) {
+	st := &SScalingTarget{TargetValue: 60}
+	cases := []struct {
+		current int
+		value   float64
+		want    int
+	}{
+		{current: 4, value: 90, want: 6},
+		{current: 4, value: 60, want: 4},
+		{current: 4, value: 20, want: 2},
+		{current: 3, value: 61, want: 4},
+		{current: 0, value: 90, want: 0},
+	}
+	for _, c := range cases {
+		if got := st.targetNumber(c.current, c.value); got != c.want {
+			t.Errorf("targetNumber(%d, %f) = %d, want %d", c.current, c.value, got, c.want)
+		}
+	}
+}
+
+func TestScalingStep(t *testing.T) {
+	f := func(v float64) *float64 {
+		return &v
+	}
+	low := compute.ScalingStep{UpperBound: f(30)}
+	mid := compute.ScalingStep{LowerBound: f(30), UpperBound: f(80)}
+	high := compute.ScalingStep{LowerBound: f(80)}
+	if isScalingStepOverlap(low, mid) || isScalingStepOverlap(mid, high) || isScalingStepOverlap(low, high) {
+		t.Errorf("adjacent steps should not overlap")
+	}
+	if !isScalingStepOverlap(mid, compute.ScalingStep{LowerBound: f(70)}) {
+		t.Errorf("steps should overlap")
+	}
+	if !isInScalingStep(mid, 30) || isInScalingStep(mid, 80) || !isInScalingStep(high, 80) || !isInScalingStep(low, -1) {
+		t.Errorf("wrong step bounds")
+	}
+}
+
+func TestWrapScalingMetric(t *testing.T) {
+	values := []float64{10, 40, 70}
+	for wrapper, want := range map[string]float64{
+		compute.WRAPPER_AVER: 40,
+		compute.WRAPPER_MAX:  70,
+		compute.WRAPPER_MIN:  10,
+	} {
+		if got := wrapScalingMetric(wrapper, values); got != want {
+			t.Errorf("wrapScalingMetric(%s) = %f, want %f", wrapper, got, want)
+		}
+	}
+}
+
+func TestScalingGroupGuestIsWarmingUp(t *testing.T) {
+	now := time.Now()
+	warmedAt := now.Add(-5 * time.Minute)
+	cases := []struct {
+		name    string
+		status  string
+		readyAt time.Time
+		updated time.Time
+		want    bool
+	}{
+		{"joining", compute.SG_GUEST_STATUS_JOINING, time.Time{}, now, true},
+		{"ready just now", compute.SG_GUEST_STATUS_READY, now.Add(-time.Minute), now, true},
+		{"ready long ago but updated recently", compute.SG_GUEST_STATUS_READY, now.Add(-time.Hour), now, false},
+		{"ready before time recorded", compute.SG_GUEST_STATUS_READY, time.Time{}, now, false},
+	}
+	for _, c := range cases {
+		sgg := &SScalingGroupGuest{GuestStatus: c.status, ReadyAt: c.readyAt}
+		sgg.UpdatedAt = c.updated
+		if got := sgg.isWarmingUp(warmedAt); got != c.want {
+			t.Errorf("%s: want warming up %v, got %v", c.name, c.want, got)
+		}
+	}
+}
@@ -194,6 +194,24 @@ func (sp *SScalingPolicy) getMoreDetails(ctx context.Context, userCred mcclient.
 			return out, errors.Wrap(err, "ScalingTimerManager.FetchById")
 		}
 		out.CycleTimer = model.(*SScalingTimer).CycleTimerDetails()
+	case api.TRIGGER_TARGET:
+		model, err := ScalingTargetManager.FetchById(sp.TriggerId)
+		if errors.Cause(err) == sql.ErrNoRows {
+			return out, nil
+		}
+		if err != nil {
+			return out, errors.Wrap(err, "ScalingTargetManager.FetchById")
+		}
+		out.Target = model.(*SScalingTarget).TargetDetails()
+	case api.TRIGGER_STEP:
+		model, err := ScalingStepManager.FetchById(sp.TriggerId)
+		if errors.Cause(err) == sql.ErrNoRows {
+			return out, nil
+		}
+		if err != nil {
+			return out, errors.Wrap(err, "ScalingStepManager.FetchById")
+		}
+		out.Step = model.(*SScalingStep).StepDetails()
 	}
 
 	return out, nil
@@ -224,9 +242,14 @@ func (spm *SScalingPolicyManager) ValidateCreateData(ctx context.Context, userCr
 	}
 	input.ScalingGroupId = model.GetId()
 
-	if !utils.IsInStringArray(input.TriggerType, []string{api.TRIGGER_TIMING, api.TRIGGER_CYCLE, api.TRIGGER_ALARM}) {
+	if !utils.IsInStringArray(input.TriggerType, []string{api.TRIGGER_TIMING, api.TRIGGER_CYCLE, api.TRIGGER_ALARM,
+		api.TRIGGER_TARGET, api.TRIGGER_STEP}) {
 		return input, httperrors.NewInputParameterError("unkown trigger type %s", input.TriggerType)
 	}
+	if utils.IsInStringArray(input.TriggerType, []string{api.TRIGGER_TARGET, api.TRIGGER_STEP}) {
+		// the number of instances is decided by the trigger itself
+		input.Action, input.Number, input.Unit, input.CoolingTime = api.ACTION_SET, 0, api.UNIT_ONE, 0
+	}
 	if !utils.IsInStringArray(input.Action, []string{api.ACTION_ADD, api.ACTION_REMOVE, api.ACTION_SET}) {
 		return input, httperrors.NewInputParameterError("unkown scaling policy action %s", input.Action)
 	}
@@ -346,6 +369,26 @@ func (sp *SScalingPolicy) Trigger(input *api.ScalingPolicyCreateInput) (IScaling
 				RealCumulate:       0,
 				LastTriggerTime:    time.Now(),
 			}, nil
+		case api.TRIGGER_TARGET:
+			return &SScalingTarget{
+				SScalingPolicyBase:  SScalingPolicyBase{sp.GetId()},
+				Indicator:           input.Target.Indicator,
+				TargetValue:         input.Target.TargetValue,
+				Cycle:               input.Target.Cycle,
+				WarmUp:              input.Target.WarmUp,
+				ScaleOutCoolingTime: input.Target.ScaleOutCoolingTime,
+				ScaleInCoolingTime:  input.Target.ScaleInCoolingTime,
+				DisableScaleIn:      input.Target.DisableScaleIn,
+			}, nil
+		case api.TRIGGER_STEP:
+			return &SScalingStep{
+				SScalingPolicyBase: SScalingPolicyBase{sp.GetId()},
+				Indicator:          input.Step.Indicator,
+				Wrapper:            input.Step.Wrapper,
+				Cycle:              input.Step.Cycle,
+				WarmUp:             input.Step.WarmUp,
+				Steps:              jsonutils.Marshal(input.Step.Steps),
+			}, nil
 		default:
 			return nil, fmt.Errorf("unkown trigger type %s", sp.TriggerType)
 		}
@@ -366,6 +409,18 @@ func (sp *SScalingPolicy) Trigger(input *api.ScalingPolicyCreateInput) (IScaling
 			return nil, errors.Wrap(err, "SScalingAlarmManager.FetchById")
 		}
 		return model.(*SScalingAlarm), nil
+	case api.TRIGGER_TARGET:
+		model, err := ScalingTargetManager.FetchById(sp.TriggerId)
+		if err != nil {
+			return nil, errors.Wrap(err, "SScalingTargetManager.FetchById")
+		}
+		return model.(*SScalingTarget), nil
+	case api.TRIGGER_STEP:
+		model, err := ScalingStepManager.FetchById(sp.TriggerId)
+		if err != nil {
+			return nil, errors.Wrap(err, "SScalingStepManager.FetchById")
+		}
+		return model.(*SScalingStep), nil
 	default:
 		return nil, fmt.Errorf("unkown trigger type %s", sp.TriggerType)
 	}
@@ -418,6 +473,21 @@ func (sp *SScalingPolicy) PerformTrigger(ctx context.Context, userCred mcclient.
 		if !trigger.IsTrigger() {
 			return nil, nil
 		}
+		if metricTrigger, ok := trigger.(IScalingMetricTrigger); ok {
+			action, err := metricTrigger.Evaluate(ctx, sg)
+			if err != nil {
+				return nil, errors.Wrap(err, "evaluate metric trigger")
+			}
+			if action == nil {
+				return nil, nil
+			}
+			err = sg.Scale(ctx, trigger, action, 0)
+			if err != nil {
+				return nil, errors.Wrap(err, "ScalingPolicy.Scale")
+			}
+			sp.EventNotify(ctx, userCred)
+			return nil, nil
+		}
 		triggerDesc = trigger
 	}
 	err = sg.Scale(ctx, triggerDesc, sp, sp.CoolingTime)
@@ -449,11 +519,15 @@ type IScalingAction interface {
 }
 
 func (sp *SScalingPolicy) Exec(from int) int {
-	diff := sp.Number
-	if sp.Unit == api.UNIT_PERCENT {
+	return scalingExec(sp.Action, sp.Number, sp.Unit, from)
+}
+
+func scalingExec(action string, number int, unit string, from int) int {
+	diff := number
+	if unit == api.UNIT_PERCENT {
 		diff = diff * from / 100
 	}
-	switch sp.Action {
+	switch action {
 	case api.ACTION_ADD:
 		return from + diff
 	case api.ACTION_REMOVE:
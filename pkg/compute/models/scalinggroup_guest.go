@@ -53,6 +53,15 @@ type SScalingGroupGuest struct {
 	ScalingGroupId string            `width:"36" charset:"ascii" nullable:"false"`
 	GuestStatus    string            `width:"36" charset:"ascii" nullable:"false" index:"true"`
 	Manual         tristate.TriState `nullable:"false" default:"false"`
+
+	// LifecycleHookId is the lifecycle hook which the guest is waiting for
+	LifecycleHookId   string    `width:"36" charset:"ascii"`
+	LifecycleToken    string    `width:"36" charset:"ascii"`
+	LifecycleResult   string    `width:"16" charset:"ascii"`
+	LifecycleDeadline time.Time `nullable:"true"`
+
+	// ReadyAt is the time the guest became ready, the metrics of guest are ignored until it warms up
+	ReadyAt time.Time `nullable:"true"`
 }
 
 func (sggm *SScalingGroupGuestManager) GetSlaveFieldName() string {
@@ -100,13 +109,60 @@ func (sgg *SScalingGroupGuest) SetGuestStatus(status string) error {
 	_, err := db.Update(sgg, func() error {
 		sgg.GuestStatus = status
 		sgg.UpdatedAt = time.Now()
+		if status == compute.SG_GUEST_STATUS_READY {
+			sgg.ReadyAt = sgg.UpdatedAt
+		}
 		sgg.UpdateVersion += 1
 		return nil
 	})
 	return err
 }
 
+// isWarmingUp tells whether the guest is not ready or became ready after warmedAt
+func (sgg *SScalingGroupGuest) isWarmingUp(warmedAt time.Time) bool {
+	if sgg.GuestStatus != compute.SG_GUEST_STATUS_READY {
+		return true
+	}
+	// guests ready before the time was recorded have warmed up long ago
+	return sgg.ReadyAt.After(warmedAt)
+}
+
 func (sggm *SScalingGroupGuestManager) Query(fields ...string) *sqlchemy.SQuery {
 	return sggm.SVirtualJointResourceBaseManager.Query(fields...).NotEquals("guest_status",
 		compute.SG_GUEST_STATUS_PENDING_REMOVE)
 }
+
+// SetLifecycleAction makes the guest waiting for the callback of lifecycle hook.
+func (sgg *SScalingGroupGuest) SetLifecycleAction(hookId, token string, deadline time.Time) error {
+	_, err := db.Update(sgg, func() error {
+		sgg.LifecycleHookId = hookId
+		sgg.LifecycleToken = token
+		sgg.LifecycleResult = ""
+		sgg.LifecycleDeadline = deadline
+		return nil
+	})
+	return err
+}
+
+func (sgg *SScalingGroupGuest) SetLifecycleResult(result string) error {
+	_, err := db.Update(sgg, func() error {
+		sgg.LifecycleResult = result
+		return nil
+	})
+	return err
+}
+
+// FinishLifecycleHooks restores the status of guest and records the final result of the lifecycle hooks.
+func (sgg *SScalingGroupGuest) FinishLifecycleHooks(status, result string) error {
+	_, err := db.Update(sgg, func() error {
+		sgg.LifecycleHookId = ""
+		sgg.LifecycleToken = ""
+		sgg.LifecycleResult = result
+		sgg.LifecycleDeadline = time.Time{}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return sgg.SetGuestStatus(status)
+}
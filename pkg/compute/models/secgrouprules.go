@@ -16,7 +16,11 @@ package models
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
 	"net"
+	"sort"
+	"time"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
@@ -70,6 +74,15 @@ type SSecurityGroupRule struct {
 	Action         string `width:"5" charset:"ascii" nullable:"false" list:"user" update:"user" create:"required"`
 	Description    string `width:"256" charset:"utf8" list:"user" update:"user" create:"optional"`
 	PeerSecgroupId string `width:"128" charset:"ascii" create:"optional" list:"user" update:"user"`
+
+	// 记录命中此规则的流量, 仅对onecloud vpc内的主机生效, 安全组被经典网络的主机使用时不允许开启
+	Log bool `nullable:"false" default:"false" list:"user" update:"user" create:"optional"`
+	// 命中的报文数, 来自acl流表的计数
+	HitCount int64 `nullable:"false" default:"0" list:"user"`
+	// 命中的字节数
+	HitBytes    int64                        `nullable:"false" default:"0" list:"user"`
+	LastHitAt   time.Time                    `nullable:"true" list:"user"`
+	DeniedFlows *api.SecgroupRuleDeniedFlows `list:"user"`
 }
 
 func (self *SSecurityGroupRule) GetId() string {
@@ -318,6 +331,13 @@ func (manager *SSecurityGroupRuleManager) ValidateCreateData(ctx context.Context
 		return input, err
 	}
 
+	if input.Log != nil && *input.Log {
+		err = secgroup.validateLog()
+		if err != nil {
+			return input, err
+		}
+	}
+
 	input.ResourceBaseCreateInput, err = manager.SResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.ResourceBaseCreateInput)
 	if err != nil {
 		return input, err
@@ -360,6 +380,15 @@ func (self *SSecurityGroupRule) ValidateUpdateData(ctx context.Context, userCred
 		return output, err
 	}
 
+	if input.Log != nil && *input.Log && !self.Log {
+		if secgroup := self.GetSecGroup(); secgroup != nil {
+			err = secgroup.validateLog()
+			if err != nil {
+				return output, err
+			}
+		}
+	}
+
 	output.ResourceBaseUpdateInput, err = self.SResourceBase.ValidateUpdateData(ctx, userCred, query, input.ResourceBaseUpdateInput)
 	if err != nil {
 		return output, errors.Wrap(err, "SResourceBase.ValidateUpdateData")
@@ -391,6 +420,14 @@ func (self *SSecurityGroupRule) toRule() (*secrules.SecurityRule, error) {
 			IP:   net.ParseIP(self.CIDR),
 			Mask: net.CIDRMask(32, 32),
 		}
+	} else if _, ipnet, err := net.ParseCIDR(self.CIDR); err == nil {
+		// ipv6 cidr
+		rule.IPNet = ipnet
+	} else if regutils.MatchIP6Addr(self.CIDR) {
+		rule.IPNet = &net.IPNet{
+			IP:   net.ParseIP(self.CIDR),
+			Mask: net.CIDRMask(128, 128),
+		}
 	} else {
 		rule.IPNet = &net.IPNet{
 			IP:   net.IPv4zero,
@@ -536,3 +573,84 @@ func (manager *SSecurityGroupRuleManager) ListItemExportKeys(ctx context.Context
 	}
 	return q, nil
 }
+
+func (manager *SSecurityGroupRuleManager) AllowPerformReportHits(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) bool {
+	return db.IsAdminAllowClassPerform(userCred, manager, "report-hits")
+}
+
+// 宿主机上报安全组规则命中统计
+func (manager *SSecurityGroupRuleManager) PerformReportHits(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.SecgroupRuleHitsReportInput) (jsonutils.JSONObject, error) {
+	if len(input.HostId) == 0 {
+		return nil, httperrors.NewMissingParameterError("host_id")
+	}
+	for i := range input.Rules {
+		hits := &input.Rules[i]
+		if hits.Hits <= 0 && hits.Bytes <= 0 && len(hits.DeniedFlows) == 0 {
+			continue
+		}
+		rule, err := manager.FetchById(hits.RuleId)
+		if err != nil {
+			if errors.Cause(err) == sql.ErrNoRows {
+				// the rule may have been removed since the hits were counted
+				continue
+			}
+			return nil, httperrors.NewGeneralError(errors.Wrapf(err, "fetch rule %s", hits.RuleId))
+		}
+		err = rule.(*SSecurityGroupRule).addHits(ctx, input.HostId, hits)
+		if err != nil {
+			return nil, httperrors.NewGeneralError(err)
+		}
+	}
+	return nil, nil
+}
+
+const maxSecgroupRuleDeniedFlows = 20
+
+// addHits accumulates hit statistics of the rule.  The row is updated
+// in place without touching updated_at, otherwise every report would be
+// seen as a rule change by hosts and vpcagent
+func (self *SSecurityGroupRule) addHits(ctx context.Context, hostId string, hits *api.SecgroupRuleHits) error {
+	lockman.LockObject(ctx, self)
+	defer lockman.ReleaseObject(ctx, self)
+
+	// reload with the lock held.  Reports of other hosts may have been
+	// merged since the rule was fetched
+	obj, err := SecurityGroupRuleManager.FetchById(self.Id)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil
+		}
+		return errors.Wrapf(err, "reload rule %s", self.Id)
+	}
+	rule := obj.(*SSecurityGroupRule)
+
+	lastHitAt := rule.LastHitAt
+	if hits.LastHitAt.After(lastHitAt) {
+		lastHitAt = hits.LastHitAt
+	}
+	flows := api.SecgroupRuleDeniedFlows{}
+	if rule.DeniedFlows != nil {
+		flows = append(flows, *rule.DeniedFlows...)
+	}
+	for _, flow := range hits.DeniedFlows {
+		flow.HostId = hostId
+		flows = append(flows, flow)
+	}
+	sort.SliceStable(flows, func(i, j int) bool {
+		return flows[i].At.Before(flows[j].At)
+	})
+	if len(flows) > maxSecgroupRuleDeniedFlows {
+		flows = flows[len(flows)-maxSecgroupRuleDeniedFlows:]
+	}
+
+	_, err = sqlchemy.GetDB().Exec(
+		fmt.Sprintf(
+			"update %s set hit_count = hit_count + ?, hit_bytes = hit_bytes + ?, last_hit_at = ?, denied_flows = ? where id = ?",
+			SecurityGroupRuleManager.TableSpec().Name(),
+		), hits.Hits, hits.Bytes, lastHitAt, flows.String(), self.Id,
+	)
+	if err != nil {
+		return errors.Wrapf(err, "update hits of rule %s", self.Id)
+	}
+	return nil
+}
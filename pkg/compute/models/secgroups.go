@@ -74,6 +74,9 @@ const (
 type SSecurityGroup struct {
 	db.SSharableVirtualResourceBase
 	IsDirty bool `nullable:"false" default:"false"`
+
+	// 记录命中所有规则的流量, 仅对onecloud vpc内的主机生效, 被经典网络的主机使用时不允许开启
+	Log bool `nullable:"false" default:"false" list:"user" update:"user" create:"optional"`
 }
 
 // 安全组列表
@@ -293,6 +296,35 @@ func (self *SSecurityGroup) GetGuests() []SGuest {
 	return guests
 }
 
+// GetClassicGuestsCount counts guests of the security group with nics in
+// classic networks
+func (self *SSecurityGroup) GetClassicGuestsCount() (int, error) {
+	guests := self.GetGuestsQuery().SubQuery()
+	guestnetworks := GuestnetworkManager.Query().SubQuery()
+	networks := NetworkManager.Query().SubQuery()
+	wires := WireManager.Query().SubQuery()
+	q := guests.Query(guests.Field("id")).Distinct()
+	q = q.Join(guestnetworks, sqlchemy.Equals(guestnetworks.Field("guest_id"), guests.Field("id")))
+	q = q.Join(networks, sqlchemy.Equals(networks.Field("id"), guestnetworks.Field("network_id")))
+	q = q.Join(wires, sqlchemy.Equals(wires.Field("id"), networks.Field("wire_id")))
+	q = q.Filter(sqlchemy.Equals(wires.Field("vpc_id"), api.DEFAULT_VPC_ID))
+	return q.CountWithError()
+}
+
+// validateLog checks that hits of the security group rules can be logged.
+// Flows of classic networks are programmed by sdnagent, which does not log
+// hits
+func (self *SSecurityGroup) validateLog() error {
+	cnt, err := self.GetClassicGuestsCount()
+	if err != nil {
+		return httperrors.NewGeneralError(errors.Wrap(err, "GetClassicGuestsCount"))
+	}
+	if cnt > 0 {
+		return httperrors.NewUnsupportOperationError("security group %s is used by %d guests of classic networks, whose traffic can not be logged", self.Name, cnt)
+	}
+	return nil
+}
+
 func (self *SSecurityGroup) GetKvmGuests() ([]SGuest, error) {
 	guests := []SGuest{}
 	q := self.GetGuestsQuery().Equals("hypervisor", api.HYPERVISOR_KVM)
@@ -448,6 +480,10 @@ func (manager *SSecurityGroupManager) FetchCustomizeColumns(
 				CIDR:        rules[j].CIDR,
 				Action:      rules[j].Action,
 				Description: rules[j].Description,
+				Log:         rules[j].Log,
+				HitCount:    rules[j].HitCount,
+				HitBytes:    rules[j].HitBytes,
+				LastHitAt:   rules[j].LastHitAt,
 			}
 			_rules = append(_rules, rule)
 			switch rule.Direction {
@@ -512,6 +548,21 @@ func (manager *SSecurityGroupManager) ValidateCreateData(
 	return input, nil
 }
 
+func (self *SSecurityGroup) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.SecgroupUpdateInput) (api.SecgroupUpdateInput, error) {
+	if input.Log != nil && *input.Log && !self.Log {
+		err := self.validateLog()
+		if err != nil {
+			return input, err
+		}
+	}
+	var err error
+	input.SharableVirtualResourceBaseUpdateInput, err = self.SSharableVirtualResourceBase.ValidateUpdateData(ctx, userCred, query, input.SharableVirtualResourceBaseUpdateInput)
+	if err != nil {
+		return input, errors.Wrap(err, "SSharableVirtualResourceBase.ValidateUpdateData")
+	}
+	return input, nil
+}
+
 func (self *SSecurityGroup) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) {
 	self.SSharableVirtualResourceBase.PostCreate(ctx, userCred, ownerId, query, data)
 
@@ -535,6 +586,9 @@ func (self *SSecurityGroup) PostCreate(ctx context.Context, userCred mcclient.To
 			Action:      r.Action,
 			Description: r.Description,
 		}
+		if r.Log != nil {
+			rule.Log = *r.Log
+		}
 		rule.SecgroupId = self.Id
 
 		SecurityGroupRuleManager.TableSpec().Insert(ctx, rule)
@@ -758,6 +812,7 @@ func (self *SSecurityGroup) PerformClone(ctx context.Context, userCred mcclient.
 	secgroup.Name = input.Name
 	secgroup.Description = input.Description
 	secgroup.Status = api.SECGROUP_STATUS_READY
+	secgroup.Log = self.Log
 	secgroup.ProjectId = userCred.GetProjectId()
 	secgroup.DomainId = userCred.GetProjectDomainId()
 
@@ -781,6 +836,7 @@ func (self *SSecurityGroup) PerformClone(ctx context.Context, userCred mcclient.
 		secgrouprule.CIDR = rule.CIDR
 		secgrouprule.Action = rule.Action
 		secgrouprule.Description = rule.Description
+		secgrouprule.Log = rule.Log
 		secgrouprule.SecgroupId = secgroup.Id
 		if err := SecurityGroupRuleManager.TableSpec().Insert(ctx, secgrouprule); err != nil {
 			return input, err
@@ -1276,6 +1332,9 @@ func (self *SSecurityGroup) PerformImportRules(ctx context.Context, userCred mcc
 			Action:      r.Action,
 			Description: r.Description,
 		}
+		if r.Log != nil {
+			rule.Log = *r.Log
+		}
 		rule.SecgroupId = self.Id
 
 		err := SecurityGroupRuleManager.TableSpec().Insert(ctx, rule)
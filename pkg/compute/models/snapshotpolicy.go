@@ -61,6 +61,8 @@ type SSnapshotPolicy struct {
 	// 0~23
 	TimePoints  uint32            `charset:"utf8" create:"required" list:"user" get:"user"`
 	IsActivated tristate.TriState `list:"user" get:"user" create:"optional" default:"true"`
+	// 是否冻结虚拟机文件系统后再创建快照
+	Quiesce bool `nullable:"false" default:"false" list:"user" get:"user" create:"optional"`
 }
 
 var SnapshotPolicyManager *SSnapshotPolicyManager
@@ -719,6 +721,7 @@ func (manager *SSnapshotPolicyManager) sSnapshotPolicyCreateInputToInternal(inpu
 		ProjectId:     input.ProjectId,
 		DomainId:      input.DomainId,
 		RetentionDays: input.RetentionDays,
+		Quiesce:       input.Quiesce,
 	}
 
 	ret.RepeatWeekdays = manager.RepeatWeekdaysParseIntArray(input.RepeatWeekdays)
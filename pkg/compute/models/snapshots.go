@@ -83,6 +83,8 @@ type SSnapshot struct {
 
 	BackingDiskId string    `width:"36" charset:"ascii" nullable:"true" default:""`
 	ExpiredAt     time.Time `nullable:"true" list:"user" create:"optional"`
+	// 是否在冻结文件系统后创建, 即应用一致性快照
+	Quiesced bool `nullable:"false" default:"false" list:"user"`
 }
 
 var SnapshotManager *SSnapshotManager
@@ -515,6 +515,9 @@ func (self *SStorage) GetUsedCapacity(isReady tristate.TriState) int64 {
 }
 
 func (self *SStorage) GetOvercommitBound() float32 {
+	if self.IsThickProvisioned() {
+		return 1
+	}
 	if self.Cmtbound > 0 {
 		return self.Cmtbound
 	} else {
@@ -522,6 +525,28 @@ func (self *SStorage) GetOvercommitBound() float32 {
 	}
 }
 
+// IsThickProvisioned returns true for storages of logical volumes without
+// thin pool, which occupy the full size at creation and can't be overcommitted
+func (self *SStorage) IsThickProvisioned() bool {
+	return (self.StorageType == api.STORAGE_LVM && !self.isLVMThin()) || self.StorageType == api.STORAGE_ISCSI_LVM
+}
+
+// GetSchedCmtbound returns overcommit bound of the storage row for the
+// scheduler, which does not load region default options
+func (self *SStorage) GetSchedCmtbound() float32 {
+	if self.IsThickProvisioned() {
+		return 1
+	}
+	return self.Cmtbound
+}
+
+func (self *SStorage) isLVMThin() bool {
+	if self.StorageConf == nil {
+		return false
+	}
+	return self.StorageConf.Contains("thin_pool")
+}
+
 func (self *SStorage) GetMasterHost() *SHost {
 	hosts := HostManager.Query().SubQuery()
 	hoststorages := HoststorageManager.Query().SubQuery()
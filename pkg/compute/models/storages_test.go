@@ -0,0 +1,60 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func TestStorageOvercommitBound(t *testing.T) {
+	thinConf := jsonutils.Marshal(map[string]string{"vg_name": "vg_data", "thin_pool": "thinpool"})
+	thickConf := jsonutils.Marshal(map[string]string{"vg_name": "vg_data"})
+	cases := []struct {
+		storageType string
+		conf        jsonutils.JSONObject
+		cmtbound    float32
+		thick       bool
+		sched       float32
+	}{
+		{api.STORAGE_LOCAL, nil, 2, false, 2},
+		{api.STORAGE_RBD, nil, 0, false, 0},
+		{api.STORAGE_LVM, thickConf, 2, true, 1},
+		{api.STORAGE_LVM, thinConf, 2, false, 2},
+		{api.STORAGE_LVM, nil, 2, true, 1},
+		{api.STORAGE_ISCSI_LVM, nil, 2, true, 1},
+	}
+	for _, c := range cases {
+		s := &SStorage{
+			StorageType: c.storageType,
+			StorageConf: c.conf,
+		}
+		s.Cmtbound = c.cmtbound
+		if got := s.IsThickProvisioned(); got != c.thick {
+			t.Errorf("%s %v: IsThickProvisioned want %v, got %v", c.storageType, c.conf, c.thick, got)
+		}
+		if got := s.GetSchedCmtbound(); got != c.sched {
+			t.Errorf("%s %v: GetSchedCmtbound want %v, got %v", c.storageType, c.conf, c.sched, got)
+		}
+		if c.thick {
+			if got := s.GetOvercommitBound(); got != 1 {
+				t.Errorf("%s %v: GetOvercommitBound want 1, got %v", c.storageType, c.conf, got)
+			}
+		}
+	}
+}
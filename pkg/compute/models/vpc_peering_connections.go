@@ -26,6 +26,7 @@ import (
 
 	api "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
 	"yunion.io/x/onecloud/pkg/cloudprovider"
 	"yunion.io/x/onecloud/pkg/httperrors"
@@ -63,6 +64,9 @@ type SVpcPeeringConnection struct {
 	PeerVpcId        string `width:"36" charset:"ascii" nullable:"true" list:"domain" create:"required" json:"peer_vpc_id"`
 	PeerAccountId    string `width:"36" charset:"ascii" nullable:"true" list:"domain"`
 	Bandwidth        int    `nullable:"false" default:"0" list:"user" create:"optional"`
+
+	// ovn中对等连接路由器互联网段的序号, 仅onecloud vpc有效
+	OvnLinkSlot int `nullable:"false" default:"-1" allow_zero:"true" list:"domain"`
 }
 
 func (manager *SVpcPeeringConnectionManager) GetContextManagers() [][]db.IModelManager {
@@ -142,6 +146,27 @@ func (manager *SVpcPeeringConnectionManager) ValidateCreateData(
 		return input, httperrors.NewGeneralError(err)
 	}
 	peerVpc := _peerVpc.(*SVpc)
+	input.VpcId = vpc.Id
+	input.PeerVpcId = peerVpc.Id
+
+	// existed peer check
+	vpcPC := SVpcPeeringConnection{}
+	err = manager.Query().Equals("vpc_id", vpc.Id).Equals("peer_vpc_id", peerVpc.Id).First(&vpcPC)
+	if err == nil {
+		return input, httperrors.NewNotSupportedError("vpc %s and vpc %s have already connected", input.VpcId, input.PeerVpcId)
+	} else {
+		if errors.Cause(err) != sql.ErrNoRows {
+			return input, httperrors.NewGeneralError(err)
+		}
+	}
+
+	if vpc.IsOneCloudVpc() || peerVpc.IsOneCloudVpc() {
+		err := manager.validateOneCloudVpcPeering(vpc, peerVpc)
+		if err != nil {
+			return input, err
+		}
+		return input, nil
+	}
 
 	// get account,providerFactory
 	account := vpc.GetCloudaccount()
@@ -157,31 +182,12 @@ func (manager *SVpcPeeringConnectionManager) ValidateCreateData(
 
 	// check vpc ip range overlap
 	if !factory.IsSupportVpcPeeringVpcCidrOverlap() {
-		vpcIpv4Ranges := []netutils.IPV4AddrRange{}
-		peervpcIpv4Ranges := []netutils.IPV4AddrRange{}
-		vpcCidrBlocks := strings.Split(vpc.CidrBlock, ",")
-		peervpcCidrBlocks := strings.Split(peerVpc.CidrBlock, ",")
-		for i := range vpcCidrBlocks {
-			vpcIpv4Range, err := newIPv4RangeFromCIDR(vpcCidrBlocks[i])
-			if err != nil {
-				return input, httperrors.NewGeneralError(errors.Wrapf(err, "convert vpc cidr %s to ipv4range error", vpcCidrBlocks[i]))
-			}
-			vpcIpv4Ranges = append(vpcIpv4Ranges, vpcIpv4Range)
-		}
-
-		for i := range peervpcCidrBlocks {
-			peervpcIpv4Range, err := newIPv4RangeFromCIDR(peervpcCidrBlocks[i])
-			if err != nil {
-				return input, httperrors.NewGeneralError(errors.Wrapf(err, "convert vpc cidr %s to ipv4range error", peervpcCidrBlocks[i]))
-			}
-			peervpcIpv4Ranges = append(peervpcIpv4Ranges, peervpcIpv4Range)
+		overlap, err := vpcCidrBlocksOverlap(vpc, peerVpc)
+		if err != nil {
+			return input, httperrors.NewGeneralError(err)
 		}
-		for i := range vpcIpv4Ranges {
-			for j := range peervpcIpv4Ranges {
-				if vpcIpv4Ranges[i].IsOverlap(peervpcIpv4Ranges[j]) {
-					return input, httperrors.NewNotSupportedError("ipv4 range overlap")
-				}
-			}
+		if overlap {
+			return input, httperrors.NewNotSupportedError("ipv4 range overlap")
 		}
 	}
 
@@ -200,18 +206,87 @@ func (manager *SVpcPeeringConnectionManager) ValidateCreateData(
 		}
 	}
 
-	// existed peer check
-	vpcPC := SVpcPeeringConnection{}
-	err = manager.Query().Equals("vpc_id", vpc.Id).Equals("peer_vpc_id", peerVpc.Id).First(&vpcPC)
-	if err == nil {
-		return input, httperrors.NewNotSupportedError("vpc %s and vpc %s have already connected", input.VpcId, input.PeerVpcId)
-	} else {
-		if errors.Cause(err) != sql.ErrNoRows {
-			return input, httperrors.NewGeneralError(err)
+	return input, nil
+}
+
+// validateOneCloudVpcPeering checks peering of two onecloud vpcs.  Their
+// routers will be linked directly with routes to each other's cidr blocks,
+// so the cidr blocks must not overlap with each other, nor with those of vpcs
+// already peered with either side
+func (manager *SVpcPeeringConnectionManager) validateOneCloudVpcPeering(vpc, peerVpc *SVpc) error {
+	if !vpc.IsOneCloudVpc() || !peerVpc.IsOneCloudVpc() {
+		return httperrors.NewNotSupportedError("peering between onecloud vpc and vpc of other providers is not supported")
+	}
+	if vpc.Id == peerVpc.Id {
+		return httperrors.NewInputParameterError("cannot peer vpc %s with itself", vpc.Name)
+	}
+	if vpc.CloudregionId != peerVpc.CloudregionId {
+		return httperrors.NewNotSupportedError("onecloud vpcs of different regions cannot be peered")
+	}
+	cnt, err := manager.Query().Equals("vpc_id", peerVpc.Id).Equals("peer_vpc_id", vpc.Id).CountWithError()
+	if err != nil {
+		return httperrors.NewGeneralError(err)
+	}
+	if cnt > 0 {
+		return httperrors.NewNotSupportedError("vpc %s and vpc %s have already connected", peerVpc.Name, vpc.Name)
+	}
+
+	check := func(vpc *SVpc, peerVpc *SVpc) error {
+		overlap, err := vpcCidrBlocksOverlap(vpc, peerVpc)
+		if err != nil {
+			return httperrors.NewGeneralError(err)
+		}
+		if overlap {
+			return httperrors.NewNotSupportedError("cidr block %s of vpc %s overlaps with %s of vpc %s",
+				vpc.CidrBlock, vpc.Name, peerVpc.CidrBlock, peerVpc.Name)
+		}
+		return nil
+	}
+	if err := check(vpc, peerVpc); err != nil {
+		return err
+	}
+	for _, pair := range [][2]*SVpc{{vpc, peerVpc}, {peerVpc, vpc}} {
+		peeredVpcs, err := pair[0].GetPeeredVpcs()
+		if err != nil {
+			return httperrors.NewGeneralError(errors.Wrapf(err, "GetPeeredVpcs of %s", pair[0].Name))
+		}
+		for i := range peeredVpcs {
+			if err := check(pair[1], &peeredVpcs[i]); err != nil {
+				return err
+			}
 		}
 	}
+	return nil
+}
 
-	return input, nil
+func vpcCidrBlocksOverlap(vpc, peerVpc *SVpc) (bool, error) {
+	vpcIpv4Ranges := []netutils.IPV4AddrRange{}
+	peervpcIpv4Ranges := []netutils.IPV4AddrRange{}
+	vpcCidrBlocks := strings.Split(vpc.CidrBlock, ",")
+	peervpcCidrBlocks := strings.Split(peerVpc.CidrBlock, ",")
+	for i := range vpcCidrBlocks {
+		vpcIpv4Range, err := newIPv4RangeFromCIDR(vpcCidrBlocks[i])
+		if err != nil {
+			return false, errors.Wrapf(err, "convert vpc cidr %s to ipv4range error", vpcCidrBlocks[i])
+		}
+		vpcIpv4Ranges = append(vpcIpv4Ranges, vpcIpv4Range)
+	}
+
+	for i := range peervpcCidrBlocks {
+		peervpcIpv4Range, err := newIPv4RangeFromCIDR(peervpcCidrBlocks[i])
+		if err != nil {
+			return false, errors.Wrapf(err, "convert vpc cidr %s to ipv4range error", peervpcCidrBlocks[i])
+		}
+		peervpcIpv4Ranges = append(peervpcIpv4Ranges, peervpcIpv4Range)
+	}
+	for i := range vpcIpv4Ranges {
+		for j := range peervpcIpv4Ranges {
+			if vpcIpv4Ranges[i].IsOverlap(peervpcIpv4Ranges[j]) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
 }
 
 func (self *SVpcPeeringConnection) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) {
@@ -395,3 +470,35 @@ func (self *SVpcPeeringConnection) GetPeerVpc() (*SVpc, error) {
 	}
 	return vpc.(*SVpc), nil
 }
+
+// AllocateOvnLinkSlot assigns the link network between routers of peered
+// onecloud vpcs.  The slot is kept on the record so that links of live
+// peerings stay the same when other peerings come and go
+func (self *SVpcPeeringConnection) AllocateOvnLinkSlot(ctx context.Context) error {
+	if self.OvnLinkSlot >= 0 {
+		return nil
+	}
+	lockman.LockRawObject(ctx, VpcPeeringConnectionManager.Keyword(), "ovn_link_slot")
+	defer lockman.ReleaseRawObject(ctx, VpcPeeringConnectionManager.Keyword(), "ovn_link_slot")
+
+	peerings := []SVpcPeeringConnection{}
+	q := VpcPeeringConnectionManager.Query().GE("ovn_link_slot", 0)
+	if err := db.FetchModelObjects(VpcPeeringConnectionManager, q, &peerings); err != nil {
+		return errors.Wrap(err, "fetch vpc peering connections")
+	}
+	used := map[int]struct{}{}
+	for i := range peerings {
+		used[peerings[i].OvnLinkSlot] = struct{}{}
+	}
+	for slot := 0; slot < api.VpcPeeringLinkSlots(); slot++ {
+		if _, ok := used[slot]; ok {
+			continue
+		}
+		_, err := db.Update(self, func() error {
+			self.OvnLinkSlot = slot
+			return nil
+		})
+		return err
+	}
+	return errors.Errorf("no free link network for vpc peering connection")
+}
@@ -181,13 +181,32 @@ func (self *SVpc) GetAccepterVpcPeeringConnections() ([]SVpcPeeringConnection, e
 	}
 	return vpcPC, nil
 }
+
+// GetPeeredVpcs returns vpcs connected with this one by vpc peering
+// connections, either as requester or accepter
+func (self *SVpc) GetPeeredVpcs() ([]SVpc, error) {
+	requesterQ := VpcPeeringConnectionManager.Query("peer_vpc_id").Equals("vpc_id", self.Id)
+	accepterQ := VpcPeeringConnectionManager.Query("vpc_id").Equals("peer_vpc_id", self.Id)
+	q := VpcManager.Query()
+	q = q.Filter(sqlchemy.OR(
+		sqlchemy.In(q.Field("id"), requesterQ.SubQuery()),
+		sqlchemy.In(q.Field("id"), accepterQ.SubQuery()),
+	))
+	ret := []SVpc{}
+	err := db.FetchModelObjects(VpcManager, q, &ret)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
 func (self *SVpc) GetVpcPeeringConnectionCount() (int, error) {
 	q := self.getRequesterVpcPeeringConnectionQuery()
 	requesterPeerCount, err := q.CountWithError()
 	if err != nil {
 		return 0, err
 	}
-	q = self.getRequesterVpcPeeringConnectionQuery()
+	q = self.getAccepterVpcPeeringConnectionQuery()
 	accepterPeerCount, err := q.CountWithError()
 	if err != nil {
 		return 0, err
@@ -334,6 +353,12 @@ func (self *SVpc) GetNetworkCount() (int, error) {
 	return q.CountWithError()
 }
 
+// IsOneCloudVpc returns true for vpcs of onecloud region implemented with
+// ovn.  The default vpc is not one of them
+func (self *SVpc) IsOneCloudVpc() bool {
+	return self.Id != api.DEFAULT_VPC_ID && self.GetProviderName() == api.CLOUD_PROVIDER_ONECLOUD
+}
+
 func (self *SVpc) GetRouteTableQuery() *sqlchemy.SQuery {
 	return RouteTableManager.Query().Equals("vpc_id", self.Id)
 }
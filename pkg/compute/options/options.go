@@ -97,6 +97,16 @@ type ComputeOptions struct {
 	DefaultMaxSnapshotCount       int `default:"9" help:"Per Disk max snapshot count, default 9"`
 	DefaultMaxManualSnapshotCount int `default:"2" help:"Per Disk max manual snapshot count, default 2"`
 
+	// disk backup options
+	DiskBackupS3Endpoint          string `help:"Endpoint of S3 compatible object storage keeping disk backups"`
+	DiskBackupS3AccessKey         string `help:"Access key of disk backup object storage"`
+	DiskBackupS3SecretKey         string `help:"Secret key of disk backup object storage"`
+	DiskBackupS3Bucket            string `default:"onecloud-disk-backups" help:"Bucket of disk backup object storage"`
+	DiskBackupS3UseSSL            bool   `default:"false" help:"Use SSL to access disk backup object storage"`
+	DiskBackupMaxIncrementalCount int    `default:"6" help:"Max incremental backups based on one full backup, default 6"`
+
+	VirtiofsHostPathRoots []string `help:"Host directories under which paths are allowed to be shared to guests by virtio-fs, host paths can't be shared if empty"`
+
 	//snapshot policy options
 	RetentionDaysLimit  int `default:"49" help:"Days of snapshot retention, default 49 days"`
 	TimePointsLimit     int `default:"1" help:"time point of every days, default 1 point"`
@@ -116,6 +126,8 @@ type ComputeOptions struct {
 
 	DefaultImageCacheDir string `default:"image_cache"`
 
+	DefaultStoragePluginVolumeDir string `help:"Host directory where volumes of storage plugin are published" default:"/opt/cloud/workspace/storage-plugins"`
+
 	SnapshotCreateDiskProtocol string `help:"Snapshot create disk protocol" choices:"url|fuse" default:"fuse"`
 
 	HostOfflineMaxSeconds        int `help:"Maximal seconds interval that a host considered offline during which it did not ping region, default is 3 minues" default:"180"`
@@ -156,6 +168,19 @@ type ComputeOptions struct {
 	SyncExtDiskSnapshotIntervalMinutes int  `help:"sync snapshot for external disk" default:"20"`
 	AutoReconcileBackupServers         bool `help:"auto reconcile backup servers" default:"false"`
 
+	EnableHostRebalance          bool    `help:"Enable rebalancing running guests from busy hosts to idle hosts" default:"false"`
+	HostRebalanceIntervalMinutes int     `help:"Interval to check load balance of hosts" default:"30"`
+	HostRebalanceMode            string  `help:"recommend only records the migration plan, auto executes live migrations" choices:"recommend|auto" default:"recommend"`
+	HostRebalanceGroupBy         string  `help:"Rebalance among hosts of the same zone or schedtag" choices:"zone|schedtag" default:"zone"`
+	HostRebalanceMaxMoves        int     `help:"Maximal live migrations of one rebalance round" default:"3"`
+	HostRebalanceThreshold       float32 `help:"Rebalance when the load gap between the busiest and idlest hosts exceeds this ratio" default:"0.2"`
+	HostRebalanceConcurrency     int     `help:"Maximal live migrations running at the same time while rebalancing" default:"1"`
+
+	HostRebalanceMetricsWindow       string `help:"Host utilization metrics average window used by rebalance" default:"10m"`
+	HostRebalanceMetricsStaleMinutes int    `help:"Use commit rate of hosts once any host metrics not reported in these minutes" default:"10"`
+
+	HaRestartMaxAttempts int `help:"Default maximal attempts to restart a guest on other hosts when its host down" default:"3"`
+
 	SCapabilityOptions
 	SASControllerOptions
 	common_options.CommonOptions
@@ -167,6 +192,8 @@ type ComputeOptions struct {
 	DefaultNetworkGatewayAddressEsxi uint32 `help:"Default address for network gateway" default:"1"`
 
 	DefaultVpcExternalAccessMode string `help:"default external access mode for on-premise vpc"`
+	EnableOvnLoadbalancer        bool   `help:"Allow on-premise vpc loadbalancers forwarded by ovn Load_Balancer of vpcagent" default:"false"`
+	EnableOvnNatGateway          bool   `help:"Allow on-premise vpc natgateways.  Set it only when ovn_nat_gateway_chassis of vpcagent is configured" default:"false"`
 
 	NoCheckOsTypeForCachedImage bool `help:"Don't check os type for cached image"`
 
@@ -186,6 +213,9 @@ type SASControllerOptions struct {
 	ConcurrentUpper     int `help:"This represents the upper limit of concurrent sacling sctivities" default:"500"`
 	CheckScaleInterval  int `help:"The interval between the two checks about scaling, unit: s" default:"60"`
 	CheckHealthInterval int `help:"The interval bewteen the two check about instance's health unit: m" default:"1"`
+	CheckMetricInterval int `help:"The interval between the two evaluations of target tracking and step scaling policies, unit: s" default:"60"`
+
+	LifecycleHookWebhookAllowPrivate bool `help:"Allow the webhook of scaling lifecycle hook to access private network addresses" default:"false"`
 }
 
 var (
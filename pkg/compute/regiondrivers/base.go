@@ -224,6 +224,14 @@ func (self *SBaseRegionDriver) RequestDeleteVpc(ctx context.Context, userCred mc
 	return fmt.Errorf("Not implement RequestDeleteVpc")
 }
 
+func (self *SBaseRegionDriver) RequestCreateVpcPeeringConnection(ctx context.Context, userCred mcclient.TokenCredential, peer *models.SVpcPeeringConnection, task taskman.ITask) error {
+	return fmt.Errorf("Not implement RequestCreateVpcPeeringConnection")
+}
+
+func (self *SBaseRegionDriver) RequestDeleteVpcPeeringConnection(ctx context.Context, userCred mcclient.TokenCredential, peer *models.SVpcPeeringConnection, task taskman.ITask) error {
+	return fmt.Errorf("Not implement RequestDeleteVpcPeeringConnection")
+}
+
 func (self *SBaseRegionDriver) RequestCacheSecurityGroup(ctx context.Context, userCred mcclient.TokenCredential, region *models.SCloudregion, vpc *models.SVpc, secgroup *models.SSecurityGroup, classic bool, remoteProjectId string, task taskman.ITask) error {
 	return fmt.Errorf("Not Implemented RequestCacheSecurityGroup")
 }
@@ -442,6 +450,30 @@ func (self *SBaseRegionDriver) IsSupportedNatAutoRenew() bool {
 	return true
 }
 
+func (self *SBaseRegionDriver) RequestCreateNatGateway(ctx context.Context, userCred mcclient.TokenCredential, nat *models.SNatGateway, task taskman.ITask) error {
+	return fmt.Errorf("Not Implement RequestCreateNatGateway")
+}
+
+func (self *SBaseRegionDriver) RequestDeleteNatGateway(ctx context.Context, userCred mcclient.TokenCredential, nat *models.SNatGateway, task taskman.ITask) error {
+	return fmt.Errorf("Not Implement RequestDeleteNatGateway")
+}
+
+func (self *SBaseRegionDriver) RequestCreateNatSEntry(ctx context.Context, userCred mcclient.TokenCredential, snat *models.SNatSEntry, task taskman.ITask) error {
+	return fmt.Errorf("Not Implement RequestCreateNatSEntry")
+}
+
+func (self *SBaseRegionDriver) RequestDeleteNatSEntry(ctx context.Context, userCred mcclient.TokenCredential, snat *models.SNatSEntry, task taskman.ITask) error {
+	return fmt.Errorf("Not Implement RequestDeleteNatSEntry")
+}
+
+func (self *SBaseRegionDriver) RequestCreateNatDEntry(ctx context.Context, userCred mcclient.TokenCredential, dnat *models.SNatDEntry, task taskman.ITask) error {
+	return fmt.Errorf("Not Implement RequestCreateNatDEntry")
+}
+
+func (self *SBaseRegionDriver) RequestDeleteNatDEntry(ctx context.Context, userCred mcclient.TokenCredential, dnat *models.SNatDEntry, task taskman.ITask) error {
+	return fmt.Errorf("Not Implement RequestDeleteNatDEntry")
+}
+
 func (self *SBaseRegionDriver) RequestAssociatEip(ctx context.Context, userCred mcclient.TokenCredential, eip *models.SElasticip, input api.ElasticipAssociateInput, obj db.IStatusStandaloneModel, task taskman.ITask) error {
 	return httperrors.NewNotImplementedError("RequestAssociatEip")
 }
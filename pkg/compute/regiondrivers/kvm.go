@@ -27,6 +27,7 @@ import (
 	"yunion.io/x/pkg/utils"
 	"yunion.io/x/sqlchemy"
 
+	billing_api "yunion.io/x/onecloud/pkg/apis/billing"
 	api "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
@@ -76,11 +77,13 @@ func (self *SKVMRegionDriver) ValidateCreateLoadbalancerData(ctx context.Context
 	networkV := validators.NewModelIdOrNameValidator("network", "network", ownerId)
 	addressV := validators.NewIPv4AddrValidator("address")
 	clusterV := validators.NewModelIdOrNameValidator("cluster", "loadbalancercluster", ownerId)
+	dataplaneV := validators.NewStringChoicesValidator("dataplane_type", api.LB_DATAPLANE_TYPES)
 	keyV := map[string]validators.IValidator{
-		"status":  validators.NewStringChoicesValidator("status", api.LB_STATUS_SPEC).Default(api.LB_STATUS_ENABLED),
-		"address": addressV.Optional(true),
-		"network": networkV,
-		"cluster": clusterV.Optional(true),
+		"status":         validators.NewStringChoicesValidator("status", api.LB_STATUS_SPEC).Default(api.LB_STATUS_ENABLED),
+		"address":        addressV.Optional(true),
+		"network":        networkV,
+		"cluster":        clusterV.Optional(true),
+		"dataplane_type": dataplaneV.Optional(true),
 	}
 	if err := RunValidators(keyV, data, false); err != nil {
 		return nil, err
@@ -94,11 +97,45 @@ func (self *SKVMRegionDriver) ValidateCreateLoadbalancerData(ctx context.Context
 	if zone == nil {
 		return nil, httperrors.NewInputParameterError("zone info missing")
 	}
-	if vpc.Id != api.DEFAULT_VPC_ID {
-		return nil, httperrors.NewInputParameterError("vpc lb is not allowed for now")
+	dataplane := dataplaneV.Value
+	if dataplane == "" {
+		dataplane = api.LB_DATAPLANE_TYPE_LBAGENT
+		data.Set("dataplane_type", jsonutils.NewString(dataplane))
+	}
+	switch dataplane {
+	case api.LB_DATAPLANE_TYPE_OVN:
+		if !options.Options.EnableOvnLoadbalancer {
+			return nil, httperrors.NewInputParameterError("ovn loadbalancer is not enabled")
+		}
+		if vpc.Id == api.DEFAULT_VPC_ID {
+			return nil, httperrors.NewInputParameterError("ovn loadbalancer must be in vpc network")
+		}
+		if clusterV.Model != nil {
+			return nil, httperrors.NewInputParameterError("ovn loadbalancer does not need lbcluster")
+		}
+	default:
+		if vpc.Id != api.DEFAULT_VPC_ID {
+			return nil, httperrors.NewInputParameterError("vpc lb is not allowed for now")
+		}
+	}
+	if data.Contains("eip") {
+		if dataplane != api.LB_DATAPLANE_TYPE_OVN {
+			return nil, httperrors.NewInputParameterError("eip is only supported by ovn loadbalancer")
+		}
+		eipV := validators.NewModelIdOrNameValidator("eip", "eip", ownerId)
+		if err := eipV.Validate(data); err != nil {
+			return nil, err
+		}
+		eip := eipV.Model.(*models.SElasticip)
+		if err := validateOvnLoadbalancerEip(vpc, eip); err != nil {
+			return nil, err
+		}
+		data.Set("eip_id", jsonutils.NewString(eip.Id))
 	}
 
-	if clusterV.Model == nil {
+	if dataplane == api.LB_DATAPLANE_TYPE_OVN {
+		// no lbcluster is needed, traffic is forwarded by ovn of the vpc
+	} else if clusterV.Model == nil {
 		clusters := models.LoadbalancerClusterManager.FindByZoneId(zone.Id)
 		if len(clusters) == 0 {
 			return nil, httperrors.NewInputParameterError("zone %s(%s) has no lbcluster", zone.Name, zone.Id)
@@ -142,7 +179,11 @@ func (self *SKVMRegionDriver) ValidateCreateLoadbalancerData(ctx context.Context
 	data.Set("cloudregion_id", jsonutils.NewString(region.GetId()))
 	data.Set("zone_id", jsonutils.NewString(zone.GetId()))
 	data.Set("vpc_id", jsonutils.NewString(vpc.GetId()))
-	data.Set("network_type", jsonutils.NewString(api.LB_NETWORK_TYPE_CLASSIC))
+	if vpc.Id != api.DEFAULT_VPC_ID {
+		data.Set("network_type", jsonutils.NewString(api.LB_NETWORK_TYPE_VPC))
+	} else {
+		data.Set("network_type", jsonutils.NewString(api.LB_NETWORK_TYPE_CLASSIC))
+	}
 	data.Set("address_type", jsonutils.NewString(api.LB_ADDR_TYPE_INTRANET))
 	return data, nil
 }
@@ -160,6 +201,13 @@ func (self *SKVMRegionDriver) ValidateUpdateLoadbalancerCertificateData(ctx cont
 }
 
 func (self *SKVMRegionDriver) ValidateCreateLoadbalancerBackendGroupData(ctx context.Context, userCred mcclient.TokenCredential, data *jsonutils.JSONDict, lb *models.SLoadbalancer, backends []cloudprovider.SLoadbalancerBackend) (*jsonutils.JSONDict, error) {
+	if lb.IsOvnDataplane() {
+		for _, backend := range backends {
+			if backend.BackendType != api.LB_BACKEND_GUEST {
+				return nil, httperrors.NewInputParameterError("ovn loadbalancer only supports guest backend")
+			}
+		}
+	}
 	return data, nil
 }
 
@@ -178,6 +226,9 @@ func (self *SKVMRegionDriver) ValidateCreateLoadbalancerBackendData(ctx context.
 	if err := RunValidators(keyV, data, false); err != nil {
 		return nil, err
 	}
+	if lb != nil && lb.IsOvnDataplane() && backendType != api.LB_BACKEND_GUEST {
+		return nil, httperrors.NewInputParameterError("ovn loadbalancer only supports guest backend")
+	}
 
 	var basename string
 	switch backendType {
@@ -451,7 +502,13 @@ func (self *SKVMRegionDriver) ValidateCreateLoadbalancerListenerData(ctx context
 		redirectSchemeV = validators.NewStringChoicesValidator("redirect_scheme", api.LB_REDIRECT_SCHEMES)
 		redirectHostV   = validators.NewHostPortValidator("redirect_host").OptionalPort(true)
 		redirectPathV   = validators.NewURLPathValidator("redirect_path")
+
+		schedulerV = validators.NewStringChoicesValidator("scheduler", api.LB_SCHEDULER_TYPES).Default(api.LB_SCHEDULER_RR)
 	)
+	if lb.IsOvnDataplane() {
+		listenerTypeV = validators.NewStringChoicesValidator("listener_type", api.LB_OVN_LISTENER_TYPES)
+		schedulerV = validators.NewStringChoicesValidator("scheduler", api.LB_OVN_SCHEDULER_TYPES).Default(api.LB_SCHEDULER_TCH)
+	}
 	keyV := map[string]validators.IValidator{
 		"status": validators.NewStringChoicesValidator("status", api.LB_STATUS_SPEC).Default(api.LB_STATUS_ENABLED),
 
@@ -464,7 +521,7 @@ func (self *SKVMRegionDriver) ValidateCreateLoadbalancerListenerData(ctx context
 		"acl_type":   aclTypeV.Optional(true),
 		"acl":        aclV.Optional(true),
 
-		"scheduler":   validators.NewStringChoicesValidator("scheduler", api.LB_SCHEDULER_TYPES).Default(api.LB_SCHEDULER_RR),
+		"scheduler":   schedulerV,
 		"egress_mbps": validators.NewRangeValidator("egress_mbps", api.LB_MbpsMin, api.LB_MbpsMax).Optional(true),
 
 		"client_request_timeout":  validators.NewRangeValidator("client_request_timeout", 0, 600).Default(10),
@@ -554,6 +611,12 @@ func (self *SKVMRegionDriver) ValidateCreateLoadbalancerListenerData(ctx context
 		return nil, err
 	}
 
+	if lb.IsOvnDataplane() {
+		if err := validateOvnLoadbalancerListenerData(data); err != nil {
+			return nil, err
+		}
+	}
+
 	// acl check
 	if err := models.LoadbalancerListenerManager.ValidateAcl(aclStatusV, aclTypeV, aclV, data, api.CLOUD_PROVIDER_ONECLOUD); err != nil {
 		return nil, err
@@ -673,6 +736,12 @@ func (self *SKVMRegionDriver) ValidateUpdateLoadbalancerListenerData(ctx context
 	//  - scheduler have default value on creation
 	//  - backend_group_id is allowed to have unset value for http, https listener
 
+	if lb := lblis.GetLoadbalancer(); lb != nil && lb.IsOvnDataplane() {
+		if err := validateOvnLoadbalancerListenerData(data); err != nil {
+			return nil, err
+		}
+	}
+
 	if err := models.LoadbalancerListenerManager.ValidateAcl(aclStatusV, aclTypeV, aclV, data, lblis.GetProviderName()); err != nil {
 		return nil, err
 	}
@@ -692,6 +761,47 @@ func (self *SKVMRegionDriver) ValidateUpdateLoadbalancerListenerData(ctx context
 	return data, nil
 }
 
+// validateOvnLoadbalancerEip checks that the eip can be the public frontend
+// of ovn loadbalancer in the vpc.  Traffic to it is brought into the vpc by
+// the eip gateway
+func validateOvnLoadbalancerEip(vpc *models.SVpc, eip *models.SElasticip) error {
+	switch vpc.ExternalAccessMode {
+	case api.VPC_EXTERNAL_ACCESS_MODE_EIP, api.VPC_EXTERNAL_ACCESS_MODE_EIP_DISTGW:
+	default:
+		return httperrors.NewInputParameterError("vpc %s external access mode %q does not support eip", vpc.Name, vpc.ExternalAccessMode)
+	}
+	if !options.Options.EnableOvnNatGateway {
+		// eip frontends are load balanced at the natgw chassis
+		return httperrors.NewInputParameterError("eip of ovn loadbalancer requires ovn natgateway chassis of vpcagent")
+	}
+	if eip.Status != api.EIP_STATUS_READY {
+		return httperrors.NewInvalidStatusError("eip %s status %s", eip.Name, eip.Status)
+	}
+	if eip.AssociateId != "" {
+		return httperrors.NewInputParameterError("eip %s has been associated", eip.Name)
+	}
+	if eip.ManagerId != "" || eip.CloudregionId != vpc.CloudregionId {
+		return httperrors.NewInputParameterError("eip %s and vpc %s are not in the same region", eip.Name, vpc.Name)
+	}
+	return nil
+}
+
+// validateOvnLoadbalancerListenerData rejects features that ovn Load_Balancer
+// cannot provide
+func validateOvnLoadbalancerListenerData(data *jsonutils.JSONDict) error {
+	if scheduler, _ := data.GetString("scheduler"); scheduler != "" && !api.LB_OVN_SCHEDULER_TYPES.Has(scheduler) {
+		return httperrors.NewInputParameterError("ovn loadbalancer does not support scheduler %s", scheduler)
+	}
+	if aclStatus, _ := data.GetString("acl_status"); aclStatus == api.LB_BOOL_ON {
+		return httperrors.NewInputParameterError("ovn loadbalancer does not support acl")
+	}
+	if checkType, _ := data.GetString("health_check_type"); checkType != "" &&
+		checkType != api.LB_HEALTH_CHECK_TCP && checkType != api.LB_HEALTH_CHECK_UDP {
+		return httperrors.NewInputParameterError("ovn loadbalancer does not support %s health check", checkType)
+	}
+	return nil
+}
+
 func (self *SKVMRegionDriver) RequestCreateLoadbalancer(ctx context.Context, userCred mcclient.TokenCredential, lb *models.SLoadbalancer, task taskman.ITask) error {
 	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
 		_, err := db.Update(lb, func() error {
@@ -714,7 +824,22 @@ func (self *SKVMRegionDriver) RequestCreateLoadbalancer(ctx context.Context, use
 			}
 			return nil
 		})
-		return nil, err
+		if err != nil {
+			return nil, err
+		}
+		if eipId, _ := task.GetParams().GetString("eip_id"); eipId != "" && lb.IsOvnDataplane() {
+			eipObj, err := models.ElasticipManager.FetchById(eipId)
+			if err != nil {
+				return nil, errors.Wrapf(err, "fetch eip %s", eipId)
+			}
+			eip := eipObj.(*models.SElasticip)
+			lockman.LockObject(ctx, eip)
+			defer lockman.ReleaseObject(ctx, eip)
+			if err := eip.AssociateLoadbalancer(ctx, userCred, lb); err != nil {
+				return nil, errors.Wrapf(err, "associate eip %s(%s) to loadbalancer %s(%s)", eip.Name, eip.Id, lb.Name, lb.Id)
+			}
+		}
+		return nil, nil
 	})
 	return nil
 }
@@ -899,6 +1024,25 @@ func (self *SKVMRegionDriver) ValidateCreateVpcData(ctx context.Context, userCre
 	return input, nil
 }
 
+func (self *SKVMRegionDriver) RequestCreateVpcPeeringConnection(ctx context.Context, userCred mcclient.TokenCredential, peer *models.SVpcPeeringConnection, task taskman.ITask) error {
+	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
+		// router links and routes are programmed by vpcagent
+		if err := peer.AllocateOvnLinkSlot(ctx); err != nil {
+			return nil, errors.Wrap(err, "AllocateOvnLinkSlot")
+		}
+		peer.SetStatus(userCred, api.VPC_PEERING_CONNECTION_STATUS_ACTIVE, "")
+		return nil, nil
+	})
+	return nil
+}
+
+func (self *SKVMRegionDriver) RequestDeleteVpcPeeringConnection(ctx context.Context, userCred mcclient.TokenCredential, peer *models.SVpcPeeringConnection, task taskman.ITask) error {
+	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
+		return nil, nil
+	})
+	return nil
+}
+
 func (self *SKVMRegionDriver) RequestDeleteVpc(ctx context.Context, userCred mcclient.TokenCredential, region *models.SCloudregion, vpc *models.SVpc, task taskman.ITask) error {
 	task.ScheduleRun(nil)
 	return nil
@@ -1237,13 +1381,54 @@ func (self *SKVMRegionDriver) RequestSyncSnapshotStatus(ctx context.Context, use
 func (self *SKVMRegionDriver) RequestBindIPToNatgateway(ctx context.Context, task taskman.ITask, natgateway *models.SNatGateway,
 	eipId string) error {
 
+	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
+		model, err := models.ElasticipManager.FetchById(eipId)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fetch eip %s", eipId)
+		}
+		lockman.LockObject(ctx, model)
+		defer lockman.ReleaseObject(ctx, model)
+		eip := model.(*models.SElasticip)
+		if err := eip.AssociateNatGateway(ctx, task.GetUserCred(), natgateway); err != nil {
+			return nil, errors.Wrapf(err, "associate eip %s(%s) to natgateway %s(%s)", eip.Name, eip.Id, natgateway.Name, natgateway.Id)
+		}
+		return nil, nil
+	})
 	return nil
 }
 
 func (self *SKVMRegionDriver) RequestUnBindIPFromNatgateway(ctx context.Context, task taskman.ITask,
 	nat models.INatHelper, natgateway *models.SNatGateway) error {
 
-	return nil
+	var ipAddr string
+	switch entry := nat.(type) {
+	case *models.SNatSEntry:
+		ipAddr = entry.IP
+	case *models.SNatDEntry:
+		ipAddr = entry.ExternalIP
+	default:
+		return errors.Errorf("unknown nat entry type %T", nat)
+	}
+	cnt, err := nat.CountByEIP()
+	if err != nil {
+		return errors.Wrapf(err, "count nat entries using eip %s", ipAddr)
+	}
+	if cnt > 0 {
+		return nil
+	}
+
+	eip := &models.SElasticip{}
+	q := models.ElasticipManager.Query().
+		Equals("associate_type", api.EIP_ASSOCIATE_TYPE_NAT_GATEWAY).
+		Equals("associate_id", natgateway.Id).
+		Equals("ip_addr", ipAddr)
+	if err := q.First(eip); err != nil {
+		return errors.Wrapf(err, "fetch eip %s associated with natgateway %s", ipAddr, natgateway.Id)
+	}
+	eip.SetModelManager(models.ElasticipManager, eip)
+	lockman.LockObject(ctx, eip)
+	defer lockman.ReleaseObject(ctx, eip)
+	return eip.Dissociate(ctx, task.GetUserCred())
 }
 
 func (self *SKVMRegionDriver) RequestPreSnapshotPolicyApply(ctx context.Context, userCred mcclient.
@@ -1257,6 +1442,123 @@ func (self *SKVMRegionDriver) RequestPreSnapshotPolicyApply(ctx context.Context,
 }
 
 func (self *SKVMRegionDriver) BindIPToNatgatewayRollback(ctx context.Context, eipId string) error {
+	model, err := models.ElasticipManager.FetchById(eipId)
+	if err != nil {
+		return errors.Wrapf(err, "fetch eip %s", eipId)
+	}
+	lockman.LockObject(ctx, model)
+	defer lockman.ReleaseObject(ctx, model)
+	eip := model.(*models.SElasticip)
+	if eip.AssociateType != api.EIP_ASSOCIATE_TYPE_NAT_GATEWAY {
+		return nil
+	}
+	_, err = db.Update(eip, func() error {
+		eip.AssociateId = ""
+		eip.AssociateType = ""
+		return nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "rollback about binding eip %s failed", eip.Id)
+	}
+	return nil
+}
+
+func (self *SKVMRegionDriver) IsSupportedNatGateway() bool {
+	return true
+}
+
+func (self *SKVMRegionDriver) IsSupportedNatAutoRenew() bool {
+	return false
+}
+
+func (self *SKVMRegionDriver) ValidateCreateNatGateway(ctx context.Context, userCred mcclient.TokenCredential, input api.NatgatewayCreateInput) (api.NatgatewayCreateInput, error) {
+	if input.BillingType == billing_api.BILLING_TYPE_PREPAID {
+		return input, httperrors.NewInputParameterError("%s natgateway does not support prepaid billing", self.GetProvider())
+	}
+	_vpc, err := models.VpcManager.FetchById(input.VpcId)
+	if err != nil {
+		return input, httperrors.NewGeneralError(errors.Wrapf(err, "fetch vpc %s", input.VpcId))
+	}
+	vpc := _vpc.(*models.SVpc)
+	if !options.Options.EnableOvnNatGateway {
+		return input, httperrors.NewInputParameterError("natgateway is not enabled, it requires ovn natgateway chassis of vpcagent")
+	}
+	if vpc.Id == api.DEFAULT_VPC_ID {
+		return input, httperrors.NewInputParameterError("natgateway is not supported in default vpc")
+	}
+	if !utils.IsInStringArray(vpc.ExternalAccessMode, []string{
+		api.VPC_EXTERNAL_ACCESS_MODE_EIP,
+		api.VPC_EXTERNAL_ACCESS_MODE_EIP_DISTGW,
+	}) {
+		return input, httperrors.NewInputParameterError("natgateway requires vpc external access mode %s or %s, got %q",
+			api.VPC_EXTERNAL_ACCESS_MODE_EIP, api.VPC_EXTERNAL_ACCESS_MODE_EIP_DISTGW, vpc.ExternalAccessMode)
+	}
+	return input, nil
+}
+
+func (self *SKVMRegionDriver) RequestSyncNatGatewayStatus(ctx context.Context, userCred mcclient.TokenCredential, nat *models.SNatGateway, task taskman.ITask) error {
+	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
+		if !options.Options.EnableOvnNatGateway {
+			// nat entries will not be programmed by vpcagent
+			return nil, nat.SetStatus(userCred, api.NAT_STATUS_UNKNOWN, "ovn natgateway is not enabled")
+		}
+		return nil, nat.SetStatus(userCred, api.NAT_STAUTS_AVAILABLE, "")
+	})
+	return nil
+}
+
+func (self *SKVMRegionDriver) RequestCreateNatGateway(ctx context.Context, userCred mcclient.TokenCredential, nat *models.SNatGateway, task taskman.ITask) error {
+	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
+		return nil, nil
+	})
+	return nil
+}
+
+func (self *SKVMRegionDriver) RequestDeleteNatGateway(ctx context.Context, userCred mcclient.TokenCredential, nat *models.SNatGateway, task taskman.ITask) error {
+	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
+		eips, err := nat.GetEips()
+		if err != nil {
+			return nil, errors.Wrapf(err, "nat.GetEips")
+		}
+		for i := range eips {
+			eip := &eips[i]
+			lockman.LockObject(ctx, eip)
+			err := eip.Dissociate(ctx, userCred)
+			lockman.ReleaseObject(ctx, eip)
+			if err != nil {
+				return nil, errors.Wrapf(err, "dissociate eip %s(%s)", eip.Name, eip.Id)
+			}
+		}
+		return nil, nil
+	})
+	return nil
+}
+
+func (self *SKVMRegionDriver) RequestCreateNatSEntry(ctx context.Context, userCred mcclient.TokenCredential, snat *models.SNatSEntry, task taskman.ITask) error {
+	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
+		return nil, nil
+	})
+	return nil
+}
+
+func (self *SKVMRegionDriver) RequestDeleteNatSEntry(ctx context.Context, userCred mcclient.TokenCredential, snat *models.SNatSEntry, task taskman.ITask) error {
+	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
+		return nil, nil
+	})
+	return nil
+}
+
+func (self *SKVMRegionDriver) RequestCreateNatDEntry(ctx context.Context, userCred mcclient.TokenCredential, dnat *models.SNatDEntry, task taskman.ITask) error {
+	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
+		return nil, nil
+	})
+	return nil
+}
+
+func (self *SKVMRegionDriver) RequestDeleteNatDEntry(ctx context.Context, userCred mcclient.TokenCredential, dnat *models.SNatDEntry, task taskman.ITask) error {
+	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
+		return nil, nil
+	})
 	return nil
 }
 
@@ -1406,6 +1708,16 @@ func (self *SKVMRegionDriver) GetMaxElasticcacheSecurityGroupCount() int {
 
 func (self *SKVMRegionDriver) RequestAssociatEip(ctx context.Context, userCred mcclient.TokenCredential, eip *models.SElasticip, input api.ElasticipAssociateInput, obj db.IStatusStandaloneModel, task taskman.ITask) error {
 	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
+		if input.InstanceType == api.EIP_ASSOCIATE_TYPE_NAT_GATEWAY {
+			nat := obj.(*models.SNatGateway)
+			if err := eip.AssociateNatGateway(ctx, userCred, nat); err != nil {
+				return nil, errors.Wrapf(err, "associate eip %s(%s) to natgateway %s(%s)", eip.Name, eip.Id, nat.Name, nat.Id)
+			}
+			if err := eip.SetStatus(userCred, api.EIP_STATUS_READY, api.EIP_STATUS_ASSOCIATE); err != nil {
+				return nil, errors.Wrapf(err, "set eip status to %s", api.EIP_STATUS_READY)
+			}
+			return nil, nil
+		}
 		if input.InstanceType != api.EIP_ASSOCIATE_TYPE_SERVER {
 			return nil, errors.Wrapf(cloudprovider.ErrNotSupported, "instance type %s", input.InstanceType)
 		}
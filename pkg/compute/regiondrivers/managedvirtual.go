@@ -1270,6 +1270,88 @@ func (self *SManagedVirtualizationRegionDriver) RequestDeleteVpc(ctx context.Con
 	return nil
 }
 
+func (self *SManagedVirtualizationRegionDriver) RequestCreateVpcPeeringConnection(ctx context.Context, userCred mcclient.TokenCredential, peer *models.SVpcPeeringConnection, task taskman.ITask) error {
+	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
+		vpc, err := peer.GetVpc()
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetVpc")
+		}
+
+		peerVpc, err := peer.GetPeerVpc()
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetPeerVpc")
+		}
+
+		iVpc, err := vpc.GetIVpc()
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetIVpc")
+		}
+
+		iPeerVpc, err := peerVpc.GetIVpc()
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetIVpc")
+		}
+
+		opts := &cloudprovider.VpcPeeringConnectionCreateOptions{
+			Name:          peer.Name,
+			Desc:          peer.Description,
+			Bandwidth:     peer.Bandwidth,
+			PeerVpcId:     iPeerVpc.GetId(),
+			PeerRegionId:  iPeerVpc.GetRegion().GetId(),
+			PeerAccountId: iPeerVpc.GetAuthorityOwnerId(),
+		}
+		iPeerConnection, err := iVpc.CreateICloudVpcPeeringConnection(opts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "CreateICloudVpcPeeringConnection")
+		}
+		err = iPeerVpc.AcceptICloudVpcPeeringConnection(iPeerConnection.GetGlobalId())
+		if err != nil {
+			return nil, errors.Wrapf(err, "AcceptICloudVpcPeeringConnection")
+		}
+
+		iPeerConnection.Refresh()
+		err = peer.SyncWithCloudPeerConnection(ctx, userCred, iPeerConnection, nil)
+		if err != nil {
+			return nil, errors.Wrapf(err, "SyncWithCloudPeerConnection")
+		}
+		return nil, nil
+	})
+	return nil
+}
+
+func (self *SManagedVirtualizationRegionDriver) RequestDeleteVpcPeeringConnection(ctx context.Context, userCred mcclient.TokenCredential, peer *models.SVpcPeeringConnection, task taskman.ITask) error {
+	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
+		vpc, err := peer.GetVpc()
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetVpc")
+		}
+
+		iVpc, err := vpc.GetIVpc()
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetIVpc")
+		}
+
+		if len(peer.ExternalId) == 0 {
+			return nil, nil
+		}
+
+		iPeer, err := iVpc.GetICloudVpcPeeringConnectionById(peer.ExternalId)
+		if err != nil {
+			if errors.Cause(err) != cloudprovider.ErrNotFound {
+				return nil, errors.Wrapf(err, "GetICloudVpcPeeringConnectionById(%s)", peer.ExternalId)
+			}
+			return nil, nil
+		}
+
+		err = iPeer.Delete()
+		if err != nil {
+			return nil, errors.Wrapf(err, "Delete")
+		}
+		return nil, nil
+	})
+	return nil
+}
+
 func (self *SManagedVirtualizationRegionDriver) RequestUpdateSnapshotPolicy(ctx context.Context, userCred mcclient.
 	TokenCredential, sp *models.SSnapshotPolicy, input cloudprovider.SnapshotPolicyInput, task taskman.ITask) error {
 	// it's too cumbersome to pass parameters in taskman, so change a simple way for the moment
@@ -2971,6 +3053,239 @@ func (self *SManagedVirtualizationRegionDriver) RequestSyncNatGatewayStatus(ctx
 	return nil
 }
 
+func (self *SManagedVirtualizationRegionDriver) RequestCreateNatGateway(ctx context.Context, userCred mcclient.TokenCredential, nat *models.SNatGateway, task taskman.ITask) error {
+	opts := cloudprovider.NatGatewayCreateOptions{
+		Name:    nat.Name,
+		Desc:    nat.Description,
+		NatSpec: nat.NatSpec,
+	}
+
+	vpc, err := nat.GetVpc()
+	if err != nil {
+		return errors.Wrapf(err, "nat.GetVpc")
+	}
+
+	opts.VpcId = vpc.ExternalId
+
+	if len(nat.NetworkId) > 0 {
+		_network, err := models.NetworkManager.FetchById(nat.NetworkId)
+		if err != nil {
+			return errors.Wrapf(err, "NetworkManager.FetchById(%s)", nat.NetworkId)
+		}
+		network := _network.(*models.SNetwork)
+		opts.NetworkId = network.ExternalId
+	}
+
+	if nat.BillingType == billing_api.BILLING_TYPE_PREPAID {
+		bc, err := billing.ParseBillingCycle(nat.BillingCycle)
+		if err != nil {
+			return errors.Wrapf(err, "ParseBillingCycle(%s)", nat.BillingCycle)
+		}
+		bc.AutoRenew = nat.AutoRenew
+		opts.BillingCycle = &bc
+	}
+
+	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
+		iVpc, err := vpc.GetIVpc()
+		if err != nil {
+			return nil, errors.Wrapf(err, "vpc.GetIVpc")
+		}
+
+		iNat, err := iVpc.CreateINatGateway(&opts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "iVpc.CreateINatGateway")
+		}
+		err = db.SetExternalId(nat, userCred, iNat.GetGlobalId())
+		if err != nil {
+			return nil, errors.Wrapf(err, "db.SetExternalId")
+		}
+
+		err = cloudprovider.WaitStatus(iNat, api.NAT_STAUTS_AVAILABLE, time.Second*5, time.Minute*10)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cloudprovider.WaitStatus")
+		}
+
+		nat.SyncWithCloudNatGateway(ctx, userCred, nat.GetCloudprovider(), iNat)
+		return nil, nil
+	})
+	return nil
+}
+
+func (self *SManagedVirtualizationRegionDriver) RequestDeleteNatGateway(ctx context.Context, userCred mcclient.TokenCredential, nat *models.SNatGateway, task taskman.ITask) error {
+	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
+		iNat, err := nat.GetINatGateway()
+		if err != nil {
+			if errors.Cause(err) == cloudprovider.ErrNotFound {
+				return nil, nil
+			}
+			return nil, errors.Wrapf(err, "nat.GetINatGateway")
+		}
+
+		dnat, err := iNat.GetINatDTable()
+		if err != nil {
+			return nil, errors.Wrapf(err, "iNat.GetINatDTable")
+		}
+		for i := range dnat {
+			err = dnat[i].Delete()
+			if err != nil {
+				return nil, errors.Wrapf(err, "delete d entry %v", dnat[i])
+			}
+		}
+		snat, err := iNat.GetINatSTable()
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetINatSTable")
+		}
+		for i := range snat {
+			err = snat[i].Delete()
+			if err != nil {
+				return nil, errors.Wrapf(err, "delete s entry %v", snat[i])
+			}
+		}
+
+		err = iNat.Delete()
+		if err != nil {
+			return nil, errors.Wrapf(err, "iNat.Delete")
+		}
+
+		err = cloudprovider.WaitDeleted(iNat, time.Second*5, time.Minute*3)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cloudprovider.WaitDeleted")
+		}
+		return nil, nil
+	})
+	return nil
+}
+
+func (self *SManagedVirtualizationRegionDriver) RequestCreateNatSEntry(ctx context.Context, userCred mcclient.TokenCredential, snatEntry *models.SNatSEntry, task taskman.ITask) error {
+	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
+		cloudNatGateway, err := snatEntry.GetINatGateway()
+		if err != nil {
+			return nil, errors.Wrapf(err, "Get NatGateway failed")
+		}
+
+		params := task.GetParams()
+		externalIPID, _ := params.GetString("eip_external_id")
+		snatRule := cloudprovider.SNatSRule{
+			ExternalIP:   snatEntry.IP,
+			ExternalIPID: externalIPID,
+		}
+		if params.Contains("network_ext_id") {
+			extID, _ := params.GetString("network_ext_id")
+			snatRule.NetworkID = extID
+		} else {
+			snatRule.SourceCIDR = snatEntry.SourceCIDR
+		}
+		extSnat, err := cloudNatGateway.CreateINatSEntry(snatRule)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Create SNat Entry '%s' failed", snatEntry.ExternalId)
+		}
+
+		err = cloudprovider.WaitStatus(extSnat, api.NAT_STAUTS_AVAILABLE, 10*time.Second, 300*time.Second)
+		if err != nil {
+			return nil, errors.Wrap(err, "cloudprovider.WaitStatus")
+		}
+
+		err = db.SetExternalId(snatEntry, userCred, extSnat.GetGlobalId())
+		if err != nil {
+			return nil, errors.Wrap(err, "set external id failed")
+		}
+		return nil, nil
+	})
+	return nil
+}
+
+func (self *SManagedVirtualizationRegionDriver) RequestDeleteNatSEntry(ctx context.Context, userCred mcclient.TokenCredential, snatEntry *models.SNatSEntry, task taskman.ITask) error {
+	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
+		cloudNatGateway, err := snatEntry.GetINatGateway()
+		if err != nil {
+			return nil, errors.Wrapf(err, "Get NatGateway failed")
+		}
+		cloudNatSEntry, err := cloudNatGateway.GetINatSEntryByID(snatEntry.ExternalId)
+		if errors.Cause(err) == cloudprovider.ErrNotFound {
+			// already delete
+			return nil, nil
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "Get SNat Entry by ID '%s' failed", snatEntry.ExternalId)
+		} else if cloudNatSEntry != nil {
+			err = cloudNatSEntry.Delete()
+			if err != nil {
+				return nil, errors.Wrapf(err, "Delete SNat Entry '%s' failed", snatEntry.ExternalId)
+			}
+
+			err = cloudprovider.WaitDeleted(cloudNatSEntry, 10*time.Second, 300*time.Second)
+			if err != nil {
+				return nil, errors.Wrap(err, "cloudprovider.WaitDeleted")
+			}
+		}
+		return nil, nil
+	})
+	return nil
+}
+
+func (self *SManagedVirtualizationRegionDriver) RequestCreateNatDEntry(ctx context.Context, userCred mcclient.TokenCredential, dnatEntry *models.SNatDEntry, task taskman.ITask) error {
+	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
+		cloudNatGateway, err := dnatEntry.GetINatGateway()
+		if err != nil {
+			return nil, errors.Wrapf(err, "Get NatGateway failed")
+		}
+
+		params := task.GetParams()
+		externalIPID, _ := params.GetString("eip_external_id")
+		dnatRule := cloudprovider.SNatDRule{
+			Protocol:     dnatEntry.IpProtocol,
+			InternalIP:   dnatEntry.InternalIP,
+			InternalPort: dnatEntry.InternalPort,
+			ExternalIP:   dnatEntry.ExternalIP,
+			ExternalIPID: externalIPID,
+			ExternalPort: dnatEntry.ExternalPort,
+		}
+		extDnat, err := cloudNatGateway.CreateINatDEntry(dnatRule)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Create DNat Entry '%s' failed", dnatEntry.ExternalId)
+		}
+
+		err = cloudprovider.WaitStatus(extDnat, api.NAT_STAUTS_AVAILABLE, 10*time.Second, 300*time.Second)
+		if err != nil {
+			return nil, errors.Wrap(err, "cloudprovider.WaitStatus")
+		}
+
+		err = db.SetExternalId(dnatEntry, userCred, extDnat.GetGlobalId())
+		if err != nil {
+			return nil, errors.Wrap(err, "set external id failed")
+		}
+		return nil, nil
+	})
+	return nil
+}
+
+func (self *SManagedVirtualizationRegionDriver) RequestDeleteNatDEntry(ctx context.Context, userCred mcclient.TokenCredential, dnatEntry *models.SNatDEntry, task taskman.ITask) error {
+	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
+		cloudNatGateway, err := dnatEntry.GetINatGateway()
+		if err != nil {
+			return nil, errors.Wrapf(err, "Get NatGateway failed")
+		}
+		cloudNatDEntry, err := cloudNatGateway.GetINatDEntryByID(dnatEntry.ExternalId)
+		if errors.Cause(err) == cloudprovider.ErrNotFound {
+			// already delete
+			return nil, nil
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "Get DNat Entry by ID '%s' failed", dnatEntry.ExternalId)
+		} else if cloudNatDEntry != nil {
+			err = cloudNatDEntry.Delete()
+			if err != nil {
+				return nil, errors.Wrapf(err, "Delete DNat Entry '%s' failed", dnatEntry.ExternalId)
+			}
+
+			err = cloudprovider.WaitDeleted(cloudNatDEntry, 10*time.Second, 300*time.Second)
+			if err != nil {
+				return nil, errors.Wrap(err, "cloudprovider.WaitDeleted")
+			}
+		}
+		return nil, nil
+	})
+	return nil
+}
+
 func (self *SManagedVirtualizationRegionDriver) RequestSyncBucketStatus(ctx context.Context, userCred mcclient.TokenCredential, bucket *models.SBucket, task taskman.ITask) error {
 	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
 		iBucket, err := bucket.GetIBucket()
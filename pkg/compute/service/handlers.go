@@ -59,6 +59,7 @@ func InitHandlers(app *appsrv.Application) {
 		db.SharedResourceManager,
 		db.I18nManager,
 		models.GuestcdromManager,
+		models.GuestVirtiofsManager,
 		models.NetInterfaceManager,
 		models.VCenterManager,
 
@@ -87,6 +88,8 @@ func InitHandlers(app *appsrv.Application) {
 
 		models.ScalingTimerManager,
 		models.ScalingAlarmManager,
+		models.ScalingTargetManager,
+		models.ScalingStepManager,
 		models.ScalingGroupGuestManager,
 		models.ScalingGroupNetworkManager,
 
@@ -136,6 +139,7 @@ func InitHandlers(app *appsrv.Application) {
 		models.SnapshotManager,
 		models.SnapshotPolicyManager,
 		models.SnapshotPolicyCacheManager,
+		models.DiskBackupManager,
 		models.BaremetalagentManager,
 		models.LoadbalancerManager,
 		models.LoadbalancerListenerManager,
@@ -190,6 +194,7 @@ func InitHandlers(app *appsrv.Application) {
 		models.ScalingGroupManager,
 		models.ScalingPolicyManager,
 		models.ScalingActivityManager,
+		models.ScalingLifecycleHookManager,
 		models.PolicyDefinitionManager,
 		models.PolicyAssignmentManager,
 
@@ -0,0 +1,118 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storagedrivers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+type SCifsStorageDriver struct {
+	SBaseStorageDriver
+}
+
+func init() {
+	driver := SCifsStorageDriver{}
+	models.RegisterStorageDriver(&driver)
+}
+
+func (self *SCifsStorageDriver) GetStorageType() string {
+	return api.STORAGE_CIFS
+}
+
+func (self *SCifsStorageDriver) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, input *api.StorageCreateInput) error {
+	input.StorageConf = jsonutils.NewDict()
+	if len(input.CifsHost) == 0 {
+		return httperrors.NewMissingParameterError("cifs_host")
+	}
+	if len(input.CifsSharedDir) == 0 {
+		return httperrors.NewMissingParameterError("cifs_shared_dir")
+	}
+	if !strings.HasPrefix(input.CifsSharedDir, "/") {
+		input.CifsSharedDir = "/" + input.CifsSharedDir
+	}
+	if len(input.CifsPassword) > 0 && len(input.CifsUsername) == 0 {
+		return httperrors.NewMissingParameterError("cifs_username")
+	}
+	for _, opt := range []string{"username=", "password=", "domain=", "credentials="} {
+		if strings.Contains(input.CifsMountOptions, opt) {
+			return httperrors.NewInputParameterError("cifs_mount_options should not contain %s", strings.TrimSuffix(opt, "="))
+		}
+	}
+	conf := map[string]string{
+		"cifs_host":       input.CifsHost,
+		"cifs_shared_dir": input.CifsSharedDir,
+	}
+	if len(input.CifsUsername) > 0 {
+		// password is saved encrypted with storage id in PostCreate
+		conf["cifs_username"] = input.CifsUsername
+	}
+	if len(input.CifsDomain) > 0 {
+		conf["cifs_domain"] = input.CifsDomain
+	}
+	if len(input.CifsMountOptions) > 0 {
+		conf["cifs_mount_options"] = input.CifsMountOptions
+	}
+	input.StorageConf.Update(jsonutils.Marshal(conf))
+	return nil
+}
+
+func (self *SCifsStorageDriver) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, storage *models.SStorage, data jsonutils.JSONObject) {
+	sc := &models.SStoragecache{}
+	sc.Path = options.Options.DefaultImageCacheDir
+	sc.ExternalId = storage.Id
+	sc.Name = "cifs-" + storage.Name + time.Now().Format("2006-01-02 15:04:05")
+	if err := models.StoragecacheManager.TableSpec().Insert(ctx, sc); err != nil {
+		log.Errorf("insert storagecache for storage %s error: %v", storage.Name, err)
+		return
+	}
+	var secret string
+	if password, _ := data.GetString("cifs_password"); len(password) > 0 {
+		var err error
+		secret, err = utils.EncryptAESBase64(storage.Id, password)
+		if err != nil {
+			log.Errorf("encrypt cifs password for storage %s error: %v", storage.Name, err)
+			return
+		}
+	}
+	_, err := db.Update(storage, func() error {
+		storage.StoragecacheId = sc.Id
+		storage.Status = api.STORAGE_ONLINE
+		if len(secret) > 0 {
+			conf := jsonutils.NewDict()
+			if storage.StorageConf != nil {
+				conf.Update(storage.StorageConf)
+			}
+			conf.Set("cifs_password", jsonutils.NewString(secret))
+			storage.StorageConf = conf
+		}
+		return nil
+	})
+	if err != nil {
+		log.Errorf("update storagecache info for storage %s error: %v", storage.Name, err)
+	}
+}
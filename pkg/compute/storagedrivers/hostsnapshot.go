@@ -0,0 +1,48 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storagedrivers
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/util/httputils"
+)
+
+// requestHostSnapshot asks master host of the snapshot storage to create or
+// delete the snapshot, for storages whose snapshots are standalone volumes
+// managed by host instead of guest disk chains.  action is snapshot or
+// delete-snapshot
+func requestHostSnapshot(ctx context.Context, snapshot *models.SSnapshot, task taskman.ITask, action string) error {
+	storage := snapshot.GetStorage()
+	host := storage.GetMasterHost()
+	if host == nil {
+		return errors.Errorf("storage %s can't get master host", storage.Id)
+	}
+	url := fmt.Sprintf("%s/disks/%s/%s/%s", host.ManagerUri, storage.Id, action, snapshot.DiskId)
+	header := task.GetTaskRequestHeader()
+	params := jsonutils.NewDict()
+	params.Set("snapshot_id", jsonutils.NewString(snapshot.Id))
+	_, _, err := httputils.JSONRequest(httputils.GetDefaultClient(), ctx, "POST", url, header, params, false)
+	if err != nil {
+		return errors.Wrapf(err, "request %s", action)
+	}
+	return nil
+}
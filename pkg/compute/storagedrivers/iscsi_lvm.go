@@ -0,0 +1,128 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storagedrivers
+
+import (
+	"context"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+type SISCSILVMStorageDriver struct {
+	SBaseStorageDriver
+}
+
+func init() {
+	driver := SISCSILVMStorageDriver{}
+	models.RegisterStorageDriver(&driver)
+}
+
+func (self *SISCSILVMStorageDriver) GetStorageType() string {
+	return api.STORAGE_ISCSI_LVM
+}
+
+func (self *SISCSILVMStorageDriver) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, input *api.StorageCreateInput) error {
+	input.StorageConf = jsonutils.NewDict()
+	if len(input.IscsiPortal) == 0 {
+		return httperrors.NewMissingParameterError("iscsi_portal")
+	}
+	if len(input.IscsiTarget) == 0 {
+		return httperrors.NewMissingParameterError("iscsi_target")
+	}
+	if len(input.LvmVgName) == 0 {
+		return httperrors.NewMissingParameterError("lvm_vg_name")
+	}
+	if len(input.IscsiChapPassword) > 0 && len(input.IscsiChapUsername) == 0 {
+		return httperrors.NewMissingParameterError("iscsi_chap_username")
+	}
+
+	storages := []models.SStorage{}
+	q := models.StorageManager.Query().Equals("storage_type", api.STORAGE_ISCSI_LVM)
+	err := db.FetchModelObjects(models.StorageManager, q, &storages)
+	if err != nil {
+		return httperrors.NewGeneralError(err)
+	}
+
+	for i := 0; i < len(storages); i++ {
+		target, _ := storages[i].StorageConf.GetString("target")
+		vgName, _ := storages[i].StorageConf.GetString("vg_name")
+		if input.IscsiTarget == target || input.LvmVgName == vgName {
+			return httperrors.NewDuplicateResourceError("This iSCSI LVM Storage[%s/%s] has already exist", storages[i].Name, vgName)
+		}
+	}
+
+	conf := map[string]string{
+		"portal":  input.IscsiPortal,
+		"target":  input.IscsiTarget,
+		"vg_name": input.LvmVgName,
+	}
+	if len(input.IscsiChapUsername) > 0 {
+		// password is saved encrypted with storage id in PostCreate
+		conf["chap_username"] = input.IscsiChapUsername
+	}
+	input.StorageConf.Update(jsonutils.Marshal(conf))
+	return nil
+}
+
+// images are cached on every host and then converted into logical volumes
+func (self *SISCSILVMStorageDriver) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, storage *models.SStorage, data jsonutils.JSONObject) {
+	sc := &models.SStoragecache{}
+	sc.Path = options.Options.DefaultImageCacheDir
+	sc.ExternalId = storage.Id
+	sc.Name = "iscsi-lvm-" + storage.Name + time.Now().Format("2006-01-02 15:04:05")
+	if err := models.StoragecacheManager.TableSpec().Insert(ctx, sc); err != nil {
+		log.Errorf("insert storagecache for storage %s error: %v", storage.Name, err)
+		return
+	}
+	var secret string
+	if password, _ := data.GetString("iscsi_chap_password"); len(password) > 0 {
+		var err error
+		secret, err = utils.EncryptAESBase64(storage.Id, password)
+		if err != nil {
+			log.Errorf("encrypt chap password for storage %s error: %v", storage.Name, err)
+			return
+		}
+	}
+	_, err := db.Update(storage, func() error {
+		storage.StoragecacheId = sc.Id
+		storage.Status = api.STORAGE_ONLINE
+		if len(secret) > 0 {
+			conf := jsonutils.NewDict()
+			if storage.StorageConf != nil {
+				conf.Update(storage.StorageConf)
+			}
+			conf.Set("chap_password", jsonutils.NewString(secret))
+			storage.StorageConf = conf
+		}
+		return nil
+	})
+	if err != nil {
+		log.Errorf("update storagecache info for storage %s error: %v", storage.Name, err)
+	}
+}
+
+func (self *SISCSILVMStorageDriver) ValidateCreateSnapshotData(ctx context.Context, userCred mcclient.TokenCredential, disk *models.SDisk, input *api.SnapshotCreateInput) error {
+	return httperrors.NewUnsupportOperationError("iscsi lvm storage not support snapshot")
+}
@@ -0,0 +1,84 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storagedrivers
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+type SLVMStorageDriver struct {
+	SBaseStorageDriver
+}
+
+func init() {
+	driver := SLVMStorageDriver{}
+	models.RegisterStorageDriver(&driver)
+}
+
+func (self *SLVMStorageDriver) GetStorageType() string {
+	return api.STORAGE_LVM
+}
+
+func (self *SLVMStorageDriver) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, input *api.StorageCreateInput) error {
+	input.StorageConf = jsonutils.NewDict()
+	if len(input.LvmVgName) == 0 {
+		return httperrors.NewMissingParameterError("lvm_vg_name")
+	}
+	input.StorageConf.Set("vg_name", jsonutils.NewString(input.LvmVgName))
+	if len(input.LvmThinPool) > 0 {
+		input.StorageConf.Set("thin_pool", jsonutils.NewString(input.LvmThinPool))
+	}
+	return nil
+}
+
+func (self *SLVMStorageDriver) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, storage *models.SStorage, data jsonutils.JSONObject) {
+}
+
+func (self *SLVMStorageDriver) ValidateSnapshotDelete(ctx context.Context, snapshot *models.SSnapshot) error {
+	return nil
+}
+
+func (self *SLVMStorageDriver) ValidateCreateSnapshotData(ctx context.Context, userCred mcclient.TokenCredential, disk *models.SDisk, input *api.SnapshotCreateInput) error {
+	storage := disk.GetStorage()
+	if storage == nil || storage.StorageConf == nil || !storage.StorageConf.Contains("thin_pool") {
+		return httperrors.NewUnsupportOperationError("lvm storage without thin pool not support snapshot")
+	}
+	return self.SBaseStorageDriver.ValidateCreateSnapshotData(ctx, userCred, disk, input)
+}
+
+func (self *SLVMStorageDriver) RequestCreateSnapshot(ctx context.Context, snapshot *models.SSnapshot, task taskman.ITask) error {
+	return requestHostSnapshot(ctx, snapshot, task, "snapshot")
+}
+
+func (self *SLVMStorageDriver) RequestDeleteSnapshot(ctx context.Context, snapshot *models.SSnapshot, task taskman.ITask) error {
+	return requestHostSnapshot(ctx, snapshot, task, "delete-snapshot")
+}
+
+// thin snapshots are standalone volumes, never in the disk backing chain
+func (self *SLVMStorageDriver) SnapshotIsOutOfChain(disk *models.SDisk) bool {
+	return true
+}
+
+func (self *SLVMStorageDriver) OnDiskReset(ctx context.Context, userCred mcclient.TokenCredential, disk *models.SDisk, snapshot *models.SSnapshot, data jsonutils.JSONObject) error {
+	return nil
+}
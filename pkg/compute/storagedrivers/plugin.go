@@ -0,0 +1,100 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storagedrivers
+
+import (
+	"context"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+// SPluginStorageDriver delegates volume operations to storage plugin
+// running on hosts, requests are forwarded by host agent
+type SPluginStorageDriver struct {
+	SBaseStorageDriver
+}
+
+func init() {
+	driver := SPluginStorageDriver{}
+	models.RegisterStorageDriver(&driver)
+}
+
+func (self *SPluginStorageDriver) GetStorageType() string {
+	return api.STORAGE_PLUGIN
+}
+
+func (self *SPluginStorageDriver) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, input *api.StorageCreateInput) error {
+	input.StorageConf = jsonutils.NewDict()
+	if len(input.PluginDriver) == 0 {
+		return httperrors.NewMissingParameterError("plugin_driver")
+	}
+	input.StorageConf.Set("driver", jsonutils.NewString(input.PluginDriver))
+	if input.PluginOptions == nil {
+		input.PluginOptions = map[string]string{}
+	}
+	input.StorageConf.Set("options", jsonutils.Marshal(input.PluginOptions))
+	return nil
+}
+
+// images are cached on every host and then converted into published volumes
+func (self *SPluginStorageDriver) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, storage *models.SStorage, data jsonutils.JSONObject) {
+	sc := &models.SStoragecache{}
+	sc.Path = options.Options.DefaultImageCacheDir
+	sc.ExternalId = storage.Id
+	sc.Name = "plugin-" + storage.Name + time.Now().Format("2006-01-02 15:04:05")
+	if err := models.StoragecacheManager.TableSpec().Insert(ctx, sc); err != nil {
+		log.Errorf("insert storagecache for storage %s error: %v", storage.Name, err)
+		return
+	}
+	_, err := db.Update(storage, func() error {
+		storage.StoragecacheId = sc.Id
+		storage.Status = api.STORAGE_ONLINE
+		return nil
+	})
+	if err != nil {
+		log.Errorf("update storagecache info for storage %s error: %v", storage.Name, err)
+	}
+}
+
+func (self *SPluginStorageDriver) ValidateSnapshotDelete(ctx context.Context, snapshot *models.SSnapshot) error {
+	return nil
+}
+
+func (self *SPluginStorageDriver) RequestCreateSnapshot(ctx context.Context, snapshot *models.SSnapshot, task taskman.ITask) error {
+	return requestHostSnapshot(ctx, snapshot, task, "snapshot")
+}
+
+func (self *SPluginStorageDriver) RequestDeleteSnapshot(ctx context.Context, snapshot *models.SSnapshot, task taskman.ITask) error {
+	return requestHostSnapshot(ctx, snapshot, task, "delete-snapshot")
+}
+
+// plugin snapshots are independent of volume as csi snapshots
+func (self *SPluginStorageDriver) SnapshotIsOutOfChain(disk *models.SDisk) bool {
+	return true
+}
+
+func (self *SPluginStorageDriver) OnDiskReset(ctx context.Context, userCred mcclient.TokenCredential, disk *models.SDisk, snapshot *models.SSnapshot, data jsonutils.JSONObject) error {
+	return nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	host_api "yunion.io/x/onecloud/pkg/apis/host"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+type DiskBackupCreateTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(DiskBackupCreateTask{})
+}
+
+func (self *DiskBackupCreateTask) taskFailed(ctx context.Context, backup *models.SDiskBackup, reason jsonutils.JSONObject) {
+	backup.SetStatus(self.UserCred, api.DISK_BACKUP_STATUS_CREATE_FAILED, reason.String())
+	db.OpsLog.LogEvent(backup, db.ACT_ALLOCATE_FAIL, reason, self.UserCred)
+	logclient.AddActionLogWithStartable(self, backup, logclient.ACT_CREATE, reason, self.UserCred, false)
+	self.SetStageFailed(ctx, reason)
+}
+
+func (self *DiskBackupCreateTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	backup := obj.(*models.SDiskBackup)
+	guest, err := backup.GetGuest()
+	if err != nil {
+		self.taskFailed(ctx, backup, jsonutils.NewString(fmt.Sprintf("get guest: %v", err)))
+		return
+	}
+	if backup.BackupMode == api.DISK_BACKUP_MODE_FULL {
+		// a full backup starts a new dirty bitmap for its incremental backups
+		_, err = db.Update(backup, func() error {
+			backup.BitmapName = api.DISK_BACKUP_BITMAP_PREFIX + backup.Id
+			return nil
+		})
+		if err != nil {
+			self.taskFailed(ctx, backup, jsonutils.NewString(err.Error()))
+			return
+		}
+	}
+	self.SetStage("OnDiskBackupComplete", nil)
+	err = guest.GetDriver().RequestDiskBackup(ctx, guest, self, backup.GetBackupRequest())
+	if err != nil {
+		self.taskFailed(ctx, backup, jsonutils.NewString(err.Error()))
+	}
+}
+
+func (self *DiskBackupCreateTask) OnDiskBackupComplete(ctx context.Context, backup *models.SDiskBackup, data jsonutils.JSONObject) {
+	resp := host_api.GuestDiskBackupResponse{}
+	data.Unmarshal(&resp)
+	_, err := db.Update(backup, func() error {
+		backup.Location = resp.Location
+		backup.SizeMb = resp.SizeMb
+		if resp.BackupMode == api.DISK_BACKUP_MODE_FULL && backup.BackupMode != api.DISK_BACKUP_MODE_FULL {
+			// host lost the dirty bitmap and took a full backup instead
+			backup.BackupMode = api.DISK_BACKUP_MODE_FULL
+			backup.ParentId = ""
+			backup.BitmapName = api.DISK_BACKUP_BITMAP_PREFIX + backup.Id
+		}
+		return nil
+	})
+	if err != nil {
+		self.taskFailed(ctx, backup, jsonutils.NewString(err.Error()))
+		return
+	}
+	backup.SetStatus(self.UserCred, api.DISK_BACKUP_STATUS_READY, "")
+	db.OpsLog.LogEvent(backup, db.ACT_ALLOCATE, backup.GetShortDesc(ctx), self.UserCred)
+	logclient.AddActionLogWithStartable(self, backup, logclient.ACT_CREATE, backup.GetShortDesc(ctx), self.UserCred, true)
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *DiskBackupCreateTask) OnDiskBackupCompleteFailed(ctx context.Context, backup *models.SDiskBackup, data jsonutils.JSONObject) {
+	self.taskFailed(ctx, backup, data)
+}
@@ -0,0 +1,57 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+type DiskBackupDeleteTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(DiskBackupDeleteTask{})
+}
+
+func (self *DiskBackupDeleteTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	backup := obj.(*models.SDiskBackup)
+	err := backup.RemoveBackupObject()
+	if err != nil {
+		reason := jsonutils.NewString(err.Error())
+		backup.SetStatus(self.UserCred, api.DISK_BACKUP_STATUS_DELETE_FAILED, err.Error())
+		db.OpsLog.LogEvent(backup, db.ACT_DELOCATE_FAIL, reason, self.UserCred)
+		logclient.AddActionLogWithStartable(self, backup, logclient.ACT_DELETE, reason, self.UserCred, false)
+		self.SetStageFailed(ctx, reason)
+		return
+	}
+	err = backup.RealDelete(ctx, self.UserCred)
+	if err != nil {
+		reason := jsonutils.NewString(err.Error())
+		backup.SetStatus(self.UserCred, api.DISK_BACKUP_STATUS_DELETE_FAILED, err.Error())
+		self.SetStageFailed(ctx, reason)
+		return
+	}
+	logclient.AddActionLogWithStartable(self, backup, logclient.ACT_DELETE, nil, self.UserCred, true)
+	self.SetStageComplete(ctx, nil)
+}
@@ -0,0 +1,120 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+type DiskBackupRecoveryTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(DiskBackupRecoveryTask{})
+}
+
+func (self *DiskBackupRecoveryTask) getRecoveryDisk() (*models.SDisk, error) {
+	diskId, _ := self.GetParams().GetString("disk_id")
+	obj, err := models.DiskManager.FetchById(diskId)
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*models.SDisk), nil
+}
+
+func (self *DiskBackupRecoveryTask) taskFailed(ctx context.Context, backup *models.SDiskBackup, disk *models.SDisk, reason jsonutils.JSONObject) {
+	if disk != nil {
+		disk.SetStatus(self.UserCred, api.DISK_ALLOC_FAILED, reason.String())
+	}
+	backup.SetStatus(self.UserCred, api.DISK_BACKUP_STATUS_READY, "")
+	logclient.AddActionLogWithStartable(self, backup, logclient.ACT_RESTORE, reason, self.UserCred, false)
+	self.SetStageFailed(ctx, reason)
+}
+
+func (self *DiskBackupRecoveryTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	backup := obj.(*models.SDiskBackup)
+	disk, err := self.getRecoveryDisk()
+	if err != nil {
+		self.taskFailed(ctx, backup, nil, jsonutils.NewString(fmt.Sprintf("get disk: %v", err)))
+		return
+	}
+	recovery, err := backup.GetRecoveryRequest()
+	if err != nil {
+		self.taskFailed(ctx, backup, disk, jsonutils.NewString(err.Error()))
+		return
+	}
+	storage := disk.GetStorage()
+	host := storage.GetMasterHost()
+	if host == nil {
+		self.taskFailed(ctx, backup, disk, jsonutils.NewString(fmt.Sprintf("no host attached to storage %s", storage.Name)))
+		return
+	}
+
+	content := jsonutils.NewDict()
+	content.Set("format", jsonutils.NewString(disk.DiskFormat))
+	content.Set("size", jsonutils.NewInt(int64(disk.DiskSize)))
+	content.Set("backup", jsonutils.Marshal(recovery))
+
+	db.OpsLog.LogEvent(disk, db.ACT_ALLOCATING, disk.GetShortDesc(ctx), self.UserCred)
+	disk.SetStatus(self.UserCred, api.DISK_STARTALLOC, fmt.Sprintf("Disk recovery from backup %s use host %s(%s)", backup.Name, host.Name, host.Id))
+	self.SetStage("OnDiskReady", nil)
+	err = host.GetHostDriver().RequestAllocateDiskOnStorage(ctx, self.UserCred, host, storage, disk, self, content)
+	if err != nil {
+		self.taskFailed(ctx, backup, disk, jsonutils.NewString(err.Error()))
+	}
+}
+
+func (self *DiskBackupRecoveryTask) OnDiskReady(ctx context.Context, backup *models.SDiskBackup, data jsonutils.JSONObject) {
+	disk, err := self.getRecoveryDisk()
+	if err != nil {
+		self.taskFailed(ctx, backup, nil, jsonutils.NewString(fmt.Sprintf("get disk: %v", err)))
+		return
+	}
+	diskSize, _ := data.Int("disk_size")
+	if _, err := db.Update(disk, func() error {
+		disk.DiskSize = int(diskSize)
+		diskFormat, _ := data.GetString("disk_format")
+		if len(diskFormat) > 0 {
+			disk.DiskFormat = diskFormat
+		}
+		disk.AccessPath, _ = data.GetString("disk_path")
+		return nil
+	}); err != nil {
+		log.Errorf("update disk info error: %v", err)
+	}
+	disk.SetStatus(self.UserCred, api.DISK_READY, "")
+	disk.GetStorage().ClearSchedDescCache()
+	db.OpsLog.LogEvent(disk, db.ACT_ALLOCATE, disk.GetShortDesc(ctx), self.UserCred)
+
+	backup.SetStatus(self.UserCred, api.DISK_BACKUP_STATUS_READY, "")
+	logclient.AddActionLogWithStartable(self, backup, logclient.ACT_RESTORE, disk.GetShortDesc(ctx), self.UserCred, true)
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *DiskBackupRecoveryTask) OnDiskReadyFailed(ctx context.Context, backup *models.SDiskBackup, data jsonutils.JSONObject) {
+	disk, _ := self.getRecoveryDisk()
+	self.taskFailed(ctx, backup, disk, data)
+}
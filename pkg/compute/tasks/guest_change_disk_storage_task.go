@@ -0,0 +1,159 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	host_api "yunion.io/x/onecloud/pkg/apis/host"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+type GuestChangeDiskStorageTask struct {
+	SGuestBaseTask
+}
+
+func init() {
+	taskman.RegisterTask(GuestChangeDiskStorageTask{})
+}
+
+func (task *GuestChangeDiskStorageTask) getDisk() (*models.SDisk, error) {
+	diskId, _ := task.Params.GetString("disk_id")
+	diskObj, err := models.DiskManager.FetchById(diskId)
+	if err != nil {
+		return nil, err
+	}
+	return diskObj.(*models.SDisk), nil
+}
+
+func (task *GuestChangeDiskStorageTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	guest := obj.(*models.SGuest)
+	disk, err := task.getDisk()
+	if err != nil {
+		task.OnTaskFailed(ctx, guest, jsonutils.NewString(err.Error()))
+		return
+	}
+	db.OpsLog.LogEvent(disk, db.ACT_MIGRATING, task.Params, task.UserCred)
+
+	targetStorageId, _ := task.Params.GetString("target_storage_id")
+	input := &host_api.GuestChangeDiskStorageRequest{
+		DiskId:          disk.Id,
+		TargetStorageId: targetStorageId,
+	}
+	task.SetStage("OnDiskMirrorComplete", nil)
+	err = guest.GetDriver().RequestChangeDiskStorage(ctx, guest, task, input)
+	if err != nil {
+		task.OnTaskFailed(ctx, guest, jsonutils.NewString(err.Error()))
+	}
+}
+
+// guest already switched to the disk on target storage
+func (task *GuestChangeDiskStorageTask) OnDiskMirrorComplete(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
+	disk, err := task.getDisk()
+	if err != nil {
+		task.OnTaskFailed(ctx, guest, jsonutils.NewString(err.Error()))
+		return
+	}
+	targetStorageId, _ := task.Params.GetString("target_storage_id")
+	resp := &host_api.GuestChangeDiskStorageResponse{}
+	if data != nil {
+		data.Unmarshal(resp)
+	}
+	_, err = db.Update(disk, func() error {
+		setDiskOnTargetStorage(disk, targetStorageId, resp)
+		return nil
+	})
+	if err != nil {
+		task.OnTaskFailed(ctx, guest, jsonutils.NewString(fmt.Sprintf("update disk storage: %v", err)))
+		return
+	}
+	disk.SetStatus(task.UserCred, api.DISK_READY, "")
+	db.OpsLog.LogEvent(disk, db.ACT_MIGRATE, task.Params, task.UserCred)
+	logclient.AddActionLogWithStartable(task, disk, logclient.ACT_MIGRATE, task.Params, task.UserCred, true)
+
+	sourceStorageId, _ := task.Params.GetString("source_storage_id")
+	sourceStorage := models.StorageManager.FetchStorageById(sourceStorageId)
+	if sourceStorage == nil {
+		task.OnSourceDiskDeleted(ctx, guest, nil)
+		return
+	}
+	host := guest.GetHost()
+	task.SetStage("OnSourceDiskDeleted", nil)
+	err = host.GetHostDriver().RequestDeallocateDiskOnHost(ctx, host, sourceStorage, disk, task)
+	if err != nil {
+		task.OnSourceDiskDeletedFailed(ctx, guest, jsonutils.NewString(err.Error()))
+	}
+}
+
+// setDiskOnTargetStorage points disk to the volume on target storage, format
+// of which may differ from the source, e.g. qcow2 file mirrored to raw
+// logical volume
+func setDiskOnTargetStorage(disk *models.SDisk, targetStorageId string, resp *host_api.GuestChangeDiskStorageResponse) {
+	disk.StorageId = targetStorageId
+	if len(resp.DiskFormat) > 0 {
+		disk.DiskFormat = resp.DiskFormat
+	}
+	if len(resp.DiskPath) > 0 {
+		disk.AccessPath = resp.DiskPath
+	}
+}
+
+func (task *GuestChangeDiskStorageTask) OnDiskMirrorCompleteFailed(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
+	task.OnTaskFailed(ctx, guest, data)
+}
+
+func (task *GuestChangeDiskStorageTask) OnSourceDiskDeleted(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
+	task.SetStage("TaskComplete", nil)
+	guest.StartSyncTask(ctx, task.UserCred, false, task.GetId())
+}
+
+// guest is running on the new disk, leftover of source disk is not fatal
+func (task *GuestChangeDiskStorageTask) OnSourceDiskDeletedFailed(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
+	log.Errorf("GuestChangeDiskStorageTask delete source disk failed: %s", data)
+	db.OpsLog.LogEvent(guest, db.ACT_DELOCATE_FAIL, data, task.UserCred)
+	task.OnSourceDiskDeleted(ctx, guest, data)
+}
+
+func (task *GuestChangeDiskStorageTask) OnTaskFailed(ctx context.Context, guest *models.SGuest, reason jsonutils.JSONObject) {
+	log.Errorf("GuestChangeDiskStorageTask fail: %s", reason)
+	if disk, _ := task.getDisk(); disk != nil {
+		disk.SetStatus(task.UserCred, api.DISK_READY, "")
+		db.OpsLog.LogEvent(disk, db.ACT_MIGRATE_FAIL, reason, task.UserCred)
+		logclient.AddActionLogWithStartable(task, disk, logclient.ACT_MIGRATE, reason, task.UserCred, false)
+	}
+	// guest keeps running on the source disk when mirror fails
+	guestStatus, _ := task.Params.GetString("guest_status")
+	if len(guestStatus) == 0 {
+		guestStatus = api.VM_RUNNING
+	}
+	guest.SetStatus(task.UserCred, guestStatus, reason.String())
+	task.SetStageFailed(ctx, reason)
+}
+
+func (task *GuestChangeDiskStorageTask) TaskComplete(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
+	task.SetStageComplete(ctx, guest.GetShortDesc(ctx))
+}
+
+func (task *GuestChangeDiskStorageTask) TaskCompleteFailed(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
+	task.SetStageFailed(ctx, data)
+}
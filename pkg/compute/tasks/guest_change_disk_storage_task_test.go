@@ -0,0 +1,57 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"testing"
+
+	"yunion.io/x/jsonutils"
+
+	host_api "yunion.io/x/onecloud/pkg/apis/host"
+	"yunion.io/x/onecloud/pkg/compute/models"
+)
+
+func TestSetDiskOnTargetStorage(t *testing.T) {
+	t.Run("qcow2 to raw lvm", func(t *testing.T) {
+		disk := &models.SDisk{
+			DiskFormat: "qcow2",
+			AccessPath: "/opt/cloud/workspace/disks/disk-id",
+		}
+		disk.StorageId = "local-storage"
+		// response of host as received by the task
+		data := jsonutils.Marshal(&host_api.GuestChangeDiskStorageResponse{
+			DiskId:     "disk-id",
+			StorageId:  "lvm-storage",
+			DiskPath:   "/dev/vg_data/disk-id",
+			DiskFormat: "raw",
+		})
+		resp := &host_api.GuestChangeDiskStorageResponse{}
+		if err := data.Unmarshal(resp); err != nil {
+			t.Fatalf("unmarshal response: %v", err)
+		}
+		setDiskOnTargetStorage(disk, "lvm-storage", resp)
+		if disk.StorageId != "lvm-storage" || disk.DiskFormat != "raw" || disk.AccessPath != "/dev/vg_data/disk-id" {
+			t.Errorf("want lvm-storage raw /dev/vg_data/disk-id, got %s %s %s", disk.StorageId, disk.DiskFormat, disk.AccessPath)
+		}
+	})
+
+	t.Run("response without format", func(t *testing.T) {
+		disk := &models.SDisk{DiskFormat: "qcow2", AccessPath: "/old/path"}
+		setDiskOnTargetStorage(disk, "target", &host_api.GuestChangeDiskStorageResponse{})
+		if disk.StorageId != "target" || disk.DiskFormat != "qcow2" || disk.AccessPath != "/old/path" {
+			t.Errorf("want format and path kept, got %s %s %s", disk.StorageId, disk.DiskFormat, disk.AccessPath)
+		}
+	})
+}
@@ -58,6 +58,24 @@ func (self *GuestDetachScalingGroupTask) taskFailed(ctx context.Context, sg *mod
 
 func (self *GuestDetachScalingGroupTask) OnInit(ctx context.Context, obj db.IStandaloneModel, body jsonutils.JSONObject) {
 	sg := obj.(*models.SScalingGroup)
+	guestId, _ := self.Params.GetString("guest")
+	guest := models.GuestManager.FetchGuestById(guestId)
+	if guest == nil {
+		self.taskFailed(ctx, sg, nil, jsonutils.NewString("unable to FetchGuestById"))
+		return
+	}
+	// the instance is terminated whatever the result is, hooks can only delay it
+	self.SetStage("OnTerminatingHooksComplete", nil)
+	started, err := sg.StartLifecycleHookTask(ctx, self.UserCred, guest, api.LIFECYCLE_TRANSITION_TERMINATING, self.Id)
+	if err != nil {
+		log.Errorf("run terminating lifecycle hooks of scaling group %s: %v", sg.Id, err)
+	}
+	if !started {
+		self.OnTerminatingHooksComplete(ctx, sg, body)
+	}
+}
+
+func (self *GuestDetachScalingGroupTask) OnTerminatingHooksComplete(ctx context.Context, sg *models.SScalingGroup, body jsonutils.JSONObject) {
 	guestId, _ := self.Params.GetString("guest")
 	guest := models.GuestManager.FetchGuestById(guestId)
 	if guest == nil {
@@ -84,6 +102,12 @@ func (self *GuestDetachScalingGroupTask) OnInit(ctx context.Context, obj db.ISta
 	}
 }
 
+func (self *GuestDetachScalingGroupTask) OnTerminatingHooksCompleteFailed(ctx context.Context, sg *models.SScalingGroup,
+	data jsonutils.JSONObject) {
+	log.Errorf("run terminating lifecycle hooks of scaling group %s failed: %s", sg.Id, data)
+	self.OnTerminatingHooksComplete(ctx, sg, data)
+}
+
 func (self *GuestDetachScalingGroupTask) OnDetachLoadbalancerComplete(ctx context.Context, sg *models.SScalingGroup, data jsonutils.JSONObject) {
 	guestId, _ := self.Params.GetString("guest")
 	delete, _ := self.Params.Bool("delete_server")
@@ -216,6 +216,12 @@ func (self *GuestMigrateTask) OnSrcPrepareComplete(ctx context.Context, guest *m
 	if hasError {
 		return
 	}
+	if data != nil && data.Contains("numa_node") {
+		// numa binding of source guest decides how guest memory is declared
+		numaNode, _ := data.Get("numa_node")
+		desc, _ := body.Get("desc")
+		desc.(*jsonutils.JSONDict).Set("numa_node", numaNode)
+	}
 	guestStatus, _ := self.Params.GetString("guest_status")
 	if !jsonutils.QueryBoolean(self.Params, "is_rescue_mode", false) && (guestStatus == api.VM_RUNNING || guestStatus == api.VM_SUSPEND) {
 		body.Set("live_migrate", jsonutils.JSONTrue)
@@ -289,6 +295,14 @@ func (self *GuestMigrateTask) sharedStorageMigrateConf(ctx context.Context, gues
 	body := jsonutils.NewDict()
 	body.Set("is_local_storage", jsonutils.JSONFalse)
 	body.Set("qemu_version", jsonutils.NewString(guest.GetQemuVersion(self.UserCred)))
+	if guest.Vtpm || guest.SecureBoot {
+		// tpm state and uefi nvram are kept in server dir of source host,
+		// destination fetches them on cold migration, qemu migrates them
+		// on live migration
+		sourceHost := guest.GetHost()
+		serverUrl := fmt.Sprintf("%s/download/servers/%s", sourceHost.ManagerUri, guest.Id)
+		body.Set("server_url", jsonutils.NewString(serverUrl))
+	}
 	targetDesc := guest.GetJsonDescAtHypervisor(ctx, targetHost)
 	body.Set("desc", targetDesc)
 	return body, false
@@ -0,0 +1,109 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+)
+
+// GuestQuiescedSnapshotsTask takes snapshots of several disks of a running
+// guest with filesystems frozen once, instead of freezing and thawing for
+// each disk
+type GuestQuiescedSnapshotsTask struct {
+	SGuestBaseTask
+}
+
+func init() {
+	taskman.RegisterTask(GuestQuiescedSnapshotsTask{})
+}
+
+func (self *GuestQuiescedSnapshotsTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	guest := obj.(*models.SGuest)
+	// guest may be stopped after the snapshots were planned
+	if guest.Status == api.VM_RUNNING {
+		snapshotQuiesceGuest(ctx, self, guest, guest)
+	}
+	self.createSnapshot(ctx, guest, 0)
+}
+
+func (self *GuestQuiescedSnapshotsTask) getSnapshotIds() []string {
+	ids := []string{}
+	self.Params.Unmarshal(&ids, "snapshot_ids")
+	return ids
+}
+
+func fetchSnapshot(id string) *models.SSnapshot {
+	obj, err := models.SnapshotManager.FetchById(id)
+	if err != nil {
+		log.Errorf("fetch snapshot %s: %v", id, err)
+		return nil
+	}
+	return obj.(*models.SSnapshot)
+}
+
+func (self *GuestQuiescedSnapshotsTask) createSnapshot(ctx context.Context, guest *models.SGuest, index int) {
+	ids := self.getSnapshotIds()
+	for ; index < len(ids); index++ {
+		snapshot := fetchSnapshot(ids[index])
+		if snapshot == nil {
+			continue
+		}
+		params := jsonutils.NewDict()
+		params.Set("index", jsonutils.NewInt(int64(index)))
+		self.SetStage("OnSnapshotCreated", params)
+		if err := snapshot.StartSnapshotCreateTask(ctx, self.UserCred, nil, self.GetId()); err != nil {
+			snapshot.SetStatus(self.UserCred, api.SNAPSHOT_FAILED, err.Error())
+			continue
+		}
+		return
+	}
+	self.taskComplete(ctx, guest)
+}
+
+func (self *GuestQuiescedSnapshotsTask) OnSnapshotCreated(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
+	index, _ := self.Params.Int("index")
+	self.createSnapshot(ctx, guest, int(index)+1)
+}
+
+// failure of one disk does not stop snapshots of the others
+func (self *GuestQuiescedSnapshotsTask) OnSnapshotCreatedFailed(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
+	index, _ := self.Params.Int("index")
+	log.Errorf("guest %s quiesced snapshot %d failed: %s", guest.Name, index, data)
+	self.createSnapshot(ctx, guest, int(index)+1)
+}
+
+func (self *GuestQuiescedSnapshotsTask) taskComplete(ctx context.Context, guest *models.SGuest) {
+	if snapshotThawGuest(ctx, self, guest, guest) {
+		for _, id := range self.getSnapshotIds() {
+			snapshot := fetchSnapshot(id)
+			if snapshot == nil || snapshot.Status != api.SNAPSHOT_READY {
+				continue
+			}
+			db.Update(snapshot, func() error {
+				snapshot.Quiesced = true
+				return nil
+			})
+		}
+	}
+	self.SetStageComplete(ctx, nil)
+}
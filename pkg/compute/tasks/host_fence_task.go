@@ -0,0 +1,64 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+// HostFenceTask powers off the down host through baremetal agent, guests
+// on shared storages restart on other hosts only after the host is fenced
+type HostFenceTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(HostFenceTask{})
+}
+
+func (self *HostFenceTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	host := obj.(*models.SHost)
+	url := fmt.Sprintf("/baremetals/%s/fence", host.Id)
+	headers := self.GetTaskRequestHeader()
+	self.SetStage("OnFenceComplete", nil)
+	_, err := host.BaremetalSyncRequest(ctx, "POST", url, headers, nil)
+	if err != nil {
+		self.OnFenceCompleteFailed(ctx, host, jsonutils.NewString(err.Error()))
+	}
+}
+
+func (self *HostFenceTask) OnFenceComplete(ctx context.Context, host *models.SHost, data jsonutils.JSONObject) {
+	db.OpsLog.LogEvent(host, db.ACT_HOST_FENCE, "", self.UserCred)
+	logclient.AddActionLogWithStartable(self, host, logclient.ACT_HOST_FENCE, "", self.UserCred, true)
+	if err := host.MigrateSharedStorageServers(ctx, self.UserCred); err != nil {
+		db.OpsLog.LogEvent(host, db.ACT_HOST_DOWN, fmt.Sprintf("migrate servers failed %s", err), self.UserCred)
+	}
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *HostFenceTask) OnFenceCompleteFailed(ctx context.Context, host *models.SHost, reason jsonutils.JSONObject) {
+	// restarting guests before the host is surely powered off may corrupt shared disks
+	db.OpsLog.LogEvent(host, db.ACT_HOST_FENCE_FAIL, fmt.Sprintf("fence host failed, skip restarting servers: %s", reason), self.UserCred)
+	logclient.AddActionLogWithStartable(self, host, logclient.ACT_HOST_FENCE, reason, self.UserCred, false)
+	self.SetStageFailed(ctx, reason)
+}
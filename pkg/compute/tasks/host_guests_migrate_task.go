@@ -16,6 +16,7 @@ package tasks
 
 import (
 	"context"
+	"fmt"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
@@ -24,6 +25,7 @@ import (
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
 	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/util/logclient"
 )
 
 func init() {
@@ -35,6 +37,22 @@ type HostGuestsMigrateTask struct {
 }
 
 func (self *HostGuestsMigrateTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	if self.Params.Contains("migrating_guest") {
+		// callback of previous guest migrate task
+		migrating := &api.GuestBatchMigrateParams{}
+		self.Params.Unmarshal(migrating, "migrating_guest")
+		if migrating.HaRestart {
+			guest := models.GuestManager.FetchGuestById(migrating.Id)
+			if guest != nil {
+				logclient.AddSimpleActionLog(guest, logclient.ACT_VM_HA_RESTART,
+					fmt.Sprintf("restart attempt %d/%d succeeded", migrating.Attempts, migrating.MaxAttempts), self.UserCred, true)
+			}
+		}
+	}
+	self.migrateNext(ctx)
+}
+
+func (self *HostGuestsMigrateTask) migrateNext(ctx context.Context) {
 	guests := make([]*api.GuestBatchMigrateParams, 0)
 	err := self.Params.Unmarshal(&guests, "guests")
 	if err != nil {
@@ -43,51 +61,84 @@ func (self *HostGuestsMigrateTask) OnInit(ctx context.Context, obj db.IStandalon
 	}
 	preferHostId, _ := self.Params.GetString("prefer_host_id")
 
-	var guestMigrating bool
-	var migrateIndex int
+	var migrating *api.GuestBatchMigrateParams
+	var someFailed bool
+	remaining := make([]*api.GuestBatchMigrateParams, 0, len(guests))
 	for i := 0; i < len(guests); i++ {
+		if migrating != nil {
+			remaining = append(remaining, guests[i])
+			continue
+		}
 		guest := models.GuestManager.FetchGuestById(guests[i].Id)
+		if guest == nil {
+			someFailed = true
+			continue
+		}
+		if guests[i].HaRestart {
+			guests[i].Attempts += 1
+		}
 		if guests[i].LiveMigrate {
-			err := guest.StartGuestLiveMigrateTask(
+			err = guest.StartGuestLiveMigrateTask(
 				ctx, self.UserCred, guests[i].OldStatus, preferHostId, nil, self.Id)
-			if err != nil {
-				log.Errorln(err)
-				continue
-			} else {
-				guestMigrating = true
-				migrateIndex = i
-				break
-			}
 		} else {
-			err := guest.StartMigrateTask(ctx, self.UserCred, guests[i].RescueMode,
+			err = guest.StartMigrateTask(ctx, self.UserCred, guests[i].RescueMode,
 				false, guests[i].OldStatus, preferHostId, self.Id)
-			if err != nil {
-				log.Errorln(err)
+		}
+		if err == nil {
+			migrating = guests[i]
+			continue
+		}
+		log.Errorln(err)
+		if guests[i].HaRestart {
+			logclient.AddSimpleActionLog(guest, logclient.ACT_VM_HA_RESTART,
+				fmt.Sprintf("restart attempt %d/%d failed: %s", guests[i].Attempts, guests[i].MaxAttempts, err), self.UserCred, false)
+			if guests[i].Attempts < guests[i].MaxAttempts {
+				// failure of starting migrate task is an attempt too
+				i--
 				continue
-			} else {
-				guestMigrating = true
-				migrateIndex = i
-				break
 			}
 		}
+		someFailed = true
 	}
-	if !guestMigrating {
-		if jsonutils.QueryBoolean(self.Params, "some_guest_migrate_failed", false) {
+	if migrating == nil {
+		if someFailed || jsonutils.QueryBoolean(self.Params, "some_guest_migrate_failed", false) {
 			self.SetStageFailed(ctx, jsonutils.NewString("some guest migrate failed"))
 		} else {
 			self.SetStageComplete(ctx, nil)
 		}
 	} else {
-		guests := append(guests[:migrateIndex], guests[migrateIndex+1:]...)
 		params := jsonutils.NewDict()
-		params.Set("guests", jsonutils.Marshal(guests))
+		params.Set("migrating_guest", jsonutils.Marshal(migrating))
+		params.Set("guests", jsonutils.Marshal(remaining))
+		if someFailed {
+			params.Set("some_guest_migrate_failed", jsonutils.JSONTrue)
+		}
 		self.SaveParams(params)
 	}
 }
 
 func (self *HostGuestsMigrateTask) OnInitFailed(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
 	kwargs := jsonutils.NewDict()
-	kwargs.Set("some_guest_migrate_failed", jsonutils.JSONTrue)
+	migrating := &api.GuestBatchMigrateParams{}
+	self.Params.Unmarshal(migrating, "migrating_guest")
+	if migrating.HaRestart {
+		guest := models.GuestManager.FetchGuestById(migrating.Id)
+		if guest != nil {
+			logclient.AddSimpleActionLog(guest, logclient.ACT_VM_HA_RESTART,
+				fmt.Sprintf("restart attempt %d/%d failed: %s", migrating.Attempts, migrating.MaxAttempts, data), self.UserCred, false)
+		}
+		if guest != nil && migrating.Attempts < migrating.MaxAttempts {
+			// retry before the guests of lower priority
+			guests := make([]*api.GuestBatchMigrateParams, 0)
+			self.Params.Unmarshal(&guests, "guests")
+			guests = append([]*api.GuestBatchMigrateParams{migrating}, guests...)
+			kwargs.Set("guests", jsonutils.Marshal(guests))
+		} else {
+			kwargs.Set("some_guest_migrate_failed", jsonutils.JSONTrue)
+		}
+	} else {
+		kwargs.Set("some_guest_migrate_failed", jsonutils.JSONTrue)
+	}
 	self.SaveParams(kwargs)
-	self.OnInit(ctx, obj, data)
+	self.migrateNext(ctx)
 }
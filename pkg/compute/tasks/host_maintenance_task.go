@@ -16,6 +16,9 @@ package tasks
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"time"
 
 	"yunion.io/x/jsonutils"
 
@@ -35,33 +38,152 @@ func init() {
 	taskman.RegisterTask(HostMaintainTask{})
 }
 
+const (
+	hostDrainWaitInterval    = 15 * time.Second
+	hostDrainBlockJobTimeout = 30 * time.Minute
+)
+
 func (self *HostMaintainTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
 	host := obj.(*models.SHost)
-	guests, _ := self.Params.Get("guests")
-	preferHostId, _ := self.Params.Get("prefer_host_id")
-
-	kwargs := jsonutils.NewDict()
-	kwargs.Set("guests", guests)
-	kwargs.Set("prefer_host_id", preferHostId)
-	self.SetStage("OnGuestsMigrate", nil)
-	err := models.GuestManager.StartHostGuestsMigrateTask(ctx, self.UserCred, host, self.Params, self.Id)
-	if err != nil {
-		self.TaskFailed(ctx, host, jsonutils.NewString(err.Error()))
+	// stop scheduling new guests to the host while draining
+	if host.GetEnabled() {
+		host.SetMetadata(ctx, api.HOST_METADATA_MAINTENANCE_DISABLED, "true", self.UserCred)
+		host.PerformDisable(ctx, self.UserCred, nil, apis.PerformDisableInput{})
+	}
+	self.SetStage("OnDrainGuests", nil)
+	self.drainGuests(ctx, host)
+}
+
+func (self *HostMaintainTask) getDrainGuests() []api.HostDrainGuest {
+	guests := make([]api.HostDrainGuest, 0)
+	self.Params.Unmarshal(&guests, "drain_guests")
+	return guests
+}
+
+func (self *HostMaintainTask) saveDrainGuests(ctx context.Context, host *models.SHost, guests []api.HostDrainGuest) {
+	params := jsonutils.NewDict()
+	params.Set("drain_guests", jsonutils.Marshal(guests))
+	self.SaveParams(params)
+	host.SetDrainProgress(ctx, self.UserCred, guests)
+}
+
+// drainGuests starts draining pending guests until the concurrency is reached.
+// Guests are drained in batches: the draining guests are subtasks of stage
+// OnDrainGuests, which is called back only after all of them finished, so the
+// next batch starts when the slowest guest of the current batch is done.
+func (self *HostMaintainTask) drainGuests(ctx context.Context, host *models.SHost) {
+	guests := self.getDrainGuests()
+	preferHostId, _ := self.Params.GetString("prefer_host_id")
+	concurrency, _ := self.Params.Int("concurrency")
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	var draining, waiting int
+	for i := range guests {
+		if guests[i].Status == api.HOST_DRAIN_GUEST_DRAINING {
+			draining += 1
+		}
+	}
+	for i := range guests {
+		if int64(draining) >= concurrency {
+			break
+		}
+		if guests[i].Status != api.HOST_DRAIN_GUEST_PENDING {
+			continue
+		}
+		if guests[i].Policy != api.HOST_DRAIN_POLICY_STOP {
+			guest := models.GuestManager.FetchGuestById(guests[i].Id)
+			if guest != nil && host.GuestHasBlockJobs(ctx, self.UserCred, guest) {
+				if guests[i].WaitingSince.IsZero() {
+					guests[i].WaitingSince = time.Now()
+				}
+				if time.Since(guests[i].WaitingSince) < hostDrainBlockJobTimeout {
+					waiting += 1
+					continue
+				}
+				guests[i].Status = api.HOST_DRAIN_GUEST_FAILED
+				guests[i].Reason = "block jobs not finished"
+				continue
+			}
+		}
+		err := host.StartDrainGuest(ctx, self.UserCred, &guests[i], preferHostId, self.Id)
+		if err != nil {
+			guests[i].Status = api.HOST_DRAIN_GUEST_FAILED
+			guests[i].Reason = err.Error()
+			continue
+		}
+		guests[i].Status = api.HOST_DRAIN_GUEST_DRAINING
+		draining += 1
+	}
+	self.saveDrainGuests(ctx, host, guests)
+
+	if draining > 0 {
+		// wait for draining guests
+		return
+	}
+	if waiting > 0 {
+		self.SetStage("OnWaitBlockJobs", nil)
+		self.ScheduleRunAfter(hostDrainWaitInterval, nil)
 		return
 	}
+	self.onDrainComplete(ctx, host, guests)
 }
 
-func (self *HostMaintainTask) OnGuestsMigrate(ctx context.Context, host *models.SHost, data jsonutils.JSONObject) {
+func (self *HostMaintainTask) OnDrainGuests(ctx context.Context, host *models.SHost, data jsonutils.JSONObject) {
+	guests := self.getDrainGuests()
+	var draining bool
+	for i := range guests {
+		if guests[i].Status == api.HOST_DRAIN_GUEST_DRAINING {
+			draining = true
+			break
+		}
+	}
+	// a guest may finish before its siblings are started
+	if draining && !self.IsCurrentStageComplete() {
+		return
+	}
+	for i := range guests {
+		if guests[i].Status != api.HOST_DRAIN_GUEST_DRAINING {
+			continue
+		}
+		if reason := host.CheckDrainGuest(&guests[i]); len(reason) > 0 {
+			guests[i].Status = api.HOST_DRAIN_GUEST_FAILED
+			guests[i].Reason = reason
+		} else {
+			guests[i].Status = api.HOST_DRAIN_GUEST_DONE
+		}
+	}
+	self.saveDrainGuests(ctx, host, guests)
+	self.drainGuests(ctx, host)
+}
+
+func (self *HostMaintainTask) OnWaitBlockJobs(ctx context.Context, host *models.SHost, data jsonutils.JSONObject) {
+	self.SetStage("OnDrainGuests", nil)
+	self.drainGuests(ctx, host)
+}
+
+func (self *HostMaintainTask) OnDrainGuestsFailed(ctx context.Context, host *models.SHost, data jsonutils.JSONObject) {
+	// result of each guest is checked on its own
+	self.OnDrainGuests(ctx, host, data)
+}
+
+func (self *HostMaintainTask) onDrainComplete(ctx context.Context, host *models.SHost, guests []api.HostDrainGuest) {
+	failed := make([]string, 0)
+	for i := range guests {
+		if guests[i].Status == api.HOST_DRAIN_GUEST_FAILED {
+			failed = append(failed, fmt.Sprintf("%s: %s", guests[i].Name, guests[i].Reason))
+		}
+	}
+	if len(failed) > 0 {
+		self.TaskFailed(ctx, host, jsonutils.NewString(fmt.Sprintf("drain guests failed: %s", strings.Join(failed, "; "))))
+		return
+	}
 	host.PerformDisable(ctx, self.UserCred, nil, apis.PerformDisableInput{})
 	host.SetStatus(self.UserCred, api.BAREMETAL_MAINTAINING, "On host maintain task complete")
 	logclient.AddSimpleActionLog(host, logclient.ACT_HOST_MAINTAINING, "host maintain", self.UserCred, true)
 	self.SetStageComplete(ctx, nil)
 }
-
-func (self *HostMaintainTask) OnGuestsMigrateFailed(ctx context.Context, host *models.SHost, data jsonutils.JSONObject) {
-	self.TaskFailed(ctx, host, data)
-}
-
 func (self *HostMaintainTask) TaskFailed(ctx context.Context, host *models.SHost, reason jsonutils.JSONObject) {
 	host.PerformDisable(ctx, self.UserCred, nil, apis.PerformDisableInput{})
 	host.SetStatus(self.UserCred, api.BAREMETAL_MAINTAIN_FAIL, "On host maintain task complete failed")
@@ -0,0 +1,85 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+)
+
+type HostRebalanceTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(HostRebalanceTask{})
+}
+
+func (self *HostRebalanceTask) OnInit(ctx context.Context, objs []db.IStandaloneModel, data jsonutils.JSONObject) {
+	self.SetStage("OnMigrateGuests", nil)
+	self.migrateGuests(ctx)
+}
+
+// migrateGuests starts the next batch of planned moves, the live migrations
+// are subtasks of stage OnMigrateGuests, so the next batch starts only after
+// every migration of the current batch finished
+func (self *HostRebalanceTask) migrateGuests(ctx context.Context) {
+	moves := make([]api.HostRebalanceMove, 0)
+	self.Params.Unmarshal(&moves, "moves")
+	next, _ := self.Params.Int("next")
+	concurrency, _ := self.Params.Int("concurrency")
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	var started int64
+	for ; next < int64(len(moves)) && started < concurrency; next++ {
+		err := models.HostManager.StartRebalanceMove(ctx, self.UserCred, moves[next], self.Id)
+		if err != nil {
+			log.Warningf("skip rebalance guest %s to %s: %v", moves[next].Guest, moves[next].TargetHost, err)
+			continue
+		}
+		started += 1
+	}
+	params := jsonutils.NewDict()
+	params.Set("next", jsonutils.NewInt(next))
+	self.SaveParams(params)
+
+	if started > 0 {
+		return
+	}
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *HostRebalanceTask) OnMigrateGuests(ctx context.Context, objs []db.IStandaloneModel, data jsonutils.JSONObject) {
+	// a migration may finish before its siblings are started
+	if !self.IsCurrentStageComplete() {
+		return
+	}
+	self.migrateGuests(ctx)
+}
+
+func (self *HostRebalanceTask) OnMigrateGuestsFailed(ctx context.Context, objs []db.IStandaloneModel, data jsonutils.JSONObject) {
+	// a failed migration leaves its guest on the source host, go on with the rest
+	log.Errorf("rebalance migration failed: %s", data)
+	self.OnMigrateGuests(ctx, objs, data)
+}
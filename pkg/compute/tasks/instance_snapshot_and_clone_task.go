@@ -83,7 +83,7 @@ func (self *InstanceSnapshotAndCloneTask) OnInit(
 
 	isp := obj.(*models.SInstanceSnapshot)
 	self.SetStage("OnCreateInstanceSnapshot", nil)
-	err := isp.StartCreateInstanceSnapshotTask(ctx, self.UserCred, nil, self.Id)
+	err := isp.StartCreateInstanceSnapshotTask(ctx, self.UserCred, nil, nil, self.Id)
 	if err != nil {
 		self.taskFailed(ctx, isp, jsonutils.NewString(err.Error()))
 		return
@@ -18,6 +18,7 @@ import (
 	"context"
 
 	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
 
 	"yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
@@ -55,6 +56,7 @@ func (self *InstanceSnapshotCreateTask) taskFail(
 	if guest == nil {
 		guest = models.GuestManager.FetchGuestById(isp.GuestId)
 	}
+	snapshotThawGuest(ctx, self, guest, isp)
 	isp.SetStatus(self.UserCred, compute.INSTANCE_SNAPSHOT_FAILED, reason.String())
 	guest.SetStatus(self.UserCred, compute.VM_INSTANCE_SNAPSHOT_FAILED, reason.String())
 
@@ -71,6 +73,9 @@ func (self *InstanceSnapshotCreateTask) taskComplete(
 	if guest == nil {
 		guest = models.GuestManager.FetchGuestById(isp.GuestId)
 	}
+	if snapshotThawGuest(ctx, self, guest, isp) {
+		self.markQuiesced(isp)
+	}
 	isp.SetStatus(self.UserCred, compute.INSTANCE_SNAPSHOT_READY, "")
 	guest.StartSyncstatus(ctx, self.UserCred, "")
 
@@ -88,6 +93,11 @@ func (self *InstanceSnapshotCreateTask) OnInit(
 
 	isp := obj.(*models.SInstanceSnapshot)
 	guest := models.GuestManager.FetchGuestById(isp.GuestId)
+	// freeze once for all disks, so that snapshots of disks are consistent with each other,
+	// filesystems of a stopped guest are consistent already
+	if guestStatus, _ := self.Params.GetString("guest_status"); guestStatus == compute.VM_RUNNING {
+		snapshotQuiesceGuest(ctx, self, guest, isp)
+	}
 	self.SetStage("OnInstanceSnapshot", nil)
 	params := jsonutils.NewDict()
 	params.Set("disk_index", jsonutils.NewInt(0))
@@ -129,3 +139,21 @@ func (self *InstanceSnapshotCreateTask) OnInstanceSnapshot(ctx context.Context,
 func (self *InstanceSnapshotCreateTask) OnInstanceSnapshotFailed(ctx context.Context, isp *models.SInstanceSnapshot, data jsonutils.JSONObject) {
 	self.taskFail(ctx, isp, nil, data)
 }
+
+func (self *InstanceSnapshotCreateTask) markQuiesced(isp *models.SInstanceSnapshot) {
+	db.Update(isp, func() error {
+		isp.Quiesced = true
+		return nil
+	})
+	snapshots, err := isp.GetSnapshots()
+	if err != nil {
+		log.Errorf("instance snapshot %s get snapshots: %s", isp.Name, err)
+		return
+	}
+	for i := range snapshots {
+		db.Update(&snapshots[i], func() error {
+			snapshots[i].Quiesced = true
+			return nil
+		})
+	}
+}
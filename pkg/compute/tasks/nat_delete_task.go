@@ -16,7 +16,6 @@ package tasks
 
 import (
 	"context"
-	"time"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/pkg/errors"
@@ -24,7 +23,6 @@ import (
 	api "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
-	"yunion.io/x/onecloud/pkg/cloudprovider"
 	"yunion.io/x/onecloud/pkg/compute/models"
 )
 
@@ -66,55 +64,22 @@ func (self *NatGatewayDeleteTask) OnEipDissociateCompleteFailed(ctx context.Cont
 }
 
 func (self *NatGatewayDeleteTask) doDeleteNatGateway(ctx context.Context, nat *models.SNatGateway) {
-	iNat, err := nat.GetINatGateway()
+	self.SetStage("OnDeleteNatGatewayComplete", nil)
+	err := nat.GetRegion().GetDriver().RequestDeleteNatGateway(ctx, self.GetUserCred(), nat, self)
 	if err != nil {
-		if errors.Cause(err) == cloudprovider.ErrNotFound {
-			self.taskComplete(ctx, nat)
-			return
-		}
-		self.taskFailed(ctx, nat, errors.Wrapf(err, "nat.GetINatGateway"))
-		return
-	}
-
-	dnat, err := iNat.GetINatDTable()
-	if err != nil {
-		self.taskFailed(ctx, nat, errors.Wrapf(err, "iNat.GetINatDTable"))
-		return
-	}
-	for i := range dnat {
-		err = dnat[i].Delete()
-		if err != nil {
-			self.taskFailed(ctx, nat, errors.Wrapf(err, "delete d entry %v", dnat[i]))
-			return
-		}
-	}
-	snat, err := iNat.GetINatSTable()
-	if err != nil {
-		self.taskFailed(ctx, nat, errors.Wrapf(err, "GetINatSTable"))
-		return
-	}
-	for i := range snat {
-		err = snat[i].Delete()
-		if err != nil {
-			self.taskFailed(ctx, nat, errors.Wrapf(err, "delete s entry %v", snat[i]))
-		}
-	}
-
-	err = iNat.Delete()
-	if err != nil {
-		self.taskFailed(ctx, nat, errors.Wrapf(err, "iNat.Delete"))
-		return
-	}
-
-	err = cloudprovider.WaitDeleted(iNat, time.Second*5, time.Minute*3)
-	if err != nil {
-		self.taskFailed(ctx, nat, errors.Wrapf(err, "cloudprovider.WaitDeleted"))
+		self.taskFailed(ctx, nat, errors.Wrapf(err, "RequestDeleteNatGateway"))
 		return
 	}
+}
 
+func (self *NatGatewayDeleteTask) OnDeleteNatGatewayComplete(ctx context.Context, nat *models.SNatGateway, data jsonutils.JSONObject) {
 	self.taskComplete(ctx, nat)
 }
 
+func (self *NatGatewayDeleteTask) OnDeleteNatGatewayCompleteFailed(ctx context.Context, nat *models.SNatGateway, data jsonutils.JSONObject) {
+	self.taskFailed(ctx, nat, errors.Error(data.String()))
+}
+
 func (self *NatGatewayDeleteTask) taskComplete(ctx context.Context, nat *models.SNatGateway) {
 	nat.RealDelete(ctx, self.GetUserCred())
 	self.SetStageComplete(ctx, nil)
@@ -17,7 +17,6 @@ package tasks
 import (
 	"context"
 	"fmt"
-	"time"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
@@ -25,7 +24,6 @@ import (
 	api "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
-	"yunion.io/x/onecloud/pkg/cloudprovider"
 	"yunion.io/x/onecloud/pkg/compute/models"
 	"yunion.io/x/onecloud/pkg/util/logclient"
 )
@@ -64,49 +62,34 @@ func (self *SNatSEntryCreateTask) OnBindIPCompleteFailed(ctx context.Context, sn
 func (self *SNatSEntryCreateTask) OnBindIPComplete(ctx context.Context, snatEntry *models.SNatSEntry,
 	body jsonutils.JSONObject) {
 
-	cloudNatGateway, err := snatEntry.GetINatGateway()
-	if err != nil {
-		self.TaskFailed(ctx, snatEntry, jsonutils.NewString(fmt.Sprintf("Get NatGateway failed: %s", err)))
-		return
-	}
-
-	externalIPID, err := self.Params.GetString("eip_external_id")
-	// construct a DNat RUle
-	snatRule := cloudprovider.SNatSRule{
-		ExternalIP:   snatEntry.IP,
-		ExternalIPID: externalIPID,
-	}
-	if self.Params.Contains("network_ext_id") {
-		extID, _ := self.Params.GetString("network_ext_id")
-		snatRule.NetworkID = extID
-	} else {
-		snatRule.SourceCIDR = snatEntry.SourceCIDR
-	}
-	extSnat, err := cloudNatGateway.CreateINatSEntry(snatRule)
+	natgateway, err := snatEntry.GetNatgateway()
 	if err != nil {
-		// create nat failed in cloud
-		if self.Params.Contains("need_bind") {
-			err1 := CreateINatFailedRollback(ctx, self, snatEntry)
-			if err1 != nil {
-				eip_id, _ := self.Params.GetString("eip_id")
-				log.Errorf("roll back after failing to create snat in cloud so that eip %s need to sync with cloud", eip_id)
-			}
-		}
-		self.TaskFailed(ctx, snatEntry, jsonutils.NewString(fmt.Sprintf("Create SNat Entry '%s' failed: %s", snatEntry.ExternalId, err)))
+		self.TaskFailed(ctx, snatEntry, jsonutils.NewString(fmt.Sprintf("fetch natgateway failed: %s", err)))
 		return
 	}
-
-	err = cloudprovider.WaitStatus(extSnat, api.NAT_STAUTS_AVAILABLE, 10*time.Second, 300*time.Second)
+	self.SetStage("OnCreateNatSEntryComplete", nil)
+	err = natgateway.GetRegion().GetDriver().RequestCreateNatSEntry(ctx, self.UserCred, snatEntry, self)
 	if err != nil {
 		self.TaskFailed(ctx, snatEntry, jsonutils.NewString(err.Error()))
 		return
 	}
+}
 
-	err = db.SetExternalId(snatEntry, self.UserCred, extSnat.GetGlobalId())
-	if err != nil {
-		self.TaskFailed(ctx, snatEntry, jsonutils.NewString(fmt.Sprintf("set external id failed: %s", err)))
-		return
+func (self *SNatSEntryCreateTask) OnCreateNatSEntryCompleteFailed(ctx context.Context, snatEntry *models.SNatSEntry,
+	reason jsonutils.JSONObject) {
+
+	if self.Params.Contains("need_bind") {
+		err := CreateINatFailedRollback(ctx, self, snatEntry)
+		if err != nil {
+			eipId, _ := self.Params.GetString("eip_id")
+			log.Errorf("roll back after failing to create snat so that eip %s need to sync with cloud", eipId)
+		}
 	}
+	self.TaskFailed(ctx, snatEntry, reason)
+}
+
+func (self *SNatSEntryCreateTask) OnCreateNatSEntryComplete(ctx context.Context, snatEntry *models.SNatSEntry,
+	body jsonutils.JSONObject) {
 
 	snatEntry.SetStatus(self.UserCred, api.NAT_STAUTS_AVAILABLE, "")
 	db.OpsLog.LogEvent(snatEntry, db.ACT_ALLOCATE, snatEntry.GetShortDesc(ctx), self.UserCred)
@@ -16,17 +16,13 @@ package tasks
 
 import (
 	"context"
-	"fmt"
-	"time"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
-	"yunion.io/x/pkg/errors"
 
 	api "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
-	"yunion.io/x/onecloud/pkg/cloudprovider"
 	"yunion.io/x/onecloud/pkg/compute/models"
 	"yunion.io/x/onecloud/pkg/util/logclient"
 )
@@ -59,29 +55,23 @@ func (self *SNatSEntryDeleteTask) OnInit(ctx context.Context, obj db.IStandalone
 		self.TaskFailed(ctx, snatEntry, jsonutils.NewString(err.Error()))
 		return
 	}
-	cloudNatGateway, err := snatEntry.GetINatGateway()
+	self.SetStage("OnDeleteNatSEntryComplete", nil)
+	err = natgateway.GetRegion().GetDriver().RequestDeleteNatSEntry(ctx, self.UserCred, snatEntry, self)
 	if err != nil {
-		self.TaskFailed(ctx, snatEntry, jsonutils.NewString(fmt.Sprintf("Get NatGateway failed: %s", err)))
+		self.TaskFailed(ctx, snatEntry, jsonutils.NewString(err.Error()))
 		return
 	}
-	cloudNatSEntry, err := cloudNatGateway.GetINatSEntryByID(snatEntry.ExternalId)
-	if errors.Cause(err) == cloudprovider.ErrNotFound {
-		//already delete
-	} else if err != nil {
-		self.TaskFailed(ctx, snatEntry, jsonutils.NewString(fmt.Sprintf("Get SNat Entry by ID '%s' failed: %s", snatEntry.ExternalId, err)))
-		return
-	} else if cloudNatSEntry != nil {
-		err = cloudNatSEntry.Delete()
-		if err != nil {
-			self.TaskFailed(ctx, snatEntry, jsonutils.NewString(fmt.Sprintf("Delete SNat Entry '%s' failed: %s", snatEntry.ExternalId, err)))
-			return
-		}
+}
+
+func (self *SNatSEntryDeleteTask) OnDeleteNatSEntryCompleteFailed(ctx context.Context, snatEntry *models.SNatSEntry, reason jsonutils.JSONObject) {
+	self.TaskFailed(ctx, snatEntry, reason)
+}
 
-		err = cloudprovider.WaitDeleted(cloudNatSEntry, 10*time.Second, 300*time.Second)
-		if err != nil {
-			self.TaskFailed(ctx, snatEntry, jsonutils.NewString(err.Error()))
-			return
-		}
+func (self *SNatSEntryDeleteTask) OnDeleteNatSEntryComplete(ctx context.Context, snatEntry *models.SNatSEntry, body jsonutils.JSONObject) {
+	natgateway, err := snatEntry.GetNatgateway()
+	if err != nil {
+		self.TaskFailed(ctx, snatEntry, jsonutils.NewString(err.Error()))
+		return
 	}
 
 	err = snatEntry.Purge(ctx, self.UserCred)
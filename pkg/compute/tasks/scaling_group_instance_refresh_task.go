@@ -0,0 +1,256 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+// ScalingGroupInstanceRefreshTask removes the instances of scaling group in batches, the autoscaling controller
+// creates new instances from the current guest template to keep the desire instance number. The next batch
+// starts only after the new instances become healthy, otherwise the refresh is paused and rolled back.
+type ScalingGroupInstanceRefreshTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(ScalingGroupInstanceRefreshTask{})
+}
+
+const scalingGroupInstanceRefreshInterval = 10 * time.Second
+
+func (self *ScalingGroupInstanceRefreshTask) getProgress() *api.ScalingGroupInstanceRefreshProgress {
+	progress := &api.ScalingGroupInstanceRefreshProgress{}
+	self.Params.Unmarshal(progress, "progress")
+	return progress
+}
+
+func (self *ScalingGroupInstanceRefreshTask) saveProgress(ctx context.Context, sg *models.SScalingGroup, progress *api.ScalingGroupInstanceRefreshProgress) {
+	params := jsonutils.NewDict()
+	params.Set("progress", jsonutils.Marshal(progress))
+	self.SaveParams(params)
+	err := sg.SetInstanceRefreshProgress(ctx, self.UserCred, progress)
+	if err != nil {
+		log.Errorf("save instance refresh progress of scaling group %s: %v", sg.Id, err)
+	}
+}
+
+func (self *ScalingGroupInstanceRefreshTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	sg := obj.(*models.SScalingGroup)
+	input := api.ScalingGroupInstanceRefreshInput{}
+	self.Params.Unmarshal(&input)
+	origin := make([]string, 0)
+	self.Params.Unmarshal(&origin, "origin_guests")
+
+	progress := &api.ScalingGroupInstanceRefreshProgress{
+		Status:             api.SG_INSTANCE_REFRESH_REFRESHING,
+		OldGuestTemplateId: sg.GuestTemplateId,
+		GuestTemplateId:    input.GuestTemplateId,
+		Pending:            origin,
+		StartAt:            time.Now(),
+	}
+	err := sg.SetGuestTemplateId(input.GuestTemplateId)
+	if err != nil {
+		self.taskFailed(ctx, sg, progress, fmt.Sprintf("set guest template: %v", err))
+		return
+	}
+	self.startBatch(ctx, sg, progress)
+}
+
+func (self *ScalingGroupInstanceRefreshTask) startBatch(ctx context.Context, sg *models.SScalingGroup, progress *api.ScalingGroupInstanceRefreshProgress) {
+	if len(progress.Pending) == 0 {
+		self.onRefreshComplete(ctx, sg, progress)
+		return
+	}
+	batchSize, _ := self.Params.Int("batch_size")
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+	if int64(len(progress.Pending)) < batchSize {
+		batchSize = int64(len(progress.Pending))
+	}
+	progress.Batch = progress.Pending[:batchSize]
+	progress.Pending = progress.Pending[batchSize:]
+	self.saveProgress(ctx, sg, progress)
+
+	drainTime, _ := self.Params.Int("drain_time")
+	self.SetStage("OnBatchDrained", nil)
+	if drainTime > 0 {
+		draining := false
+		for _, guestId := range progress.Batch {
+			started, err := sg.StartDrainInstance(ctx, self.UserCred, guestId, self.Id)
+			if err != nil {
+				log.Errorf("drain instance %s of scaling group %s: %v", guestId, sg.Id, err)
+				continue
+			}
+			draining = draining || started
+		}
+		if draining {
+			return
+		}
+	}
+	self.waitDrain(ctx, sg)
+}
+
+func (self *ScalingGroupInstanceRefreshTask) OnBatchDrained(ctx context.Context, sg *models.SScalingGroup, data jsonutils.JSONObject) {
+	if !self.IsCurrentStageComplete() {
+		return
+	}
+	self.waitDrain(ctx, sg)
+}
+
+func (self *ScalingGroupInstanceRefreshTask) waitDrain(ctx context.Context, sg *models.SScalingGroup) {
+	drainTime, _ := self.Params.Int("drain_time")
+	self.SetStage("OnDrainTimeout", nil)
+	if drainTime <= 0 {
+		self.OnDrainTimeout(ctx, sg, nil)
+		return
+	}
+	// wait for the established connections to finish
+	self.ScheduleRunAfter(time.Duration(drainTime)*time.Second, nil)
+}
+
+func (self *ScalingGroupInstanceRefreshTask) OnBatchDrainedFailed(ctx context.Context, sg *models.SScalingGroup, data jsonutils.JSONObject) {
+	// the instances will be removed from loadbalancer when they are detached anyway
+	log.Errorf("drain instances of scaling group %s failed: %s", sg.Id, data)
+	self.OnBatchDrained(ctx, sg, data)
+}
+
+func (self *ScalingGroupInstanceRefreshTask) OnDrainTimeout(ctx context.Context, sg *models.SScalingGroup, data jsonutils.JSONObject) {
+	progress := self.getProgress()
+	input := api.SGPerformDetachScalingGroupInput{
+		ScalingGroup: sg.Id,
+		DeleteServer: true,
+		// keep the desire instance number, so that the instances are replaced
+		Auto: true,
+	}
+	for _, guestId := range progress.Batch {
+		sggs, err := models.ScalingGroupGuestManager.Fetch(sg.Id, guestId)
+		if err != nil || len(sggs) == 0 {
+			// removed already
+			continue
+		}
+		err = sg.StartDetachGuestTask(ctx, self.UserCred, &sggs[0], input)
+		if err != nil {
+			self.onBatchFailed(ctx, sg, progress, fmt.Sprintf("remove instance %s: %v", guestId, err))
+			return
+		}
+	}
+	params := jsonutils.NewDict()
+	params.Set("batch_removed_at", jsonutils.NewTimeString(time.Now()))
+	self.SaveParams(params)
+	self.SetStage("OnWaitBatchReplaced", nil)
+	self.OnWaitBatchReplaced(ctx, sg, nil)
+}
+
+func (self *ScalingGroupInstanceRefreshTask) OnDrainTimeoutFailed(ctx context.Context, sg *models.SScalingGroup, data jsonutils.JSONObject) {
+	self.OnDrainTimeout(ctx, sg, data)
+}
+
+func (self *ScalingGroupInstanceRefreshTask) OnWaitBatchReplaced(ctx context.Context, sg *models.SScalingGroup, data jsonutils.JSONObject) {
+	progress := self.getProgress()
+	replaced, reason := sg.CheckInstanceRefreshBatch(progress.Batch)
+	if len(reason) > 0 {
+		self.onBatchFailed(ctx, sg, progress, reason)
+		return
+	}
+	if replaced {
+		progress.Replaced += len(progress.Batch)
+		progress.Batch = nil
+		self.startBatch(ctx, sg, progress)
+		return
+	}
+	timeout, _ := self.Params.Int("health_check_timeout")
+	removedAt, _ := self.Params.GetTime("batch_removed_at")
+	if time.Since(removedAt) > time.Duration(timeout)*time.Second {
+		self.onBatchFailed(ctx, sg, progress, fmt.Sprintf("new instances are not healthy in %d seconds", timeout))
+		return
+	}
+	self.ScheduleRunAfter(scalingGroupInstanceRefreshInterval, nil)
+}
+
+func (self *ScalingGroupInstanceRefreshTask) OnWaitBatchReplacedFailed(ctx context.Context, sg *models.SScalingGroup, data jsonutils.JSONObject) {
+	self.OnWaitBatchReplaced(ctx, sg, data)
+}
+
+func (self *ScalingGroupInstanceRefreshTask) onBatchFailed(ctx context.Context, sg *models.SScalingGroup, progress *api.ScalingGroupInstanceRefreshProgress, reason string) {
+	disableRollback := jsonutils.QueryBoolean(self.Params, "disable_rollback", false)
+	if progress.Status != api.SG_INSTANCE_REFRESH_REFRESHING || disableRollback {
+		self.taskFailed(ctx, sg, progress, reason)
+		return
+	}
+	logclient.AddActionLogWithStartable(self, sg, logclient.ACT_INSTANCE_REFRESH,
+		fmt.Sprintf("refresh paused and rolling back: %s", reason), self.UserCred, false)
+
+	// replace the new instances with the old guest template
+	origin := make([]string, 0)
+	self.Params.Unmarshal(&origin, "origin_guests")
+	guests, err := sg.Guests()
+	if err != nil {
+		self.taskFailed(ctx, sg, progress, fmt.Sprintf("%s, fetch guests for rollback: %v", reason, err))
+		return
+	}
+	pending := make([]string, 0)
+	for i := range guests {
+		if !utils.IsInStringArray(guests[i].Id, origin) {
+			pending = append(pending, guests[i].Id)
+		}
+	}
+	err = sg.SetGuestTemplateId(progress.OldGuestTemplateId)
+	if err != nil {
+		self.taskFailed(ctx, sg, progress, fmt.Sprintf("%s, restore guest template: %v", reason, err))
+		return
+	}
+	progress.Status = api.SG_INSTANCE_REFRESH_ROLLING_BACK
+	progress.Reason = reason
+	progress.Pending = pending
+	progress.Batch = nil
+	progress.Replaced = 0
+	self.startBatch(ctx, sg, progress)
+}
+
+func (self *ScalingGroupInstanceRefreshTask) onRefreshComplete(ctx context.Context, sg *models.SScalingGroup, progress *api.ScalingGroupInstanceRefreshProgress) {
+	if progress.Status == api.SG_INSTANCE_REFRESH_ROLLING_BACK {
+		self.taskFailed(ctx, sg, progress, fmt.Sprintf("rolled back: %s", progress.Reason))
+		return
+	}
+	progress.Status = api.SG_INSTANCE_REFRESH_SUCCEED
+	self.saveProgress(ctx, sg, progress)
+	sg.SetStatus(self.UserCred, api.SG_STATUS_READY, "instance refresh complete")
+	logclient.AddActionLogWithStartable(self, sg, logclient.ACT_INSTANCE_REFRESH,
+		fmt.Sprintf("%d instances are refreshed", progress.Replaced), self.UserCred, true)
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *ScalingGroupInstanceRefreshTask) taskFailed(ctx context.Context, sg *models.SScalingGroup, progress *api.ScalingGroupInstanceRefreshProgress, reason string) {
+	progress.Status = api.SG_INSTANCE_REFRESH_FAILED
+	progress.Reason = reason
+	self.saveProgress(ctx, sg, progress)
+	sg.SetStatus(self.UserCred, api.SG_STATUS_INSTANCE_REFRESH_FAILED, reason)
+	logclient.AddActionLogWithStartable(self, sg, logclient.ACT_INSTANCE_REFRESH, reason, self.UserCred, false)
+	self.SetStageFailed(ctx, jsonutils.NewString(reason))
+}
@@ -0,0 +1,152 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+// ScalingLifecycleHookTask runs the lifecycle hooks of an instance of scaling group one by one. Each hook is
+// notified and then checked periodically by a timer-resumed stage until it is completed by the callback, the
+// ansible playbook or the timeout. The final result is returned to the parent task.
+type ScalingLifecycleHookTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(ScalingLifecycleHookTask{})
+}
+
+const scalingLifecycleHookInterval = 5 * time.Second
+
+func (self *ScalingLifecycleHookTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	sg := obj.(*models.SScalingGroup)
+	self.runHook(ctx, sg, 0)
+}
+
+func (self *ScalingLifecycleHookTask) fetchGuest(sg *models.SScalingGroup) (*models.SGuest, *models.SScalingGroupGuest) {
+	guestId, _ := self.Params.GetString("guest_id")
+	guest := models.GuestManager.FetchGuestById(guestId)
+	sggs, err := models.ScalingGroupGuestManager.Fetch(sg.Id, guestId)
+	if err != nil || len(sggs) == 0 {
+		return guest, nil
+	}
+	return guest, &sggs[0]
+}
+
+func (self *ScalingLifecycleHookTask) fetchHook(index int) *models.SScalingLifecycleHook {
+	hookIds := make([]string, 0)
+	self.Params.Unmarshal(&hookIds, "hook_ids")
+	if index >= len(hookIds) {
+		return nil
+	}
+	model, err := models.ScalingLifecycleHookManager.FetchById(hookIds[index])
+	if err != nil {
+		return nil
+	}
+	return model.(*models.SScalingLifecycleHook)
+}
+
+func (self *ScalingLifecycleHookTask) runHook(ctx context.Context, sg *models.SScalingGroup, index int) {
+	hookIds := make([]string, 0)
+	self.Params.Unmarshal(&hookIds, "hook_ids")
+	if index >= len(hookIds) {
+		self.taskComplete(ctx, sg, api.LIFECYCLE_RESULT_CONTINUE)
+		return
+	}
+	guest, sgg := self.fetchGuest(sg)
+	if guest == nil || sgg == nil {
+		// the instance is gone, nothing to wait for
+		self.taskComplete(ctx, sg, api.LIFECYCLE_RESULT_CONTINUE)
+		return
+	}
+	hook := self.fetchHook(index)
+	if hook == nil {
+		// the hook is deleted
+		self.runHook(ctx, sg, index+1)
+		return
+	}
+	token, pbId, err := hook.NotifyLifecycleAction(ctx, sg, guest, sgg)
+	if err != nil {
+		self.onHookResult(ctx, sg, hook, guest, index, hook.DefaultResult, fmt.Sprintf("notify failed: %v", err))
+		return
+	}
+	params := jsonutils.NewDict()
+	params.Set("hook_index", jsonutils.NewInt(int64(index)))
+	params.Set("token", jsonutils.NewString(token))
+	params.Set("playbook_id", jsonutils.NewString(pbId))
+	self.SaveParams(params)
+	self.SetStage("OnWaitLifecycleAction", nil)
+	self.ScheduleRunAfter(scalingLifecycleHookInterval, nil)
+}
+
+func (self *ScalingLifecycleHookTask) OnWaitLifecycleAction(ctx context.Context, sg *models.SScalingGroup, data jsonutils.JSONObject) {
+	index, _ := self.Params.Int("hook_index")
+	guest, sgg := self.fetchGuest(sg)
+	if guest == nil || sgg == nil {
+		self.taskComplete(ctx, sg, api.LIFECYCLE_RESULT_CONTINUE)
+		return
+	}
+	hook := self.fetchHook(int(index))
+	if hook == nil {
+		self.runHook(ctx, sg, int(index)+1)
+		return
+	}
+	token, _ := self.Params.GetString("token")
+	pbId, _ := self.Params.GetString("playbook_id")
+	done, result, reason := hook.CheckLifecycleAction(ctx, sgg, token, pbId)
+	if !done {
+		self.ScheduleRunAfter(scalingLifecycleHookInterval, nil)
+		return
+	}
+	self.onHookResult(ctx, sg, hook, guest, int(index), result, reason)
+}
+
+func (self *ScalingLifecycleHookTask) onHookResult(ctx context.Context, sg *models.SScalingGroup,
+	hook *models.SScalingLifecycleHook, guest *models.SGuest, index int, result, reason string) {
+	logclient.AddActionLogWithStartable(self, sg, logclient.ACT_LIFECYCLE_HOOK,
+		fmt.Sprintf("lifecycle hook %s of instance %s: %s, %s", hook.Name, guest.Name, result, reason), self.UserCred,
+		result == api.LIFECYCLE_RESULT_CONTINUE)
+	if result == api.LIFECYCLE_RESULT_ABANDON {
+		self.taskComplete(ctx, sg, result)
+		return
+	}
+	self.runHook(ctx, sg, index+1)
+}
+
+func (self *ScalingLifecycleHookTask) taskComplete(ctx context.Context, sg *models.SScalingGroup, result string) {
+	_, sgg := self.fetchGuest(sg)
+	if sgg != nil {
+		originStatus, _ := self.Params.GetString("origin_status")
+		err := sgg.FinishLifecycleHooks(originStatus, result)
+		if err != nil {
+			log.Errorf("finish lifecycle hooks of scaling group %s and guest %s: %v", sg.Id, sgg.GuestId, err)
+		}
+	}
+	ret := jsonutils.NewDict()
+	ret.Set("result", jsonutils.NewString(result))
+	self.SetStageComplete(ctx, ret)
+}
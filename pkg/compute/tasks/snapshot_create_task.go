@@ -41,6 +41,7 @@ func (self *SnapshotCreateTask) OnInit(ctx context.Context, obj db.IStandaloneMo
 }
 
 func (self *SnapshotCreateTask) TaskFailed(ctx context.Context, snapshot *models.SSnapshot, reason jsonutils.JSONObject) {
+	self.thawGuest(ctx, snapshot)
 	snapshot.SetStatus(self.UserCred, api.SNAPSHOT_FAILED, reason.String())
 	db.OpsLog.LogEvent(snapshot, db.ACT_SNAPSHOT_FAIL, reason, self.UserCred)
 	logclient.AddActionLogWithStartable(self, snapshot, logclient.ACT_CREATE, reason, self.UserCred, false)
@@ -48,6 +49,12 @@ func (self *SnapshotCreateTask) TaskFailed(ctx context.Context, snapshot *models
 }
 
 func (self *SnapshotCreateTask) TaskComplete(ctx context.Context, snapshot *models.SSnapshot, data jsonutils.JSONObject) {
+	if self.thawGuest(ctx, snapshot) {
+		db.Update(snapshot, func() error {
+			snapshot.Quiesced = true
+			return nil
+		})
+	}
 	snapshot.SetStatus(self.UserCred, api.SNAPSHOT_READY, "")
 	db.OpsLog.LogEvent(snapshot, db.ACT_SNAPSHOT_DONE, snapshot.GetShortDesc(ctx), self.UserCred)
 	logclient.AddActionLogWithStartable(self, snapshot, logclient.ACT_CREATE, snapshot.GetShortDesc(ctx), self.UserCred, true)
@@ -58,7 +65,25 @@ func (self *SnapshotCreateTask) TaskComplete(ctx context.Context, snapshot *mode
 	self.SetStageComplete(ctx, nil)
 }
 
+func (self *SnapshotCreateTask) quiesceGuest(ctx context.Context, snapshot *models.SSnapshot) {
+	if !jsonutils.QueryBoolean(self.Params, "quiesce", false) {
+		return
+	}
+	guest, _ := snapshot.GetGuest()
+	if guest != nil && guest.Status != api.VM_RUNNING {
+		// filesystems of a stopped guest are consistent already
+		return
+	}
+	snapshotQuiesceGuest(ctx, self, guest, snapshot)
+}
+
+func (self *SnapshotCreateTask) thawGuest(ctx context.Context, snapshot *models.SSnapshot) bool {
+	guest, _ := snapshot.GetGuest()
+	return snapshotThawGuest(ctx, self, guest, snapshot)
+}
+
 func (self *SnapshotCreateTask) DoDiskSnapshot(ctx context.Context, snapshot *models.SSnapshot) {
+	self.quiesceGuest(ctx, snapshot)
 	self.SetStage("OnCreateSnapshot", nil)
 	if err := snapshot.GetRegionDriver().RequestCreateSnapshot(ctx, snapshot, self); err != nil {
 		self.TaskFailed(ctx, snapshot, jsonutils.NewString(err.Error()))
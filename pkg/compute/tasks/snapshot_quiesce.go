@@ -0,0 +1,82 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+// snapshotQuiesceGuest freeze guest filesystems through guest agent if task params ask for quiesce,
+// it never fails the task, the snapshot falls back to crash consistent with a warning recorded on obj
+func snapshotQuiesceGuest(ctx context.Context, task taskman.ITask, guest *models.SGuest, obj db.IModel) bool {
+	if !jsonutils.QueryBoolean(task.GetParams(), "quiesce", false) {
+		return false
+	}
+	var err error
+	if guest == nil {
+		err = fmt.Errorf("guest not found")
+	} else {
+		var count int
+		count, err = guest.GuestAgentFsFreeze(ctx, task.GetUserCred())
+		if err == nil {
+			log.Infof("guest %s froze %d filesystems for snapshot %s", guest.Name, count, obj.GetName())
+		}
+	}
+	if err != nil {
+		reason := fmt.Sprintf("guest agent fsfreeze failed, fallback to crash consistent snapshot: %s", err)
+		db.OpsLog.LogEvent(obj, db.ACT_SNAPSHOT_QUIESCE_FAIL, reason, task.GetUserCred())
+		logclient.AddActionLogWithStartable(task, obj, logclient.ACT_VM_SNAPSHOT_QUIESCE, reason, task.GetUserCred(), false)
+		return false
+	}
+	params := jsonutils.NewDict()
+	params.Set("frozen", jsonutils.JSONTrue)
+	task.SetStage("", params)
+	return true
+}
+
+// snapshotThawGuest thaw guest filesystems frozen by snapshotQuiesceGuest, returns whether the guest
+// stayed frozen until now.  Host thaws the guest by itself if it is frozen longer than
+// GuestFsFreezeMaxSeconds, snapshots taken after that are not quiesced
+func snapshotThawGuest(ctx context.Context, task taskman.ITask, guest *models.SGuest, obj db.IModel) bool {
+	if !jsonutils.QueryBoolean(task.GetParams(), "frozen", false) {
+		return false
+	}
+	params := jsonutils.NewDict()
+	params.Set("frozen", jsonutils.JSONFalse)
+	task.SetStage("", params)
+	if guest == nil {
+		return false
+	}
+	count, err := guest.GuestAgentFsThaw(ctx, task.GetUserCred())
+	if err == nil && count == 0 {
+		err = fmt.Errorf("filesystems were thawed by host before snapshot finished")
+	}
+	if err != nil {
+		reason := fmt.Sprintf("guest agent fsthaw failed: %s", err)
+		db.OpsLog.LogEvent(obj, db.ACT_SNAPSHOT_QUIESCE_FAIL, reason, task.GetUserCred())
+		logclient.AddActionLogWithStartable(task, obj, logclient.ACT_VM_SNAPSHOT_QUIESCE, reason, task.GetUserCred(), false)
+		return false
+	}
+	return true
+}
@@ -51,6 +51,16 @@ func (self *VpcPeeringConnectionSyncstatusTask) OnInit(ctx context.Context, obj
 		return
 	}
 
+	if svpc.IsOneCloudVpc() {
+		if err := peer.AllocateOvnLinkSlot(ctx); err != nil {
+			self.taskFail(ctx, peer, errors.Wrap(err, "peer.AllocateOvnLinkSlot"))
+			return
+		}
+		peer.SetStatus(self.UserCred, api.VPC_PEERING_CONNECTION_STATUS_ACTIVE, "")
+		self.SetStageComplete(ctx, nil)
+		return
+	}
+
 	extVpc, err := svpc.GetIVpc()
 	if err != nil {
 		self.taskFail(ctx, peer, errors.Wrap(err, "svpc.GetIVpc()"))
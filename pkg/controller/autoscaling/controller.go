@@ -89,6 +89,7 @@ func (asc *SASController) Init(options options.SASControllerOptions, cronm *cron
 	cronm.AddJobAtIntervalsWithStartRun("CheckTimer", time.Duration(options.TimerInterval)*time.Second, asc.Timer, true)
 	cronm.AddJobAtIntervalsWithStartRun("CheckScale", time.Duration(options.CheckScaleInterval)*time.Second, asc.CheckScale, true)
 	cronm.AddJobAtIntervalsWithStartRun("CheckInstanceHealth", time.Duration(options.CheckHealthInterval)*time.Minute, asc.CheckInstanceHealth, true)
+	cronm.AddJobAtIntervalsWithStartRun("CheckMetricPolicy", time.Duration(options.CheckMetricInterval)*time.Second, asc.CheckMetricPolicy, false)
 	asc.timerQueue = make(chan struct{}, 20)
 	asc.scalingQueue = make(chan struct{}, options.ConcurrentUpper)
 	asc.scalingGroupSet = &SLockedSet{set: sets.NewString()}
@@ -357,7 +358,8 @@ Loop:
 				case compute.SG_GUEST_STATUS_REMOVE_FAILED:
 					succeedList.Delete(sggs[i].GetId())
 					failedList = append(failedList, fmt.Sprintf("remove instance '%s' failed", sggs[i].GetId()))
-				case compute.SG_GUEST_STATUS_READY, compute.SG_GUEST_STATUS_REMOVING, compute.SG_GUEST_STATUS_PENDING_REMOVE:
+				case compute.SG_GUEST_STATUS_READY, compute.SG_GUEST_STATUS_REMOVING, compute.SG_GUEST_STATUS_PENDING_REMOVE,
+					compute.SG_GUEST_STATUS_PENDING_TERMINATE:
 					waitList = append(waitList, sggs[i].GetId())
 				default:
 					log.Errorf("unkown guest status for ScalingGroupGuest '%s'", sggs[i].GetId())
@@ -699,6 +701,15 @@ func (asc *SASController) actionAfterCreate(
 		}
 		return
 	}
+	// lifecycle hooks: the instance keeps pending until the hooks are completed
+	guest := models.GuestManager.FetchGuestById(ret.Id)
+	if guest != nil {
+		result := asc.waitLifecycleHooks(ctx, userCred, sg, guest)
+		if result == compute.LIFECYCLE_RESULT_ABANDON {
+			rollback(fmt.Sprintf("the launching of instance '%s' is abandoned by lifecycle hook", ret.Id))
+			return
+		}
+	}
 	// bind lb
 	if len(sg.BackendGroupId) != 0 {
 		params := jsonutils.NewDict()
@@ -724,6 +735,50 @@ func (asc *SASController) actionAfterCreate(
 	return true
 }
 
+// waitLifecycleHooks runs the launching lifecycle hooks of guest and waits for the result
+func (asc *SASController) waitLifecycleHooks(ctx context.Context, userCred mcclient.TokenCredential,
+	sg *models.SScalingGroup, guest *models.SGuest) string {
+	started, err := sg.StartLifecycleHookTask(ctx, userCred, guest, compute.LIFECYCLE_TRANSITION_LAUNCHING, "")
+	if err != nil {
+		log.Errorf("run launching lifecycle hooks of instance '%s': %v", guest.Id, err)
+	}
+	if !started {
+		return compute.LIFECYCLE_RESULT_CONTINUE
+	}
+	hooks, _ := sg.LifecycleHooks(compute.LIFECYCLE_TRANSITION_LAUNCHING)
+	timeout := time.Minute
+	for i := range hooks {
+		timeout += time.Duration(hooks[i].Timeout) * time.Second
+	}
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			sggs, err := models.ScalingGroupGuestManager.Fetch(sg.Id, guest.Id)
+			if err != nil {
+				log.Errorf("ScalingGroupGuestManager.Fetch failed: %s", err.Error())
+				continue
+			}
+			if len(sggs) == 0 {
+				return compute.LIFECYCLE_RESULT_ABANDON
+			}
+			if sggs[0].GuestStatus == compute.SG_GUEST_STATUS_PENDING_LAUNCH {
+				continue
+			}
+			if sggs[0].LifecycleResult == compute.LIFECYCLE_RESULT_ABANDON {
+				return compute.LIFECYCLE_RESULT_ABANDON
+			}
+			return compute.LIFECYCLE_RESULT_CONTINUE
+		case <-timer.C:
+			log.Errorf("wait for launching lifecycle hooks of instance '%s' timeout", guest.Id)
+			return compute.LIFECYCLE_RESULT_CONTINUE
+		}
+	}
+}
+
 func (asc *SASController) randStringRunes(n int) string {
 	var letterRunes = []rune("abcdefghijklmnopqrstuvwxyz1234567890")
 	b := make([]rune, n)
@@ -16,10 +16,16 @@ package autoscaling
 
 import (
 	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
 	"yunion.io/x/pkg/util/sets"
 	"yunion.io/x/sqlchemy"
 
@@ -29,6 +35,7 @@ import (
 	"yunion.io/x/onecloud/pkg/mcclient"
 	"yunion.io/x/onecloud/pkg/mcclient/auth"
 	"yunion.io/x/onecloud/pkg/mcclient/modules"
+	"yunion.io/x/onecloud/pkg/util/httputils"
 )
 
 var UnhealthStatus = []string{
@@ -55,12 +62,29 @@ func (asc *SASController) HealthCheckSql() *sqlchemy.SQuery {
 }
 
 func (asc *SASController) CheckInstanceHealth(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	// Fetch the ScalingGroups which probe the service of instances before NextCheckTime is updated
+	probeGroups := make([]models.SScalingGroup, 0)
+	probeQ := models.ScalingGroupManager.Query().IsTrue("enabled").LT("next_check_time", time.Now()).Filter(
+		sqlchemy.OR(
+			sqlchemy.Equals(models.ScalingGroupManager.Query().Field("health_check_mode"), apis.HEALTH_CHECK_MODE_LOADBALANCER),
+			sqlchemy.In(models.ScalingGroupManager.Query().Field("health_check_probe"), []string{apis.HEALTH_CHECK_PROBE_TCP, apis.HEALTH_CHECK_PROBE_HTTP}),
+		),
+	)
+	err := db.FetchModelObjects(models.ScalingGroupManager, probeQ, &probeGroups)
+	if err != nil {
+		log.Errorf("unable to fetch ScalingGroups to probe: %v", err)
+	}
+
 	// Fetch all unhealth status instace
 	unnormalGuests := make([]sUnnormalGuest, 0, 5)
 	scalingGroupIdSet := sets.NewString()
+	for i := range probeGroups {
+		scalingGroupIdSet.Insert(probeGroups[i].Id)
+	}
 	rows, err := asc.HealthCheckSql().Rows()
 	if err != nil {
 		log.Errorf("GuestManager's SQuery.Rows: %s", err.Error())
+		return
 	}
 	for rows.Next() {
 		var ug sUnnormalGuest
@@ -82,6 +106,10 @@ func (asc *SASController) CheckInstanceHealth(ctx context.Context, userCred mccl
 	for i := range scalingGroups {
 		scalingGroupMap[scalingGroups[i].GetId()] = &scalingGroups[i]
 	}
+	probeGroupIds := make([]string, 0, len(probeGroups))
+	for i := range probeGroups {
+		probeGroupIds = append(probeGroupIds, probeGroups[i].Id)
+	}
 
 	// update NextCheckTime for ScalingGroup
 	now := time.Now()
@@ -121,6 +149,15 @@ func (asc *SASController) CheckInstanceHealth(ctx context.Context, userCred mccl
 		}
 	}
 
+	// probe the service of instances
+	if len(probeGroupIds) > 0 {
+		go func() {
+			for _, id := range probeGroupIds {
+				asc.probeInstances(ctx, session, scalingGroupMap[id])
+			}
+		}()
+	}
+
 	// check NextCheckTime for ScalngGroup
 
 	if len(readyGuestList) > 0 {
@@ -149,3 +186,198 @@ func (asc *SASController) CheckInstanceHealth(ctx context.Context, userCred mccl
 		}()
 	}
 }
+
+// probeFailureThreshold is the number of consecutive probe failures before an instance is replaced
+const probeFailureThreshold = 2
+
+const (
+	// probeMaxReplacePercent caps the instances replaced by probe in one check cycle
+	probeMaxReplacePercent = 20
+	// probeMinHealthyPercent is the minimal percent of healthy instances, below it the probe or the network
+	// is considered broken instead of the instances and nothing is replaced
+	probeMinHealthyPercent = 50
+)
+
+type sProbeFailures struct {
+	lock     sync.Mutex
+	failures map[string]int
+}
+
+// Record records the probe result of guest and returns whether the guest reaches the failure threshold
+func (pf *sProbeFailures) Record(guestId string, healthy bool) bool {
+	pf.lock.Lock()
+	defer pf.lock.Unlock()
+	if healthy {
+		delete(pf.failures, guestId)
+		return false
+	}
+	pf.failures[guestId]++
+	return pf.failures[guestId] >= probeFailureThreshold
+}
+
+// Reset forgets the failures of guest after it is replaced
+func (pf *sProbeFailures) Reset(guestId string) {
+	pf.lock.Lock()
+	defer pf.lock.Unlock()
+	delete(pf.failures, guestId)
+}
+
+var probeFailures = &sProbeFailures{failures: make(map[string]int)}
+
+// probeReplaceCount returns how many of the unhealthy instances can be replaced in one check cycle
+func probeReplaceCount(total, unhealthy int) int {
+	if unhealthy == 0 {
+		return 0
+	}
+	if (total-unhealthy)*100 < total*probeMinHealthyPercent {
+		return 0
+	}
+	max := total * probeMaxReplacePercent / 100
+	if max < 1 {
+		max = 1
+	}
+	if unhealthy > max {
+		return max
+	}
+	return unhealthy
+}
+
+// probeInstances checks the loadbalancer backend status and probes the service of the ready instances in
+// ScalingGroup, the unhealthy instances are replaced. The service is probed from the controller, so only the
+// instances in classic network are probed, the instances in vpc rely on the loadbalancer health check.
+func (asc *SASController) probeInstances(ctx context.Context, session *mcclient.ClientSession, sg *models.SScalingGroup) {
+	if sg == nil {
+		return
+	}
+	sggs := make([]models.SScalingGroupGuest, 0)
+	q := models.ScalingGroupGuestManager.Query().Equals("scaling_group_id", sg.Id).Equals("guest_status", apis.SG_GUEST_STATUS_READY)
+	err := db.FetchModelObjects(models.ScalingGroupGuestManager, q, &sggs)
+	if err != nil {
+		log.Errorf("fetch guests of ScalingGroup '%s': %v", sg.Id, err)
+		return
+	}
+	var lbStatus map[string]string
+	if sg.HealthCheckMode == apis.HEALTH_CHECK_MODE_LOADBALANCER {
+		lbStatus = asc.loadbalancerBackendStatus(ctx, sg)
+	}
+	now := time.Now()
+	var checked int
+	unhealthy := make([]string, 0)
+	reasons := make(map[string]string)
+	for i := range sggs {
+		// give the service time to start
+		if sggs[i].UpdatedAt.Add(time.Duration(sg.HealthCheckGov) * time.Second).After(now) {
+			continue
+		}
+		guest := models.GuestManager.FetchGuestById(sggs[i].GuestId)
+		if guest == nil || guest.Status != apis.VM_RUNNING {
+			continue
+		}
+		healthy, reason := true, ""
+		status, lbChecked := lbStatus[guest.Id]
+		if lbChecked && !strings.Contains(status, "OK") && status != "INI" {
+			healthy, reason = false, fmt.Sprintf("loadbalancer backend check status %s", status)
+		}
+		probed := false
+		if healthy && len(sg.HealthCheckProbe) > 0 {
+			ips := guest.GetClassicIPs(ctx)
+			if len(ips) > 0 {
+				probed = true
+				err := asc.probeInstance(ctx, sg, ips)
+				if err != nil {
+					healthy, reason = false, err.Error()
+				}
+			}
+		}
+		if !lbChecked && !probed {
+			continue
+		}
+		checked += 1
+		if probeFailures.Record(guest.Id, healthy) {
+			unhealthy = append(unhealthy, guest.Id)
+			reasons[guest.Id] = reason
+		}
+	}
+
+	count := probeReplaceCount(checked, len(unhealthy))
+	if count < len(unhealthy) {
+		log.Warningf("%d of %d checked instances of ScalingGroup '%s' are unhealthy, replace %d of them in this cycle",
+			len(unhealthy), checked, sg.Id, count)
+	}
+	removeParams := jsonutils.NewDict()
+	removeParams.Set("scaling_group", jsonutils.NewString(sg.Id))
+	removeParams.Set("delete_server", jsonutils.JSONTrue)
+	removeParams.Set("auto", jsonutils.JSONTrue)
+	for _, guestId := range unhealthy[:count] {
+		log.Infof("instance '%s' of ScalingGroup '%s' is unhealthy: %s", guestId, sg.Id, reasons[guestId])
+		_, err := modules.Servers.PerformAction(session, guestId, "detach-scaling-group", removeParams)
+		if err != nil {
+			log.Errorf("Request Detach Scaling Group failed: %s", err.Error())
+			continue
+		}
+		probeFailures.Reset(guestId)
+	}
+}
+
+// loadbalancerBackendStatus returns the check status of loadbalancer backends of ScalingGroup keyed by guest id
+func (asc *SASController) loadbalancerBackendStatus(ctx context.Context, sg *models.SScalingGroup) map[string]string {
+	ret := make(map[string]string)
+	if len(sg.BackendGroupId) == 0 {
+		return ret
+	}
+	listeners := make([]models.SLoadbalancerListener, 0)
+	q := models.LoadbalancerListenerManager.Query().Equals("backend_group_id", sg.BackendGroupId).IsFalse("pending_deleted")
+	err := db.FetchModelObjects(models.LoadbalancerListenerManager, q, &listeners)
+	if err != nil {
+		log.Errorf("fetch listeners of loadbalancer backend group '%s': %v", sg.BackendGroupId, err)
+		return ret
+	}
+	for i := range listeners {
+		backends, err := listeners[i].GetDetailsBackendStatus(ctx, auth.AdminCredential(), nil)
+		if err != nil {
+			log.Errorf("fetch backend status of listener '%s': %v", listeners[i].Id, err)
+			continue
+		}
+		objs, _ := backends.GetArray()
+		for _, obj := range objs {
+			guestId, _ := obj.GetString("backend_id")
+			status, _ := obj.GetString("check_status")
+			if len(status) == 0 {
+				continue
+			}
+			// the instance is unhealthy if any listener reports so
+			if origin, ok := ret[guestId]; ok && !strings.Contains(origin, "OK") {
+				continue
+			}
+			ret[guestId] = status
+		}
+	}
+	return ret
+}
+
+func (asc *SASController) probeInstance(ctx context.Context, sg *models.SScalingGroup, ips []string) error {
+	if len(ips) == 0 {
+		return fmt.Errorf("no ip")
+	}
+	addr := net.JoinHostPort(ips[0], strconv.Itoa(sg.HealthCheckPort))
+	switch sg.HealthCheckProbe {
+	case apis.HEALTH_CHECK_PROBE_TCP:
+		conn, err := net.DialTimeout("tcp", addr, 3*time.Second)
+		if err != nil {
+			return errors.Wrapf(err, "tcp probe %s", addr)
+		}
+		conn.Close()
+	case apis.HEALTH_CHECK_PROBE_HTTP:
+		url := fmt.Sprintf("http://%s%s", addr, sg.HealthCheckPath)
+		client := httputils.GetTimeoutClient(5 * time.Second)
+		resp, err := httputils.Request(client, ctx, httputils.GET, url, nil, nil, false)
+		if err != nil {
+			return errors.Wrapf(err, "http probe %s", url)
+		}
+		httputils.CloseResponse(resp)
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("http probe %s: status code %d", url, resp.StatusCode)
+		}
+	}
+	return nil
+}
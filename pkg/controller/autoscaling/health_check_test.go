@@ -0,0 +1,66 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autoscaling
+
+import "testing"
+
+func TestProbeReplaceCount(t *testing.T) {
+	cases := []struct {
+		name      string
+		total     int
+		unhealthy int
+		want      int
+	}{
+		{"all healthy", 10, 0, 0},
+		{"one unhealthy", 10, 1, 1},
+		{"capped per cycle", 10, 4, 2},
+		{"small group at least one", 3, 1, 1},
+		{"half healthy", 2, 1, 1},
+		{"too few healthy", 10, 6, 0},
+		{"single instance", 1, 1, 0},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := probeReplaceCount(c.total, c.unhealthy)
+			if got != c.want {
+				t.Errorf("total %d unhealthy %d: want %d, got %d", c.total, c.unhealthy, c.want, got)
+			}
+		})
+	}
+}
+
+func TestProbeFailuresRecord(t *testing.T) {
+	pf := &sProbeFailures{failures: make(map[string]int)}
+	if pf.Record("g1", false) {
+		t.Errorf("first failure should not reach threshold")
+	}
+	if pf.Record("g1", true) {
+		t.Errorf("healthy probe should not reach threshold")
+	}
+	if pf.Record("g1", false) {
+		t.Errorf("healthy probe should reset failures")
+	}
+	if !pf.Record("g1", false) {
+		t.Errorf("consecutive failures should reach threshold")
+	}
+	// not replaced in this cycle, still unhealthy in the next one
+	if !pf.Record("g1", false) {
+		t.Errorf("failures should be kept until reset")
+	}
+	pf.Reset("g1")
+	if pf.Record("g1", false) {
+		t.Errorf("failures should be cleared by reset")
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autoscaling
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+
+	"yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	"yunion.io/x/onecloud/pkg/mcclient/modules"
+)
+
+// CheckMetricPolicy triggers the target tracking and step scaling policies periodically,
+// the policies evaluate the metrics of instances and decide whether to scale by themselves.
+func (asc *SASController) CheckMetricPolicy(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	sgSubQ := models.ScalingGroupManager.Query("id").IsTrue("enabled").SubQuery()
+	q := models.ScalingPolicyManager.Query().Equals("status", compute.SP_STATUS_READY).IsTrue("enabled").
+		In("trigger_type", []string{compute.TRIGGER_TARGET, compute.TRIGGER_STEP}).In("scaling_group_id", sgSubQ)
+	policies := make([]models.SScalingPolicy, 0, 5)
+	err := db.FetchModelObjects(models.ScalingPolicyManager, q, &policies)
+	if err != nil {
+		log.Errorf("db.FetchModelObjects error: %s", err.Error())
+		return
+	}
+	log.Debugf("total %d metric scaling policies need to evaluate", len(policies))
+	session := auth.GetSession(ctx, userCred, "", "")
+	triggerParams := jsonutils.NewDict()
+	for i := range policies {
+		policyId := policies[i].Id
+		asc.timerQueue <- struct{}{}
+		go func() {
+			defer func() {
+				<-asc.timerQueue
+			}()
+			_, err := modules.ScalingPolicy.PerformAction(session, policyId, "trigger", triggerParams)
+			if err != nil {
+				log.Errorf("unable to request to trigger ScalingPolicy '%s': %v", policyId, err)
+			}
+		}()
+	}
+}
@@ -146,7 +146,6 @@ func (r *SRegionDNS) ServeDNS(ctx context.Context, w dns.ResponseWriter, rmsg *d
 	case dns.TypeA:
 		records, err = plugin.A(r, zone, state, nil, opt)
 	case dns.TypeAAAA:
-		// TODO fallthrough to next
 		records, err = plugin.AAAA(r, zone, state, nil, opt)
 	case dns.TypeTXT:
 		records, err = plugin.TXT(r, zone, state, opt)
@@ -184,6 +183,11 @@ func (r *SRegionDNS) ServeDNS(ctx context.Context, w dns.ResponseWriter, rmsg *d
 	}
 
 	if len(records) == 0 {
+		if err == nil && (state.QType() == dns.TypeA || state.QType() == dns.TypeAAAA) {
+			// the name exists but has no address of the queried family,
+			// answer NODATA so that dual-stack resolvers keep the other one
+			return plugin.BackendError(r, zone, dns.RcodeSuccess, state, nil, opt)
+		}
 		return plugin.BackendError(r, zone, dns.RcodeNameError, state, err, opt)
 	}
 
@@ -22,6 +22,7 @@ import (
 
 	"yunion.io/x/jsonutils"
 
+	hostapi "yunion.io/x/onecloud/pkg/apis/host"
 	"yunion.io/x/onecloud/pkg/appsrv"
 	"yunion.io/x/onecloud/pkg/hostman/guestman"
 	"yunion.io/x/onecloud/pkg/hostman/hostutils"
@@ -67,6 +68,8 @@ func AddGuestTaskHandler(prefix string, app *appsrv.Application) {
 			"io-throttle":          guestIoThrottle,
 			"snapshot":             guestSnapshot,
 			"delete-snapshot":      guestDeleteSnapshot,
+			"disk-backup":          guestDiskBackup,
+			"change-disk-storage":  guestChangeDiskStorage,
 			"reload-disk-snapshot": guestReloadDiskSnapshot,
 			"src-prepare-migrate":  guestSrcPrepareMigrate,
 			"dest-prepare-migrate": guestDestPrepareMigrate,
@@ -80,6 +83,12 @@ func AddGuestTaskHandler(prefix string, app *appsrv.Application) {
 			"open-forward":         guestOpenForward,
 			"list-forward":         guestListForward,
 			"close-forward":        guestCloseForward,
+			"qga-fsfreeze":         guestQgaFsFreeze,
+			"qga-fsthaw":           guestQgaFsThaw,
+			"qga-set-password":     guestQgaSetPassword,
+			"qga-guest-info":       guestQgaGuestInfo,
+			"qga-file-read":        guestQgaFileRead,
+			"qga-file-write":       guestQgaFileWrite,
 		} {
 			app.AddHandler("POST",
 				fmt.Sprintf("%s/%s/<sid>/%s", prefix, keyWord, action),
@@ -351,6 +360,9 @@ func guestDestPrepareMigrate(ctx context.Context, sid string, body jsonutils.JSO
 
 		}
 		params.RebaseDisks = jsonutils.QueryBoolean(body, "rebase_disks", false)
+	} else if body.Contains("server_url") {
+		// shared storage guest with vtpm or secure boot state files
+		params.ServerUrl, _ = body.GetString("server_url")
 	}
 	hostutils.DelayTask(ctx, guestman.GetGuestManager().DestPrepareMigrate, params)
 	return nil, nil
@@ -511,6 +523,89 @@ func guestSnapshot(ctx context.Context, sid string, body jsonutils.JSONObject) (
 	return nil, nil
 }
 
+func guestDiskBackup(ctx context.Context, sid string, body jsonutils.JSONObject) (interface{}, error) {
+	input := &hostapi.GuestDiskBackupRequest{}
+	if err := body.Unmarshal(input); err != nil {
+		return nil, httperrors.NewInputParameterError("unmarshal: %v", err)
+	}
+	if len(input.DiskId) == 0 {
+		return nil, httperrors.NewMissingParameterError("disk_id")
+	}
+	if len(input.BackupId) == 0 {
+		return nil, httperrors.NewMissingParameterError("backup_id")
+	}
+	guest, ok := guestman.GetGuestManager().GetServer(sid)
+	if !ok {
+		return nil, httperrors.NewNotFoundError("guest %s not found", sid)
+	}
+
+	var disk storageman.IDisk
+	disks, _ := guest.Desc.GetArray("disks")
+	for _, d := range disks {
+		id, _ := d.GetString("disk_id")
+		if input.DiskId == id {
+			diskPath, _ := d.GetString("path")
+			disk = storageman.GetManager().GetDiskByPath(diskPath)
+			break
+		}
+	}
+	if disk == nil {
+		return nil, httperrors.NewNotFoundError("Disk not found")
+	}
+
+	hostutils.DelayTask(ctx, guestman.GetGuestManager().DiskBackup, &guestman.SDiskBackup{
+		Sid:   sid,
+		Disk:  disk,
+		Input: input,
+	})
+	return nil, nil
+}
+
+func guestChangeDiskStorage(ctx context.Context, sid string, body jsonutils.JSONObject) (interface{}, error) {
+	input := &hostapi.GuestChangeDiskStorageRequest{}
+	if err := body.Unmarshal(input); err != nil {
+		return nil, httperrors.NewInputParameterError("unmarshal: %v", err)
+	}
+	if len(input.DiskId) == 0 {
+		return nil, httperrors.NewMissingParameterError("disk_id")
+	}
+	if len(input.TargetStorageId) == 0 {
+		return nil, httperrors.NewMissingParameterError("target_storage_id")
+	}
+	guest, ok := guestman.GetGuestManager().GetServer(sid)
+	if !ok {
+		return nil, httperrors.NewNotFoundError("guest %s not found", sid)
+	}
+	if !guest.IsRunning() {
+		return nil, httperrors.NewInvalidStatusError("guest %s not running", sid)
+	}
+
+	var disk storageman.IDisk
+	disks, _ := guest.Desc.GetArray("disks")
+	for _, d := range disks {
+		id, _ := d.GetString("disk_id")
+		if input.DiskId == id {
+			diskPath, _ := d.GetString("path")
+			disk = storageman.GetManager().GetDiskByPath(diskPath)
+			break
+		}
+	}
+	if disk == nil {
+		return nil, httperrors.NewNotFoundError("Disk not found")
+	}
+	storage := storageman.GetManager().GetStorage(input.TargetStorageId)
+	if storage == nil {
+		return nil, httperrors.NewNotFoundError("Storage %s not found", input.TargetStorageId)
+	}
+
+	hostutils.DelayTask(ctx, guestman.GetGuestManager().ChangeDiskStorage, &guestman.SChangeDiskStorage{
+		Sid:           sid,
+		Disk:          disk,
+		TargetStorage: storage,
+	})
+	return nil, nil
+}
+
 func guestDeleteSnapshot(ctx context.Context, sid string, body jsonutils.JSONObject) (interface{}, error) {
 	deleteSnapshot, err := body.GetString("delete_snapshot")
 	if err != nil {
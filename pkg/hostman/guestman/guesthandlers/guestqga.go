@@ -0,0 +1,153 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guesthandlers
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+
+	hostapis "yunion.io/x/onecloud/pkg/apis/host"
+	"yunion.io/x/onecloud/pkg/hostman/guestman"
+	"yunion.io/x/onecloud/pkg/httperrors"
+)
+
+const (
+	qgaFileReadDefaultSize = 64 * 1024
+	qgaFileReadMaxSize     = 1024 * 1024
+)
+
+func guestQgaFsFreeze(ctx context.Context, sid string, body jsonutils.JSONObject) (interface{}, error) {
+	count, err := guestman.GetGuestManager().GuestAgentFsFreeze(sid)
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	return jsonutils.Marshal(&hostapis.GuestQgaFsFreezeResponse{Count: count}), nil
+}
+
+func guestQgaFsThaw(ctx context.Context, sid string, body jsonutils.JSONObject) (interface{}, error) {
+	count, err := guestman.GetGuestManager().GuestAgentFsThaw(sid)
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	return jsonutils.Marshal(&hostapis.GuestQgaFsFreezeResponse{Count: count}), nil
+}
+
+func guestQgaSetPassword(ctx context.Context, sid string, body jsonutils.JSONObject) (interface{}, error) {
+	req := &hostapis.GuestQgaSetPasswordRequest{}
+	if err := body.Unmarshal(req); err != nil {
+		return nil, httperrors.NewInputParameterError("unmarshal: %v", err)
+	}
+	if len(req.Username) == 0 {
+		return nil, httperrors.NewMissingParameterError("username")
+	}
+	if len(req.Password) == 0 {
+		return nil, httperrors.NewMissingParameterError("password")
+	}
+	qga, err := guestman.GetGuestManager().GetGuestAgent(sid)
+	if err != nil {
+		return nil, err
+	}
+	if err := qga.SetUserPassword(req.Username, req.Password, false); err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	return nil, nil
+}
+
+func guestQgaGuestInfo(ctx context.Context, sid string, body jsonutils.JSONObject) (interface{}, error) {
+	qga, err := guestman.GetGuestManager().GetGuestAgent(sid)
+	if err != nil {
+		return nil, err
+	}
+	ifaces, err := qga.GetNetworkInterfaces()
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	resp := &hostapis.GuestQgaGuestInfoResponse{
+		Interfaces: make([]hostapis.GuestQgaNetworkInterface, 0, len(ifaces)),
+	}
+	for _, iface := range ifaces {
+		nic := hostapis.GuestQgaNetworkInterface{
+			Name: iface.Name,
+			Mac:  iface.HardwareAddress,
+			Ips:  make([]string, 0, len(iface.IpAddresses)),
+		}
+		for _, addr := range iface.IpAddresses {
+			nic.Ips = append(nic.Ips, fmt.Sprintf("%s/%d", addr.IpAddress, addr.Prefix))
+		}
+		resp.Interfaces = append(resp.Interfaces, nic)
+	}
+	// guest-get-osinfo requires qemu-ga 2.10 or later
+	if info, err := qga.GetOsInfo(); err == nil {
+		resp.OsInfo = &hostapis.GuestQgaOsInfo{
+			Id:            info.Id,
+			Name:          info.Name,
+			PrettyName:    info.PrettyName,
+			Version:       info.Version,
+			VersionId:     info.VersionId,
+			KernelRelease: info.KernelRelease,
+			KernelVersion: info.KernelVersion,
+			Machine:       info.Machine,
+		}
+	}
+	return jsonutils.Marshal(resp), nil
+}
+
+func guestQgaFileRead(ctx context.Context, sid string, body jsonutils.JSONObject) (interface{}, error) {
+	req := &hostapis.GuestQgaFileReadRequest{}
+	if err := body.Unmarshal(req); err != nil {
+		return nil, httperrors.NewInputParameterError("unmarshal: %v", err)
+	}
+	if len(req.Path) == 0 {
+		return nil, httperrors.NewMissingParameterError("path")
+	}
+	if req.MaxSize <= 0 {
+		req.MaxSize = qgaFileReadDefaultSize
+	} else if req.MaxSize > qgaFileReadMaxSize {
+		return nil, httperrors.NewInputParameterError("max_size should not exceed %d", qgaFileReadMaxSize)
+	}
+	qga, err := guestman.GetGuestManager().GetGuestAgent(sid)
+	if err != nil {
+		return nil, err
+	}
+	content, err := qga.FileRead(req.Path, req.MaxSize)
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	resp := &hostapis.GuestQgaFileReadResponse{
+		Content: string(content),
+		Size:    len(content),
+	}
+	return jsonutils.Marshal(resp), nil
+}
+
+func guestQgaFileWrite(ctx context.Context, sid string, body jsonutils.JSONObject) (interface{}, error) {
+	req := &hostapis.GuestQgaFileWriteRequest{}
+	if err := body.Unmarshal(req); err != nil {
+		return nil, httperrors.NewInputParameterError("unmarshal: %v", err)
+	}
+	if len(req.Path) == 0 {
+		return nil, httperrors.NewMissingParameterError("path")
+	}
+	qga, err := guestman.GetGuestManager().GetGuestAgent(sid)
+	if err != nil {
+		return nil, err
+	}
+	if err := qga.FileWrite(req.Path, []byte(req.Content), req.Append); err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	return nil, nil
+}
@@ -17,6 +17,7 @@ package guestman
 import (
 	"yunion.io/x/jsonutils"
 
+	hostapi "yunion.io/x/onecloud/pkg/apis/host"
 	"yunion.io/x/onecloud/pkg/hostman/storageman"
 	"yunion.io/x/onecloud/pkg/multicloud/esxi/vcenter"
 )
@@ -83,6 +84,18 @@ type SDiskSnapshot struct {
 	Disk       storageman.IDisk
 }
 
+type SDiskBackup struct {
+	Sid   string
+	Disk  storageman.IDisk
+	Input *hostapi.GuestDiskBackupRequest
+}
+
+type SChangeDiskStorage struct {
+	Sid           string
+	Disk          storageman.IDisk
+	TargetStorage storageman.IStorage
+}
+
 type SDeleteDiskSnapshot struct {
 	Sid             string
 	DeleteSnapshot  string
@@ -21,6 +21,7 @@ import (
 	"os"
 	"path"
 	"runtime/debug"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -31,6 +32,7 @@ import (
 	"yunion.io/x/log"
 	"yunion.io/x/pkg/util/regutils"
 	"yunion.io/x/pkg/util/seclib"
+	"yunion.io/x/pkg/utils"
 
 	"yunion.io/x/onecloud/pkg/apis/compute"
 	hostapi "yunion.io/x/onecloud/pkg/apis/host"
@@ -40,6 +42,7 @@ import (
 	"yunion.io/x/onecloud/pkg/hostman/guestman/types"
 	deployapi "yunion.io/x/onecloud/pkg/hostman/hostdeployer/apis"
 	"yunion.io/x/onecloud/pkg/hostman/hostutils"
+	"yunion.io/x/onecloud/pkg/hostman/monitor"
 	"yunion.io/x/onecloud/pkg/hostman/options"
 	"yunion.io/x/onecloud/pkg/hostman/storageman"
 	"yunion.io/x/onecloud/pkg/httperrors"
@@ -251,8 +254,57 @@ func (m *SGuestManager) StartCpusetBalancer() {
 
 func (m *SGuestManager) cpusetBalance() {
 	if !options.HostOptions.DisableSetCgroup {
-		cgrouputils.RebalanceProcesses(nil)
+		pids, err := m.getUnboundPids()
+		if err != nil {
+			log.Errorf("get pids to rebalance: %s", err)
+			return
+		}
+		cgrouputils.RebalanceProcesses(pids)
+	}
+}
+
+// getNumaBoundCpus returns vcpus of running guests bound to each numa node
+func (m *SGuestManager) getNumaBoundCpus(excludeId string) map[int]int {
+	boundCpus := make(map[int]int)
+	m.Servers.Range(func(k, v interface{}) bool {
+		guest := v.(*SKVMGuestInstance)
+		if guest.Id == excludeId || !guest.IsRunning() {
+			return true
+		}
+		if nodeId, ok := guest.getDescNumaNode(); ok && nodeId >= 0 {
+			cpu, _ := guest.Desc.Int("cpu")
+			boundCpus[nodeId] += int(cpu)
+		}
+		return true
+	})
+	return boundCpus
+}
+
+// getUnboundPids returns processes could be rebalanced, guests bound to
+// numa nodes are excluded, nil means all processes
+func (m *SGuestManager) getUnboundPids() ([]string, error) {
+	boundPids := make([]string, 0)
+	m.Servers.Range(func(k, v interface{}) bool {
+		guest := v.(*SKVMGuestInstance)
+		if guest.IsRunning() && guest.GetNumaNode() != nil {
+			boundPids = append(boundPids, strconv.Itoa(guest.GetPid()))
+		}
+		return true
+	})
+	if len(boundPids) == 0 {
+		return nil, nil
+	}
+	allPids, err := cgrouputils.GetAllPids()
+	if err != nil {
+		return nil, err
 	}
+	pids := make([]string, 0, len(allPids))
+	for _, pid := range allPids {
+		if !utils.IsInStringArray(pid, boundPids) {
+			pids = append(pids, pid)
+		}
+	}
+	return pids, nil
 }
 
 func (m *SGuestManager) IsGuestDir(f os.FileInfo) bool {
@@ -385,6 +437,34 @@ func (m *SGuestManager) Monitor(sid, cmd string, callback func(string)) error {
 	}
 }
 
+func (m *SGuestManager) GetGuestAgent(sid string) (*monitor.QemuGuestAgent, error) {
+	guest, ok := m.GetServer(sid)
+	if !ok {
+		return nil, httperrors.NewNotFoundError("Not found")
+	}
+	qga, err := guest.GetGuestAgent()
+	if err != nil {
+		return nil, httperrors.NewBadRequestError("%v", err)
+	}
+	return qga, nil
+}
+
+func (m *SGuestManager) GuestAgentFsFreeze(sid string) (int, error) {
+	guest, ok := m.GetServer(sid)
+	if !ok {
+		return 0, httperrors.NewNotFoundError("Not found")
+	}
+	return guest.GuestAgentFsFreeze()
+}
+
+func (m *SGuestManager) GuestAgentFsThaw(sid string) (int, error) {
+	guest, ok := m.GetServer(sid)
+	if !ok {
+		return 0, httperrors.NewNotFoundError("Not found")
+	}
+	return guest.GuestAgentFsThaw()
+}
+
 func (m *SGuestManager) sdnClient() (fwdpb.ForwarderClient, error) {
 	sockPath := options.HostOptions.SdnSocketPath
 	if strings.HasPrefix(sockPath, "/") {
@@ -769,9 +849,15 @@ func (m *SGuestManager) SrcPrepareMigrate(ctx context.Context, params interface{
 	if err != nil {
 		return nil, err
 	}
+	ret := jsonutils.NewDict()
 	if disksPrepare.Length() > 0 {
-		ret := jsonutils.NewDict()
 		ret.Set("disks_back", disksPrepare)
+	}
+	if nodeId, ok := guest.getDescNumaNode(); ok {
+		// destination declares guest memory the same way
+		ret.Set("numa_node", jsonutils.NewInt(int64(nodeId)))
+	}
+	if ret.Length() > 0 {
 		return ret, nil
 	}
 	return nil, nil
@@ -788,6 +874,14 @@ func (m *SGuestManager) DestPrepareMigrate(ctx context.Context, params interface
 		return nil, err
 	}
 
+	// tpm state and uefi nvram of a running guest are migrated by qemu along
+	// with memory, files on source host are stale once guest keeps writing
+	if !migParams.LiveMigrate && len(migParams.ServerUrl) > 0 && (guest.isVtpmEnabled() || guest.isSecureBoot()) {
+		if err := guest.fetchGuestStateFiles(ctx, migParams.ServerUrl); err != nil {
+			return nil, errors.Wrap(err, "fetch guest state files")
+		}
+	}
+
 	disks, _ := migParams.Desc.GetArray("disks")
 	if len(migParams.TargetStorageIds) > 0 {
 		for i := 0; i < len(migParams.TargetStorageIds); i++ {
@@ -905,6 +999,24 @@ func (m *SGuestManager) DoSnapshot(ctx context.Context, params interface{}) (jso
 	return guest.ExecDiskSnapshotTask(ctx, snapshotParams.Disk, snapshotParams.SnapshotId)
 }
 
+func (m *SGuestManager) DiskBackup(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	backupParams, ok := params.(*SDiskBackup)
+	if !ok {
+		return nil, hostutils.ParamsError
+	}
+	guest, _ := m.GetServer(backupParams.Sid)
+	return guest.ExecDiskBackupTask(ctx, backupParams.Disk, backupParams.Input)
+}
+
+func (m *SGuestManager) ChangeDiskStorage(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	changeParams, ok := params.(*SChangeDiskStorage)
+	if !ok {
+		return nil, hostutils.ParamsError
+	}
+	guest, _ := m.GetServer(changeParams.Sid)
+	return guest.ExecChangeDiskStorageTask(ctx, changeParams.Disk, changeParams.TargetStorage)
+}
+
 func (m *SGuestManager) DeleteSnapshot(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
 	delParams, ok := params.(*SDeleteDiskSnapshot)
 	if !ok {
@@ -927,6 +1039,8 @@ func (m *SGuestManager) Resume(ctx context.Context, sid string, isLiveMigrate bo
 	resumeTask := NewGuestResumeTask(ctx, guest)
 	if isLiveMigrate {
 		guest.StartPresendArp()
+		// disks were opened with shared locks as the migration destination
+		go guest.reacquireExclusiveDiskLocks()
 	}
 	resumeTask.Start()
 	return nil, nil
@@ -20,6 +20,7 @@ import (
 	"os"
 	"path"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -27,6 +28,8 @@ import (
 	"yunion.io/x/log"
 	"yunion.io/x/pkg/utils"
 
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	hostapi "yunion.io/x/onecloud/pkg/apis/host"
 	"yunion.io/x/onecloud/pkg/appctx"
 	"yunion.io/x/onecloud/pkg/hostman/hostutils"
 	"yunion.io/x/onecloud/pkg/hostman/options"
@@ -489,15 +492,22 @@ func (s *SGuestLiveMigrateTask) Start() {
 
 func (s *SGuestLiveMigrateTask) onSetZeroBlocks(res string) {
 	if strings.Contains(strings.ToLower(res), "error") {
-		hostutils.TaskFailed(s.ctx, fmt.Sprintf("Migrate set capability error: %s", res))
+		s.migrateFailed(fmt.Sprintf("Migrate set capability error: %s", res))
 		return
 	}
 	s.Monitor.MigrateSetCapability("auto-converge", "on", s.startMigrate)
 }
 
+// migrateFailed turns disk locks shared for migration back to exclusive,
+// the guest keeps running on this host
+func (s *SGuestLiveMigrateTask) migrateFailed(reason string) {
+	go s.reacquireExclusiveDiskLocks()
+	hostutils.TaskFailed(s.ctx, reason)
+}
+
 func (s *SGuestLiveMigrateTask) startMigrate(res string) {
 	if strings.Contains(strings.ToLower(res), "error") {
-		hostutils.TaskFailed(s.ctx, fmt.Sprintf("Migrate set capability error: %s", res))
+		s.migrateFailed(fmt.Sprintf("Migrate set capability error: %s", res))
 		return
 	}
 
@@ -511,7 +521,7 @@ func (s *SGuestLiveMigrateTask) startMigrate(res string) {
 
 func (s *SGuestLiveMigrateTask) startMigrateStatusCheck(res string) {
 	if strings.Contains(strings.ToLower(res), "error") {
-		hostutils.TaskFailed(s.ctx, fmt.Sprintf("Migrate error: %s", res))
+		s.migrateFailed(fmt.Sprintf("Migrate error: %s", res))
 		return
 	}
 
@@ -533,7 +543,7 @@ func (s *SGuestLiveMigrateTask) onGetMigrateStatus(status string) {
 		hostutils.TaskComplete(s.ctx, nil)
 	} else if status == "failed" {
 		close(s.c)
-		hostutils.TaskFailed(s.ctx, fmt.Sprintf("Query migrate got status: %s", status))
+		s.migrateFailed(fmt.Sprintf("Query migrate got status: %s", status))
 	}
 }
 
@@ -973,6 +983,147 @@ func (s *SGuestDiskSnapshotTask) onResumeSucc(res string) {
 	hostutils.TaskComplete(s.ctx, body)
 }
 
+/**
+ *  GuestDiskBackupTask
+**/
+
+type SGuestDiskBackupTask struct {
+	*SGuestReloadDiskTask
+
+	input      *hostapi.GuestDiskBackupRequest
+	backupMode string
+	bitmap     string
+	device     string
+	tmpDir     string
+	target     string
+}
+
+func NewGuestDiskBackupTask(
+	ctx context.Context, s *SKVMGuestInstance, disk storageman.IDisk, input *hostapi.GuestDiskBackupRequest,
+) *SGuestDiskBackupTask {
+	return &SGuestDiskBackupTask{
+		SGuestReloadDiskTask: NewGuestReloadDiskTask(ctx, s, disk),
+		input:                input,
+		backupMode:           input.BackupMode,
+		bitmap:               input.BitmapName,
+	}
+}
+
+func (s *SGuestDiskBackupTask) Start() {
+	s.Monitor.GetBlocks(s.onGetBlocksSucc)
+}
+
+func (s *SGuestDiskBackupTask) onGetBlocksSucc(res *jsonutils.JSONArray) {
+	if res == nil {
+		s.taskFailed("Get blocks failed")
+		return
+	}
+	bitmaps := []string{}
+	devs, _ := res.GetArray()
+	for _, d := range devs {
+		if device := s.getDiskOfDrive(d); len(device) > 0 {
+			s.device = device
+			dirtyBitmaps, _ := d.GetArray("dirty-bitmaps")
+			for _, bitmap := range dirtyBitmaps {
+				name, _ := bitmap.GetString("name")
+				bitmaps = append(bitmaps, name)
+			}
+			break
+		}
+	}
+	if len(s.device) == 0 {
+		s.taskFailed("Device not found")
+		return
+	}
+
+	if s.backupMode == api.DISK_BACKUP_MODE_INCREMENTAL && !utils.IsInStringArray(s.bitmap, bitmaps) {
+		// bitmap lost, e.g. guest restarted by an old qemu, fallback to full backup
+		log.Warningf("Dirty bitmap %s of %s not found, fallback to full backup", s.bitmap, s.device)
+		s.backupMode = api.DISK_BACKUP_MODE_FULL
+	}
+	if s.backupMode == api.DISK_BACKUP_MODE_FULL {
+		s.bitmap = api.DISK_BACKUP_BITMAP_PREFIX + s.input.BackupId
+		staleBitmaps := []string{}
+		for _, name := range bitmaps {
+			if strings.HasPrefix(name, api.DISK_BACKUP_BITMAP_PREFIX) {
+				staleBitmaps = append(staleBitmaps, name)
+			}
+		}
+		s.removeStaleBitmaps(staleBitmaps)
+	} else {
+		s.startBackup()
+	}
+}
+
+func (s *SGuestDiskBackupTask) removeStaleBitmaps(bitmaps []string) {
+	if len(bitmaps) == 0 {
+		s.startBackup()
+		return
+	}
+	s.Monitor.BlockDirtyBitmapRemove(s.device, bitmaps[0], func(res string) {
+		if len(res) > 0 {
+			log.Errorf("Remove dirty bitmap %s of %s failed: %s", bitmaps[0], s.device, res)
+		}
+		s.removeStaleBitmaps(bitmaps[1:])
+	})
+}
+
+func (s *SGuestDiskBackupTask) startBackup() {
+	var err error
+	s.tmpDir, err = storageman.GetDiskBackupTempDir(s.input.BackupId)
+	if err != nil {
+		s.taskFailed(err.Error())
+		return
+	}
+	diskImg, err := qemuimg.NewQemuImage(s.disk.GetPath())
+	if err != nil {
+		s.onBackupFailed(fmt.Sprintf("open disk %s: %s", s.disk.GetPath(), err))
+		return
+	}
+	s.target = path.Join(s.tmpDir, s.input.BackupId)
+	img, err := qemuimg.NewQemuImage(s.target)
+	if err != nil {
+		s.onBackupFailed(err.Error())
+		return
+	}
+	// backup target must not preallocate, unallocated clusters fall through to parent backup on recovery
+	if err := img.CreateQcow2(diskImg.GetSizeMB(), true, ""); err != nil {
+		s.onBackupFailed(fmt.Sprintf("create backup target: %s", err))
+		return
+	}
+	s.diskBackupJobs.Store(s.device, s.onBackupJobFinished)
+	s.Monitor.DriveBackup(s.onDriveBackupStarted, s.device, s.target, s.backupMode, s.bitmap)
+}
+
+func (s *SGuestDiskBackupTask) onDriveBackupStarted(res string) {
+	if len(res) > 0 {
+		s.diskBackupJobs.Delete(s.device)
+		s.onBackupFailed(fmt.Sprintf("drive backup: %s", res))
+	}
+}
+
+// called on BLOCK_JOB_COMPLETED or BLOCK_JOB_CANCELLED
+func (s *SGuestDiskBackupTask) onBackupJobFinished(errMsg string) {
+	if len(errMsg) > 0 {
+		s.onBackupFailed(fmt.Sprintf("backup job: %s", errMsg))
+		return
+	}
+	resp, err := storageman.UploadDiskBackupFile(s.input, s.target, s.backupMode)
+	storageman.CleanDiskBackupTempDir(s.tmpDir)
+	if err != nil {
+		s.taskFailed(err.Error())
+		return
+	}
+	hostutils.TaskComplete(s.ctx, jsonutils.Marshal(resp))
+}
+
+func (s *SGuestDiskBackupTask) onBackupFailed(reason string) {
+	if len(s.tmpDir) > 0 {
+		storageman.CleanDiskBackupTempDir(s.tmpDir)
+	}
+	s.taskFailed(reason)
+}
+
 /**
  *  GuestSnapshotDeleteTask
 **/
@@ -1137,7 +1288,7 @@ func (s *SDriveMirrorTask) startMirror(res string) {
 	if s.index < len(disks) {
 		target := fmt.Sprintf("%s:exportname=drive_%d", s.nbdUri, s.index)
 		s.Monitor.DriveMirror(s.startMirror, fmt.Sprintf("drive_%d", s.index),
-			target, s.syncMode, true, blockReplication)
+			target, s.syncMode, "", true, blockReplication)
 		s.index += 1
 	} else {
 		if s.onSucc != nil {
@@ -1148,6 +1299,157 @@ func (s *SDriveMirrorTask) startMirror(res string) {
 	}
 }
 
+/**
+ *  GuestChangeDiskStorageTask
+**/
+
+type SGuestChangeDiskStorageTask struct {
+	*SKVMGuestInstance
+
+	ctx           context.Context
+	disk          storageman.IDisk
+	targetStorage storageman.IStorage
+	targetDisk    storageman.IDisk
+	targetFormat  string
+	drive         string
+}
+
+func NewGuestChangeDiskStorageTask(
+	ctx context.Context, s *SKVMGuestInstance, disk storageman.IDisk, targetStorage storageman.IStorage,
+) *SGuestChangeDiskStorageTask {
+	return &SGuestChangeDiskStorageTask{
+		SKVMGuestInstance: s,
+		ctx:               ctx,
+		disk:              disk,
+		targetStorage:     targetStorage,
+	}
+}
+
+func (s *SGuestChangeDiskStorageTask) Start() {
+	var format string
+	disks, _ := s.Desc.GetArray("disks")
+	for _, d := range disks {
+		if id, _ := d.GetString("disk_id"); id == s.disk.GetId() {
+			index, _ := d.Int("index")
+			format, _ = d.GetString("format")
+			s.drive = fmt.Sprintf("drive_%d", index)
+			break
+		}
+	}
+	if len(s.drive) == 0 {
+		s.taskFailed("Disk not found in guest desc")
+		return
+	}
+	desc := s.disk.GetDiskDesc()
+	if desc == nil {
+		s.taskFailed("Get disk desc failed")
+		return
+	}
+	sizeMb, _ := desc.Int("disk_size")
+	if len(format) == 0 {
+		format = "qcow2"
+	}
+
+	s.targetDisk = s.targetStorage.CreateDisk(s.disk.GetId())
+	if s.targetDisk == nil {
+		s.taskFailed("Create disk on target storage failed")
+		return
+	}
+	if _, err := s.targetDisk.CreateRaw(s.ctx, int(sizeMb), format, "", false, "", ""); err != nil {
+		s.onMirrorFailed(fmt.Sprintf("create target disk: %s", err))
+		return
+	}
+	if lockDisk, ok := s.targetDisk.(storageman.IClusterLockDisk); ok {
+		if err := lockDisk.AcquireLock(false); err != nil {
+			s.onMirrorFailed(fmt.Sprintf("acquire lock of target disk: %s", err))
+			return
+		}
+	}
+	// block devices of lvm, rbd and plugin storages are always raw
+	// whatever the format of source disk is
+	s.targetFormat = format
+	if targetDesc := s.targetDisk.GetDiskDesc(); targetDesc != nil {
+		for _, key := range []string{"format", "disk_format"} {
+			if targetFormat, _ := targetDesc.GetString(key); len(targetFormat) > 0 {
+				s.targetFormat = targetFormat
+				break
+			}
+		}
+	}
+	s.diskMirrorJobs.Store(s.drive, s)
+	s.Monitor.DriveMirror(s.onDriveMirrorStarted, s.drive, s.targetDisk.GetPath(), "full", s.targetFormat, true, false)
+}
+
+func (s *SGuestChangeDiskStorageTask) onDriveMirrorStarted(res string) {
+	if len(res) > 0 {
+		s.diskMirrorJobs.Delete(s.drive)
+		s.onMirrorFailed(fmt.Sprintf("drive mirror: %s", res))
+	}
+}
+
+// called on BLOCK_JOB_READY, source and target are in sync from now on
+func (s *SGuestChangeDiskStorageTask) onMirrorReady() {
+	log.Infof("Guest %s %s mirror ready, pivot to %s", s.GetName(), s.drive, s.targetDisk.GetPath())
+	s.Monitor.BlockJobComplete(s.drive, func(res string) {
+		if len(res) > 0 {
+			log.Errorf("Guest %s block job complete %s: %s", s.GetName(), s.drive, res)
+			s.Monitor.CancelBlockJob(s.drive, true, func(string) {})
+		}
+	})
+}
+
+// called on BLOCK_JOB_COMPLETED or BLOCK_JOB_CANCELLED
+func (s *SGuestChangeDiskStorageTask) onMirrorJobFinished(errMsg string) {
+	if len(errMsg) > 0 {
+		s.onMirrorFailed(fmt.Sprintf("mirror job: %s", errMsg))
+		return
+	}
+
+	// guest is writing to the target disk now
+	targetPath := s.targetDisk.GetPath()
+	disks, _ := s.Desc.GetArray("disks")
+	for _, d := range disks {
+		if id, _ := d.GetString("disk_id"); id == s.disk.GetId() {
+			diskDesc := d.(*jsonutils.JSONDict)
+			diskDesc.Set("path", jsonutils.NewString(targetPath))
+			diskDesc.Set("format", jsonutils.NewString(s.targetFormat))
+			diskDesc.Set("storage_id", jsonutils.NewString(s.targetStorage.GetId()))
+			break
+		}
+	}
+	if err := s.SaveDesc(s.Desc); err != nil {
+		log.Errorf("Guest %s save desc: %s", s.GetName(), err)
+	}
+	if lockDisk, ok := s.disk.(storageman.IClusterLockDisk); ok {
+		if err := lockDisk.ReleaseLock(); err != nil {
+			log.Errorf("Release lock of source disk %s: %s", s.disk.GetPath(), err)
+		}
+	}
+	hostutils.TaskComplete(s.ctx, jsonutils.Marshal(&hostapi.GuestChangeDiskStorageResponse{
+		DiskId:     s.disk.GetId(),
+		StorageId:  s.targetStorage.GetId(),
+		DiskPath:   targetPath,
+		DiskFormat: s.targetFormat,
+	}))
+}
+
+func (s *SGuestChangeDiskStorageTask) onMirrorFailed(reason string) {
+	if s.targetDisk != nil {
+		if lockDisk, ok := s.targetDisk.(storageman.IClusterLockDisk); ok {
+			lockDisk.ReleaseLock()
+		}
+		if _, err := s.targetDisk.Delete(s.ctx, nil); err != nil {
+			log.Errorf("Delete target disk %s: %s", s.targetDisk.GetPath(), err)
+		}
+	}
+	s.taskFailed(reason)
+}
+
+func (s *SGuestChangeDiskStorageTask) taskFailed(reason string) {
+	log.Errorf("SGuestChangeDiskStorageTask error: %s", reason)
+	hostutils.TaskFailed(s.ctx, reason)
+}
+
 /**
  *  GuestOnlineResizeDiskTask
 **/
@@ -1286,6 +1588,10 @@ func (task *SGuestHotplugCpuMemTask) onGetSlotIndex(index int) {
 		"id":   fmt.Sprintf("mem%d", *task.memSlotNewIndex),
 		"size": fmt.Sprintf("%dM", task.addMemSize),
 	}
+	if node := task.GetNumaNode(); node != nil {
+		params["host-nodes"] = strconv.Itoa(node.NodeId)
+		params["policy"] = "bind"
+	}
 	task.Monitor.ObjectAdd("memory-backend-ram", params, task.onAddMemObject)
 }
 
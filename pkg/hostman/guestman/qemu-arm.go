@@ -288,7 +288,9 @@ func (s *SKVMGuestInstance) generateArmStartScript(data *jsonutils.JSONDict) (st
 		cmd += fmt.Sprintf(" -%s %s", k, v.String())
 	}
 
-	// cmd += s.getQgaDesc()
+	// no virtio-serial bus is created by default on virt machine
+	cmd += fmt.Sprintf(" -device virtio-serial-pci,id=qga-serial0,bus=%s", s.GetPciBus())
+	cmd += s.getQgaDesc()
 	if fileutils2.Exists("/dev/random") {
 		cmd += " -object rng-random,filename=/dev/random,id=rng0"
 		cmd += " -device virtio-rng-pci,rng=rng0,max-bytes=1024,period=1000"
@@ -22,6 +22,7 @@ import (
 	"path"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"yunion.io/x/jsonutils"
@@ -32,6 +33,7 @@ import (
 	"yunion.io/x/pkg/utils"
 
 	"yunion.io/x/onecloud/pkg/apis/compute"
+	hostapi "yunion.io/x/onecloud/pkg/apis/host"
 	"yunion.io/x/onecloud/pkg/appctx"
 	deployapi "yunion.io/x/onecloud/pkg/hostman/hostdeployer/apis"
 	"yunion.io/x/onecloud/pkg/hostman/hostdeployer/deployclient"
@@ -40,6 +42,7 @@ import (
 	"yunion.io/x/onecloud/pkg/hostman/monitor"
 	"yunion.io/x/onecloud/pkg/hostman/options"
 	"yunion.io/x/onecloud/pkg/hostman/storageman"
+	"yunion.io/x/onecloud/pkg/hostman/storageman/remotefile"
 	"yunion.io/x/onecloud/pkg/mcclient/modules"
 	"yunion.io/x/onecloud/pkg/util/cgrouputils"
 	"yunion.io/x/onecloud/pkg/util/fileutils2"
@@ -70,6 +73,16 @@ type SKVMGuestInstance struct {
 	startupTask *SGuestResumeTask
 	stopping    bool
 	syncMeta    *jsonutils.JSONDict
+
+	// device => callback of running drive-backup job
+	diskBackupJobs sync.Map
+	// device => task moving the disk to another storage
+	diskMirrorJobs sync.Map
+
+	guestAgent     *monitor.QemuGuestAgent
+	guestAgentLock sync.Mutex
+	// thaws guest filesystems if nobody does it in time
+	fsThawTimer *time.Timer
 }
 
 func NewKVMGuestInstance(id string, manager *SGuestManager) *SKVMGuestInstance {
@@ -114,6 +127,36 @@ func (s *SKVMGuestInstance) PrepareDir() error {
 	return nil
 }
 
+// fetchGuestStateFiles download tpm state and uefi nvram of guest from source host,
+// these files is persisted in server dir instead of guest disks.  Only for cold
+// migration, guest must not be running on source host
+func (s *SKVMGuestInstance) fetchGuestStateFiles(ctx context.Context, serverUrl string) error {
+	tarPath := s.HomeDir() + ".tar"
+	tmpDir := s.HomeDir() + ".state"
+	defer procutils.NewCommand("rm", "-rf", tarPath, tmpDir).Run()
+
+	remoteFile := remotefile.NewRemoteFile(ctx, serverUrl, tarPath, false, "", -1, nil, "", "")
+	if !remoteFile.Fetch() {
+		return errors.Errorf("fetch server dir from %s failed", serverUrl)
+	}
+	if output, err := procutils.NewCommand("mkdir", "-p", tmpDir).Output(); err != nil {
+		return errors.Wrapf(err, "mkdir %s failed: %s", tmpDir, output)
+	}
+	if output, err := procutils.NewCommand("tar", "-xf", tarPath, "-C", tmpDir).Output(); err != nil {
+		return errors.Wrapf(err, "untar server dir failed: %s", output)
+	}
+	for _, f := range []string{path.Base(s.getTpmStateDir()), path.Base(s.getNvramPath())} {
+		src := path.Join(tmpDir, s.Id, f)
+		if !fileutils2.Exists(src) {
+			continue
+		}
+		if output, err := procutils.NewCommand("cp", "-rf", src, s.HomeDir()).Output(); err != nil {
+			return errors.Wrapf(err, "copy %s failed: %s", f, output)
+		}
+	}
+	return nil
+}
+
 func (s *SKVMGuestInstance) GetPidFilePath() string {
 	return path.Join(s.HomeDir(), "pid")
 }
@@ -242,6 +285,16 @@ func (s *SKVMGuestInstance) asyncScriptStart(ctx context.Context, params interfa
 
 	hostbridge.CleanDeletedPorts(options.HostOptions.BridgeDriver)
 
+	// incoming migration shares disks with the source guest
+	if err := s.acquireDiskLocks(jsonutils.QueryBoolean(data, "need_migrate", false)); err != nil {
+		log.Errorf("Acquire disk locks of server %s failed: %s", s.GetName(), err)
+		if ctx != nil && len(appctx.AppContextTaskId(ctx)) >= 0 {
+			hostutils.TaskFailed(ctx, fmt.Sprintf("Acquire disk locks failed: %s", err))
+		}
+		s.SyncStatus()
+		return nil, err
+	}
+
 	time.Sleep(100 * time.Millisecond)
 	var isStarted, tried = false, 0
 	var err error
@@ -464,6 +517,11 @@ func (s *SKVMGuestInstance) StartMonitor(ctx context.Context) {
 
 func (s *SKVMGuestInstance) onReceiveQMPEvent(event *monitor.Event) {
 	switch {
+	case event.Event == `"BLOCK_JOB_READY"` && s.isDiskMirrorJob(event):
+		device, _ := event.Data["device"].(string)
+		if task, ok := s.diskMirrorJobs.Load(device); ok {
+			task.(*SGuestChangeDiskStorageTask).onMirrorReady()
+		}
 	case event.Event == `"BLOCK_JOB_READY"` && s.IsMaster():
 		if itype, ok := event.Data["type"]; ok {
 			stype, _ := itype.(string)
@@ -480,7 +538,28 @@ func (s *SKVMGuestInstance) onReceiveQMPEvent(event *monitor.Event) {
 			}
 		}
 	case event.Event == `"BLOCK_JOB_ERROR"`:
-		s.SyncMirrorJobFailed("BLOCK_JOB_ERROR")
+		if device, _ := event.Data["device"].(string); s.isDiskBackupJob(device) || s.isDiskMirrorJob(event) {
+			// backup and storage mirror job report the error on BLOCK_JOB_COMPLETED
+			log.Errorf("block job of %s got io error", device)
+		} else {
+			s.SyncMirrorJobFailed("BLOCK_JOB_ERROR")
+		}
+	case utils.IsInStringArray(event.Event, []string{`"BLOCK_JOB_COMPLETED"`, `"BLOCK_JOB_CANCELLED"`}):
+		if stype, _ := event.Data["type"].(string); stype == "backup" {
+			device, _ := event.Data["device"].(string)
+			errMsg, _ := event.Data["error"].(string)
+			if event.Event == `"BLOCK_JOB_CANCELLED"` {
+				errMsg = "job cancelled"
+			}
+			s.onDiskBackupJobFinished(device, errMsg)
+		} else if s.isDiskMirrorJob(event) {
+			device, _ := event.Data["device"].(string)
+			errMsg, _ := event.Data["error"].(string)
+			if event.Event == `"BLOCK_JOB_CANCELLED"` {
+				errMsg = "job cancelled"
+			}
+			s.onDiskMirrorJobFinished(device, errMsg)
+		}
 	case event.Event == `"GUEST_PANICKED"`:
 		// qemu runc state event source qemu/src/qapi/run-state.json
 		params := jsonutils.NewDict()
@@ -783,11 +862,16 @@ func (s *SKVMGuestInstance) CheckBlockOrRunning(jobs int) {
 }
 
 func (s *SKVMGuestInstance) SaveDesc(desc jsonutils.JSONObject) error {
-	var ok bool
-	s.Desc, ok = desc.(*jsonutils.JSONDict)
+	newDesc, ok := desc.(*jsonutils.JSONDict)
 	if !ok {
 		return fmt.Errorf("Unknown desc format, not JSONDict")
 	}
+	if s.Desc != nil && s.Desc.Contains("numa_node") && !newDesc.Contains("numa_node") {
+		// numa binding is decided by host, keep it across desc from region
+		numaNode, _ := s.Desc.Get("numa_node")
+		newDesc.Set("numa_node", numaNode)
+	}
+	s.Desc = newDesc
 	{
 		// fill in ovn vpc nic bridge field
 		nics, _ := s.Desc.GetArray("nics")
@@ -892,6 +976,218 @@ func (s *SKVMGuestInstance) ExitCleanup(clear bool) {
 		s.Monitor.Disconnect()
 		s.Monitor = nil
 	}
+	if clear {
+		s.stopVirtiofsd()
+	}
+	s.guestAgentLock.Lock()
+	if s.fsThawTimer != nil {
+		s.fsThawTimer.Stop()
+		s.fsThawTimer = nil
+	}
+	if s.guestAgent != nil {
+		s.guestAgent.Close()
+		s.guestAgent = nil
+	}
+	s.guestAgentLock.Unlock()
+}
+
+// GuestAgentFsFreeze freeze guest filesystems, they are thawed
+// automatically if GuestAgentFsThaw is not called within
+// GuestFsFreezeMaxSeconds, so that a lost thaw request never hangs the guest
+func (s *SKVMGuestInstance) GuestAgentFsFreeze() (int, error) {
+	qga, err := s.GetGuestAgent()
+	if err != nil {
+		return 0, err
+	}
+	// fail fast if qemu-ga is not running in guest, fsfreeze waits much longer
+	if err := qga.Ping(); err != nil {
+		return 0, errors.Wrap(err, "guest agent not available")
+	}
+	count, err := qga.FsFreeze()
+	if err != nil {
+		return 0, err
+	}
+	maxDuration := time.Duration(options.HostOptions.GuestFsFreezeMaxSeconds) * time.Second
+	s.guestAgentLock.Lock()
+	defer s.guestAgentLock.Unlock()
+	if s.fsThawTimer != nil {
+		s.fsThawTimer.Stop()
+	}
+	s.fsThawTimer = time.AfterFunc(maxDuration, func() {
+		log.Warningf("guest %s filesystems frozen longer than %s, thaw them", s.GetName(), maxDuration)
+		if _, err := qga.FsThaw(); err != nil {
+			log.Errorf("guest %s auto thaw: %s", s.GetName(), err)
+		}
+	})
+	return count, nil
+}
+
+func (s *SKVMGuestInstance) GuestAgentFsThaw() (int, error) {
+	qga, err := s.GetGuestAgent()
+	if err != nil {
+		return 0, err
+	}
+	s.guestAgentLock.Lock()
+	if s.fsThawTimer != nil {
+		s.fsThawTimer.Stop()
+		s.fsThawTimer = nil
+	}
+	s.guestAgentLock.Unlock()
+	return qga.FsThaw()
+}
+
+// GetGuestAgent returns client of qemu-ga running inside guest,
+// qemu-ga must be installed and started in guest os
+func (s *SKVMGuestInstance) GetGuestAgent() (*monitor.QemuGuestAgent, error) {
+	if !s.IsRunning() {
+		return nil, errors.Errorf("guest %s not running", s.GetName())
+	}
+	s.guestAgentLock.Lock()
+	defer s.guestAgentLock.Unlock()
+	if s.guestAgent == nil {
+		s.guestAgent = monitor.NewQemuGuestAgent(s.Id, s.getQgaSocketPath())
+	}
+	return s.guestAgent, nil
+}
+
+// stopVirtiofsd kill virtiofsd left behind, normally they exit along with qemu
+func (s *SKVMGuestInstance) stopVirtiofsd() {
+	for _, share := range s.getVirtiofsShares() {
+		tag, _ := share.GetString("tag")
+		pidFile := s.getVirtiofsdPidPath(tag)
+		if !fileutils2.Exists(pidFile) {
+			continue
+		}
+		pid, err := fileutils2.FileGetContents(pidFile)
+		if err != nil {
+			log.Errorf("read %s failed: %s", pidFile, err)
+			continue
+		}
+		pid = strings.TrimSpace(pid)
+		if len(pid) > 0 && fileutils2.Exists(path.Join("/proc", pid)) {
+			if output, err := procutils.NewCommand("kill", pid).Output(); err != nil {
+				log.Errorf("kill virtiofsd %s failed: %s, %s", pid, err, output)
+			}
+		}
+		procutils.NewCommand("rm", "-f", pidFile, s.getVirtiofsSockPath(tag)).Run()
+	}
+}
+
+// descNumaNodeUnbound marks guest memory declared by a memory backend object
+// without host node binding, it happens when a bound guest migrated to a host
+// that has no node fitting the guest
+const descNumaNodeUnbound = -1
+
+// getDescNumaNode returns the numa node kept in guest desc, ok is false if
+// guest memory is not declared by a memory backend object for numa binding
+func (s *SKVMGuestInstance) getDescNumaNode() (int, bool) {
+	if s.Desc == nil || !s.Desc.Contains("numa_node") {
+		return 0, false
+	}
+	nodeId, err := s.Desc.Int("numa_node")
+	if err != nil {
+		return 0, false
+	}
+	return int(nodeId), true
+}
+
+func (s *SKVMGuestInstance) setDescNumaNode(nodeId int) {
+	s.Desc.Set("numa_node", jsonutils.NewInt(int64(nodeId)))
+	s.SaveDesc(s.Desc)
+}
+
+func (s *SKVMGuestInstance) removeDescNumaNode() {
+	if s.Desc.Contains("numa_node") {
+		s.Desc.Remove("numa_node")
+		s.SaveDesc(s.Desc)
+	}
+}
+
+// getNumaInfo returns host numa nodes with cpus pinned by other guests,
+// nil if numa binding is disabled or host has only one node
+func (s *SKVMGuestInstance) getNumaInfo() *compute.HostNumaInfo {
+	if !options.HostOptions.EnableNumaBinding {
+		return nil
+	}
+	info, err := s.manager.host.GetNumaInfo()
+	if err != nil {
+		log.Errorf("guest %s get numa info: %s", s.GetName(), err)
+		return nil
+	}
+	if len(info.Nodes) < 2 {
+		return nil
+	}
+	boundCpus := s.manager.getNumaBoundCpus(s.Id)
+	for i := range info.Nodes {
+		info.Nodes[i].BoundCpuCount = boundCpus[info.Nodes[i].NodeId]
+	}
+	return info
+}
+
+// allocNumaNode picks the numa node holding the whole guest on starting,
+// the choice is kept in guest desc, so restarts reuse the node and migration
+// destinations declare guest memory the same way as the source. It returns
+// the node to bind and whether guest memory needs a memory backend object
+func (s *SKVMGuestInstance) allocNumaNode(cpu, mem int64, isMigrate bool) (*compute.HostNumaNode, bool) {
+	nodeId, bound := s.getDescNumaNode()
+	if isMigrate && !bound {
+		// source guest memory has no memory backend, nor could destination
+		return nil, false
+	}
+	info := s.getNumaInfo()
+	if info == nil {
+		if isMigrate {
+			s.setDescNumaNode(descNumaNodeUnbound)
+			return nil, true
+		}
+		s.removeDescNumaNode()
+		return nil, false
+	}
+	if bound && nodeId >= 0 {
+		if node := info.GetNode(nodeId); node != nil && node.Fits(int(cpu), int(mem)) {
+			return node, true
+		}
+	}
+	if node := info.FitNode(int(cpu), int(mem)); node != nil {
+		s.setDescNumaNode(node.NodeId)
+		return node, true
+	}
+	log.Infof("guest %s cpu %d mem %dM can't fit in one numa node", s.GetName(), cpu, mem)
+	if isMigrate {
+		s.setDescNumaNode(descNumaNodeUnbound)
+		return nil, true
+	}
+	s.removeDescNumaNode()
+	return nil, false
+}
+
+// GetNumaNode returns the numa node guest bound to, nil if not bound
+func (s *SKVMGuestInstance) GetNumaNode() *compute.HostNumaNode {
+	nodeId, ok := s.getDescNumaNode()
+	if !ok || nodeId < 0 {
+		return nil
+	}
+	info, err := s.manager.host.GetNumaInfo()
+	if err != nil {
+		log.Errorf("guest %s get numa info: %s", s.GetName(), err)
+		return nil
+	}
+	return info.GetNode(nodeId)
+}
+
+func (s *SKVMGuestInstance) setCgroupCpuset() {
+	node := s.GetNumaNode()
+	if node == nil {
+		return
+	}
+	cpus := make([]string, len(node.Cpus))
+	for i := range node.Cpus {
+		cpus[i] = strconv.Itoa(node.Cpus[i])
+	}
+	task := cgrouputils.NewCGroupCPUSetTask(strconv.Itoa(s.cgroupPid), 0, strings.Join(cpus, ","))
+	if !task.SetTask() {
+		log.Errorf("guest %s bind cpuset of numa node %d failed", s.GetName(), node.NodeId)
+	}
 }
 
 func (s *SKVMGuestInstance) CleanupCpuset() {
@@ -945,6 +1241,7 @@ func (s *SKVMGuestInstance) delFlatFiles(ctx context.Context) error {
 }
 
 func (s *SKVMGuestInstance) Delete(ctx context.Context, migrated bool) error {
+	s.releaseDiskLocks()
 	if err := s.delTmpDisks(ctx, migrated); err != nil {
 		return err
 	}
@@ -961,12 +1258,73 @@ func (s *SKVMGuestInstance) Delete(ctx context.Context, migrated bool) error {
 func (s *SKVMGuestInstance) Stop() bool {
 	s.ExitCleanup(true)
 	if s.scriptStop() {
+		s.releaseDiskLocks()
 		return true
 	} else {
 		return false
 	}
 }
 
+// acquireDiskLocks activate disks on cluster shared storage,
+// so the same disk can't be opened by guest on other host
+func (s *SKVMGuestInstance) acquireDiskLocks(shared bool) error {
+	disks, _ := s.Desc.GetArray("disks")
+	for _, disk := range disks {
+		diskPath, _ := disk.GetString("path")
+		d := storageman.GetManager().GetDiskByPath(diskPath)
+		if d == nil {
+			continue
+		}
+		if lockDisk, ok := d.(storageman.IClusterLockDisk); ok {
+			if err := lockDisk.AcquireLock(shared); err != nil {
+				return errors.Wrapf(err, "acquire lock of disk %s", diskPath)
+			}
+		}
+	}
+	return nil
+}
+
+// reacquireExclusiveDiskLocks turns the shared locks taken for live
+// migration back to exclusive.  The peer guest of the migration holds the
+// shared lock until it is stopped, so it retries until the peer is gone
+func (s *SKVMGuestInstance) reacquireExclusiveDiskLocks() {
+	const (
+		interval = 5 * time.Second
+		timeout  = 10 * time.Minute
+	)
+	for start := time.Now(); ; time.Sleep(interval) {
+		if !s.IsRunning() {
+			// locks are released on stop and taken again on next start
+			return
+		}
+		err := s.acquireDiskLocks(false)
+		if err == nil {
+			log.Infof("guest %s disk locks turned back to exclusive", s.GetName())
+			return
+		}
+		if time.Now().Sub(start) > timeout {
+			log.Errorf("guest %s reacquire exclusive disk locks: %s", s.GetName(), err)
+			return
+		}
+	}
+}
+
+func (s *SKVMGuestInstance) releaseDiskLocks() {
+	disks, _ := s.Desc.GetArray("disks")
+	for _, disk := range disks {
+		diskPath, _ := disk.GetString("path")
+		d := storageman.GetManager().GetDiskByPath(diskPath)
+		if d == nil {
+			continue
+		}
+		if lockDisk, ok := d.(storageman.IClusterLockDisk); ok {
+			if err := lockDisk.ReleaseLock(); err != nil {
+				log.Errorf("release lock of disk %s: %s", diskPath, err)
+			}
+		}
+	}
+}
+
 func (s *SKVMGuestInstance) scriptStart() error {
 	output, err := procutils.NewRemoteCommandAsFarAsPossible("bash", s.GetStartScriptPath()).Output()
 	if err != nil {
@@ -1213,6 +1571,7 @@ func (s *SKVMGuestInstance) SetCgroup() {
 	s.cgroupPid = s.GetPid()
 	s.setCgroupIo()
 	s.setCgroupCpu()
+	s.setCgroupCpuset()
 }
 
 func (s *SKVMGuestInstance) setCgroupIo() {
@@ -1469,6 +1828,69 @@ func (s *SKVMGuestInstance) ExecDiskSnapshotTask(
 	}
 }
 
+func (s *SKVMGuestInstance) ExecDiskBackupTask(
+	ctx context.Context, disk storageman.IDisk, input *hostapi.GuestDiskBackupRequest,
+) (jsonutils.JSONObject, error) {
+	if s.IsRunning() {
+		if !s.isLiveSnapshotEnabled() {
+			return nil, fmt.Errorf("Guest dosen't support live backup")
+		}
+		task := NewGuestDiskBackupTask(ctx, s, disk, input)
+		task.Start()
+		return nil, nil
+	}
+	resp, err := storageman.OfflineDiskBackup(disk, input)
+	if err != nil {
+		return nil, err
+	}
+	return jsonutils.Marshal(resp), nil
+}
+
+func (s *SKVMGuestInstance) isDiskBackupJob(device string) bool {
+	_, ok := s.diskBackupJobs.Load(device)
+	return ok
+}
+
+func (s *SKVMGuestInstance) onDiskBackupJobFinished(device, errMsg string) {
+	cb, ok := s.diskBackupJobs.Load(device)
+	if !ok {
+		return
+	}
+	s.diskBackupJobs.Delete(device)
+	// upload may take long, don't block qmp event loop
+	go cb.(func(string))(errMsg)
+}
+
+func (s *SKVMGuestInstance) ExecChangeDiskStorageTask(
+	ctx context.Context, disk storageman.IDisk, targetStorage storageman.IStorage,
+) (jsonutils.JSONObject, error) {
+	if !s.IsRunning() {
+		return nil, fmt.Errorf("Guest not running")
+	}
+	task := NewGuestChangeDiskStorageTask(ctx, s, disk, targetStorage)
+	task.Start()
+	return nil, nil
+}
+
+func (s *SKVMGuestInstance) isDiskMirrorJob(event *monitor.Event) bool {
+	if stype, _ := event.Data["type"].(string); stype != "mirror" {
+		return false
+	}
+	device, _ := event.Data["device"].(string)
+	_, ok := s.diskMirrorJobs.Load(device)
+	return ok
+}
+
+func (s *SKVMGuestInstance) onDiskMirrorJobFinished(device, errMsg string) {
+	task, ok := s.diskMirrorJobs.Load(device)
+	if !ok {
+		return
+	}
+	s.diskMirrorJobs.Delete(device)
+	// don't block qmp event loop
+	go task.(*SGuestChangeDiskStorageTask).onMirrorJobFinished(errMsg)
+}
+
 func (s *SKVMGuestInstance) StaticSaveSnapshot(
 	ctx context.Context, disk storageman.IDisk, snapshotId string,
 ) (jsonutils.JSONObject, error) {
@@ -1521,6 +1943,13 @@ func (s *SKVMGuestInstance) PrepareMigrate(liveMigrage bool) (*jsonutils.JSONDic
 		if disk.Contains("path") {
 			diskPath, _ := disk.GetString("path")
 			d := storageman.GetManager().GetDiskByPath(diskPath)
+			if lockDisk, ok := d.(storageman.IClusterLockDisk); ok && liveMigrage {
+				// convert to shared lock so that the destination guest can open disk,
+				// the lock turns back to exclusive when migration completes or fails
+				if err := lockDisk.AcquireLock(true); err != nil {
+					return nil, err
+				}
+			}
 			if d.GetType() == compute.STORAGE_LOCAL {
 				back, err := d.PrepareMigrate(liveMigrage)
 				if err != nil {
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"net"
 	"path"
+	"strings"
 	"time"
 
 	"github.com/mdlayher/arp"
@@ -27,6 +28,7 @@ import (
 	"yunion.io/x/log"
 	"yunion.io/x/pkg/utils"
 
+	"yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/hostman/options"
 	"yunion.io/x/onecloud/pkg/hostman/storageman"
 	"yunion.io/x/onecloud/pkg/util/fileutils2"
@@ -109,6 +111,128 @@ func (s *SKVMGuestInstance) isQ35() bool {
 	return s.getMachine() == "q35"
 }
 
+func (s *SKVMGuestInstance) isVtpmEnabled() bool {
+	return jsonutils.QueryBoolean(s.Desc, "vtpm", false)
+}
+
+func (s *SKVMGuestInstance) isSecureBoot() bool {
+	return jsonutils.QueryBoolean(s.Desc, "secure_boot", false)
+}
+
+func (s *SKVMGuestInstance) getTpmStateDir() string {
+	return path.Join(s.HomeDir(), "tpm")
+}
+
+func (s *SKVMGuestInstance) getSwtpmSockPath() string {
+	return path.Join(s.HomeDir(), "swtpm.sock")
+}
+
+func (s *SKVMGuestInstance) getNvramPath() string {
+	return path.Join(s.HomeDir(), "OVMF_VARS.fd")
+}
+
+func (s *SKVMGuestInstance) getSwtpmScripts() string {
+	cmd := fmt.Sprintf("mkdir -p %s\n", s.getTpmStateDir())
+	cmd += fmt.Sprintf("%s socket --tpm2 --tpmstate dir=%s", options.HostOptions.SwtpmPath, s.getTpmStateDir())
+	cmd += fmt.Sprintf(" --ctrl type=unixio,path=%s", s.getSwtpmSockPath())
+	cmd += fmt.Sprintf(" --pid file=%s", path.Join(s.HomeDir(), "swtpm.pid"))
+	cmd += " --terminate --daemon\n"
+	return cmd
+}
+
+func (s *SKVMGuestInstance) getVirtiofsShares() []jsonutils.JSONObject {
+	shares, _ := s.Desc.GetArray("virtiofs")
+	return shares
+}
+
+func (s *SKVMGuestInstance) getVirtiofsSockPath(tag string) string {
+	return path.Join(s.HomeDir(), fmt.Sprintf("virtiofs-%s.sock", tag))
+}
+
+func (s *SKVMGuestInstance) getVirtiofsdPidPath(tag string) string {
+	return path.Join(s.HomeDir(), fmt.Sprintf("virtiofsd-%s.pid", tag))
+}
+
+// shellQuote quotes s as a single word of bash, nothing inside
+// single quotes is expanded
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// getVirtiofsdScripts starts one virtiofsd per share before qemu,
+// virtiofsd exits by itself after qemu closed the vhost-user socket
+func (s *SKVMGuestInstance) getVirtiofsdScripts() string {
+	cmd := ""
+	for _, share := range s.getVirtiofsShares() {
+		tag, _ := share.GetString("tag")
+		sharePath, _ := share.GetString("path")
+		sock := s.getVirtiofsSockPath(tag)
+		cmd += fmt.Sprintf("mkdir -p %s\n", shellQuote(sharePath))
+		cmd += fmt.Sprintf("rm -f %s\n", sock)
+		cmd += fmt.Sprintf("nohup %s --socket-path=%s --shared-dir=%s --cache=auto",
+			options.HostOptions.VirtiofsdPath, sock, shellQuote(sharePath))
+		if jsonutils.QueryBoolean(share, "readonly", false) {
+			cmd += " --readonly"
+		}
+		cmd += " > /dev/null 2>&1 &\n"
+		cmd += fmt.Sprintf("echo $! > %s\n", s.getVirtiofsdPidPath(tag))
+		cmd += fmt.Sprintf("for i in $(seq 1 10); do [ -S %s ] && break; sleep 1; done\n", sock)
+	}
+	return cmd
+}
+
+// getMemoryBackendDesc backs guest memory with an explicit memory object,
+// vhost-user devices need access to whole guest memory and
+// numa binding needs host-nodes policy of the memory object
+func (s *SKVMGuestInstance) getMemoryBackendDesc(mem int64, uuid string, shared bool, numaNode *compute.HostNumaNode) string {
+	var cmd string
+	if s.manager.host.IsHugepagesEnabled() {
+		cmd = fmt.Sprintf(" -object memory-backend-file,id=mem,size=%dM,mem-path=/dev/hugepages/%s,prealloc=on", mem, uuid)
+		if shared {
+			cmd += ",share=on"
+		}
+	} else if shared {
+		cmd = fmt.Sprintf(" -object memory-backend-memfd,id=mem,size=%dM,share=on", mem)
+	} else {
+		cmd = fmt.Sprintf(" -object memory-backend-ram,id=mem,size=%dM", mem)
+	}
+	if numaNode != nil {
+		cmd += fmt.Sprintf(",host-nodes=%d,policy=bind", numaNode.NodeId)
+	}
+	cmd += " -numa node,memdev=mem"
+	return cmd
+}
+
+func (s *SKVMGuestInstance) getVirtiofsDesc() string {
+	cmd := ""
+	for i, share := range s.getVirtiofsShares() {
+		tag, _ := share.GetString("tag")
+		cmd += fmt.Sprintf(" -chardev socket,id=char-vfs%d,path=%s", i, s.getVirtiofsSockPath(tag))
+		cmd += fmt.Sprintf(" -device vhost-user-fs-pci,queue-size=1024,chardev=char-vfs%d,tag=%s", i, tag)
+	}
+	return cmd
+}
+
+func (s *SKVMGuestInstance) getVtpmDesc() string {
+	cmd := fmt.Sprintf(" -chardev socket,id=chrtpm,path=%s", s.getSwtpmSockPath())
+	cmd += " -tpmdev emulator,id=tpm0,chardev=chrtpm"
+	cmd += " -device tpm-crb,tpmdev=tpm0"
+	return cmd
+}
+
+// secure boot needs writable nvram of the guest besides the code image
+func (s *SKVMGuestInstance) getFirmwareDesc() string {
+	cmd := ""
+	if s.isSecureBoot() {
+		cmd += fmt.Sprintf(" -drive if=pflash,format=raw,readonly=on,file=%s",
+			options.HostOptions.OvmfSecureBootCodePath)
+		cmd += fmt.Sprintf(" -drive if=pflash,format=raw,file=%s", s.getNvramPath())
+	} else if s.getBios() == "UEFI" {
+		cmd += fmt.Sprintf(" -bios %s", options.HostOptions.OvmfPath)
+	}
+	return cmd
+}
+
 func (s *SKVMGuestInstance) GetVdiProtocol() string {
 	vdi, err := s.Desc.GetString("vdi")
 	if err != nil {
@@ -303,9 +427,13 @@ func (s *SKVMGuestInstance) getVnicDesc(nic jsonutils.JSONObject) string {
 	return cmd
 }
 
+func (s *SKVMGuestInstance) getQgaSocketPath() string {
+	return path.Join(s.HomeDir(), "qga.sock")
+}
+
 func (s *SKVMGuestInstance) getQgaDesc() string {
 	cmd := " -chardev socket,path="
-	cmd += path.Join(s.HomeDir(), "qga.sock")
+	cmd += s.getQgaSocketPath()
 	cmd += ",server,nowait,id=qga0"
 	cmd += " -device virtserialport,chardev=qga0,name=org.qemu.guest_agent.0"
 	return cmd
@@ -331,7 +459,7 @@ func (s *SKVMGuestInstance) _generateStartScript(data *jsonutils.JSONDict) (stri
 		cmd      = ""
 	)
 
-	if osname == OS_NAME_MACOS {
+	if osname == OS_NAME_MACOS || s.isSecureBoot() {
 		s.Desc.Set("machine", jsonutils.NewString("q35"))
 		s.Desc.Set("bios", jsonutils.NewString("UEFI"))
 	}
@@ -380,6 +508,17 @@ func (s *SKVMGuestInstance) _generateStartScript(data *jsonutils.JSONDict) (stri
 		cmd += d.GetDiskSetupScripts(int(diskIndex))
 	}
 
+	if s.isSecureBoot() {
+		// nvram is per guest, init from the template with enrolled keys
+		cmd += fmt.Sprintf("if [ ! -f %s ]; then\n", s.getNvramPath())
+		cmd += fmt.Sprintf("    cp %s %s\n", options.HostOptions.OvmfSecureBootVarsPath, s.getNvramPath())
+		cmd += "fi\n"
+	}
+	if s.isVtpmEnabled() {
+		cmd += s.getSwtpmScripts()
+	}
+	cmd += s.getVirtiofsdScripts()
+
 	// cmd += fmt.Sprintf("STATE_FILE=`ls -d %s* | head -n 1`\n", s.getStateFilePathRootPrefix())
 	cmd += fmt.Sprintf("PID_FILE=%s\n", s.GetPidFilePath())
 
@@ -482,6 +621,10 @@ function nic_mtu() {
 	cmd += " -no-kvm-pit-reinjection"
 	cmd += " -global kvm-pit.lost_tick_policy=discard"
 	cmd += fmt.Sprintf(" -machine %s,accel=%s", s.getMachine(), accel)
+	if s.isSecureBoot() {
+		cmd += ",smm=on"
+		cmd += " -global driver=cfi.pflash01,property=secure,value=on"
+	}
 	cmd += " -k en-us"
 	// #cmd += " -g 800x600"
 	cmd += fmt.Sprintf(" -smp %d,maxcpus=255", cpu)
@@ -489,7 +632,10 @@ function nic_mtu() {
 	// #cmd += fmt.Sprintf(" -uuid %s", self.desc["uuid"])
 	cmd += fmt.Sprintf(" -m %dM,slots=4,maxmem=524288M", mem)
 
-	if s.manager.host.IsHugepagesEnabled() {
+	numaNode, memBackend := s.allocNumaNode(cpu, mem, jsonutils.QueryBoolean(data, "need_migrate", false))
+	if len(s.getVirtiofsShares()) > 0 || memBackend {
+		cmd += s.getMemoryBackendDesc(mem, uuid, len(s.getVirtiofsShares()) > 0, numaNode)
+	} else if s.manager.host.IsHugepagesEnabled() {
 		cmd += fmt.Sprintf(" -mem-prealloc -mem-path %s", fmt.Sprintf("/dev/hugepages/%s", uuid))
 	}
 
@@ -500,10 +646,14 @@ function nic_mtu() {
 		cmd += ",menu=on"
 	}
 
-	if s.getBios() == "UEFI" {
-		cmd += fmt.Sprintf(" -bios %s", options.HostOptions.OvmfPath)
+	cmd += s.getFirmwareDesc()
+
+	if s.isVtpmEnabled() {
+		cmd += s.getVtpmDesc()
 	}
 
+	cmd += s.getVirtiofsDesc()
+
 	if osname == OS_NAME_MACOS {
 		cmd += " -device isa-applesmc,osk=ourhardworkbythesewordsguardedpleasedontsteal(c)AppleComputerInc"
 		for i := 0; i < len(disks); i++ {
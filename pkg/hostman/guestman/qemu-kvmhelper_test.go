@@ -0,0 +1,91 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"strings"
+	"testing"
+
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/hostman/options"
+)
+
+func newTestGuest(desc map[string]interface{}) *SKVMGuestInstance {
+	guest := NewKVMGuestInstance("guest-id", &SGuestManager{ServersPath: "/opt/cloud/workspace/servers"})
+	guest.Desc = jsonutils.Marshal(desc).(*jsonutils.JSONDict)
+	return guest
+}
+
+func TestGetVtpmDesc(t *testing.T) {
+	guest := newTestGuest(map[string]interface{}{"vtpm": true})
+	if !guest.isVtpmEnabled() {
+		t.Fatalf("vtpm should be enabled")
+	}
+	want := " -chardev socket,id=chrtpm,path=/opt/cloud/workspace/servers/guest-id/swtpm.sock" +
+		" -tpmdev emulator,id=tpm0,chardev=chrtpm" +
+		" -device tpm-crb,tpmdev=tpm0"
+	if got := guest.getVtpmDesc(); got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	options.HostOptions.SwtpmPath = "/usr/bin/swtpm"
+	script := guest.getSwtpmScripts()
+	for _, want := range []string{
+		"mkdir -p /opt/cloud/workspace/servers/guest-id/tpm\n",
+		"/usr/bin/swtpm socket --tpm2 --tpmstate dir=/opt/cloud/workspace/servers/guest-id/tpm",
+		" --ctrl type=unixio,path=/opt/cloud/workspace/servers/guest-id/swtpm.sock",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("swtpm script %q should contain %q", script, want)
+		}
+	}
+
+	if newTestGuest(map[string]interface{}{}).isVtpmEnabled() {
+		t.Errorf("vtpm should be disabled by default")
+	}
+}
+
+func TestGetFirmwareDesc(t *testing.T) {
+	options.HostOptions.OvmfPath = "/opt/cloud/contrib/OVMF.fd"
+	options.HostOptions.OvmfSecureBootCodePath = "/opt/cloud/contrib/OVMF_CODE.secboot.fd"
+	cases := []struct {
+		name string
+		desc map[string]interface{}
+		want string
+	}{
+		{
+			name: "secure boot",
+			desc: map[string]interface{}{"bios": "UEFI", "secure_boot": true},
+			want: " -drive if=pflash,format=raw,readonly=on,file=/opt/cloud/contrib/OVMF_CODE.secboot.fd" +
+				" -drive if=pflash,format=raw,file=/opt/cloud/workspace/servers/guest-id/OVMF_VARS.fd",
+		},
+		{
+			name: "uefi",
+			desc: map[string]interface{}{"bios": "UEFI"},
+			want: " -bios /opt/cloud/contrib/OVMF.fd",
+		},
+		{
+			name: "bios",
+			desc: map[string]interface{}{},
+			want: "",
+		},
+	}
+	for _, c := range cases {
+		if got := newTestGuest(c.desc).getFirmwareDesc(); got != c.want {
+			t.Errorf("%s: want %q, got %q", c.name, c.want, got)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostdhcp
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/types"
+	guestman "yunion.io/x/onecloud/pkg/hostman/guestman/types"
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/util/dhcp6"
+	"yunion.io/x/onecloud/pkg/util/netutils2"
+)
+
+// SGuestDHCP6Server serves DHCPv6 and answers router solicitations of
+// guests on a bridge.  Router advertisements carry prefix and address
+// configuration mode only, with router lifetime 0, the default route is
+// expected to be advertised by the ipv6 gateway of the network
+type SGuestDHCP6Server struct {
+	server   *dhcp6.DHCP6Server
+	ra       *dhcp6.RAServer
+	serverId []byte
+
+	iface string
+}
+
+func NewGuestDHCP6Server(iface string) (*SGuestDHCP6Server, error) {
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, err
+	}
+	guestdhcp := &SGuestDHCP6Server{
+		serverId: dhcp6.MakeDUIDLL(ifi.HardwareAddr),
+		iface:    iface,
+	}
+	guestdhcp.server, err = dhcp6.NewDHCP6Server(iface)
+	if err != nil {
+		return nil, err
+	}
+	guestdhcp.ra, err = dhcp6.NewRAServer(iface)
+	if err != nil {
+		return nil, err
+	}
+	return guestdhcp, nil
+}
+
+func (s *SGuestDHCP6Server) Start() {
+	log.Infof("SGuestDHCP6Server starting on %s ...", s.iface)
+	go func() {
+		err := s.server.ListenAndServe(s)
+		if err != nil {
+			log.Errorf("DHCPv6 serve error: %s", err)
+		}
+	}()
+	go func() {
+		err := s.ra.ListenAndServe(s)
+		if err != nil {
+			log.Errorf("Router advertisement serve error: %s", err)
+		}
+	}()
+}
+
+func (s *SGuestDHCP6Server) getGuestNic(mac string) *types.SServerNic {
+	var (
+		ip, port    = "", ""
+		isCandidate = false
+	)
+	_, guestNic := guestman.GuestDescGetter.GetGuestNicDesc(mac, ip, port, s.iface, isCandidate)
+	if guestNic == nil {
+		_, guestNic = guestman.GuestDescGetter.GetGuestNicDesc(mac, ip, port, s.iface, !isCandidate)
+	}
+	if guestNic == nil || jsonutils.QueryBoolean(guestNic, "virtual", false) {
+		return nil
+	}
+	var nicdesc = new(types.SServerNic)
+	if err := guestNic.Unmarshal(nicdesc); err != nil {
+		log.Errorln(err)
+		return nil
+	}
+	if len(nicdesc.Ip6) == 0 {
+		return nil
+	}
+	return nicdesc
+}
+
+// getClientMac finds out the mac address of the client from its link-layer
+// based DUID, or from its EUI-64 link local address
+func getClientMac(duid []byte, ip net.IP) string {
+	if mac, ok := dhcp6.MacFromDUID(duid); ok {
+		return mac.String()
+	}
+	if mac, ok := netutils2.MacFromIPV6EUI64(ip); ok {
+		return mac
+	}
+	return ""
+}
+
+func getLifetime() time.Duration {
+	return time.Duration(options.HostOptions.DhcpLeaseTime) * time.Second
+}
+
+func (s *SGuestDHCP6Server) ServeDHCP6(msg *dhcp6.Message, addr *net.UDPAddr) (*dhcp6.Message, error) {
+	mac := getClientMac(msg.ClientID(), addr.IP)
+	if len(mac) == 0 {
+		return nil, nil
+	}
+	nicdesc := s.getGuestNic(mac)
+	if nicdesc == nil {
+		return nil, nil
+	}
+	if nicdesc.Ip6Mode == api.NETWORK_IP6_MODE_SLAAC && msg.Type != dhcp6.InformationRequest {
+		// addresses are self configured, only serve stateless requests
+		return nil, nil
+	}
+	conf := &dhcp6.ResponseConfig{
+		ClientIP:          net.ParseIP(nicdesc.Ip6),
+		Domain:            nicdesc.Domain,
+		PreferredLifetime: getLifetime(),
+		ValidLifetime:     getLifetime(),
+	}
+	if conf.ClientIP == nil {
+		return nil, fmt.Errorf("invalid ipv6 address %q of %s", nicdesc.Ip6, mac)
+	}
+	if dns := net.ParseIP(nicdesc.Dns6); dns != nil {
+		conf.DNSServers = []net.IP{dns}
+	}
+	log.Infof("Make DHCPv6 Reply %s TO %s", conf.ClientIP, mac)
+	return dhcp6.MakeReplyMessage(msg, s.serverId, conf)
+}
+
+func (s *SGuestDHCP6Server) ServeRouterSolicitation(hwaddr net.HardwareAddr, addr *net.IPAddr) (*dhcp6.RouterAdvertisement, error) {
+	var mac string
+	if len(hwaddr) > 0 {
+		mac = hwaddr.String()
+	} else {
+		mac = getClientMac(nil, addr.IP)
+	}
+	if len(mac) == 0 {
+		return nil, nil
+	}
+	nicdesc := s.getGuestNic(mac)
+	if nicdesc == nil {
+		return nil, nil
+	}
+	slaac := nicdesc.Ip6Mode == api.NETWORK_IP6_MODE_SLAAC
+	ra := &dhcp6.RouterAdvertisement{
+		Managed:           !slaac,
+		Other:             true,
+		Prefix:            net.ParseIP(nicdesc.Ip6),
+		PrefixLen:         nicdesc.Masklen6,
+		Autonomous:        slaac,
+		ValidLifetime:     getLifetime(),
+		PreferredLifetime: getLifetime(),
+		MTU:               nicdesc.Mtu,
+	}
+	if dns := net.ParseIP(nicdesc.Dns6); dns != nil {
+		ra.DNSServers = []net.IP{dns}
+	}
+	return ra, nil
+}
@@ -67,6 +67,8 @@ type SHostInfo struct {
 	saved  bool
 	pinger *SHostPingTask
 
+	aclLogCollector *SOvnAclLogCollector
+
 	Cpu     *SCPUInfo
 	Mem     *SMemory
 	sysinfo *SSysInfo
@@ -118,6 +120,9 @@ func (h *SHostInfo) GetBridgeDev(bridge string) hostbridge.IBridgeDriver {
 func (h *SHostInfo) StartDHCPServer() {
 	for _, nic := range h.Nics {
 		nic.dhcpServer.Start()
+		if nic.dhcp6Server != nil {
+			nic.dhcp6Server.Start()
+		}
 	}
 }
 
@@ -1022,6 +1027,11 @@ func (h *SHostInfo) updateHostRecord(hostId string) {
 	content.Set("__meta__", jsonutils.Marshal(h.getSysInfo()))
 	content.Set("version", jsonutils.NewString(version.GetShortString()))
 	content.Set("ovn_version", jsonutils.NewString(MustGetOvnVersion()))
+	if numaInfo, err := h.GetNumaInfo(); err != nil {
+		log.Errorf("get numa info: %s", err)
+	} else {
+		content.Set("numa_info", jsonutils.Marshal(numaInfo))
+	}
 
 	var (
 		res jsonutils.JSONObject
@@ -1532,6 +1542,7 @@ func (h *SHostInfo) onSucc() {
 			panic(err.Error())
 		}
 		h.StartPinger()
+		h.StartOvnAclLogCollector()
 		if h.registerCallback != nil {
 			h.registerCallback()
 		}
@@ -1549,6 +1560,17 @@ func (h *SHostInfo) StartPinger() {
 	}
 }
 
+func (h *SHostInfo) StartOvnAclLogCollector() {
+	opts := &options.HostOptions
+	if opts.BridgeDriver != hostbridge.DRV_OPEN_VSWITCH || !HasOvnSupport() {
+		return
+	}
+	h.aclLogCollector = NewOvnAclLogCollector(opts.OvnAclLogFile, opts.OvnIntegrationBridge, opts.OvnAclLogReportInterval)
+	if h.aclLogCollector != nil {
+		go h.aclLogCollector.Start()
+	}
+}
+
 func (h *SHostInfo) save() error {
 	if h.saved {
 		return nil
@@ -1602,6 +1624,9 @@ func (h *SHostInfo) stop() {
 	if h.pinger != nil {
 		h.pinger.Stop()
 	}
+	if h.aclLogCollector != nil {
+		h.aclLogCollector.Stop()
+	}
 	for _, nic := range h.Nics {
 		nic.ExitCleanup()
 	}
@@ -240,6 +240,8 @@ type SNIC struct {
 	Bandwidth  int
 	BridgeDev  hostbridge.IBridgeDriver
 	dhcpServer *hostdhcp.SGuestDHCPServer
+
+	dhcp6Server *hostdhcp.SGuestDHCP6Server
 }
 
 func (n *SNIC) EnableDHCPRelay() bool {
@@ -363,6 +365,14 @@ func NewNIC(desc string) (*SNIC, error) {
 	if err != nil {
 		return nil, err
 	}
+	if options.HostOptions.EnableDhcp6Server {
+		// ipv6 may be disabled on the bridge, do not fail the host agent
+		nic.dhcp6Server, err = hostdhcp.NewGuestDHCP6Server(nic.Bridge)
+		if err != nil {
+			log.Warningf("new dhcp6 server on %s: %v", nic.Bridge, err)
+			nic.dhcp6Server = nil
+		}
+	}
 	// dhcp server start after guest manager init
 	return nic, nil
 }
@@ -0,0 +1,137 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostinfo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/util/cgrouputils"
+	"yunion.io/x/onecloud/pkg/util/fileutils2"
+)
+
+const sysNodePath = "/sys/devices/system/node"
+
+var numaNodeDirRegexp = regexp.MustCompile(`^node(\d+)$`)
+
+// GetNumaInfo reads NUMA topology from sysfs, free memory of nodes is
+// a snapshot at the time of calling
+func (h *SHostInfo) GetNumaInfo() (*api.HostNumaInfo, error) {
+	files, err := ioutil.ReadDir(sysNodePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read dir %s", sysNodePath)
+	}
+	var hugepageSizeKb int
+	if h.IsHugepagesEnabled() {
+		hugepageSizeKb = h.Mem.GetHugepagesizeMb() * 1024
+	}
+	info := &api.HostNumaInfo{Nodes: make([]api.HostNumaNode, 0)}
+	for _, f := range files {
+		m := numaNodeDirRegexp.FindStringSubmatch(f.Name())
+		if m == nil {
+			continue
+		}
+		nodeId, _ := strconv.Atoi(m[1])
+		node, err := getNumaNode(nodeId, hugepageSizeKb)
+		if err != nil {
+			return nil, errors.Wrapf(err, "node %d", nodeId)
+		}
+		// memory-less node could not hold guests
+		if len(node.Cpus) == 0 || node.MemSizeMb == 0 {
+			continue
+		}
+		info.Nodes = append(info.Nodes, *node)
+	}
+	sort.Slice(info.Nodes, func(i, j int) bool {
+		return info.Nodes[i].NodeId < info.Nodes[j].NodeId
+	})
+	return info, nil
+}
+
+func getNumaNode(nodeId int, hugepageSizeKb int) (*api.HostNumaNode, error) {
+	nodeDir := path.Join(sysNodePath, fmt.Sprintf("node%d", nodeId))
+	node := &api.HostNumaNode{NodeId: nodeId}
+
+	cpulist, err := fileutils2.FileGetContents(path.Join(nodeDir, "cpulist"))
+	if err != nil {
+		return nil, errors.Wrap(err, "read cpulist")
+	}
+	cpulist = strings.TrimSpace(cpulist)
+	if len(cpulist) > 0 {
+		for _, cpu := range strings.Split(cgrouputils.ParseCpusetStr(cpulist), ",") {
+			idx, err := strconv.Atoi(cpu)
+			if err != nil {
+				return nil, errors.Wrapf(err, "parse cpulist %s", cpulist)
+			}
+			node.Cpus = append(node.Cpus, idx)
+		}
+	}
+
+	// Node 0 MemTotal:       65843224 kB
+	meminfo, err := fileutils2.FileGetContents(path.Join(nodeDir, "meminfo"))
+	if err != nil {
+		return nil, errors.Wrap(err, "read meminfo")
+	}
+	for _, line := range strings.Split(meminfo, "\n") {
+		segs := strings.Fields(line)
+		if len(segs) < 4 {
+			continue
+		}
+		sizeKb, err := strconv.Atoi(segs[3])
+		if err != nil {
+			continue
+		}
+		switch segs[2] {
+		case "MemTotal:":
+			node.MemSizeMb = sizeKb / 1024
+		case "MemFree:":
+			node.FreeMemMb = sizeKb / 1024
+		}
+	}
+
+	if hugepageSizeKb > 0 {
+		hugepageDir := path.Join(nodeDir, "hugepages", fmt.Sprintf("hugepages-%dkB", hugepageSizeKb))
+		node.HugepageSizeKb = hugepageSizeKb
+		node.HugepageTotal, err = readIntFile(path.Join(hugepageDir, "nr_hugepages"))
+		if err != nil {
+			return nil, err
+		}
+		node.HugepageFree, err = readIntFile(path.Join(hugepageDir, "free_hugepages"))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
+
+func readIntFile(fileName string) (int, error) {
+	content, err := fileutils2.FileGetContents(fileName)
+	if err != nil {
+		return 0, errors.Wrapf(err, "read %s", fileName)
+	}
+	val, err := strconv.Atoi(strings.TrimSpace(content))
+	if err != nil {
+		return 0, errors.Wrapf(err, "parse %s", fileName)
+	}
+	return val, nil
+}
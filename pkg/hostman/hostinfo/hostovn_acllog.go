@@ -0,0 +1,369 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostinfo
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/hostman/hostutils"
+	"yunion.io/x/onecloud/pkg/mcclient/modules"
+	"yunion.io/x/onecloud/pkg/util/procutils"
+)
+
+const maxOvnAclLogDeniedFlows = 20
+
+// ovnAclLogRegexp matches hits of logged acls in ovn-controller log, e.g.
+//
+//	2021-03-04T05:06:07.890Z|00012|acl_log(ovn_pinctrl0)|INFO|name="<rule id>", verdict=drop, severity=warning: tcp,vlan_tci=0x0000,...
+var ovnAclLogRegexp = regexp.MustCompile(`^(\S+)\|\d+\|acl_log[^|]*\|[A-Z]+\|name="([^"]*)", verdict=(\w+), severity=\w+: (.*)$`)
+
+// flow fields kept in denied flow records
+var ovnAclLogFlowKeys = map[string]struct{}{
+	"nw_src":    {},
+	"nw_dst":    {},
+	"ipv6_src":  {},
+	"ipv6_dst":  {},
+	"tp_src":    {},
+	"tp_dst":    {},
+	"icmp_type": {},
+	"icmp_code": {},
+}
+
+type ovnAclLogEntry struct {
+	RuleId  string
+	Verdict string
+	Flow    string
+	At      time.Time
+}
+
+// ovnActionOpcodeLog is the opcode of log() action of ovn, sent to
+// ovn-controller in userdata of the controller action
+const ovnActionOpcodeLog = 7
+
+// ovnAclFlowLogRegexp matches the controller action of openflow flows of
+// logged acls, e.g.
+//
+//	actions=controller(userdata=00.00.00.07.00.00.00.00.01.04.72.75.6c.65.30,meter_id=1),resubmit(,45)
+var ovnAclFlowLogRegexp = regexp.MustCompile(`controller\([^)]*userdata=([0-9a-fA-F.]+)`)
+
+type ovnAclFlow struct {
+	// Key identifies the flow with its cookie, table, priority and match
+	Key     string
+	RuleId  string
+	Packets int64
+	Bytes   int64
+}
+
+// parseOvnAclFlow parses one flow of ovs-ofctl dump-flows output.  Only
+// flows logging hits of named acls are returned
+func parseOvnAclFlow(line string) (*ovnAclFlow, bool) {
+	line = strings.TrimSpace(line)
+	i := strings.Index(line, " actions=")
+	if i < 0 {
+		return nil, false
+	}
+	m := ovnAclFlowLogRegexp.FindStringSubmatch(line[i:])
+	if m == nil {
+		return nil, false
+	}
+	ruleId, ok := ovnAclFlowLogName(m[1])
+	if !ok {
+		return nil, false
+	}
+	flow := &ovnAclFlow{
+		RuleId: ruleId,
+	}
+	var keys []string
+	for _, field := range strings.Split(line[:i], ", ") {
+		kv := strings.SplitN(field, "=", 2)
+		switch kv[0] {
+		case "n_packets", "n_bytes":
+			if len(kv) != 2 {
+				return nil, false
+			}
+			n, err := strconv.ParseInt(kv[1], 10, 64)
+			if err != nil {
+				return nil, false
+			}
+			if kv[0] == "n_packets" {
+				flow.Packets = n
+			} else {
+				flow.Bytes = n
+			}
+		case "duration", "idle_age", "hard_age":
+		default:
+			keys = append(keys, field)
+		}
+	}
+	flow.Key = strings.Join(keys, ", ")
+	return flow, true
+}
+
+// ovnAclFlowLogName decodes acl name from userdata of the log action.  The
+// opcode and 4 bytes padding, verdict and severity are followed by the name
+func ovnAclFlowLogName(userdata string) (string, bool) {
+	hexs := strings.Split(userdata, ".")
+	if len(hexs) <= 10 {
+		// unnamed
+		return "", false
+	}
+	data := make([]byte, len(hexs))
+	for i, h := range hexs {
+		b, err := strconv.ParseUint(h, 16, 8)
+		if err != nil {
+			return "", false
+		}
+		data[i] = byte(b)
+	}
+	if binary.BigEndian.Uint32(data[:4]) != ovnActionOpcodeLog {
+		return "", false
+	}
+	return string(data[10:]), true
+}
+
+func parseOvnAclLog(line string) (*ovnAclLogEntry, bool) {
+	m := ovnAclLogRegexp.FindStringSubmatch(strings.TrimSpace(line))
+	if m == nil || m[2] == "" || m[2] == "<unnamed>" {
+		// acls of security group rules are always named
+		return nil, false
+	}
+	at, err := time.Parse("2006-01-02T15:04:05.000Z", m[1])
+	if err != nil {
+		at = time.Now().UTC()
+	}
+	entry := &ovnAclLogEntry{
+		RuleId:  m[2],
+		Verdict: m[3],
+		Flow:    ovnAclLogFlowSummary(m[4]),
+		At:      at,
+	}
+	return entry, true
+}
+
+// ovnAclLogFlowSummary keeps protocol and address, port fields of the
+// flow description
+func ovnAclLogFlowSummary(flow string) string {
+	var r []string
+	for i, field := range strings.Split(flow, ",") {
+		if i == 0 {
+			r = append(r, field)
+			continue
+		}
+		kv := strings.SplitN(field, "=", 2)
+		if _, ok := ovnAclLogFlowKeys[kv[0]]; ok {
+			r = append(r, field)
+		}
+	}
+	return strings.Join(r, ",")
+}
+
+// SOvnAclLogCollector counts hits of logged security group rules and
+// reports them to region periodically.  Hits are counted with counters of
+// openflow flows of the acls.  Log messages of ovn-controller are rate
+// limited, they are only used for samples of denied flows
+type SOvnAclLogCollector struct {
+	path     string
+	bridge   string
+	interval int // second
+	stopCh   chan struct{}
+	stopOnce sync.Once
+
+	offset int64
+	flows  map[string]*ovnAclFlow
+	hits   map[string]*api.SecgroupRuleHits
+}
+
+func NewOvnAclLogCollector(path, bridge string, interval int) *SOvnAclLogCollector {
+	if path == "" || bridge == "" || interval <= 0 {
+		return nil
+	}
+	return &SOvnAclLogCollector{
+		path:     path,
+		bridge:   bridge,
+		interval: interval,
+		stopCh:   make(chan struct{}),
+		offset:   -1,
+		hits:     map[string]*api.SecgroupRuleHits{},
+	}
+}
+
+func (c *SOvnAclLogCollector) Start() {
+	hostId := Instance().GetHostId()
+	ticker := time.NewTicker(time.Duration(c.interval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stopCh:
+			return
+		case <-ticker.C:
+		}
+		if err := c.collectCounters(); err != nil {
+			log.Errorf("collect ovn acl flow counters: %v", err)
+		}
+		if err := c.collectLog(); err != nil {
+			log.Errorf("collect ovn acl log: %v", err)
+		}
+		if len(c.hits) == 0 {
+			continue
+		}
+		if err := c.report(hostId); err != nil {
+			// keep the counts for the next round
+			log.Errorf("report security group rule hits: %v", err)
+			continue
+		}
+		c.hits = map[string]*api.SecgroupRuleHits{}
+	}
+}
+
+func (c *SOvnAclLogCollector) Stop() {
+	c.stopOnce.Do(func() {
+		close(c.stopCh)
+	})
+}
+
+func (c *SOvnAclLogCollector) collectCounters() error {
+	output, err := procutils.NewCommand("ovs-ofctl", "dump-flows", c.bridge).Output()
+	if err != nil {
+		return errors.Wrapf(err, "dump flows of %s: %s", c.bridge, output)
+	}
+	flows := map[string]*ovnAclFlow{}
+	for _, line := range strings.Split(string(output), "\n") {
+		if flow, ok := parseOvnAclFlow(line); ok {
+			flows[flow.Key] = flow
+		}
+	}
+	c.addCounters(flows, time.Now().UTC())
+	return nil
+}
+
+// addCounters adds increases of flow counters since the last dump.
+// Counters of flows reinstalled by ovn-controller start again from zero
+func (c *SOvnAclLogCollector) addCounters(flows map[string]*ovnAclFlow, now time.Time) {
+	if c.flows == nil {
+		// hits before start are not counted
+		c.flows = flows
+		return
+	}
+	for key, flow := range flows {
+		packets, bytes := flow.Packets, flow.Bytes
+		if prev, ok := c.flows[key]; ok && prev.Packets <= packets && prev.Bytes <= bytes {
+			packets -= prev.Packets
+			bytes -= prev.Bytes
+		}
+		if packets == 0 && bytes == 0 {
+			continue
+		}
+		hits := c.getHits(flow.RuleId)
+		hits.Hits += packets
+		hits.Bytes += bytes
+		if now.After(hits.LastHitAt) {
+			hits.LastHitAt = now
+		}
+	}
+	c.flows = flows
+}
+
+func (c *SOvnAclLogCollector) collectLog() error {
+	f, err := os.Open(c.path)
+	if err != nil {
+		return errors.Wrap(err, "open")
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return errors.Wrap(err, "stat")
+	}
+	size := fi.Size()
+	if c.offset < 0 {
+		// hits before start are not counted
+		c.offset = size
+		return nil
+	}
+	if size < c.offset {
+		// log rotated
+		c.offset = 0
+	}
+	if _, err := f.Seek(c.offset, io.SeekStart); err != nil {
+		return errors.Wrap(err, "seek")
+	}
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			if err == io.EOF {
+				// partial line is left for the next round
+				return nil
+			}
+			return errors.Wrap(err, "read")
+		}
+		c.offset += int64(len(line))
+		if entry, ok := parseOvnAclLog(line); ok {
+			c.addDeniedFlow(entry)
+		}
+	}
+}
+
+func (c *SOvnAclLogCollector) getHits(ruleId string) *api.SecgroupRuleHits {
+	hits, ok := c.hits[ruleId]
+	if !ok {
+		hits = &api.SecgroupRuleHits{
+			RuleId: ruleId,
+		}
+		c.hits[ruleId] = hits
+	}
+	return hits
+}
+
+// addDeniedFlow keeps denied flow samples of the log entry.  Hits are
+// counted by flow counters
+func (c *SOvnAclLogCollector) addDeniedFlow(entry *ovnAclLogEntry) {
+	if entry.Verdict != "drop" {
+		return
+	}
+	hits := c.getHits(entry.RuleId)
+	hits.DeniedFlows = append(hits.DeniedFlows, api.SecgroupRuleDeniedFlow{
+		Flow: entry.Flow,
+		At:   entry.At,
+	})
+	if n := len(hits.DeniedFlows); n > maxOvnAclLogDeniedFlows {
+		hits.DeniedFlows = hits.DeniedFlows[n-maxOvnAclLogDeniedFlows:]
+	}
+}
+
+func (c *SOvnAclLogCollector) report(hostId string) error {
+	input := api.SecgroupRuleHitsReportInput{
+		HostId: hostId,
+	}
+	for _, hits := range c.hits {
+		input.Rules = append(input.Rules, *hits)
+	}
+	_, err := modules.SecGroupRules.PerformClassAction(hostutils.GetComputeSession(context.Background()),
+		"report-hits", jsonutils.Marshal(input))
+	return err
+}
@@ -0,0 +1,201 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostinfo
+
+import (
+	"testing"
+	"time"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func TestParseOvnAclLog(t *testing.T) {
+	cases := []struct {
+		in      string
+		ok      bool
+		ruleId  string
+		verdict string
+		flow    string
+	}{
+		{
+			in:      `2021-03-04T05:06:07.890Z|00012|acl_log(ovn_pinctrl0)|INFO|name="2b1e3c4d-5f60-4a7b-8c9d-0e1f2a3b4c5d", verdict=drop, severity=warning: tcp,vlan_tci=0x0000,dl_src=00:22:bb:39:1c:6e,dl_dst=00:22:66:6c:f1:2d,nw_src=10.0.0.2,nw_dst=10.0.0.3,nw_tos=0,nw_ecn=0,nw_ttl=64,tp_src=41234,tp_dst=22,tcp_flags=syn`,
+			ok:      true,
+			ruleId:  "2b1e3c4d-5f60-4a7b-8c9d-0e1f2a3b4c5d",
+			verdict: "drop",
+			flow:    "tcp,nw_src=10.0.0.2,nw_dst=10.0.0.3,tp_src=41234,tp_dst=22",
+		},
+		{
+			in:      `2021-03-04T05:06:07.890Z|00013|acl_log|INFO|name="rule0", verdict=allow, severity=info: icmp,vlan_tci=0x0000,nw_src=10.0.0.2,nw_dst=10.0.0.3,nw_tos=0,icmp_type=8,icmp_code=0` + "\n",
+			ok:      true,
+			ruleId:  "rule0",
+			verdict: "allow",
+			flow:    "icmp,nw_src=10.0.0.2,nw_dst=10.0.0.3,icmp_type=8,icmp_code=0",
+		},
+		{
+			// ovn-controller prints <unnamed> for acls without name
+			in: `2021-03-04T05:06:07.890Z|00014|acl_log(ovn_pinctrl0)|INFO|name="<unnamed>", verdict=drop, severity=warning: tcp`,
+			ok: false,
+		},
+		{
+			in: `2021-03-04T05:06:07.890Z|00015|binding|INFO|Claiming lport iface-0 for this chassis.`,
+			ok: false,
+		},
+	}
+	for _, c := range cases {
+		entry, ok := parseOvnAclLog(c.in)
+		if ok != c.ok {
+			t.Fatalf("ok: got %v, want %v, input: %s", ok, c.ok, c.in)
+		}
+		if !ok {
+			continue
+		}
+		if entry.RuleId != c.ruleId || entry.Verdict != c.verdict || entry.Flow != c.flow {
+			t.Fatalf("got: %#v, input: %s", entry, c.in)
+		}
+		if entry.At.Year() != 2021 {
+			t.Fatalf("bad timestamp: %s", entry.At)
+		}
+	}
+}
+
+func TestParseOvnAclFlow(t *testing.T) {
+	cases := []struct {
+		name    string
+		in      string
+		ok      bool
+		key     string
+		ruleId  string
+		packets int64
+		bytes   int64
+	}{
+		{
+			name:    "logged acl",
+			in:      ` cookie=0x5f3c2a1b, duration=123.456s, table=44, n_packets=10, n_bytes=980, idle_age=3, priority=2002,tcp,reg15=0x2,metadata=0x3,tp_dst=22 actions=controller(userdata=00.00.00.07.00.00.00.00.01.04.72.75.6c.65.30,meter_id=1),resubmit(,45)`,
+			ok:      true,
+			key:     "cookie=0x5f3c2a1b, table=44, priority=2002,tcp,reg15=0x2,metadata=0x3,tp_dst=22",
+			ruleId:  "rule0",
+			packets: 10,
+			bytes:   980,
+		},
+		{
+			name:   "hard age",
+			in:     ` cookie=0x5f3c2a1b, duration=123.456s, table=44, n_packets=0, n_bytes=0, idle_age=3, hard_age=65534, priority=2002,ip,metadata=0x3 actions=controller(userdata=00.00.00.07.00.00.00.00.00.02.72.75.6c.65.31),resubmit(,45)`,
+			ok:     true,
+			key:    "cookie=0x5f3c2a1b, table=44, priority=2002,ip,metadata=0x3",
+			ruleId: "rule1",
+		},
+		{
+			name: "unnamed acl",
+			in:   ` cookie=0x5f3c2a1b, duration=1.0s, table=44, n_packets=10, n_bytes=980, idle_age=3, priority=2002,ip,metadata=0x3 actions=controller(userdata=00.00.00.07.00.00.00.00.01.04,meter_id=1),resubmit(,45)`,
+		},
+		{
+			name: "other controller action",
+			in:   ` cookie=0x1, duration=1.0s, table=19, n_packets=1, n_bytes=342, idle_age=3, priority=100,udp,metadata=0x3,tp_src=68,tp_dst=67 actions=controller(userdata=00.00.00.02.00.00.00.00.72.75.6c.65.30.31),resubmit(,20)`,
+		},
+		{
+			name: "no controller action",
+			in:   ` cookie=0x1, duration=1.0s, table=44, n_packets=1, n_bytes=98, idle_age=3, priority=2002,ip,metadata=0x3 actions=resubmit(,45)`,
+		},
+		{
+			name: "header",
+			in:   `NXST_FLOW reply (xid=0x4):`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			flow, ok := parseOvnAclFlow(c.in)
+			if ok != c.ok {
+				t.Fatalf("ok: got %v, want %v", ok, c.ok)
+			}
+			if !ok {
+				return
+			}
+			if flow.Key != c.key || flow.RuleId != c.ruleId || flow.Packets != c.packets || flow.Bytes != c.bytes {
+				t.Errorf("got: %#v", flow)
+			}
+		})
+	}
+}
+
+func TestOvnAclLogCollectorAddCounters(t *testing.T) {
+	newFlows := func(flows ...*ovnAclFlow) map[string]*ovnAclFlow {
+		r := map[string]*ovnAclFlow{}
+		for _, flow := range flows {
+			r[flow.Key] = flow
+		}
+		return r
+	}
+	c := NewOvnAclLogCollector("ovn-controller.log", "brvpc", 60)
+	now := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	// hits before start are not counted
+	c.addCounters(newFlows(
+		&ovnAclFlow{Key: "f0", RuleId: "rule0", Packets: 10, Bytes: 1000},
+		&ovnAclFlow{Key: "f1", RuleId: "rule0", Packets: 5, Bytes: 500},
+		&ovnAclFlow{Key: "f2", RuleId: "rule1", Packets: 7, Bytes: 700},
+	), now)
+	if len(c.hits) != 0 {
+		t.Fatalf("hits of the first dump are counted: %#v", c.hits)
+	}
+
+	// f1 reinstalled, f2 removed, f3 added
+	now = now.Add(time.Minute)
+	c.addCounters(newFlows(
+		&ovnAclFlow{Key: "f0", RuleId: "rule0", Packets: 12, Bytes: 1200},
+		&ovnAclFlow{Key: "f1", RuleId: "rule0", Packets: 1, Bytes: 100},
+		&ovnAclFlow{Key: "f3", RuleId: "rule2", Packets: 3, Bytes: 300},
+	), now)
+	want := map[string][2]int64{
+		"rule0": {3, 300},
+		"rule2": {3, 300},
+	}
+	if len(c.hits) != len(want) {
+		t.Fatalf("want hits of %d rules, got %#v", len(want), c.hits)
+	}
+	for ruleId, w := range want {
+		hits := c.hits[ruleId]
+		if hits == nil || hits.Hits != w[0] || hits.Bytes != w[1] || !hits.LastHitAt.Equal(now) {
+			t.Errorf("rule %s: want %v, got %#v", ruleId, w, hits)
+		}
+	}
+
+	// idle flows are not reported
+	c.hits = map[string]*api.SecgroupRuleHits{}
+	c.addCounters(newFlows(
+		&ovnAclFlow{Key: "f0", RuleId: "rule0", Packets: 12, Bytes: 1200},
+	), now.Add(time.Minute))
+	if len(c.hits) != 0 {
+		t.Errorf("hits of idle flows are counted: %#v", c.hits)
+	}
+}
+
+func TestOvnAclLogCollectorAddDeniedFlow(t *testing.T) {
+	c := NewOvnAclLogCollector("ovn-controller.log", "brvpc", 60)
+	at := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	c.addDeniedFlow(&ovnAclLogEntry{RuleId: "rule0", Verdict: "allow", Flow: "tcp", At: at})
+	if len(c.hits) != 0 {
+		t.Fatalf("allowed flow is kept: %#v", c.hits)
+	}
+	for i := 0; i < maxOvnAclLogDeniedFlows+5; i++ {
+		c.addDeniedFlow(&ovnAclLogEntry{RuleId: "rule0", Verdict: "drop", Flow: "tcp", At: at.Add(time.Duration(i) * time.Second)})
+	}
+	hits := c.hits["rule0"]
+	if hits == nil || hits.Hits != 0 || len(hits.DeniedFlows) != maxOvnAclLogDeniedFlows {
+		t.Fatalf("got %#v", hits)
+	}
+	if last := hits.DeniedFlows[len(hits.DeniedFlows)-1].At; !last.Equal(at.Add(time.Duration(maxOvnAclLogDeniedFlows+4) * time.Second)) {
+		t.Errorf("latest denied flow not kept, got %s", last)
+	}
+}
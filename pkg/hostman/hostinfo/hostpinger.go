@@ -18,6 +18,7 @@ import (
 	"context"
 	"time"
 
+	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
 
 	"yunion.io/x/onecloud/pkg/hostman/hostutils"
@@ -81,8 +82,14 @@ func (p *SHostPingTask) Start() {
 }
 
 func (p *SHostPingTask) ping(div int, hostId string) error {
+	data := jsonutils.NewDict()
+	if numaInfo, err := Instance().GetNumaInfo(); err != nil {
+		log.Errorf("get numa info: %s", err)
+	} else {
+		data.Set("numa_info", jsonutils.Marshal(numaInfo))
+	}
 	res, err := modules.Hosts.PerformAction(hostutils.GetComputeSession(context.Background()),
-		hostId, "ping", nil)
+		hostId, "ping", data)
 	if err != nil {
 		return err
 	} else {
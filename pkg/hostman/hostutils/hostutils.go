@@ -22,6 +22,7 @@ import (
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
 
+	"yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/appctx"
 	"yunion.io/x/onecloud/pkg/appsrv"
 	"yunion.io/x/onecloud/pkg/cloudcommon/workmanager"
@@ -42,6 +43,7 @@ type IHost interface {
 	GetMasterIp() string
 	GetCpuArchitecture() string
 	IsHugepagesEnabled() bool
+	GetNumaInfo() (*compute.HostNumaInfo, error)
 
 	IsKvmSupport() bool
 	IsNestedVirtualization() bool
@@ -384,7 +384,7 @@ func (m *HmpMonitor) ReloadDiskBlkdev(device, path string, callback StringCallba
 	m.Query(fmt.Sprintf("reload_disk_snapshot_blkdev -n %s %s", device, path), callback)
 }
 
-func (m *HmpMonitor) DriveMirror(callback StringCallback, drive, target, syncMode string, unmap, blockReplication bool) {
+func (m *HmpMonitor) DriveMirror(callback StringCallback, drive, target, syncMode, format string, unmap, blockReplication bool) {
 	cmd := "drive_mirror -n"
 	if blockReplication {
 		cmd += " -c"
@@ -393,9 +393,34 @@ func (m *HmpMonitor) DriveMirror(callback StringCallback, drive, target, syncMod
 		cmd += " -f"
 	}
 	cmd += fmt.Sprintf(" %s %s", drive, target)
+	if len(format) > 0 {
+		cmd += " " + format
+	}
 	m.Query(cmd, callback)
 }
 
+func (m *HmpMonitor) DriveBackup(callback StringCallback, drive, target, syncMode, bitmap string) {
+	if len(bitmap) > 0 {
+		// dirty bitmap is only accessible through qmp
+		go callback("Error: dirty bitmap not supported by hmp")
+		return
+	}
+	cmd := "drive_backup -n"
+	if syncMode == "full" {
+		cmd += " -f"
+	}
+	cmd += fmt.Sprintf(" %s %s qcow2", drive, target)
+	m.Query(cmd, callback)
+}
+
+func (m *HmpMonitor) BlockDirtyBitmapAdd(node, name string, persistent bool, callback StringCallback) {
+	go callback("Error: dirty bitmap not supported by hmp")
+}
+
+func (m *HmpMonitor) BlockDirtyBitmapRemove(node, name string, callback StringCallback) {
+	go callback("Error: dirty bitmap not supported by hmp")
+}
+
 func (m *HmpMonitor) BlockStream(drive string, callback StringCallback) {
 	var (
 		speed = 100 // limit 100 MB/s
@@ -477,6 +502,10 @@ func (m *HmpMonitor) CancelBlockJob(driveName string, force bool, callback Strin
 	m.Query(cmd, callback)
 }
 
+func (m *HmpMonitor) BlockJobComplete(driveName string, callback StringCallback) {
+	m.Query(fmt.Sprintf("block_job_complete %s", driveName), callback)
+}
+
 func (m *HmpMonitor) NetdevAdd(id, netType string, params map[string]string, callback StringCallback) {
 	cmd := fmt.Sprintf("netdev_add %s,id=%s", netType, id)
 	for k, v := range params {
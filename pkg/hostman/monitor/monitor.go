@@ -60,7 +60,13 @@ type Monitor interface {
 	DeviceAdd(dev string, params map[string]interface{}, callback StringCallback)
 
 	BlockStream(drive string, callback StringCallback)
-	DriveMirror(callback StringCallback, drive, target, syncMode string, unmap, blockReplication bool)
+	DriveMirror(callback StringCallback, drive, target, syncMode, format string, unmap, blockReplication bool)
+	// DriveBackup backup drive to an existing target image,
+	// for full sync a persistent dirty bitmap is added atomically if bitmap given,
+	// for incremental sync only the clusters tracked by bitmap are copied
+	DriveBackup(callback StringCallback, drive, target, syncMode, bitmap string)
+	BlockDirtyBitmapAdd(node, name string, persistent bool, callback StringCallback)
+	BlockDirtyBitmapRemove(node, name string, callback StringCallback)
 
 	MigrateSetCapability(capability, state string, callback StringCallback)
 	Migrate(destStr string, copyIncremental, copyFull bool, callback StringCallback)
@@ -73,6 +79,8 @@ type Monitor interface {
 	ResizeDisk(driveName string, sizeMB int64, callback StringCallback)
 	BlockIoThrottle(driveName string, bps, iops int64, callback StringCallback)
 	CancelBlockJob(driveName string, force bool, callback StringCallback)
+	// BlockJobComplete pivot a ready mirror job to its target
+	BlockJobComplete(driveName string, callback StringCallback)
 
 	NetdevAdd(id, netType string, params map[string]string, callback StringCallback)
 	NetdevDel(id string, callback StringCallback)
@@ -0,0 +1,363 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitor
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"yunion.io/x/log"
+)
+
+// https://qemu.readthedocs.io/en/latest/interop/qemu-ga-ref.html
+/*
+qemu-ga speaks the same json protocol as qmp without greeting and events,
+the channel is a byte stream shared by all clients and qemu-ga never knows
+a client reconnected, so every session starts with guest-sync to drop the
+stale responses left in the channel.
+*/
+
+const (
+	QGA_DEFAULT_TIMEOUT = 10 * time.Second
+	// fsfreeze waits for guest flushing all filesystems
+	QGA_FSFREEZE_TIMEOUT = 60 * time.Second
+
+	qgaFileReadChunk = 48 * 1024
+)
+
+var ErrGuestAgentTimeout = errors.New("guest agent timeout")
+
+type QemuGuestAgent struct {
+	id            string
+	qgaSocketPath string
+
+	mutex   *sync.Mutex
+	rwc     net.Conn
+	scanner *bufio.Scanner
+}
+
+type GuestNetworkInterfaceIpAddress struct {
+	IpAddress     string `json:"ip-address"`
+	IpAddressType string `json:"ip-address-type"`
+	Prefix        int    `json:"prefix"`
+}
+
+type GuestNetworkInterface struct {
+	Name            string                           `json:"name"`
+	HardwareAddress string                           `json:"hardware-address"`
+	IpAddresses     []GuestNetworkInterfaceIpAddress `json:"ip-addresses"`
+}
+
+type GuestOsInfo struct {
+	Id            string `json:"id"`
+	Name          string `json:"name"`
+	PrettyName    string `json:"pretty-name"`
+	Version       string `json:"version"`
+	VersionId     string `json:"version-id"`
+	KernelRelease string `json:"kernel-release"`
+	KernelVersion string `json:"kernel-version"`
+	Machine       string `json:"machine"`
+}
+
+type guestFileRead struct {
+	Count  int    `json:"count"`
+	BufB64 string `json:"buf-b64"`
+	Eof    bool   `json:"eof"`
+}
+
+func NewQemuGuestAgent(id, qgaSocketPath string) *QemuGuestAgent {
+	return &QemuGuestAgent{
+		id:            id,
+		qgaSocketPath: qgaSocketPath,
+		mutex:         &sync.Mutex{},
+	}
+}
+
+func (qga *QemuGuestAgent) connect() error {
+	conn, err := net.Dial("unix", qga.qgaSocketPath)
+	if err != nil {
+		return errors.Wrapf(err, "connect qga socket %s", qga.qgaSocketPath)
+	}
+	qga.rwc = conn
+	qga.scanner = bufio.NewScanner(conn)
+	qga.scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	if err := qga.sync(); err != nil {
+		qga.close()
+		return err
+	}
+	log.Infof("Guest %s qga connected", qga.id)
+	return nil
+}
+
+func (qga *QemuGuestAgent) close() {
+	if qga.rwc != nil {
+		qga.rwc.Close()
+		qga.rwc = nil
+		qga.scanner = nil
+	}
+}
+
+// Close disconnect from the qga socket, it will reconnect on next command
+func (qga *QemuGuestAgent) Close() {
+	qga.mutex.Lock()
+	defer qga.mutex.Unlock()
+	qga.close()
+}
+
+func (qga *QemuGuestAgent) write(cmd *Command) error {
+	c, err := json.Marshal(cmd)
+	if err != nil {
+		return errors.Wrap(err, "marshal command")
+	}
+	log.Debugf("QGA Write %s: %s", qga.id, string(c))
+	_, err = qga.rwc.Write(append(c, '\n'))
+	return err
+}
+
+func (qga *QemuGuestAgent) read(timeout time.Duration) (*Response, error) {
+	qga.rwc.SetReadDeadline(time.Now().Add(timeout))
+	defer qga.rwc.SetReadDeadline(time.Time{})
+	for qga.scanner.Scan() {
+		var objmap map[string]*json.RawMessage
+		// qemu-ga may prepend 0xff to a response after guest-sync-delimited
+		b := qga.scanner.Bytes()
+		for len(b) > 0 && b[0] == 0xff {
+			b = b[1:]
+		}
+		if len(b) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(b, &objmap); err != nil {
+			log.Errorf("QGA %s unmarshal %q: %s", qga.id, b, err)
+			continue
+		}
+		res := &Response{}
+		if val, ok := objmap["error"]; ok {
+			res.ErrorVal = &Error{}
+			json.Unmarshal(*val, res.ErrorVal)
+		} else if val, ok := objmap["return"]; ok {
+			res.Return = []byte(*val)
+		} else {
+			continue
+		}
+		return res, nil
+	}
+	err := qga.scanner.Err()
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		return nil, ErrGuestAgentTimeout
+	}
+	if err == nil {
+		err = errors.New("qga connection closed")
+	}
+	return nil, err
+}
+
+// sync makes sure the next response read belongs to our next command
+func (qga *QemuGuestAgent) sync() error {
+	id := rand.Int31()
+	err := qga.write(&Command{
+		Execute: "guest-sync",
+		Args:    map[string]interface{}{"id": id},
+	})
+	if err != nil {
+		return errors.Wrap(err, "write guest-sync")
+	}
+	deadline := time.Now().Add(QGA_DEFAULT_TIMEOUT)
+	for time.Now().Before(deadline) {
+		res, err := qga.read(time.Until(deadline))
+		if err != nil {
+			return errors.Wrap(err, "guest-sync")
+		}
+		if res.ErrorVal != nil {
+			continue
+		}
+		var ret int32
+		if json.Unmarshal(res.Return, &ret) == nil && ret == id {
+			return nil
+		}
+	}
+	return errors.Wrap(ErrGuestAgentTimeout, "guest-sync")
+}
+
+// exec run a qga command and unmarshal return value into ret if it is not nil
+func (qga *QemuGuestAgent) exec(cmd *Command, ret interface{}, timeout time.Duration) error {
+	qga.mutex.Lock()
+	defer qga.mutex.Unlock()
+
+	if qga.rwc == nil {
+		if err := qga.connect(); err != nil {
+			return err
+		}
+	}
+	if err := qga.write(cmd); err != nil {
+		qga.close()
+		return errors.Wrapf(err, "write %s", cmd.Execute)
+	}
+	res, err := qga.read(timeout)
+	if err != nil {
+		// response may arrive later, drop the connection to resync next time
+		qga.close()
+		return errors.Wrap(err, cmd.Execute)
+	}
+	if res.ErrorVal != nil {
+		return errors.Wrap(res.ErrorVal, cmd.Execute)
+	}
+	if ret != nil {
+		if err := json.Unmarshal(res.Return, ret); err != nil {
+			return errors.Wrapf(err, "unmarshal %s return", cmd.Execute)
+		}
+	}
+	return nil
+}
+
+func (qga *QemuGuestAgent) Ping() error {
+	return qga.exec(&Command{Execute: "guest-ping"}, nil, QGA_DEFAULT_TIMEOUT)
+}
+
+// FsFreeze freeze all guest filesystems, returns the number of frozen filesystems
+func (qga *QemuGuestAgent) FsFreeze() (int, error) {
+	var count int
+	err := qga.exec(&Command{Execute: "guest-fsfreeze-freeze"}, &count, QGA_FSFREEZE_TIMEOUT)
+	return count, err
+}
+
+// FsThaw thaw all frozen guest filesystems, returns the number of thawed filesystems
+func (qga *QemuGuestAgent) FsThaw() (int, error) {
+	var count int
+	err := qga.exec(&Command{Execute: "guest-fsfreeze-thaw"}, &count, QGA_FSFREEZE_TIMEOUT)
+	return count, err
+}
+
+// FsFreezeStatus returns thawed or frozen
+func (qga *QemuGuestAgent) FsFreezeStatus() (string, error) {
+	var status string
+	err := qga.exec(&Command{Execute: "guest-fsfreeze-status"}, &status, QGA_DEFAULT_TIMEOUT)
+	return status, err
+}
+
+func (qga *QemuGuestAgent) SetUserPassword(username, password string, crypted bool) error {
+	cmd := &Command{
+		Execute: "guest-set-user-password",
+		Args: map[string]interface{}{
+			"username": username,
+			"password": base64.StdEncoding.EncodeToString([]byte(password)),
+			"crypted":  crypted,
+		},
+	}
+	return qga.exec(cmd, nil, QGA_DEFAULT_TIMEOUT)
+}
+
+func (qga *QemuGuestAgent) GetNetworkInterfaces() ([]GuestNetworkInterface, error) {
+	ifaces := make([]GuestNetworkInterface, 0)
+	err := qga.exec(&Command{Execute: "guest-network-get-interfaces"}, &ifaces, QGA_DEFAULT_TIMEOUT)
+	return ifaces, err
+}
+
+func (qga *QemuGuestAgent) GetOsInfo() (*GuestOsInfo, error) {
+	info := &GuestOsInfo{}
+	err := qga.exec(&Command{Execute: "guest-get-osinfo"}, info, QGA_DEFAULT_TIMEOUT)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (qga *QemuGuestAgent) fileOpen(path, mode string) (int64, error) {
+	var handle int64
+	cmd := &Command{
+		Execute: "guest-file-open",
+		Args:    map[string]interface{}{"path": path, "mode": mode},
+	}
+	err := qga.exec(cmd, &handle, QGA_DEFAULT_TIMEOUT)
+	return handle, err
+}
+
+func (qga *QemuGuestAgent) fileClose(handle int64) error {
+	cmd := &Command{
+		Execute: "guest-file-close",
+		Args:    map[string]interface{}{"handle": handle},
+	}
+	return qga.exec(cmd, nil, QGA_DEFAULT_TIMEOUT)
+}
+
+// FileRead read at most maxSize bytes from the beginning of a guest file
+func (qga *QemuGuestAgent) FileRead(path string, maxSize int) ([]byte, error) {
+	handle, err := qga.fileOpen(path, "r")
+	if err != nil {
+		return nil, err
+	}
+	defer qga.fileClose(handle)
+
+	content := make([]byte, 0)
+	for len(content) < maxSize {
+		count := maxSize - len(content)
+		if count > qgaFileReadChunk {
+			count = qgaFileReadChunk
+		}
+		ret := guestFileRead{}
+		cmd := &Command{
+			Execute: "guest-file-read",
+			Args:    map[string]interface{}{"handle": handle, "count": count},
+		}
+		if err := qga.exec(cmd, &ret, QGA_DEFAULT_TIMEOUT); err != nil {
+			return nil, err
+		}
+		buf, err := base64.StdEncoding.DecodeString(ret.BufB64)
+		if err != nil {
+			return nil, errors.Wrap(err, "decode buf-b64")
+		}
+		content = append(content, buf...)
+		if ret.Eof || ret.Count == 0 {
+			break
+		}
+	}
+	return content, nil
+}
+
+// FileWrite write content to a guest file, truncate the file unless append
+func (qga *QemuGuestAgent) FileWrite(path string, content []byte, append bool) error {
+	mode := "w"
+	if append {
+		mode = "a"
+	}
+	handle, err := qga.fileOpen(path, mode)
+	if err != nil {
+		return err
+	}
+	defer qga.fileClose(handle)
+
+	cmd := &Command{
+		Execute: "guest-file-write",
+		Args: map[string]interface{}{
+			"handle":  handle,
+			"buf-b64": base64.StdEncoding.EncodeToString(content),
+		},
+	}
+	if err := qga.exec(cmd, nil, QGA_DEFAULT_TIMEOUT); err != nil {
+		return err
+	}
+	cmd = &Command{
+		Execute: "guest-file-flush",
+		Args:    map[string]interface{}{"handle": handle},
+	}
+	return qga.exec(cmd, nil, QGA_DEFAULT_TIMEOUT)
+}
@@ -0,0 +1,127 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitor
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"testing"
+)
+
+// fakeGuestAgent serves a tiny subset of qemu-ga commands
+func fakeGuestAgent(t *testing.T, l net.Listener, file []byte) {
+	conn, err := l.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	// stale response of a previous client
+	conn.Write([]byte(`{"return": {}}` + "\n"))
+
+	scanner := bufio.NewScanner(conn)
+	var offset int
+	for scanner.Scan() {
+		cmd := struct {
+			Execute string                 `json:"execute"`
+			Args    map[string]interface{} `json:"arguments"`
+		}{}
+		if err := json.Unmarshal(scanner.Bytes(), &cmd); err != nil {
+			t.Errorf("unmarshal command %s: %s", scanner.Bytes(), err)
+			return
+		}
+		var ret interface{}
+		switch cmd.Execute {
+		case "guest-sync":
+			ret = cmd.Args["id"]
+		case "guest-ping", "guest-file-close":
+			ret = map[string]interface{}{}
+		case "guest-fsfreeze-freeze":
+			ret = 2
+		case "guest-file-open":
+			offset = 0
+			ret = 1000
+		case "guest-file-read":
+			count := int(cmd.Args["count"].(float64))
+			end := offset + count
+			if end > len(file) {
+				end = len(file)
+			}
+			buf := file[offset:end]
+			offset = end
+			ret = map[string]interface{}{
+				"count":   len(buf),
+				"buf-b64": base64.StdEncoding.EncodeToString(buf),
+				"eof":     offset == len(file),
+			}
+		default:
+			resp, _ := json.Marshal(map[string]interface{}{
+				"error": map[string]string{"class": "CommandNotFound", "desc": cmd.Execute},
+			})
+			conn.Write(append(resp, '\n'))
+			continue
+		}
+		resp, _ := json.Marshal(map[string]interface{}{"return": ret})
+		conn.Write(append(resp, '\n'))
+	}
+}
+
+func TestQemuGuestAgent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "qga")
+	if err != nil {
+		t.Fatalf("create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	sock := path.Join(dir, "qga.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatalf("listen %s: %s", sock, err)
+	}
+	defer l.Close()
+
+	file := make([]byte, qgaFileReadChunk+100)
+	for i := range file {
+		file[i] = byte(i)
+	}
+	go fakeGuestAgent(t, l, file)
+
+	qga := NewQemuGuestAgent("test", sock)
+	defer qga.Close()
+	if err := qga.Ping(); err != nil {
+		t.Fatalf("ping: %s", err)
+	}
+	count, err := qga.FsFreeze()
+	if err != nil || count != 2 {
+		t.Errorf("fsfreeze got %d, %v, want 2", count, err)
+	}
+	if _, err := qga.GetOsInfo(); err == nil {
+		t.Errorf("expect error of unknown command")
+	}
+	content, err := qga.FileRead("/etc/hosts", len(file))
+	if err != nil {
+		t.Fatalf("file read: %s", err)
+	}
+	if string(content) != string(file) {
+		t.Errorf("file read got %d bytes, want %d", len(content), len(file))
+	}
+	content, err = qga.FileRead("/etc/hosts", 10)
+	if err != nil || len(content) != 10 {
+		t.Errorf("file read limited got %d bytes, %v, want 10", len(content), err)
+	}
+}
@@ -692,7 +692,7 @@ func (m *QmpMonitor) ReloadDiskBlkdev(device, path string, callback StringCallba
 	m.Query(cmd, cb)
 }
 
-func (m *QmpMonitor) DriveMirror(callback StringCallback, drive, target, syncMode string, unmap, blockReplication bool) {
+func (m *QmpMonitor) DriveMirror(callback StringCallback, drive, target, syncMode, format string, unmap, blockReplication bool) {
 	var (
 		cb = func(res *Response) {
 			callback(m.actionResult(res))
@@ -705,6 +705,9 @@ func (m *QmpMonitor) DriveMirror(callback StringCallback, drive, target, syncMod
 			"unmap":  unmap,
 		}
 	)
+	if len(format) > 0 {
+		args["format"] = format
+	}
 	if blockReplication {
 		args["block-replication"] = true
 	}
@@ -716,6 +719,89 @@ func (m *QmpMonitor) DriveMirror(callback StringCallback, drive, target, syncMod
 	m.Query(cmd, cb)
 }
 
+func (m *QmpMonitor) DriveBackup(callback StringCallback, drive, target, syncMode, bitmap string) {
+	var (
+		cb = func(res *Response) {
+			callback(m.actionResult(res))
+		}
+		args = map[string]interface{}{
+			"device": drive,
+			"target": target,
+			"mode":   "existing",
+			"format": "qcow2",
+			"sync":   syncMode,
+		}
+		cmd *Command
+	)
+	if syncMode == "incremental" {
+		args["bitmap"] = bitmap
+		cmd = &Command{
+			Execute: "drive-backup",
+			Args:    args,
+		}
+	} else if len(bitmap) > 0 {
+		// start tracking dirty clusters at the point of full backup
+		cmd = &Command{
+			Execute: "transaction",
+			Args: map[string]interface{}{
+				"actions": []interface{}{
+					map[string]interface{}{
+						"type": "block-dirty-bitmap-add",
+						"data": map[string]interface{}{
+							"node":       drive,
+							"name":       bitmap,
+							"persistent": true,
+						},
+					},
+					map[string]interface{}{
+						"type": "drive-backup",
+						"data": args,
+					},
+				},
+			},
+		}
+	} else {
+		cmd = &Command{
+			Execute: "drive-backup",
+			Args:    args,
+		}
+	}
+	m.Query(cmd, cb)
+}
+
+func (m *QmpMonitor) BlockDirtyBitmapAdd(node, name string, persistent bool, callback StringCallback) {
+	var (
+		cb = func(res *Response) {
+			callback(m.actionResult(res))
+		}
+		cmd = &Command{
+			Execute: "block-dirty-bitmap-add",
+			Args: map[string]interface{}{
+				"node":       node,
+				"name":       name,
+				"persistent": persistent,
+			},
+		}
+	)
+	m.Query(cmd, cb)
+}
+
+func (m *QmpMonitor) BlockDirtyBitmapRemove(node, name string, callback StringCallback) {
+	var (
+		cb = func(res *Response) {
+			callback(m.actionResult(res))
+		}
+		cmd = &Command{
+			Execute: "block-dirty-bitmap-remove",
+			Args: map[string]interface{}{
+				"node": node,
+				"name": name,
+			},
+		}
+	)
+	m.Query(cmd, cb)
+}
+
 func (m *QmpMonitor) BlockStream(drive string, callback StringCallback) {
 	var (
 		speed = 100 * 1024 * 1024 // limit 100 MB/s
@@ -833,6 +919,21 @@ func (m *QmpMonitor) CancelBlockJob(driveName string, force bool, callback Strin
 	m.HumanMonitorCommand(cmd, callback)
 }
 
+func (m *QmpMonitor) BlockJobComplete(driveName string, callback StringCallback) {
+	var (
+		cb = func(res *Response) {
+			callback(m.actionResult(res))
+		}
+		cmd = &Command{
+			Execute: "block-job-complete",
+			Args: map[string]string{
+				"device": driveName,
+			},
+		}
+	)
+	m.Query(cmd, cb)
+}
+
 func (m *QmpMonitor) NetdevAdd(id, netType string, params map[string]string, callback StringCallback) {
 	cmd := fmt.Sprintf("netdev_add %s,id=%s", netType, id)
 	for k, v := range params {
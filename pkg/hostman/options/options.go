@@ -38,6 +38,8 @@ type SHostOptions struct {
 	AgentTempPath  string `help:"Path for ESXi agent"`
 	AgentTempLimit int    `help:"Maximal storage space for ESXi agent, in GB" default:"10"`
 
+	DiskBackupTempPath string `help:"Path for staging disk backup files" default:"/opt/cloud/workspace/disks/backup_tmp"`
+
 	RecycleDiskfile         bool `help:"Recycle instead of remove deleted disk file" default:"true"`
 	RecycleDiskfileKeepDays int  `help:"How long recycled files kept, default 28 days" default:"28"`
 
@@ -59,6 +61,13 @@ type SHostOptions struct {
 	OvmfPath             string `help:"Path to OVMF.fd" default:"/opt/cloud/contrib/OVMF.fd"`
 	LinuxDefaultRootUser bool   `help:"Default account for linux system is root"`
 
+	OvmfSecureBootCodePath string `help:"Path to OVMF code image built with secure boot and SMM" default:"/opt/cloud/contrib/OVMF_CODE.secboot.fd"`
+	OvmfSecureBootVarsPath string `help:"Path to OVMF variables template with secure boot keys enrolled" default:"/opt/cloud/contrib/OVMF_VARS.secboot.fd"`
+	SwtpmPath              string `help:"Path to swtpm binary used as vTPM backend" default:"/usr/bin/swtpm"`
+	VirtiofsdPath          string `help:"Path to virtiofsd binary used by virtio-fs shares" default:"/usr/libexec/virtiofsd"`
+
+	GuestFsFreezeMaxSeconds int `help:"Guest filesystems frozen through guest agent are thawed automatically after this many seconds" default:"120"`
+
 	BlockIoScheduler string `help:"Block IO scheduler, deadline or cfq" default:"deadline"`
 	EnableKsm        bool   `help:"Enable Kernel Same Page Merging"`
 	HugepagesOption  string `help:"Hugepages option: disable|native|transparent" default:"transparent"`
@@ -67,6 +76,9 @@ type SHostOptions struct {
 	PrivatePrefixes []string `help:"IPv4 private prefixes"`
 	LocalImagePath  []string `help:"Local image storage paths"`
 	SharedStorages  []string `help:"Path of shared storages"`
+	LvmVolumeGroups []string `help:"LVM volume groups used as local storage, vg_name for thick provisioning or vg_name/thin_pool for thin provisioning"`
+
+	StoragePluginSocketDir string `help:"Directory of storage plugin unix sockets, plugin listens on <driver>.sock" default:"/var/run/onecloud/storage-plugins"`
 
 	DefaultQemuVersion string `help:"Default qemu version" default:"2.12.1"`
 
@@ -74,6 +86,8 @@ type SHostOptions struct {
 	DhcpLeaseTime   int      `default:"100663296" help:"DHCP lease time in seconds"`
 	DhcpRenewalTime int      `default:"67108864" help:"DHCP renewal time in seconds"`
 
+	EnableDhcp6Server bool `default:"true" help:"Serve DHCPv6 and answer router solicitations for guests of ipv6 enabled networks"`
+
 	TunnelPaddingBytes int64 `help:"Specify tunnel padding bytes" default:"0"`
 
 	CheckSystemServices bool `help:"Check system services (ntpd, telegraf) on startup" default:"true"`
@@ -92,6 +106,7 @@ type SHostOptions struct {
 	UseBootVga             bool `default:"false" help:"Use boot VGA GPU for guest"`
 
 	EnableCpuBinding         bool `default:"true" help:"Enable cpu binding and rebalance"`
+	EnableNumaBinding        bool `default:"false" help:"Bind guest vcpus and memory to one numa node if the guest fits in it"`
 	EnableOpenflowController bool `default:"false"`
 
 	PingRegionInterval     int      `default:"60" help:"interval to ping region, deefault is 1 minute"`
@@ -131,6 +146,8 @@ type SHostOptions struct {
 	OvnMappedBridge           string `help:"name of bridge for mapped traffic management" default:"$HOST_OVN_MAPPED_BRIDGE|brmapped"`
 	OvnEipBridge              string `help:"name of bridge for eip traffic management" default:"$HOST_OVN_EIP_BRIDGE|breip"`
 	OvnUnderlayMtu            int    `help:"mtu of ovn underlay network" default:"1500"`
+	OvnAclLogFile             string `help:"ovn-controller log file where denied flows of logged security group rules are recorded" default:"$HOST_OVN_ACL_LOG_FILE|/var/log/openvswitch/ovn-controller.log"`
+	OvnAclLogReportInterval   int    `help:"interval in seconds to report hits of logged security group rules, 0 to disable" default:"60"`
 
 	EnableRemoteExecutor bool   `help:"Enable remote executor" default:"false"`
 	EnableHealthChecker  bool   `help:"enable host health checker" default:"true"`
@@ -77,6 +77,22 @@ func NewStorageManager(host hostutils.IHost) (*SStorageManager, error) {
 		}
 	}
 
+	for i, vg := range options.HostOptions.LvmVolumeGroups {
+		s := NewLVMStorage(ret, vg, i)
+		if ret.GetStorageByPath(s.GetPath()) != nil {
+			log.Errorf("lvm volume group %s duplicated", s.VgName)
+			continue
+		}
+		if err := s.Accessible(); err == nil {
+			ret.Storages = append(ret.Storages, s)
+			if allFull && s.GetFreeSizeMb() > MINIMAL_FREE_SPACE {
+				allFull = false
+			}
+		} else {
+			log.Errorf("storage %s not accessible: %s", s.Path, err)
+		}
+	}
+
 	for _, d := range options.HostOptions.SharedStorages {
 		s := ret.NewSharedStorageInstance(d, "")
 		if s != nil {
@@ -247,6 +263,9 @@ func (s *SStorageManager) NewSharedStorageInstance(mountPoint, storageType strin
 func (s *SStorageManager) InitSharedStorageImageCache(storageType, storagecacheId, imagecachePath string, storage IStorage) {
 	if utils.IsInStringArray(storageType, api.SHARED_FILE_STORAGE) {
 		s.InitSharedFileStorageImagecache(storagecacheId, imagecachePath)
+	} else if utils.IsInStringArray(storageType, []string{api.STORAGE_ISCSI_LVM, api.STORAGE_PLUGIN}) {
+		// images are cached on host local directory, then converted into volumes
+		s.InitSharedFileStorageImagecache(storagecacheId, imagecachePath)
 	} else if storageType == api.STORAGE_RBD {
 		if rbdStorage := s.GetStoragecacheById(storagecacheId); rbdStorage == nil {
 			s.AddRbdStorageImagecache(imagecachePath, storage, storagecacheId)
@@ -347,7 +366,7 @@ func StartSyncStorageSizeTask(interval time.Duration) {
 		manager := GetManager()
 		for i := 0; i < len(manager.Storages); i++ {
 			iS := manager.Storages[i]
-			if iS.StorageType() == api.STORAGE_LOCAL {
+			if utils.IsInStringArray(iS.StorageType(), []string{api.STORAGE_LOCAL, api.STORAGE_LVM}) {
 				err := iS.SyncStorageSize()
 				if err != nil {
 					log.Errorf("sync storage %s size failed: %s", iS.GetStorageName(), err)
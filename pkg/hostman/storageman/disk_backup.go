@@ -0,0 +1,210 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storageman
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/minio/minio-go"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	hostapi "yunion.io/x/onecloud/pkg/apis/host"
+	"yunion.io/x/onecloud/pkg/hostman/hostutils"
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/mcclient/modules"
+	"yunion.io/x/onecloud/pkg/util/procutils"
+	"yunion.io/x/onecloud/pkg/util/qemuimg"
+)
+
+func newDiskBackupClient(storage *hostapi.DiskBackupStorage) (*minio.Client, error) {
+	client, err := minio.New(storage.Endpoint, storage.AccessKey, storage.SecretKey, storage.UseSSL)
+	if err != nil {
+		return nil, errors.Wrap(err, "new minio client")
+	}
+	return client, nil
+}
+
+// fetchDiskBackupStorage fetches object storage config of the backup from
+// region, credentials of the object storage are not passed in requests
+func fetchDiskBackupStorage(ctx context.Context, backupId string) (*hostapi.DiskBackupStorage, error) {
+	res, err := modules.DiskBackups.GetSpecific(hostutils.GetComputeSession(ctx), backupId, "backup-storage", nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get backup storage of %s", backupId)
+	}
+	storage := &hostapi.DiskBackupStorage{}
+	if err := res.Unmarshal(storage); err != nil {
+		return nil, errors.Wrap(err, "unmarshal backup storage")
+	}
+	return storage, nil
+}
+
+func GetDiskBackupLocation(diskId, backupId string) string {
+	return fmt.Sprintf("%s/%s", diskId, backupId)
+}
+
+// GetDiskBackupTempDir returns a clean staging directory for backup files
+func GetDiskBackupTempDir(name string) (string, error) {
+	dir := path.Join(options.HostOptions.DiskBackupTempPath, name)
+	if output, err := procutils.NewCommand("rm", "-rf", dir).Output(); err != nil {
+		return "", errors.Wrapf(err, "rm %s: %s", dir, output)
+	}
+	if output, err := procutils.NewCommand("mkdir", "-p", dir).Output(); err != nil {
+		return "", errors.Wrapf(err, "mkdir %s: %s", dir, output)
+	}
+	return dir, nil
+}
+
+func CleanDiskBackupTempDir(dir string) {
+	if output, err := procutils.NewCommand("rm", "-rf", dir).Output(); err != nil {
+		log.Errorf("rm %s failed: %s, %s", dir, err, output)
+	}
+}
+
+// UploadDiskBackup uploads backup file to object storage, returns uploaded size in bytes
+func UploadDiskBackup(storage *hostapi.DiskBackupStorage, location, filePath string) (int64, error) {
+	client, err := newDiskBackupClient(storage)
+	if err != nil {
+		return 0, err
+	}
+	exists, err := client.BucketExists(storage.Bucket)
+	if err != nil {
+		return 0, errors.Wrap(err, "call bucket exists")
+	}
+	if !exists {
+		if err = client.MakeBucket(storage.Bucket, ""); err != nil {
+			return 0, errors.Wrap(err, "call make bucket")
+		}
+	}
+	size, err := client.FPutObject(storage.Bucket, location, filePath, minio.PutObjectOptions{})
+	if err != nil {
+		return 0, errors.Wrapf(err, "put object %s", location)
+	}
+	log.Infof("upload disk backup %s size %d", location, size)
+	return size, nil
+}
+
+func DownloadDiskBackup(storage *hostapi.DiskBackupStorage, location, filePath string) error {
+	client, err := newDiskBackupClient(storage)
+	if err != nil {
+		return err
+	}
+	err = client.FGetObject(storage.Bucket, location, filePath, minio.GetObjectOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "get object %s", location)
+	}
+	return nil
+}
+
+// OfflineDiskBackup takes a full backup of disk not used by any running guest
+func OfflineDiskBackup(disk IDisk, input *hostapi.GuestDiskBackupRequest) (*hostapi.GuestDiskBackupResponse, error) {
+	dir, err := GetDiskBackupTempDir(input.BackupId)
+	if err != nil {
+		return nil, err
+	}
+	defer CleanDiskBackupTempDir(dir)
+
+	img, err := qemuimg.NewQemuImage(disk.GetPath())
+	if err != nil {
+		return nil, errors.Wrapf(err, "open disk %s", disk.GetPath())
+	}
+	target := path.Join(dir, input.BackupId)
+	if err := img.Convert2Qcow2To(target, true); err != nil {
+		return nil, errors.Wrap(err, "convert disk")
+	}
+	return UploadDiskBackupFile(input, target, api.DISK_BACKUP_MODE_FULL)
+}
+
+func UploadDiskBackupFile(input *hostapi.GuestDiskBackupRequest, filePath, backupMode string) (*hostapi.GuestDiskBackupResponse, error) {
+	location := GetDiskBackupLocation(input.DiskId, input.BackupId)
+	storage, err := fetchDiskBackupStorage(context.Background(), input.BackupId)
+	if err != nil {
+		return nil, err
+	}
+	size, err := UploadDiskBackup(storage, location, filePath)
+	if err != nil {
+		return nil, err
+	}
+	return &hostapi.GuestDiskBackupResponse{
+		Location:   location,
+		SizeMb:     int(size / 1024 / 1024),
+		BackupMode: backupMode,
+	}, nil
+}
+
+func (s *SBaseStorage) CreateDiskFromBackup(ctx context.Context, disk IDisk, createParams *SDiskCreateByDiskinfo) (jsonutils.JSONObject, error) {
+	input := hostapi.DiskRecoveryFromBackup{}
+	if err := createParams.DiskInfo.Unmarshal(&input, "backup"); err != nil {
+		return nil, errors.Wrap(err, "unmarshal backup")
+	}
+	if len(input.Chain) == 0 {
+		return nil, fmt.Errorf("empty backup chain")
+	}
+	sizeMb, _ := createParams.DiskInfo.Int("size")
+
+	dir, err := GetDiskBackupTempDir(createParams.DiskId)
+	if err != nil {
+		return nil, err
+	}
+	defer CleanDiskBackupTempDir(dir)
+
+	storage, err := fetchDiskBackupStorage(ctx, input.Chain[len(input.Chain)-1].BackupId)
+	if err != nil {
+		return nil, err
+	}
+
+	var top *qemuimg.SQemuImage
+	for i := range input.Chain {
+		filePath := path.Join(dir, input.Chain[i].BackupId)
+		if err := DownloadDiskBackup(storage, input.Chain[i].Location, filePath); err != nil {
+			return nil, err
+		}
+		img, err := qemuimg.NewQemuImage(filePath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "open backup %s", filePath)
+		}
+		if top != nil {
+			// incremental backups only contain changed clusters, chain them up
+			if err := img.Rebase(top.Path, true); err != nil {
+				return nil, errors.Wrapf(err, "rebase %s to %s", filePath, top.Path)
+			}
+		}
+		top = img
+	}
+
+	diskPath := disk.GetPath()
+	if err := os.MkdirAll(path.Dir(diskPath), 0755); err != nil {
+		return nil, errors.Wrapf(err, "mkdir %s", path.Dir(diskPath))
+	}
+	if err := top.Convert2Qcow2To(diskPath, false); err != nil {
+		return nil, errors.Wrap(err, "convert backup chain")
+	}
+	img, err := qemuimg.NewQemuImage(diskPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "open disk %s", diskPath)
+	}
+	if sizeMb > int64(img.GetSizeMB()) {
+		if err := img.Resize(int(sizeMb)); err != nil {
+			return nil, errors.Wrap(err, "resize disk")
+		}
+	}
+	return disk.GetDiskDesc(), nil
+}
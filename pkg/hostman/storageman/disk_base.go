@@ -64,6 +64,14 @@ type IDisk interface {
 		deployInfo *deployapi.DeployInfo) (jsonutils.JSONObject, error)
 }
 
+// IClusterLockDisk is implemented by disks on storage shared by many hosts,
+// the lock must be held before guest open the disk
+type IClusterLockDisk interface {
+	// shared lock is only used while live migrating
+	AcquireLock(shared bool) error
+	ReleaseLock() error
+}
+
 type SBaseDisk struct {
 	Id      string
 	Storage IStorage
@@ -0,0 +1,120 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storageman
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	deployapi "yunion.io/x/onecloud/pkg/hostman/hostdeployer/apis"
+	"yunion.io/x/onecloud/pkg/hostman/storageman/lvmutils"
+)
+
+// SISCSILVMDisk is a logical volume of shared volume group,
+// it is only activated on the host running the guest
+type SISCSILVMDisk struct {
+	SLVMDisk
+}
+
+func NewISCSILVMDisk(storage IStorage, id string) *SISCSILVMDisk {
+	var ret = new(SISCSILVMDisk)
+	ret.SLVMDisk = *NewLVMDisk(storage, id)
+	return ret
+}
+
+func (d *SISCSILVMDisk) GetType() string {
+	return api.STORAGE_ISCSI_LVM
+}
+
+// Probe never activate the volume, that takes the cluster lock
+func (d *SISCSILVMDisk) Probe() error {
+	_, err := d.getLv()
+	return err
+}
+
+func (d *SISCSILVMDisk) AcquireLock(shared bool) error {
+	storage := d.getStorage()
+	if shared {
+		return lvmutils.ActivateLogicalVolumeShared(storage.VgName, d.Id)
+	}
+	return lvmutils.ActivateLogicalVolumeExclusive(storage.VgName, d.Id)
+}
+
+func (d *SISCSILVMDisk) ReleaseLock() error {
+	return lvmutils.DeactivateLogicalVolume(d.getStorage().VgName, d.Id)
+}
+
+// lvcreate activate the new volume exclusively, release it after data prepared
+func (d *SISCSILVMDisk) CreateRaw(ctx context.Context, sizeMb int, diskFromat string, fsFormat string, encryption bool, diskId string, back string) (jsonutils.JSONObject, error) {
+	if _, err := d.SLVMDisk.CreateRaw(ctx, sizeMb, diskFromat, fsFormat, encryption, diskId, back); err != nil {
+		return nil, err
+	}
+	if err := d.ReleaseLock(); err != nil {
+		return nil, err
+	}
+	return d.GetDiskDesc(), nil
+}
+
+func (d *SISCSILVMDisk) CreateFromTemplate(ctx context.Context, imageId string, format string, size int64) (jsonutils.JSONObject, error) {
+	if _, err := d.SLVMDisk.CreateFromTemplate(ctx, imageId, format, size); err != nil {
+		return nil, err
+	}
+	if err := d.ReleaseLock(); err != nil {
+		return nil, err
+	}
+	return d.GetDiskDesc(), nil
+}
+
+// resize need the volume activated, it is active when guest is running
+func (d *SISCSILVMDisk) Resize(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	lv, err := d.getLv()
+	if err != nil {
+		return nil, err
+	}
+	if !lv.IsActive() {
+		if err := d.AcquireLock(false); err != nil {
+			return nil, err
+		}
+		defer d.ReleaseLock()
+	}
+	return d.SLVMDisk.Resize(ctx, params)
+}
+
+// guest fs is deployed before guest start, activate volume while deploying
+func (d *SISCSILVMDisk) DeployGuestFs(diskPath string, guestDesc *jsonutils.JSONDict,
+	deployInfo *deployapi.DeployInfo) (jsonutils.JSONObject, error) {
+	lv, err := d.getLv()
+	if err != nil {
+		return nil, err
+	}
+	if !lv.IsActive() {
+		if err := d.AcquireLock(false); err != nil {
+			return nil, err
+		}
+		defer d.ReleaseLock()
+	}
+	return d.SLVMDisk.DeployGuestFs(diskPath, guestDesc, deployInfo)
+}
+
+func (d *SISCSILVMDisk) CreateSnapshot(snapshotId string) error {
+	return fmt.Errorf("iscsi lvm storage not support snapshot")
+}
+
+func (d *SISCSILVMDisk) ResetFromSnapshot(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	return nil, fmt.Errorf("iscsi lvm storage not support snapshot")
+}
@@ -0,0 +1,294 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storageman
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/hostman/hostutils"
+	"yunion.io/x/onecloud/pkg/hostman/storageman/lvmutils"
+	"yunion.io/x/onecloud/pkg/util/procutils"
+	"yunion.io/x/onecloud/pkg/util/qemuimg"
+	"yunion.io/x/onecloud/pkg/util/qemutils"
+)
+
+type SLVMDisk struct {
+	SBaseDisk
+}
+
+func NewLVMDisk(storage IStorage, id string) *SLVMDisk {
+	var ret = new(SLVMDisk)
+	ret.SBaseDisk = *NewBaseDisk(storage, id)
+	return ret
+}
+
+func (d *SLVMDisk) GetType() string {
+	return api.STORAGE_LVM
+}
+
+func (d *SLVMDisk) getStorage() *SLVMStorage {
+	return d.Storage.(ilvmStorage).getLvmStorage()
+}
+
+func (d *SLVMDisk) getLv() (*lvmutils.SLogicalVolume, error) {
+	return lvmutils.GetLogicalVolume(d.getStorage().VgName, d.Id)
+}
+
+func (d *SLVMDisk) Probe() error {
+	lv, err := d.getLv()
+	if err != nil {
+		return err
+	}
+	if !lv.IsActive() {
+		return lvmutils.ActivateLogicalVolume(lv.VgName, lv.Name)
+	}
+	return nil
+}
+
+func (d *SLVMDisk) GetSnapshotDir() string {
+	return ""
+}
+
+func (d *SLVMDisk) GetDiskDesc() jsonutils.JSONObject {
+	lv, err := d.getLv()
+	if err != nil {
+		log.Errorf("get logical volume %s: %s", d.GetPath(), err)
+		return nil
+	}
+	var desc = jsonutils.NewDict()
+	desc.Set("disk_id", jsonutils.NewString(d.Id))
+	desc.Set("disk_size", jsonutils.NewInt(lv.SizeMb))
+	desc.Set("format", jsonutils.NewString("raw"))
+	desc.Set("disk_format", jsonutils.NewString("raw"))
+	desc.Set("disk_path", jsonutils.NewString(d.GetPath()))
+	return desc
+}
+
+func (d *SLVMDisk) GetDiskSetupScripts(idx int) string {
+	return fmt.Sprintf("DISK_%d=%s\n", idx, d.GetPath())
+}
+
+func (d *SLVMDisk) Delete(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	log.Infof("Delete guest disk %s", d.GetPath())
+	storage := d.getStorage()
+	if err := lvmutils.RemoveLogicalVolume(storage.VgName, d.Id); err != nil {
+		if _, e := d.getLv(); errors.Cause(e) != cloudprovider.ErrNotFound {
+			return nil, err
+		}
+	}
+	d.Storage.RemoveDisk(d)
+	return nil, nil
+}
+
+func (d *SLVMDisk) OnRebuildRoot(ctx context.Context, params jsonutils.JSONObject) error {
+	_, err := d.Delete(ctx, params)
+	return err
+}
+
+func (d *SLVMDisk) Resize(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	diskInfo, ok := params.(*jsonutils.JSONDict)
+	if !ok {
+		return nil, hostutils.ParamsError
+	}
+	sizeMb, _ := diskInfo.Int("size")
+	lv, err := d.getLv()
+	if err != nil {
+		return nil, err
+	}
+	if sizeMb > lv.SizeMb {
+		if err := lvmutils.ExtendLogicalVolume(lv.VgName, lv.Name, sizeMb); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := d.ResizeFs(d.GetPath()); err != nil {
+		return nil, errors.Wrapf(err, "resize fs %s", d.GetPath())
+	}
+
+	return d.GetDiskDesc(), nil
+}
+
+func (d *SLVMDisk) createLv(sizeMb int64) error {
+	storage := d.getStorage()
+	if storage.IsThin() {
+		return lvmutils.CreateThinVolume(storage.VgName, storage.ThinPool, d.Id, sizeMb)
+	}
+	return lvmutils.CreateLogicalVolume(storage.VgName, d.Id, sizeMb)
+}
+
+func (d *SLVMDisk) CreateRaw(ctx context.Context, sizeMb int, diskFromat string, fsFormat string, encryption bool, diskId string, back string) (jsonutils.JSONObject, error) {
+	if err := d.createLv(int64(sizeMb)); err != nil {
+		return nil, err
+	}
+
+	if utils.IsInStringArray(fsFormat, []string{"swap", "ext2", "ext3", "ext4", "xfs"}) {
+		d.FormatFs(fsFormat, diskId, d.GetPath())
+	}
+
+	return d.GetDiskDesc(), nil
+}
+
+// CreateFromTemplate convert cached image into logical volume,
+// volume size is the larger one of requested size and image virtual size
+func (d *SLVMDisk) CreateFromTemplate(ctx context.Context, imageId string, format string, size int64) (jsonutils.JSONObject, error) {
+	var imageCacheManager = storageManager.GetStoragecacheById(d.Storage.GetStoragecacheId())
+	if imageCacheManager == nil {
+		imageCacheManager = storageManager.LocalStorageImagecacheManager
+	}
+	imageCache := imageCacheManager.AcquireImage(ctx, imageId, d.GetZoneName(), "", "")
+	if imageCache == nil {
+		return nil, fmt.Errorf("Fail to fetch image %s", imageId)
+	}
+	defer imageCacheManager.ReleaseImage(ctx, imageId)
+
+	cacheImagePath := imageCache.GetPath()
+	img, err := qemuimg.NewQemuImage(cacheImagePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "open image %s", cacheImagePath)
+	}
+	sizeMb := int64(img.GetSizeMB())
+	if size > sizeMb {
+		sizeMb = size
+	}
+	if err := d.createLv(sizeMb); err != nil {
+		return nil, err
+	}
+	out, err := procutils.NewRemoteCommandAsFarAsPossible(qemutils.GetQemuImg(),
+		"convert", "-n", "-O", "raw", cacheImagePath, d.GetPath()).Output()
+	if err != nil {
+		lvmutils.RemoveLogicalVolume(d.getStorage().VgName, d.Id)
+		return nil, errors.Wrapf(err, "convert image %s to %s: %s", cacheImagePath, d.GetPath(), out)
+	}
+	return d.GetDiskDesc(), nil
+}
+
+func (d *SLVMDisk) CreateFromImageFuse(ctx context.Context, url string, size int64) error {
+	return fmt.Errorf("Not support")
+}
+
+func (d *SLVMDisk) CreateFromUrl(ctx context.Context, url string, size int64) error {
+	return fmt.Errorf("Not support")
+}
+
+func (d *SLVMDisk) CreateFromSnapshotLocation(ctx context.Context, location string, size int64) error {
+	return fmt.Errorf("Not support")
+}
+
+func (d *SLVMDisk) PostCreateFromImageFuse() {
+	log.Errorf("Not support PostCreateFromImageFuse")
+}
+
+func (d *SLVMDisk) PrepareMigrate(liveMigrate bool) (string, error) {
+	return "", fmt.Errorf("Not support")
+}
+
+func (d *SLVMDisk) PrepareSaveToGlance(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	return nil, fmt.Errorf("Not support")
+}
+
+func (d *SLVMDisk) CreateSnapshot(snapshotId string) error {
+	storage := d.getStorage()
+	if !storage.IsThin() {
+		return fmt.Errorf("lvm storage %s without thin pool not support snapshot", storage.VgName)
+	}
+	return lvmutils.CreateThinSnapshot(storage.VgName, d.Id, storage.getSnapshotLvName(d.Id, snapshotId))
+}
+
+func (d *SLVMDisk) DeleteSnapshot(snapshotId, convertSnapshot string, pendingDelete bool) error {
+	return d.DoDeleteSnapshot(snapshotId)
+}
+
+func (d *SLVMDisk) DoDeleteSnapshot(snapshotId string) error {
+	storage := d.getStorage()
+	exist, err := storage.IsSnapshotExist(d.Id, snapshotId)
+	if err != nil {
+		return err
+	}
+	if !exist {
+		return nil
+	}
+	return lvmutils.RemoveLogicalVolume(storage.VgName, storage.getSnapshotLvName(d.Id, snapshotId))
+}
+
+func (d *SLVMDisk) DiskSnapshot(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	snapshotId, ok := params.(string)
+	if !ok {
+		return nil, hostutils.ParamsError
+	}
+	return nil, d.CreateSnapshot(snapshotId)
+}
+
+func (d *SLVMDisk) DiskDeleteSnapshot(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	snapshotId, ok := params.(string)
+	if !ok {
+		return nil, hostutils.ParamsError
+	}
+	if err := d.DeleteSnapshot(snapshotId, "", false); err != nil {
+		return nil, err
+	}
+	res := jsonutils.NewDict()
+	res.Set("deleted", jsonutils.JSONTrue)
+	return res, nil
+}
+
+func (d *SLVMDisk) DeleteAllSnapshot() error {
+	_, err := d.Storage.DeleteSnapshots(context.Background(), d.Id)
+	return err
+}
+
+// thin snapshots are independent volumes, nothing to convert
+func (d *SLVMDisk) CleanupSnapshots(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	return nil, nil
+}
+
+// ResetFromSnapshot replace disk volume with a new thin snapshot of the snapshot volume
+func (d *SLVMDisk) ResetFromSnapshot(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	resetParams, ok := params.(*SDiskReset)
+	if !ok {
+		return nil, hostutils.ParamsError
+	}
+	storage := d.getStorage()
+	snapName := storage.getSnapshotLvName(d.Id, resetParams.SnapshotId)
+	if _, err := lvmutils.GetLogicalVolume(storage.VgName, snapName); err != nil {
+		return nil, errors.Wrapf(err, "get snapshot %s", resetParams.SnapshotId)
+	}
+	// keep the origin volume aside until the new one is created, so that
+	// a failed reset leaves the disk as it was
+	origName := fmt.Sprintf("reset_%s", d.Id)
+	if _, err := lvmutils.GetLogicalVolume(storage.VgName, origName); err == nil {
+		return nil, errors.Errorf("volume %s of a previous reset exists, check it manually", origName)
+	}
+	if err := lvmutils.RenameLogicalVolume(storage.VgName, d.Id, origName); err != nil {
+		return nil, errors.Wrap(err, "rename origin volume")
+	}
+	if err := lvmutils.CreateThinSnapshot(storage.VgName, snapName, d.Id); err != nil {
+		if e := lvmutils.RenameLogicalVolume(storage.VgName, origName, d.Id); e != nil {
+			log.Errorf("rename %s back to %s: %v", origName, d.Id, e)
+		}
+		return nil, errors.Wrap(err, "reset disk from thin snapshot")
+	}
+	if err := lvmutils.RemoveLogicalVolume(storage.VgName, origName); err != nil {
+		log.Errorf("remove origin volume %s: %v", origName, err)
+	}
+	return nil, d.Probe()
+}
@@ -136,3 +136,17 @@ func NewGPFSDisk(storage IStorage, id string) *SGPFSDisk {
 func (d *SGPFSDisk) GetType() string {
 	return api.STORAGE_GPFS
 }
+
+type SCIFSDisk struct {
+	SNasDisk
+}
+
+func NewCIFSDisk(storage IStorage, id string) *SCIFSDisk {
+	return &SCIFSDisk{
+		SNasDisk: *NewNasDisk(storage, id),
+	}
+}
+
+func (d *SCIFSDisk) GetType() string {
+	return api.STORAGE_CIFS
+}
@@ -0,0 +1,394 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storageman
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	deployapi "yunion.io/x/onecloud/pkg/hostman/hostdeployer/apis"
+	"yunion.io/x/onecloud/pkg/hostman/hostutils"
+	"yunion.io/x/onecloud/pkg/hostman/storageman/storageplugin"
+	pb "yunion.io/x/onecloud/pkg/hostman/storageman/storageplugin/api"
+	"yunion.io/x/onecloud/pkg/util/fileutils2"
+	"yunion.io/x/onecloud/pkg/util/procutils"
+	"yunion.io/x/onecloud/pkg/util/qemuimg"
+	"yunion.io/x/onecloud/pkg/util/qemutils"
+)
+
+// SPluginDisk is a volume of storage plugin, the volume is published
+// at disk path only while guest running or host preparing data
+type SPluginDisk struct {
+	SBaseDisk
+}
+
+func NewPluginDisk(storage IStorage, id string) *SPluginDisk {
+	var ret = new(SPluginDisk)
+	ret.SBaseDisk = *NewBaseDisk(storage, id)
+	return ret
+}
+
+func (d *SPluginDisk) GetType() string {
+	return api.STORAGE_PLUGIN
+}
+
+func (d *SPluginDisk) getStorage() *SPluginStorage {
+	return d.Storage.(*SPluginStorage)
+}
+
+func (d *SPluginDisk) getVolume() (*pb.Volume, error) {
+	storage := d.getStorage()
+	client, err := storage.getClient()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := storageplugin.NewContext(pluginQueryTimeout)
+	defer cancel()
+	vol, err := client.GetVolume(ctx, &pb.GetVolumeRequest{Options: storage.Options, VolumeId: d.Id})
+	if err != nil {
+		return nil, storageplugin.Error(err, "GetVolume")
+	}
+	return vol, nil
+}
+
+func (d *SPluginDisk) Probe() error {
+	_, err := d.getVolume()
+	return err
+}
+
+func (d *SPluginDisk) GetSnapshotDir() string {
+	return ""
+}
+
+func (d *SPluginDisk) GetDiskDesc() jsonutils.JSONObject {
+	vol, err := d.getVolume()
+	if err != nil {
+		log.Errorf("get plugin volume %s: %s", d.Id, err)
+		return nil
+	}
+	var desc = jsonutils.NewDict()
+	desc.Set("disk_id", jsonutils.NewString(d.Id))
+	desc.Set("disk_size", jsonutils.NewInt(vol.SizeMb))
+	desc.Set("format", jsonutils.NewString("raw"))
+	desc.Set("disk_format", jsonutils.NewString("raw"))
+	desc.Set("disk_path", jsonutils.NewString(d.GetPath()))
+	return desc
+}
+
+func (d *SPluginDisk) GetDiskSetupScripts(idx int) string {
+	return fmt.Sprintf("DISK_%d=%s\n", idx, d.GetPath())
+}
+
+// AcquireLock publish volume at disk path before guest start
+func (d *SPluginDisk) AcquireLock(shared bool) error {
+	storage := d.getStorage()
+	client, err := storage.getClient()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := storageplugin.NewContext(pluginVolumeTimeout)
+	defer cancel()
+	_, err = client.PublishVolume(ctx, &pb.PublishVolumeRequest{
+		Options:    storage.Options,
+		VolumeId:   d.Id,
+		TargetPath: d.GetPath(),
+		Shared:     shared,
+	})
+	return storageplugin.Error(err, "PublishVolume")
+}
+
+func (d *SPluginDisk) ReleaseLock() error {
+	storage := d.getStorage()
+	client, err := storage.getClient()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := storageplugin.NewContext(pluginVolumeTimeout)
+	defer cancel()
+	_, err = client.UnpublishVolume(ctx, &pb.UnpublishVolumeRequest{
+		Options:    storage.Options,
+		VolumeId:   d.Id,
+		TargetPath: d.GetPath(),
+	})
+	return storageplugin.Error(err, "UnpublishVolume")
+}
+
+// withPublished publish volume for host side operations if it isn't published yet
+func (d *SPluginDisk) withPublished(f func() error) error {
+	if fileutils2.Exists(d.GetPath()) {
+		return f()
+	}
+	if err := d.AcquireLock(false); err != nil {
+		return err
+	}
+	defer d.ReleaseLock()
+	return f()
+}
+
+func (d *SPluginDisk) DeployGuestFs(diskPath string, guestDesc *jsonutils.JSONDict,
+	deployInfo *deployapi.DeployInfo) (jsonutils.JSONObject, error) {
+	var ret jsonutils.JSONObject
+	err := d.withPublished(func() error {
+		var err error
+		ret, err = d.SBaseDisk.DeployGuestFs(diskPath, guestDesc, deployInfo)
+		return err
+	})
+	return ret, err
+}
+
+func (d *SPluginDisk) Delete(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	log.Infof("Delete plugin volume %s", d.Id)
+	storage := d.getStorage()
+	client, err := storage.getClient()
+	if err != nil {
+		return nil, err
+	}
+	pctx, cancel := storageplugin.NewContext(pluginVolumeTimeout)
+	defer cancel()
+	_, err = client.DeleteVolume(pctx, &pb.DeleteVolumeRequest{Options: storage.Options, VolumeId: d.Id})
+	if err = storageplugin.Error(err, "DeleteVolume"); err != nil && errors.Cause(err) != cloudprovider.ErrNotFound {
+		return nil, err
+	}
+	d.Storage.RemoveDisk(d)
+	return nil, nil
+}
+
+func (d *SPluginDisk) OnRebuildRoot(ctx context.Context, params jsonutils.JSONObject) error {
+	_, err := d.Delete(ctx, params)
+	return err
+}
+
+func (d *SPluginDisk) Resize(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	diskInfo, ok := params.(*jsonutils.JSONDict)
+	if !ok {
+		return nil, hostutils.ParamsError
+	}
+	sizeMb, _ := diskInfo.Int("size")
+	storage := d.getStorage()
+	client, err := storage.getClient()
+	if err != nil {
+		return nil, err
+	}
+	pctx, cancel := storageplugin.NewContext(pluginVolumeTimeout)
+	defer cancel()
+	_, err = client.ResizeVolume(pctx, &pb.ResizeVolumeRequest{Options: storage.Options, VolumeId: d.Id, SizeMb: sizeMb})
+	if err != nil {
+		return nil, storageplugin.Error(err, "ResizeVolume")
+	}
+
+	err = d.withPublished(func() error {
+		return d.ResizeFs(d.GetPath())
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "resize fs %s", d.GetPath())
+	}
+
+	return d.GetDiskDesc(), nil
+}
+
+func (d *SPluginDisk) createVolume(sizeMb int64) error {
+	storage := d.getStorage()
+	client, err := storage.getClient()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := storageplugin.NewContext(pluginVolumeTimeout)
+	defer cancel()
+	_, err = client.CreateVolume(ctx, &pb.CreateVolumeRequest{Options: storage.Options, VolumeId: d.Id, SizeMb: sizeMb})
+	return storageplugin.Error(err, "CreateVolume")
+}
+
+func (d *SPluginDisk) CreateRaw(ctx context.Context, sizeMb int, diskFromat string, fsFormat string, encryption bool, diskId string, back string) (jsonutils.JSONObject, error) {
+	if err := d.createVolume(int64(sizeMb)); err != nil {
+		return nil, err
+	}
+
+	if utils.IsInStringArray(fsFormat, []string{"swap", "ext2", "ext3", "ext4", "xfs"}) {
+		err := d.withPublished(func() error {
+			d.FormatFs(fsFormat, diskId, d.GetPath())
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return d.GetDiskDesc(), nil
+}
+
+// CreateFromTemplate convert cached image into published volume,
+// volume size is the larger one of requested size and image virtual size
+func (d *SPluginDisk) CreateFromTemplate(ctx context.Context, imageId string, format string, size int64) (jsonutils.JSONObject, error) {
+	var imageCacheManager = storageManager.GetStoragecacheById(d.Storage.GetStoragecacheId())
+	if imageCacheManager == nil {
+		imageCacheManager = storageManager.LocalStorageImagecacheManager
+	}
+	imageCache := imageCacheManager.AcquireImage(ctx, imageId, d.GetZoneName(), "", "")
+	if imageCache == nil {
+		return nil, fmt.Errorf("Fail to fetch image %s", imageId)
+	}
+	defer imageCacheManager.ReleaseImage(ctx, imageId)
+
+	cacheImagePath := imageCache.GetPath()
+	img, err := qemuimg.NewQemuImage(cacheImagePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "open image %s", cacheImagePath)
+	}
+	sizeMb := int64(img.GetSizeMB())
+	if size > sizeMb {
+		sizeMb = size
+	}
+	if err := d.createVolume(sizeMb); err != nil {
+		return nil, err
+	}
+	err = d.withPublished(func() error {
+		out, err := procutils.NewRemoteCommandAsFarAsPossible(qemutils.GetQemuImg(),
+			"convert", "-n", "-O", "raw", cacheImagePath, d.GetPath()).Output()
+		if err != nil {
+			return errors.Wrapf(err, "convert image %s to %s: %s", cacheImagePath, d.GetPath(), out)
+		}
+		return nil
+	})
+	if err != nil {
+		d.Delete(ctx, nil)
+		return nil, err
+	}
+	return d.GetDiskDesc(), nil
+}
+
+func (d *SPluginDisk) CreateFromImageFuse(ctx context.Context, url string, size int64) error {
+	return fmt.Errorf("Not support")
+}
+
+func (d *SPluginDisk) CreateFromUrl(ctx context.Context, url string, size int64) error {
+	return fmt.Errorf("Not support")
+}
+
+func (d *SPluginDisk) CreateFromSnapshotLocation(ctx context.Context, location string, size int64) error {
+	return fmt.Errorf("Not support")
+}
+
+func (d *SPluginDisk) PostCreateFromImageFuse() {
+	log.Errorf("Not support PostCreateFromImageFuse")
+}
+
+func (d *SPluginDisk) PrepareMigrate(liveMigrate bool) (string, error) {
+	return "", fmt.Errorf("Not support")
+}
+
+func (d *SPluginDisk) PrepareSaveToGlance(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	return nil, fmt.Errorf("Not support")
+}
+
+func (d *SPluginDisk) CreateSnapshot(snapshotId string) error {
+	storage := d.getStorage()
+	client, err := storage.getClient()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := storageplugin.NewContext(pluginVolumeTimeout)
+	defer cancel()
+	_, err = client.CreateSnapshot(ctx, &pb.CreateSnapshotRequest{Options: storage.Options, VolumeId: d.Id, SnapshotId: snapshotId})
+	return storageplugin.Error(err, "CreateSnapshot")
+}
+
+func (d *SPluginDisk) DeleteSnapshot(snapshotId, convertSnapshot string, pendingDelete bool) error {
+	return d.DoDeleteSnapshot(snapshotId)
+}
+
+func (d *SPluginDisk) DoDeleteSnapshot(snapshotId string) error {
+	storage := d.getStorage()
+	client, err := storage.getClient()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := storageplugin.NewContext(pluginVolumeTimeout)
+	defer cancel()
+	_, err = client.DeleteSnapshot(ctx, &pb.DeleteSnapshotRequest{Options: storage.Options, VolumeId: d.Id, SnapshotId: snapshotId})
+	if err = storageplugin.Error(err, "DeleteSnapshot"); err != nil && errors.Cause(err) != cloudprovider.ErrNotFound {
+		return err
+	}
+	return nil
+}
+
+func (d *SPluginDisk) DiskSnapshot(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	snapshotId, ok := params.(string)
+	if !ok {
+		return nil, hostutils.ParamsError
+	}
+	return nil, d.CreateSnapshot(snapshotId)
+}
+
+func (d *SPluginDisk) DiskDeleteSnapshot(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	snapshotId, ok := params.(string)
+	if !ok {
+		return nil, hostutils.ParamsError
+	}
+	if err := d.DeleteSnapshot(snapshotId, "", false); err != nil {
+		return nil, err
+	}
+	res := jsonutils.NewDict()
+	res.Set("deleted", jsonutils.JSONTrue)
+	return res, nil
+}
+
+func (d *SPluginDisk) DeleteAllSnapshot() error {
+	return nil
+}
+
+func (d *SPluginDisk) CleanupSnapshots(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	return nil, nil
+}
+
+// ResetFromSnapshot recreate volume from snapshot with the same volume id
+func (d *SPluginDisk) ResetFromSnapshot(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	resetParams, ok := params.(*SDiskReset)
+	if !ok {
+		return nil, hostutils.ParamsError
+	}
+	vol, err := d.getVolume()
+	if err != nil {
+		return nil, err
+	}
+	storage := d.getStorage()
+	client, err := storage.getClient()
+	if err != nil {
+		return nil, err
+	}
+	pctx, cancel := storageplugin.NewContext(pluginVolumeTimeout)
+	defer cancel()
+	_, err = client.DeleteVolume(pctx, &pb.DeleteVolumeRequest{Options: storage.Options, VolumeId: d.Id})
+	if err != nil {
+		return nil, storageplugin.Error(err, "DeleteVolume")
+	}
+	_, err = client.CreateVolume(pctx, &pb.CreateVolumeRequest{
+		Options:     storage.Options,
+		VolumeId:    d.Id,
+		SizeMb:      vol.SizeMb,
+		SnapshotId:  resetParams.SnapshotId,
+		SrcVolumeId: d.Id,
+	})
+	if err != nil {
+		return nil, storageplugin.Error(err, "CreateVolume from snapshot")
+	}
+	return nil, nil
+}
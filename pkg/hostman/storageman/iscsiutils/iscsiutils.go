@@ -0,0 +1,253 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iscsiutils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/util/procutils"
+)
+
+const (
+	// node records of open-iscsi on host
+	NodeRecordsPath = "/etc/iscsi/nodes"
+	// node records with chap secrets are prepared here, which is shared
+	// with host, and then copied to NodeRecordsPath
+	NodeRecordTmpPath = "/opt/cloud/workspace/iscsi-nodes"
+)
+
+type SSession struct {
+	Portal string
+	Target string
+}
+
+// normalize portal to ip:port, iscsiadm reports default port 3260
+func normalizePortal(portal string) string {
+	if !strings.Contains(portal, ":") {
+		return portal + ":3260"
+	}
+	return portal
+}
+
+// parse output of `iscsiadm -m session`, lines like
+// tcp: [1] 192.168.1.10:3260,1 iqn.2003-01.org.linux-iscsi.target:lun0 (non-flash)
+func parseSessions(output string) []SSession {
+	ret := make([]SSession, 0)
+	for _, line := range strings.Split(output, "\n") {
+		segs := strings.Fields(line)
+		if len(segs) < 4 {
+			continue
+		}
+		portal := segs[2]
+		if idx := strings.Index(portal, ","); idx >= 0 {
+			portal = portal[:idx]
+		}
+		ret = append(ret, SSession{Portal: portal, Target: segs[3]})
+	}
+	return ret
+}
+
+func runIscsiadm(args ...string) (string, error) {
+	out, err := procutils.NewRemoteCommandAsFarAsPossible("iscsiadm", args...).Output()
+	if err != nil {
+		return "", errors.Wrapf(err, "iscsiadm %s: %s", strings.Join(args, " "), out)
+	}
+	return string(out), nil
+}
+
+func ListSessions() ([]SSession, error) {
+	out, err := procutils.NewRemoteCommandAsFarAsPossible("iscsiadm", "-m", "session").Output()
+	if err != nil {
+		// iscsiadm exit with 21 when no active sessions
+		if strings.Contains(string(out), "No active sessions") {
+			return []SSession{}, nil
+		}
+		return nil, errors.Wrapf(err, "iscsiadm -m session: %s", out)
+	}
+	return parseSessions(string(out)), nil
+}
+
+func IsLoggedIn(portal, target string) (bool, error) {
+	sessions, err := ListSessions()
+	if err != nil {
+		return false, err
+	}
+	portal = normalizePortal(portal)
+	for _, s := range sessions {
+		if s.Target == target && normalizePortal(s.Portal) == portal {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func Discovery(portal string) error {
+	_, err := runIscsiadm("-m", "discovery", "-t", "sendtargets", "-p", portal)
+	return err
+}
+
+func setNodeAuth(portal, target, name, value string) error {
+	_, err := runIscsiadm("-m", "node", "-T", target, "-p", portal, "--op", "update", "-n", name, "-v", value)
+	return err
+}
+
+// portal ip:port to the prefix of node record entries, ip,port,tpgt
+func nodeRecordPrefix(portal string) string {
+	portal = normalizePortal(portal)
+	idx := strings.LastIndex(portal, ":")
+	ip := strings.Trim(portal[:idx], "[]")
+	return fmt.Sprintf("%s,%s,", ip, portal[idx+1:])
+}
+
+// updateNodeRecord sets values of keys in content of a node record,
+// keys absent in the record are added at the end
+func updateNodeRecord(content string, values map[string]string) string {
+	lines := []string{}
+	set := map[string]bool{}
+	for _, line := range strings.Split(strings.TrimRight(content, "\n"), "\n") {
+		if idx := strings.Index(line, "="); idx > 0 {
+			key := strings.TrimSpace(line[:idx])
+			if value, ok := values[key]; ok {
+				line = fmt.Sprintf("%s = %s", key, value)
+				set[key] = true
+			}
+		}
+		lines = append(lines, line)
+	}
+	added := []string{}
+	for _, key := range []string{
+		"node.session.auth.authmethod",
+		"node.session.auth.username",
+		"node.session.auth.password",
+	} {
+		if value, ok := values[key]; ok && !set[key] {
+			added = append(added, fmt.Sprintf("%s = %s", key, value))
+		}
+	}
+	// keep the end mark of the record last
+	end := len(lines)
+	if end > 0 && strings.HasPrefix(lines[end-1], "# END RECORD") {
+		end--
+	}
+	lines = append(lines[:end], append(added, lines[end:]...)...)
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func runRemote(name string, args ...string) (string, error) {
+	out, err := procutils.NewRemoteCommandAsFarAsPossible(name, args...).Output()
+	if err != nil {
+		return "", errors.Wrapf(err, "%s %s: %s", name, strings.Join(args, " "), out)
+	}
+	return string(out), nil
+}
+
+// getNodeRecordPath returns path of the node record created by discovery,
+// which is a directory holding file default in newer open-iscsi
+func getNodeRecordPath(portal, target string) (string, error) {
+	targetDir := path.Join(NodeRecordsPath, target)
+	out, err := runRemote("ls", targetDir)
+	if err != nil {
+		return "", err
+	}
+	prefix := nodeRecordPrefix(portal)
+	for _, entry := range strings.Fields(out) {
+		if !strings.HasPrefix(entry, prefix) {
+			continue
+		}
+		recordPath := path.Join(targetDir, entry)
+		if _, err := runRemote("test", "-d", recordPath); err == nil {
+			recordPath = path.Join(recordPath, "default")
+		}
+		return recordPath, nil
+	}
+	return "", errors.Wrapf(errors.ErrNotFound, "node record of %s in %s", portal, targetDir)
+}
+
+// setNodeChap writes chap settings to the node record through a file,
+// iscsiadm takes values only from arguments, which shows the password in
+// the process list
+func setNodeChap(portal, target, chapUser, chapPassword string) error {
+	recordPath, err := getNodeRecordPath(portal, target)
+	if err != nil {
+		return err
+	}
+	content, err := runRemote("cat", recordPath)
+	if err != nil {
+		return err
+	}
+	content = updateNodeRecord(content, map[string]string{
+		"node.session.auth.authmethod": "CHAP",
+		"node.session.auth.username":   chapUser,
+		"node.session.auth.password":   chapPassword,
+	})
+	if err := os.MkdirAll(NodeRecordTmpPath, 0700); err != nil {
+		return errors.Wrapf(err, "mkdir %s", NodeRecordTmpPath)
+	}
+	tmp, err := ioutil.TempFile(NodeRecordTmpPath, "node")
+	if err != nil {
+		return errors.Wrap(err, "create node record file")
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.WriteString(content)
+	tmp.Close()
+	if err != nil {
+		return errors.Wrap(err, "write node record file")
+	}
+	_, err = runRemote("cp", tmp.Name(), recordPath)
+	return err
+}
+
+// Login discovery targets of portal and login target,
+// chap authentication is enabled if chapUser given
+func Login(portal, target, chapUser, chapPassword string) error {
+	loggedIn, err := IsLoggedIn(portal, target)
+	if err != nil {
+		return err
+	}
+	if loggedIn {
+		return nil
+	}
+	if err := Discovery(portal); err != nil {
+		return err
+	}
+	if len(chapUser) > 0 {
+		if err := setNodeChap(portal, target, chapUser, chapPassword); err != nil {
+			return errors.Wrap(err, "set chap of node record")
+		}
+	}
+	if err := setNodeAuth(portal, target, "node.startup", "automatic"); err != nil {
+		return err
+	}
+	_, err = runIscsiadm("-m", "node", "-T", target, "-p", portal, "--login")
+	return err
+}
+
+func Logout(portal, target string) error {
+	loggedIn, err := IsLoggedIn(portal, target)
+	if err != nil {
+		return err
+	}
+	if !loggedIn {
+		return nil
+	}
+	_, err = runIscsiadm("-m", "node", "-T", target, "-p", portal, "--logout")
+	return err
+}
@@ -0,0 +1,79 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iscsiutils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSessions(t *testing.T) {
+	output := "tcp: [1] 192.168.1.10:3260,1 iqn.2003-01.org.linux-iscsi.san:lun0 (non-flash)\n" +
+		"tcp: [2] [fe80::1]:3260,1 iqn.2003-01.org.linux-iscsi.san:lun1 (non-flash)\n\n"
+	want := []SSession{
+		{Portal: "192.168.1.10:3260", Target: "iqn.2003-01.org.linux-iscsi.san:lun0"},
+		{Portal: "[fe80::1]:3260", Target: "iqn.2003-01.org.linux-iscsi.san:lun1"},
+	}
+	got := parseSessions(output)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %#v, got %#v", want, got)
+	}
+}
+
+func TestNormalizePortal(t *testing.T) {
+	cases := map[string]string{
+		"192.168.1.10":      "192.168.1.10:3260",
+		"192.168.1.10:3261": "192.168.1.10:3261",
+	}
+	for in, want := range cases {
+		if got := normalizePortal(in); got != want {
+			t.Errorf("normalizePortal(%s) want %s, got %s", in, want, got)
+		}
+	}
+}
+
+func TestNodeRecordPrefix(t *testing.T) {
+	cases := map[string]string{
+		"192.168.1.10":      "192.168.1.10,3260,",
+		"192.168.1.10:3261": "192.168.1.10,3261,",
+		"[fe80::1]:3260":    "fe80::1,3260,",
+	}
+	for in, want := range cases {
+		if got := nodeRecordPrefix(in); got != want {
+			t.Errorf("nodeRecordPrefix(%s) want %s, got %s", in, want, got)
+		}
+	}
+}
+
+func TestUpdateNodeRecord(t *testing.T) {
+	content := "# BEGIN RECORD 2.0-874\n" +
+		"node.name = iqn.2003-01.org.linux-iscsi.san:lun0\n" +
+		"node.session.auth.authmethod = None\n" +
+		"# END RECORD\n"
+	got := updateNodeRecord(content, map[string]string{
+		"node.session.auth.authmethod": "CHAP",
+		"node.session.auth.username":   "user",
+		"node.session.auth.password":   "secret",
+	})
+	want := "# BEGIN RECORD 2.0-874\n" +
+		"node.name = iqn.2003-01.org.linux-iscsi.san:lun0\n" +
+		"node.session.auth.authmethod = CHAP\n" +
+		"node.session.auth.username = user\n" +
+		"node.session.auth.password = secret\n" +
+		"# END RECORD\n"
+	if got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
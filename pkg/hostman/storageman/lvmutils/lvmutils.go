@@ -0,0 +1,256 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lvmutils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/util/procutils"
+)
+
+const (
+	separator = "|"
+
+	vgFields = "vg_name,vg_size,vg_free"
+	lvFields = "lv_name,vg_name,lv_attr,lv_size,pool_lv,origin,data_percent"
+)
+
+var reportArgs = []string{"--noheadings", "--units", "m", "--nosuffix", "--separator", separator}
+
+type SVolumeGroup struct {
+	Name   string
+	SizeMb int64
+	FreeMb int64
+}
+
+type SLogicalVolume struct {
+	Name        string
+	VgName      string
+	Attr        string
+	SizeMb      int64
+	PoolLv      string
+	Origin      string
+	DataPercent float64
+}
+
+// lv_attr bit 1: volume type, t for thin pool, V for thin volume
+func (lv *SLogicalVolume) IsThinPool() bool {
+	return len(lv.Attr) > 0 && lv.Attr[0] == 't'
+}
+
+// lv_attr bit 5: state, a for active
+func (lv *SLogicalVolume) IsActive() bool {
+	return len(lv.Attr) > 4 && lv.Attr[4] == 'a'
+}
+
+func (lv *SLogicalVolume) GetPath() string {
+	return fmt.Sprintf("/dev/%s/%s", lv.VgName, lv.Name)
+}
+
+// used size of thin pool in MB
+func (lv *SLogicalVolume) GetUsedSizeMb() int64 {
+	return int64(float64(lv.SizeMb) * lv.DataPercent / 100)
+}
+
+func parseSizeMb(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if len(s) == 0 {
+		return 0, nil
+	}
+	size, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "parse size %q", s)
+	}
+	return int64(size), nil
+}
+
+func splitReportLine(line string, fieldCount int) ([]string, bool) {
+	line = strings.TrimSpace(line)
+	if len(line) == 0 {
+		return nil, false
+	}
+	segs := strings.Split(line, separator)
+	if len(segs) < fieldCount {
+		return nil, false
+	}
+	for i := range segs {
+		segs[i] = strings.TrimSpace(segs[i])
+	}
+	return segs, true
+}
+
+func parseVolumeGroups(output string) ([]SVolumeGroup, error) {
+	ret := make([]SVolumeGroup, 0)
+	for _, line := range strings.Split(output, "\n") {
+		segs, ok := splitReportLine(line, 3)
+		if !ok {
+			continue
+		}
+		vg := SVolumeGroup{Name: segs[0]}
+		var err error
+		if vg.SizeMb, err = parseSizeMb(segs[1]); err != nil {
+			return nil, err
+		}
+		if vg.FreeMb, err = parseSizeMb(segs[2]); err != nil {
+			return nil, err
+		}
+		ret = append(ret, vg)
+	}
+	return ret, nil
+}
+
+func parseLogicalVolumes(output string) ([]SLogicalVolume, error) {
+	ret := make([]SLogicalVolume, 0)
+	for _, line := range strings.Split(output, "\n") {
+		segs, ok := splitReportLine(line, 7)
+		if !ok {
+			continue
+		}
+		lv := SLogicalVolume{
+			Name:   segs[0],
+			VgName: segs[1],
+			Attr:   segs[2],
+			PoolLv: segs[4],
+			Origin: segs[5],
+		}
+		var err error
+		if lv.SizeMb, err = parseSizeMb(segs[3]); err != nil {
+			return nil, err
+		}
+		if len(segs[6]) > 0 {
+			if lv.DataPercent, err = strconv.ParseFloat(segs[6], 64); err != nil {
+				return nil, errors.Wrapf(err, "parse data percent %q", segs[6])
+			}
+		}
+		ret = append(ret, lv)
+	}
+	return ret, nil
+}
+
+func GetVolumeGroup(vgName string) (*SVolumeGroup, error) {
+	args := append([]string{}, reportArgs...)
+	args = append(args, "-o", vgFields, vgName)
+	out, err := procutils.NewRemoteCommandAsFarAsPossible("vgs", args...).Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "vgs %s: %s", vgName, out)
+	}
+	vgs, err := parseVolumeGroups(string(out))
+	if err != nil {
+		return nil, err
+	}
+	for i := range vgs {
+		if vgs[i].Name == vgName {
+			return &vgs[i], nil
+		}
+	}
+	return nil, errors.Wrapf(cloudprovider.ErrNotFound, "volume group %s", vgName)
+}
+
+func ListLogicalVolumes(vgName string) ([]SLogicalVolume, error) {
+	args := append([]string{}, reportArgs...)
+	args = append(args, "-o", lvFields, vgName)
+	out, err := procutils.NewRemoteCommandAsFarAsPossible("lvs", args...).Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "lvs %s: %s", vgName, out)
+	}
+	return parseLogicalVolumes(string(out))
+}
+
+func GetLogicalVolume(vgName, lvName string) (*SLogicalVolume, error) {
+	lvs, err := ListLogicalVolumes(vgName)
+	if err != nil {
+		return nil, err
+	}
+	for i := range lvs {
+		if lvs[i].Name == lvName {
+			return &lvs[i], nil
+		}
+	}
+	return nil, errors.Wrapf(cloudprovider.ErrNotFound, "logical volume %s/%s", vgName, lvName)
+}
+
+// runLvmCommand is replaced in tests to check the generated commands
+var runLvmCommand = execLvmCommand
+
+func execLvmCommand(name string, args ...string) error {
+	out, err := procutils.NewRemoteCommandAsFarAsPossible(name, args...).Output()
+	if err != nil {
+		return errors.Wrapf(err, "%s %s: %s", name, strings.Join(args, " "), out)
+	}
+	return nil
+}
+
+// create thick provisioned logical volume
+func CreateLogicalVolume(vgName, lvName string, sizeMb int64) error {
+	return runLvmCommand("lvcreate", "-y", "-n", lvName, "-L", fmt.Sprintf("%dM", sizeMb), vgName)
+}
+
+// create thin volume in thin pool
+func CreateThinVolume(vgName, thinPool, lvName string, sizeMb int64) error {
+	return runLvmCommand("lvcreate", "-y", "-n", lvName, "-V", fmt.Sprintf("%dM", sizeMb),
+		"-T", fmt.Sprintf("%s/%s", vgName, thinPool))
+}
+
+// create thin snapshot of origin, snapshot activation skip flag is cleared
+// so the snapshot can be activated like a normal volume
+func CreateThinSnapshot(vgName, originName, snapName string) error {
+	return runLvmCommand("lvcreate", "-y", "-s", "-kn", "-n", snapName, fmt.Sprintf("%s/%s", vgName, originName))
+}
+
+func RemoveLogicalVolume(vgName, lvName string) error {
+	return runLvmCommand("lvremove", "-f", fmt.Sprintf("%s/%s", vgName, lvName))
+}
+
+func ExtendLogicalVolume(vgName, lvName string, sizeMb int64) error {
+	return runLvmCommand("lvextend", "-L", fmt.Sprintf("%dM", sizeMb), fmt.Sprintf("%s/%s", vgName, lvName))
+}
+
+func ActivateLogicalVolume(vgName, lvName string) error {
+	return runLvmCommand("lvchange", "-ay", "-K", fmt.Sprintf("%s/%s", vgName, lvName))
+}
+
+func RenameLogicalVolume(vgName, oldName, newName string) error {
+	return runLvmCommand("lvrename", vgName, oldName, newName)
+}
+
+// activate logical volume with exclusive lock of lvmlockd,
+// fails if the volume is active on any other host
+func ActivateLogicalVolumeExclusive(vgName, lvName string) error {
+	return runLvmCommand("lvchange", "-aey", fmt.Sprintf("%s/%s", vgName, lvName))
+}
+
+// activate logical volume with shared lock of lvmlockd,
+// an exclusive lock held by this host is converted to shared
+func ActivateLogicalVolumeShared(vgName, lvName string) error {
+	return runLvmCommand("lvchange", "-asy", fmt.Sprintf("%s/%s", vgName, lvName))
+}
+
+func DeactivateLogicalVolume(vgName, lvName string) error {
+	return runLvmCommand("lvchange", "-an", fmt.Sprintf("%s/%s", vgName, lvName))
+}
+
+// join the lockspace of a shared volume group
+func StartVgLock(vgName string) error {
+	return runLvmCommand("vgchange", "--lock-start", vgName)
+}
+
+func StopVgLock(vgName string) error {
+	return runLvmCommand("vgchange", "--lock-stop", vgName)
+}
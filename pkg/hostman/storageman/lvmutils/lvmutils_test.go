@@ -0,0 +1,116 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lvmutils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseVolumeGroups(t *testing.T) {
+	output := "  vg_data|10236.00|2044.00\n  vg_sys|51196.00|0\n\n"
+	want := []SVolumeGroup{
+		{Name: "vg_data", SizeMb: 10236, FreeMb: 2044},
+		{Name: "vg_sys", SizeMb: 51196, FreeMb: 0},
+	}
+	got, err := parseVolumeGroups(output)
+	if err != nil {
+		t.Fatalf("parseVolumeGroups: %s", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %#v, got %#v", want, got)
+	}
+
+	if _, err := parseVolumeGroups("vg_data|abc|0\n"); err == nil {
+		t.Errorf("invalid size should fail")
+	}
+}
+
+func TestParseLogicalVolumes(t *testing.T) {
+	output := "  thinpool|vg_data|twi-aotz--|8192.00|||12.50\n" +
+		"  disk1|vg_data|Vwi-a-tz--|10240.00|thinpool||9.77\n" +
+		"  snap_disk1_s1|vg_data|Vwi---tz-k|10240.00|thinpool|disk1|\n" +
+		"  disk2|vg_data|-wi-------|1024.00|||\n"
+	lvs, err := parseLogicalVolumes(output)
+	if err != nil {
+		t.Fatalf("parseLogicalVolumes: %s", err)
+	}
+	if len(lvs) != 4 {
+		t.Fatalf("want 4 logical volumes, got %d", len(lvs))
+	}
+
+	cases := []struct {
+		lv       SLogicalVolume
+		thinPool bool
+		active   bool
+		path     string
+	}{
+		{lvs[0], true, true, "/dev/vg_data/thinpool"},
+		{lvs[1], false, true, "/dev/vg_data/disk1"},
+		{lvs[2], false, false, "/dev/vg_data/snap_disk1_s1"},
+		{lvs[3], false, false, "/dev/vg_data/disk2"},
+	}
+	for _, c := range cases {
+		if c.lv.IsThinPool() != c.thinPool {
+			t.Errorf("%s IsThinPool want %v", c.lv.Name, c.thinPool)
+		}
+		if c.lv.IsActive() != c.active {
+			t.Errorf("%s IsActive want %v", c.lv.Name, c.active)
+		}
+		if c.lv.GetPath() != c.path {
+			t.Errorf("%s GetPath want %s, got %s", c.lv.Name, c.path, c.lv.GetPath())
+		}
+	}
+
+	if lvs[0].GetUsedSizeMb() != 1024 {
+		t.Errorf("thin pool used size want 1024, got %d", lvs[0].GetUsedSizeMb())
+	}
+	if lvs[2].Origin != "disk1" || lvs[2].PoolLv != "thinpool" {
+		t.Errorf("unexpected snapshot origin %q pool %q", lvs[2].Origin, lvs[2].PoolLv)
+	}
+}
+
+func TestLvmCommands(t *testing.T) {
+	var got []string
+	runLvmCommand = func(name string, args ...string) error {
+		got = append(got, name+" "+strings.Join(args, " "))
+		return nil
+	}
+	defer func() {
+		runLvmCommand = execLvmCommand
+	}()
+
+	CreateLogicalVolume("vg_data", "disk1", 10240)
+	CreateThinVolume("vg_data", "thinpool", "disk2", 20480)
+	CreateThinSnapshot("vg_data", "disk2", "snap_disk2_s1")
+	ExtendLogicalVolume("vg_data", "disk1", 20480)
+	ActivateLogicalVolumeExclusive("vg_data", "disk1")
+	ActivateLogicalVolumeShared("vg_data", "disk1")
+
+	want := []string{
+		// thick volumes take the size from volume group at once
+		"lvcreate -y -n disk1 -L 10240M vg_data",
+		// thin volumes only take virtual size in the thin pool
+		"lvcreate -y -n disk2 -V 20480M -T vg_data/thinpool",
+		"lvcreate -y -s -kn -n snap_disk2_s1 vg_data/disk2",
+		"lvextend -L 20480M vg_data/disk1",
+		"lvchange -aey vg_data/disk1",
+		"lvchange -asy vg_data/disk1",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want commands:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
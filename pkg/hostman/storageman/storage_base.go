@@ -319,6 +319,9 @@ func (s *SBaseStorage) CreateDiskByDiskinfo(ctx context.Context, params interfac
 	case createParams.DiskInfo.Contains("snapshot"):
 		log.Infof("CreateDiskFromSnpashot %s", createParams)
 		return s.CreateDiskFromSnpashot(ctx, disk, createParams)
+	case createParams.DiskInfo.Contains("backup"):
+		log.Infof("CreateDiskFromBackup %s", createParams)
+		return s.CreateDiskFromBackup(ctx, disk, createParams)
 	case createParams.DiskInfo.Contains("image_id"):
 		log.Infof("CreateDiskFromTemplate %s", createParams)
 		return s.CreateDiskFromTemplate(ctx, disk, createParams)
@@ -0,0 +1,179 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storageman
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/hostman/hostutils"
+	"yunion.io/x/onecloud/pkg/hostman/storageman/iscsiutils"
+	"yunion.io/x/onecloud/pkg/hostman/storageman/lvmutils"
+	"yunion.io/x/onecloud/pkg/mcclient/modules"
+)
+
+func init() {
+	registerStorageFactory(&SISCSILVMStorageFactory{})
+}
+
+type SISCSILVMStorageFactory struct {
+}
+
+func (factory *SISCSILVMStorageFactory) NewStorage(manager *SStorageManager, mountPoint string) IStorage {
+	return NewISCSILVMStorage(manager, mountPoint)
+}
+
+func (factory *SISCSILVMStorageFactory) StorageType() string {
+	return api.STORAGE_ISCSI_LVM
+}
+
+// SISCSILVMStorage is a shared LVM volume group on an iSCSI LUN attached to many hosts.
+// The volume group must be created with lvmlockd (vgcreate --shared), every disk
+// is activated with an exclusive lock before guest start, so a disk can never be
+// opened by two hosts at the same time.
+type SISCSILVMStorage struct {
+	SLVMStorage
+
+	Portal       string
+	Target       string
+	ChapUsername string
+	ChapPassword string
+}
+
+// mount point of iscsi lvm storage is /dev/<vg_name>
+func NewISCSILVMStorage(manager *SStorageManager, mountPoint string) *SISCSILVMStorage {
+	var ret = new(SISCSILVMStorage)
+	ret.SLVMStorage = *NewLVMStorage(manager, path.Base(mountPoint), 0)
+	return ret
+}
+
+func (s *SISCSILVMStorage) StorageType() string {
+	return api.STORAGE_ISCSI_LVM
+}
+
+func (s *SISCSILVMStorage) SetStorageInfo(storageId, storageName string, conf jsonutils.JSONObject) error {
+	s.StorageId = storageId
+	s.StorageName = storageName
+	if dconf, ok := conf.(*jsonutils.JSONDict); ok {
+		s.StorageConf = dconf
+	}
+	if s.StorageConf == nil {
+		return fmt.Errorf("iscsi lvm storage %s missing storage conf", storageName)
+	}
+	s.Portal, _ = s.StorageConf.GetString("portal")
+	s.Target, _ = s.StorageConf.GetString("target")
+	s.ChapUsername, _ = s.StorageConf.GetString("chap_username")
+	if password, _ := s.StorageConf.GetString("chap_password"); len(password) > 0 {
+		// password is encrypted with storage id by region
+		decrypted, err := utils.DescryptAESBase64(s.StorageId, password)
+		if err != nil {
+			return errors.Wrap(err, "decrypt chap password")
+		}
+		s.ChapPassword = decrypted
+	}
+	if vgName, _ := s.StorageConf.GetString("vg_name"); len(vgName) > 0 {
+		s.VgName = vgName
+		s.Path = path.Join("/dev", vgName)
+	}
+	if err := iscsiutils.Login(s.Portal, s.Target, s.ChapUsername, s.ChapPassword); err != nil {
+		return errors.Wrapf(err, "login iscsi target %s", s.Target)
+	}
+	if err := lvmutils.StartVgLock(s.VgName); err != nil {
+		return errors.Wrapf(err, "start lockspace of volume group %s", s.VgName)
+	}
+	return nil
+}
+
+func (s *SISCSILVMStorage) SyncStorageInfo() (jsonutils.JSONObject, error) {
+	if len(s.StorageId) == 0 {
+		return nil, fmt.Errorf("Sync iscsi lvm storage without storage id")
+	}
+	content := jsonutils.NewDict()
+	content.Set("capacity", jsonutils.NewInt(int64(s.GetAvailSizeMb())))
+	content.Set("actual_capacity_used", jsonutils.NewInt(int64(s.GetUsedSizeMb())))
+	content.Set("storage_type", jsonutils.NewString(s.StorageType()))
+	content.Set("status", jsonutils.NewString(api.STORAGE_ONLINE))
+	content.Set("zone", jsonutils.NewString(s.GetZoneName()))
+	log.Infof("Sync storage info %s", s.StorageId)
+	res, err := modules.Storages.Put(
+		hostutils.GetComputeSession(context.Background()),
+		s.StorageId, content)
+	if err != nil {
+		log.Errorf("SyncStorageInfo Failed: %s: %s", content, err)
+	}
+	return res, err
+}
+
+func (s *SISCSILVMStorage) GetDiskById(diskId string) (IDisk, error) {
+	s.DiskLock.Lock()
+	defer s.DiskLock.Unlock()
+	for i := 0; i < len(s.Disks); i++ {
+		if s.Disks[i].GetId() == diskId {
+			return s.Disks[i], s.Disks[i].Probe()
+		}
+	}
+	var disk = NewISCSILVMDisk(s, diskId)
+	if disk.Probe() == nil {
+		s.Disks = append(s.Disks, disk)
+		return disk, nil
+	}
+	return nil, cloudprovider.ErrNotFound
+}
+
+func (s *SISCSILVMStorage) CreateDisk(diskId string) IDisk {
+	s.DiskLock.Lock()
+	defer s.DiskLock.Unlock()
+	disk := NewISCSILVMDisk(s, diskId)
+	s.Disks = append(s.Disks, disk)
+	return disk
+}
+
+func (s *SISCSILVMStorage) Accessible() error {
+	loggedIn, err := iscsiutils.IsLoggedIn(s.Portal, s.Target)
+	if err != nil {
+		return errors.Wrap(err, "list iscsi sessions")
+	}
+	if !loggedIn {
+		return fmt.Errorf("iscsi target %s on %s isn't logged in", s.Target, s.Portal)
+	}
+	return s.SLVMStorage.Accessible()
+}
+
+func (s *SISCSILVMStorage) Detach() error {
+	if err := lvmutils.StopVgLock(s.VgName); err != nil {
+		return errors.Wrapf(err, "stop lockspace of volume group %s", s.VgName)
+	}
+	return iscsiutils.Logout(s.Portal, s.Target)
+}
+
+func (s *SISCSILVMStorage) DeleteSnapshots(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	res := jsonutils.NewDict()
+	res.Set("deleted", jsonutils.JSONTrue)
+	return res, nil
+}
+
+func (s *SISCSILVMStorage) CreateDiskFromSnapshot(
+	ctx context.Context, disk IDisk, createParams *SDiskCreateByDiskinfo,
+) error {
+	return fmt.Errorf("iscsi lvm storage not support snapshot")
+}
@@ -0,0 +1,312 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storageman
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/hostman/hostutils"
+	"yunion.io/x/onecloud/pkg/hostman/storageman/lvmutils"
+	"yunion.io/x/onecloud/pkg/mcclient/modules"
+)
+
+// SLVMStorage is a host local storage backed by a LVM volume group,
+// each disk is a logical volume named by disk id under /dev/<vg_name>.
+// If thin pool is given, disks are created as thin volumes and
+// snapshots are thin snapshots in the same pool.
+type SLVMStorage struct {
+	SBaseStorage
+
+	VgName   string
+	ThinPool string
+
+	Index int
+}
+
+// ilvmStorage is implemented by storages based on SLVMStorage
+type ilvmStorage interface {
+	getLvmStorage() *SLVMStorage
+}
+
+// NewLVMStorage accepts vg_name or vg_name/thin_pool
+func NewLVMStorage(manager *SStorageManager, vg string, index int) *SLVMStorage {
+	var ret = new(SLVMStorage)
+	segs := strings.SplitN(vg, "/", 2)
+	ret.VgName = segs[0]
+	if len(segs) > 1 {
+		ret.ThinPool = segs[1]
+	}
+	ret.SBaseStorage = *NewBaseStorage(manager, path.Join("/dev", ret.VgName))
+	ret.Index = index
+	return ret
+}
+
+func (s *SLVMStorage) StorageType() string {
+	return api.STORAGE_LVM
+}
+
+func (s *SLVMStorage) getLvmStorage() *SLVMStorage {
+	return s
+}
+
+func (s *SLVMStorage) IsThin() bool {
+	return len(s.ThinPool) > 0
+}
+
+func (s *SLVMStorage) GetComposedName() string {
+	return fmt.Sprintf("host_%s_%s_storage_%d", s.Manager.host.GetMasterIp(), s.StorageType(), s.Index)
+}
+
+// lvm device nodes are managed by udev, no need to bind mount
+func (s *SLVMStorage) SetStorageInfo(storageId, storageName string, conf jsonutils.JSONObject) error {
+	s.StorageId = storageId
+	s.StorageName = storageName
+	if dconf, ok := conf.(*jsonutils.JSONDict); ok {
+		s.StorageConf = dconf
+	}
+	return nil
+}
+
+// returns total size and used size in MB
+func (s *SLVMStorage) getSizeMb() (int64, int64, error) {
+	if s.IsThin() {
+		pool, err := lvmutils.GetLogicalVolume(s.VgName, s.ThinPool)
+		if err != nil {
+			return 0, 0, err
+		}
+		return pool.SizeMb, pool.GetUsedSizeMb(), nil
+	}
+	vg, err := lvmutils.GetVolumeGroup(s.VgName)
+	if err != nil {
+		return 0, 0, err
+	}
+	return vg.SizeMb, vg.SizeMb - vg.FreeMb, nil
+}
+
+func (s *SLVMStorage) GetCapacity() int {
+	return s.GetAvailSizeMb()
+}
+
+func (s *SLVMStorage) GetAvailSizeMb() int {
+	total, _, err := s.getSizeMb()
+	if err != nil {
+		log.Errorf("failed get lvm %s total size: %s", s.VgName, err)
+		return -1
+	}
+	return int(total)
+}
+
+func (s *SLVMStorage) GetUsedSizeMb() int {
+	_, used, err := s.getSizeMb()
+	if err != nil {
+		log.Errorf("failed get lvm %s used size: %s", s.VgName, err)
+		return -1
+	}
+	return int(used)
+}
+
+func (s *SLVMStorage) GetFreeSizeMb() int {
+	total, used, err := s.getSizeMb()
+	if err != nil {
+		log.Errorf("failed get lvm %s free size: %s", s.VgName, err)
+		return -1
+	}
+	return int(total - used)
+}
+
+func (s *SLVMStorage) SyncStorageSize() error {
+	content := jsonutils.NewDict()
+	content.Set("actual_capacity_used", jsonutils.NewInt(int64(s.GetUsedSizeMb())))
+	_, err := modules.Storages.Put(
+		hostutils.GetComputeSession(context.Background()),
+		s.StorageId, content)
+	return err
+}
+
+func (s *SLVMStorage) SyncStorageInfo() (jsonutils.JSONObject, error) {
+	content := jsonutils.NewDict()
+	content.Set("name", jsonutils.NewString(s.GetName(s.GetComposedName)))
+	content.Set("capacity", jsonutils.NewInt(int64(s.GetAvailSizeMb())))
+	content.Set("actual_capacity_used", jsonutils.NewInt(int64(s.GetUsedSizeMb())))
+	content.Set("storage_type", jsonutils.NewString(s.StorageType()))
+	content.Set("medium_type", jsonutils.NewString(s.GetMediumType()))
+	content.Set("zone", jsonutils.NewString(s.GetZoneName()))
+	if len(s.Manager.LocalStorageImagecacheManager.GetId()) > 0 {
+		content.Set("storagecache_id",
+			jsonutils.NewString(s.Manager.LocalStorageImagecacheManager.GetId()))
+	}
+	var (
+		err error
+		res jsonutils.JSONObject
+	)
+
+	log.Infof("Sync storage info %s", s.StorageId)
+
+	if len(s.StorageId) > 0 {
+		res, err = modules.Storages.Put(
+			hostutils.GetComputeSession(context.Background()),
+			s.StorageId, content)
+	} else {
+		content.Set("lvm_vg_name", jsonutils.NewString(s.VgName))
+		if s.IsThin() {
+			content.Set("lvm_thin_pool", jsonutils.NewString(s.ThinPool))
+		}
+		res, err = modules.Storages.Create(
+			hostutils.GetComputeSession(context.Background()), content)
+	}
+	if err != nil {
+		log.Errorf("SyncStorageInfo Failed: %s: %s", content, err)
+	}
+	return res, err
+}
+
+func (s *SLVMStorage) GetDiskById(diskId string) (IDisk, error) {
+	s.DiskLock.Lock()
+	defer s.DiskLock.Unlock()
+	for i := 0; i < len(s.Disks); i++ {
+		if s.Disks[i].GetId() == diskId {
+			return s.Disks[i], s.Disks[i].Probe()
+		}
+	}
+	var disk = NewLVMDisk(s, diskId)
+	if disk.Probe() == nil {
+		s.Disks = append(s.Disks, disk)
+		return disk, nil
+	}
+	return nil, cloudprovider.ErrNotFound
+}
+
+func (s *SLVMStorage) CreateDisk(diskId string) IDisk {
+	s.DiskLock.Lock()
+	defer s.DiskLock.Unlock()
+	disk := NewLVMDisk(s, diskId)
+	s.Disks = append(s.Disks, disk)
+	return disk
+}
+
+func (s *SLVMStorage) Accessible() error {
+	if _, err := lvmutils.GetVolumeGroup(s.VgName); err != nil {
+		return errors.Wrapf(err, "get volume group %s", s.VgName)
+	}
+	if s.IsThin() {
+		pool, err := lvmutils.GetLogicalVolume(s.VgName, s.ThinPool)
+		if err != nil {
+			return errors.Wrapf(err, "get thin pool %s/%s", s.VgName, s.ThinPool)
+		}
+		if !pool.IsThinPool() {
+			return fmt.Errorf("logical volume %s/%s isn't thin pool", s.VgName, s.ThinPool)
+		}
+	}
+	return nil
+}
+
+func (s *SLVMStorage) Detach() error {
+	return nil
+}
+
+func (s *SLVMStorage) GetSnapshotDir() string {
+	return ""
+}
+
+func (s *SLVMStorage) GetFuseTmpPath() string {
+	return ""
+}
+
+func (s *SLVMStorage) GetFuseMountPath() string {
+	return ""
+}
+
+func (s *SLVMStorage) GetImgsaveBackupPath() string {
+	return ""
+}
+
+func (s *SLVMStorage) getSnapshotLvName(diskId, snapshotId string) string {
+	return fmt.Sprintf("snap_%s_%s", diskId, snapshotId)
+}
+
+func (s *SLVMStorage) GetSnapshotPathByIds(diskId, snapshotId string) string {
+	return path.Join(s.Path, s.getSnapshotLvName(diskId, snapshotId))
+}
+
+func (s *SLVMStorage) IsSnapshotExist(diskId, snapshotId string) (bool, error) {
+	_, err := lvmutils.GetLogicalVolume(s.VgName, s.getSnapshotLvName(diskId, snapshotId))
+	if err != nil {
+		if errors.Cause(err) == cloudprovider.ErrNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// DeleteSnapshots remove all snapshot logical volumes of disk
+func (s *SLVMStorage) DeleteSnapshots(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	diskId, ok := params.(string)
+	if !ok {
+		return nil, hostutils.ParamsError
+	}
+	lvs, err := lvmutils.ListLogicalVolumes(s.VgName)
+	if err != nil {
+		return nil, err
+	}
+	prefix := fmt.Sprintf("snap_%s_", diskId)
+	for _, lv := range lvs {
+		if strings.HasPrefix(lv.Name, prefix) {
+			if err := lvmutils.RemoveLogicalVolume(s.VgName, lv.Name); err != nil {
+				return nil, err
+			}
+		}
+	}
+	res := jsonutils.NewDict()
+	res.Set("deleted", jsonutils.JSONTrue)
+	return res, nil
+}
+
+func (s *SLVMStorage) CreateDiskFromSnapshot(
+	ctx context.Context, disk IDisk, createParams *SDiskCreateByDiskinfo,
+) error {
+	var (
+		snapshotId, _ = createParams.DiskInfo.GetString("snapshot_url")
+		srcDiskId, _  = createParams.DiskInfo.GetString("src_disk_id")
+	)
+	if !s.IsThin() {
+		return fmt.Errorf("lvm storage %s without thin pool not support snapshot", s.VgName)
+	}
+	snapName := s.getSnapshotLvName(srcDiskId, snapshotId)
+	if _, err := lvmutils.GetLogicalVolume(s.VgName, snapName); err != nil {
+		return errors.Wrapf(err, "snapshot %s not found on storage %s", snapshotId, s.VgName)
+	}
+	if err := lvmutils.CreateThinSnapshot(s.VgName, snapName, disk.GetId()); err != nil {
+		return errors.Wrap(err, "create disk from thin snapshot")
+	}
+	return disk.Probe()
+}
+
+func (s *SLVMStorage) SaveToGlance(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	return nil, fmt.Errorf("Not support")
+}
+
+func (s *SLVMStorage) CreateSnapshotFormUrl(ctx context.Context, snapshotUrl, diskId, snapshotPath string) error {
+	return fmt.Errorf("Not support")
+}
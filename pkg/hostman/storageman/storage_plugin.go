@@ -0,0 +1,286 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storageman
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/hostman/hostutils"
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/hostman/storageman/storageplugin"
+	pb "yunion.io/x/onecloud/pkg/hostman/storageman/storageplugin/api"
+	"yunion.io/x/onecloud/pkg/mcclient/modules"
+)
+
+const (
+	pluginQueryTimeout  = 30 * time.Second
+	pluginVolumeTimeout = 10 * time.Minute
+)
+
+func init() {
+	registerStorageFactory(&SPluginStorageFactory{})
+}
+
+type SPluginStorageFactory struct {
+}
+
+func (factory *SPluginStorageFactory) NewStorage(manager *SStorageManager, mountPoint string) IStorage {
+	return NewPluginStorage(manager, mountPoint)
+}
+
+func (factory *SPluginStorageFactory) StorageType() string {
+	return api.STORAGE_PLUGIN
+}
+
+// SPluginStorage delegates volume operations to out-of-tree storage plugin
+// serving StoragePlugin gRPC service on <storage_plugin_socket_dir>/<driver>.sock.
+// Volumes are published by plugin under the mount point of storage,
+// so disk path is <mount_point>/<disk_id> as other storages.
+type SPluginStorage struct {
+	SBaseStorage
+
+	Driver  string
+	Options map[string]string
+}
+
+func NewPluginStorage(manager *SStorageManager, mountPoint string) *SPluginStorage {
+	var ret = new(SPluginStorage)
+	ret.SBaseStorage = *NewBaseStorage(manager, mountPoint)
+	return ret
+}
+
+func (s *SPluginStorage) StorageType() string {
+	return api.STORAGE_PLUGIN
+}
+
+func (s *SPluginStorage) getClient() (pb.StoragePluginClient, error) {
+	if len(s.Driver) == 0 {
+		return nil, fmt.Errorf("storage %s missing plugin driver", s.StorageName)
+	}
+	return storageplugin.GetClient(options.HostOptions.StoragePluginSocketDir, s.Driver)
+}
+
+func (s *SPluginStorage) SetStorageInfo(storageId, storageName string, conf jsonutils.JSONObject) error {
+	if dconf, ok := conf.(*jsonutils.JSONDict); ok {
+		s.Driver, _ = dconf.GetString("driver")
+		s.Options = map[string]string{}
+		if dconf.Contains("options") {
+			if err := dconf.Unmarshal(&s.Options, "options"); err != nil {
+				return errors.Wrap(err, "unmarshal plugin options")
+			}
+		}
+	}
+	if err := os.MkdirAll(s.Path, 0755); err != nil {
+		return errors.Wrapf(err, "mkdir %s", s.Path)
+	}
+	return s.SBaseStorage.SetStorageInfo(storageId, storageName, conf)
+}
+
+// returns total size and used size in MB
+func (s *SPluginStorage) getSizeMb() (int64, int64, error) {
+	client, err := s.getClient()
+	if err != nil {
+		return 0, 0, err
+	}
+	ctx, cancel := storageplugin.NewContext(pluginQueryTimeout)
+	defer cancel()
+	resp, err := client.GetCapacity(ctx, &pb.GetCapacityRequest{Options: s.Options})
+	if err != nil {
+		return 0, 0, storageplugin.Error(err, "GetCapacity")
+	}
+	return resp.CapacityMb, resp.UsedMb, nil
+}
+
+func (s *SPluginStorage) GetCapacity() int {
+	return s.GetAvailSizeMb()
+}
+
+func (s *SPluginStorage) GetAvailSizeMb() int {
+	total, _, err := s.getSizeMb()
+	if err != nil {
+		log.Errorf("failed get plugin storage %s total size: %s", s.StorageName, err)
+		return -1
+	}
+	return int(total)
+}
+
+func (s *SPluginStorage) GetUsedSizeMb() int {
+	_, used, err := s.getSizeMb()
+	if err != nil {
+		log.Errorf("failed get plugin storage %s used size: %s", s.StorageName, err)
+		return -1
+	}
+	return int(used)
+}
+
+func (s *SPluginStorage) SyncStorageInfo() (jsonutils.JSONObject, error) {
+	if len(s.StorageId) == 0 {
+		return nil, fmt.Errorf("Sync plugin storage without storage id")
+	}
+	total, used, err := s.getSizeMb()
+	if err != nil {
+		log.Errorf("get plugin storage %s capacity: %s", s.StorageName, err)
+		return modules.Storages.PerformAction(hostutils.GetComputeSession(context.Background()), s.StorageId, "offline", nil)
+	}
+	content := jsonutils.NewDict()
+	content.Set("capacity", jsonutils.NewInt(total))
+	content.Set("actual_capacity_used", jsonutils.NewInt(used))
+	content.Set("storage_type", jsonutils.NewString(s.StorageType()))
+	content.Set("status", jsonutils.NewString(api.STORAGE_ONLINE))
+	content.Set("zone", jsonutils.NewString(s.GetZoneName()))
+	log.Infof("Sync storage info %s", s.StorageId)
+	res, err := modules.Storages.Put(
+		hostutils.GetComputeSession(context.Background()),
+		s.StorageId, content)
+	if err != nil {
+		log.Errorf("SyncStorageInfo Failed: %s: %s", content, err)
+	}
+	return res, err
+}
+
+func (s *SPluginStorage) GetDiskById(diskId string) (IDisk, error) {
+	s.DiskLock.Lock()
+	defer s.DiskLock.Unlock()
+	for i := 0; i < len(s.Disks); i++ {
+		if s.Disks[i].GetId() == diskId {
+			return s.Disks[i], s.Disks[i].Probe()
+		}
+	}
+	var disk = NewPluginDisk(s, diskId)
+	if err := disk.Probe(); err != nil {
+		if errors.Cause(err) == cloudprovider.ErrNotFound {
+			return nil, cloudprovider.ErrNotFound
+		}
+		return nil, err
+	}
+	s.Disks = append(s.Disks, disk)
+	return disk, nil
+}
+
+func (s *SPluginStorage) CreateDisk(diskId string) IDisk {
+	s.DiskLock.Lock()
+	defer s.DiskLock.Unlock()
+	disk := NewPluginDisk(s, diskId)
+	s.Disks = append(s.Disks, disk)
+	return disk
+}
+
+func (s *SPluginStorage) Accessible() error {
+	client, err := s.getClient()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := storageplugin.NewContext(pluginQueryTimeout)
+	defer cancel()
+	info, err := client.GetPluginInfo(ctx, &pb.GetPluginInfoRequest{})
+	if err != nil {
+		return errors.Wrapf(err, "get info of storage plugin %s", s.Driver)
+	}
+	log.Infof("Storage %s use plugin %s version %s", s.StorageName, info.Name, info.Version)
+	return nil
+}
+
+func (s *SPluginStorage) Detach() error {
+	return nil
+}
+
+func (s *SPluginStorage) GetSnapshotDir() string {
+	return ""
+}
+
+func (s *SPluginStorage) GetFuseTmpPath() string {
+	return ""
+}
+
+func (s *SPluginStorage) GetFuseMountPath() string {
+	return ""
+}
+
+func (s *SPluginStorage) GetImgsaveBackupPath() string {
+	return ""
+}
+
+func (s *SPluginStorage) GetSnapshotPathByIds(diskId, snapshotId string) string {
+	return ""
+}
+
+func (s *SPluginStorage) IsSnapshotExist(diskId, snapshotId string) (bool, error) {
+	client, err := s.getClient()
+	if err != nil {
+		return false, err
+	}
+	ctx, cancel := storageplugin.NewContext(pluginQueryTimeout)
+	defer cancel()
+	_, err = client.GetSnapshot(ctx, &pb.GetSnapshotRequest{Options: s.Options, VolumeId: diskId, SnapshotId: snapshotId})
+	if err != nil {
+		err = storageplugin.Error(err, "GetSnapshot")
+		if errors.Cause(err) == cloudprovider.ErrNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// snapshots of plugin are independent of volume and deleted by region one by one
+func (s *SPluginStorage) DeleteSnapshots(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	res := jsonutils.NewDict()
+	res.Set("deleted", jsonutils.JSONTrue)
+	return res, nil
+}
+
+func (s *SPluginStorage) CreateDiskFromSnapshot(
+	ctx context.Context, disk IDisk, createParams *SDiskCreateByDiskinfo,
+) error {
+	var (
+		snapshotId, _ = createParams.DiskInfo.GetString("snapshot_url")
+		srcDiskId, _  = createParams.DiskInfo.GetString("src_disk_id")
+		diskSize, _   = createParams.DiskInfo.Int("size")
+	)
+	client, err := s.getClient()
+	if err != nil {
+		return err
+	}
+	pctx, cancel := storageplugin.NewContext(pluginVolumeTimeout)
+	defer cancel()
+	_, err = client.CreateVolume(pctx, &pb.CreateVolumeRequest{
+		Options:     s.Options,
+		VolumeId:    disk.GetId(),
+		SizeMb:      diskSize,
+		SnapshotId:  snapshotId,
+		SrcVolumeId: srcDiskId,
+	})
+	if err != nil {
+		return storageplugin.Error(err, "CreateVolume from snapshot")
+	}
+	return disk.Probe()
+}
+
+func (s *SPluginStorage) SaveToGlance(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	return nil, fmt.Errorf("Not support")
+}
+
+func (s *SPluginStorage) CreateSnapshotFormUrl(ctx context.Context, snapshotUrl, diskId, snapshotPath string) error {
+	return fmt.Errorf("Not support")
+}
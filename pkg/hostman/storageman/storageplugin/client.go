@@ -0,0 +1,81 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storageplugin
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	pb "yunion.io/x/onecloud/pkg/hostman/storageman/storageplugin/api"
+)
+
+var (
+	clients     = map[string]pb.StoragePluginClient{}
+	clientsLock = &sync.Mutex{}
+)
+
+func GetSocketPath(socketDir, driver string) string {
+	return path.Join(socketDir, fmt.Sprintf("%s.sock", driver))
+}
+
+func dialUnix(ctx context.Context, addr string) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, "unix", addr)
+}
+
+// GetClient returns client of storage plugin listening on <socketDir>/<driver>.sock,
+// connections are reused between storages of the same driver
+func GetClient(socketDir, driver string) (pb.StoragePluginClient, error) {
+	clientsLock.Lock()
+	defer clientsLock.Unlock()
+	socketPath := GetSocketPath(socketDir, driver)
+	if c, ok := clients[socketPath]; ok {
+		return c, nil
+	}
+	cc, err := grpc.Dial(socketPath, grpc.WithInsecure(), grpc.WithContextDialer(dialUnix))
+	if err != nil {
+		return nil, errors.Wrapf(err, "grpc Dial %s", socketPath)
+	}
+	c := pb.NewStoragePluginClient(cc)
+	clients[socketPath] = c
+	return c, nil
+}
+
+// NewContext returns context with timeout for plugin calls
+func NewContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+// Error converts NotFound status of plugin to cloudprovider.ErrNotFound
+func Error(err error, msg string) error {
+	if err == nil {
+		return nil
+	}
+	if status.Code(err) == codes.NotFound {
+		return errors.Wrap(cloudprovider.ErrNotFound, msg)
+	}
+	return errors.Wrap(err, msg)
+}
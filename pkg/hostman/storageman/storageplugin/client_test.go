@@ -0,0 +1,94 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storageplugin
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	pb "yunion.io/x/onecloud/pkg/hostman/storageman/storageplugin/api"
+)
+
+type fakePlugin struct {
+	pb.UnimplementedStoragePluginServer
+
+	volumes map[string]int64
+}
+
+func (p *fakePlugin) CreateVolume(ctx context.Context, req *pb.CreateVolumeRequest) (*pb.Volume, error) {
+	p.volumes[req.VolumeId] = req.SizeMb
+	return &pb.Volume{VolumeId: req.VolumeId, SizeMb: req.SizeMb}, nil
+}
+
+func (p *fakePlugin) GetVolume(ctx context.Context, req *pb.GetVolumeRequest) (*pb.Volume, error) {
+	size, ok := p.volumes[req.VolumeId]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "volume %s not found", req.VolumeId)
+	}
+	return &pb.Volume{VolumeId: req.VolumeId, SizeMb: size}, nil
+}
+
+func TestClient(t *testing.T) {
+	dir, err := ioutil.TempDir("", "storageplugin")
+	if err != nil {
+		t.Fatalf("create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	lis, err := net.Listen("unix", GetSocketPath(dir, "fake"))
+	if err != nil {
+		t.Fatalf("listen: %s", err)
+	}
+	srv := grpc.NewServer()
+	pb.RegisterStoragePluginServer(srv, &fakePlugin{volumes: map[string]int64{}})
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	client, err := GetClient(dir, "fake")
+	if err != nil {
+		t.Fatalf("GetClient: %s", err)
+	}
+	ctx, cancel := NewContext(5 * time.Second)
+	defer cancel()
+
+	_, err = client.GetVolume(ctx, &pb.GetVolumeRequest{VolumeId: "disk1"})
+	if errors.Cause(Error(err, "GetVolume")) != cloudprovider.ErrNotFound {
+		t.Errorf("want not found error, got %v", err)
+	}
+	if _, err := client.CreateVolume(ctx, &pb.CreateVolumeRequest{VolumeId: "disk1", SizeMb: 1024}); err != nil {
+		t.Fatalf("CreateVolume: %s", err)
+	}
+	vol, err := client.GetVolume(ctx, &pb.GetVolumeRequest{VolumeId: "disk1"})
+	if err != nil {
+		t.Fatalf("GetVolume: %s", err)
+	}
+	if vol.SizeMb != 1024 {
+		t.Errorf("want size 1024, got %d", vol.SizeMb)
+	}
+	if _, err := client.DeleteVolume(ctx, &pb.DeleteVolumeRequest{VolumeId: "disk1"}); status.Code(err) != codes.Unimplemented {
+		t.Errorf("want unimplemented error, got %v", err)
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package modules
+
+import "yunion.io/x/onecloud/pkg/mcclient/modulebase"
+
+var (
+	DiskBackups modulebase.ResourceManager
+)
+
+func init() {
+	DiskBackups = NewComputeManager("diskbackup", "diskbackups",
+		[]string{"ID", "Name", "Status", "Disk_id", "Disk",
+			"Backup_mode", "Parent_id", "Size_mb", "Disk_size_mb", "Created_at"},
+		[]string{"Storage_id", "Bitmap_name", "Location", "Tenant"})
+
+	registerCompute(&DiskBackups)
+}
@@ -20,6 +20,8 @@ var (
 	ScalingGroup    modulebase.ResourceManager
 	ScalingPolicy   modulebase.ResourceManager
 	ScalingActivity modulebase.ResourceManager
+
+	ScalingLifecycleHook modulebase.ResourceManager
 )
 
 func init() {
@@ -38,7 +40,13 @@ func init() {
 			"End_Time", "Reason"},
 		[]string{},
 	)
+	ScalingLifecycleHook = NewComputeManager("scalinglifecyclehook", "scalinglifecyclehooks",
+		[]string{"ID", "Name", "Scaling_Group", "Transition", "Notify_Type", "Webhook_Url", "Ansible_Playbook_ID",
+			"Timeout", "Default_Result"},
+		[]string{},
+	)
 	registerCompute(&ScalingGroup)
 	registerCompute(&ScalingPolicy)
 	registerCompute(&ScalingActivity)
+	registerCompute(&ScalingLifecycleHook)
 }
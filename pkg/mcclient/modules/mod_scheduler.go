@@ -90,6 +90,20 @@ func (this *SchedulerManager) Test(s *mcclient.ClientSession, params *api.Schedu
 }
 
 func (this *SchedulerManager) DoForecast(s *mcclient.ClientSession, params jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	return this.doSuggest(s, "forecast", params)
+}
+
+// DoExplain returns predicate verdicts and priority scores of every candidate
+func (this *SchedulerManager) DoExplain(s *mcclient.ClientSession, params jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	return this.doSuggest(s, "explain", params)
+}
+
+// DoSimulate runs simulate_count hypothetical requests without reserving anything
+func (this *SchedulerManager) DoSimulate(s *mcclient.ClientSession, params jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	return this.doSuggest(s, "simulate", params)
+}
+
+func (this *SchedulerManager) doSuggest(s *mcclient.ClientSession, action string, params jsonutils.JSONObject) (jsonutils.JSONObject, error) {
 	projectId := s.GetProjectId()
 	domainId := s.GetProjectDomainId()
 	cliProjectId, _ := params.GetString("project_id")
@@ -108,7 +122,7 @@ func (this *SchedulerManager) DoForecast(s *mcclient.ClientSession, params jsonu
 	data := params.(*jsonutils.JSONDict)
 	data.Set("domain_id", jsonutils.NewString(domainId))
 	data.Set("project_id", jsonutils.NewString(projectId))
-	url := newSchedURL("forecast")
+	url := newSchedURL(action)
 	_, obj, err := modulebase.JsonRequest(this.ResourceManager, s, "POST", url, nil, data)
 	if err != nil {
 		return nil, err
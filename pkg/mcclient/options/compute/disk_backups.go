@@ -0,0 +1,64 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/mcclient/options"
+)
+
+type DiskBackupListOptions struct {
+	options.BaseListOptions
+	Disk       string   `help:"Disk id or name"`
+	BackupMode []string `help:"Filter by backup mode" choices:"full|incremental"`
+	ParentId   string   `help:"Filter incremental backups of parent backup"`
+}
+
+func (opts *DiskBackupListOptions) Params() (jsonutils.JSONObject, error) {
+	return options.ListStructToParams(opts)
+}
+
+type DiskBackupIdOptions struct {
+	ID string `help:"ID or name of disk backup"`
+}
+
+func (opts *DiskBackupIdOptions) GetId() string {
+	return opts.ID
+}
+
+func (opts *DiskBackupIdOptions) Params() (jsonutils.JSONObject, error) {
+	return nil, nil
+}
+
+type DiskBackupCreateOptions struct {
+	NAME       string `help:"Name of disk backup"`
+	DISK       string `help:"Disk id or name"`
+	BackupMode string `help:"Backup mode, auto does incremental backup when possible" choices:"full|incremental|auto" default:"auto"`
+}
+
+func (opts *DiskBackupCreateOptions) Params() (jsonutils.JSONObject, error) {
+	return options.StructToParams(opts)
+}
+
+type DiskBackupRecoveryOptions struct {
+	DiskBackupIdOptions
+	Name    string `help:"Name of the new disk"`
+	Storage string `help:"Storage of the new disk, default to storage of backed up disk"`
+}
+
+func (opts *DiskBackupRecoveryOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(map[string]string{"name": opts.Name, "storage": opts.Storage}), nil
+}
@@ -44,6 +44,7 @@ type SecGroupRulesCreateOptions struct {
 	Priority       int64  `help:"priority of Rule" default:"50"`
 	Desc           string `help:"Description" json:"description"`
 	PeerSecgroupId string `help:"Peer Secgroup Id" json:"peer_secgroup_id"`
+	Log            *bool  `help:"log hits of the rule.  Only guests in onecloud vpcs are covered"`
 }
 
 func (opts *SecGroupRulesCreateOptions) Params() (jsonutils.JSONObject, error) {
@@ -51,7 +52,7 @@ func (opts *SecGroupRulesCreateOptions) Params() (jsonutils.JSONObject, error) {
 	if err != nil {
 		return nil, errors.Wrapf(err, "invalid rule %s", opts.RULE)
 	}
-	return jsonutils.Marshal(map[string]interface{}{
+	params := jsonutils.Marshal(map[string]interface{}{
 		"direction":        rule.Direction,
 		"action":           rule.Action,
 		"protocol":         rule.Protocol,
@@ -61,7 +62,11 @@ func (opts *SecGroupRulesCreateOptions) Params() (jsonutils.JSONObject, error) {
 		"description":      opts.Desc,
 		"secgroup_id":      opts.SECGROUP,
 		"peer_secgroup_id": opts.PeerSecgroupId,
-	}), nil
+	}).(*jsonutils.JSONDict)
+	if opts.Log != nil {
+		params.Add(jsonutils.NewBool(*opts.Log), "log")
+	}
+	return params, nil
 }
 
 type SecGroupRulesUpdateOptions struct {
@@ -74,6 +79,7 @@ type SecGroupRulesUpdateOptions struct {
 	Action         string `help:"filter Actin of rule" choices:"allow|deny"`
 	Desc           string `help:"Description" metavar:"Description"`
 	PeerSecgroupId string `help:"Peer Secgroup Id" json:"peer_secgroup_id"`
+	Log            *bool  `help:"log hits of the rule.  Only guests in onecloud vpcs are covered"`
 }
 
 func (opts *SecGroupRulesUpdateOptions) Params() (jsonutils.JSONObject, error) {
@@ -102,5 +108,8 @@ func (opts *SecGroupRulesUpdateOptions) Params() (jsonutils.JSONObject, error) {
 	if len(opts.PeerSecgroupId) > 0 {
 		params.Add(jsonutils.NewString(opts.PeerSecgroupId), "peer_secgroup_id")
 	}
+	if opts.Log != nil {
+		params.Add(jsonutils.NewBool(*opts.Log), "log")
+	}
 	return params, nil
 }
@@ -69,6 +69,13 @@ type NetworkUpdateOptions struct {
 	ExternalId  string `help:"External ID"`
 	AllocPolicy string `help:"Address allocation policy" choices:"none|stepdown|stepup|random"`
 	IsAutoAlloc *bool  `help:"Add network into auto-allocation pool" negative:"no_auto_alloc"`
+
+	StartIp6 string `help:"Start of IPv6 address range"`
+	EndIp6   string `help:"End of IPv6 address range"`
+	NetMask6 int64  `help:"Length of IPv6 network mask"`
+	Gateway6 string `help:"IPv6 gateway"`
+	Dns6     string `help:"IPv6 DNS server"`
+	Ip6Mode  string `help:"IPv6 address configuration mode" choices:"dhcpv6|slaac"`
 }
 
 func (opts *NetworkUpdateOptions) Params() (jsonutils.JSONObject, error) {
@@ -91,6 +98,24 @@ func (opts *NetworkUpdateOptions) Params() (jsonutils.JSONObject, error) {
 	if len(opts.Gateway) > 0 {
 		params.Add(jsonutils.NewString(opts.Gateway), "guest_gateway")
 	}
+	if len(opts.StartIp6) > 0 {
+		params.Add(jsonutils.NewString(opts.StartIp6), "guest_ip6_start")
+	}
+	if len(opts.EndIp6) > 0 {
+		params.Add(jsonutils.NewString(opts.EndIp6), "guest_ip6_end")
+	}
+	if opts.NetMask6 > 0 {
+		params.Add(jsonutils.NewInt(opts.NetMask6), "guest_ip6_mask")
+	}
+	if len(opts.Gateway6) > 0 {
+		params.Add(jsonutils.NewString(opts.Gateway6), "guest_gateway6")
+	}
+	if len(opts.Dns6) > 0 {
+		params.Add(jsonutils.NewString(opts.Dns6), "guest_dns6")
+	}
+	if len(opts.Ip6Mode) > 0 {
+		params.Add(jsonutils.NewString(opts.Ip6Mode), "guest_ip6_mode")
+	}
 	if len(opts.Dns) > 0 {
 		if opts.Dns == "none" {
 			params.Add(jsonutils.NewString(""), "guest_dns")
@@ -99,3 +99,25 @@ func (o SchedulerForecastOptions) Params(s *mcclient.ClientSession) (*scheduler.
 	input.ScheduleBaseConfig = *opts
 	return input, nil
 }
+
+type SchedulerExplainOptions struct {
+	SchedulerTestBaseOptions
+}
+
+func (o SchedulerExplainOptions) Params(s *mcclient.ClientSession) (*scheduler.ScheduleInput, error) {
+	return SchedulerForecastOptions{o.SchedulerTestBaseOptions}.Params(s)
+}
+
+type SchedulerSimulateOptions struct {
+	SchedulerTestBaseOptions
+	Requests int `help:"Count of hypothetical requests run one by one" default:"1"`
+}
+
+func (o SchedulerSimulateOptions) Params(s *mcclient.ClientSession) (*scheduler.ScheduleInput, error) {
+	input, err := SchedulerForecastOptions{o.SchedulerTestBaseOptions}.Params(s)
+	if err != nil {
+		return nil, err
+	}
+	input.SimulateCount = o.Requests
+	return input, nil
+}
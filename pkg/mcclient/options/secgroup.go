@@ -44,6 +44,7 @@ func (opts *SecgroupListOptions) Params() (jsonutils.JSONObject, error) {
 type SecgroupCreateOptions struct {
 	BaseCreateOptions
 	Rules []string `help:"security rule to create"`
+	Log   *bool    `help:"log hits of all rules.  Only guests in onecloud vpcs are covered"`
 }
 
 func (opts *SecgroupCreateOptions) Params() (jsonutils.JSONObject, error) {
@@ -64,6 +65,19 @@ func (opts *SecgroupCreateOptions) Params() (jsonutils.JSONObject, error) {
 	return params, nil
 }
 
+type SecgroupUpdateOptions struct {
+	BaseUpdateOptions
+	Log *bool `help:"log hits of all rules.  Only guests in onecloud vpcs are covered"`
+}
+
+func (opts *SecgroupUpdateOptions) Params() (jsonutils.JSONObject, error) {
+	params, _ := opts.BaseUpdateOptions.Params()
+	if opts.Log != nil {
+		params.(*jsonutils.JSONDict).Add(jsonutils.NewBool(*opts.Log), "log")
+	}
+	return params, nil
+}
+
 type SecgroupIdOptions struct {
 	ID string `help:"ID or Name of security group destination"`
 }
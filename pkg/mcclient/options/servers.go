@@ -231,6 +231,8 @@ type ServerConfigs struct {
 	Project        string   `help:"'Owner project ID or Name" json:"tenant"`
 	User           string   `help:"Owner user ID or Name"`
 	Count          int      `help:"Create multiple simultaneously" default:"1"`
+
+	AffinityRule []string `help:"Affinity rule, e.g. 'anti-affinity,hard,rack,group=<group>' or 'affinity,soft,zone,label=app=db,weight=5'" json:"-"`
 }
 
 func (o ServerConfigs) Data() (*computeapi.ServerConfigs, error) {
@@ -308,6 +310,13 @@ func (o ServerConfigs) Data() (*computeapi.ServerConfigs, error) {
 		}
 		data.Schedtags = append(data.Schedtags, schedtag)
 	}
+	for _, desc := range o.AffinityRule {
+		rule, err := cmdline.ParseAffinityRule(desc)
+		if err != nil {
+			return nil, err
+		}
+		data.AffinityRules = append(data.AffinityRules, rule)
+	}
 	return data, nil
 }
 
@@ -364,6 +373,8 @@ type ServerCreateOptionalOptions struct {
 	Vga              string   `help:"VGA driver" choices:"std|vmware|cirrus|qxl"`
 	Vdi              string   `help:"VDI protocool" choices:"vnc|spice"`
 	Bios             string   `help:"BIOS" choices:"BIOS|UEFI"`
+	Vtpm             bool     `help:"Attach a vTPM (TPM 2.0) device"`
+	SecureBoot       bool     `help:"Enable UEFI secure boot"`
 	Desc             string   `help:"Description" metavar:"<DESCRIPTION>" json:"description"`
 	Boot             string   `help:"Boot device" metavar:"<BOOT_DEVICE>" choices:"disk|cdrom" json:"-"`
 	EnableCloudInit  bool     `help:"Enable cloud-init service"`
@@ -467,6 +478,8 @@ func (opts *ServerCreateOptionalOptions) OptionalParams() (*computeapi.ServerCre
 		Vga:                opts.Vga,
 		Vdi:                opts.Vdi,
 		Bios:               opts.Bios,
+		Vtpm:               opts.Vtpm,
+		SecureBoot:         opts.SecureBoot,
 		ShutdownBehavior:   opts.ShutdownBehavior,
 		AutoStart:          opts.AutoStart,
 		Duration:           opts.Duration,
@@ -903,6 +916,120 @@ func (o *ServerLiveMigrateOptions) Params() (jsonutils.JSONObject, error) {
 	return StructToParams(o)
 }
 
+type ServerChangeDiskStorageOptions struct {
+	ID             string `help:"ID or name of server" json:"-"`
+	DISK           string `help:"ID or name of disk to move" json:"disk"`
+	TARGET_STORAGE string `help:"ID or name of target storage on the same host" json:"target_storage"`
+}
+
+func (o *ServerChangeDiskStorageOptions) GetId() string {
+	return o.ID
+}
+
+func (o *ServerChangeDiskStorageOptions) Params() (jsonutils.JSONObject, error) {
+	return StructToParams(o)
+}
+
+type ServerAttachVirtiofsOptions struct {
+	ID          string `help:"ID or name of server" json:"-"`
+	TAG         string `help:"Mount tag used by guest, eg: mount -t virtiofs <tag> /mnt" json:"tag"`
+	SOURCE_PATH string `help:"Absolute directory of host, or relative directory in storage if --storage is given" json:"source_path"`
+	Storage     string `help:"ID or name of shared file storage(nfs, gpfs, cifs) attached to host" json:"storage"`
+	Readonly    bool   `help:"Share directory readonly" json:"readonly"`
+}
+
+func (o *ServerAttachVirtiofsOptions) GetId() string {
+	return o.ID
+}
+
+func (o *ServerAttachVirtiofsOptions) Params() (jsonutils.JSONObject, error) {
+	return StructToParams(o)
+}
+
+type ServerDetachVirtiofsOptions struct {
+	ID  string `help:"ID or name of server" json:"-"`
+	TAG string `help:"Mount tag of the share" json:"tag"`
+}
+
+func (o *ServerDetachVirtiofsOptions) GetId() string {
+	return o.ID
+}
+
+func (o *ServerDetachVirtiofsOptions) Params() (jsonutils.JSONObject, error) {
+	return StructToParams(o)
+}
+
+type ServerQgaSetPasswordOptions struct {
+	ID       string `help:"ID or name of server" json:"-"`
+	PASSWORD string `help:"New password" json:"password"`
+	Username string `help:"User in guest, default is the login account of server" json:"username"`
+}
+
+func (o *ServerQgaSetPasswordOptions) GetId() string {
+	return o.ID
+}
+
+func (o *ServerQgaSetPasswordOptions) Params() (jsonutils.JSONObject, error) {
+	return StructToParams(o)
+}
+
+type ServerQgaFileReadOptions struct {
+	ID      string `help:"ID or name of server" json:"-"`
+	PATH    string `help:"File path in guest" json:"path"`
+	MaxSize int    `help:"Read at most max size bytes" json:"max_size"`
+}
+
+func (o *ServerQgaFileReadOptions) GetId() string {
+	return o.ID
+}
+
+func (o *ServerQgaFileReadOptions) Params() (jsonutils.JSONObject, error) {
+	return StructToParams(o)
+}
+
+type ServerQgaFileWriteOptions struct {
+	ID      string `help:"ID or name of server" json:"-"`
+	PATH    string `help:"File path in guest" json:"path"`
+	CONTENT string `help:"Content to write" json:"content"`
+	Append  bool   `help:"Append to the file instead of truncating it" json:"append"`
+}
+
+func (o *ServerQgaFileWriteOptions) GetId() string {
+	return o.ID
+}
+
+func (o *ServerQgaFileWriteOptions) Params() (jsonutils.JSONObject, error) {
+	return StructToParams(o)
+}
+
+type ServerSetHaPolicyOptions struct {
+	ID          string `help:"ID or name of server" json:"-"`
+	Priority    *int   `help:"Restart priority when host down, higher priority restarts first" json:"priority"`
+	Disabled    *bool  `help:"Do not restart the server when host down" json:"disabled"`
+	MaxRestarts *int   `help:"Maximal restart attempts when host down" json:"max_restarts"`
+}
+
+func (o *ServerSetHaPolicyOptions) GetId() string {
+	return o.ID
+}
+
+func (o *ServerSetHaPolicyOptions) Params() (jsonutils.JSONObject, error) {
+	return StructToParams(o)
+}
+
+type ServerSetHostDrainPolicyOptions struct {
+	ID     string `help:"ID or name of server" json:"-"`
+	Policy string `help:"Drain policy when host entering maintenance, empty to follow the host maintenance request" choices:"live-migrate|cold-migrate|stop|skip" json:"policy"`
+}
+
+func (o *ServerSetHostDrainPolicyOptions) GetId() string {
+	return o.ID
+}
+
+func (o *ServerSetHostDrainPolicyOptions) Params() (jsonutils.JSONObject, error) {
+	return StructToParams(o)
+}
+
 type ResourceMetadataOptions struct {
 	ID   string   `help:"ID or name of resources" json:"-"`
 	TAGS []string `help:"Tags info, eg: hypervisor=aliyun、os_type=Linux、os_version"`
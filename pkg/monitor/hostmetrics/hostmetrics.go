@@ -0,0 +1,219 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostmetrics
+
+import (
+	"context"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/apis"
+	monitorapi "yunion.io/x/onecloud/pkg/apis/monitor"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	"yunion.io/x/onecloud/pkg/monitor/tsdb"
+	_ "yunion.io/x/onecloud/pkg/monitor/tsdb/driver/influxdb"
+)
+
+const (
+	metricsDatabase     = "telegraf"
+	metricsQueryTimeout = 10 * time.Second
+
+	hostMetricsRefCPU    = "cpu"
+	hostMetricsRefMem    = "mem"
+	hostMetricsRefDiskIO = "diskio"
+	hostMetricsRefNet    = "net"
+)
+
+// HostMetrics is the recent average utilization of a host,
+// every usage is a ratio between 0 and 1
+type HostMetrics struct {
+	CPUUsage    float64   `json:"cpu_usage"`
+	MemUsage    float64   `json:"mem_usage"`
+	DiskIOUsage float64   `json:"diskio_usage"`
+	NetUsage    float64   `json:"net_usage"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (m *HostMetrics) IsStale(staleAfter time.Duration) bool {
+	return time.Since(m.UpdatedAt) > staleAfter
+}
+
+// NewMeanQuery builds the query of mean fields per minute of every series grouped by groupByTag,
+// counters are converted to rate per second if derivative
+func NewMeanQuery(refId, measurement string, tags []monitorapi.MetricQueryTag, fields []string, groupByTag string, derivative bool) *tsdb.Query {
+	selects := make([]monitorapi.MetricQuerySelect, 0, len(fields))
+	for _, field := range fields {
+		sel := monitorapi.NewMetricQuerySelect(
+			monitorapi.MetricQueryPart{Type: "field", Params: []string{field}},
+			monitorapi.MetricQueryPart{Type: "mean"},
+		)
+		if derivative {
+			sel = append(sel, monitorapi.MetricQueryPart{Type: "non_negative_derivative", Params: []string{"1s"}})
+		}
+		selects = append(selects, sel)
+	}
+	return &tsdb.Query{
+		RefId: refId,
+		MetricQuery: monitorapi.MetricQuery{
+			Database:    metricsDatabase,
+			Measurement: measurement,
+			Tags:        tags,
+			Selects:     selects,
+			GroupBy: []monitorapi.MetricQueryPart{
+				{Type: "time", Params: []string{"1m"}},
+				{Type: "tag", Params: []string{groupByTag}},
+				{Type: "fill", Params: []string{"none"}},
+			},
+		},
+	}
+}
+
+func newHostMetricsQuery(refId, measurement string, tags []monitorapi.MetricQueryTag, fields []string, derivative bool) *tsdb.Query {
+	tags = append([]monitorapi.MetricQueryTag{
+		{Key: "res_type", Operator: "=", Value: "host"},
+	}, tags...)
+	return NewMeanQuery(refId, measurement, tags, fields, "host_id", derivative)
+}
+
+// Query sends the queries to the telegraf database of influxdb in region
+func Query(ctx context.Context, region string, req *tsdb.TsdbQuery) (*tsdb.Response, error) {
+	url, err := auth.GetServiceURL(apis.SERVICE_TYPE_INFLUXDB, region, "", "")
+	if err != nil {
+		return nil, errors.Wrap(err, "get influxdb url")
+	}
+	ds := &tsdb.DataSource{
+		Id:       metricsDatabase,
+		Name:     apis.SERVICE_TYPE_INFLUXDB,
+		Type:     apis.SERVICE_TYPE_INFLUXDB,
+		Url:      url,
+		Database: metricsDatabase,
+	}
+	ctx, cancel := context.WithTimeout(ctx, metricsQueryTimeout)
+	defer cancel()
+	resp, err := tsdb.HandleRequest(ctx, ds, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "query influxdb")
+	}
+	return resp, nil
+}
+
+// FetchHostMetrics queries the average utilization of every host in the window
+// from telegraf metrics of influxdb, hosts are keyed by id
+func FetchHostMetrics(ctx context.Context, region string, window string) (map[string]*HostMetrics, error) {
+	andTag := func(key, value string) monitorapi.MetricQueryTag {
+		return monitorapi.MetricQueryTag{Key: key, Operator: "=", Value: value, Condition: "AND"}
+	}
+	req := &tsdb.TsdbQuery{
+		TimeRange: tsdb.NewTimeRange(window, "now"),
+		Queries: []*tsdb.Query{
+			newHostMetricsQuery(hostMetricsRefCPU, "cpu",
+				[]monitorapi.MetricQueryTag{andTag("cpu", "cpu-total")}, []string{"usage_active"}, false),
+			newHostMetricsQuery(hostMetricsRefMem, "mem", nil, []string{"used_percent"}, false),
+			newHostMetricsQuery(hostMetricsRefDiskIO, "diskio", nil, []string{"read_bps", "write_bps"}, false),
+			newHostMetricsQuery(hostMetricsRefNet, "net", nil, []string{"bytes_recv", "bytes_sent"}, true),
+		},
+	}
+	resp, err := Query(ctx, region, req)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := make(map[string]*HostMetrics)
+	getMetrics := func(hostId string) *HostMetrics {
+		m, ok := metrics[hostId]
+		if !ok {
+			m = &HostMetrics{}
+			metrics[hostId] = m
+		}
+		return m
+	}
+	var maxDiskIO, maxNet float64
+	for refId, result := range resp.Results {
+		for _, series := range result.Series {
+			hostId := series.Tags["host_id"]
+			if len(hostId) == 0 {
+				continue
+			}
+			value, updatedAt, ok := AverageSeries(series)
+			if !ok {
+				continue
+			}
+			m := getMetrics(hostId)
+			if updatedAt.After(m.UpdatedAt) {
+				m.UpdatedAt = updatedAt
+			}
+			switch refId {
+			case hostMetricsRefCPU:
+				m.CPUUsage = percentToRatio(value)
+			case hostMetricsRefMem:
+				m.MemUsage = percentToRatio(value)
+			case hostMetricsRefDiskIO:
+				m.DiskIOUsage = value
+				if value > maxDiskIO {
+					maxDiskIO = value
+				}
+			case hostMetricsRefNet:
+				m.NetUsage = value
+				if value > maxNet {
+					maxNet = value
+				}
+			}
+		}
+	}
+	// disk io and network have no upper bound, compare with the busiest host
+	for _, m := range metrics {
+		if maxDiskIO > 0 {
+			m.DiskIOUsage = m.DiskIOUsage / maxDiskIO
+		}
+		if maxNet > 0 {
+			m.NetUsage = m.NetUsage / maxNet
+		}
+	}
+	return metrics, nil
+}
+
+// AverageSeries returns the average of summed fields and the time of the latest point
+func AverageSeries(series *tsdb.TimeSeries) (float64, time.Time, bool) {
+	var sum, latest float64
+	count := 0
+	for _, point := range series.Points {
+		if len(point) < 2 || !point.IsValids() {
+			continue
+		}
+		for _, val := range point.Values() {
+			sum += val
+		}
+		if ts := point.Timestamp(); ts > latest {
+			latest = ts
+		}
+		count++
+	}
+	if count == 0 {
+		return 0, time.Time{}, false
+	}
+	return sum / float64(count), time.Unix(0, int64(latest)*int64(time.Millisecond)), true
+}
+
+func percentToRatio(val float64) float64 {
+	val = val / 100
+	if val < 0 {
+		return 0
+	}
+	if val > 1 {
+		return 1
+	}
+	return val
+}
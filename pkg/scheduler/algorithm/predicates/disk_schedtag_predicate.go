@@ -100,6 +100,13 @@ func (p *DiskSchedtagPredicate) IsResourceFitInput(u *core.Unit, c core.Candidat
 		}
 	}
 
+	if err := u.FilterStorage(storage.Id); err != nil {
+		return &FailReason{
+			fmt.Sprintf("Storage %s: %v", storage.Name, err),
+			StorageAffinity,
+		}
+	}
+
 	d := input.(*diskW)
 	if d.Storage != "" {
 		if storage.Id != d.Storage && storage.Name != d.Storage {
@@ -127,4 +127,5 @@ const (
 	StorageType      = "storage_type"
 	StorageOwnership = "storage_ownership"
 	StorageMedium    = "storage_medium"
+	StorageAffinity  = "storage_affinity"
 )
@@ -0,0 +1,216 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guest
+
+import (
+	"fmt"
+
+	"yunion.io/x/log"
+
+	"yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/scheduler/algorithm/plugin"
+	"yunion.io/x/onecloud/pkg/scheduler/algorithm/predicates"
+	"yunion.io/x/onecloud/pkg/scheduler/core"
+	"yunion.io/x/onecloud/pkg/scheduler/core/score"
+)
+
+// AffinityPredicate filters candidates by the hard affinity and anti-affinity
+// rules of the guest, and scores them by the soft ones.
+type AffinityPredicate struct {
+	predicates.BasePredicate
+	plugin.BasePlugin
+
+	rules []*compute.SchedAffinityRule
+	// the count of matched guests in each topology domain, indexed as rules
+	domainCounts []map[string]int
+	// the count of placed guests in each topology domain, keyed by topology key,
+	// whose hard anti-affinity rules match the guest
+	antiCounts map[string]map[string]int
+}
+
+func (p *AffinityPredicate) Name() string {
+	return "guest_affinity"
+}
+
+func (p *AffinityPredicate) Clone() core.FitPredicate {
+	return &AffinityPredicate{}
+}
+
+func (p *AffinityPredicate) PreExecute(u *core.Unit, cs []core.Candidater) (bool, error) {
+	schedData := u.SchedData()
+	excludeIds := make([]string, 0)
+	if len(schedData.Id) > 0 {
+		excludeIds = append(excludeIds, schedData.Id)
+	}
+	for _, g := range schedData.ForGuests {
+		excludeIds = append(excludeIds, g.Id)
+	}
+
+	antiCounts, err := models.FetchAntiAffinityDomains(schedData.Id, schedData.InstanceGroupIds, schedData.Metadata, excludeIds)
+	if err != nil {
+		return false, err
+	}
+	if len(schedData.AffinityRules) == 0 && len(antiCounts) == 0 {
+		return false, nil
+	}
+	p.antiCounts = antiCounts
+	if counts := antiCounts[compute.AFFINITY_TOPOLOGY_STORAGE]; len(counts) > 0 {
+		antiRule := &compute.SchedAffinityRule{Type: compute.AFFINITY_TYPE_ANTI_AFFINITY}
+		u.AppendStorageFilter(affinityStorageFilter(antiRule, counts))
+	}
+
+	p.rules = schedData.AffinityRules
+	p.domainCounts = make([]map[string]int, len(p.rules))
+	for i, rule := range p.rules {
+		guests, err := models.FetchAffinityGuests(rule, excludeIds)
+		if err != nil {
+			return false, err
+		}
+		counts := make(map[string]int)
+		for j := range guests {
+			for _, domain := range guests[j].GetAffinityDomains(rule.TopologyKey) {
+				counts[domain]++
+			}
+		}
+		log.Debugf("affinity rule %d %s/%s/%s matched %d guests", i, rule.Type, rule.Mode, rule.TopologyKey, len(guests))
+		p.domainCounts[i] = counts
+		if rule.Mode == compute.AFFINITY_MODE_HARD && rule.TopologyKey == compute.AFFINITY_TOPOLOGY_STORAGE && len(counts) > 0 {
+			// the storages of disks are selected after the host, restrict them directly
+			u.AppendStorageFilter(affinityStorageFilter(rule, counts))
+		}
+	}
+
+	u.AppendSelectPlugin(p)
+	return true, nil
+}
+
+// affinityStorageFilter allows the storages which satisfy the hard storage rule
+func affinityStorageFilter(rule *compute.SchedAffinityRule, counts map[string]int) func(string) error {
+	return func(storageId string) error {
+		count := counts[storageId]
+		if rule.Type == compute.AFFINITY_TYPE_ANTI_AFFINITY && count > 0 {
+			return fmt.Errorf("anti-affinity with %d guests", count)
+		}
+		if rule.Type == compute.AFFINITY_TYPE_AFFINITY && count == 0 {
+			return fmt.Errorf("no affinity guests")
+		}
+		return nil
+	}
+}
+
+// candidateDomains returns the domains of c, the storage domains are the ones
+// which can be selected for the disks of request
+func (p *AffinityPredicate) candidateDomains(u *core.Unit, c core.Candidater, topologyKey string) []string {
+	getter := c.Getter()
+	host := getter.Host()
+	if host == nil {
+		return nil
+	}
+	storageIds := make([]string, 0)
+	for _, storage := range getter.Storages() {
+		if storage.Enabled.IsFalse() || u.FilterStorage(storage.Id) != nil {
+			continue
+		}
+		storageIds = append(storageIds, storage.Id)
+	}
+	return models.AffinityDomains(topologyKey, host, storageIds, getter.HostSchedtags())
+}
+
+func countInDomains(counts map[string]int, domains []string) int {
+	count := 0
+	for _, domain := range domains {
+		count += counts[domain]
+	}
+	return count
+}
+
+// matchedCount returns the count of guests matched by rule i in the domains of c
+func (p *AffinityPredicate) matchedCount(i int, domains []string) int {
+	return countInDomains(p.domainCounts[i], domains)
+}
+
+func (p *AffinityPredicate) Execute(u *core.Unit, c core.Candidater) (bool, []core.PredicateFailureReason, error) {
+	h := predicates.NewPredicateHelper(p, u, c)
+
+	// the placed guests refuse the guest by their own anti-affinity rules
+	for topologyKey, counts := range p.antiCounts {
+		count := countInDomains(counts, p.candidateDomains(u, c, topologyKey))
+		if count > 0 {
+			h.Exclude(fmt.Sprintf("anti-affinity of %d guests in %s", count, topologyKey))
+			return h.GetResult()
+		}
+	}
+
+	for i, rule := range p.rules {
+		if rule.Mode != compute.AFFINITY_MODE_HARD {
+			continue
+		}
+		domains := p.candidateDomains(u, c, rule.TopologyKey)
+		count := p.matchedCount(i, domains)
+		switch rule.Type {
+		case compute.AFFINITY_TYPE_ANTI_AFFINITY:
+			if count > 0 {
+				h.Exclude(fmt.Sprintf("anti-affinity with %d guests in %s", count, rule.TopologyKey))
+				return h.GetResult()
+			}
+			if len(domains) > 0 {
+				// guests of one request must not share the domain either
+				exclusive := make([]string, len(domains))
+				for j := range domains {
+					exclusive[j] = fmt.Sprintf("%d/%s", i, domains[j])
+				}
+				u.AppendExclusiveDomains(c.IndexKey(), exclusive)
+				h.SetCapacity(1)
+			}
+		case compute.AFFINITY_TYPE_AFFINITY:
+			if len(p.domainCounts[i]) > 0 {
+				if count == 0 {
+					h.Exclude(fmt.Sprintf("no affinity guests in %s", rule.TopologyKey))
+					return h.GetResult()
+				}
+				continue
+			}
+			// the first guests of an affinity rule can be placed in any domain,
+			// but guests of one request must share it
+			if len(domains) == 0 {
+				h.Exclude(fmt.Sprintf("no %s topology domain", rule.TopologyKey))
+				return h.GetResult()
+			}
+			u.AppendAffinityDomains(c.IndexKey(), fmt.Sprintf("%d", i), domains)
+		}
+	}
+
+	return h.GetResult()
+}
+
+func (p *AffinityPredicate) OnPriorityEnd(u *core.Unit, c core.Candidater) {
+	for i, rule := range p.rules {
+		if rule.Mode != compute.AFFINITY_MODE_SOFT {
+			continue
+		}
+		count := p.matchedCount(i, p.candidateDomains(u, c, rule.TopologyKey))
+		if count == 0 {
+			continue
+		}
+		name := fmt.Sprintf("%s:%d", p.Name(), i)
+		val := score.NewScore(score.TScore(count*rule.Weight), name)
+		if rule.Type == compute.AFFINITY_TYPE_AFFINITY {
+			u.SetPreferScore(c.IndexKey(), val)
+		} else {
+			u.SetAvoidScore(c.IndexKey(), val)
+		}
+	}
+}
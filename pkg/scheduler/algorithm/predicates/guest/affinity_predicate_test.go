@@ -0,0 +1,283 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guest
+
+import (
+	"testing"
+
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
+	schedapi "yunion.io/x/onecloud/pkg/apis/scheduler"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/scheduler/api"
+	"yunion.io/x/onecloud/pkg/scheduler/core"
+	"yunion.io/x/onecloud/pkg/scheduler/core/score"
+)
+
+type fakeAffinityGetter struct {
+	core.CandidatePropertyGetter
+	host     *models.SHost
+	storages []*api.CandidateStorage
+}
+
+func (g *fakeAffinityGetter) Host() *models.SHost {
+	return g.host
+}
+
+func (g *fakeAffinityGetter) Storages() []*api.CandidateStorage {
+	return g.storages
+}
+
+func (g *fakeAffinityGetter) HostSchedtags() []models.SSchedtag {
+	return nil
+}
+
+type fakeAffinityCandidate struct {
+	core.Candidater
+	getter *fakeAffinityGetter
+}
+
+func (c *fakeAffinityCandidate) IndexKey() string {
+	return c.getter.host.Id
+}
+
+func (c *fakeAffinityCandidate) Getter() core.CandidatePropertyGetter {
+	return c.getter
+}
+
+func newFakeAffinityCandidate(hostId, zoneId string, storageIds ...string) core.Candidater {
+	host := &models.SHost{}
+	host.Id = hostId
+	host.ZoneId = zoneId
+	storages := make([]*api.CandidateStorage, 0)
+	for _, id := range storageIds {
+		storage := &models.SStorage{}
+		storage.Id = id
+		storages = append(storages, &api.CandidateStorage{SStorage: storage})
+	}
+	return &fakeAffinityCandidate{getter: &fakeAffinityGetter{host: host, storages: storages}}
+}
+
+func newAffinityTestUnit(count int) *core.Unit {
+	info := &api.SchedInfo{
+		ScheduleInput: &schedapi.ScheduleInput{
+			ServerConfig: schedapi.ServerConfig{
+				ServerConfigs: &computeapi.ServerConfigs{Count: count},
+			},
+		},
+	}
+	return core.NewScheduleUnit(info, nil)
+}
+
+func newAffinityTestPredicate(rule *computeapi.SchedAffinityRule, counts map[string]int) *AffinityPredicate {
+	return &AffinityPredicate{
+		rules:        []*computeapi.SchedAffinityRule{rule},
+		domainCounts: []map[string]int{counts},
+	}
+}
+
+func TestAffinityPredicateExecute(t *testing.T) {
+	cases := []struct {
+		name   string
+		rule   *computeapi.SchedAffinityRule
+		counts map[string]int
+		want   map[string]bool
+	}{
+		{
+			name: "anti-affinity excludes occupied host",
+			rule: &computeapi.SchedAffinityRule{
+				Type: computeapi.AFFINITY_TYPE_ANTI_AFFINITY, Mode: computeapi.AFFINITY_MODE_HARD, TopologyKey: computeapi.AFFINITY_TOPOLOGY_HOST,
+			},
+			counts: map[string]int{"h1": 1},
+			want:   map[string]bool{"h1": false, "h2": true, "h3": true},
+		},
+		{
+			name: "affinity requires zone of existing guests",
+			rule: &computeapi.SchedAffinityRule{
+				Type: computeapi.AFFINITY_TYPE_AFFINITY, Mode: computeapi.AFFINITY_MODE_HARD, TopologyKey: computeapi.AFFINITY_TOPOLOGY_ZONE,
+			},
+			counts: map[string]int{"z2": 2},
+			want:   map[string]bool{"h1": false, "h2": true, "h3": true},
+		},
+		{
+			name: "affinity of empty group allows any zone",
+			rule: &computeapi.SchedAffinityRule{
+				Type: computeapi.AFFINITY_TYPE_AFFINITY, Mode: computeapi.AFFINITY_MODE_HARD, TopologyKey: computeapi.AFFINITY_TOPOLOGY_ZONE,
+			},
+			counts: map[string]int{},
+			want:   map[string]bool{"h1": true, "h2": true, "h3": true},
+		},
+		{
+			name: "soft rule never excludes",
+			rule: &computeapi.SchedAffinityRule{
+				Type: computeapi.AFFINITY_TYPE_ANTI_AFFINITY, Mode: computeapi.AFFINITY_MODE_SOFT, TopologyKey: computeapi.AFFINITY_TOPOLOGY_HOST,
+			},
+			counts: map[string]int{"h1": 1},
+			want:   map[string]bool{"h1": true, "h2": true, "h3": true},
+		},
+	}
+	candidates := []core.Candidater{
+		newFakeAffinityCandidate("h1", "z1", "s1"),
+		newFakeAffinityCandidate("h2", "z2", "s2"),
+		newFakeAffinityCandidate("h3", "z2", "s2"),
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			u := newAffinityTestUnit(1)
+			p := newAffinityTestPredicate(c.rule, c.counts)
+			for _, candidate := range candidates {
+				ok, _, err := p.Execute(u, candidate)
+				if err != nil {
+					t.Fatalf("execute %s: %v", candidate.IndexKey(), err)
+				}
+				if ok != c.want[candidate.IndexKey()] {
+					t.Errorf("candidate %s: want %v, got %v", candidate.IndexKey(), c.want[candidate.IndexKey()], ok)
+				}
+			}
+		})
+	}
+}
+
+func TestAffinityPredicatePinRequestToOneDomain(t *testing.T) {
+	rule := &computeapi.SchedAffinityRule{
+		Type: computeapi.AFFINITY_TYPE_AFFINITY, Mode: computeapi.AFFINITY_MODE_HARD, TopologyKey: computeapi.AFFINITY_TOPOLOGY_ZONE,
+	}
+	u := newAffinityTestUnit(2)
+	p := newAffinityTestPredicate(rule, map[string]int{})
+	candidates := []core.Candidater{
+		newFakeAffinityCandidate("h1", "z1"),
+		newFakeAffinityCandidate("h2", "z1"),
+		newFakeAffinityCandidate("h3", "z2"),
+	}
+	priorityList := core.HostPriorityList{}
+	for _, candidate := range candidates {
+		ok, _, err := p.Execute(u, candidate)
+		if !ok || err != nil {
+			t.Fatalf("candidate %s should fit: %v", candidate.IndexKey(), err)
+		}
+		u.SetCapacity(candidate.IndexKey(), "test", core.NewNormalCounter(1))
+		priorityList = append(priorityList, core.HostPriority{
+			Host:      candidate.IndexKey(),
+			Score:     core.Score{ScoreBucket: score.NewScoreBuckets()},
+			Candidate: candidate,
+		})
+	}
+	// candidates of the same score are selected by name desc, h3 is the first
+	// but z2 has no room for the second guest
+	selected, err := core.SelectHosts(u, priorityList)
+	if err == nil {
+		t.Fatalf("guests of one request should not be spread over zones: %#v", selected)
+	}
+
+	u = newAffinityTestUnit(2)
+	for _, candidate := range candidates {
+		p.Execute(u, candidate)
+		u.SetCapacity(candidate.IndexKey(), "test", core.NewNormalCounter(2))
+	}
+	selected, err = core.SelectHosts(u, priorityList)
+	if err != nil {
+		t.Fatalf("select hosts: %v", err)
+	}
+	if len(selected) != 1 || selected[0].Candidate.IndexKey() != "h3" || selected[0].Count != 2 {
+		t.Errorf("want both guests on h3, got %#v", selected)
+	}
+}
+
+func TestAffinityPredicateStorageFilter(t *testing.T) {
+	rule := &computeapi.SchedAffinityRule{
+		Type: computeapi.AFFINITY_TYPE_ANTI_AFFINITY, Mode: computeapi.AFFINITY_MODE_HARD, TopologyKey: computeapi.AFFINITY_TOPOLOGY_STORAGE,
+	}
+	u := newAffinityTestUnit(1)
+	u.AppendStorageFilter(affinityStorageFilter(rule, map[string]int{"s1": 1}))
+	if u.FilterStorage("s1") == nil {
+		t.Errorf("storage s1 with anti-affinity guest should be filtered")
+	}
+	if err := u.FilterStorage("s2"); err != nil {
+		t.Errorf("storage s2 should be allowed: %v", err)
+	}
+
+	p := newAffinityTestPredicate(rule, map[string]int{"s1": 1})
+	// the host is still usable by the storage without anti-affinity guests
+	ok, _, err := p.Execute(u, newFakeAffinityCandidate("h1", "z1", "s1", "s2"))
+	if !ok || err != nil {
+		t.Errorf("host with free storage s2 should fit: %v", err)
+	}
+	domains := p.candidateDomains(u, newFakeAffinityCandidate("h1", "z1", "s1", "s2"), rule.TopologyKey)
+	if len(domains) != 1 || domains[0] != "s2" {
+		t.Errorf("want storage domains [s2], got %v", domains)
+	}
+
+	affinity := &computeapi.SchedAffinityRule{
+		Type: computeapi.AFFINITY_TYPE_AFFINITY, Mode: computeapi.AFFINITY_MODE_HARD, TopologyKey: computeapi.AFFINITY_TOPOLOGY_STORAGE,
+	}
+	filter := affinityStorageFilter(affinity, map[string]int{"s1": 1})
+	if filter("s1") != nil || filter("s2") == nil {
+		t.Errorf("affinity storage filter should only allow s1")
+	}
+}
+
+func TestAffinityPredicateAntiAffinityOfPlacedGuests(t *testing.T) {
+	cases := []struct {
+		name       string
+		antiCounts map[string]map[string]int
+		want       map[string]bool
+	}{
+		{
+			name:       "placed guest refuses its host",
+			antiCounts: map[string]map[string]int{computeapi.AFFINITY_TOPOLOGY_HOST: {"h1": 1}},
+			want:       map[string]bool{"h1": false, "h2": true, "h3": true},
+		},
+		{
+			name:       "placed guest refuses its zone",
+			antiCounts: map[string]map[string]int{computeapi.AFFINITY_TOPOLOGY_ZONE: {"z2": 1}},
+			want:       map[string]bool{"h1": true, "h2": false, "h3": false},
+		},
+		{
+			// storages are restricted by the storage filter instead of the host
+			name:       "placed guest refuses its storage",
+			antiCounts: map[string]map[string]int{computeapi.AFFINITY_TOPOLOGY_STORAGE: {"s2": 1}},
+			want:       map[string]bool{"h1": true, "h2": true, "h3": true},
+		},
+	}
+	candidates := []core.Candidater{
+		newFakeAffinityCandidate("h1", "z1", "s1"),
+		newFakeAffinityCandidate("h2", "z2", "s2"),
+		newFakeAffinityCandidate("h3", "z2", "s2", "s3"),
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			u := newAffinityTestUnit(1)
+			if counts := c.antiCounts[computeapi.AFFINITY_TOPOLOGY_STORAGE]; len(counts) > 0 {
+				antiRule := &computeapi.SchedAffinityRule{Type: computeapi.AFFINITY_TYPE_ANTI_AFFINITY}
+				u.AppendStorageFilter(affinityStorageFilter(antiRule, counts))
+			}
+			// the request has no affinity rules of its own
+			p := &AffinityPredicate{antiCounts: c.antiCounts}
+			for _, candidate := range candidates {
+				ok, _, err := p.Execute(u, candidate)
+				if err != nil {
+					t.Fatalf("execute %s: %v", candidate.IndexKey(), err)
+				}
+				if ok != c.want[candidate.IndexKey()] {
+					t.Errorf("candidate %s: want %v, got %v", candidate.IndexKey(), c.want[candidate.IndexKey()], ok)
+				}
+			}
+			for storageId := range c.antiCounts[computeapi.AFFINITY_TOPOLOGY_STORAGE] {
+				if u.FilterStorage(storageId) == nil {
+					t.Errorf("storage %s should be filtered", storageId)
+				}
+			}
+		})
+	}
+}
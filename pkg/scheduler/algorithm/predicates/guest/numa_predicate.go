@@ -0,0 +1,77 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guest
+
+import (
+	"fmt"
+
+	"yunion.io/x/onecloud/pkg/scheduler/algorithm/predicates"
+	"yunion.io/x/onecloud/pkg/scheduler/core"
+	o "yunion.io/x/onecloud/pkg/scheduler/options"
+)
+
+// NumaPredicate check whether guest fits in one numa node of the host,
+// hosts without fit node are filtered out only if numa_fit_strict is set,
+// otherwise the numa priority prefers hosts having fit node.
+type NumaPredicate struct {
+	predicates.BasePredicate
+}
+
+func (p *NumaPredicate) Name() string {
+	return "host_numa"
+}
+
+func (p *NumaPredicate) Clone() core.FitPredicate {
+	return &NumaPredicate{}
+}
+
+func (p *NumaPredicate) PreExecute(u *core.Unit, cs []core.Candidater) (bool, error) {
+	if !o.GetOptions().NumaFitStrict {
+		return false, nil
+	}
+	if !u.GetHypervisorDriver().DoScheduleMemoryFilter() {
+		return false, nil
+	}
+
+	data := u.SchedData()
+	if data.Memory <= 0 || data.Ncpu <= 0 {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+func (p *NumaPredicate) Execute(u *core.Unit, c core.Candidater) (bool, []core.PredicateFailureReason, error) {
+	h := predicates.NewPredicateHelper(p, u, c)
+	d := u.SchedData()
+
+	numaInfo := c.Getter().Host().GetNumaInfo()
+	if numaInfo == nil || len(numaInfo.Nodes) < 2 {
+		return h.GetResult()
+	}
+
+	var capacity int64
+	for _, node := range numaInfo.Nodes {
+		if len(node.Cpus) >= d.Ncpu {
+			capacity += int64(node.GuestFreeMemMb() / d.Memory)
+		}
+	}
+	if capacity == 0 {
+		h.Exclude(fmt.Sprintf("no numa node fits cpu %d memory %dM", d.Ncpu, d.Memory))
+	}
+
+	h.SetCapacity(capacity)
+	return h.GetResult()
+}
@@ -0,0 +1,138 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guest
+
+import (
+	"testing"
+
+	"yunion.io/x/jsonutils"
+
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
+	schedapi "yunion.io/x/onecloud/pkg/apis/scheduler"
+	"yunion.io/x/onecloud/pkg/scheduler/api"
+	"yunion.io/x/onecloud/pkg/scheduler/core"
+	o "yunion.io/x/onecloud/pkg/scheduler/options"
+)
+
+func newFakeNumaCandidate(hostId string, nodes ...computeapi.HostNumaNode) core.Candidater {
+	c := newFakeAffinityCandidate(hostId, "z1").(*fakeAffinityCandidate)
+	if len(nodes) > 0 {
+		c.getter.host.NumaInfo = jsonutils.Marshal(&computeapi.HostNumaInfo{Nodes: nodes})
+	}
+	return c
+}
+
+func newNumaTestUnit(ncpu, memMb int) *core.Unit {
+	info := &api.SchedInfo{
+		ScheduleInput: &schedapi.ScheduleInput{
+			ServerConfig: schedapi.ServerConfig{
+				ServerConfigs: &computeapi.ServerConfigs{Count: 1},
+				Ncpu:          ncpu,
+				Memory:        memMb,
+			},
+		},
+	}
+	return core.NewScheduleUnit(info, nil)
+}
+
+func TestNumaPredicatePreExecute(t *testing.T) {
+	o.GetOptions().NumaFitStrict = false
+	p := &NumaPredicate{}
+	if ok, _ := p.PreExecute(newNumaTestUnit(2, 2048), nil); ok {
+		t.Errorf("numa predicate should be skipped unless numa_fit_strict")
+	}
+}
+
+func TestNumaPredicateExecute(t *testing.T) {
+	cases := []struct {
+		name      string
+		candidate core.Candidater
+		ncpu      int
+		memMb     int
+		fit       bool
+		capacity  int64
+	}{
+		{
+			name: "fit in both nodes",
+			candidate: newFakeNumaCandidate("h1",
+				computeapi.HostNumaNode{NodeId: 0, Cpus: []int{0, 1, 2, 3}, FreeMemMb: 8192},
+				computeapi.HostNumaNode{NodeId: 1, Cpus: []int{4, 5, 6, 7}, FreeMemMb: 4096},
+			),
+			ncpu: 2, memMb: 2048, fit: true, capacity: 6,
+		},
+		{
+			name: "fit in one node only",
+			candidate: newFakeNumaCandidate("h2",
+				computeapi.HostNumaNode{NodeId: 0, Cpus: []int{0, 1, 2, 3}, FreeMemMb: 8192},
+				computeapi.HostNumaNode{NodeId: 1, Cpus: []int{4, 5, 6, 7}, FreeMemMb: 1024},
+			),
+			ncpu: 2, memMb: 4096, fit: true, capacity: 2,
+		},
+		{
+			name: "memory spread over nodes",
+			candidate: newFakeNumaCandidate("h3",
+				computeapi.HostNumaNode{NodeId: 0, Cpus: []int{0, 1, 2, 3}, FreeMemMb: 4096},
+				computeapi.HostNumaNode{NodeId: 1, Cpus: []int{4, 5, 6, 7}, FreeMemMb: 4096},
+			),
+			ncpu: 2, memMb: 6144, fit: false, capacity: 0,
+		},
+		{
+			name: "cpus spread over nodes",
+			candidate: newFakeNumaCandidate("h4",
+				computeapi.HostNumaNode{NodeId: 0, Cpus: []int{0, 1}, FreeMemMb: 8192},
+				computeapi.HostNumaNode{NodeId: 1, Cpus: []int{2, 3}, FreeMemMb: 8192},
+			),
+			ncpu: 4, memMb: 1024, fit: false, capacity: 0,
+		},
+		{
+			name: "hugepages count instead of free memory",
+			candidate: newFakeNumaCandidate("h5",
+				computeapi.HostNumaNode{NodeId: 0, Cpus: []int{0, 1, 2, 3}, FreeMemMb: 8192, HugepageSizeKb: 2048, HugepageTotal: 1024, HugepageFree: 512},
+				computeapi.HostNumaNode{NodeId: 1, Cpus: []int{4, 5, 6, 7}, FreeMemMb: 8192, HugepageSizeKb: 2048, HugepageTotal: 1024, HugepageFree: 0},
+			),
+			ncpu: 2, memMb: 2048, fit: false, capacity: 0,
+		},
+		{
+			name: "single node host is not filtered",
+			candidate: newFakeNumaCandidate("h6",
+				computeapi.HostNumaNode{NodeId: 0, Cpus: []int{0, 1}, FreeMemMb: 1024},
+			),
+			ncpu: 4, memMb: 8192, fit: true, capacity: -1,
+		},
+		{
+			name:      "host without numa info is not filtered",
+			candidate: newFakeNumaCandidate("h7"),
+			ncpu:      4, memMb: 8192, fit: true, capacity: -1,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			u := newNumaTestUnit(c.ncpu, c.memMb)
+			p := &NumaPredicate{}
+			ok, _, err := p.Execute(u, c.candidate)
+			if err != nil {
+				t.Fatalf("execute: %v", err)
+			}
+			if ok != c.fit {
+				t.Errorf("want fit %v, got %v", c.fit, ok)
+			}
+			if c.capacity >= 0 {
+				if got := u.GetCapacityOfName(c.candidate.IndexKey(), p.Name()); got != c.capacity {
+					t.Errorf("want capacity %d, got %d", c.capacity, got)
+				}
+			}
+		})
+	}
+}
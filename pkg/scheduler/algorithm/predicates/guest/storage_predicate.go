@@ -114,7 +114,7 @@ func (p *StoragePredicate) Execute(u *core.Unit, c core.Candidater) (bool, []cor
 					free := total - s.ActualCapacityUsed
 					ss = append(ss, fmt.Sprintf("actual_total:%d - actual_used:%d = free:%d", total, s.ActualCapacityUsed, free))
 				} else {
-					total := int64(float32(s.Capacity) * s.Cmtbound)
+					total := int64(float32(s.Capacity) * s.GetSchedCmtbound())
 					used := s.GetUsedCapacity(tristate.True)
 					waste := s.GetUsedCapacity(tristate.False)
 					free := total - int64(used) - int64(waste)
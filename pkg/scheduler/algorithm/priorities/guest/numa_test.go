@@ -0,0 +1,141 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guest
+
+import (
+	"testing"
+
+	"yunion.io/x/jsonutils"
+
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
+	schedapi "yunion.io/x/onecloud/pkg/apis/scheduler"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/scheduler/api"
+	"yunion.io/x/onecloud/pkg/scheduler/core"
+)
+
+type fakePriorityGetter struct {
+	core.CandidatePropertyGetter
+	host *models.SHost
+}
+
+func (g *fakePriorityGetter) Host() *models.SHost {
+	return g.host
+}
+
+type fakePriorityCandidate struct {
+	core.Candidater
+	getter *fakePriorityGetter
+}
+
+func (c *fakePriorityCandidate) IndexKey() string {
+	return c.getter.host.Id
+}
+
+func (c *fakePriorityCandidate) Getter() core.CandidatePropertyGetter {
+	return c.getter
+}
+
+func newFakePriorityCandidate(hostId string) *fakePriorityCandidate {
+	host := &models.SHost{}
+	host.Id = hostId
+	return &fakePriorityCandidate{getter: &fakePriorityGetter{host: host}}
+}
+
+func newPriorityTestUnit(ncpu, memMb int) *core.Unit {
+	info := &api.SchedInfo{
+		ScheduleInput: &schedapi.ScheduleInput{
+			ServerConfig: schedapi.ServerConfig{
+				ServerConfigs: &computeapi.ServerConfigs{Count: 1},
+				Ncpu:          ncpu,
+				Memory:        memMb,
+			},
+		},
+	}
+	return core.NewScheduleUnit(info, nil)
+}
+
+func getPriorityScore(u *core.Unit, c core.Candidater, name string) (int, bool) {
+	val, ok := u.GetScore(c.IndexKey()).Details()["normal"][name]
+	return val, ok
+}
+
+func TestNumaPriorityMap(t *testing.T) {
+	cases := []struct {
+		name  string
+		nodes []computeapi.HostNumaNode
+		ncpu  int
+		memMb int
+		score bool
+	}{
+		{
+			name: "fit in one node",
+			nodes: []computeapi.HostNumaNode{
+				{NodeId: 0, Cpus: []int{0, 1, 2, 3}, FreeMemMb: 8192},
+				{NodeId: 1, Cpus: []int{4, 5, 6, 7}, FreeMemMb: 1024},
+			},
+			ncpu: 4, memMb: 4096, score: true,
+		},
+		{
+			name: "cpus bound by other guests",
+			nodes: []computeapi.HostNumaNode{
+				{NodeId: 0, Cpus: []int{0, 1, 2, 3}, FreeMemMb: 8192, BoundCpuCount: 2},
+				{NodeId: 1, Cpus: []int{4, 5, 6, 7}, FreeMemMb: 1024},
+			},
+			ncpu: 4, memMb: 4096, score: false,
+		},
+		{
+			name: "spread over nodes",
+			nodes: []computeapi.HostNumaNode{
+				{NodeId: 0, Cpus: []int{0, 1}, FreeMemMb: 4096},
+				{NodeId: 1, Cpus: []int{2, 3}, FreeMemMb: 4096},
+			},
+			ncpu: 4, memMb: 6144, score: false,
+		},
+		{
+			name: "single node host",
+			nodes: []computeapi.HostNumaNode{
+				{NodeId: 0, Cpus: []int{0, 1, 2, 3}, FreeMemMb: 8192},
+			},
+			ncpu: 2, memMb: 1024, score: false,
+		},
+		{
+			name:  "no numa info",
+			ncpu:  2,
+			memMb: 1024,
+			score: false,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			candidate := newFakePriorityCandidate(c.name)
+			if c.nodes != nil {
+				candidate.getter.host.NumaInfo = jsonutils.Marshal(&computeapi.HostNumaInfo{Nodes: c.nodes})
+			}
+			u := newPriorityTestUnit(c.ncpu, c.memMb)
+			p := &NumaPriority{}
+			if _, err := p.Map(u, candidate); err != nil {
+				t.Fatalf("map: %v", err)
+			}
+			val, ok := getPriorityScore(u, candidate, p.Name())
+			if ok != c.score {
+				t.Fatalf("want scored %v, got %v", c.score, ok)
+			}
+			if ok && val != 1 {
+				t.Errorf("want score 1, got %d", val)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guest
+
+import (
+	"yunion.io/x/pkg/utils"
+
+	"yunion.io/x/onecloud/pkg/scheduler/algorithm/priorities"
+	"yunion.io/x/onecloud/pkg/scheduler/core"
+	"yunion.io/x/onecloud/pkg/scheduler/core/score"
+	o "yunion.io/x/onecloud/pkg/scheduler/options"
+)
+
+// UtilizationPriority prefers hosts really idle, the committed resources of
+// overcommitted hosts hardly tell how busy they are
+type UtilizationPriority struct {
+	priorities.BasePriority
+}
+
+func (p *UtilizationPriority) Name() string {
+	return "host_utilization"
+}
+
+func (p *UtilizationPriority) Clone() core.Priority {
+	return &UtilizationPriority{}
+}
+
+func (p *UtilizationPriority) Map(u *core.Unit, c core.Candidater) (core.HostPriority, error) {
+	h := priorities.NewPriorityHelper(p, u, c)
+
+	metrics := c.Getter().HostMetrics()
+	opts := o.GetOptions()
+	if metrics == nil || metrics.IsStale(utils.ToDuration(opts.HostMetricsStaleAfter)) {
+		return h.GetResult()
+	}
+	weights := opts.UtilizationCPUWeight + opts.UtilizationMemWeight + opts.UtilizationDiskIOWeight + opts.UtilizationNetWeight
+	if weights <= 0 {
+		return h.GetResult()
+	}
+	usage := (metrics.CPUUsage*opts.UtilizationCPUWeight +
+		metrics.MemUsage*opts.UtilizationMemWeight +
+		metrics.DiskIOUsage*opts.UtilizationDiskIOWeight +
+		metrics.NetUsage*opts.UtilizationNetWeight) / weights
+	h.SetScore(int(10 * (1 - usage)))
+
+	return h.GetResult()
+}
+
+func (p *UtilizationPriority) ScoreIntervals() score.Intervals {
+	return score.NewIntervals(0, 3, 7)
+}
@@ -0,0 +1,129 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guest
+
+import (
+	"testing"
+	"time"
+
+	"yunion.io/x/onecloud/pkg/scheduler/core"
+	o "yunion.io/x/onecloud/pkg/scheduler/options"
+)
+
+type fakeMetricsGetter struct {
+	*fakePriorityGetter
+	metrics *core.HostMetrics
+}
+
+func (g *fakeMetricsGetter) HostMetrics() *core.HostMetrics {
+	return g.metrics
+}
+
+type fakeMetricsCandidate struct {
+	*fakePriorityCandidate
+	getter *fakeMetricsGetter
+}
+
+func (c *fakeMetricsCandidate) Getter() core.CandidatePropertyGetter {
+	return c.getter
+}
+
+func newFakeMetricsCandidate(hostId string, metrics *core.HostMetrics) *fakeMetricsCandidate {
+	c := newFakePriorityCandidate(hostId)
+	return &fakeMetricsCandidate{
+		fakePriorityCandidate: c,
+		getter:                &fakeMetricsGetter{fakePriorityGetter: c.getter, metrics: metrics},
+	}
+}
+
+func setUtilizationWeights(cpu, mem, diskio, net float64) {
+	opts := o.GetOptions()
+	opts.HostMetricsStaleAfter = "10m"
+	opts.UtilizationCPUWeight = cpu
+	opts.UtilizationMemWeight = mem
+	opts.UtilizationDiskIOWeight = diskio
+	opts.UtilizationNetWeight = net
+}
+
+func TestUtilizationPriorityMap(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		name    string
+		metrics *core.HostMetrics
+		weights [4]float64
+		score   int
+		scored  bool
+	}{
+		{
+			name:    "idle host",
+			metrics: &core.HostMetrics{UpdatedAt: now},
+			weights: [4]float64{1, 1, 0.5, 0.5},
+			score:   10,
+			scored:  true,
+		},
+		{
+			name:    "busy host",
+			metrics: &core.HostMetrics{CPUUsage: 1, MemUsage: 1, DiskIOUsage: 1, NetUsage: 1, UpdatedAt: now},
+			weights: [4]float64{1, 1, 0.5, 0.5},
+			score:   0,
+			scored:  true,
+		},
+		{
+			name:    "weighted usage",
+			metrics: &core.HostMetrics{CPUUsage: 0.8, MemUsage: 0.2, DiskIOUsage: 0.5, NetUsage: 0.5, UpdatedAt: now},
+			weights: [4]float64{1, 1, 0, 0},
+			score:   5,
+			scored:  true,
+		},
+		{
+			name:    "only cpu weighted",
+			metrics: &core.HostMetrics{CPUUsage: 0.3, MemUsage: 0.9, UpdatedAt: now},
+			weights: [4]float64{1, 0, 0, 0},
+			score:   7,
+			scored:  true,
+		},
+		{
+			name:    "missing metrics",
+			weights: [4]float64{1, 1, 0.5, 0.5},
+		},
+		{
+			name:    "stale metrics",
+			metrics: &core.HostMetrics{CPUUsage: 0.1, UpdatedAt: now.Add(-time.Hour)},
+			weights: [4]float64{1, 1, 0.5, 0.5},
+		},
+		{
+			name:    "no weights",
+			metrics: &core.HostMetrics{CPUUsage: 0.1, UpdatedAt: now},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			setUtilizationWeights(c.weights[0], c.weights[1], c.weights[2], c.weights[3])
+			candidate := newFakeMetricsCandidate(c.name, c.metrics)
+			u := newPriorityTestUnit(1, 1024)
+			p := &UtilizationPriority{}
+			if _, err := p.Map(u, candidate); err != nil {
+				t.Fatalf("map: %v", err)
+			}
+			val, ok := getPriorityScore(u, candidate, p.Name())
+			if ok != c.scored {
+				t.Fatalf("want scored %v, got %v", c.scored, ok)
+			}
+			if ok && val != c.score {
+				t.Errorf("want score %d, got %d", c.score, val)
+			}
+		})
+	}
+}
@@ -36,8 +36,10 @@ func defaultPredicates() sets.String {
 		factory.RegisterFitPredicate("e-GuestDomainFilter", &predicates.DomainPredicate{}),
 		factory.RegisterFitPredicate("e-GuestImageFilter", &predicateguest.ImagePredicate{}),
 		//factory.RegisterFitPredicate("f-GuestGroupFilter", &predicateguest.GroupPredicate{}),
+		factory.RegisterFitPredicate("f-GuestAffinityFilter", &predicateguest.AffinityPredicate{}),
 		factory.RegisterFitPredicate("g-GuestCPUFilter", &predicateguest.CPUPredicate{}),
 		factory.RegisterFitPredicate("h-GuestMemoryFilter", &predicateguest.MemoryPredicate{}),
+		factory.RegisterFitPredicate("h-GuestNumaFilter", &predicateguest.NumaPredicate{}),
 		factory.RegisterFitPredicate("i-GuestStorageFilter", &predicateguest.StoragePredicate{}),
 		factory.RegisterFitPredicate("j-GuestNetworkFilter", &predicates.NetworkPredicate{}),
 		factory.RegisterFitPredicate("k-GuestIsolatedDeviceFilter", &predicates.IsolatedDevicePredicate{}),
@@ -58,5 +60,7 @@ func defaultPriorities() sets.String {
 		factory.RegisterPriority("guest-lowload", &priorityguest.LowLoadPriority{}, 1),
 		factory.RegisterPriority("guest-creating", &priorityguest.CreatingPriority{}, 1),
 		factory.RegisterPriority("guest-capacity", &priorityguest.CapacityPriority{}, 1),
+		factory.RegisterPriority("guest-numa", &priorityguest.NumaPriority{}, 1),
+		factory.RegisterPriority("guest-utilization", &priorityguest.UtilizationPriority{}, 1),
 	)
 }
@@ -46,6 +46,7 @@ type SchedInfo struct {
 	IgnoreFilters         map[string]bool `json:"ignore_filters"`
 	IsSuggestion          bool            `json:"suggestion"`
 	ShowSuggestionDetails bool            `json:"suggestion_details"`
+	Explain               bool            `json:"explain"`
 	Raw                   string
 
 	InstanceGroupsDetail map[string]*models.SGroup
@@ -124,6 +125,11 @@ func FetchSchedInfo(req *http.Request) (*SchedInfo, error) {
 		if groups[i].Enabled.IsFalse() {
 			continue
 		}
+		// affinity groups are scheduled by rule instead of granularity
+		if groups[i].IsAffinityGroup() {
+			data.AffinityRules = append(data.AffinityRules, groups[i].ToAffinityRule())
+			continue
+		}
 		details[groups[i].Id] = &groups[i]
 	}
 	data.InstanceGroupsDetail = details
@@ -293,3 +299,51 @@ type SchedForecastResult struct {
 	NotAllowReasons    []string                 `json:"not_allow_reasons"`
 	FilteredCandidates []FilteredCandidate      `json:"filtered_candidates"`
 }
+
+type PredicateVerdict struct {
+	Predicate string   `json:"predicate"`
+	Fit       bool     `json:"fit"`
+	Reasons   []string `json:"reasons"`
+}
+
+type ExplainedCandidate struct {
+	ID         string             `json:"id"`
+	Name       string             `json:"name"`
+	Fit        bool               `json:"fit"`
+	Capacity   int64              `json:"capacity"`
+	Count      int64              `json:"count"`
+	Predicates []PredicateVerdict `json:"predicates"`
+	// scores of each priority grouped by prefer, avoid and normal
+	Scores map[string]map[string]int `json:"scores"`
+}
+
+type SchedExplainResult struct {
+	CanCreate  bool                 `json:"can_create"`
+	ReqCount   int64                `json:"req_count"`
+	AllowCount int64                `json:"allow_count"`
+	Candidates []ExplainedCandidate `json:"candidates"`
+}
+
+type SimulatedRequest struct {
+	Index           int      `json:"index"`
+	CanCreate       bool     `json:"can_create"`
+	Hosts           []string `json:"hosts"`
+	NotAllowReasons []string `json:"not_allow_reasons"`
+}
+
+type SimulatedHostUsage struct {
+	ID     string         `json:"id"`
+	Name   string         `json:"name"`
+	Count  int            `json:"count"`
+	Cpu    int            `json:"cpu"`
+	Memory int            `json:"memory"`
+	Disk   map[string]int `json:"disk"`
+}
+
+type SchedSimulateResult struct {
+	ReqCount int `json:"req_count"`
+	// AllowCount is the number of requests fit before the first failure
+	AllowCount int                  `json:"allow_count"`
+	Requests   []SimulatedRequest   `json:"requests"`
+	Hosts      []SimulatedHostUsage `json:"hosts"`
+}
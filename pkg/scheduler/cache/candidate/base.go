@@ -163,6 +163,10 @@ func (b baseHostGetter) OvnCapable() bool {
 	return false
 }
 
+func (b baseHostGetter) HostMetrics() *core.HostMetrics {
+	return nil
+}
+
 func (b baseHostGetter) ResourceType() string {
 	return reviseResourceType(b.h.ResourceType)
 }
@@ -212,7 +216,7 @@ func (b baseHostGetter) GetFreeStorageSizeOfType(storageType string, useRsvd boo
 	var actualSize int64
 	for _, s := range b.Storages() {
 		if s.StorageType == storageType {
-			size += int64(float32(s.Capacity) * s.Cmtbound)
+			size += int64(float32(s.Capacity) * s.GetSchedCmtbound())
 			actualSize += s.Capacity - s.ActualCapacityUsed
 		}
 	}
@@ -93,6 +93,10 @@ func (h *hostGetter) GetFreePort(netId string) int {
 	return h.h.GetFreePort(netId)
 }
 
+func (h *hostGetter) HostMetrics() *core.HostMetrics {
+	return h.h.Metrics
+}
+
 func (h *hostGetter) OvnCapable() bool {
 	return len(h.h.OvnVersion) > 0
 }
@@ -127,6 +131,9 @@ type HostDesc struct {
 	IOBoundCount int64    `json:"io_bound_count"`
 	IOLoad       *float64 `json:"io_load"`
 
+	// real utilization from telegraf
+	Metrics *core.HostMetrics `json:"metrics"`
+
 	// server
 	GuestCount         int64 `json:"guest_count"`
 	CreatingGuestCount int64 `json:"creating_guest_count"`
@@ -244,7 +251,8 @@ type HostBuilder struct {
 	//diskStats           []models.StorageCapacity
 	// isolatedDevicesDict map[string][]interface{}
 
-	cpuIOLoads map[string]map[string]float64
+	cpuIOLoads  map[string]map[string]float64
+	hostMetrics map[string]*core.HostMetrics
 
 	schedtags []computemodels.SSchedtag
 	zoneSkus  map[string][]computemodels.SServerSku
@@ -454,6 +462,7 @@ func (b *HostBuilder) init(ids []string) error {
 			b.setGuests(ids, errMessageChannel)
 			b.setIsolatedDevs(ids, errMessageChannel)
 		},
+		func() { b.setHostMetrics() },
 	}
 
 	for _, f := range setFuncs {
@@ -816,6 +825,7 @@ func (b *HostBuilder) buildOne(host *computemodels.SHost) (interface{}, error) {
 		//b.fillResidentGroups,
 		b.fillMetadata,
 		b.fillCPUIOLoads,
+		b.fillHostMetrics,
 	}
 
 	for _, f := range fillFuncs {
@@ -1051,6 +1061,15 @@ func (b *HostBuilder) fillCPUIOLoads(desc *HostDesc, host *computemodels.SHost)
 	return nil
 }
 
+func (b *HostBuilder) setHostMetrics() {
+	b.hostMetrics = hostMetrics.get()
+}
+
+func (b *HostBuilder) fillHostMetrics(desc *HostDesc, host *computemodels.SHost) error {
+	desc.Metrics = b.hostMetrics[host.Id]
+	return nil
+}
+
 func (b *HostBuilder) loadByName(hostID, name string) *float64 {
 	if b.cpuIOLoads == nil {
 		return nil
@@ -0,0 +1,55 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package candidate
+
+import (
+	"context"
+	gosync "sync"
+	"time"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/utils"
+
+	"yunion.io/x/onecloud/pkg/monitor/hostmetrics"
+	"yunion.io/x/onecloud/pkg/scheduler/core"
+	o "yunion.io/x/onecloud/pkg/scheduler/options"
+)
+
+type hostMetricsCache struct {
+	lock      gosync.Mutex
+	metrics   map[string]*core.HostMetrics
+	fetchedAt time.Time
+}
+
+var hostMetrics = &hostMetricsCache{}
+
+// get returns metrics of all hosts, influxdb is queried at most once per HostMetricsCachePeriod
+func (c *hostMetricsCache) get() map[string]*core.HostMetrics {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.metrics != nil && time.Since(c.fetchedAt) < utils.ToDuration(o.GetOptions().HostMetricsCachePeriod) {
+		return c.metrics
+	}
+	// failed fetch also waits a period, old metrics are dropped by staleness check
+	c.fetchedAt = time.Now()
+	metrics, err := hostmetrics.FetchHostMetrics(context.Background(), o.GetOptions().Region, o.GetOptions().HostMetricsWindow)
+	if err != nil {
+		log.Warningf("fetch host metrics: %v", err)
+		return c.metrics
+	}
+	c.metrics = metrics
+	return c.metrics
+}
@@ -407,6 +407,18 @@ type Unit struct {
 	SelectPriorityMap        map[string]SSelectPriority
 	SelectPriorityUpdaterMap map[string]SSelectPriorityUpdater
 	SelectPriorityLock       sync.Mutex
+
+	// candidate id => topology domains that only one selected candidate may occupy
+	exclusiveDomains     map[string][]string
+	exclusiveDomainsLock sync.Mutex
+
+	// candidate id => rule key => topology domains, all selected candidates must
+	// share a domain of each rule key with the first selected one
+	affinityDomains     map[string]map[string][]string
+	affinityDomainsLock sync.Mutex
+
+	// filters that every storage used by the request must pass
+	storageFilters []func(storageId string) error
 }
 
 func NewScheduleUnit(info *api.SchedInfo, schedManager interface{}) *Unit {
@@ -431,6 +443,9 @@ func NewScheduleUnit(info *api.SchedInfo, schedManager interface{}) *Unit {
 
 		SelectPriorityMap:        spmap,
 		SelectPriorityUpdaterMap: spumap,
+
+		exclusiveDomains: make(map[string][]string),
+		affinityDomains:  make(map[string]map[string][]string),
 	}
 	return unit
 }
@@ -485,6 +500,56 @@ func (u *Unit) AllSelectPlugins() []SelectPlugin {
 	return u.selectPlugins
 }
 
+// AppendExclusiveDomains marks domains of candidate id as exclusive, SelectHosts
+// will not select two candidates sharing one of them in the same request
+func (u *Unit) AppendExclusiveDomains(id string, domains []string) {
+	u.exclusiveDomainsLock.Lock()
+	defer u.exclusiveDomainsLock.Unlock()
+
+	u.exclusiveDomains[id] = append(u.exclusiveDomains[id], domains...)
+}
+
+func (u *Unit) GetExclusiveDomains(id string) []string {
+	u.exclusiveDomainsLock.Lock()
+	defer u.exclusiveDomainsLock.Unlock()
+
+	return u.exclusiveDomains[id]
+}
+
+// AppendAffinityDomains marks domains of candidate id for rule key, SelectHosts
+// will only select candidates sharing a domain with the first selected one
+func (u *Unit) AppendAffinityDomains(id string, key string, domains []string) {
+	u.affinityDomainsLock.Lock()
+	defer u.affinityDomainsLock.Unlock()
+
+	if _, ok := u.affinityDomains[id]; !ok {
+		u.affinityDomains[id] = make(map[string][]string)
+	}
+	u.affinityDomains[id][key] = append(u.affinityDomains[id][key], domains...)
+}
+
+func (u *Unit) GetAffinityDomains(id string) map[string][]string {
+	u.affinityDomainsLock.Lock()
+	defer u.affinityDomainsLock.Unlock()
+
+	return u.affinityDomains[id]
+}
+
+// AppendStorageFilter adds a filter of storages, it should be called in PreExecute
+func (u *Unit) AppendStorageFilter(filter func(storageId string) error) {
+	u.storageFilters = append(u.storageFilters, filter)
+}
+
+// FilterStorage returns the reason why the storage can not be used by the request
+func (u *Unit) FilterStorage(storageId string) error {
+	for _, filter := range u.storageFilters {
+		if err := filter(storageId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (u *Unit) GetCapacity(id string) int64 {
 	var (
 		capacityObj *Capacity
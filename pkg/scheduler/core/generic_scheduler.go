@@ -25,6 +25,7 @@ import (
 	"yunion.io/x/log"
 	"yunion.io/x/pkg/util/errors"
 	gp "yunion.io/x/pkg/util/goroutine_pool"
+	"yunion.io/x/pkg/util/sets"
 	utiltrace "yunion.io/x/pkg/util/trace"
 	"yunion.io/x/pkg/util/workqueue"
 
@@ -332,6 +333,8 @@ func SelectHosts(unit *Unit, priorityList HostPriorityList) ([]*SelectedCandidat
 	selectedCandidates := []*SelectedCandidate{}
 
 	plugins := unit.AllSelectPlugins()
+	usedDomains := sets.NewString()
+	pinnedDomains := make(map[string]sets.String)
 
 	sort.Sort(sort.Reverse(priorityList))
 
@@ -348,6 +351,24 @@ completed:
 				selectedItem *SelectedCandidate
 				ok           bool
 			)
+			domains := unit.GetExclusiveDomains(hostID)
+			affinityDomains := unit.GetAffinityDomains(hostID)
+			if _, ok := selectedMap[hostID]; !ok {
+				if usedDomains.HasAny(domains...) {
+					// topology domain already occupied by another selected candidate
+					continue
+				}
+				if !matchPinnedDomains(pinnedDomains, affinityDomains) {
+					// not in the topology domain of the first selected candidate
+					continue
+				}
+			}
+			usedDomains.Insert(domains...)
+			for key, ds := range affinityDomains {
+				if _, ok := pinnedDomains[key]; !ok {
+					pinnedDomains[key] = sets.NewString(ds...)
+				}
+			}
 			if selectedItem, ok = selectedMap[hostID]; !ok {
 				selectedItem = &SelectedCandidate{
 					Count:     0,
@@ -383,6 +404,15 @@ completed:
 	return selectedCandidates, nil
 }
 
+func matchPinnedDomains(pinned map[string]sets.String, domains map[string][]string) bool {
+	for key, ds := range pinned {
+		if !ds.HasAny(domains[key]...) {
+			return false
+		}
+	}
+	return true
+}
+
 func findCandidatesThatFit(unit *Unit, candidates []Candidater, predicates map[string]FitPredicate) ([]Candidater, error) {
 	var filtered []Candidater
 
@@ -533,7 +563,8 @@ func unitFitsOnCandidate(
 			// the configured predicates even after one or more of them fails.
 			// When the flag is set to false, scheduler skips checking the rest
 			// of the predicates after it finds one predicate that failed.
-			if !o.GetOptions().AlwaysCheckAllPredicates {
+			// Explain mode shows verdicts of all predicates as well.
+			if !o.GetOptions().AlwaysCheckAllPredicates && !unit.SchedInfo.Explain {
 				break
 			}
 		}
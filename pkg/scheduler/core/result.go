@@ -30,6 +30,8 @@ type ScheduleResult struct {
 	ForecastResult *api.SchedForecastResult
 	// TestResult is test schedule result
 	TestResult interface{}
+	// ExplainResult is predicate verdicts and priority scores of all candidates
+	ExplainResult *api.SchedExplainResult
 }
 
 type SchedResultItem struct {
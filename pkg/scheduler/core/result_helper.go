@@ -41,6 +41,12 @@ func ResultHelpForTest(result *SchedResultItemList, schedInfo *api.SchedInfo) *S
 	return out
 }
 
+func ResultHelpForExplain(result *SchedResultItemList, _ *api.SchedInfo) *ScheduleResult {
+	out := new(ScheduleResult)
+	out.ExplainResult = transToSchedExplainResult(result)
+	return out
+}
+
 type IResultHelper interface {
 	ResultHelp(result *SchedResultItemList, schedInfo *api.SchedInfo) *ScheduleResult
 }
@@ -173,3 +179,40 @@ func transToSchedForecastResult(result *SchedResultItemList) *api.SchedForecastR
 	}
 	return ret
 }
+
+func transToSchedExplainResult(result *SchedResultItemList) *api.SchedExplainResult {
+	unit := result.Unit
+	ret := &api.SchedExplainResult{
+		ReqCount:   int64(unit.SchedData().Count),
+		Candidates: make([]api.ExplainedCandidate, 0, len(result.Data)),
+	}
+
+	verdicts := make(map[string][]api.PredicateVerdict)
+	for _, l := range unit.LogManager.Logs {
+		verdict := api.PredicateVerdict{
+			Predicate: l.Action,
+			Fit:       !l.IsFailed,
+		}
+		for _, msg := range l.Messages {
+			verdict.Reasons = append(verdict.Reasons, msg.Info)
+		}
+		verdicts[l.Candidate] = append(verdicts[l.Candidate], verdict)
+	}
+	for _, item := range result.Data {
+		candidate := api.ExplainedCandidate{
+			ID:         item.ID,
+			Name:       item.Name,
+			Fit:        item.Capacity > 0,
+			Capacity:   item.Capacity,
+			Count:      item.Count,
+			Predicates: verdicts[fmt.Sprintf("%s:%s", item.Name, item.ID)],
+		}
+		if candidate.Fit {
+			candidate.Scores = item.Score.Details()
+		}
+		ret.AllowCount += item.Count
+		ret.Candidates = append(ret.Candidates, candidate)
+	}
+	ret.CanCreate = ret.AllowCount >= ret.ReqCount
+	return ret
+}
@@ -116,6 +116,22 @@ func NormalLess(b1, b2 *ScoreBucket) bool {
 	return b1.normalScore.Total() < b2.normalScore.Total()
 }
 
+// Details returns the scores of each priority grouped by prefer, avoid and normal
+func (b *ScoreBucket) Details() map[string]map[string]int {
+	ret := make(map[string]map[string]int)
+	for kind, ss := range map[string]scores{
+		"prefer": b.preferScore,
+		"avoid":  b.avoidScore,
+		"normal": b.normalScore,
+	} {
+		ret[kind] = make(map[string]int, len(ss))
+		for name, val := range ss {
+			ret[kind][name] = val
+		}
+	}
+	return ret
+}
+
 func (b *ScoreBucket) debugString(kind string, vals map[string]int) string {
 	return fmt.Sprintf("%s: %v", kind, vals)
 }
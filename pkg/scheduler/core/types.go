@@ -26,6 +26,7 @@ import (
 	"yunion.io/x/onecloud/pkg/cloudcommon/types"
 	"yunion.io/x/onecloud/pkg/compute/baremetal"
 	computemodels "yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/monitor/hostmetrics"
 	"yunion.io/x/onecloud/pkg/scheduler/api"
 	"yunion.io/x/onecloud/pkg/scheduler/core/score"
 	"yunion.io/x/onecloud/pkg/scheduler/data_manager/sku"
@@ -119,6 +120,9 @@ type CandidatePropertyGetter interface {
 	UnusedGpuDevices() []*IsolatedDeviceDesc
 	GetIsolatedDevices() []*IsolatedDeviceDesc
 
+	// HostMetrics returns the real utilization collected by telegraf, nil if unknown
+	HostMetrics() *HostMetrics
+
 	db.IResource
 }
 
@@ -241,6 +245,9 @@ type IsolatedDeviceDesc struct {
 	VendorDeviceID string
 }
 
+// HostMetrics is the recent average utilization of a host
+type HostMetrics = hostmetrics.HostMetrics
+
 func (i *IsolatedDeviceDesc) VendorID() string {
 	return strings.Split(i.VendorDeviceID, ":")[0]
 }
@@ -67,6 +67,10 @@ func schedulerActionHandler(c *gin.Context) {
 		doSchedulerTest(c)
 	case "forecast":
 		doSchedulerForecast(c)
+	case "explain":
+		doSchedulerExplain(c)
+	case "simulate":
+		doSchedulerSimulate(c)
 	case "candidate-list":
 		doCandidateList(c)
 	case "cleanup":
@@ -145,6 +149,50 @@ func doSchedulerForecast(c *gin.Context) {
 	c.JSON(http.StatusOK, result.ForecastResult)
 }
 
+func doSchedulerExplain(c *gin.Context) {
+	if !schedman.IsReady() {
+		c.AbortWithError(http.StatusBadRequest, fmt.Errorf("Global scheduler not init"))
+		return
+	}
+
+	schedInfo, err := api.FetchSchedInfo(c.Request)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	schedInfo.IsSuggestion = true
+	schedInfo.ShowSuggestionDetails = true
+	schedInfo.SuggestionAll = true
+	schedInfo.Explain = true
+	result, err := schedman.Schedule(schedInfo)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	c.JSON(http.StatusOK, result.ExplainResult)
+}
+
+func doSchedulerSimulate(c *gin.Context) {
+	if !schedman.IsReady() {
+		c.AbortWithError(http.StatusBadRequest, fmt.Errorf("Global scheduler not init"))
+		return
+	}
+
+	schedInfo, err := api.FetchSchedInfo(c.Request)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := schedman.Simulate(schedInfo)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
 func doCandidateList(c *gin.Context) {
 	args, err := api.NewCandidateListArgs(c.Request.Body)
 	if err != nil {
@@ -130,6 +130,13 @@ type Scheduler interface {
 	//DirtySelectedCandidates([]*core.SelectedCandidate)
 }
 
+func newScheduler(manager *SchedulerManager, info *api.SchedInfo) (Scheduler, error) {
+	if info.Hypervisor == api.SchedTypeBaremetal {
+		return newBaremetalScheduler(manager, info)
+	}
+	return newGuestScheduler(manager, info)
+}
+
 type BaseScheduler struct {
 	schedManager *SchedulerManager
 	schedInfo    *api.SchedInfo
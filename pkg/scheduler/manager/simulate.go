@@ -0,0 +1,192 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"sort"
+
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/scheduler/api"
+	"yunion.io/x/onecloud/pkg/scheduler/core"
+	schedmodels "yunion.io/x/onecloud/pkg/scheduler/models"
+)
+
+const MaxSimulateCount = 1000
+
+// simulatedGetter takes away the resources used by previous simulated
+// requests, the real pending usage of the host is left untouched
+type simulatedGetter struct {
+	core.CandidatePropertyGetter
+	usage *schedmodels.SPendingUsage
+}
+
+func (g *simulatedGetter) FreeCPUCount(useRsvd bool) int64 {
+	return g.CandidatePropertyGetter.FreeCPUCount(useRsvd) - int64(g.usage.Cpu)
+}
+
+func (g *simulatedGetter) FreeMemorySize(useRsvd bool) int64 {
+	return g.CandidatePropertyGetter.FreeMemorySize(useRsvd) - int64(g.usage.Memory)
+}
+
+func (g *simulatedGetter) GetFreeStorageSizeOfType(storageType string, useRsvd bool) (int64, int64) {
+	total, actual := g.CandidatePropertyGetter.GetFreeStorageSizeOfType(storageType, useRsvd)
+	used := int64(g.usage.DiskUsage.Get(storageType))
+	return total - used, actual - used
+}
+
+func (g *simulatedGetter) GetFreePort(netId string) int {
+	return g.CandidatePropertyGetter.GetFreePort(netId) - g.usage.NetUsage.Get(netId)
+}
+
+type simulatedCandidate struct {
+	core.Candidater
+	getter *simulatedGetter
+	count  int
+}
+
+func (c *simulatedCandidate) Getter() core.CandidatePropertyGetter {
+	return c.getter
+}
+
+func (c *simulatedCandidate) GetGuestCount() int64 {
+	return c.Candidater.GetGuestCount() + int64(c.count)
+}
+
+// Simulate runs hypothetical requests one by one against a snapshot of the
+// candidates, nothing is reserved and the result is only kept in memory
+func Simulate(info *api.SchedInfo) (*api.SchedSimulateResult, error) {
+	count := info.SimulateCount
+	if count <= 0 {
+		count = 1
+	}
+	if count > MaxSimulateCount {
+		return nil, errors.Errorf("simulate count %d exceeds %d", count, MaxSimulateCount)
+	}
+	info.IsSuggestion = true
+	info.ShowSuggestionDetails = true
+	info.SuggestionAll = true
+	info.Explain = false
+
+	scheduler, err := newScheduler(schedManager, info)
+	if err != nil {
+		return nil, errors.Wrap(err, "newScheduler")
+	}
+	genericScheduler, err := core.NewGenericScheduler(scheduler.(core.Scheduler))
+	if err != nil {
+		return nil, errors.Wrap(err, "NewGenericScheduler")
+	}
+	origin, err := scheduler.Candidates()
+	if err != nil {
+		return nil, errors.Wrap(err, "Candidates")
+	}
+	helper := core.SResultHelperFunc(core.ResultHelpForForcast)
+	return simulateRequests(info, count, origin, func(candidates []core.Candidater) (*api.SchedForecastResult, error) {
+		// every request is scheduled by a fresh unit, results of the former
+		// requests are only kept in the simulated candidates
+		result, err := genericScheduler.Schedule(core.NewScheduleUnit(info, schedManager), candidates, helper)
+		if err != nil {
+			return nil, err
+		}
+		return result.ForecastResult, nil
+	})
+}
+
+// simulateRequests runs the request count times, resources allocated by
+// each request are taken away from the candidates before the next one
+func simulateRequests(
+	info *api.SchedInfo, count int, origin []core.Candidater,
+	schedule func(candidates []core.Candidater) (*api.SchedForecastResult, error),
+) (*api.SchedSimulateResult, error) {
+	candidates := make([]core.Candidater, len(origin))
+	simulated := make(map[string]*simulatedCandidate)
+	for i := range origin {
+		c := &simulatedCandidate{
+			Candidater: origin[i],
+			getter: &simulatedGetter{
+				CandidatePropertyGetter: origin[i].Getter(),
+				usage:                   schedmodels.NewPendingUsageBySchedInfo(origin[i].IndexKey(), nil),
+			},
+		}
+		candidates[i] = c
+		simulated[c.IndexKey()] = c
+	}
+
+	ret := &api.SchedSimulateResult{
+		ReqCount: count,
+		Requests: make([]api.SimulatedRequest, 0),
+	}
+	for i := 0; i < count; i++ {
+		forecast, err := schedule(candidates)
+		if err != nil {
+			return nil, errors.Wrapf(err, "simulate request %d", i)
+		}
+		req := api.SimulatedRequest{
+			Index:           i,
+			CanCreate:       forecast.CanCreate,
+			Hosts:           make([]string, 0),
+			NotAllowReasons: forecast.NotAllowReasons,
+		}
+		if forecast.CanCreate {
+			// one request of count guests may place several guests on the same host
+			hostCounts := make(map[string]int)
+			hostIds := make([]string, 0)
+			for _, candi := range forecast.Candidates {
+				if _, ok := simulated[candi.HostId]; !ok {
+					continue
+				}
+				if hostCounts[candi.HostId] == 0 {
+					hostIds = append(hostIds, candi.HostId)
+				}
+				hostCounts[candi.HostId] += 1
+				req.Hosts = append(req.Hosts, candi.Name)
+			}
+			for _, hostId := range hostIds {
+				c := simulated[hostId]
+				for j := 0; j < hostCounts[hostId]; j++ {
+					c.getter.usage.Add(schedmodels.NewPendingUsageBySchedInfo(hostId, info))
+				}
+				c.count += hostCounts[hostId]
+			}
+		}
+		ret.Requests = append(ret.Requests, req)
+		if !forecast.CanCreate {
+			// nothing changed, the rest requests fail as well
+			break
+		}
+		ret.AllowCount += 1
+	}
+
+	for _, c := range simulated {
+		if c.count == 0 {
+			continue
+		}
+		ret.Hosts = append(ret.Hosts, api.SimulatedHostUsage{
+			ID:     c.IndexKey(),
+			Name:   c.getter.Name(),
+			Count:  c.count,
+			Cpu:    c.getter.usage.Cpu,
+			Memory: c.getter.usage.Memory,
+			Disk:   c.getter.usage.DiskUsage.ToMap(),
+		})
+	}
+	sort.Slice(ret.Hosts, func(i, j int) bool {
+		if ret.Hosts[i].Count != ret.Hosts[j].Count {
+			return ret.Hosts[i].Count > ret.Hosts[j].Count
+		}
+		return ret.Hosts[i].Name < ret.Hosts[j].Name
+	})
+	return ret, nil
+}
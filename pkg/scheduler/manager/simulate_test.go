@@ -0,0 +1,130 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"testing"
+
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
+	schedapi "yunion.io/x/onecloud/pkg/apis/scheduler"
+	"yunion.io/x/onecloud/pkg/scheduler/api"
+	"yunion.io/x/onecloud/pkg/scheduler/core"
+)
+
+type fakeSimulateGetter struct {
+	core.CandidatePropertyGetter
+	id      string
+	freeCpu int64
+}
+
+func (g *fakeSimulateGetter) Name() string {
+	return g.id
+}
+
+func (g *fakeSimulateGetter) FreeCPUCount(useRsvd bool) int64 {
+	return g.freeCpu
+}
+
+type fakeSimulateCandidate struct {
+	core.Candidater
+	getter *fakeSimulateGetter
+}
+
+func (c *fakeSimulateCandidate) IndexKey() string {
+	return c.getter.id
+}
+
+func (c *fakeSimulateCandidate) Getter() core.CandidatePropertyGetter {
+	return c.getter
+}
+
+func (c *fakeSimulateCandidate) GetGuestCount() int64 {
+	return 0
+}
+
+func newFakeSimulateCandidate(id string, freeCpu int64) core.Candidater {
+	return &fakeSimulateCandidate{getter: &fakeSimulateGetter{id: id, freeCpu: freeCpu}}
+}
+
+// scheduleMostFreeCpu places all guests of a request on the host having the
+// most free cpus, like the scheduler it only sees the candidates passed in
+func scheduleMostFreeCpu(info *api.SchedInfo) func(candidates []core.Candidater) (*api.SchedForecastResult, error) {
+	return func(candidates []core.Candidater) (*api.SchedForecastResult, error) {
+		ret := &api.SchedForecastResult{ReqCount: int64(info.Count)}
+		var best core.Candidater
+		for _, c := range candidates {
+			if best == nil || c.Getter().FreeCPUCount(false) > best.Getter().FreeCPUCount(false) {
+				best = c
+			}
+		}
+		if best == nil || best.Getter().FreeCPUCount(false) < int64(info.Ncpu*info.Count) {
+			ret.NotAllowReasons = []string{"Out of resource"}
+			return ret, nil
+		}
+		for i := 0; i < info.Count; i++ {
+			ret.Candidates = append(ret.Candidates, &schedapi.CandidateResource{
+				HostId: best.IndexKey(),
+				Name:   best.Getter().Name(),
+			})
+		}
+		ret.AllowCount = int64(info.Count)
+		ret.CanCreate = true
+		return ret, nil
+	}
+}
+
+func TestSimulateRequests(t *testing.T) {
+	info := &api.SchedInfo{
+		ScheduleInput: &schedapi.ScheduleInput{
+			ServerConfig: schedapi.ServerConfig{
+				ServerConfigs: &computeapi.ServerConfigs{Count: 2},
+				Ncpu:          2,
+				Memory:        1024,
+			},
+		},
+	}
+	candidates := []core.Candidater{
+		newFakeSimulateCandidate("host1", 8),
+		newFakeSimulateCandidate("host2", 6),
+	}
+	ret, err := simulateRequests(info, 5, candidates, scheduleMostFreeCpu(info))
+	if err != nil {
+		t.Fatalf("simulate: %v", err)
+	}
+	// host1 8 -> 4 -> 0, host2 6 -> 2, the fourth request fails
+	if ret.AllowCount != 3 {
+		t.Errorf("want 3 requests allowed, got %d", ret.AllowCount)
+	}
+	if len(ret.Requests) != 4 || ret.Requests[3].CanCreate {
+		t.Errorf("want simulation stops at the first failed request, got %#v", ret.Requests)
+	}
+	wantHosts := [][]string{{"host1", "host1"}, {"host2", "host2"}, {"host1", "host1"}}
+	for i, want := range wantHosts {
+		got := ret.Requests[i].Hosts
+		if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+			t.Errorf("request %d: want hosts %v, got %v", i, want, got)
+		}
+	}
+	usages := make(map[string]api.SimulatedHostUsage)
+	for _, h := range ret.Hosts {
+		usages[h.ID] = h
+	}
+	if h := usages["host1"]; h.Count != 4 || h.Cpu != 8 || h.Memory != 4096 {
+		t.Errorf("want host1 holds 4 guests of 8 cpus and 4096M memory, got %#v", h)
+	}
+	if h := usages["host2"]; h.Count != 2 || h.Cpu != 4 || h.Memory != 2048 {
+		t.Errorf("want host2 holds 2 guests of 4 cpus and 2048M memory, got %#v", h)
+	}
+}
@@ -117,6 +117,9 @@ func GenerateResultHelper(schedInfo *api.SchedInfo) core.IResultHelper {
 	if !schedInfo.IsSuggestion {
 		return core.SResultHelperFunc(core.ResultHelp)
 	}
+	if schedInfo.Explain {
+		return core.SResultHelperFunc(core.ResultHelpForExplain)
+	}
 	if schedInfo.ShowSuggestionDetails && schedInfo.SuggestionAll {
 		return core.SResultHelperFunc(core.ResultHelpForForcast)
 	}
@@ -270,18 +273,9 @@ func (tm *TaskManager) Run() {
 // split into multiple scheduling tasks, added to the scheduling
 // task manager.
 func (tm *TaskManager) AddTask(schedulerManager *SchedulerManager, schedInfo *api.SchedInfo) (*Task, error) {
-	var (
-		scheduler Scheduler
-		err       error
-	)
-
 	task := NewTask(schedulerManager, schedInfo)
 	// Split into multiple scheduling tasks by host specification type.
-	if schedInfo.Hypervisor == api.SchedTypeBaremetal {
-		scheduler, err = newBaremetalScheduler(schedulerManager, schedInfo)
-	} else {
-		scheduler, err = newGuestScheduler(schedulerManager, schedInfo)
-	}
+	scheduler, err := newScheduler(schedulerManager, schedInfo)
 	if err != nil {
 		return nil, err
 	}
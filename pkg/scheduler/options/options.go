@@ -36,6 +36,7 @@ type SchedOptions struct {
 	IgnoreFakeDeletedGuests bool `help:"Ignore fake deleted guests when build host memory and cpu size" default:"false"`
 
 	AlwaysCheckAllPredicates    bool   `help:"Excute all predicates when scheduling" default:"false"`
+	NumaFitStrict               bool   `help:"Filter out hosts that no numa node could hold the whole guest" default:"false"`
 	DisableBaremetalPredicates  bool   `help:"Switch to trigger baremetal related predicates" default:"false"`
 	SchedulerTestLimit          int    `help:"Scheduler test items' limitations" default:"100"`
 	SchedulerHistoryLimit       int    `help:"Scheduler history items' limitations" default:"1000"`
@@ -89,6 +90,16 @@ type SchedOptions struct {
 	WireDBCachePeriod string `help:"Wire database cache period" default:"5m"`
 
 	SkuRefreshInterval string `help:"Server SKU refresh interval" default:"12h"`
+
+	// host utilization options
+	HostMetricsCachePeriod string `help:"Host utilization metrics fetched from influxdb cache period" default:"1m"`
+	HostMetricsWindow      string `help:"Host utilization metrics average window" default:"5m"`
+	HostMetricsStaleAfter  string `help:"Ignore host utilization metrics not reported in this duration" default:"10m"`
+
+	UtilizationCPUWeight    float64 `help:"Weight of cpu usage in host utilization priority" default:"1.0"`
+	UtilizationMemWeight    float64 `help:"Weight of memory usage in host utilization priority" default:"1.0"`
+	UtilizationDiskIOWeight float64 `help:"Weight of disk io in host utilization priority" default:"0.5"`
+	UtilizationNetWeight    float64 `help:"Weight of network traffic in host utilization priority" default:"0.5"`
 }
 
 var (
@@ -263,6 +263,20 @@ func (mr *MockCandidatePropertyGetterMockRecorder) Host() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Host", reflect.TypeOf((*MockCandidatePropertyGetter)(nil).Host))
 }
 
+// HostMetrics mocks base method
+func (m *MockCandidatePropertyGetter) HostMetrics() *core.HostMetrics {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HostMetrics")
+	ret0, _ := ret[0].(*core.HostMetrics)
+	return ret0
+}
+
+// HostMetrics indicates an expected call of HostMetrics
+func (mr *MockCandidatePropertyGetterMockRecorder) HostMetrics() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HostMetrics", reflect.TypeOf((*MockCandidatePropertyGetter)(nil).HostMetrics))
+}
+
 // HostSchedtags mocks base method
 func (m *MockCandidatePropertyGetter) HostSchedtags() []models.SSchedtag {
 	m.ctrl.T.Helper()
@@ -0,0 +1,291 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dhcp6
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+type MessageType byte
+
+const (
+	Solicit            MessageType = 1
+	Advertise          MessageType = 2
+	Request            MessageType = 3
+	Confirm            MessageType = 4
+	Renew              MessageType = 5
+	Rebind             MessageType = 6
+	Reply              MessageType = 7
+	Release            MessageType = 8
+	Decline            MessageType = 9
+	InformationRequest MessageType = 11
+)
+
+type OptionCode uint16
+
+const (
+	OptionClientID    OptionCode = 1
+	OptionServerID    OptionCode = 2
+	OptionIANA        OptionCode = 3
+	OptionIAAddr      OptionCode = 5
+	OptionStatusCode  OptionCode = 13
+	OptionRapidCommit OptionCode = 14
+	OptionDNSServers  OptionCode = 23
+	OptionDomainList  OptionCode = 24
+)
+
+const (
+	StatusSuccess      uint16 = 0
+	StatusNoAddrsAvail uint16 = 2
+	StatusNoBinding    uint16 = 3
+	StatusNotOnLink    uint16 = 4
+)
+
+const (
+	duidTypeLLT uint16 = 1
+	duidTypeLL  uint16 = 3
+
+	hwTypeEthernet uint16 = 1
+)
+
+type Option struct {
+	Code OptionCode
+	Data []byte
+}
+
+// Message is a client/server DHCPv6 message, relay messages are not supported
+type Message struct {
+	Type          MessageType
+	TransactionID [3]byte
+	Options       []Option
+}
+
+func ParseMessage(b []byte) (*Message, error) {
+	if len(b) < 4 {
+		return nil, fmt.Errorf("dhcp6 message too short: %d", len(b))
+	}
+	msg := &Message{Type: MessageType(b[0])}
+	copy(msg.TransactionID[:], b[1:4])
+	opts, err := parseOptions(b[4:])
+	if err != nil {
+		return nil, err
+	}
+	msg.Options = opts
+	return msg, nil
+}
+
+func parseOptions(b []byte) ([]Option, error) {
+	opts := make([]Option, 0)
+	for len(b) > 0 {
+		if len(b) < 4 {
+			return nil, fmt.Errorf("truncated dhcp6 option header")
+		}
+		code := OptionCode(binary.BigEndian.Uint16(b[0:2]))
+		length := int(binary.BigEndian.Uint16(b[2:4]))
+		if len(b) < 4+length {
+			return nil, fmt.Errorf("truncated dhcp6 option %d", code)
+		}
+		opts = append(opts, Option{Code: code, Data: b[4 : 4+length]})
+		b = b[4+length:]
+	}
+	return opts, nil
+}
+
+func marshalOptions(opts []Option) []byte {
+	b := make([]byte, 0)
+	for _, opt := range opts {
+		hdr := make([]byte, 4)
+		binary.BigEndian.PutUint16(hdr[0:2], uint16(opt.Code))
+		binary.BigEndian.PutUint16(hdr[2:4], uint16(len(opt.Data)))
+		b = append(b, hdr...)
+		b = append(b, opt.Data...)
+	}
+	return b
+}
+
+func (msg *Message) Marshal() []byte {
+	b := []byte{byte(msg.Type), msg.TransactionID[0], msg.TransactionID[1], msg.TransactionID[2]}
+	return append(b, marshalOptions(msg.Options)...)
+}
+
+func (msg *Message) GetOption(code OptionCode) []byte {
+	for _, opt := range msg.Options {
+		if opt.Code == code {
+			return opt.Data
+		}
+	}
+	return nil
+}
+
+func (msg *Message) HasOption(code OptionCode) bool {
+	for _, opt := range msg.Options {
+		if opt.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+func (msg *Message) AddOption(code OptionCode, data []byte) {
+	msg.Options = append(msg.Options, Option{Code: code, Data: data})
+}
+
+func (msg *Message) ClientID() []byte {
+	return msg.GetOption(OptionClientID)
+}
+
+// IAID returns the identity association id of the first IA_NA option
+func (msg *Message) IAID() ([]byte, bool) {
+	iana := msg.GetOption(OptionIANA)
+	if len(iana) < 12 {
+		return nil, false
+	}
+	return iana[0:4], true
+}
+
+// MacFromDUID recovers the link-layer address from DUID-LLT and DUID-LL
+func MacFromDUID(duid []byte) (net.HardwareAddr, bool) {
+	if len(duid) < 4 {
+		return nil, false
+	}
+	if binary.BigEndian.Uint16(duid[2:4]) != hwTypeEthernet {
+		return nil, false
+	}
+	var hw []byte
+	switch binary.BigEndian.Uint16(duid[0:2]) {
+	case duidTypeLLT:
+		hw = duid[8:]
+	case duidTypeLL:
+		hw = duid[4:]
+	default:
+		return nil, false
+	}
+	if len(hw) != 6 {
+		return nil, false
+	}
+	return net.HardwareAddr(hw), true
+}
+
+// MakeDUIDLL returns the DUID-LL of an ethernet address
+func MakeDUIDLL(mac net.HardwareAddr) []byte {
+	duid := make([]byte, 4, 4+len(mac))
+	binary.BigEndian.PutUint16(duid[0:2], duidTypeLL)
+	binary.BigEndian.PutUint16(duid[2:4], hwTypeEthernet)
+	return append(duid, mac...)
+}
+
+type ResponseConfig struct {
+	ClientIP   net.IP
+	DNSServers []net.IP
+	Domain     string
+
+	PreferredLifetime time.Duration
+	ValidLifetime     time.Duration
+}
+
+func seconds(d time.Duration) uint32 {
+	return uint32(d / time.Second)
+}
+
+func makeStatusCode(code uint16, msg string) []byte {
+	b := make([]byte, 2, 2+len(msg))
+	binary.BigEndian.PutUint16(b, code)
+	return append(b, msg...)
+}
+
+func makeIANA(iaid []byte, conf *ResponseConfig) []byte {
+	b := make([]byte, 12)
+	copy(b[0:4], iaid)
+	// T1 and T2 at 0.5 and 0.8 of the preferred lifetime
+	binary.BigEndian.PutUint32(b[4:8], seconds(conf.PreferredLifetime)/2)
+	binary.BigEndian.PutUint32(b[8:12], seconds(conf.PreferredLifetime)/5*4)
+	addr := make([]byte, 24)
+	copy(addr[0:16], conf.ClientIP.To16())
+	binary.BigEndian.PutUint32(addr[16:20], seconds(conf.PreferredLifetime))
+	binary.BigEndian.PutUint32(addr[20:24], seconds(conf.ValidLifetime))
+	return append(b, marshalOptions([]Option{{Code: OptionIAAddr, Data: addr}})...)
+}
+
+func makeDomainList(domain string) []byte {
+	b := make([]byte, 0)
+	for _, label := range strings.Split(strings.Trim(domain, "."), ".") {
+		if len(label) == 0 || len(label) > 63 {
+			continue
+		}
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	return append(b, 0)
+}
+
+// MakeReplyMessage answers a client message with an address binding as
+// described by conf, a nil message is returned for messages that need no reply
+func MakeReplyMessage(req *Message, serverId []byte, conf *ResponseConfig) (*Message, error) {
+	resp := &Message{
+		Type:          Reply,
+		TransactionID: req.TransactionID,
+	}
+	switch req.Type {
+	case Solicit:
+		if !req.HasOption(OptionRapidCommit) {
+			resp.Type = Advertise
+		}
+	case Request, Renew, Rebind, Confirm, Release, Decline, InformationRequest:
+	default:
+		return nil, nil
+	}
+	clientId := req.ClientID()
+	if len(clientId) == 0 && req.Type != InformationRequest {
+		return nil, fmt.Errorf("dhcp6 message %d without client id", req.Type)
+	}
+	if srvId := req.GetOption(OptionServerID); len(srvId) > 0 && string(srvId) != string(serverId) {
+		// not for us
+		return nil, nil
+	}
+	if len(clientId) > 0 {
+		resp.AddOption(OptionClientID, clientId)
+	}
+	resp.AddOption(OptionServerID, serverId)
+
+	switch req.Type {
+	case Solicit, Request, Renew, Rebind:
+		if iaid, ok := req.IAID(); ok {
+			resp.AddOption(OptionIANA, makeIANA(iaid, conf))
+		}
+		if req.Type == Solicit && resp.Type == Reply {
+			resp.AddOption(OptionRapidCommit, []byte{})
+		}
+	case Confirm:
+		resp.AddOption(OptionStatusCode, makeStatusCode(StatusSuccess, "all addresses on link"))
+	case Release, Decline:
+		resp.AddOption(OptionStatusCode, makeStatusCode(StatusSuccess, ""))
+		return resp, nil
+	}
+	if len(conf.DNSServers) > 0 {
+		dns := make([]byte, 0, 16*len(conf.DNSServers))
+		for _, srv := range conf.DNSServers {
+			dns = append(dns, srv.To16()...)
+		}
+		resp.AddOption(OptionDNSServers, dns)
+	}
+	if len(conf.Domain) > 0 {
+		resp.AddOption(OptionDomainList, makeDomainList(conf.Domain))
+	}
+	return resp, nil
+}
@@ -0,0 +1,122 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dhcp6
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestMakeReplyMessage(t *testing.T) {
+	mac, _ := net.ParseMAC("00:22:43:01:02:03")
+	serverId := MakeDUIDLL(net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55})
+	// DUID-LLT of the client
+	clientId := []byte{0, 1, 0, 1, 0x27, 0x10, 0x00, 0x01}
+	clientId = append(clientId, mac...)
+	iana := make([]byte, 12)
+	copy(iana, []byte{0xde, 0xad, 0xbe, 0xef})
+
+	req := &Message{Type: Solicit, TransactionID: [3]byte{1, 2, 3}}
+	req.AddOption(OptionClientID, clientId)
+	req.AddOption(OptionIANA, iana)
+	parsed, err := ParseMessage(req.Marshal())
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got, ok := MacFromDUID(parsed.ClientID()); !ok || got.String() != mac.String() {
+		t.Fatalf("mac from duid: want %s, got %s", mac, got)
+	}
+
+	conf := &ResponseConfig{
+		ClientIP:          net.ParseIP("fd00:a::10"),
+		DNSServers:        []net.IP{net.ParseIP("fd00:a::2")},
+		Domain:            "cloud.local",
+		PreferredLifetime: time.Hour,
+		ValidLifetime:     2 * time.Hour,
+	}
+	resp, err := MakeReplyMessage(parsed, serverId, conf)
+	if err != nil {
+		t.Fatalf("reply: %v", err)
+	}
+	if resp.Type != Advertise || resp.TransactionID != req.TransactionID {
+		t.Fatalf("unexpected reply type %d, xid %v", resp.Type, resp.TransactionID)
+	}
+	resp, _ = ParseMessage(resp.Marshal())
+	if !bytes.Equal(resp.GetOption(OptionServerID), serverId) {
+		t.Errorf("server id mismatch")
+	}
+	ia := resp.GetOption(OptionIANA)
+	if len(ia) != 12+4+24 || !bytes.Equal(ia[0:4], iana[0:4]) {
+		t.Fatalf("bad IA_NA %x", ia)
+	}
+	if t1 := binary.BigEndian.Uint32(ia[4:8]); t1 != 1800 {
+		t.Errorf("want T1 1800, got %d", t1)
+	}
+	if ip := net.IP(ia[16:32]); !ip.Equal(conf.ClientIP) {
+		t.Errorf("want address %s, got %s", conf.ClientIP, ip)
+	}
+	if dns := net.IP(resp.GetOption(OptionDNSServers)); !dns.Equal(conf.DNSServers[0]) {
+		t.Errorf("want dns %s, got %s", conf.DNSServers[0], dns)
+	}
+	if domain := resp.GetOption(OptionDomainList); !bytes.Equal(domain, []byte("\x05cloud\x05local\x00")) {
+		t.Errorf("bad domain list %q", domain)
+	}
+
+	req.AddOption(OptionRapidCommit, nil)
+	resp, _ = MakeReplyMessage(req, serverId, conf)
+	if resp.Type != Reply || !resp.HasOption(OptionRapidCommit) {
+		t.Errorf("rapid commit solicit should be replied")
+	}
+
+	req = &Message{Type: Request}
+	req.AddOption(OptionClientID, clientId)
+	req.AddOption(OptionServerID, MakeDUIDLL(mac))
+	resp, _ = MakeReplyMessage(req, serverId, conf)
+	if resp != nil {
+		t.Errorf("request for other server should be ignored")
+	}
+}
+
+func TestRouterAdvertisement(t *testing.T) {
+	mac, _ := net.ParseMAC("00:22:43:01:02:03")
+	rs := []byte{icmpTypeRouterSolicitation, 0, 0, 0, 0, 0, 0, 0, ndOptSourceLinkLayerAddr, 1}
+	rs = append(rs, mac...)
+	got, err := ParseRouterSolicitation(rs)
+	if err != nil || got.String() != mac.String() {
+		t.Fatalf("parse rs: want %s, got %s, %v", mac, got, err)
+	}
+
+	ra := &RouterAdvertisement{
+		Managed:           true,
+		Other:             true,
+		Prefix:            net.ParseIP("fd00:a::1"),
+		PrefixLen:         64,
+		ValidLifetime:     time.Hour,
+		PreferredLifetime: time.Hour,
+	}
+	b := ra.Marshal()
+	if len(b) != 16+32 || b[0] != icmpTypeRouterAdvertisement || b[5] != raFlagManaged|raFlagOther {
+		t.Fatalf("bad ra header %x", b)
+	}
+	if prefix := net.IP(b[32:48]); !prefix.Equal(net.ParseIP("fd00:a::")) {
+		t.Errorf("bad prefix %s", prefix)
+	}
+	if b[19]&prefixFlagAutonomous != 0 {
+		t.Errorf("autonomous flag should not be set")
+	}
+}
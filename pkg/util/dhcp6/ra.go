@@ -0,0 +1,137 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dhcp6
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	icmpTypeRouterSolicitation  = 133
+	icmpTypeRouterAdvertisement = 134
+
+	ndOptSourceLinkLayerAddr = 1
+	ndOptPrefixInformation   = 3
+	ndOptMTU                 = 5
+	ndOptRDNSS               = 25
+
+	raFlagManaged = 0x80
+	raFlagOther   = 0x40
+
+	prefixFlagOnLink     = 0x80
+	prefixFlagAutonomous = 0x40
+
+	defaultCurHopLimit = 64
+)
+
+// ParseRouterSolicitation returns the source link-layer address carried in
+// a router solicitation message, if any
+func ParseRouterSolicitation(b []byte) (net.HardwareAddr, error) {
+	if len(b) < 8 || b[0] != icmpTypeRouterSolicitation {
+		return nil, fmt.Errorf("not a router solicitation")
+	}
+	opts := b[8:]
+	for len(opts) >= 8 {
+		length := int(opts[1]) * 8
+		if length == 0 || len(opts) < length {
+			return nil, fmt.Errorf("truncated nd option %d", opts[0])
+		}
+		if opts[0] == ndOptSourceLinkLayerAddr && length == 8 {
+			return net.HardwareAddr(opts[2:8]), nil
+		}
+		opts = opts[length:]
+	}
+	return nil, nil
+}
+
+type RouterAdvertisement struct {
+	// Managed tells hosts to get addresses by DHCPv6, Other tells hosts to
+	// get other configurations, e.g. dns, by DHCPv6
+	Managed bool
+	Other   bool
+
+	// RouterLifetime is 0 when this is not a default router
+	RouterLifetime time.Duration
+
+	Prefix     net.IP
+	PrefixLen  int
+	Autonomous bool
+
+	ValidLifetime     time.Duration
+	PreferredLifetime time.Duration
+
+	MTU        int
+	DNSServers []net.IP
+	SourceMac  net.HardwareAddr
+}
+
+// Marshal encodes the ICMPv6 message with a zero checksum, which is filled
+// by the kernel for raw ICMPv6 sockets
+func (ra *RouterAdvertisement) Marshal() []byte {
+	b := make([]byte, 16)
+	b[0] = icmpTypeRouterAdvertisement
+	b[4] = defaultCurHopLimit
+	if ra.Managed {
+		b[5] |= raFlagManaged
+	}
+	if ra.Other {
+		b[5] |= raFlagOther
+	}
+	binary.BigEndian.PutUint16(b[6:8], uint16(seconds(ra.RouterLifetime)))
+
+	if len(ra.SourceMac) == 6 {
+		opt := make([]byte, 8)
+		opt[0] = ndOptSourceLinkLayerAddr
+		opt[1] = 1
+		copy(opt[2:], ra.SourceMac)
+		b = append(b, opt...)
+	}
+	if ra.MTU > 0 {
+		opt := make([]byte, 8)
+		opt[0] = ndOptMTU
+		opt[1] = 1
+		binary.BigEndian.PutUint32(opt[4:8], uint32(ra.MTU))
+		b = append(b, opt...)
+	}
+	if ra.Prefix != nil {
+		opt := make([]byte, 32)
+		opt[0] = ndOptPrefixInformation
+		opt[1] = 4
+		opt[2] = byte(ra.PrefixLen)
+		opt[3] = prefixFlagOnLink
+		if ra.Autonomous {
+			opt[3] |= prefixFlagAutonomous
+		}
+		binary.BigEndian.PutUint32(opt[4:8], seconds(ra.ValidLifetime))
+		binary.BigEndian.PutUint32(opt[8:12], seconds(ra.PreferredLifetime))
+		prefix := ra.Prefix.Mask(net.CIDRMask(ra.PrefixLen, 8*net.IPv6len))
+		copy(opt[16:32], prefix.To16())
+		b = append(b, opt...)
+	}
+	if len(ra.DNSServers) > 0 {
+		opt := make([]byte, 8, 8+16*len(ra.DNSServers))
+		opt[0] = ndOptRDNSS
+		opt[1] = byte(1 + 2*len(ra.DNSServers))
+		binary.BigEndian.PutUint32(opt[4:8], seconds(ra.PreferredLifetime))
+		for _, srv := range ra.DNSServers {
+			opt = append(opt, srv.To16()...)
+		}
+		b = append(b, opt...)
+	}
+	return b
+}
@@ -0,0 +1,196 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dhcp6
+
+import (
+	"fmt"
+	"net"
+	"runtime/debug"
+
+	"golang.org/x/net/ipv6"
+
+	"yunion.io/x/log"
+)
+
+const (
+	DHCP6ServerPort = 547
+
+	// hop limit of neighbor discovery messages must be 255
+	ndHopLimit = 255
+)
+
+var (
+	AllDHCPRelayAgentsAndServers = net.ParseIP("ff02::1:2")
+	AllRouters                   = net.ParseIP("ff02::2")
+	AllNodes                     = net.ParseIP("ff02::1")
+)
+
+type DHCP6Handler interface {
+	ServeDHCP6(msg *Message, addr *net.UDPAddr) (*Message, error)
+}
+
+type RouterSolicitationHandler interface {
+	ServeRouterSolicitation(mac net.HardwareAddr, addr *net.IPAddr) (*RouterAdvertisement, error)
+}
+
+func isTemporary(err error) bool {
+	if ne, ok := err.(net.Error); ok {
+		return ne.Temporary()
+	}
+	return false
+}
+
+type DHCP6Server struct {
+	iface string
+	conn  *ipv6.PacketConn
+}
+
+func NewDHCP6Server(iface string) (*DHCP6Server, error) {
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, fmt.Errorf("interface by name: %v", err)
+	}
+	c, err := listenPacketOnDevice("udp6", fmt.Sprintf("[::]:%d", DHCP6ServerPort), iface)
+	if err != nil {
+		return nil, fmt.Errorf("listen dhcp6 on %s: %v", iface, err)
+	}
+	conn := ipv6.NewPacketConn(c)
+	if err := conn.JoinGroup(ifi, &net.UDPAddr{IP: AllDHCPRelayAgentsAndServers}); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("join %s on %s: %v", AllDHCPRelayAgentsAndServers, iface, err)
+	}
+	return &DHCP6Server{
+		iface: iface,
+		conn:  conn,
+	}, nil
+}
+
+func (s *DHCP6Server) ListenAndServe(handler DHCP6Handler) error {
+	defer s.conn.Close()
+	buf := make([]byte, 1500)
+	for {
+		n, _, src, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			if !isTemporary(err) {
+				return err
+			}
+			log.Errorf("[DHCP6] receiving packet on %s: %s", s.iface, err)
+			continue
+		}
+		addr, ok := src.(*net.UDPAddr)
+		if !ok {
+			continue
+		}
+		msg, err := ParseMessage(append([]byte(nil), buf[:n]...))
+		if err != nil {
+			log.Debugf("[DHCP6] invalid packet from %s: %v", addr, err)
+			continue
+		}
+		go func() {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Errorf("Serve panic error: %v", r)
+					debug.PrintStack()
+				}
+			}()
+
+			resp, err := handler.ServeDHCP6(msg, addr)
+			if err != nil {
+				log.Warningf("[DHCP6] handler serve error: %v", err)
+				return
+			}
+			if resp == nil {
+				return
+			}
+			if _, err := s.conn.WriteTo(resp.Marshal(), nil, addr); err != nil {
+				log.Errorf("[DHCP6] failed to response packet to %s: %v", addr, err)
+			}
+		}()
+	}
+}
+
+type RAServer struct {
+	iface string
+	conn  *ipv6.PacketConn
+}
+
+func NewRAServer(iface string) (*RAServer, error) {
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, fmt.Errorf("interface by name: %v", err)
+	}
+	c, err := listenPacketOnDevice("ip6:ipv6-icmp", "::", iface)
+	if err != nil {
+		return nil, fmt.Errorf("listen icmpv6 on %s: %v", iface, err)
+	}
+	conn := ipv6.NewPacketConn(c)
+	var filter ipv6.ICMPFilter
+	filter.SetAll(true)
+	filter.Accept(ipv6.ICMPTypeRouterSolicitation)
+	for _, setup := range []func() error{
+		func() error { return conn.SetICMPFilter(&filter) },
+		func() error { return conn.SetHopLimit(ndHopLimit) },
+		func() error { return conn.SetMulticastHopLimit(ndHopLimit) },
+		func() error { return conn.JoinGroup(ifi, &net.IPAddr{IP: AllRouters}) },
+	} {
+		if err := setup(); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("setup icmpv6 conn on %s: %v", iface, err)
+		}
+	}
+	return &RAServer{
+		iface: iface,
+		conn:  conn,
+	}, nil
+}
+
+func (s *RAServer) ListenAndServe(handler RouterSolicitationHandler) error {
+	defer s.conn.Close()
+	buf := make([]byte, 1500)
+	for {
+		n, _, src, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			if !isTemporary(err) {
+				return err
+			}
+			log.Errorf("[RA] receiving packet on %s: %s", s.iface, err)
+			continue
+		}
+		addr, ok := src.(*net.IPAddr)
+		if !ok {
+			continue
+		}
+		mac, err := ParseRouterSolicitation(buf[:n])
+		if err != nil {
+			log.Debugf("[RA] invalid packet from %s: %v", addr, err)
+			continue
+		}
+		ra, err := handler.ServeRouterSolicitation(mac, addr)
+		if err != nil {
+			log.Warningf("[RA] handler serve error: %v", err)
+			continue
+		}
+		if ra == nil {
+			continue
+		}
+		if addr.IP.IsUnspecified() {
+			// solicitations from unspecified address are answered by multicast
+			addr = &net.IPAddr{IP: AllNodes, Zone: s.iface}
+		}
+		if _, err := s.conn.WriteTo(ra.Marshal(), nil, addr); err != nil {
+			log.Errorf("[RA] failed to send router advertisement to %s: %v", addr, err)
+		}
+	}
+}
@@ -48,10 +48,12 @@ const (
 	ACT_UNCACHED_IMAGE               = "uncached_image"
 	ACT_UPDATE                       = "update"
 	ACT_VM_ATTACH_DISK               = "vm_attach_disk"
+	ACT_VM_ATTACH_VIRTIOFS           = "vm_attach_virtiofs"
 	ACT_VM_BIND_KEYPAIR              = "vm_bind_keypair"
 	ACT_VM_CHANGE_FLAVOR             = "vm_change_flavor"
 	ACT_VM_DEPLOY                    = "vm_deploy"
 	ACT_VM_DETACH_DISK               = "vm_detach_disk"
+	ACT_VM_DETACH_VIRTIOFS           = "vm_detach_virtiofs"
 	ACT_VM_PURGE                     = "vm_purge"
 	ACT_VM_REBUILD                   = "vm_rebuild"
 	ACT_VM_RESET_PSWD                = "vm_reset_pswd"
@@ -94,6 +96,9 @@ const (
 	ACT_VM_IO_THROTTLE               = "vm_io_throttle"
 	ACT_VM_RESET                     = "vm_reset"
 	ACT_VM_SNAPSHOT_AND_CLONE        = "vm_snapshot_and_clone"
+	ACT_VM_SNAPSHOT_QUIESCE          = "vm_snapshot_quiesce"
+	ACT_VM_HA_RESTART                = "vm_ha_restart"
+	ACT_HOST_FENCE                   = "host_fence"
 	ACT_VM_BLOCK_STREAM              = "vm_block_stream"
 	ACT_ATTACH_NETWORK               = "attach_network"
 	ACT_VM_CONVERT                   = "vm_convert"
@@ -170,6 +175,8 @@ const (
 	ACT_REMOVE_GUEST          = "remove_guest"
 	ACT_CREATE_SCALING_POLICY = "create_scaling_policy"
 	ACT_DELETE_SCALING_POLICY = "delete_scaling_policy"
+	ACT_INSTANCE_REFRESH      = "instance_refresh"
+	ACT_LIFECYCLE_HOOK        = "lifecycle_hook"
 
 	ACT_SAVE_TO_TEMPLATE = "save_to_template"
 
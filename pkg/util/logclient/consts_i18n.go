@@ -156,6 +156,10 @@ func init() {
 		EN("Vm Attach Disk").
 		CN("挂载磁盘"),
 	)
+	t.Set(ACT_VM_ATTACH_VIRTIOFS, i18n.NewTableEntry().
+		EN("Vm Attach Virtiofs").
+		CN("挂载共享目录"),
+	)
 	t.Set(ACT_VM_BIND_KEYPAIR, i18n.NewTableEntry().
 		EN("Vm Bind Keypair").
 		CN("绑定密钥"),
@@ -172,6 +176,10 @@ func init() {
 		EN("Vm Detach Disk").
 		CN("卸载磁盘"),
 	)
+	t.Set(ACT_VM_DETACH_VIRTIOFS, i18n.NewTableEntry().
+		EN("Vm Detach Virtiofs").
+		CN("卸载共享目录"),
+	)
 	t.Set(ACT_VM_PURGE, i18n.NewTableEntry().
 		EN("Vm Purge").
 		CN("清除"),
@@ -340,6 +348,18 @@ func init() {
 		EN("Vm Snapshot And Clone").
 		CN("虚拟机快照并克隆"),
 	)
+	t.Set(ACT_VM_SNAPSHOT_QUIESCE, i18n.NewTableEntry().
+		EN("Vm Snapshot Quiesce").
+		CN("虚拟机快照静默"),
+	)
+	t.Set(ACT_VM_HA_RESTART, i18n.NewTableEntry().
+		EN("Vm HA Restart").
+		CN("虚拟机高可用重启"),
+	)
+	t.Set(ACT_HOST_FENCE, i18n.NewTableEntry().
+		EN("Host Fence").
+		CN("宿主机隔离"),
+	)
 	t.Set(ACT_VM_BLOCK_STREAM, i18n.NewTableEntry().
 		EN("Vm Block Stream").
 		CN("同步数据"),
@@ -572,6 +592,14 @@ func init() {
 		EN("Delete Scaling Policy").
 		CN("删除伸缩策略"),
 	)
+	t.Set(ACT_INSTANCE_REFRESH, i18n.NewTableEntry().
+		EN("Instance Refresh").
+		CN("刷新实例"),
+	)
+	t.Set(ACT_LIFECYCLE_HOOK, i18n.NewTableEntry().
+		EN("Lifecycle Hook").
+		CN("生命周期挂钩"),
+	)
 
 	t.Set(ACT_SAVE_TO_TEMPLATE, i18n.NewTableEntry().
 		EN("Save To Template").
@@ -0,0 +1,170 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netutils2
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+)
+
+// IPV6Addr is an IPv6 address in network byte order
+type IPV6Addr [net.IPv6len]byte
+
+func NewIPV6Addr(s string) (IPV6Addr, error) {
+	var addr IPV6Addr
+	ip := net.ParseIP(s)
+	if ip == nil || ip.To4() != nil {
+		return addr, fmt.Errorf("invalid ipv6 address %q", s)
+	}
+	copy(addr[:], ip.To16())
+	return addr, nil
+}
+
+func (addr IPV6Addr) ToIP() net.IP {
+	ip := make(net.IP, net.IPv6len)
+	copy(ip, addr[:])
+	return ip
+}
+
+func (addr IPV6Addr) String() string {
+	return addr.ToIP().String()
+}
+
+// NetAddr returns the prefix of addr with masklen bits
+func (addr IPV6Addr) NetAddr(masklen int8) IPV6Addr {
+	mask := net.CIDRMask(int(masklen), 8*net.IPv6len)
+	var ret IPV6Addr
+	for i := range addr {
+		ret[i] = addr[i] & mask[i]
+	}
+	return ret
+}
+
+func (addr IPV6Addr) StepUp() IPV6Addr {
+	for i := len(addr) - 1; i >= 0; i-- {
+		addr[i]++
+		if addr[i] != 0 {
+			break
+		}
+	}
+	return addr
+}
+
+func (addr IPV6Addr) StepDown() IPV6Addr {
+	for i := len(addr) - 1; i >= 0; i-- {
+		addr[i]--
+		if addr[i] != 0xff {
+			break
+		}
+	}
+	return addr
+}
+
+// Compare returns -1, 0 or 1 when addr is less than, equal to or greater than addr2
+func (addr IPV6Addr) Compare(addr2 IPV6Addr) int {
+	for i := range addr {
+		if addr[i] < addr2[i] {
+			return -1
+		} else if addr[i] > addr2[i] {
+			return 1
+		}
+	}
+	return 0
+}
+
+type IPV6AddrRange struct {
+	start IPV6Addr
+	end   IPV6Addr
+}
+
+func NewIPV6AddrRange(start, end IPV6Addr) IPV6AddrRange {
+	if start.Compare(end) > 0 {
+		start, end = end, start
+	}
+	return IPV6AddrRange{start: start, end: end}
+}
+
+func (r IPV6AddrRange) StartIp() IPV6Addr {
+	return r.start
+}
+
+func (r IPV6AddrRange) EndIp() IPV6Addr {
+	return r.end
+}
+
+func (r IPV6AddrRange) Contains(addr IPV6Addr) bool {
+	return r.start.Compare(addr) <= 0 && addr.Compare(r.end) <= 0
+}
+
+func (r IPV6AddrRange) ContainsRange(r2 IPV6AddrRange) bool {
+	return r.Contains(r2.start) && r.Contains(r2.end)
+}
+
+func (r IPV6AddrRange) IsOverlap(r2 IPV6AddrRange) bool {
+	return r.start.Compare(r2.end) <= 0 && r2.start.Compare(r.end) <= 0
+}
+
+// Random returns a random address in the range, only the lower 64 bits
+// differing between start and end are randomized
+func (r IPV6AddrRange) Random() IPV6Addr {
+	addr := r.start
+	for i := 8; i < len(addr); i++ {
+		addr[i] = byte(rand.Intn(256))
+	}
+	for i := 0; i < 8; i++ {
+		if r.start[i] != r.end[i] {
+			return r.start
+		}
+	}
+	if !r.Contains(addr) {
+		return r.start
+	}
+	return addr
+}
+
+// IPV6EUI64 returns the SLAAC address of the interface with mac in the /64 prefix
+func IPV6EUI64(prefix IPV6Addr, mac string) (IPV6Addr, error) {
+	hw, err := net.ParseMAC(mac)
+	if err != nil || len(hw) != 6 {
+		return prefix, fmt.Errorf("invalid mac address %q", mac)
+	}
+	addr := prefix.NetAddr(64)
+	addr[8] = hw[0] ^ 0x02
+	addr[9] = hw[1]
+	addr[10] = hw[2]
+	addr[11] = 0xff
+	addr[12] = 0xfe
+	addr[13] = hw[3]
+	addr[14] = hw[4]
+	addr[15] = hw[5]
+	return addr, nil
+}
+
+// IPV6LinkLocal returns the EUI-64 link local address of the interface with mac
+func IPV6LinkLocal(mac string) (IPV6Addr, error) {
+	prefix, _ := NewIPV6Addr("fe80::")
+	return IPV6EUI64(prefix, mac)
+}
+
+// MacFromIPV6EUI64 recovers the mac address from an EUI-64 interface identifier
+func MacFromIPV6EUI64(ip net.IP) (string, bool) {
+	ip = ip.To16()
+	if ip == nil || ip.To4() != nil || ip[11] != 0xff || ip[12] != 0xfe {
+		return "", false
+	}
+	hw := net.HardwareAddr{ip[8] ^ 0x02, ip[9], ip[10], ip[13], ip[14], ip[15]}
+	return hw.String(), true
+}
@@ -0,0 +1,70 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netutils2
+
+import (
+	"net"
+	"testing"
+)
+
+func TestIPV6Addr(t *testing.T) {
+	addr, err := NewIPV6Addr("2001:db8::ffff")
+	if err != nil {
+		t.Fatalf("NewIPV6Addr: %v", err)
+	}
+	if got := addr.StepUp().String(); got != "2001:db8::1:0" {
+		t.Errorf("StepUp = %s", got)
+	}
+	if got := addr.StepUp().StepDown(); got != addr {
+		t.Errorf("StepDown = %s", got)
+	}
+	if got := addr.NetAddr(64).String(); got != "2001:db8::" {
+		t.Errorf("NetAddr = %s", got)
+	}
+	if _, err := NewIPV6Addr("10.0.0.1"); err == nil {
+		t.Errorf("ipv4 address accepted")
+	}
+
+	start, _ := NewIPV6Addr("2001:db8::10")
+	end, _ := NewIPV6Addr("2001:db8::20")
+	r := NewIPV6AddrRange(end, start)
+	if r.StartIp() != start || r.EndIp() != end {
+		t.Errorf("range not ordered: %s-%s", r.StartIp(), r.EndIp())
+	}
+	if !r.Contains(start.StepUp()) || r.Contains(end.StepUp()) {
+		t.Errorf("Contains mismatch")
+	}
+	if rnd := r.Random(); !r.Contains(rnd) {
+		t.Errorf("Random %s out of range", rnd)
+	}
+}
+
+func TestIPV6EUI64(t *testing.T) {
+	prefix, _ := NewIPV6Addr("2001:db8:1:2::")
+	addr, err := IPV6EUI64(prefix, "00:22:0d:c0:ff:ee")
+	if err != nil {
+		t.Fatalf("IPV6EUI64: %v", err)
+	}
+	if got := addr.String(); got != "2001:db8:1:2:222:dff:fec0:ffee" {
+		t.Errorf("IPV6EUI64 = %s", got)
+	}
+	mac, ok := MacFromIPV6EUI64(net.ParseIP("fe80::222:dff:fec0:ffee"))
+	if !ok || mac != "00:22:0d:c0:ff:ee" {
+		t.Errorf("MacFromIPV6EUI64 = %s, %v", mac, ok)
+	}
+	if _, ok := MacFromIPV6EUI64(net.ParseIP("fe80::1")); ok {
+		t.Errorf("non EUI-64 address matched")
+	}
+}
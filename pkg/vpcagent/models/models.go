@@ -21,8 +21,15 @@ import (
 type Vpc struct {
 	compute_models.SVpc
 
-	Wire     *Wire    `json:"-"`
-	Networks Networks `json:"-"`
+	Wire          *Wire         `json:"-"`
+	Networks      Networks      `json:"-"`
+	Loadbalancers Loadbalancers `json:"-"`
+	NatGateways   NatGateways   `json:"-"`
+	RouteTables   RouteTables   `json:"-"`
+
+	// PeeringConnections are vpc peering connections requested by or
+	// accepted by this vpc
+	PeeringConnections VpcPeeringConnections `json:"-"`
 }
 
 func (el *Vpc) Copy() *Vpc {
@@ -174,3 +181,121 @@ func (el *DnsRecord) Copy() *DnsRecord {
 		SDnsRecord: el.SDnsRecord,
 	}
 }
+
+type Loadbalancer struct {
+	compute_models.SLoadbalancer
+
+	Vpc           *Vpc                      `json:"-"`
+	Network       *Network                  `json:"-"`
+	Elasticip     *Elasticip                `json:"-"`
+	Listeners     LoadbalancerListeners     `json:"-"`
+	BackendGroups LoadbalancerBackendGroups `json:"-"`
+}
+
+func (el *Loadbalancer) Copy() *Loadbalancer {
+	return &Loadbalancer{
+		SLoadbalancer: el.SLoadbalancer,
+	}
+}
+
+type LoadbalancerListener struct {
+	compute_models.SLoadbalancerListener
+
+	Loadbalancer *Loadbalancer             `json:"-"`
+	BackendGroup *LoadbalancerBackendGroup `json:"-"`
+}
+
+func (el *LoadbalancerListener) Copy() *LoadbalancerListener {
+	return &LoadbalancerListener{
+		SLoadbalancerListener: el.SLoadbalancerListener,
+	}
+}
+
+type LoadbalancerBackendGroup struct {
+	compute_models.SLoadbalancerBackendGroup
+
+	Loadbalancer *Loadbalancer        `json:"-"`
+	Backends     LoadbalancerBackends `json:"-"`
+}
+
+func (el *LoadbalancerBackendGroup) Copy() *LoadbalancerBackendGroup {
+	return &LoadbalancerBackendGroup{
+		SLoadbalancerBackendGroup: el.SLoadbalancerBackendGroup,
+	}
+}
+
+type LoadbalancerBackend struct {
+	compute_models.SLoadbalancerBackend
+
+	BackendGroup *LoadbalancerBackendGroup `json:"-"`
+}
+
+func (el *LoadbalancerBackend) Copy() *LoadbalancerBackend {
+	return &LoadbalancerBackend{
+		SLoadbalancerBackend: el.SLoadbalancerBackend,
+	}
+}
+
+type NatGateway struct {
+	compute_models.SNatGateway
+
+	Vpc      *Vpc        `json:"-"`
+	SEntries NatSEntries `json:"-"`
+	DEntries NatDEntries `json:"-"`
+}
+
+func (el *NatGateway) Copy() *NatGateway {
+	return &NatGateway{
+		SNatGateway: el.SNatGateway,
+	}
+}
+
+type NatSEntry struct {
+	compute_models.SNatSEntry
+
+	NatGateway *NatGateway `json:"-"`
+	Network    *Network    `json:"-"`
+}
+
+func (el *NatSEntry) Copy() *NatSEntry {
+	return &NatSEntry{
+		SNatSEntry: el.SNatSEntry,
+	}
+}
+
+type NatDEntry struct {
+	compute_models.SNatDEntry
+
+	NatGateway *NatGateway `json:"-"`
+}
+
+func (el *NatDEntry) Copy() *NatDEntry {
+	return &NatDEntry{
+		SNatDEntry: el.SNatDEntry,
+	}
+}
+
+type RouteTable struct {
+	compute_models.SRouteTable
+
+	Vpc *Vpc `json:"-"`
+}
+
+func (el *RouteTable) Copy() *RouteTable {
+	return &RouteTable{
+		SRouteTable: el.SRouteTable,
+	}
+}
+
+type VpcPeeringConnection struct {
+	compute_models.SVpcPeeringConnection
+
+	Vpc     *Vpc `json:"-"`
+	PeerVpc *Vpc `json:"-"`
+}
+
+func (el *VpcPeeringConnection) Copy() *VpcPeeringConnection {
+	return &VpcPeeringConnection{
+		SVpcPeeringConnection: el.SVpcPeeringConnection,
+	}
+}
@@ -16,6 +16,7 @@ package models
 
 import (
 	"fmt"
+	"strings"
 
 	"yunion.io/x/log"
 
@@ -41,6 +42,18 @@ type (
 	Guestsecgroups map[string]*Guestsecgroup // key: guestId/secgroupId
 
 	DnsRecords map[string]*DnsRecord
+
+	Loadbalancers             map[string]*Loadbalancer
+	LoadbalancerListeners     map[string]*LoadbalancerListener
+	LoadbalancerBackendGroups map[string]*LoadbalancerBackendGroup
+	LoadbalancerBackends      map[string]*LoadbalancerBackend
+
+	NatGateways map[string]*NatGateway
+	NatSEntries map[string]*NatSEntry
+	NatDEntries map[string]*NatDEntry
+
+	RouteTables           map[string]*RouteTable
+	VpcPeeringConnections map[string]*VpcPeeringConnection
 )
 
 func (set Vpcs) ModelManager() mcclient_modulebase.IBaseManager {
@@ -117,6 +130,92 @@ func (ms Vpcs) joinNetworks(subEntries Networks) bool {
 	return correct
 }
 
+func (ms Vpcs) joinLoadbalancers(subEntries Loadbalancers) bool {
+	for _, m := range ms {
+		m.Loadbalancers = Loadbalancers{}
+	}
+	for subId, subEntry := range subEntries {
+		if !subEntry.IsOvnDataplane() {
+			// served by lbagent
+			delete(subEntries, subId)
+			continue
+		}
+		id := subEntry.VpcId
+		m, ok := ms[id]
+		if !ok {
+			log.Warningf("loadbalancer %s(%s): vpc id %s not found",
+				subEntry.Name, subEntry.Id, id)
+			delete(subEntries, subId)
+			continue
+		}
+		subEntry.Vpc = m
+		m.Loadbalancers[subId] = subEntry
+	}
+	return true
+}
+
+func (ms Vpcs) joinNatGateways(subEntries NatGateways) bool {
+	for _, m := range ms {
+		m.NatGateways = NatGateways{}
+	}
+	for subId, subEntry := range subEntries {
+		id := subEntry.VpcId
+		m, ok := ms[id]
+		if !ok {
+			log.Warningf("natgateway %s(%s): vpc id %s not found",
+				subEntry.Name, subEntry.Id, id)
+			delete(subEntries, subId)
+			continue
+		}
+		subEntry.Vpc = m
+		m.NatGateways[subId] = subEntry
+	}
+	return true
+}
+
+func (ms Vpcs) joinRouteTables(subEntries RouteTables) bool {
+	for _, m := range ms {
+		m.RouteTables = RouteTables{}
+	}
+	for subId, subEntry := range subEntries {
+		id := subEntry.VpcId
+		m, ok := ms[id]
+		if !ok {
+			// route tables of vpcs of other providers
+			delete(subEntries, subId)
+			continue
+		}
+		subEntry.Vpc = m
+		m.RouteTables[subId] = subEntry
+	}
+	return true
+}
+
+func (ms Vpcs) joinVpcPeeringConnections(subEntries VpcPeeringConnections) bool {
+	for _, m := range ms {
+		m.PeeringConnections = VpcPeeringConnections{}
+	}
+	for subId, subEntry := range subEntries {
+		m, ok := ms[subEntry.VpcId]
+		if !ok {
+			delete(subEntries, subId)
+			continue
+		}
+		peer, ok := ms[subEntry.PeerVpcId]
+		if !ok {
+			log.Warningf("vpc peering connection %s(%s): peer vpc id %s not found",
+				subEntry.Name, subEntry.Id, subEntry.PeerVpcId)
+			delete(subEntries, subId)
+			continue
+		}
+		subEntry.Vpc = m
+		subEntry.PeerVpc = peer
+		m.PeeringConnections[subId] = subEntry
+		peer.PeeringConnections[subId] = subEntry
+	}
+	return true
+}
+
 func (set Wires) ModelManager() mcclient_modulebase.IBaseManager {
 	return &mcclient_modules.Wires
 }
@@ -631,3 +730,354 @@ func (set DnsRecords) Copy() apihelper.IModelSet {
 	}
 	return setCopy
 }
+
+func (set Loadbalancers) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.Loadbalancers
+}
+
+func (set Loadbalancers) NewModel() db.IModel {
+	return &Loadbalancer{}
+}
+
+func (set Loadbalancers) AddModel(i db.IModel) {
+	m := i.(*Loadbalancer)
+	set[m.Id] = m
+}
+
+func (set Loadbalancers) Copy() apihelper.IModelSet {
+	setCopy := Loadbalancers{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (ms Loadbalancers) joinNetworks(subEntries Networks) bool {
+	for _, m := range ms {
+		netId := m.NetworkId
+		if i := strings.IndexByte(netId, ','); i >= 0 {
+			netId = netId[:i]
+		}
+		subEntry, ok := subEntries[netId]
+		if !ok {
+			log.Warningf("loadbalancer %s(%s): network id %s not found",
+				m.Name, m.Id, netId)
+			continue
+		}
+		m.Network = subEntry
+	}
+	return true
+}
+
+func (ms Loadbalancers) joinElasticips(subEntries Elasticips) bool {
+	for _, m := range ms {
+		m.Elasticip = nil
+	}
+	correct := true
+	for _, subEntry := range subEntries {
+		if subEntry.AssociateType != computeapis.EIP_ASSOCIATE_TYPE_LOADBALANCER {
+			continue
+		}
+		m, ok := ms[subEntry.AssociateId]
+		if !ok {
+			// loadbalancers not handled by ovn
+			continue
+		}
+		if m.Elasticip != nil {
+			log.Errorf("loadbalancer %s(%s) associated to more than 1 eip: %s, %s", m.Name, m.Id,
+				m.Elasticip.Id, subEntry.Id)
+			correct = false
+			continue
+		}
+		m.Elasticip = subEntry
+	}
+	return correct
+}
+
+func (ms Loadbalancers) joinListeners(subEntries LoadbalancerListeners) bool {
+	for _, m := range ms {
+		m.Listeners = LoadbalancerListeners{}
+	}
+	for subId, subEntry := range subEntries {
+		id := subEntry.LoadbalancerId
+		m, ok := ms[id]
+		if !ok {
+			// listeners of loadbalancers not handled by ovn
+			delete(subEntries, subId)
+			continue
+		}
+		subEntry.Loadbalancer = m
+		m.Listeners[subId] = subEntry
+	}
+	return true
+}
+
+func (ms Loadbalancers) joinBackendGroups(subEntries LoadbalancerBackendGroups) bool {
+	for _, m := range ms {
+		m.BackendGroups = LoadbalancerBackendGroups{}
+	}
+	for subId, subEntry := range subEntries {
+		id := subEntry.LoadbalancerId
+		m, ok := ms[id]
+		if !ok {
+			delete(subEntries, subId)
+			continue
+		}
+		subEntry.Loadbalancer = m
+		m.BackendGroups[subId] = subEntry
+	}
+	return true
+}
+
+func (set LoadbalancerListeners) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.LoadbalancerListeners
+}
+
+func (set LoadbalancerListeners) NewModel() db.IModel {
+	return &LoadbalancerListener{}
+}
+
+func (set LoadbalancerListeners) AddModel(i db.IModel) {
+	m := i.(*LoadbalancerListener)
+	set[m.Id] = m
+}
+
+func (set LoadbalancerListeners) Copy() apihelper.IModelSet {
+	setCopy := LoadbalancerListeners{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (ms LoadbalancerListeners) joinBackendGroups(subEntries LoadbalancerBackendGroups) bool {
+	for _, m := range ms {
+		m.BackendGroup = nil
+		if m.BackendGroupId == "" {
+			continue
+		}
+		subEntry, ok := subEntries[m.BackendGroupId]
+		if !ok {
+			log.Warningf("loadbalancer listener %s(%s): backend group %s not found",
+				m.Name, m.Id, m.BackendGroupId)
+			continue
+		}
+		m.BackendGroup = subEntry
+	}
+	return true
+}
+
+func (set LoadbalancerBackendGroups) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.LoadbalancerBackendGroups
+}
+
+func (set LoadbalancerBackendGroups) NewModel() db.IModel {
+	return &LoadbalancerBackendGroup{}
+}
+
+func (set LoadbalancerBackendGroups) AddModel(i db.IModel) {
+	m := i.(*LoadbalancerBackendGroup)
+	set[m.Id] = m
+}
+
+func (set LoadbalancerBackendGroups) Copy() apihelper.IModelSet {
+	setCopy := LoadbalancerBackendGroups{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (ms LoadbalancerBackendGroups) joinBackends(subEntries LoadbalancerBackends) bool {
+	for _, m := range ms {
+		m.Backends = LoadbalancerBackends{}
+	}
+	for subId, subEntry := range subEntries {
+		id := subEntry.BackendGroupId
+		m, ok := ms[id]
+		if !ok {
+			delete(subEntries, subId)
+			continue
+		}
+		subEntry.BackendGroup = m
+		m.Backends[subId] = subEntry
+	}
+	return true
+}
+
+func (set LoadbalancerBackends) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.LoadbalancerBackends
+}
+
+func (set LoadbalancerBackends) NewModel() db.IModel {
+	return &LoadbalancerBackend{}
+}
+
+func (set LoadbalancerBackends) AddModel(i db.IModel) {
+	m := i.(*LoadbalancerBackend)
+	set[m.Id] = m
+}
+
+func (set LoadbalancerBackends) Copy() apihelper.IModelSet {
+	setCopy := LoadbalancerBackends{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (set NatGateways) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.NatGateways
+}
+
+func (set NatGateways) NewModel() db.IModel {
+	return &NatGateway{}
+}
+
+func (set NatGateways) AddModel(i db.IModel) {
+	m := i.(*NatGateway)
+	set[m.Id] = m
+}
+
+func (set NatGateways) Copy() apihelper.IModelSet {
+	setCopy := NatGateways{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (ms NatGateways) joinSEntries(subEntries NatSEntries) bool {
+	for _, m := range ms {
+		m.SEntries = NatSEntries{}
+	}
+	for subId, subEntry := range subEntries {
+		id := subEntry.NatgatewayId
+		m, ok := ms[id]
+		if !ok {
+			delete(subEntries, subId)
+			continue
+		}
+		subEntry.NatGateway = m
+		m.SEntries[subId] = subEntry
+	}
+	return true
+}
+
+func (ms NatGateways) joinDEntries(subEntries NatDEntries) bool {
+	for _, m := range ms {
+		m.DEntries = NatDEntries{}
+	}
+	for subId, subEntry := range subEntries {
+		id := subEntry.NatgatewayId
+		m, ok := ms[id]
+		if !ok {
+			delete(subEntries, subId)
+			continue
+		}
+		subEntry.NatGateway = m
+		m.DEntries[subId] = subEntry
+	}
+	return true
+}
+
+func (set NatSEntries) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.NatSTable
+}
+
+func (set NatSEntries) NewModel() db.IModel {
+	return &NatSEntry{}
+}
+
+func (set NatSEntries) AddModel(i db.IModel) {
+	m := i.(*NatSEntry)
+	set[m.Id] = m
+}
+
+func (set NatSEntries) Copy() apihelper.IModelSet {
+	setCopy := NatSEntries{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (ms NatSEntries) joinNetworks(subEntries Networks) bool {
+	for _, m := range ms {
+		m.Network = nil
+		if m.NetworkId == "" {
+			continue
+		}
+		subEntry, ok := subEntries[m.NetworkId]
+		if !ok {
+			log.Warningf("snat entry %s(%s): network id %s not found",
+				m.Name, m.Id, m.NetworkId)
+			continue
+		}
+		m.Network = subEntry
+	}
+	return true
+}
+
+func (set NatDEntries) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.NatDTable
+}
+
+func (set NatDEntries) NewModel() db.IModel {
+	return &NatDEntry{}
+}
+
+func (set NatDEntries) AddModel(i db.IModel) {
+	m := i.(*NatDEntry)
+	set[m.Id] = m
+}
+
+func (set NatDEntries) Copy() apihelper.IModelSet {
+	setCopy := NatDEntries{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (set RouteTables) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.RouteTables
+}
+
+func (set RouteTables) NewModel() db.IModel {
+	return &RouteTable{}
+}
+
+func (set RouteTables) AddModel(i db.IModel) {
+	m := i.(*RouteTable)
+	set[m.Id] = m
+}
+
+func (set RouteTables) Copy() apihelper.IModelSet {
+	setCopy := RouteTables{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (set VpcPeeringConnections) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.VpcPeeringConnections
+}
+
+func (set VpcPeeringConnections) NewModel() db.IModel {
+	return &VpcPeeringConnection{}
+}
+
+func (set VpcPeeringConnections) AddModel(i db.IModel) {
+	m := i.(*VpcPeeringConnection)
+	set[m.Id] = m
+}
+
+func (set VpcPeeringConnections) Copy() apihelper.IModelSet {
+	setCopy := VpcPeeringConnections{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
@@ -34,6 +34,18 @@ type ModelSetsMaxUpdatedAt struct {
 	NetworkAddresses   time.Time
 
 	DnsRecords time.Time
+
+	Loadbalancers             time.Time
+	LoadbalancerListeners     time.Time
+	LoadbalancerBackendGroups time.Time
+	LoadbalancerBackends      time.Time
+
+	NatGateways time.Time
+	NatSEntries time.Time
+	NatDEntries time.Time
+
+	RouteTables           time.Time
+	VpcPeeringConnections time.Time
 }
 
 func NewModelSetsMaxUpdatedAt() *ModelSetsMaxUpdatedAt {
@@ -51,6 +63,18 @@ func NewModelSetsMaxUpdatedAt() *ModelSetsMaxUpdatedAt {
 		NetworkAddresses:   apihelper.PseudoZeroTime,
 
 		DnsRecords: apihelper.PseudoZeroTime,
+
+		Loadbalancers:             apihelper.PseudoZeroTime,
+		LoadbalancerListeners:     apihelper.PseudoZeroTime,
+		LoadbalancerBackendGroups: apihelper.PseudoZeroTime,
+		LoadbalancerBackends:      apihelper.PseudoZeroTime,
+
+		NatGateways: apihelper.PseudoZeroTime,
+		NatSEntries: apihelper.PseudoZeroTime,
+		NatDEntries: apihelper.PseudoZeroTime,
+
+		RouteTables:           apihelper.PseudoZeroTime,
+		VpcPeeringConnections: apihelper.PseudoZeroTime,
 	}
 }
 
@@ -68,6 +92,18 @@ type ModelSets struct {
 	NetworkAddresses   NetworkAddresses
 
 	DnsRecords DnsRecords
+
+	Loadbalancers             Loadbalancers
+	LoadbalancerListeners     LoadbalancerListeners
+	LoadbalancerBackendGroups LoadbalancerBackendGroups
+	LoadbalancerBackends      LoadbalancerBackends
+
+	NatGateways NatGateways
+	NatSEntries NatSEntries
+	NatDEntries NatDEntries
+
+	RouteTables           RouteTables
+	VpcPeeringConnections VpcPeeringConnections
 }
 
 func NewModelSets() *ModelSets {
@@ -85,6 +121,18 @@ func NewModelSets() *ModelSets {
 		NetworkAddresses:   NetworkAddresses{},
 
 		DnsRecords: DnsRecords{},
+
+		Loadbalancers:             Loadbalancers{},
+		LoadbalancerListeners:     LoadbalancerListeners{},
+		LoadbalancerBackendGroups: LoadbalancerBackendGroups{},
+		LoadbalancerBackends:      LoadbalancerBackends{},
+
+		NatGateways: NatGateways{},
+		NatSEntries: NatSEntries{},
+		NatDEntries: NatDEntries{},
+
+		RouteTables:           RouteTables{},
+		VpcPeeringConnections: VpcPeeringConnections{},
 	}
 }
 
@@ -104,6 +152,18 @@ func (mss *ModelSets) ModelSetList() []apihelper.IModelSet {
 		mss.NetworkAddresses,
 
 		mss.DnsRecords,
+
+		mss.Loadbalancers,
+		mss.LoadbalancerListeners,
+		mss.LoadbalancerBackendGroups,
+		mss.LoadbalancerBackends,
+
+		mss.NatGateways,
+		mss.NatSEntries,
+		mss.NatDEntries,
+
+		mss.RouteTables,
+		mss.VpcPeeringConnections,
 	}
 }
 
@@ -126,6 +186,18 @@ func (mss *ModelSets) copy_() *ModelSets {
 		NetworkAddresses:   mss.NetworkAddresses.Copy().(NetworkAddresses),
 
 		DnsRecords: mss.DnsRecords.Copy().(DnsRecords),
+
+		Loadbalancers:             mss.Loadbalancers.Copy().(Loadbalancers),
+		LoadbalancerListeners:     mss.LoadbalancerListeners.Copy().(LoadbalancerListeners),
+		LoadbalancerBackendGroups: mss.LoadbalancerBackendGroups.Copy().(LoadbalancerBackendGroups),
+		LoadbalancerBackends:      mss.LoadbalancerBackends.Copy().(LoadbalancerBackends),
+
+		NatGateways: mss.NatGateways.Copy().(NatGateways),
+		NatSEntries: mss.NatSEntries.Copy().(NatSEntries),
+		NatDEntries: mss.NatDEntries.Copy().(NatDEntries),
+
+		RouteTables:           mss.RouteTables.Copy().(RouteTables),
+		VpcPeeringConnections: mss.VpcPeeringConnections.Copy().(VpcPeeringConnections),
 	}
 	return mssCopy
 }
@@ -176,6 +248,19 @@ func (mss *ModelSets) join() bool {
 	p = append(p, mss.Guestnetworks.joinGuests(mss.Guests))
 	p = append(p, mss.Guestnetworks.joinElasticips(mss.Elasticips))
 	p = append(p, mss.Guestnetworks.joinNetworkAddresses(mss.NetworkAddresses))
+	p = append(p, mss.Vpcs.joinLoadbalancers(mss.Loadbalancers))
+	p = append(p, mss.Loadbalancers.joinNetworks(mss.Networks))
+	p = append(p, mss.Loadbalancers.joinElasticips(mss.Elasticips))
+	p = append(p, mss.Loadbalancers.joinBackendGroups(mss.LoadbalancerBackendGroups))
+	p = append(p, mss.Loadbalancers.joinListeners(mss.LoadbalancerListeners))
+	p = append(p, mss.LoadbalancerBackendGroups.joinBackends(mss.LoadbalancerBackends))
+	p = append(p, mss.LoadbalancerListeners.joinBackendGroups(mss.LoadbalancerBackendGroups))
+	p = append(p, mss.Vpcs.joinNatGateways(mss.NatGateways))
+	p = append(p, mss.NatGateways.joinSEntries(mss.NatSEntries))
+	p = append(p, mss.NatGateways.joinDEntries(mss.NatDEntries))
+	p = append(p, mss.NatSEntries.joinNetworks(mss.Networks))
+	p = append(p, mss.Vpcs.joinRouteTables(mss.RouteTables))
+	p = append(p, mss.Vpcs.joinVpcPeeringConnections(mss.VpcPeeringConnections))
 	for _, b := range p {
 		if !b {
 			return false
@@ -40,6 +40,9 @@ type VpcAgentOptions struct {
 	OvnWorkerCheckInterval int    `default:"180"`
 	OvnNorthDatabase       string `help:"address for accessing ovn north database.  Default to local unix socket"`
 	OvnUnderlayMtu         int    `help:"mtu of ovn underlay network" default:"1500"`
+	OvnAclLogRate          int    `help:"rate limit of logged acl hits of security group rules, in packets per second" default:"100"`
+	OvnLbHealthCheck       bool   `help:"health check backends of vpc loadbalancers.  Requires ovn 20.03 or later" default:"true"`
+	OvnNatGatewayChassis   string `help:"name of ovn chassis doing snat and dnat for vpc natgateways and eip loadbalancers.  It should be the chassis of eip gateway.  Eip traffic of vpcs with them will all go through it.  Natgateways are not programmed when empty"`
 }
 
 type Options struct {
@@ -35,6 +35,8 @@ import (
 const (
 	externalKeyOcVersion = "oc-version"
 	externalKeyOcRef     = "oc-ref"
+	externalKeyOcAclLog  = "oc-acl-log"
+	externalKeyOcRate    = "oc-rate"
 )
 
 type OVNNorthboundKeeper struct {
@@ -54,11 +56,23 @@ func DumpOVNNorthbound(ctx context.Context, cli *ovnutil.OvnNbCtl) (*OVNNorthbou
 		&db.DHCPOptions,
 		&db.QoS,
 		&db.DNS,
+		&db.LoadBalancer,
+		&db.NAT,
+		&db.Meter,
+	}
+	// columns introduced by later ovn versions are not known to the
+	// schema package
+	tblColumns := map[string]string{
+		db.LoadBalancer.OvsdbTableName(): "_uuid,_version,external_ids,name,protocol,vips",
+		db.NAT.OvsdbTableName():          "_uuid,_version,external_ids,external_ip,external_mac,logical_ip,logical_port,type",
+		db.Meter.OvsdbTableName():        "_uuid,_version,bands,external_ids,name,unit",
 	}
-	args := []string{"--format=json", "list", "<tbl>"}
 	for _, itbl := range itbls {
 		tbl := itbl.OvsdbTableName()
-		args[2] = tbl
+		args := []string{"--format=json", "list", tbl}
+		if columns, ok := tblColumns[tbl]; ok {
+			args = []string{"--format=json", "--columns=" + columns, "list", tbl}
+		}
 		res := cli.Must(ctx, "List "+tbl, args)
 		if err := cli_util.UnmarshalJSON([]byte(res.Output), itbl); err != nil {
 			return nil, errors.Wrapf(err, "Unmarshal %s:\n%s",
@@ -83,7 +97,7 @@ func ovnCreateArgs(irow types.IRow, idRef string) []string {
 	return args
 }
 
-func (keeper *OVNNorthboundKeeper) ClaimVpc(ctx context.Context, vpc *agentmodels.Vpc) error {
+func (keeper *OVNNorthboundKeeper) ClaimVpc(ctx context.Context, vpc *agentmodels.Vpc, natgwChassis string) error {
 	var (
 		args      []string
 		ocVersion = fmt.Sprintf("%s.%d", vpc.UpdatedAt, vpc.UpdateVersion)
@@ -210,6 +224,10 @@ func (keeper *OVNNorthboundKeeper) ClaimVpc(ctx context.Context, vpc *agentmodel
 			Name:     vpcRepName(vpc.Id),
 			Mac:      apis.VpcEipGatewayMac,
 			Networks: []string{fmt.Sprintf("%s/%d", apis.VpcEipGatewayIP(), apis.VpcEipGatewayIPMask)},
+			Options:  map[string]string{},
+		}
+		if chassis := vpcRepRedirectChassis(vpc, natgwChassis); chassis != "" {
+			vpcRep.Options["redirect-chassis"] = chassis
 		}
 		vpcErp = &ovn_nb.LogicalSwitchPort{
 			Name:      vpcErpName(vpc.Id),
@@ -287,6 +305,17 @@ func (keeper *OVNNorthboundKeeper) ClaimNetwork(ctx context.Context, network *ag
 			"router-port": netRnpName(network.Id),
 		},
 	}
+	if vips := networkLoadbalancerVips(network); len(vips) > 0 {
+		// answer arp requests for vips with mac of the router port.
+		// Traffic to them is dnat'ed by load balancer of the client
+		// switch and then routed by the vpc router
+		addrs := []string{rpMac, network.GuestGateway}
+		if network.IsIPv6Enabled() {
+			addrs = append(addrs, network.GetGateway6())
+		}
+		addrs = append(addrs, vips...)
+		netNrp.Addresses = []string{strings.Join(addrs, " ")}
+	}
 	netMdp := &ovn_nb.LogicalSwitchPort{
 		Name:      netMdpName(network.Id),
 		Type:      "localport",
@@ -323,18 +352,51 @@ func (keeper *OVNNorthboundKeeper) ClaimNetwork(ctx context.Context, network *ag
 	} else {
 		dhcpopts.Options["dns_server"] = "{223.5.5.5,223.6.6.6}"
 	}
-
-	var (
-		args      []string
-		ocVersion = fmt.Sprintf("%s.%d", network.UpdatedAt, network.UpdateVersion)
-	)
-	allFound, args := cmp(&keeper.DB, ocVersion,
+	irows := []types.IRow{
 		netLs,
 		netRnp,
 		netNrp,
 		netMdp,
 		dhcpopts,
+	}
+
+	var dhcp6opts *ovn_nb.DHCPOptions
+	if network.IsIPv6Enabled() {
+		// slaac networks get addresses from router advertisement and
+		// other configurations from stateless dhcpv6
+		addressMode := "dhcpv6_stateful"
+		if network.GetIp6Mode() == apis.NETWORK_IP6_MODE_SLAAC {
+			addressMode = "dhcpv6_stateless"
+		}
+		netRnp.Networks = append(netRnp.Networks, fmt.Sprintf("%s/%d", network.GetGateway6(), network.GuestIp6Mask))
+		netRnp.Ipv6RaConfigs = map[string]string{
+			"address_mode":  addressMode,
+			"send_periodic": "true",
+			"mtu":           fmt.Sprintf("%d", mtu),
+		}
+		dhcp6opts = &ovn_nb.DHCPOptions{
+			Cidr: network.GetIp6Prefix(),
+			Options: map[string]string{
+				"server_id": dhcpMac,
+			},
+			ExternalIds: map[string]string{
+				externalKeyOcRef: dhcp6optsRef(network.Id),
+			},
+		}
+		if addressMode == "dhcpv6_stateless" {
+			dhcp6opts.Options["dhcpv6_stateless"] = "true"
+		}
+		if network.GuestDns6 != "" {
+			dhcp6opts.Options["dns_server"] = "{" + network.GuestDns6 + "}"
+		}
+		irows = append(irows, dhcp6opts)
+	}
+
+	var (
+		args      []string
+		ocVersion = fmt.Sprintf("%s.%d", network.UpdatedAt, network.UpdateVersion)
 	)
+	allFound, args := cmp(&keeper.DB, ocVersion, irows...)
 	if allFound {
 		return nil
 	}
@@ -343,6 +405,9 @@ func (keeper *OVNNorthboundKeeper) ClaimNetwork(ctx context.Context, network *ag
 	args = append(args, ovnCreateArgs(netNrp, netNrp.Name)...)
 	args = append(args, ovnCreateArgs(netMdp, netMdp.Name)...)
 	args = append(args, ovnCreateArgs(dhcpopts, "dhcpopts")...)
+	if dhcp6opts != nil {
+		args = append(args, ovnCreateArgs(dhcp6opts, "dhcp6opts")...)
+	}
 	args = append(args, "--", "add", "Logical_Switch", netLs.Name, "ports", "@"+netNrp.Name, "@"+netMdp.Name)
 	args = append(args, "--", "add", "Logical_Router", vpcLrName(network.Vpc.Id), "ports", "@"+netRnp.Name)
 	return keeper.cli.Must(ctx, "ClaimNetwork", args)
@@ -405,7 +470,24 @@ func (keeper *OVNNorthboundKeeper) ClaimVpcEipgw(ctx context.Context, vpc *agent
 	return keeper.cli.Must(ctx, "ClaimVpcEipgw", args)
 }
 
-func (keeper *OVNNorthboundKeeper) ClaimGuestnetwork(ctx context.Context, guestnetwork *agentmodels.Guestnetwork) error {
+func (keeper *OVNNorthboundKeeper) findDhcpOpts(ctx context.Context, ref string) string {
+	dhcpOptQuery := &ovn_nb.DHCPOptions{
+		ExternalIds: map[string]string{
+			externalKeyOcRef: ref,
+		},
+	}
+	if m := keeper.DB.DHCPOptions.FindOneMatchNonZeros(dhcpOptQuery); m != nil {
+		return m.OvsdbUuid()
+	}
+	args := []string{
+		"--bare", "--columns=_uuid", "find", "DHCP_Options",
+		fmt.Sprintf("external_ids:%s=%q", externalKeyOcRef, ref),
+	}
+	res := keeper.cli.Must(ctx, "find dhcpopt", args)
+	return strings.TrimSpace(res.Output)
+}
+
+func (keeper *OVNNorthboundKeeper) ClaimGuestnetwork(ctx context.Context, guestnetwork *agentmodels.Guestnetwork, natgw bool) error {
 	var (
 		// Callers assure that guestnetwork.Guest is not nil
 		guest   = guestnetwork.Guest
@@ -421,26 +503,18 @@ func (keeper *OVNNorthboundKeeper) ClaimGuestnetwork(ctx context.Context, guestn
 		ocQosRef        = fmt.Sprintf("qos/%s/%s/%s", network.Id, guestnetwork.GuestId, guestnetwork.Ifname)
 		ocQosEipRef     = fmt.Sprintf("qos-eip/%s/%s/%s", vpc.Id, guestnetwork.GuestId, guestnetwork.Ifname)
 		dhcpOpt         string
+		dhcp6Opt        string
+		hasIp6          = guestnetwork.Ip6Addr != "" && network.IsIPv6Enabled()
 	)
 
-	{
-		dhcpOptQuery := &ovn_nb.DHCPOptions{
-			ExternalIds: map[string]string{
-				externalKeyOcRef: guestnetwork.NetworkId,
-			},
-		}
-		if m := keeper.DB.DHCPOptions.FindOneMatchNonZeros(dhcpOptQuery); m != nil {
-			dhcpOpt = m.OvsdbUuid()
-		} else {
-			args := []string{
-				"--bare", "--columns=_uuid", "find", "DHCP_Options",
-				fmt.Sprintf("external_ids:%s=%q", externalKeyOcRef, guestnetwork.NetworkId),
-			}
-			res := keeper.cli.Must(ctx, "find dhcpopt", args)
-			dhcpOpt = strings.TrimSpace(res.Output)
-		}
-		if dhcpOpt == "" {
-			return fmt.Errorf("cannot find dhcpopt for subnet %s", guestnetwork.NetworkId)
+	dhcpOpt = keeper.findDhcpOpts(ctx, guestnetwork.NetworkId)
+	if dhcpOpt == "" {
+		return fmt.Errorf("cannot find dhcpopt for subnet %s", guestnetwork.NetworkId)
+	}
+	if hasIp6 {
+		dhcp6Opt = keeper.findDhcpOpts(ctx, dhcp6optsRef(guestnetwork.NetworkId))
+		if dhcp6Opt == "" {
+			return fmt.Errorf("cannot find dhcp6opt for subnet %s", guestnetwork.NetworkId)
 		}
 	}
 
@@ -454,12 +528,21 @@ func (keeper *OVNNorthboundKeeper) ClaimGuestnetwork(ctx context.Context, guestn
 	}
 	sort.Strings(subIPs[1:])
 	sort.Strings(subIPms[1:])
+	if hasIp6 {
+		// port security of ipv6 is the exact address, link local address
+		// derived from mac is allowed by ovn itself
+		subIPs = append(subIPs, guestnetwork.Ip6Addr)
+		subIPms = append(subIPms, guestnetwork.Ip6Addr)
+	}
 	gnp := &ovn_nb.LogicalSwitchPort{
 		Name:          lportName,
 		Addresses:     []string{fmt.Sprintf("%s %s", guestnetwork.MacAddr, strings.Join(subIPs, " "))},
 		Dhcpv4Options: &dhcpOpt,
 		Options:       map[string]string{},
 	}
+	if hasIp6 {
+		gnp.Dhcpv6Options = &dhcp6Opt
+	}
 	if guest.SrcMacCheck.IsFalse() {
 		gnp.Addresses = append(gnp.Addresses, "unknown")
 		// empty, not nil, as match condition
@@ -517,7 +600,8 @@ func (keeper *OVNNorthboundKeeper) ClaimGuestnetwork(ctx context.Context, guestn
 	)
 	{
 		gnrDefaultPolicy := "src-ip"
-		if eip != nil && vpcHasEipgw(vpc) {
+		useNatgw := natgw && eip == nil && (guestnetworkUseNatgw(guestnetwork) || guestnetworkUseLbEip(guestnetwork))
+		if (eip != nil || useNatgw) && vpcHasEipgw(vpc) {
 			gnrDefault = &ovn_nb.LogicalRouterStaticRoute{
 				Policy:     &gnrDefaultPolicy,
 				IpPrefix:   guestnetwork.IpAddr + "/32",
@@ -527,9 +611,10 @@ func (keeper *OVNNorthboundKeeper) ClaimGuestnetwork(ctx context.Context, guestn
 					externalKeyOcRef: ocGnrDefaultRef,
 				},
 			}
-			if bwMbps := eip.Bandwidth; bwMbps > 0 {
+			// eip bandwidth limit.  Guests nat'ed by natgateway have none
+			if eip != nil && eip.Bandwidth > 0 {
 				var (
-					kbps     = int64(bwMbps * 1000)
+					kbps     = int64(eip.Bandwidth * 1000)
 					kbur     = int64(kbps * 2)
 					eipgwVip = apis.VpcEipGatewayIP3().String()
 				)
@@ -582,7 +667,7 @@ func (keeper *OVNNorthboundKeeper) ClaimGuestnetwork(ctx context.Context, guestn
 			if len(sgr.PeerSecgroupId) > 0 {
 				continue
 			}
-			acl, err := ruleToAcl(lportName, sgr)
+			acl, err := ruleToAcl(lportName, sgr, hasIp6)
 			if err != nil {
 				log.Errorf("converting security group rule to acl: %v", err)
 				break
@@ -590,6 +675,11 @@ func (keeper *OVNNorthboundKeeper) ClaimGuestnetwork(ctx context.Context, guestn
 			acl.ExternalIds = map[string]string{
 				externalKeyOcRef: ocAclRef,
 			}
+			if acl.Log {
+				// false log column is a zero value and not compared,
+				// so turning logging off would not be noticed without it
+				acl.ExternalIds[externalKeyOcAclLog] = "true"
+			}
 			acls = append(acls, acl)
 		}
 	}
@@ -652,6 +742,10 @@ func (keeper *OVNNorthboundKeeper) ClaimVpcGuestDnsRecords(ctx context.Context,
 					ip   = guestnetwork.IpAddr
 				)
 				grs[name] = append(grs[name], ip)
+				if guestnetwork.Ip6Addr != "" && network.IsIPv6Enabled() {
+					// ovn answers AAAA queries with ipv6 addresses of the record
+					grs[name] = append(grs[name], guestnetwork.Ip6Addr)
+				}
 				if !hasValid {
 					hasValid = true
 				}
@@ -687,6 +781,218 @@ func (keeper *OVNNorthboundKeeper) ClaimVpcGuestDnsRecords(ctx context.Context,
 	return nil
 }
 
+func (keeper *OVNNorthboundKeeper) ClaimVpcLoadbalancers(ctx context.Context, vpc *agentmodels.Vpc, healthCheck bool, eip bool) error {
+	if len(vpc.Loadbalancers) == 0 {
+		return nil
+	}
+	var (
+		ports   = vpcLbBackendPorts(vpc)
+		args    []string
+		refs    []string
+		eipRefs []string
+	)
+	claimRows := func(ocVersion string, specs []*lbRowSpec) []string {
+		var refs []string
+		for _, spec := range specs {
+			found := keeper.DB.LoadBalancer.FindOneMatchNonZeros(spec.row)
+			allFound, _ := cmp(&keeper.DB, ocVersion, spec.row)
+			if allFound {
+				refs = append(refs, found.Uuid)
+				continue
+			}
+			args = append(args, spec.createArgs...)
+			refs = append(refs, "@"+spec.row.Name)
+		}
+		return refs
+	}
+	for _, lb := range vpc.Loadbalancers {
+		ocVersion := fmt.Sprintf("%s.%d", lb.UpdatedAt, lb.UpdateVersion)
+		refs = append(refs, claimRows(ocVersion, lbRowSpecs(lb, ports, healthCheck))...)
+		if eip {
+			eipRefs = append(eipRefs, claimRows(ocVersion, lbEipRowSpecs(lb, ports, healthCheck))...)
+		}
+	}
+	// load balancing happens at the switch of clients.  Clients outside
+	// of vpc networks, e.g. those from peered vpcs, reach vips through
+	// the vpc router
+	for _, network := range vpc.Networks {
+		lsName := netLsName(network.Id)
+		args = append(args, keeper.lbAttachArgs("Logical_Switch", lsName, refs)...)
+	}
+	args = append(args, keeper.lbAttachArgs("Logical_Router", vpcLrName(vpc.Id), refs)...)
+	// eip traffic enters the vpc through the eip gateway port of vpc
+	// external router
+	args = append(args, keeper.lbAttachArgs("Logical_Router", vpcExtLrName(vpc.Id), eipRefs)...)
+	if len(args) == 0 {
+		return nil
+	}
+	return keeper.cli.Must(ctx, "ClaimVpcLoadbalancers", args)
+}
+
+// lbAttachArgs returns args for adding load balancers to the logical switch
+// or logical router that does not have them yet
+func (keeper *OVNNorthboundKeeper) lbAttachArgs(tbl string, name string, refs []string) []string {
+	if len(refs) == 0 {
+		return nil
+	}
+	var lbUuids []string
+	switch tbl {
+	case "Logical_Switch":
+		for i := range keeper.DB.LogicalSwitch {
+			if ls := &keeper.DB.LogicalSwitch[i]; ls.Name == name {
+				lbUuids = ls.LoadBalancer
+				break
+			}
+		}
+	case "Logical_Router":
+		for i := range keeper.DB.LogicalRouter {
+			if lr := &keeper.DB.LogicalRouter[i]; lr.Name == name {
+				lbUuids = lr.LoadBalancer
+				break
+			}
+		}
+	}
+	has := map[string]struct{}{}
+	for _, lbUuid := range lbUuids {
+		has[lbUuid] = struct{}{}
+	}
+	var adds []string
+	for _, ref := range refs {
+		if _, ok := has[ref]; !ok {
+			adds = append(adds, ref)
+		}
+	}
+	if len(adds) == 0 {
+		return nil
+	}
+	args := []string{"--", "add", tbl, name, "load_balancer"}
+	return append(args, adds...)
+}
+
+func (keeper *OVNNorthboundKeeper) ClaimVpcNatgateways(ctx context.Context, vpc *agentmodels.Vpc) error {
+	var (
+		vpcExtLrName_ = vpcExtLrName(vpc.Id)
+		args          []string
+		lbRefs        []string
+		nSnat         int
+	)
+	for _, natgw := range vpc.NatGateways {
+		ocVersion := fmt.Sprintf("%s.%d", natgw.UpdatedAt, natgw.UpdateVersion)
+		for _, row := range natgwSnatRows(natgw) {
+			allFound, _ := cmp(&keeper.DB, ocVersion, row)
+			if allFound {
+				continue
+			}
+			ref := fmt.Sprintf("snat%d", nSnat)
+			nSnat++
+			args = append(args, ovnCreateArgs(row, ref)...)
+			args = append(args, "--", "add", "Logical_Router", vpcExtLrName_, "nat", "@"+ref)
+		}
+		for _, row := range natgwDnatRows(natgw) {
+			found := keeper.DB.LoadBalancer.FindOneMatchNonZeros(row)
+			allFound, _ := cmp(&keeper.DB, ocVersion, row)
+			if allFound {
+				lbRefs = append(lbRefs, found.Uuid)
+				continue
+			}
+			args = append(args, ovnCreateArgs(row, row.Name)...)
+			lbRefs = append(lbRefs, "@"+row.Name)
+		}
+	}
+	if len(lbRefs) > 0 {
+		has := map[string]struct{}{}
+		for i := range keeper.DB.LogicalRouter {
+			lr := &keeper.DB.LogicalRouter[i]
+			if lr.Name == vpcExtLrName_ {
+				for _, lbUuid := range lr.LoadBalancer {
+					has[lbUuid] = struct{}{}
+				}
+				break
+			}
+		}
+		var adds []string
+		for _, ref := range lbRefs {
+			if _, ok := has[ref]; !ok {
+				adds = append(adds, ref)
+			}
+		}
+		if len(adds) > 0 {
+			args = append(args, "--", "add", "Logical_Router", vpcExtLrName_, "load_balancer")
+			args = append(args, adds...)
+		}
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	return keeper.cli.Must(ctx, "ClaimVpcNatgateways", args)
+}
+
+func (keeper *OVNNorthboundKeeper) ClaimVpcPeeringConnection(ctx context.Context, link *vpcPeeringLink) error {
+	var (
+		peering   = link.peering
+		ocVersion = fmt.Sprintf("%s.%d", peering.UpdatedAt, peering.UpdateVersion)
+		vpcRp     = link.rp(peering.VpcId)
+		peerVpcRp = link.rp(peering.PeerVpcId)
+	)
+	allFound, args := cmp(&keeper.DB, ocVersion, vpcRp, peerVpcRp)
+	if allFound {
+		return nil
+	}
+	args = append(args, ovnCreateArgs(vpcRp, vpcRp.Name)...)
+	args = append(args, ovnCreateArgs(peerVpcRp, peerVpcRp.Name)...)
+	args = append(args, "--", "add", "Logical_Router", vpcLrName(peering.VpcId), "ports", "@"+vpcRp.Name)
+	args = append(args, "--", "add", "Logical_Router", vpcLrName(peering.PeerVpcId), "ports", "@"+peerVpcRp.Name)
+	return keeper.cli.Must(ctx, "ClaimVpcPeeringConnection", args)
+}
+
+func (keeper *OVNNorthboundKeeper) ClaimVpcRoutes(ctx context.Context, vpc *agentmodels.Vpc, links map[string]*vpcPeeringLink) error {
+	var (
+		vpcLrName_ = vpcLrName(vpc.Id)
+		ocVersion  = fmt.Sprintf("%s.%d", vpc.UpdatedAt, vpc.UpdateVersion)
+		args       []string
+	)
+	for i, row := range vpcStaticRouteRows(vpc, links) {
+		allFound, _ := cmp(&keeper.DB, ocVersion, row)
+		if allFound {
+			continue
+		}
+		ref := fmt.Sprintf("route%d", i)
+		args = append(args, ovnCreateArgs(row, ref)...)
+		args = append(args, "--", "add", "Logical_Router", vpcLrName_, "static_routes", "@"+ref)
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	return keeper.cli.Must(ctx, "ClaimVpcRoutes", args)
+}
+
+// ClaimAclLogMeter claims the meter referred to by logged acls.  Packets
+// exceeding the rate are still handled by acls, only not logged
+func (keeper *OVNNorthboundKeeper) ClaimAclLogMeter(ctx context.Context, rate int) error {
+	var (
+		ocVersion = fmt.Sprintf("%d", rate)
+		meter     = &ovn_nb.Meter{
+			Name: aclLogMeterName,
+			Unit: "pktps",
+			ExternalIds: map[string]string{
+				externalKeyOcRate: ocVersion,
+			},
+		}
+		band = &ovn_nb.MeterBand{
+			Action: "drop",
+			Rate:   int64(rate),
+		}
+	)
+	allFound, args := cmp(&keeper.DB, ocVersion, meter)
+	if allFound {
+		return nil
+	}
+	args = append(args, ovnCreateArgs(band, "aclLogBand")...)
+	args = append(args, ovnCreateArgs(meter, "aclLogMeter")...)
+	args = append(args, types.OvsdbCmdArgsUuidMultiples("bands", []string{"@aclLogBand"})...)
+	return keeper.cli.Must(ctx, "ClaimAclLogMeter", args)
+}
+
 func (keeper *OVNNorthboundKeeper) ClaimDnsRecords(ctx context.Context, vpcs agentmodels.Vpcs, dnsrecords agentmodels.DnsRecords) error {
 	var (
 		names = map[string][]string{}
@@ -759,6 +1065,9 @@ func (keeper *OVNNorthboundKeeper) Mark(ctx context.Context) {
 		&db.DHCPOptions,
 		&db.QoS,
 		&db.DNS,
+		&db.LoadBalancer,
+		&db.Meter,
+		&db.NAT,
 	}
 	for _, itbl := range itbls {
 		for _, irow := range itbl.Rows() {
@@ -777,6 +1086,8 @@ func (keeper *OVNNorthboundKeeper) Sweep(ctx context.Context) error {
 		&db.LogicalRouter,
 		&db.DHCPOptions,
 		&db.DNS,
+		&db.LoadBalancer,
+		&db.Meter,
 	}
 	var irows []types.IRow
 	for _, itbl := range itbls {
@@ -834,5 +1145,19 @@ func (keeper *OVNNorthboundKeeper) Sweep(ctx context.Context) error {
 			keeper.cli.Must(ctx, "Sweep qos", args)
 		}
 	}
+	{
+		var args []string
+		for _, irow := range db.NAT.Rows() {
+			_, ok := irow.GetExternalId(externalKeyOcVersion)
+			if !ok {
+				for _, lr := range db.LogicalRouter.FindNATReferrer_nat(irow.OvsdbUuid()) {
+					args = append(args, "--", "--if-exists", "remove", "Logical_Router", lr.Name, "nat", irow.OvsdbUuid())
+				}
+			}
+		}
+		if len(args) > 0 {
+			keeper.cli.Must(ctx, "Sweep nat", args)
+		}
+	}
 	return nil
 }
@@ -0,0 +1,264 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"crypto/md5"
+	"fmt"
+	"sort"
+	"strings"
+
+	"yunion.io/x/ovsdb/schema/ovn_nb"
+	"yunion.io/x/ovsdb/types"
+	"yunion.io/x/pkg/util/netutils"
+
+	apis "yunion.io/x/onecloud/pkg/apis/compute"
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+)
+
+const (
+	externalKeyOcHealthCheck = "oc-hc"
+)
+
+// networkLoadbalancerVips returns addresses of ovn load balancers allocated
+// from the network
+func networkLoadbalancerVips(network *agentmodels.Network) []string {
+	if network.Vpc == nil {
+		return nil
+	}
+	var vips []string
+	for _, lb := range network.Vpc.Loadbalancers {
+		if lb.Network == network && lb.Address != "" {
+			vips = append(vips, lb.Address)
+		}
+	}
+	sort.Strings(vips)
+	return vips
+}
+
+// lbHealthCheckSrcIp returns the source address of health check probes
+// sent by ovn-controller into the network.  Vpc networks leave the 2nd to
+// last address unallocated.  Empty string will be returned if it was
+// made allocatable by users
+func lbHealthCheckSrcIp(network *agentmodels.Network) string {
+	gw, err := netutils.NewIPV4Addr(network.GuestGateway)
+	if err != nil {
+		return ""
+	}
+	srcIp := gw.BroadcastAddr(network.GuestIpMask).StepDown()
+	start, err := netutils.NewIPV4Addr(network.GuestIpStart)
+	if err != nil {
+		return ""
+	}
+	end, err := netutils.NewIPV4Addr(network.GuestIpEnd)
+	if err != nil {
+		return ""
+	}
+	if netutils.NewIPV4AddrRange(start, end).Contains(srcIp) {
+		return ""
+	}
+	return srcIp.String()
+}
+
+// vpcHasLbEip returns true if eip frontends of loadbalancers in the vpc
+// should be programmed.  Like dnat of natgateways, they are load balanced
+// by the vpc external router and require the eip gateway port to be bound
+// to the natgw chassis
+func vpcHasLbEip(vpc *agentmodels.Vpc, natgwChassis string) bool {
+	if natgwChassis == "" || !vpcHasEipgw(vpc) {
+		return false
+	}
+	for _, lb := range vpc.Loadbalancers {
+		if lb.Elasticip != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// guestnetworkUseLbEip returns true if the guestnetwork is backend of a
+// loadbalancer with eip.  Replies to eip clients have to go back through
+// the eip gateway port where they are un-dnat'ed
+func guestnetworkUseLbEip(guestnetwork *agentmodels.Guestnetwork) bool {
+	vpc := guestnetwork.Network.Vpc
+	for _, lb := range vpc.Loadbalancers {
+		if lb.Elasticip == nil {
+			continue
+		}
+		for _, backendGroup := range lb.BackendGroups {
+			for _, backend := range backendGroup.Backends {
+				if backend.BackendId == guestnetwork.GuestId && backend.Address == guestnetwork.IpAddr {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+type lbBackendPort struct {
+	lport string
+	srcIp string
+}
+
+// vpcLbBackendPorts maps guestId/ipAddr of guestnetworks to their logical
+// switch port and health check source address
+func vpcLbBackendPorts(vpc *agentmodels.Vpc) map[string]lbBackendPort {
+	r := map[string]lbBackendPort{}
+	for _, network := range vpc.Networks {
+		srcIp := lbHealthCheckSrcIp(network)
+		for _, guestnetwork := range network.Guestnetworks {
+			r[guestnetwork.GuestId+"/"+guestnetwork.IpAddr] = lbBackendPort{
+				lport: gnpName(guestnetwork.NetworkId, guestnetwork.Ifname),
+				srcIp: srcIp,
+			}
+		}
+	}
+	return r
+}
+
+type lbRowSpec struct {
+	row        *ovn_nb.LoadBalancer
+	createArgs []string
+}
+
+// lbRowSpecs returns one Load_Balancer row for each protocol of enabled
+// listeners of the loadbalancer, with args for creating them and their
+// health checks
+func lbRowSpecs(lb *agentmodels.Loadbalancer, ports map[string]lbBackendPort, healthCheck bool) []*lbRowSpec {
+	if lb.Address == "" {
+		return nil
+	}
+	return lbFrontendRowSpecs(lb, lb.Address, lbName, ports, healthCheck)
+}
+
+// lbEipRowSpecs is like lbRowSpecs but with the eip address as frontend.
+// The rows are for the vpc external router where eip traffic enters
+func lbEipRowSpecs(lb *agentmodels.Loadbalancer, ports map[string]lbBackendPort, healthCheck bool) []*lbRowSpec {
+	if lb.Elasticip == nil || lb.Elasticip.IpAddr == "" {
+		return nil
+	}
+	return lbFrontendRowSpecs(lb, lb.Elasticip.IpAddr, lbEipName, ports, healthCheck)
+}
+
+func lbFrontendRowSpecs(
+	lb *agentmodels.Loadbalancer,
+	addr string,
+	nameFunc func(lbId, proto string) string,
+	ports map[string]lbBackendPort,
+	healthCheck bool,
+) []*lbRowSpec {
+	listeners := make([]*agentmodels.LoadbalancerListener, 0, len(lb.Listeners))
+	for _, listener := range lb.Listeners {
+		if listener.Status != apis.LB_STATUS_ENABLED {
+			continue
+		}
+		if listener.BackendGroup == nil || len(listener.BackendGroup.Backends) == 0 {
+			continue
+		}
+		listeners = append(listeners, listener)
+	}
+	sort.Slice(listeners, func(i, j int) bool {
+		return listeners[i].ListenerPort < listeners[j].ListenerPort
+	})
+
+	var specs []*lbRowSpec
+	for _, proto := range []string{apis.LB_LISTENER_TYPE_TCP, apis.LB_LISTENER_TYPE_UDP} {
+		var (
+			name        = nameFunc(lb.Id, proto)
+			vips        = map[string]string{}
+			ipPortMaps  = map[string]string{}
+			hcRefs      []string
+			hcArgs      []string
+			hcDigestSrc []string
+		)
+		for _, listener := range listeners {
+			if listener.ListenerType != proto {
+				continue
+			}
+			var (
+				vip      = fmt.Sprintf("%s:%d", addr, listener.ListenerPort)
+				backends = make([]string, 0, len(listener.BackendGroup.Backends))
+				doHc     = healthCheck && listener.HealthCheck == apis.LB_BOOL_ON
+			)
+			for _, backend := range listener.BackendGroup.Backends {
+				if backend.Address == "" {
+					continue
+				}
+				backends = append(backends, fmt.Sprintf("%s:%d", backend.Address, backend.Port))
+				if doHc {
+					port, ok := ports[backend.BackendId+"/"+backend.Address]
+					if ok && port.srcIp != "" {
+						ipPortMaps[backend.Address] = port.lport + ":" + port.srcIp
+					}
+				}
+			}
+			if len(backends) == 0 {
+				continue
+			}
+			sort.Strings(backends)
+			vips[vip] = strings.Join(backends, ",")
+			if doHc {
+				ref := fmt.Sprintf("%s/hc%d", name, len(hcRefs))
+				opts := map[string]string{
+					"interval":      fmt.Sprintf("%d", listener.HealthCheckInterval),
+					"timeout":       fmt.Sprintf("%d", listener.HealthCheckTimeout),
+					"success_count": fmt.Sprintf("%d", listener.HealthCheckRise),
+					"failure_count": fmt.Sprintf("%d", listener.HealthCheckFall),
+				}
+				hcArgs = append(hcArgs, "--", "--id=@"+ref, "create", "Load_Balancer_Health_Check")
+				hcArgs = append(hcArgs, types.OvsdbCmdArgsString("vip", vip)...)
+				hcArgs = append(hcArgs, types.OvsdbCmdArgsMapStringString("options", opts)...)
+				hcRefs = append(hcRefs, "@"+ref)
+				hcDigestSrc = append(hcDigestSrc, fmt.Sprintf("%s/%d/%d/%d/%d", vip,
+					listener.HealthCheckInterval, listener.HealthCheckTimeout,
+					listener.HealthCheckRise, listener.HealthCheckFall))
+			}
+		}
+		if len(vips) == 0 {
+			continue
+		}
+		row := &ovn_nb.LoadBalancer{
+			Name:     name,
+			Protocol: ptr(proto),
+			Vips:     vips,
+			ExternalIds: map[string]string{
+				externalKeyOcRef: lb.Id,
+			},
+		}
+		var args []string
+		if len(hcRefs) > 0 {
+			for ip, m := range ipPortMaps {
+				hcDigestSrc = append(hcDigestSrc, ip+"="+m)
+			}
+			sort.Strings(hcDigestSrc)
+			// health checks are not visible to the schema package.
+			// Changes to them are reflected by the digest
+			row.ExternalIds[externalKeyOcHealthCheck] = fmt.Sprintf("%x",
+				md5.Sum([]byte(strings.Join(hcDigestSrc, ","))))
+			args = append(args, hcArgs...)
+		}
+		args = append(args, ovnCreateArgs(row, row.Name)...)
+		if len(hcRefs) > 0 {
+			args = append(args, types.OvsdbCmdArgsUuidMultiples("health_check", hcRefs)...)
+			args = append(args, types.OvsdbCmdArgsMapStringString("ip_port_mappings", ipPortMaps)...)
+		}
+		specs = append(specs, &lbRowSpec{
+			row:        row,
+			createArgs: args,
+		})
+	}
+	return specs
+}
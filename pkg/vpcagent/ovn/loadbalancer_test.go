@@ -0,0 +1,265 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"yunion.io/x/ovsdb/schema/ovn_nb"
+
+	apis "yunion.io/x/onecloud/pkg/apis/compute"
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+)
+
+func newTestVpcLoadbalancer() (*agentmodels.Vpc, *agentmodels.Loadbalancer) {
+	vpc := &agentmodels.Vpc{
+		Networks:      agentmodels.Networks{},
+		Loadbalancers: agentmodels.Loadbalancers{},
+	}
+	vpc.Id = "vpc0"
+	vpc.ExternalAccessMode = apis.VPC_EXTERNAL_ACCESS_MODE_EIP
+
+	network := &agentmodels.Network{
+		Vpc:           vpc,
+		Guestnetworks: agentmodels.Guestnetworks{},
+	}
+	network.Id = "net0"
+	network.GuestGateway = "192.168.0.1"
+	network.GuestIpStart = "192.168.0.2"
+	network.GuestIpEnd = "192.168.0.200"
+	network.GuestIpMask = 24
+	vpc.Networks[network.Id] = network
+	for i, ip := range []string{"192.168.0.10", "192.168.0.11"} {
+		gn := &agentmodels.Guestnetwork{Network: network}
+		gn.RowId = int64(i)
+		gn.GuestId = "guest" + ip[len(ip)-1:]
+		gn.NetworkId = network.Id
+		gn.IpAddr = ip
+		gn.Ifname = "eth0"
+		network.Guestnetworks[gn.GuestId] = gn
+	}
+
+	lb := &agentmodels.Loadbalancer{
+		Vpc:           vpc,
+		Network:       network,
+		Listeners:     agentmodels.LoadbalancerListeners{},
+		BackendGroups: agentmodels.LoadbalancerBackendGroups{},
+	}
+	lb.Id = "lb0"
+	lb.Address = "192.168.0.100"
+	vpc.Loadbalancers[lb.Id] = lb
+
+	bg := &agentmodels.LoadbalancerBackendGroup{
+		Loadbalancer: lb,
+		Backends:     agentmodels.LoadbalancerBackends{},
+	}
+	bg.Id = "bg0"
+	lb.BackendGroups[bg.Id] = bg
+	for _, ip := range []string{"192.168.0.11", "192.168.0.10"} {
+		backend := &agentmodels.LoadbalancerBackend{BackendGroup: bg}
+		backend.Id = "backend" + ip[len(ip)-1:]
+		backend.BackendId = "guest" + ip[len(ip)-1:]
+		backend.BackendType = apis.LB_BACKEND_GUEST
+		backend.Address = ip
+		backend.Port = 8080
+		bg.Backends[backend.Id] = backend
+	}
+
+	for i, proto := range []string{apis.LB_LISTENER_TYPE_TCP, apis.LB_LISTENER_TYPE_UDP} {
+		listener := &agentmodels.LoadbalancerListener{
+			Loadbalancer: lb,
+			BackendGroup: bg,
+		}
+		listener.Id = "listener-" + proto
+		listener.Status = apis.LB_STATUS_ENABLED
+		listener.ListenerType = proto
+		listener.ListenerPort = 80 + i
+		listener.HealthCheck = apis.LB_BOOL_OFF
+		lb.Listeners[listener.Id] = listener
+	}
+	return vpc, lb
+}
+
+func TestLbRowSpecs(t *testing.T) {
+	vpc, lb := newTestVpcLoadbalancer()
+	ports := vpcLbBackendPorts(vpc)
+
+	t.Run("one row for each protocol", func(t *testing.T) {
+		specs := lbRowSpecs(lb, ports, true)
+		want := []*ovn_nb.LoadBalancer{
+			{
+				Name:        lbName(lb.Id, "tcp"),
+				Protocol:    ptr("tcp"),
+				Vips:        map[string]string{"192.168.0.100:80": "192.168.0.10:8080,192.168.0.11:8080"},
+				ExternalIds: map[string]string{externalKeyOcRef: lb.Id},
+			},
+			{
+				Name:        lbName(lb.Id, "udp"),
+				Protocol:    ptr("udp"),
+				Vips:        map[string]string{"192.168.0.100:81": "192.168.0.10:8080,192.168.0.11:8080"},
+				ExternalIds: map[string]string{externalKeyOcRef: lb.Id},
+			},
+		}
+		if len(specs) != len(want) {
+			t.Fatalf("want %d rows, got %d", len(want), len(specs))
+		}
+		for i := range want {
+			if !reflect.DeepEqual(specs[i].row, want[i]) {
+				t.Errorf("row %d: want %#v, got %#v", i, want[i], specs[i].row)
+			}
+		}
+	})
+
+	t.Run("no eip", func(t *testing.T) {
+		if specs := lbEipRowSpecs(lb, ports, true); len(specs) != 0 {
+			t.Errorf("want no eip rows, got %d", len(specs))
+		}
+	})
+
+	t.Run("eip frontend", func(t *testing.T) {
+		lb.Elasticip = &agentmodels.Elasticip{}
+		lb.Elasticip.IpAddr = "10.0.0.100"
+		defer func() { lb.Elasticip = nil }()
+
+		specs := lbEipRowSpecs(lb, ports, true)
+		if len(specs) != 2 {
+			t.Fatalf("want 2 eip rows, got %d", len(specs))
+		}
+		row := specs[0].row
+		if row.Name != lbEipName(lb.Id, "tcp") {
+			t.Errorf("want name %s, got %s", lbEipName(lb.Id, "tcp"), row.Name)
+		}
+		wantVips := map[string]string{"10.0.0.100:80": "192.168.0.10:8080,192.168.0.11:8080"}
+		if !reflect.DeepEqual(row.Vips, wantVips) {
+			t.Errorf("want vips %v, got %v", wantVips, row.Vips)
+		}
+	})
+
+	t.Run("health check", func(t *testing.T) {
+		listener := lb.Listeners["listener-tcp"]
+		listener.HealthCheck = apis.LB_BOOL_ON
+		listener.HealthCheckInterval = 5
+		listener.HealthCheckTimeout = 3
+		listener.HealthCheckRise = 2
+		listener.HealthCheckFall = 4
+		defer func() { listener.HealthCheck = apis.LB_BOOL_OFF }()
+
+		specs := lbRowSpecs(lb, ports, true)
+		tcp, udp := specs[0], specs[1]
+		if tcp.row.ExternalIds[externalKeyOcHealthCheck] == "" {
+			t.Errorf("tcp row has no health check digest")
+		}
+		if _, ok := udp.row.ExternalIds[externalKeyOcHealthCheck]; ok {
+			t.Errorf("udp row should have no health check digest")
+		}
+		args := strings.Join(tcp.createArgs, " ")
+		for _, want := range []string{
+			"create Load_Balancer_Health_Check",
+			`vip="192.168.0.100:80"`,
+			"failure_count",
+			`"192.168.0.10"="iface-net0-eth0:192.168.0.254"`,
+		} {
+			if !strings.Contains(args, want) {
+				t.Errorf("create args missing %s: %s", want, args)
+			}
+		}
+
+		// digest changes with health check parameters
+		digest := tcp.row.ExternalIds[externalKeyOcHealthCheck]
+		listener.HealthCheckInterval = 10
+		defer func() { listener.HealthCheckInterval = 5 }()
+		if d := lbRowSpecs(lb, ports, true)[0].row.ExternalIds[externalKeyOcHealthCheck]; d == digest {
+			t.Errorf("digest not changed with health check interval")
+		}
+
+		// health check disabled by vpcagent
+		if specs := lbRowSpecs(lb, ports, false); specs[0].row.ExternalIds[externalKeyOcHealthCheck] != "" {
+			t.Errorf("health check not disabled")
+		}
+	})
+}
+
+func TestLbEip(t *testing.T) {
+	vpc, lb := newTestVpcLoadbalancer()
+	gn := vpc.Networks["net0"].Guestnetworks["guest0"]
+
+	if vpcHasLbEip(vpc, "chassis0") {
+		t.Errorf("vpc has no lb eip")
+	}
+	if guestnetworkUseLbEip(gn) {
+		t.Errorf("guestnetwork is not backend of lb with eip")
+	}
+
+	lb.Elasticip = &agentmodels.Elasticip{}
+	lb.Elasticip.IpAddr = "10.0.0.100"
+	if !vpcHasLbEip(vpc, "chassis0") {
+		t.Errorf("vpc has lb eip")
+	}
+	if vpcHasLbEip(vpc, "") {
+		t.Errorf("lb eip requires natgw chassis")
+	}
+	if !guestnetworkUseLbEip(gn) {
+		t.Errorf("guestnetwork is backend of lb with eip")
+	}
+	vpc.ExternalAccessMode = apis.VPC_EXTERNAL_ACCESS_MODE_DISTGW
+	if vpcHasLbEip(vpc, "chassis0") {
+		t.Errorf("lb eip requires eipgw")
+	}
+}
+
+func TestLbAttachArgs(t *testing.T) {
+	keeper := &OVNNorthboundKeeper{
+		DB: ovn_nb.OVNNorthbound{
+			LogicalSwitch: ovn_nb.LogicalSwitchTable{
+				{Name: "ls0", LoadBalancer: []string{"uuid0"}},
+			},
+			LogicalRouter: ovn_nb.LogicalRouterTable{
+				{Name: "lr0", LoadBalancer: []string{"uuid0", "uuid1"}},
+			},
+		},
+	}
+	cases := []struct {
+		tbl  string
+		name string
+		refs []string
+		want []string
+	}{
+		{
+			tbl:  "Logical_Switch",
+			name: "ls0",
+			refs: []string{"uuid0", "@lb1"},
+			want: []string{"--", "add", "Logical_Switch", "ls0", "load_balancer", "@lb1"},
+		},
+		{
+			tbl:  "Logical_Router",
+			name: "lr0",
+			refs: []string{"uuid0", "uuid1"},
+		},
+		{
+			tbl:  "Logical_Router",
+			name: "lr1",
+			refs: []string{"uuid0"},
+			want: []string{"--", "add", "Logical_Router", "lr1", "load_balancer", "uuid0"},
+		},
+	}
+	for _, c := range cases {
+		got := keeper.lbAttachArgs(c.tbl, c.name, c.refs)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s %s: want %v, got %v", c.tbl, c.name, c.want, got)
+		}
+	}
+}
@@ -36,3 +36,7 @@ func HashSubnetDhcpMac(netId string) string {
 func HashSubnetMetadataMac(netId string) string {
 	return HashMac(netId, "md")
 }
+
+func HashVpcPeeringRouterPortMac(peeringId string, vpcId string) string {
+	return HashMac(peeringId, vpcId, "rp")
+}
@@ -67,6 +67,11 @@ func vpcEipLspName(vpcId string, eipgwId string) string {
 	return fmt.Sprintf("vpc-ep/%s/%s", vpcId, eipgwId)
 }
 
+// vpc peering
+func vpcPeeringRpName(vpcId string, peerVpcId string) string {
+	return fmt.Sprintf("vpc-rp/%s/%s", vpcId, peerVpcId)
+}
+
 func netLsName(netId string) string {
 	return fmt.Sprintf("subnet/%s", netId)
 }
@@ -83,9 +88,31 @@ func netMdpName(netId string) string {
 	return fmt.Sprintf("subnet-md/%s", netId)
 }
 
+// dhcp6optsRef returns oc-ref of DHCP_Options for dhcpv6 of the subnet,
+// the one for dhcpv4 uses the bare subnet id
+func dhcp6optsRef(netId string) string {
+	return fmt.Sprintf("dhcp6/%s", netId)
+}
+
 // gnpName returns Logical_Switch_Port name for guestnetwork
 //
 // The name must match what's going to be set on each chassis
 func gnpName(netId string, ifname string) string {
 	return fmt.Sprintf("iface-%s-%s", netId, ifname)
 }
+
+// lbName returns Load_Balancer name for listeners of the same protocol
+func lbName(lbId string, proto string) string {
+	return fmt.Sprintf("vpc-lb/%s/%s", lbId, proto)
+}
+
+// natgwLbName returns Load_Balancer name for dnat entries of the same protocol
+func natgwLbName(natgwId string, proto string) string {
+	return fmt.Sprintf("vpc-nat/%s/%s", natgwId, proto)
+}
+
+// lbEipName returns Load_Balancer name for eip frontends of listeners of the
+// same protocol
+func lbEipName(lbId string, proto string) string {
+	return fmt.Sprintf("vpc-lb-eip/%s/%s", lbId, proto)
+}
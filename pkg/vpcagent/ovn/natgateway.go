@@ -0,0 +1,159 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"yunion.io/x/log"
+	"yunion.io/x/ovsdb/schema/ovn_nb"
+	"yunion.io/x/pkg/util/netutils"
+
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+)
+
+// vpcHasNatgw returns true if snat/dnat rules of natgateways in the vpc
+// should be programmed.  They are done by the eip gateway port of vpc
+// external router, which will be bound to the natgw chassis.  Nat entries
+// are left unprogrammed when no natgw chassis is configured
+func vpcHasNatgw(vpc *agentmodels.Vpc, natgwChassis string) bool {
+	if natgwChassis == "" || !vpcHasEipgw(vpc) {
+		return false
+	}
+	return vpcHasNatEntries(vpc)
+}
+
+// vpcRepRedirectChassis returns the redirect-chassis of the eip gateway
+// port of vpc.  Nat and load balancing on the router require a distributed
+// gateway port.  It is only set for vpcs needing them, because all eip
+// traffic of the vpc, including that of guest eips, will then be
+// centralized on the natgw chassis
+func vpcRepRedirectChassis(vpc *agentmodels.Vpc, natgwChassis string) string {
+	if vpcHasNatgw(vpc, natgwChassis) || vpcHasLbEip(vpc, natgwChassis) {
+		return natgwChassis
+	}
+	return ""
+}
+
+func vpcHasNatEntries(vpc *agentmodels.Vpc) bool {
+	for _, natgw := range vpc.NatGateways {
+		if len(natgw.SEntries) > 0 || len(natgw.DEntries) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// natSEntryLogicalIp returns source cidr of the snat entry in the form
+// accepted by logical_ip column of NAT table
+func natSEntryLogicalIp(sentry *agentmodels.NatSEntry) string {
+	var cidr string
+	if sentry.Network != nil {
+		cidr = fmt.Sprintf("%s/%d", sentry.Network.GuestIpStart, sentry.Network.GuestIpMask)
+	} else {
+		cidr = sentry.SourceCIDR
+	}
+	prefix, err := netutils.NewIPV4Prefix(cidr)
+	if err != nil {
+		log.Errorf("snat entry %s(%s): invalid source cidr %q: %v", sentry.Name, sentry.Id, cidr, err)
+		return ""
+	}
+	return prefix.String()
+}
+
+// guestnetworkUseNatgw returns true if the guestnetwork is covered by a
+// snat entry, or is the internal address of a dnat entry.  Traffic of
+// them has to go through the eip gateway port where nat happens
+func guestnetworkUseNatgw(guestnetwork *agentmodels.Guestnetwork) bool {
+	vpc := guestnetwork.Network.Vpc
+	ip, err := netutils.NewIPV4Addr(guestnetwork.IpAddr)
+	if err != nil {
+		return false
+	}
+	for _, natgw := range vpc.NatGateways {
+		for _, sentry := range natgw.SEntries {
+			if sentry.NetworkId != "" {
+				if sentry.NetworkId == guestnetwork.NetworkId {
+					return true
+				}
+				continue
+			}
+			prefix, err := netutils.NewIPV4Prefix(sentry.SourceCIDR)
+			if err == nil && prefix.Contains(ip) {
+				return true
+			}
+		}
+		for _, dentry := range natgw.DEntries {
+			if dentry.InternalIP == guestnetwork.IpAddr {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// natgwSnatRows returns NAT rows of snat entries of the natgateway
+func natgwSnatRows(natgw *agentmodels.NatGateway) []*ovn_nb.NAT {
+	var rows []*ovn_nb.NAT
+	for _, sentry := range natgw.SEntries {
+		logicalIp := natSEntryLogicalIp(sentry)
+		if logicalIp == "" || sentry.IP == "" {
+			continue
+		}
+		rows = append(rows, &ovn_nb.NAT{
+			Type:       "snat",
+			ExternalIp: sentry.IP,
+			LogicalIp:  logicalIp,
+			ExternalIds: map[string]string{
+				externalKeyOcRef: sentry.Id,
+			},
+		})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].ExternalIds[externalKeyOcRef] < rows[j].ExternalIds[externalKeyOcRef]
+	})
+	return rows
+}
+
+// natgwDnatRows returns one Load_Balancer row for each protocol of dnat
+// entries of the natgateway.  Port mapping is done by load balancing
+// external address and port to the single internal address and port
+func natgwDnatRows(natgw *agentmodels.NatGateway) []*ovn_nb.LoadBalancer {
+	var rows []*ovn_nb.LoadBalancer
+	for _, proto := range []string{"tcp", "udp"} {
+		vips := map[string]string{}
+		for _, dentry := range natgw.DEntries {
+			if strings.ToLower(dentry.IpProtocol) != proto {
+				continue
+			}
+			vip := fmt.Sprintf("%s:%d", dentry.ExternalIP, dentry.ExternalPort)
+			vips[vip] = fmt.Sprintf("%s:%d", dentry.InternalIP, dentry.InternalPort)
+		}
+		if len(vips) == 0 {
+			continue
+		}
+		rows = append(rows, &ovn_nb.LoadBalancer{
+			Name:     natgwLbName(natgw.Id, proto),
+			Protocol: ptr(proto),
+			Vips:     vips,
+			ExternalIds: map[string]string{
+				externalKeyOcRef: natgw.Id,
+			},
+		})
+	}
+	return rows
+}
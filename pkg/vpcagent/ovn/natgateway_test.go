@@ -0,0 +1,347 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"testing"
+
+	apis "yunion.io/x/onecloud/pkg/apis/compute"
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+)
+
+func TestVpcHasNatgw(t *testing.T) {
+	newVpc := func(mode string, nEntries int) *agentmodels.Vpc {
+		vpc := &agentmodels.Vpc{
+			NatGateways: agentmodels.NatGateways{},
+		}
+		vpc.ExternalAccessMode = mode
+		natgw := &agentmodels.NatGateway{
+			Vpc:      vpc,
+			SEntries: agentmodels.NatSEntries{},
+		}
+		natgw.Id = "natgw0"
+		for i := 0; i < nEntries; i++ {
+			sentry := &agentmodels.NatSEntry{}
+			sentry.Id = string(rune('a' + i))
+			natgw.SEntries[sentry.Id] = sentry
+		}
+		vpc.NatGateways[natgw.Id] = natgw
+		return vpc
+	}
+	cases := []struct {
+		name    string
+		vpc     *agentmodels.Vpc
+		chassis string
+		want    bool
+	}{
+		{
+			name:    "nat entries",
+			vpc:     newVpc(apis.VPC_EXTERNAL_ACCESS_MODE_EIP, 1),
+			chassis: "chassis0",
+			want:    true,
+		},
+		{
+			name: "no natgw chassis",
+			vpc:  newVpc(apis.VPC_EXTERNAL_ACCESS_MODE_EIP, 1),
+		},
+		{
+			name:    "no nat entries",
+			vpc:     newVpc(apis.VPC_EXTERNAL_ACCESS_MODE_EIP_DISTGW, 0),
+			chassis: "chassis0",
+		},
+		{
+			name:    "no eipgw",
+			vpc:     newVpc(apis.VPC_EXTERNAL_ACCESS_MODE_DISTGW, 1),
+			chassis: "chassis0",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := vpcHasNatgw(c.vpc, c.chassis); got != c.want {
+				t.Errorf("want %v, got %v", c.want, got)
+			}
+		})
+	}
+}
+
+func newTestNatSEntry(id, ip, sourceCidr string, network *agentmodels.Network) *agentmodels.NatSEntry {
+	sentry := &agentmodels.NatSEntry{Network: network}
+	sentry.Id = id
+	sentry.Name = id
+	sentry.IP = ip
+	sentry.SourceCIDR = sourceCidr
+	if network != nil {
+		sentry.NetworkId = network.Id
+	}
+	return sentry
+}
+
+func newTestNatDEntry(id, proto, extIp string, extPort int, intIp string, intPort int) *agentmodels.NatDEntry {
+	dentry := &agentmodels.NatDEntry{}
+	dentry.Id = id
+	dentry.IpProtocol = proto
+	dentry.ExternalIP = extIp
+	dentry.ExternalPort = extPort
+	dentry.InternalIP = intIp
+	dentry.InternalPort = intPort
+	return dentry
+}
+
+func newTestNatgw(sentries []*agentmodels.NatSEntry, dentries []*agentmodels.NatDEntry) *agentmodels.NatGateway {
+	natgw := &agentmodels.NatGateway{
+		SEntries: agentmodels.NatSEntries{},
+		DEntries: agentmodels.NatDEntries{},
+	}
+	natgw.Id = "natgw0"
+	for _, sentry := range sentries {
+		natgw.SEntries[sentry.Id] = sentry
+	}
+	for _, dentry := range dentries {
+		natgw.DEntries[dentry.Id] = dentry
+	}
+	return natgw
+}
+
+func newTestNatNetwork(id, ipStart string, mask int8) *agentmodels.Network {
+	network := &agentmodels.Network{}
+	network.Id = id
+	network.GuestIpStart = ipStart
+	network.GuestIpMask = mask
+	return network
+}
+
+func TestNatgwSnatRows(t *testing.T) {
+	network := newTestNatNetwork("net0", "10.0.1.1", 24)
+	type snatRow struct {
+		ref, externalIp, logicalIp string
+	}
+	cases := []struct {
+		name     string
+		sentries []*agentmodels.NatSEntry
+		want     []snatRow
+	}{
+		{
+			name: "network and cidr sorted by id",
+			sentries: []*agentmodels.NatSEntry{
+				newTestNatSEntry("s2", "192.168.0.11", "10.0.2.0/24", nil),
+				newTestNatSEntry("s1", "192.168.0.10", "", network),
+			},
+			want: []snatRow{
+				{"s1", "192.168.0.10", "10.0.1.0/24"},
+				{"s2", "192.168.0.11", "10.0.2.0/24"},
+			},
+		},
+		{
+			name: "invalid cidr",
+			sentries: []*agentmodels.NatSEntry{
+				newTestNatSEntry("s1", "192.168.0.10", "10.0.2.0/33", nil),
+			},
+		},
+		{
+			name: "no external ip",
+			sentries: []*agentmodels.NatSEntry{
+				newTestNatSEntry("s1", "", "10.0.2.0/24", nil),
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rows := natgwSnatRows(newTestNatgw(c.sentries, nil))
+			if len(rows) != len(c.want) {
+				t.Fatalf("want %d rows, got %d", len(c.want), len(rows))
+			}
+			for i, row := range rows {
+				got := snatRow{row.ExternalIds[externalKeyOcRef], row.ExternalIp, row.LogicalIp}
+				if row.Type != "snat" || got != c.want[i] {
+					t.Errorf("row %d: want snat %v, got %s %v", i, c.want[i], row.Type, got)
+				}
+			}
+		})
+	}
+}
+
+func TestNatgwDnatRows(t *testing.T) {
+	cases := []struct {
+		name     string
+		dentries []*agentmodels.NatDEntry
+		want     map[string]map[string]string
+	}{
+		{
+			name: "tcp and udp",
+			dentries: []*agentmodels.NatDEntry{
+				newTestNatDEntry("d1", "tcp", "192.168.0.20", 80, "10.0.1.5", 8080),
+				newTestNatDEntry("d2", "TCP", "192.168.0.20", 443, "10.0.1.5", 8443),
+				newTestNatDEntry("d3", "udp", "192.168.0.21", 53, "10.0.2.3", 53),
+			},
+			want: map[string]map[string]string{
+				"tcp": {
+					"192.168.0.20:80":  "10.0.1.5:8080",
+					"192.168.0.20:443": "10.0.1.5:8443",
+				},
+				"udp": {
+					"192.168.0.21:53": "10.0.2.3:53",
+				},
+			},
+		},
+		{
+			name: "udp only",
+			dentries: []*agentmodels.NatDEntry{
+				newTestNatDEntry("d1", "udp", "192.168.0.21", 53, "10.0.2.3", 53),
+			},
+			want: map[string]map[string]string{
+				"udp": {"192.168.0.21:53": "10.0.2.3:53"},
+			},
+		},
+		{
+			name: "unsupported protocol",
+			dentries: []*agentmodels.NatDEntry{
+				newTestNatDEntry("d1", "icmp", "192.168.0.21", 0, "10.0.2.3", 0),
+			},
+			want: map[string]map[string]string{},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			natgw := newTestNatgw(nil, c.dentries)
+			rows := natgwDnatRows(natgw)
+			if len(rows) != len(c.want) {
+				t.Fatalf("want %d rows, got %d", len(c.want), len(rows))
+			}
+			for _, row := range rows {
+				proto := *row.Protocol
+				if row.Name != natgwLbName(natgw.Id, proto) || row.ExternalIds[externalKeyOcRef] != natgw.Id {
+					t.Errorf("%s: unexpected name %s or ref %s", proto, row.Name, row.ExternalIds[externalKeyOcRef])
+				}
+				want := c.want[proto]
+				if len(row.Vips) != len(want) {
+					t.Errorf("%s: want vips %v, got %v", proto, want, row.Vips)
+					continue
+				}
+				for vip, backend := range want {
+					if row.Vips[vip] != backend {
+						t.Errorf("%s: vip %s want %s, got %s", proto, vip, backend, row.Vips[vip])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestGuestnetworkUseNatgw(t *testing.T) {
+	network := newTestNatNetwork("net0", "10.0.1.1", 24)
+	otherNetwork := newTestNatNetwork("net1", "10.0.1.1", 24)
+	vpc := &agentmodels.Vpc{NatGateways: agentmodels.NatGateways{}}
+	natgw := newTestNatgw(
+		[]*agentmodels.NatSEntry{
+			newTestNatSEntry("s1", "192.168.0.10", "", network),
+			newTestNatSEntry("s2", "192.168.0.11", "10.0.2.0/24", nil),
+		},
+		[]*agentmodels.NatDEntry{
+			newTestNatDEntry("d1", "tcp", "192.168.0.20", 80, "10.0.3.5", 8080),
+		},
+	)
+	natgw.Vpc = vpc
+	vpc.NatGateways[natgw.Id] = natgw
+	network.Vpc = vpc
+	otherNetwork.Vpc = vpc
+
+	cases := []struct {
+		name    string
+		network *agentmodels.Network
+		ip      string
+		want    bool
+	}{
+		{"snat by network", network, "10.0.1.5", true},
+		{"snat by cidr", otherNetwork, "10.0.2.9", true},
+		{"dnat internal ip", otherNetwork, "10.0.3.5", true},
+		{"same range of other network", otherNetwork, "10.0.1.5", false},
+		{"not covered", otherNetwork, "10.0.3.6", false},
+		{"invalid ip", otherNetwork, "", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			guestnetwork := &agentmodels.Guestnetwork{Network: c.network}
+			guestnetwork.NetworkId = c.network.Id
+			guestnetwork.IpAddr = c.ip
+			if got := guestnetworkUseNatgw(guestnetwork); got != c.want {
+				t.Errorf("want %v, got %v", c.want, got)
+			}
+		})
+	}
+}
+
+func TestVpcRepRedirectChassis(t *testing.T) {
+	newVpc := func(mode string, natEntries, lbEip bool) *agentmodels.Vpc {
+		vpc := &agentmodels.Vpc{
+			NatGateways:   agentmodels.NatGateways{},
+			Loadbalancers: agentmodels.Loadbalancers{},
+		}
+		vpc.ExternalAccessMode = mode
+		if natEntries {
+			natgw := newTestNatgw(nil, []*agentmodels.NatDEntry{
+				newTestNatDEntry("d1", "tcp", "192.168.0.20", 80, "10.0.3.5", 8080),
+			})
+			natgw.Vpc = vpc
+			vpc.NatGateways[natgw.Id] = natgw
+		}
+		lb := &agentmodels.Loadbalancer{Vpc: vpc}
+		lb.Id = "lb0"
+		if lbEip {
+			lb.Elasticip = &agentmodels.Elasticip{}
+		}
+		vpc.Loadbalancers[lb.Id] = lb
+		return vpc
+	}
+	cases := []struct {
+		name    string
+		vpc     *agentmodels.Vpc
+		chassis string
+		want    string
+	}{
+		{
+			name:    "nat entries",
+			vpc:     newVpc(apis.VPC_EXTERNAL_ACCESS_MODE_EIP, true, false),
+			chassis: "chassis0",
+			want:    "chassis0",
+		},
+		{
+			name:    "loadbalancer eip",
+			vpc:     newVpc(apis.VPC_EXTERNAL_ACCESS_MODE_EIP_DISTGW, false, true),
+			chassis: "chassis0",
+			want:    "chassis0",
+		},
+		{
+			name:    "guest eips only",
+			vpc:     newVpc(apis.VPC_EXTERNAL_ACCESS_MODE_EIP, false, false),
+			chassis: "chassis0",
+		},
+		{
+			name: "no natgw chassis",
+			vpc:  newVpc(apis.VPC_EXTERNAL_ACCESS_MODE_EIP, true, true),
+		},
+		{
+			name:    "no eipgw",
+			vpc:     newVpc(apis.VPC_EXTERNAL_ACCESS_MODE_DISTGW, true, true),
+			chassis: "chassis0",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := vpcRepRedirectChassis(c.vpc, c.chassis); got != c.want {
+				t.Errorf("want %q, got %q", c.want, got)
+			}
+		})
+	}
+}
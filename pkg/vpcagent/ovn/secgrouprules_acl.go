@@ -21,7 +21,19 @@ const (
 	aclDirFromLport = "from-lport"
 )
 
-func ruleToAcl(lport string, rule *agentmodels.SecurityGroupRule) (*ovn_nb.ACL, error) {
+const (
+	aclSeverityAllow = "info"
+	aclSeverityDeny  = "warning"
+)
+
+// aclLogMeterName is the meter limiting rate of acl log messages sent to
+// ovn-controller
+const aclLogMeterName = "oc-acl-log"
+
+// ruleToAcl converts security group rule to acl of lport.  Rules for any
+// address, the default 0.0.0.0/0 included, apply to ipv6 as well only when
+// the lport is dual-stack, so that ipv4 only guests keep the old behavior
+func ruleToAcl(lport string, rule *agentmodels.SecurityGroupRule, dualStack bool) (*ovn_nb.ACL, error) {
 	var (
 		dir    string
 		action string
@@ -57,10 +69,27 @@ func ruleToAcl(lport string, rule *agentmodels.SecurityGroupRule) (*ovn_nb.ACL,
 		return nil, errors.Wrapf(errBadSecgroupRule, "unknown action %q", rule.Action)
 	}
 
+	var (
+		cidr      = strings.TrimSpace(rule.CIDR)
+		l3proto   = "ip4"
+		icmpMatch = "icmp4"
+	)
+	switch {
+	case cidr == "" || cidr == "0.0.0.0/0":
+		cidr = ""
+		if dualStack {
+			l3proto, icmpMatch = "ip", "(icmp4 || icmp6)"
+		}
+	case cidr == "::/0":
+		cidr = ""
+		l3proto, icmpMatch = "ip6", "icmp6"
+	case strings.Contains(cidr, ":"):
+		l3proto, icmpMatch = "ip6", "icmp6"
+	}
 	addL3Match := func() {
-		matches = append(matches, "ip4")
-		if cidr := strings.TrimSpace(rule.CIDR); cidr != "" && cidr != "0.0.0.0/0" {
-			matches = append(matches, fmt.Sprintf("ip4.%s == %s", l3subfn, cidr))
+		matches = append(matches, l3proto)
+		if cidr != "" {
+			matches = append(matches, fmt.Sprintf("%s.%s == %s", l3proto, l3subfn, cidr))
 		}
 	}
 	addL4Match := func(l4proto string) {
@@ -124,9 +153,17 @@ func ruleToAcl(lport string, rule *agentmodels.SecurityGroupRule) (*ovn_nb.ACL,
 			matches = append(matches, strings.Join(portMatches, " || "))
 		}
 	}
+	// neighbor discovery must not be dropped, or ipv6 stops working
+	// altogether
+	addNdMatch := func() {
+		if action == "drop" && l3proto != "ip4" {
+			matches = append(matches, "!(nd || nd_rs || nd_ra)")
+		}
+	}
 	switch rule.Protocol {
 	case secrules.PROTO_ANY:
 		addL3Match()
+		addNdMatch()
 	case secrules.PROTO_TCP:
 		addL3Match()
 		addL4Match("tcp")
@@ -135,7 +172,8 @@ func ruleToAcl(lport string, rule *agentmodels.SecurityGroupRule) (*ovn_nb.ACL,
 		addL4Match("udp")
 	case secrules.PROTO_ICMP:
 		addL3Match()
-		matches = append(matches, "icmp4")
+		matches = append(matches, icmpMatch)
+		addNdMatch()
 	default:
 		return nil, errors.Wrapf(errBadSecgroupRule, "unknown protocol %q", rule.Protocol)
 	}
@@ -150,5 +188,25 @@ func ruleToAcl(lport string, rule *agentmodels.SecurityGroupRule) (*ovn_nb.ACL,
 		Match:     match,
 		Action:    action,
 	}
+	if ruleLogEnabled(rule) {
+		// the acl name is encoded in the log action of its openflow
+		// flows, by which hosts find counters of the rule.  Log
+		// messages, also named, are used for samples of denied flows
+		acl.Log = true
+		acl.Name = ptr(rule.Id)
+		acl.Meter = ptr(aclLogMeterName)
+		if action == "drop" {
+			acl.Severity = ptr(aclSeverityDeny)
+		} else {
+			acl.Severity = ptr(aclSeverityAllow)
+		}
+	}
 	return acl, nil
 }
+
+func ruleLogEnabled(rule *agentmodels.SecurityGroupRule) bool {
+	if rule.Log {
+		return true
+	}
+	return rule.SecurityGroup != nil && rule.SecurityGroup.Log
+}
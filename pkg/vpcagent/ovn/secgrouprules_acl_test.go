@@ -0,0 +1,121 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"testing"
+
+	"yunion.io/x/pkg/util/secrules"
+
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+)
+
+func TestRuleToAcl(t *testing.T) {
+	newRule := func(action, protocol, cidr string) *agentmodels.SecurityGroupRule {
+		rule := &agentmodels.SecurityGroupRule{}
+		rule.Direction = string(secrules.SecurityRuleIngress)
+		rule.Action = action
+		rule.Protocol = protocol
+		rule.CIDR = cidr
+		rule.Ports = "22"
+		return rule
+	}
+	allow, deny := string(secrules.SecurityRuleAllow), string(secrules.SecurityRuleDeny)
+	cases := []struct {
+		name      string
+		rule      *agentmodels.SecurityGroupRule
+		dualStack bool
+		want      string
+	}{
+		{
+			name: "any address of ipv4 only port",
+			rule: newRule(allow, secrules.PROTO_TCP, "0.0.0.0/0"),
+			want: `outport == "lp" && ip4 && tcp && tcp.dst == 22`,
+		},
+		{
+			name:      "any address of dual-stack port",
+			rule:      newRule(allow, secrules.PROTO_TCP, ""),
+			dualStack: true,
+			want:      `outport == "lp" && ip && tcp && tcp.dst == 22`,
+		},
+		{
+			name:      "ipv4 cidr of dual-stack port",
+			rule:      newRule(allow, secrules.PROTO_TCP, "10.0.0.0/8"),
+			dualStack: true,
+			want:      `outport == "lp" && ip4 && ip4.src == 10.0.0.0/8 && tcp && tcp.dst == 22`,
+		},
+		{
+			name: "ipv6 cidr",
+			rule: newRule(allow, secrules.PROTO_ICMP, "fd00::/64"),
+			want: `outport == "lp" && ip6 && ip6.src == fd00::/64 && icmp6`,
+		},
+		{
+			name:      "drop any keeps neighbor discovery",
+			rule:      newRule(deny, secrules.PROTO_ANY, "0.0.0.0/0"),
+			dualStack: true,
+			want:      `outport == "lp" && ip && !(nd || nd_rs || nd_ra)`,
+		},
+		{
+			name: "drop any of ipv4 only port",
+			rule: newRule(deny, secrules.PROTO_ANY, "0.0.0.0/0"),
+			want: `outport == "lp" && ip4`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			acl, err := ruleToAcl("lp", c.rule, c.dualStack)
+			if err != nil {
+				t.Fatalf("ruleToAcl: %v", err)
+			}
+			if acl.Match != c.want {
+				t.Errorf("want match %s, got %s", c.want, acl.Match)
+			}
+		})
+	}
+}
+
+func TestRuleToAclLog(t *testing.T) {
+	rule := &agentmodels.SecurityGroupRule{}
+	rule.Id = "rule0"
+	rule.Direction = string(secrules.SecurityRuleIngress)
+	rule.Action = string(secrules.SecurityRuleDeny)
+	rule.Protocol = secrules.PROTO_ANY
+
+	acl, err := ruleToAcl("lp", rule, false)
+	if err != nil {
+		t.Fatalf("ruleToAcl: %v", err)
+	}
+	if acl.Log || acl.Meter != nil || acl.Name != nil {
+		t.Errorf("acl of rule without log flag is logged")
+	}
+
+	rule.Log = true
+	acl, err = ruleToAcl("lp", rule, false)
+	if err != nil {
+		t.Fatalf("ruleToAcl: %v", err)
+	}
+	if !acl.Log {
+		t.Errorf("acl not logged")
+	}
+	if acl.Name == nil || *acl.Name != rule.Id {
+		t.Errorf("want acl name %s, got %v", rule.Id, acl.Name)
+	}
+	if acl.Meter == nil || *acl.Meter != aclLogMeterName {
+		t.Errorf("want acl meter %s, got %v", aclLogMeterName, acl.Meter)
+	}
+	if acl.Severity == nil || *acl.Severity != aclSeverityDeny {
+		t.Errorf("want severity %s, got %v", aclSeverityDeny, acl.Severity)
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"yunion.io/x/log"
+	"yunion.io/x/ovsdb/schema/ovn_nb"
+	"yunion.io/x/pkg/util/netutils"
+
+	apis "yunion.io/x/onecloud/pkg/apis/compute"
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+	"yunion.io/x/onecloud/pkg/vpcagent/ovn/mac"
+)
+
+func vpcPeeringIsActive(peering *agentmodels.VpcPeeringConnection) bool {
+	return peering.Enabled.Bool() &&
+		peering.Status == apis.VPC_PEERING_CONNECTION_STATUS_ACTIVE &&
+		peering.Vpc != nil &&
+		peering.PeerVpc != nil &&
+		peering.VpcId != apis.DEFAULT_VPC_ID &&
+		peering.PeerVpcId != apis.DEFAULT_VPC_ID
+}
+
+// vpcPeeringLink is the point-to-point link between routers of the two
+// vpcs of a peering connection.  Each link takes a /30 from the vpc
+// peering cidr.  The requester router takes the first address, the
+// accepter router takes the second
+type vpcPeeringLink struct {
+	peering *agentmodels.VpcPeeringConnection
+	slot    uint32
+}
+
+func (link *vpcPeeringLink) ip(vpcId string) netutils.IPV4Addr {
+	base := apis.VpcPeeringCidr().Address + netutils.IPV4Addr(link.slot<<(32-apis.VpcPeeringLinkMask))
+	if vpcId == link.peering.VpcId {
+		return base + 1
+	}
+	return base + 2
+}
+
+func (link *vpcPeeringLink) peerVpc(vpcId string) *agentmodels.Vpc {
+	if vpcId == link.peering.VpcId {
+		return link.peering.PeerVpc
+	}
+	return link.peering.Vpc
+}
+
+func (link *vpcPeeringLink) peerIp(vpcId string) netutils.IPV4Addr {
+	return link.ip(link.peerVpc(vpcId).Id)
+}
+
+func (link *vpcPeeringLink) rpName(vpcId string) string {
+	return vpcPeeringRpName(vpcId, link.peerVpc(vpcId).Id)
+}
+
+func (link *vpcPeeringLink) rp(vpcId string) *ovn_nb.LogicalRouterPort {
+	peerVpcId := link.peerVpc(vpcId).Id
+	return &ovn_nb.LogicalRouterPort{
+		Name:     vpcPeeringRpName(vpcId, peerVpcId),
+		Mac:      mac.HashVpcPeeringRouterPortMac(link.peering.Id, vpcId),
+		Networks: []string{fmt.Sprintf("%s/%d", link.ip(vpcId), apis.VpcPeeringLinkMask)},
+		Peer:     ptr(vpcPeeringRpName(peerVpcId, vpcId)),
+	}
+}
+
+// vpcPeeringLinks returns links of active vpc peering connections.  Link
+// networks are taken from slots allocated by the region and kept on
+// peering records, so they are not affected by other peerings.  Should
+// two peerings ever claim the same slot, the older one keeps it
+func vpcPeeringLinks(peerings agentmodels.VpcPeeringConnections) map[string]*vpcPeeringLink {
+	var ids []string
+	for id, peering := range peerings {
+		if vpcPeeringIsActive(peering) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		ci, cj := peerings[ids[i]].CreatedAt, peerings[ids[j]].CreatedAt
+		if !ci.Equal(cj) {
+			return ci.Before(cj)
+		}
+		return ids[i] < ids[j]
+	})
+
+	var (
+		nslot = apis.VpcPeeringLinkSlots()
+		used  = map[int]string{}
+		links = map[string]*vpcPeeringLink{}
+	)
+	for _, id := range ids {
+		slot := peerings[id].OvnLinkSlot
+		if slot < 0 || slot >= nslot {
+			log.Warningf("vpc peering connection %s: invalid link slot %d", id, slot)
+			continue
+		}
+		if usedBy, ok := used[slot]; ok {
+			log.Errorf("vpc peering connection %s: link slot %d used by %s", id, slot, usedBy)
+			continue
+		}
+		used[slot] = id
+		links[id] = &vpcPeeringLink{
+			peering: peerings[id],
+			slot:    uint32(slot),
+		}
+	}
+	return links
+}
+
+// vpcLinksOf returns links of the vpc in the order of peering id
+func vpcLinksOf(vpc *agentmodels.Vpc, links map[string]*vpcPeeringLink) []*vpcPeeringLink {
+	var r []*vpcPeeringLink
+	for id := range vpc.PeeringConnections {
+		if link, ok := links[id]; ok {
+			r = append(r, link)
+		}
+	}
+	sort.Slice(r, func(i, j int) bool {
+		return r[i].peering.Id < r[j].peering.Id
+	})
+	return r
+}
+
+func vpcCidrPrefixes(vpc *agentmodels.Vpc) []netutils.IPV4Prefix {
+	var r []netutils.IPV4Prefix
+	for _, block := range strings.Split(vpc.CidrBlock, ",") {
+		block = strings.TrimSpace(block)
+		if block == "" {
+			continue
+		}
+		prefix, err := netutils.NewIPV4Prefix(block)
+		if err != nil {
+			log.Errorf("vpc %s(%s): invalid cidr block %q: %v", vpc.Name, vpc.Id, block, err)
+			continue
+		}
+		r = append(r, prefix)
+	}
+	return r
+}
+
+// vpcStaticRouteRows returns dst-ip static routes of the vpc router.
+// Entries of route tables come first.  Routes to cidr blocks of peered
+// vpcs are added unless a route table entry with the same prefix exists
+func vpcStaticRouteRows(vpc *agentmodels.Vpc, links map[string]*vpcPeeringLink) []*ovn_nb.LogicalRouterStaticRoute {
+	var (
+		rows []*ovn_nb.LogicalRouterStaticRoute
+		has  = map[string]struct{}{}
+	)
+
+	var rtIds []string
+	for id := range vpc.RouteTables {
+		rtIds = append(rtIds, id)
+	}
+	sort.Strings(rtIds)
+	for _, rtId := range rtIds {
+		rt := vpc.RouteTables[rtId]
+		if rt.Routes == nil {
+			continue
+		}
+		for _, route := range *rt.Routes {
+			prefix, err := netutils.NewIPV4Prefix(route.Cidr)
+			if err != nil {
+				log.Errorf("route table %s(%s): invalid cidr %q: %v", rt.Name, rt.Id, route.Cidr, err)
+				continue
+			}
+			ipPrefix := prefix.String()
+			if _, ok := has[ipPrefix]; ok {
+				continue
+			}
+			row := &ovn_nb.LogicalRouterStaticRoute{
+				Policy:   ptr("dst-ip"),
+				IpPrefix: ipPrefix,
+				ExternalIds: map[string]string{
+					externalKeyOcRef: rt.Id,
+				},
+			}
+			switch route.NextHopType {
+			case apis.Next_HOP_TYPE_IP:
+				row.Nexthop = route.NextHopId
+			case apis.Next_HOP_TYPE_VPCPEERING:
+				link, ok := links[route.NextHopId]
+				if !ok {
+					continue
+				}
+				row.Nexthop = link.peerIp(vpc.Id).String()
+				row.OutputPort = ptr(link.rpName(vpc.Id))
+			default:
+				continue
+			}
+			has[ipPrefix] = struct{}{}
+			rows = append(rows, row)
+		}
+	}
+
+	for _, link := range vpcLinksOf(vpc, links) {
+		for _, prefix := range vpcCidrPrefixes(link.peerVpc(vpc.Id)) {
+			ipPrefix := prefix.String()
+			if _, ok := has[ipPrefix]; ok {
+				continue
+			}
+			has[ipPrefix] = struct{}{}
+			rows = append(rows, &ovn_nb.LogicalRouterStaticRoute{
+				Policy:     ptr("dst-ip"),
+				IpPrefix:   ipPrefix,
+				Nexthop:    link.peerIp(vpc.Id).String(),
+				OutputPort: ptr(link.rpName(vpc.Id)),
+				ExternalIds: map[string]string{
+					externalKeyOcRef: link.peering.Id,
+				},
+			})
+		}
+	}
+	return rows
+}
@@ -0,0 +1,218 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"yunion.io/x/pkg/tristate"
+
+	apis "yunion.io/x/onecloud/pkg/apis/compute"
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+)
+
+func newTestVpc(id string, cidrBlock string) *agentmodels.Vpc {
+	vpc := &agentmodels.Vpc{
+		RouteTables:        agentmodels.RouteTables{},
+		PeeringConnections: agentmodels.VpcPeeringConnections{},
+	}
+	vpc.Id = id
+	vpc.CidrBlock = cidrBlock
+	return vpc
+}
+
+func newTestVpcPeering(id string, vpc, peerVpc *agentmodels.Vpc, slot int) *agentmodels.VpcPeeringConnection {
+	peering := &agentmodels.VpcPeeringConnection{
+		Vpc:     vpc,
+		PeerVpc: peerVpc,
+	}
+	peering.Id = id
+	peering.VpcId = vpc.Id
+	peering.PeerVpcId = peerVpc.Id
+	peering.Enabled = tristate.True
+	peering.Status = apis.VPC_PEERING_CONNECTION_STATUS_ACTIVE
+	peering.OvnLinkSlot = slot
+	vpc.PeeringConnections[id] = peering
+	peerVpc.PeeringConnections[id] = peering
+	return peering
+}
+
+func TestVpcPeeringLinks(t *testing.T) {
+	var (
+		vpc0 = newTestVpc("vpc0", "10.0.0.0/16")
+		vpc1 = newTestVpc("vpc1", "10.1.0.0/16")
+		vpc2 = newTestVpc("vpc2", "10.2.0.0/16")
+	)
+	inactive := newTestVpcPeering("p-inactive", vpc1, vpc2, 5)
+	inactive.Status = apis.VPC_PEERING_CONNECTION_STATUS_PENDING_ACCEPT
+	disabled := newTestVpcPeering("p-disabled", vpc1, vpc2, 6)
+	disabled.Enabled = tristate.False
+
+	peerings := agentmodels.VpcPeeringConnections{
+		"p1":         newTestVpcPeering("p1", vpc0, vpc1, 1),
+		"p0":         newTestVpcPeering("p0", vpc0, vpc2, 0),
+		"p-dup":      newTestVpcPeering("p-dup", vpc1, vpc2, 1),
+		"p-none":     newTestVpcPeering("p-none", vpc1, vpc2, -1),
+		"p-overflow": newTestVpcPeering("p-overflow", vpc1, vpc2, apis.VpcPeeringLinkSlots()),
+		"p-inactive": inactive,
+		"p-disabled": disabled,
+	}
+	// p-dup claims the slot of p1 which is older
+	now := time.Now()
+	peerings["p1"].CreatedAt = now.Add(-time.Hour)
+	peerings["p-dup"].CreatedAt = now
+	links := vpcPeeringLinks(peerings)
+
+	cases := []struct {
+		id       string
+		slot     uint32
+		vpcIp    string
+		peerIp   string
+		excluded bool
+	}{
+		{id: "p0", slot: 0, vpcIp: "100.65.128.1", peerIp: "100.65.128.2"},
+		{id: "p1", slot: 1, vpcIp: "100.65.128.5", peerIp: "100.65.128.6"},
+		// p-dup sorts before p1 by id but must not take its slot
+		{id: "p-dup", excluded: true},
+		{id: "p-none", excluded: true},
+		{id: "p-overflow", excluded: true},
+		{id: "p-inactive", excluded: true},
+		{id: "p-disabled", excluded: true},
+	}
+	for _, c := range cases {
+		t.Run(c.id, func(t *testing.T) {
+			link, ok := links[c.id]
+			if c.excluded {
+				if ok {
+					t.Fatalf("want no link, got slot %d", link.slot)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("want link, got none")
+			}
+			if link.slot != c.slot {
+				t.Errorf("want slot %d, got %d", c.slot, link.slot)
+			}
+			vpcId := link.peering.VpcId
+			if ip := link.ip(vpcId).String(); ip != c.vpcIp {
+				t.Errorf("want vpc ip %s, got %s", c.vpcIp, ip)
+			}
+			if ip := link.peerIp(vpcId).String(); ip != c.peerIp {
+				t.Errorf("want peer ip %s, got %s", c.peerIp, ip)
+			}
+		})
+	}
+	if len(links) != 2 {
+		t.Errorf("want 2 links, got %d", len(links))
+	}
+}
+
+func TestVpcStaticRouteRows(t *testing.T) {
+	type routeRow struct {
+		ipPrefix   string
+		nexthop    string
+		outputPort string
+		ref        string
+	}
+	newRouteTable := func(vpc *agentmodels.Vpc, id string, routes ...*apis.SRoute) {
+		rt := &agentmodels.RouteTable{Vpc: vpc}
+		rt.Id = id
+		srs := apis.SRoutes(routes)
+		rt.Routes = &srs
+		vpc.RouteTables[id] = rt
+	}
+
+	cases := []struct {
+		name  string
+		setup func() (*agentmodels.Vpc, map[string]*vpcPeeringLink)
+		want  []routeRow
+	}{
+		{
+			name: "peer cidr blocks",
+			setup: func() (*agentmodels.Vpc, map[string]*vpcPeeringLink) {
+				vpc0 := newTestVpc("vpc0", "10.0.0.0/16")
+				vpc1 := newTestVpc("vpc1", "10.1.0.0/16,10.11.0.0/16")
+				peering := newTestVpcPeering("p0", vpc1, vpc0, 2)
+				return vpc0, vpcPeeringLinks(agentmodels.VpcPeeringConnections{"p0": peering})
+			},
+			want: []routeRow{
+				{"10.1.0.0/16", "100.65.128.9", "vpc-rp/vpc0/vpc1", "p0"},
+				{"10.11.0.0/16", "100.65.128.9", "vpc-rp/vpc0/vpc1", "p0"},
+			},
+		},
+		{
+			name: "route table entries first",
+			setup: func() (*agentmodels.Vpc, map[string]*vpcPeeringLink) {
+				vpc0 := newTestVpc("vpc0", "10.0.0.0/16")
+				vpc1 := newTestVpc("vpc1", "10.1.0.0/16")
+				peering := newTestVpcPeering("p0", vpc0, vpc1, 0)
+				newRouteTable(vpc0, "rt0",
+					&apis.SRoute{Cidr: "10.1.0.0/16", NextHopType: apis.Next_HOP_TYPE_IP, NextHopId: "10.0.0.254"},
+					&apis.SRoute{Cidr: "192.168.0.0/24", NextHopType: apis.Next_HOP_TYPE_VPCPEERING, NextHopId: "p0"},
+					&apis.SRoute{Cidr: "192.168.1.0/24", NextHopType: apis.Next_HOP_TYPE_VPCPEERING, NextHopId: "p-missing"},
+					&apis.SRoute{Cidr: "bad", NextHopType: apis.Next_HOP_TYPE_IP, NextHopId: "10.0.0.254"},
+				)
+				return vpc0, vpcPeeringLinks(agentmodels.VpcPeeringConnections{"p0": peering})
+			},
+			want: []routeRow{
+				{"10.1.0.0/16", "10.0.0.254", "", "rt0"},
+				{"192.168.0.0/24", "100.65.128.2", "vpc-rp/vpc0/vpc1", "rt0"},
+			},
+		},
+		{
+			name: "first route table wins",
+			setup: func() (*agentmodels.Vpc, map[string]*vpcPeeringLink) {
+				vpc0 := newTestVpc("vpc0", "10.0.0.0/16")
+				newRouteTable(vpc0, "rt1",
+					&apis.SRoute{Cidr: "172.16.0.0/12", NextHopType: apis.Next_HOP_TYPE_IP, NextHopId: "10.0.0.2"},
+				)
+				newRouteTable(vpc0, "rt0",
+					&apis.SRoute{Cidr: "172.16.0.0/12", NextHopType: apis.Next_HOP_TYPE_IP, NextHopId: "10.0.0.1"},
+				)
+				return vpc0, nil
+			},
+			want: []routeRow{
+				{"172.16.0.0/12", "10.0.0.1", "", "rt0"},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			vpc, links := c.setup()
+			rows := vpcStaticRouteRows(vpc, links)
+			got := make([]routeRow, 0, len(rows))
+			for _, row := range rows {
+				r := routeRow{
+					ipPrefix: row.IpPrefix,
+					nexthop:  row.Nexthop,
+					ref:      row.ExternalIds[externalKeyOcRef],
+				}
+				if row.OutputPort != nil {
+					r.outputPort = *row.OutputPort
+				}
+				if *row.Policy != "dst-ip" {
+					t.Errorf("%s: want dst-ip policy, got %s", row.IpPrefix, *row.Policy)
+				}
+				got = append(got, r)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("want %v, got %v", c.want, got)
+			}
+		})
+	}
+}
@@ -115,11 +115,16 @@ func (w *Worker) run(ctx context.Context, mss *agentmodels.ModelSets) (err error
 	}
 
 	ovndb.Mark(ctx)
+	ovndb.ClaimAclLogMeter(ctx, w.opts.OvnAclLogRate)
 	for _, vpc := range mss.Vpcs {
 		if vpc.Id == apis.DEFAULT_VPC_ID {
 			continue
 		}
-		ovndb.ClaimVpc(ctx, vpc)
+		if w.opts.OvnNatGatewayChassis == "" && vpcHasNatEntries(vpc) {
+			log.Warningf("vpc %s(%s) has nat entries but ovn_nat_gateway_chassis is not set", vpc.Name, vpc.Id)
+		}
+		natgw := vpcHasNatgw(vpc, w.opts.OvnNatGatewayChassis) || vpcHasLbEip(vpc, w.opts.OvnNatGatewayChassis)
+		ovndb.ClaimVpc(ctx, vpc, w.opts.OvnNatGatewayChassis)
 		if vpcHasEipgw(vpc) {
 			ovndb.ClaimVpcEipgw(ctx, vpc)
 		}
@@ -161,15 +166,24 @@ func (w *Worker) run(ctx context.Context, mss *agentmodels.ModelSets) (err error
 
 					ovndb.ClaimVpcHost(ctx, vpc, host)
 				}
-				ovndb.ClaimGuestnetwork(ctx, guestnetwork)
+				ovndb.ClaimGuestnetwork(ctx, guestnetwork, natgw)
 			}
 		}
 	}
+	links := vpcPeeringLinks(mss.VpcPeeringConnections)
+	for _, link := range links {
+		ovndb.ClaimVpcPeeringConnection(ctx, link)
+	}
 	for _, vpc := range mss.Vpcs {
 		if vpc.Id == apis.DEFAULT_VPC_ID {
 			continue
 		}
+		ovndb.ClaimVpcRoutes(ctx, vpc, links)
 		ovndb.ClaimVpcGuestDnsRecords(ctx, vpc)
+		ovndb.ClaimVpcLoadbalancers(ctx, vpc, w.opts.OvnLbHealthCheck, vpcHasLbEip(vpc, w.opts.OvnNatGatewayChassis))
+		if vpcHasNatgw(vpc, w.opts.OvnNatGatewayChassis) {
+			ovndb.ClaimVpcNatgateways(ctx, vpc)
+		}
 	}
 	ovndb.ClaimDnsRecords(ctx, mss.Vpcs, mss.DnsRecords)
 	ovndb.Sweep(ctx)
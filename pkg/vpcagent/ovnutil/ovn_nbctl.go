@@ -162,6 +162,7 @@ func OvnNbctlArgsDestroy(irows []types.IRow) []string {
 		case *ovn_nb.LogicalRouterStaticRoute:
 		case *ovn_nb.ACL:
 		case *ovn_nb.QoS:
+		case *ovn_nb.NAT:
 		default:
 			if !irow.OvsdbIsRoot() {
 				panic(irow.OvsdbTableName())